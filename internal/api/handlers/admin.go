@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/GlebRadaev/shlink/internal/dto"
+	"github.com/GlebRadaev/shlink/internal/middleware/auth"
+	"github.com/GlebRadaev/shlink/internal/service"
+	"github.com/GlebRadaev/shlink/internal/service/admin"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AdminHandlers defines the handlers of the admin API, acting on the links of any user.
+// Every handler requires an administrator.
+type AdminHandlers struct {
+	// adminService is the service performing and auditing the actions of administrators.
+	adminService *service.AdminService
+}
+
+// NewAdminHandlers creates a new instance of AdminHandlers.
+func NewAdminHandlers(adminService *service.AdminService) *AdminHandlers {
+	return &AdminHandlers{adminService: adminService}
+}
+
+// LookupURL handles the request to look up a URL, deleted or not, with its owner.
+func (h *AdminHandlers) LookupURL(w http.ResponseWriter, r *http.Request) {
+	identity, ok := authorizeAdmin(w, r)
+	if !ok {
+		return
+	}
+	url, err := h.adminService.LookupURL(r.Context(), actor(identity), chi.URLParam(r, "id"))
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, url)
+}
+
+// DisableURL handles the request to disable a URL, with an optional JSON body giving the reason.
+func (h *AdminHandlers) DisableURL(w http.ResponseWriter, r *http.Request) {
+	h.setURLDisabled(w, r, true)
+}
+
+// RestoreURL handles the request to restore a disabled URL, with an optional JSON body giving the reason.
+func (h *AdminHandlers) RestoreURL(w http.ResponseWriter, r *http.Request) {
+	h.setURLDisabled(w, r, false)
+}
+
+// setURLDisabled disables or restores the URL of the request.
+func (h *AdminHandlers) setURLDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	identity, ok := authorizeAdmin(w, r)
+	if !ok {
+		return
+	}
+	reason, err := readReason(r)
+	if err != nil {
+		http.Error(w, "cannot decode request", http.StatusBadRequest)
+		return
+	}
+	count, err := h.adminService.SetURLDisabled(r.Context(), actor(identity), chi.URLParam(r, "id"), disabled, reason)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, dto.AdminActionResponseDTO{Affected: count})
+}
+
+// ListUserURLs handles the request to list a page of the URLs of a user, deleted and disabled
+// ones included, with the query options of GET /api/user/urls.
+func (h *AdminHandlers) ListUserURLs(w http.ResponseWriter, r *http.Request) {
+	identity, ok := authorizeAdmin(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	page, err := h.adminService.ListUserURLs(r.Context(), actor(identity), chi.URLParam(r, "userID"), dto.GetUserURLsRequestDTO{
+		Limit:       query.Get("limit"),
+		Cursor:      query.Get("cursor"),
+		CreatedFrom: query.Get("created_from"),
+		CreatedTo:   query.Get("created_to"),
+		Status:      query.Get("status"),
+		Search:      query.Get("search"),
+		Sort:        query.Get("sort"),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if page.NextCursor != "" {
+		w.Header().Set(NextCursorHeader, page.NextCursor)
+	}
+	if len(page.URLs) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, page.URLs)
+}
+
+// DisableUserURLs handles the request to disable all URLs of a user, with an optional JSON body
+// giving the reason.
+func (h *AdminHandlers) DisableUserURLs(w http.ResponseWriter, r *http.Request) {
+	identity, ok := authorizeAdmin(w, r)
+	if !ok {
+		return
+	}
+	reason, err := readReason(r)
+	if err != nil {
+		http.Error(w, "cannot decode request", http.StatusBadRequest)
+		return
+	}
+	count, err := h.adminService.DisableUserURLs(r.Context(), actor(identity), chi.URLParam(r, "userID"), reason)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, dto.AdminActionResponseDTO{Affected: count})
+}
+
+// DisableDomainURLs handles the request to disable all URLs pointing to a domain or one of its
+// subdomains, with an optional JSON body giving the reason.
+func (h *AdminHandlers) DisableDomainURLs(w http.ResponseWriter, r *http.Request) {
+	identity, ok := authorizeAdmin(w, r)
+	if !ok {
+		return
+	}
+	reason, err := readReason(r)
+	if err != nil {
+		http.Error(w, "cannot decode request", http.StatusBadRequest)
+		return
+	}
+	count, err := h.adminService.DisableDomainURLs(r.Context(), actor(identity), chi.URLParam(r, "domain"), reason)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, dto.AdminActionResponseDTO{Affected: count})
+}
+
+// AuditLog handles the request to list a page of the audit log, newest entries first, filtered
+// by the target, before and limit query parameters.
+func (h *AdminHandlers) AuditLog(w http.ResponseWriter, r *http.Request) {
+	if _, ok := authorizeAdmin(w, r); !ok {
+		return
+	}
+	query := r.URL.Query()
+	page, err := h.adminService.AuditLog(r.Context(), dto.AuditLogRequestDTO{
+		Target: query.Get("target"),
+		Before: query.Get("before"),
+		Limit:  query.Get("limit"),
+	})
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, page)
+}
+
+// DeadTasks handles the request to list the background tasks that ran out of attempts, newest
+// first, at most as many as the limit query parameter.
+func (h *AdminHandlers) DeadTasks(w http.ResponseWriter, r *http.Request) {
+	identity, ok := authorizeAdmin(w, r)
+	if !ok {
+		return
+	}
+	tasks, err := h.adminService.DeadTasks(r.Context(), actor(identity), r.URL.Query().Get("limit"))
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, tasks)
+}
+
+// ReplayDeadTask handles the request to move a dead letter back to the task queue, with an
+// optional JSON body giving the reason.
+func (h *AdminHandlers) ReplayDeadTask(w http.ResponseWriter, r *http.Request) {
+	identity, ok := authorizeAdmin(w, r)
+	if !ok {
+		return
+	}
+	reason, err := readReason(r)
+	if err != nil {
+		http.Error(w, "cannot decode request", http.StatusBadRequest)
+		return
+	}
+	replayed, err := h.adminService.ReplayDeadTask(r.Context(), actor(identity), chi.URLParam(r, "id"), reason)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, replayed)
+}
+
+// TaskStats handles the request for the live statistics of the workers processing the background tasks.
+func (h *AdminHandlers) TaskStats(w http.ResponseWriter, r *http.Request) {
+	if _, ok := authorizeAdmin(w, r); !ok {
+		return
+	}
+	stats, err := h.adminService.TaskStats(r.Context())
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, stats)
+}
+
+// ResizeWorkers handles the request to change the number of workers processing the background
+// tasks and the concurrency limits of the task types, responding with the resulting statistics.
+func (h *AdminHandlers) ResizeWorkers(w http.ResponseWriter, r *http.Request) {
+	identity, ok := authorizeAdmin(w, r)
+	if !ok {
+		return
+	}
+	var data dto.ResizeWorkersRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, "cannot decode request", http.StatusBadRequest)
+		return
+	}
+	stats, err := h.adminService.ResizeWorkers(r.Context(), actor(identity), data)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, stats)
+}
+
+// actor returns the administrator of the identity as recorded in the audit log.
+func actor(identity auth.Identity) admin.Actor {
+	result := admin.Actor{UserID: identity.UserID}
+	if identity.APIKey != nil {
+		result.APIKeyID = identity.APIKey.ID
+	}
+	return result
+}
+
+// readReason returns the reason of the optional JSON body of an admin action.
+func readReason(r *http.Request) (string, error) {
+	var data dto.AdminActionRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return data.Reason, nil
+}
+
+// writeAdminError responds with the status matching an error of the admin service.
+func writeAdminError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, admin.ErrURLNotFound) || errors.Is(err, admin.ErrTaskNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, admin.ErrInvalidReason) || errors.Is(err, admin.ErrInvalidDomain) || errors.Is(err, admin.ErrInvalidAuditQuery) ||
+		errors.Is(err, admin.ErrInvalidTaskQuery) || errors.Is(err, admin.ErrInvalidWorkers):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeJSON responds with the value encoded as JSON.
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GlebRadaev/shlink/internal/dto"
+	"github.com/GlebRadaev/shlink/internal/middleware/auth"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHandlers(t *testing.T) {
+	ctx := context.Background()
+	services, _, err := setupURL(ctx)
+	require.NoError(t, err)
+	handler := NewAdminHandlers(services.AdminService)
+	shortURL, err := services.URLService.Shorten(ctx, "admin-owner", dto.ShortenJSONRequestDTO{URL: "http://admin-handlers.example.com/1"})
+	require.NoError(t, err)
+	shortID := shortURL[strings.LastIndex(shortURL, "/")+1:]
+
+	router := chi.NewRouter()
+	router.Get("/api/admin/urls/{id}", handler.LookupURL)
+	router.Post("/api/admin/urls/{id}/disable", handler.DisableURL)
+	router.Post("/api/admin/urls/{id}/restore", handler.RestoreURL)
+	router.Get("/api/admin/users/{userID}/urls", handler.ListUserURLs)
+	router.Post("/api/admin/users/{userID}/disable", handler.DisableUserURLs)
+	router.Post("/api/admin/domains/{domain}/disable", handler.DisableDomainURLs)
+	router.Get("/api/admin/audit", handler.AuditLog)
+	router.Get("/api/admin/tasks/dead", handler.DeadTasks)
+	router.Post("/api/admin/tasks/dead/{id}/replay", handler.ReplayDeadTask)
+	router.Get("/api/admin/tasks/stats", handler.TaskStats)
+	router.Put("/api/admin/tasks/workers", handler.ResizeWorkers)
+	send := func(identity *auth.Identity, method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		if identity != nil {
+			req = req.WithContext(auth.WithIdentity(req.Context(), *identity))
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	administrator := &auth.Identity{UserID: "admin-handlers", APIKey: &model.APIKey{ID: "admin-key"}, Admin: true}
+
+	tests := []struct {
+		name       string
+		identity   *auth.Identity
+		method     string
+		url        string
+		body       string
+		wantStatus int
+	}{
+		{name: "unauthorized", method: http.MethodGet, url: "/api/admin/urls/" + shortID, wantStatus: http.StatusUnauthorized},
+		{name: "not an administrator", identity: &auth.Identity{UserID: "admin-owner"}, method: http.MethodGet, url: "/api/admin/urls/" + shortID, wantStatus: http.StatusForbidden},
+		{name: "lookup", identity: administrator, method: http.MethodGet, url: "/api/admin/urls/" + shortID, wantStatus: http.StatusOK},
+		{name: "lookup of an unknown URL", identity: administrator, method: http.MethodGet, url: "/api/admin/urls/unknown1", wantStatus: http.StatusNotFound},
+		{name: "disable with invalid body", identity: administrator, method: http.MethodPost, url: "/api/admin/urls/" + shortID + "/disable", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "disable with a long reason", identity: administrator, method: http.MethodPost, url: "/api/admin/urls/" + shortID + "/disable", body: `{"reason":"` + strings.Repeat("a", 501) + `"}`, wantStatus: http.StatusBadRequest},
+		{name: "disable", identity: administrator, method: http.MethodPost, url: "/api/admin/urls/" + shortID + "/disable", body: `{"reason":"spam"}`, wantStatus: http.StatusOK},
+		{name: "restore without a body", identity: administrator, method: http.MethodPost, url: "/api/admin/urls/" + shortID + "/restore", wantStatus: http.StatusOK},
+		{name: "restore an unknown URL", identity: administrator, method: http.MethodPost, url: "/api/admin/urls/unknown1/restore", wantStatus: http.StatusNotFound},
+		{name: "list", identity: administrator, method: http.MethodGet, url: "/api/admin/users/admin-owner/urls", wantStatus: http.StatusOK},
+		{name: "list with invalid options", identity: administrator, method: http.MethodGet, url: "/api/admin/users/admin-owner/urls?sort=invalid", wantStatus: http.StatusBadRequest},
+		{name: "list of a user without URLs", identity: administrator, method: http.MethodGet, url: "/api/admin/users/admin-nobody/urls", wantStatus: http.StatusNoContent},
+		{name: "disable user", identity: administrator, method: http.MethodPost, url: "/api/admin/users/admin-owner/disable", wantStatus: http.StatusOK},
+		{name: "disable invalid domain", identity: administrator, method: http.MethodPost, url: "/api/admin/domains/com/disable", wantStatus: http.StatusBadRequest},
+		{name: "disable domain", identity: administrator, method: http.MethodPost, url: "/api/admin/domains/admin-handlers.example.com/disable", wantStatus: http.StatusOK},
+		{name: "audit with invalid options", identity: administrator, method: http.MethodGet, url: "/api/admin/audit?limit=0", wantStatus: http.StatusBadRequest},
+		{name: "audit not an administrator", identity: &auth.Identity{UserID: "admin-owner"}, method: http.MethodGet, url: "/api/admin/audit", wantStatus: http.StatusForbidden},
+		{name: "dead tasks", identity: administrator, method: http.MethodGet, url: "/api/admin/tasks/dead", wantStatus: http.StatusOK},
+		{name: "dead tasks with invalid options", identity: administrator, method: http.MethodGet, url: "/api/admin/tasks/dead?limit=x", wantStatus: http.StatusBadRequest},
+		{name: "replay an unknown task", identity: administrator, method: http.MethodPost, url: "/api/admin/tasks/dead/12345/replay", wantStatus: http.StatusNotFound},
+		{name: "replay an invalid task ID", identity: administrator, method: http.MethodPost, url: "/api/admin/tasks/dead/x/replay", wantStatus: http.StatusBadRequest},
+		{name: "task stats", identity: administrator, method: http.MethodGet, url: "/api/admin/tasks/stats", wantStatus: http.StatusOK},
+		{name: "task stats not an administrator", identity: &auth.Identity{UserID: "admin-owner"}, method: http.MethodGet, url: "/api/admin/tasks/stats", wantStatus: http.StatusForbidden},
+		{name: "resize with invalid body", identity: administrator, method: http.MethodPut, url: "/api/admin/tasks/workers", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "resize to too many workers", identity: administrator, method: http.MethodPut, url: "/api/admin/tasks/workers", body: `{"workers":100000}`, wantStatus: http.StatusBadRequest},
+		{name: "limit an unknown task type", identity: administrator, method: http.MethodPut, url: "/api/admin/tasks/workers", body: `{"limits":{"unknown_task":1}}`, wantStatus: http.StatusBadRequest},
+		{name: "resize", identity: administrator, method: http.MethodPut, url: "/api/admin/tasks/workers", body: `{"workers":2,"limits":{"delete_urls_task":1}}`, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantStatus, send(tt.identity, tt.method, tt.url, tt.body).Code)
+		})
+	}
+
+	w := send(administrator, http.MethodGet, "/api/admin/audit?target="+shortID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var audit dto.AuditLogResponseDTO
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&audit))
+	require.Len(t, audit.Entries, 3)
+	assert.Equal(t, model.AuditRestoreURL, audit.Entries[0].Action)
+	assert.Equal(t, model.AuditDisableURL, audit.Entries[1].Action)
+	assert.Equal(t, "spam", audit.Entries[1].Reason)
+	assert.Equal(t, "admin-key", audit.Entries[1].APIKeyID)
+	assert.Equal(t, int64(1), audit.Entries[1].Affected)
+
+	w = send(administrator, http.MethodGet, "/api/admin/tasks/stats", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var stats dto.WorkerPoolStatsResponseDTO
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&stats))
+	assert.Equal(t, 2, stats.Workers, "Expected the workers to be resized")
+	assert.Equal(t, 1, stats.Types["delete_urls_task"].Limit)
+
+	w = send(administrator, http.MethodGet, "/api/admin/urls/"+shortID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var found dto.AdminURLResponseDTO
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&found))
+	assert.Equal(t, "admin-owner", found.UserID)
+	assert.True(t, found.IsDisabled, "Expected the URL to be disabled with the URLs of its user")
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/GlebRadaev/shlink/internal/dto"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/service"
+	"github.com/GlebRadaev/shlink/internal/service/apikey"
+	"github.com/GlebRadaev/shlink/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// APIKeyHandlers defines the handlers managing the API keys of the user. Keys are managed with
+// the user cookie only, so that a leaked key cannot be used to mint further keys.
+type APIKeyHandlers struct {
+	// apiKeyService is the service that creates, lists and revokes API keys.
+	apiKeyService *service.APIKeyService
+}
+
+// NewAPIKeyHandlers creates a new instance of APIKeyHandlers.
+func NewAPIKeyHandlers(apiKeyService *service.APIKeyService) *APIKeyHandlers {
+	return &APIKeyHandlers{apiKeyService: apiKeyService}
+}
+
+// CreateAPIKey handles the request to create an API key with the requested name and scopes.
+// The response is the only one containing the key itself.
+func (h *APIKeyHandlers) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	identity, ok := authorizeCookie(w, r)
+	if !ok {
+		return
+	}
+	if err := utils.ValidateContentType(w, r, "application/json"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var data dto.CreateAPIKeyRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, "cannot decode request", http.StatusBadRequest)
+		return
+	}
+	if slices.Contains(data.Scopes, model.ScopeAdmin) && !identity.Admin {
+		http.Error(w, "Only administrators can grant the "+model.ScopeAdmin+" scope", http.StatusForbidden)
+		return
+	}
+
+	key, err := h.apiKeyService.Create(r.Context(), identity.UserID, data)
+	if err != nil {
+		if errors.Is(err, apikey.ErrInvalidScope) || errors.Is(err, apikey.ErrInvalidName) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(key); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetAPIKeys lists the API keys of the user, revoked ones included, without the keys themselves.
+func (h *APIKeyHandlers) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	identity, ok := authorizeCookie(w, r)
+	if !ok {
+		return
+	}
+	keys, err := h.apiKeyService.List(r.Context(), identity.UserID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(keys) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(keys); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// RevokeAPIKey revokes an API key of the user, so that it is no longer accepted.
+func (h *APIKeyHandlers) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	identity, ok := authorizeCookie(w, r)
+	if !ok {
+		return
+	}
+	err := h.apiKeyService.Revoke(r.Context(), identity.UserID, chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, apikey.ErrAPIKeyNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GlebRadaev/shlink/internal/dto"
+	"github.com/GlebRadaev/shlink/internal/middleware/auth"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyHandlers(t *testing.T) {
+	ctx := context.Background()
+	services, _, err := setupURL(ctx)
+	require.NoError(t, err)
+	handler := NewAPIKeyHandlers(services.APIKeyService)
+
+	router := chi.NewRouter()
+	router.Post("/api/user/keys", handler.CreateAPIKey)
+	router.Get("/api/user/keys", handler.GetAPIKeys)
+	router.Delete("/api/user/keys/{id}", handler.RevokeAPIKey)
+	send := func(identity *auth.Identity, method, url, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		if identity != nil {
+			req = req.WithContext(auth.WithIdentity(req.Context(), *identity))
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	owner := &auth.Identity{UserID: "keys-owner"}
+	apiKeyUser := &auth.Identity{UserID: "keys-owner", APIKey: &model.APIKey{Scopes: model.Scopes}}
+
+	tests := []struct {
+		name        string
+		identity    *auth.Identity
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{name: "unauthorized", contentType: "application/json", body: `{"scopes":["read"]}`, wantStatus: http.StatusUnauthorized},
+		{name: "API key", identity: apiKeyUser, contentType: "application/json", body: `{"scopes":["read"]}`, wantStatus: http.StatusForbidden},
+		{name: "wrong content type", identity: owner, contentType: "text/plain", body: `{"scopes":["read"]}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid body", identity: owner, contentType: "application/json", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "no scopes", identity: owner, contentType: "application/json", body: `{"name":"ci"}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown scope", identity: owner, contentType: "application/json", body: `{"scopes":["superuser"]}`, wantStatus: http.StatusBadRequest},
+		{name: "admin scope of a user", identity: owner, contentType: "application/json", body: `{"scopes":["admin"]}`, wantStatus: http.StatusForbidden},
+		{name: "admin scope of an admin", identity: &auth.Identity{UserID: "keys-admin", Admin: true}, contentType: "application/json", body: `{"scopes":["admin"]}`, wantStatus: http.StatusCreated},
+		{name: "name too long", identity: owner, contentType: "application/json", body: `{"name":"` + strings.Repeat("a", 101) + `","scopes":["read"]}`, wantStatus: http.StatusBadRequest},
+		{name: "valid request", identity: owner, contentType: "application/json", body: `{"name":"ci","scopes":["read","shorten"]}`, wantStatus: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantStatus, send(tt.identity, http.MethodPost, "/api/user/keys", tt.contentType, tt.body).Code)
+		})
+	}
+
+	assert.Equal(t, http.StatusNoContent, send(&auth.Identity{UserID: "keys-nobody"}, http.MethodGet, "/api/user/keys", "", "").Code)
+	w := send(owner, http.MethodGet, "/api/user/keys", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var keys []dto.APIKeyResponseDTO
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&keys))
+	require.Len(t, keys, 1)
+	assert.Equal(t, "ci", keys[0].Name)
+	assert.Equal(t, []string{model.ScopeShorten, model.ScopeRead}, keys[0].Scopes)
+	assert.Equal(t, http.StatusForbidden, send(apiKeyUser, http.MethodGet, "/api/user/keys", "", "").Code)
+
+	assert.Equal(t, http.StatusNotFound, send(&auth.Identity{UserID: "keys-other"}, http.MethodDelete, "/api/user/keys/"+keys[0].ID, "", "").Code)
+	assert.Equal(t, http.StatusNoContent, send(owner, http.MethodDelete, "/api/user/keys/"+keys[0].ID, "", "").Code)
+	assert.Equal(t, http.StatusNotFound, send(owner, http.MethodDelete, "/api/user/keys/"+keys[0].ID, "", "").Code)
+}
+
+func TestURLHandlers_Scopes(t *testing.T) {
+	ctx := context.Background()
+	services, _, err := setupURL(ctx)
+	require.NoError(t, err)
+	handler := NewURLHandlers(services.URLService, services.AnalyticsService, nil)
+
+	router := chi.NewRouter()
+	router.Post("/api/shorten", handler.ShortenJSON)
+	router.Get("/api/user/urls", handler.GetUserURLs)
+	router.Delete("/api/user/urls", handler.DeleteUserURLs)
+	router.Get("/api/user/urls/{id}/stats", handler.GetURLStats)
+	requests := map[string]func() *http.Request{
+		model.ScopeShorten: func() *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"http://example.com/scopes"}`))
+			req.Header.Set("Content-Type", "application/json")
+			return req
+		},
+		model.ScopeRead: func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/api/user/urls/unknown1/stats", nil)
+		},
+		model.ScopeDelete: func() *http.Request {
+			return httptest.NewRequest(http.MethodDelete, "/api/user/urls", strings.NewReader(`["unknown1"]`))
+		},
+	}
+
+	for scope, newRequest := range requests {
+		t.Run(scope, func(t *testing.T) {
+			for _, granted := range model.Scopes {
+				identity := auth.Identity{UserID: "scopes-user", APIKey: &model.APIKey{Scopes: []string{granted}}}
+				req := newRequest()
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req.WithContext(auth.WithIdentity(req.Context(), identity)))
+				if granted == scope {
+					assert.NotEqual(t, http.StatusForbidden, w.Code, "Expected a key with the %s scope to be allowed", granted)
+				} else {
+					assert.Equal(t, http.StatusForbidden, w.Code, "Expected a key with the %s scope to be forbidden", granted)
+				}
+			}
+		})
+	}
+}
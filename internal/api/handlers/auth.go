@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/GlebRadaev/shlink/internal/middleware/auth"
+)
+
+// authorize returns the user making the request if the request may perform the operations of
+// the scope. Otherwise it responds with 401 Unauthorized without an identity, or 403 Forbidden
+// for an API key lacking the scope.
+func authorize(w http.ResponseWriter, r *http.Request, scope string) (auth.Identity, bool) {
+	identity, ok := auth.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return auth.Identity{}, false
+	}
+	if !identity.HasScope(scope) {
+		http.Error(w, "API key lacks the "+scope+" scope", http.StatusForbidden)
+		return auth.Identity{}, false
+	}
+	return identity, true
+}
+
+// authorizeCookie returns the user making the request if it was authenticated by the cookie.
+// Otherwise it responds with 401 Unauthorized, or 403 Forbidden for an API key.
+func authorizeCookie(w http.ResponseWriter, r *http.Request) (auth.Identity, bool) {
+	identity, ok := auth.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return auth.Identity{}, false
+	}
+	if identity.APIKey != nil {
+		http.Error(w, "API keys cannot be used for this operation", http.StatusForbidden)
+		return auth.Identity{}, false
+	}
+	return identity, true
+}
+
+// authorizeAdmin returns the administrator making the request. Otherwise it responds with
+// 401 Unauthorized without an identity, or 403 Forbidden for other users.
+func authorizeAdmin(w http.ResponseWriter, r *http.Request) (auth.Identity, bool) {
+	identity, ok := auth.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return auth.Identity{}, false
+	}
+	if !identity.Admin {
+		http.Error(w, "Administrators only", http.StatusForbidden)
+		return auth.Identity{}, false
+	}
+	return identity, true
+}
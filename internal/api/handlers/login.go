@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/dto"
+	"github.com/GlebRadaev/shlink/internal/oidc"
+	"github.com/GlebRadaev/shlink/internal/service"
+	"github.com/GlebRadaev/shlink/internal/service/account"
+	"github.com/GlebRadaev/shlink/internal/utils"
+)
+
+// Cookies used by the sign-in flow.
+const (
+	loginCookieName = "oidc_login"      // State, nonce and PKCE verifier of a sign-in in progress.
+	loginCookiePath = oidc.CallbackPath // The login cookie is only sent to the callback.
+	loginCookieTTL  = 10 * time.Minute  // How long the user has to sign in at the provider.
+	claimCookieName = "claim_token"     // Token of the anonymous user whose links can be claimed.
+	claimCookiePath = "/api/user/claim" // The claim cookie is only sent to the claim route.
+	claimCookieTTL  = 24 * time.Hour    // How long the anonymous links can be claimed after signing in.
+)
+
+// LoginHandlers defines the handlers signing users in with their OpenID Connect accounts.
+type LoginHandlers struct {
+	// provider is the OpenID Connect provider users sign in with.
+	provider *oidc.Provider
+	// accountService is the service linking the accounts to user IDs.
+	accountService *service.AccountService
+	// secureCookies sets the Secure attribute of the cookies of the sign-in flow.
+	secureCookies bool
+}
+
+// NewLoginHandlers creates a new instance of LoginHandlers.
+func NewLoginHandlers(provider *oidc.Provider, accountService *service.AccountService, secureCookies bool) *LoginHandlers {
+	return &LoginHandlers{provider: provider, accountService: accountService, secureCookies: secureCookies}
+}
+
+// Login handles the request to sign in by redirecting the user to the provider. The optional
+// return_to query parameter is the local path the user is sent back to after signing in.
+func (h *LoginHandlers) Login(w http.ResponseWriter, r *http.Request) {
+	state, err := oidc.RandomString()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	authURL, err := h.provider.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	login := url.Values{
+		"state":     {state},
+		"nonce":     {nonce},
+		"verifier":  {verifier},
+		"return_to": {localPath(r.URL.Query().Get("return_to"))},
+	}
+	http.SetCookie(w, h.cookie(loginCookieName, base64.RawURLEncoding.EncodeToString([]byte(login.Encode())),
+		loginCookiePath, loginCookieTTL))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback handles the redirect back from the provider. It signs the user in with the account,
+// sets the user cookie and, if the browser held the cookie of an anonymous user, lets the user
+// claim the links of that user.
+func (h *LoginHandlers) Callback(w http.ResponseWriter, r *http.Request) {
+	login, ok := h.loginInProgress(r)
+	http.SetCookie(w, h.cookie(loginCookieName, "", loginCookiePath, -1))
+	if !ok {
+		http.Error(w, "No sign-in in progress", http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query()
+	if query.Get("state") != login.Get("state") {
+		http.Error(w, "Invalid state", http.StatusBadRequest)
+		return
+	}
+	if errCode := query.Get("error"); errCode != "" {
+		http.Error(w, "Sign-in failed: "+errCode, http.StatusUnauthorized)
+		return
+	}
+	claims, err := h.provider.Exchange(r.Context(), query.Get("code"), login.Get("verifier"), login.Get("nonce"))
+	if err != nil {
+		if errors.Is(err, oidc.ErrInvalidIDToken) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	userID, err := h.accountService.SignIn(r.Context(), h.provider.Issuer(), claims.Subject, claims.Email)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if previous, ok := utils.GetUserIDFromCookie(r); ok && previous != userID {
+		signedIn, err := h.accountService.IsSignedIn(r.Context(), previous)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !signedIn {
+			cookie, _ := r.Cookie(utils.NameCookieUserID)
+			http.SetCookie(w, h.cookie(claimCookieName, cookie.Value, claimCookiePath, claimCookieTTL))
+		}
+	}
+	if err := utils.SetCookieForUserID(w, userID); err != nil {
+		http.Error(w, "Failed to set user ID", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, login.Get("return_to"), http.StatusFound)
+}
+
+// Logout signs the user out by removing the user cookie, and at the provider if it supports it.
+func (h *LoginHandlers) Logout(w http.ResponseWriter, r *http.Request) {
+	utils.ClearUserIDCookie(w)
+	http.SetCookie(w, h.cookie(claimCookieName, "", claimCookiePath, -1))
+	if logoutURL, ok := h.provider.LogoutURL(r.Context()); ok {
+		http.Redirect(w, r, logoutURL, http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// ClaimURLs handles the request to move the links the user created anonymously in this browser,
+// before signing in, to the signed-in user.
+func (h *LoginHandlers) ClaimURLs(w http.ResponseWriter, r *http.Request) {
+	identity, ok := authorizeCookie(w, r)
+	if !ok {
+		return
+	}
+	cookie, err := r.Cookie(claimCookieName)
+	if err != nil {
+		http.Error(w, "No anonymous links to claim", http.StatusBadRequest)
+		return
+	}
+	claims := &utils.Claims{}
+	if err := utils.ParseJWT(cookie.Value, claims); err != nil {
+		http.SetCookie(w, h.cookie(claimCookieName, "", claimCookiePath, -1))
+		http.Error(w, "No anonymous links to claim", http.StatusBadRequest)
+		return
+	}
+
+	count, err := h.accountService.ClaimURLs(r.Context(), claims.UserID, identity.UserID)
+	if err != nil {
+		if errors.Is(err, account.ErrNotSignedIn) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, account.ErrNotAnonymous) {
+			http.SetCookie(w, h.cookie(claimCookieName, "", claimCookiePath, -1))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, h.cookie(claimCookieName, "", claimCookiePath, -1))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(dto.ClaimURLsResponseDTO{Claimed: count}); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// loginInProgress returns the state, nonce, PKCE verifier and return path of the sign-in in progress.
+func (h *LoginHandlers) loginInProgress(r *http.Request) (url.Values, bool) {
+	cookie, err := r.Cookie(loginCookieName)
+	if err != nil {
+		return nil, false
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return nil, false
+	}
+	login, err := url.ParseQuery(string(decoded))
+	if err != nil || login.Get("state") == "" {
+		return nil, false
+	}
+	return login, true
+}
+
+// cookie returns a cookie of the sign-in flow, removing it from the client if maxAge is negative.
+// The cookies are Lax, so they are sent when the provider redirects back.
+func (h *LoginHandlers) cookie(name, value, path string, maxAge time.Duration) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		MaxAge:   int(maxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   h.secureCookies,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if maxAge < 0 {
+		cookie.MaxAge = -1
+	}
+	return cookie
+}
+
+// localPath returns the path if it is a path on this host, so sign-in cannot redirect elsewhere,
+// and the root path otherwise.
+func localPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/GlebRadaev/shlink/internal/dto"
+	"github.com/GlebRadaev/shlink/internal/middleware/auth"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/oidc"
+	"github.com/GlebRadaev/shlink/internal/oidc/oidctest"
+	"github.com/GlebRadaev/shlink/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginHandlers_Login(t *testing.T) {
+	idp := oidctest.NewServer(t, "shlink", "")
+	provider := oidc.NewProvider(oidc.Config{IssuerURL: idp.URL, ClientID: "shlink", RedirectURL: "http://shlink.test/auth/callback"}, idp.Client())
+	handler := NewLoginHandlers(provider, nil, true)
+
+	w := httptest.NewRecorder()
+	handler.Login(w, httptest.NewRequest(http.MethodGet, "/auth/login?return_to=/api/user/urls", nil))
+	require.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, idp.URL+"/authorize", location.Scheme+"://"+location.Host+location.Path)
+
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, loginCookieName, cookies[0].Name)
+	assert.Equal(t, oidc.CallbackPath, cookies[0].Path)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.True(t, cookies[0].Secure)
+	req := httptest.NewRequest(http.MethodGet, oidc.CallbackPath, nil)
+	req.AddCookie(cookies[0])
+	login, ok := handler.loginInProgress(req)
+	require.True(t, ok)
+	assert.Equal(t, location.Query().Get("state"), login.Get("state"))
+	assert.Equal(t, location.Query().Get("nonce"), login.Get("nonce"))
+	assert.Equal(t, oidc.CodeChallenge(login.Get("verifier")), location.Query().Get("code_challenge"))
+	assert.Equal(t, "/api/user/urls", login.Get("return_to"))
+
+	unreachable := NewLoginHandlers(oidc.NewProvider(oidc.Config{IssuerURL: idp.URL + "/missing", ClientID: "shlink"}, idp.Client()), nil, false)
+	w = httptest.NewRecorder()
+	unreachable.Login(w, httptest.NewRequest(http.MethodGet, "/auth/login", nil))
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+}
+
+// startLogin starts a sign-in and returns its cookie and the values it holds.
+func startLogin(t *testing.T, handler *LoginHandlers, returnTo string) (*http.Cookie, url.Values) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler.Login(w, httptest.NewRequest(http.MethodGet, "/auth/login?return_to="+url.QueryEscape(returnTo), nil))
+	require.Equal(t, http.StatusFound, w.Code)
+	cookie := w.Result().Cookies()[0]
+	req := httptest.NewRequest(http.MethodGet, oidc.CallbackPath, nil)
+	req.AddCookie(cookie)
+	login, ok := handler.loginInProgress(req)
+	require.True(t, ok)
+	return cookie, login
+}
+
+// authorizeAt sends the user to the provider and returns the code it redirects back with.
+func authorizeAt(t *testing.T, idp *oidctest.Server, provider *oidc.Provider, login url.Values) string {
+	t.Helper()
+	authURL, err := provider.AuthCodeURL(context.Background(), login.Get("state"), login.Get("nonce"), login.Get("verifier"))
+	require.NoError(t, err)
+	client := idp.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return location.Query().Get("code")
+}
+
+func TestLoginHandlers_Callback(t *testing.T) {
+	ctx := context.Background()
+	services, _, err := setupURL(ctx)
+	require.NoError(t, err)
+	idp := oidctest.NewServer(t, "shlink", "")
+	idp.SignInAs("callback-user", "callback@example.com")
+	provider := oidc.NewProvider(oidc.Config{IssuerURL: idp.URL, ClientID: "shlink", RedirectURL: "http://shlink.test/auth/callback"}, idp.Client())
+	handler := NewLoginHandlers(provider, services.AccountService, false)
+	callback := func(query url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, oidc.CallbackPath+"?"+query.Encode(), nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		handler.Callback(w, req)
+		return w
+	}
+
+	cookie, login := startLogin(t, handler, "/")
+	assert.Equal(t, http.StatusBadRequest, callback(url.Values{"state": {login.Get("state")}, "code": {"c"}}).Code,
+		"Expected a sign-in in progress to be required")
+	assert.Equal(t, http.StatusBadRequest, callback(url.Values{"state": {"other"}, "code": {"c"}}, cookie).Code,
+		"Expected the state to be checked")
+	assert.Equal(t, http.StatusUnauthorized, callback(url.Values{"state": {login.Get("state")}, "error": {"access_denied"}}, cookie).Code)
+	assert.Equal(t, http.StatusBadGateway, callback(url.Values{"state": {login.Get("state")}, "code": {"unknown"}}, cookie).Code)
+
+	anonymousToken, err := utils.GenerateJWT("callback-anonymous")
+	require.NoError(t, err)
+	cookie, login = startLogin(t, handler, "/api/user/urls")
+	code := authorizeAt(t, idp, provider, login)
+	w := callback(url.Values{"state": {login.Get("state")}, "code": {code}}, cookie,
+		&http.Cookie{Name: utils.NameCookieUserID, Value: anonymousToken})
+	require.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/api/user/urls", w.Header().Get("Location"))
+	set := make(map[string]*http.Cookie)
+	for _, c := range w.Result().Cookies() {
+		set[c.Name] = c
+	}
+	require.Contains(t, set, loginCookieName)
+	assert.Equal(t, -1, set[loginCookieName].MaxAge, "Expected the login cookie to be removed")
+	require.Contains(t, set, claimCookieName)
+	assert.Equal(t, anonymousToken, set[claimCookieName].Value, "Expected the anonymous links to be claimable")
+	require.Contains(t, set, utils.NameCookieUserID)
+	claims := &utils.Claims{}
+	require.NoError(t, utils.ParseJWT(set[utils.NameCookieUserID].Value, claims))
+	signedIn, err := services.AccountService.IsSignedIn(ctx, claims.UserID)
+	require.NoError(t, err)
+	assert.True(t, signedIn)
+
+	// Signing in again from a browser of the same user offers nothing to claim.
+	cookie, login = startLogin(t, handler, "/")
+	code = authorizeAt(t, idp, provider, login)
+	w = callback(url.Values{"state": {login.Get("state")}, "code": {code}}, cookie, set[utils.NameCookieUserID])
+	require.Equal(t, http.StatusFound, w.Code)
+	for _, c := range w.Result().Cookies() {
+		assert.NotEqual(t, claimCookieName, c.Name)
+	}
+}
+
+func TestLoginHandlers_ClaimURLs(t *testing.T) {
+	ctx := context.Background()
+	services, _, err := setupURL(ctx)
+	require.NoError(t, err)
+	handler := NewLoginHandlers(nil, services.AccountService, false)
+	userID, err := services.AccountService.SignIn(ctx, "https://idp.example.com", "claim-user", "")
+	require.NoError(t, err)
+	otherUserID, err := services.AccountService.SignIn(ctx, "https://idp.example.com", "claim-other", "")
+	require.NoError(t, err)
+	_, err = services.URLService.Shorten(ctx, "claim-anonymous", dto.ShortenJSONRequestDTO{URL: "http://example.com/claim"})
+	require.NoError(t, err)
+	anonymousToken, err := utils.GenerateJWT("claim-anonymous")
+	require.NoError(t, err)
+	signedInToken, err := utils.GenerateJWT(otherUserID)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		identity   *auth.Identity
+		claimToken string
+		wantStatus int
+	}{
+		{name: "unauthorized", claimToken: anonymousToken, wantStatus: http.StatusUnauthorized},
+		{name: "API key", identity: &auth.Identity{UserID: userID, APIKey: &model.APIKey{Scopes: model.Scopes}},
+			claimToken: anonymousToken, wantStatus: http.StatusForbidden},
+		{name: "no claim cookie", identity: &auth.Identity{UserID: userID}, wantStatus: http.StatusBadRequest},
+		{name: "invalid claim cookie", identity: &auth.Identity{UserID: userID}, claimToken: "invalid", wantStatus: http.StatusBadRequest},
+		{name: "not signed in", identity: &auth.Identity{UserID: "claim-anonymous2"}, claimToken: anonymousToken, wantStatus: http.StatusForbidden},
+		{name: "not anonymous", identity: &auth.Identity{UserID: userID}, claimToken: signedInToken, wantStatus: http.StatusBadRequest},
+		{name: "valid request", identity: &auth.Identity{UserID: userID}, claimToken: anonymousToken, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/user/claim", nil)
+			if tt.identity != nil {
+				req = req.WithContext(auth.WithIdentity(req.Context(), *tt.identity))
+			}
+			if tt.claimToken != "" {
+				req.AddCookie(&http.Cookie{Name: claimCookieName, Value: tt.claimToken})
+			}
+			w := httptest.NewRecorder()
+			handler.ClaimURLs(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+
+	page, err := services.URLService.GetUserURLs(ctx, userID, dto.GetUserURLsRequestDTO{})
+	require.NoError(t, err)
+	assert.Len(t, page.URLs, 1, "Expected the anonymous link to be claimed")
+}
+
+func TestLocalPath(t *testing.T) {
+	tests := map[string]string{
+		"":                         "/",
+		"/api/user/urls?page=2":    "/api/user/urls?page=2",
+		"//evil.example.com":       "/",
+		"/\\evil.example.com":      "/",
+		"https://evil.example.com": "/",
+		"relative":                 "/",
+	}
+	for path, want := range tests {
+		assert.Equal(t, want, localPath(path), "localPath(%q)", path)
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// PasswordHeader is the request header API clients use to pass the password of a protected link.
+const PasswordHeader = "X-Link-Password"
+
+// passwordForm is the page shown in browsers before redirecting to a password protected link.
+var passwordForm = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Password required</title>
+</head>
+<body>
+<form method="post">
+<p>This link is password protected.</p>
+{{if .Error}}<p>{{.Error}}</p>{{end}}
+<input type="password" name="password" autofocus required>
+<button type="submit">Open</button>
+</form>
+</body>
+</html>
+`))
+
+// passwordFromRequest returns the link password from the header, the query string or a submitted form.
+func passwordFromRequest(r *http.Request) string {
+	if password := r.Header.Get(PasswordHeader); password != "" {
+		return password
+	}
+	if password := r.URL.Query().Get("password"); password != "" {
+		return password
+	}
+	if r.Method == http.MethodPost {
+		return r.PostFormValue("password")
+	}
+	return ""
+}
+
+// wantsHTML reports whether the request comes from a browser that can show the password form.
+func wantsHTML(r *http.Request) bool {
+	return r.Method == http.MethodPost || strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// writePasswordForm renders the password form with the given status and optional error message.
+func writePasswordForm(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	data := struct {
+		Error string
+	}{Error: message}
+	if err := passwordForm.Execute(w, data); err != nil {
+		log.Printf("Failed to render password form: %v", err)
+	}
+}
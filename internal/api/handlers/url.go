@@ -4,6 +4,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -11,43 +12,66 @@ import (
 	"strings"
 
 	"github.com/GlebRadaev/shlink/internal/dto"
+	"github.com/GlebRadaev/shlink/internal/metrics"
+	"github.com/GlebRadaev/shlink/internal/model"
 	"github.com/GlebRadaev/shlink/internal/service"
+	"github.com/GlebRadaev/shlink/internal/service/analytics"
+	"github.com/GlebRadaev/shlink/internal/service/url"
 	"github.com/GlebRadaev/shlink/internal/utils"
 
 	"github.com/go-chi/chi/v5"
 )
 
-// URLHandlers defines the handlers for URL shortening.
+// NextCursorHeader is the response header carrying the cursor of the next page of a user's URLs.
+const NextCursorHeader = "X-Next-Cursor"
+
+// URLHandlers defines the handlers for URL shortening. The handlers take the user from the
+// identity stored in the request context by the auth middleware. Requests authenticated by an
+// API key need its shorten scope to shorten URLs, read to list them and read their statistics,
+// and delete to delete or change them.
 type URLHandlers struct {
 	// urlService is the service that manages URL shortening and retrieval operations.
 	urlService *service.URLService
+
+	// analyticsService is the service that records redirects and builds link statistics.
+	analyticsService *service.AnalyticsService
+
+	// metrics counts the outcomes of redirects and shortened URLs; nil disables them.
+	metrics *metrics.Metrics
 }
 
 // NewURLHandlers creates a new instance of URLHandlers.
-func NewURLHandlers(urlService *service.URLService) *URLHandlers {
-	return &URLHandlers{urlService: urlService}
+func NewURLHandlers(urlService *service.URLService, analyticsService *service.AnalyticsService, metrics *metrics.Metrics) *URLHandlers {
+	return &URLHandlers{urlService: urlService, analyticsService: analyticsService, metrics: metrics}
 }
 
 // Shorten handles the request to shorten a URL.
 func (h *URLHandlers) Shorten(w http.ResponseWriter, r *http.Request) {
-	userID, _ := utils.GetOrSetUserIDFromCookie(w, r)
+	identity, ok := authorize(w, r, model.ScopeShorten)
+	if !ok {
+		return
+	}
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
+		h.metrics.ObserveShorten(metrics.ShortenInvalid)
 		http.Error(w, "Failed to read request body", http.StatusBadRequest)
 		return
 	}
 	defer r.Body.Close()
-	shortID, err := h.urlService.Shorten(r.Context(), userID, string(body))
+	shortID, err := h.urlService.Shorten(r.Context(), identity.UserID, dto.ShortenJSONRequestDTO{URL: string(body)})
 	if err != nil {
 		if strings.Contains(err.Error(), "conflict") {
+			h.metrics.ObserveShorten(metrics.ShortenConflict)
 			w.Header().Set("Content-Type", "text/plain")
 			w.WriteHeader(http.StatusConflict)
 			_, _ = w.Write([]byte(shortID))
 			return
 		}
+		h.metrics.ObserveShorten(metrics.ShortenInvalid)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	h.metrics.ObserveShorten(metrics.ShortenSuccess)
 	w.Header().Set("Content-Type", "text/plain")
 	w.WriteHeader(http.StatusCreated)
 	_, err = w.Write([]byte(shortID))
@@ -59,51 +83,141 @@ func (h *URLHandlers) Shorten(w http.ResponseWriter, r *http.Request) {
 }
 
 // Redirect handles the request to redirect to the original URL.
+// Password protected URLs accept the password in the X-Link-Password header, the password
+// query parameter or a form submitted with POST; browsers are shown the form otherwise.
 func (h *URLHandlers) Redirect(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	originalURL, err := h.urlService.GetOriginal(r.Context(), id)
+	originalURL, err := h.urlService.GetOriginalWithPassword(r.Context(), id, passwordFromRequest(r))
 	log.Printf("Redirecting to: %s", originalURL)
 	if err != nil {
-		if err.Error() == "URL is deleted" {
+		h.metrics.ObserveRedirect(redirectResult(err))
+		switch {
+		case errors.Is(err, url.ErrURLDeleted) || errors.Is(err, url.ErrURLDisabled) || errors.Is(err, url.ErrURLExpired):
 			http.Error(w, err.Error(), http.StatusGone)
-		} else {
+		case errors.Is(err, url.ErrPasswordRequired) && wantsHTML(r):
+			writePasswordForm(w, http.StatusUnauthorized, "")
+		case errors.Is(err, url.ErrWrongPassword) && wantsHTML(r):
+			writePasswordForm(w, http.StatusUnauthorized, "Wrong password, try again.")
+		case errors.Is(err, url.ErrPasswordRequired) || errors.Is(err, url.ErrWrongPassword):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, url.ErrTooManyAttempts):
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+		default:
 			http.Error(w, err.Error(), http.StatusBadRequest)
 		}
 		return
 	}
+	h.metrics.ObserveRedirect(metrics.RedirectFound)
+	h.analyticsService.RecordClick(id, r.Referer(), r.UserAgent(), utils.ClientIP(r), utils.ClientCountry(r))
 	w.Header().Set("Location", originalURL)
+	if r.Method == http.MethodPost {
+		w.WriteHeader(http.StatusSeeOther)
+		return
+	}
 	w.WriteHeader(http.StatusTemporaryRedirect)
 }
 
+// redirectResult returns the result label of a redirect that failed with err.
+func redirectResult(err error) string {
+	switch {
+	case errors.Is(err, url.ErrURLDeleted) || errors.Is(err, url.ErrURLDisabled) || errors.Is(err, url.ErrURLExpired):
+		return metrics.RedirectGone
+	case errors.Is(err, url.ErrPasswordRequired) || errors.Is(err, url.ErrWrongPassword):
+		return metrics.RedirectUnauthorized
+	case errors.Is(err, url.ErrTooManyAttempts):
+		return metrics.RedirectThrottled
+	default:
+		return metrics.RedirectInvalid
+	}
+}
+
+// QRCode handles the request to render the short URL as a QR code image.
+// The format, size, ecc and margin query parameters control the image; responses carry
+// an ETag so clients can revalidate cached images with If-None-Match.
+func (h *URLHandlers) QRCode(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	opts, err := utils.ParseQROptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	shortURL, err := h.urlService.GetShortURL(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, url.ErrURLNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, url.ErrURLDeleted) || errors.Is(err, url.ErrURLDisabled) || errors.Is(err, url.ErrURLExpired):
+			http.Error(w, err.Error(), http.StatusGone)
+		case errors.Is(err, url.ErrInvalidID):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	etag := opts.ETag(shortURL)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if match := r.Header.Get("If-None-Match"); match != "" && (match == "*" || strings.Contains(match, etag)) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	image, err := utils.EncodeQR(shortURL, opts)
+	if err != nil {
+		http.Error(w, "Failed to encode QR code", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", opts.ContentType())
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(image); err != nil {
+		log.Printf("Failed to write QR code: %v", err)
+	}
+}
+
 // ShortenJSON handles the request to shorten a single URL in JSON format.
 func (h *URLHandlers) ShortenJSON(w http.ResponseWriter, r *http.Request) {
-	userID, _ := utils.GetOrSetUserIDFromCookie(w, r)
+	identity, ok := authorize(w, r, model.ScopeShorten)
+	if !ok {
+		return
+	}
 	if err := utils.ValidateContentType(w, r, "application/json"); err != nil {
+		h.metrics.ObserveShorten(metrics.ShortenInvalid)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	var data dto.ShortenJSONRequestDTO
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.metrics.ObserveShorten(metrics.ShortenInvalid)
 		http.Error(w, "cannot decode request", http.StatusBadRequest)
 		return
 	}
 	if data.URL == "" {
+		h.metrics.ObserveShorten(metrics.ShortenInvalid)
 		http.Error(w, "url is required", http.StatusBadRequest)
 		return
 	}
 
-	shortID, err := h.urlService.Shorten(r.Context(), userID, data.URL)
+	shortID, err := h.urlService.Shorten(r.Context(), identity.UserID, data)
 	if err != nil {
+		if errors.Is(err, url.ErrAliasTaken) {
+			h.metrics.ObserveShorten(metrics.ShortenConflict)
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		if strings.Contains(err.Error(), "conflict") {
+			h.metrics.ObserveShorten(metrics.ShortenConflict)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusConflict)
 			_ = json.NewEncoder(w).Encode(dto.ShortenJSONResponseDTO{Result: shortID})
 			return
 		}
+		h.metrics.ObserveShorten(metrics.ShortenInvalid)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	h.metrics.ObserveShorten(metrics.ShortenSuccess)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(dto.ShortenJSONResponseDTO{Result: shortID}); err != nil {
@@ -114,22 +228,29 @@ func (h *URLHandlers) ShortenJSON(w http.ResponseWriter, r *http.Request) {
 
 // ShortenJSONBatch handles the request to shorten multiple URLs provided in a batch JSON format.
 func (h *URLHandlers) ShortenJSONBatch(w http.ResponseWriter, r *http.Request) {
-	userID, _ := utils.GetOrSetUserIDFromCookie(w, r)
+	identity, ok := authorize(w, r, model.ScopeShorten)
+	if !ok {
+		return
+	}
 	if err := utils.ValidateContentType(w, r, "application/json"); err != nil {
+		h.metrics.ObserveShorten(metrics.ShortenInvalid)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	var data dto.BatchShortenRequestDTO
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.metrics.ObserveShorten(metrics.ShortenInvalid)
 		http.Error(w, "cannot decode request", http.StatusBadRequest)
 		return
 	}
 
-	shortenResults, err := h.urlService.ShortenList(r.Context(), userID, data)
+	shortenResults, err := h.urlService.ShortenList(r.Context(), identity.UserID, data)
 	if err != nil {
+		h.metrics.ObserveShorten(metrics.ShortenInvalid)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	h.metrics.ObserveShorten(metrics.ShortenSuccess)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(shortenResults); err != nil {
@@ -138,19 +259,33 @@ func (h *URLHandlers) ShortenJSONBatch(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// GetUserURLs retrieves the list of URLs associated with the authenticated user.
+// GetUserURLs retrieves a page of the URLs associated with the authenticated user.
+// The limit, cursor, created_from, created_to, status, search and sort query parameters
+// select the page; the cursor of the next page is returned in the X-Next-Cursor header.
 func (h *URLHandlers) GetUserURLs(w http.ResponseWriter, r *http.Request) {
-	userID, err := utils.GetOrSetUserIDFromCookie(w, r)
-	if err != nil {
-		http.Error(w, "Failed to get or set user ID", http.StatusInternalServerError)
+	identity, ok := authorize(w, r, model.ScopeRead)
+	if !ok {
 		return
 	}
 
-	urls, err := h.urlService.GetUserURLs(r.Context(), userID)
+	query := r.URL.Query()
+	page, err := h.urlService.GetUserURLs(r.Context(), identity.UserID, dto.GetUserURLsRequestDTO{
+		Limit:       query.Get("limit"),
+		Cursor:      query.Get("cursor"),
+		CreatedFrom: query.Get("created_from"),
+		CreatedTo:   query.Get("created_to"),
+		Status:      query.Get("status"),
+		Search:      query.Get("search"),
+		Sort:        query.Get("sort"),
+	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if page.NextCursor != "" {
+		w.Header().Set(NextCursorHeader, page.NextCursor)
+	}
+	urls := page.URLs
 	if len(urls) == 0 {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNoContent)
@@ -163,11 +298,54 @@ func (h *URLHandlers) GetUserURLs(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// DeleteUserURLs deletes a list of URLs associated with the authenticated user.
+// UpdateUserURL changes the destination of a URL owned by the authenticated user.
+func (h *URLHandlers) UpdateUserURL(w http.ResponseWriter, r *http.Request) {
+	identity, ok := authorize(w, r, model.ScopeDelete)
+	if !ok {
+		return
+	}
+	if err := utils.ValidateContentType(w, r, "application/json"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var data dto.UpdateURLRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, "cannot decode request", http.StatusBadRequest)
+		return
+	}
+	if data.URL == "" {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	result, err := h.urlService.UpdateOriginalURL(r.Context(), identity.UserID, id, data.URL)
+	if err != nil {
+		switch {
+		case errors.Is(err, url.ErrURLNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, url.ErrURLDeleted):
+			http.Error(w, err.Error(), http.StatusGone)
+		case errors.Is(err, url.ErrTargetTaken):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// DeleteUserURLs deletes a list of URLs associated with the authenticated user. The URLs are
+// deleted in the background: the response gives the ID of the task in its body and the URL of
+// its state in the Location header. A full task queue is answered with 503 Service Unavailable.
 func (h *URLHandlers) DeleteUserURLs(w http.ResponseWriter, r *http.Request) {
-	userID, ok := utils.GetUserIDFromCookie(r)
+	identity, ok := authorize(w, r, model.ScopeDelete)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 	body, err := io.ReadAll(r.Body)
@@ -182,10 +360,66 @@ func (h *URLHandlers) DeleteUserURLs(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	err = h.urlService.DeleteUserURLs(r.Context(), userID, data)
+	taskID, err := h.urlService.DeleteUserURLs(r.Context(), identity.UserID, data)
+	if errors.Is(err, url.ErrQueueFull) {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/tasks/"+taskID)
 	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(dto.DeleteURLResponseDTO{TaskID: taskID}); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// GetTask returns the state of a task enqueued by the authenticated user, such as the deletion
+// of URLs, with the result of each of its items.
+func (h *URLHandlers) GetTask(w http.ResponseWriter, r *http.Request) {
+	identity, ok := authorize(w, r, model.ScopeRead)
+	if !ok {
+		return
+	}
+	task, err := h.urlService.GetTask(r.Context(), identity.UserID, chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, url.ErrTaskNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(task); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetURLStats returns click statistics for a URL owned by the authenticated user.
+func (h *URLHandlers) GetURLStats(w http.ResponseWriter, r *http.Request) {
+	identity, ok := authorize(w, r, model.ScopeRead)
+	if !ok {
+		return
+	}
+	id := chi.URLParam(r, "id")
+	stats, err := h.analyticsService.GetURLStats(r.Context(), identity.UserID, id)
+	if err != nil {
+		if errors.Is(err, analytics.ErrURLNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
 }
@@ -13,14 +13,20 @@ import (
 	"time"
 
 	"github.com/GlebRadaev/shlink/internal/config"
+	"github.com/GlebRadaev/shlink/internal/dto"
 	"github.com/GlebRadaev/shlink/internal/logger"
+	"github.com/GlebRadaev/shlink/internal/metrics"
+	"github.com/GlebRadaev/shlink/internal/middleware/auth"
+	"github.com/GlebRadaev/shlink/internal/model"
 	"github.com/GlebRadaev/shlink/internal/repository"
 	"github.com/GlebRadaev/shlink/internal/service"
 	"github.com/GlebRadaev/shlink/internal/service/url"
 	"github.com/GlebRadaev/shlink/internal/taskmanager"
+	"github.com/GlebRadaev/shlink/internal/utils"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type mockErrorReader struct{}
@@ -29,7 +35,17 @@ func (m *mockErrorReader) Read(p []byte) (n int, err error) {
 	return 0, fmt.Errorf("mock read error")
 }
 
-func setupURL(ctx context.Context) (*url.URLService, *config.Config, error) {
+// withAuth passes the requests through the auth middleware, identifying users by their cookie.
+func withAuth(h http.HandlerFunc) http.HandlerFunc {
+	return auth.NewAuthenticator(nil, nil, nil).Middleware(h).ServeHTTP
+}
+
+// withUser passes the requests through the auth middleware, giving requests without a cookie a new user.
+func withUser(h http.HandlerFunc) http.HandlerFunc {
+	return withAuth(auth.EnsureUser(h).ServeHTTP)
+}
+
+func setupURL(ctx context.Context) (*service.Services, *config.Config, error) {
 	if cfgTest == nil {
 		var err error
 		cfgTest, err = config.ParseAndLoadConfig()
@@ -40,8 +56,8 @@ func setupURL(ctx context.Context) (*url.URLService, *config.Config, error) {
 	log, _ := logger.NewLogger("info")
 	pool := taskmanager.NewWorkerPool(ctx, 10, 1)
 	repositories := repository.NewRepositoryFactory(ctx, cfgTest, log)
-	services := service.NewServiceFactory(ctx, cfgTest, log, pool, repositories)
-	return services.URLService, cfgTest, nil
+	services := service.NewServiceFactory(ctx, cfgTest, log, pool, repositories, nil)
+	return services, cfgTest, nil
 }
 
 func TestURLHandlers_Shorten(t *testing.T) {
@@ -99,19 +115,19 @@ func TestURLHandlers_Shorten(t *testing.T) {
 		},
 	}
 
-	urlService, cfg, err := setupURL(ctx)
+	services, cfg, err := setupURL(ctx)
 	if err != nil {
 		t.Fatalf("Failed to set up test: %v", err)
 	}
 
-	handler := NewURLHandlers(urlService)
+	handler := NewURLHandlers(services.URLService, services.AnalyticsService, nil)
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			req := httptest.NewRequest("POST", "/", tt.mockReader)
 			req.Header.Set("Content-Type", tt.args.contentType)
 			w := httptest.NewRecorder()
 			router := chi.NewRouter()
-			router.Post("/", handler.Shorten)
+			router.Post("/", withUser(handler.Shorten))
 
 			router.ServeHTTP(w, req)
 
@@ -146,7 +162,7 @@ func TestURLHandlers_Redirect(t *testing.T) {
 			name: "valid ID",
 			args: args{id: "validIDD"},
 			setup: func(service *url.URLService) string {
-				url, _ := service.Shorten(ctx, "userID", "http://example.com")
+				url, _ := service.Shorten(ctx, "userID", dto.ShortenJSONRequestDTO{URL: "http://example.com"})
 				splitURL := strings.Split(url, "/")
 				shortID := splitURL[len(splitURL)-1]
 				return shortID
@@ -161,7 +177,7 @@ func TestURLHandlers_Redirect(t *testing.T) {
 		},
 		{
 			name:       "invalid ID length",
-			args:       args{id: "short"},
+			args:       args{id: "ab"},
 			setup:      nil,
 			wantStatus: http.StatusBadRequest,
 		},
@@ -171,18 +187,33 @@ func TestURLHandlers_Redirect(t *testing.T) {
 			setup:      nil,
 			wantStatus: http.StatusBadRequest,
 		},
+		{
+			name: "click budget used up",
+			setup: func(service *url.URLService) string {
+				maxClicks := 1
+				url, _ := service.Shorten(ctx, "userID", dto.ShortenJSONRequestDTO{
+					URL:       fmt.Sprintf("http://example.com/once?test=%d", time.Now().UnixNano()),
+					MaxClicks: &maxClicks,
+				})
+				splitURL := strings.Split(url, "/")
+				shortID := splitURL[len(splitURL)-1]
+				_, _ = service.GetOriginal(ctx, shortID)
+				return shortID
+			},
+			wantStatus: http.StatusGone,
+		},
 	}
 
-	urlService, _, err := setupURL(ctx)
+	services, _, err := setupURL(ctx)
 	if err != nil {
 		t.Fatalf("Failed to set up test: %v", err)
 	}
-	handler := NewURLHandlers(urlService)
+	handler := NewURLHandlers(services.URLService, services.AnalyticsService, nil)
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			if tt.setup != nil {
-				tt.args.id = tt.setup(urlService)
+				tt.args.id = tt.setup(services.URLService)
 			}
 			req := httptest.NewRequest("GET", "/"+tt.args.id, nil)
 			w := httptest.NewRecorder()
@@ -204,6 +235,190 @@ func TestURLHandlers_Redirect(t *testing.T) {
 	}
 }
 
+func TestURLHandlers_RedirectProtected(t *testing.T) {
+	ctx := context.Background()
+	services, _, err := setupURL(ctx)
+	if err != nil {
+		t.Fatalf("Failed to set up test: %v", err)
+	}
+	handler := NewURLHandlers(services.URLService, services.AnalyticsService, nil)
+
+	target := fmt.Sprintf("http://example.com/internal?test=%d", time.Now().UnixNano())
+	shortURL, err := services.URLService.Shorten(ctx, "userID", dto.ShortenJSONRequestDTO{URL: target, Password: "s3cret"})
+	assert.NoError(t, err)
+	shortID := shortURL[strings.LastIndex(shortURL, "/")+1:]
+
+	router := chi.NewRouter()
+	router.Get("/{id}", handler.Redirect)
+	router.Post("/{id}", handler.Redirect)
+
+	tests := []struct {
+		name         string
+		request      func() *http.Request
+		wantStatus   int
+		wantLocation string
+		wantForm     bool
+	}{
+		{
+			name: "browser is shown the form",
+			request: func() *http.Request {
+				req := httptest.NewRequest("GET", "/"+shortID, nil)
+				req.Header.Set("Accept", "text/html,application/xhtml+xml")
+				return req
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantForm:   true,
+		},
+		{
+			name: "API client without password",
+			request: func() *http.Request {
+				return httptest.NewRequest("GET", "/"+shortID, nil)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "wrong password in header",
+			request: func() *http.Request {
+				req := httptest.NewRequest("GET", "/"+shortID, nil)
+				req.Header.Set(PasswordHeader, "guess")
+				return req
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "password in header",
+			request: func() *http.Request {
+				req := httptest.NewRequest("GET", "/"+shortID, nil)
+				req.Header.Set(PasswordHeader, "s3cret")
+				return req
+			},
+			wantStatus:   http.StatusTemporaryRedirect,
+			wantLocation: target,
+		},
+		{
+			name: "password in query",
+			request: func() *http.Request {
+				return httptest.NewRequest("GET", "/"+shortID+"?password=s3cret", nil)
+			},
+			wantStatus:   http.StatusTemporaryRedirect,
+			wantLocation: target,
+		},
+		{
+			name: "wrong password in form",
+			request: func() *http.Request {
+				req := httptest.NewRequest("POST", "/"+shortID, strings.NewReader("password=guess"))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantForm:   true,
+		},
+		{
+			name: "password in form",
+			request: func() *http.Request {
+				req := httptest.NewRequest("POST", "/"+shortID, strings.NewReader("password=s3cret"))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: target,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, tt.request())
+
+			res := w.Result()
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+			assert.Equal(t, tt.wantLocation, res.Header.Get("Location"))
+			body, _ := io.ReadAll(res.Body)
+			assert.Equal(t, tt.wantForm, strings.Contains(string(body), `name="password"`))
+		})
+	}
+
+	t.Run("too many wrong attempts", func(t *testing.T) {
+		for i := 0; i < cfgTest.PasswordMaxAttempts; i++ {
+			req := httptest.NewRequest("GET", "/"+shortID+"?password=guess", nil)
+			router.ServeHTTP(httptest.NewRecorder(), req)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/"+shortID+"?password=s3cret", nil))
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	})
+}
+
+func TestURLHandlers_QRCode(t *testing.T) {
+	ctx := context.Background()
+	services, _, err := setupURL(ctx)
+	if err != nil {
+		t.Fatalf("Failed to set up test: %v", err)
+	}
+	handler := NewURLHandlers(services.URLService, services.AnalyticsService, nil)
+
+	suffix := time.Now().UnixNano()
+	shortURL, err := services.URLService.Shorten(ctx, "userID", dto.ShortenJSONRequestDTO{URL: fmt.Sprintf("http://example.com/qr?test=%d", suffix)})
+	assert.NoError(t, err)
+	shortID := shortURL[strings.LastIndex(shortURL, "/")+1:]
+	maxClicks := 1
+	usedURL, err := services.URLService.Shorten(ctx, "userID", dto.ShortenJSONRequestDTO{
+		URL:       fmt.Sprintf("http://example.com/qr-once?test=%d", suffix),
+		MaxClicks: &maxClicks,
+	})
+	assert.NoError(t, err)
+	usedID := usedURL[strings.LastIndex(usedURL, "/")+1:]
+	_, err = services.URLService.GetOriginal(ctx, usedID)
+	assert.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.Get("/{id}/qr", handler.QRCode)
+	etag := utils.QROptions{Format: utils.QRFormatPNG, Size: utils.DefaultQRSize, Level: "M", Margin: utils.DefaultQRMargin}.ETag(shortURL)
+
+	tests := []struct {
+		name            string
+		target          string
+		ifNoneMatch     string
+		wantStatus      int
+		wantContentType string
+		wantETag        string
+	}{
+		{name: "PNG by default", target: "/" + shortID + "/qr", wantStatus: http.StatusOK, wantContentType: "image/png", wantETag: etag},
+		{name: "SVG with options", target: "/" + shortID + "/qr?format=svg&size=512&ecc=H&margin=2", wantStatus: http.StatusOK, wantContentType: "image/svg+xml"},
+		{name: "not modified", target: "/" + shortID + "/qr", ifNoneMatch: etag, wantStatus: http.StatusNotModified},
+		{name: "stale ETag", target: "/" + shortID + "/qr", ifNoneMatch: `"stale"`, wantStatus: http.StatusOK, wantContentType: "image/png"},
+		{name: "invalid options", target: "/" + shortID + "/qr?size=1", wantStatus: http.StatusBadRequest},
+		{name: "invalid ID", target: "/ab/qr", wantStatus: http.StatusBadRequest},
+		{name: "unknown URL", target: "/unknown1/qr", wantStatus: http.StatusNotFound},
+		{name: "expired URL", target: "/" + usedID + "/qr", wantStatus: http.StatusGone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.target, nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			res := w.Result()
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+			if tt.wantContentType != "" {
+				assert.Equal(t, tt.wantContentType, res.Header.Get("Content-Type"))
+				assert.NotEmpty(t, res.Header.Get("ETag"))
+				body, _ := io.ReadAll(res.Body)
+				assert.NotEmpty(t, body)
+			}
+			if tt.wantETag != "" {
+				assert.Equal(t, tt.wantETag, res.Header.Get("ETag"))
+			}
+		})
+	}
+}
+
 func TestURLHandlers_ShortenJSON(t *testing.T) {
 	ctx := context.Background()
 	type args struct {
@@ -261,14 +476,41 @@ func TestURLHandlers_ShortenJSON(t *testing.T) {
 			wantStatus: http.StatusBadRequest,
 			wantBody:   "url is required\n",
 		},
+		{
+			name: "valid alias",
+			args: args{
+				contentType: "application/json",
+				body:        `{"url": "http://example.com/spring", "alias": "spring-sale"}`,
+			},
+			wantStatus: http.StatusCreated,
+			wantBody:   `http://localhost:8080/spring-sale`,
+		},
+		{
+			name: "alias already taken",
+			args: args{
+				contentType: "application/json",
+				body:        `{"url": "http://example.com/autumn", "alias": "spring-sale"}`,
+			},
+			wantStatus: http.StatusConflict,
+			wantBody:   "conflict: alias already taken\n",
+		},
+		{
+			name: "reserved alias",
+			args: args{
+				contentType: "application/json",
+				body:        `{"url": "http://example.com/api", "alias": "api"}`,
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "alias is reserved\n",
+		},
 	}
 
-	urlService, _, err := setupURL(ctx)
+	services, _, err := setupURL(ctx)
 	if err != nil {
 		t.Fatalf("Failed to set up test: %v", err)
 	}
 
-	handler := NewURLHandlers(urlService)
+	handler := NewURLHandlers(services.URLService, services.AnalyticsService, nil)
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -277,7 +519,7 @@ func TestURLHandlers_ShortenJSON(t *testing.T) {
 			w := httptest.NewRecorder()
 
 			router := chi.NewRouter()
-			router.Post("/api/shorten", handler.ShortenJSON)
+			router.Post("/api/shorten", withUser(handler.ShortenJSON))
 			router.ServeHTTP(w, req)
 
 			res := w.Result()
@@ -362,11 +604,11 @@ func TestURLHandlers_ShortenJSONBatch(t *testing.T) {
 		},
 	}
 
-	urlService, _, err := setupURL(ctx)
+	services, _, err := setupURL(ctx)
 	if err != nil {
 		t.Fatalf("Failed to set up test: %v", err)
 	}
-	handler := NewURLHandlers(urlService)
+	handler := NewURLHandlers(services.URLService, services.AnalyticsService, nil)
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -374,7 +616,7 @@ func TestURLHandlers_ShortenJSONBatch(t *testing.T) {
 			req.Header.Set("Content-Type", tt.args.contentType)
 			w := httptest.NewRecorder()
 
-			handler.ShortenJSONBatch(w, req)
+			withUser(handler.ShortenJSONBatch)(w, req)
 
 			res := w.Result()
 			defer res.Body.Close()
@@ -389,3 +631,313 @@ func TestURLHandlers_ShortenJSONBatch(t *testing.T) {
 		})
 	}
 }
+
+func TestURLHandlers_UpdateUserURL(t *testing.T) {
+	ctx := context.Background()
+	services, cfg, err := setupURL(ctx)
+	if err != nil {
+		t.Fatalf("Failed to set up test: %v", err)
+	}
+	handler := NewURLHandlers(services.URLService, services.AnalyticsService, nil)
+
+	ownerToken, _ := utils.GenerateJWT("update-owner")
+	otherToken, _ := utils.GenerateJWT("update-other")
+	suffix := time.Now().UnixNano()
+	shortURL, err := services.URLService.Shorten(ctx, "update-owner", dto.ShortenJSONRequestDTO{
+		URL: fmt.Sprintf("http://example.com/update?test=%d", suffix),
+	})
+	assert.NoError(t, err)
+	shortID := shortURL[strings.LastIndex(shortURL, "/")+1:]
+	takenURL := fmt.Sprintf("http://example.com/taken?test=%d", suffix)
+	_, err = services.URLService.Shorten(ctx, "update-owner", dto.ShortenJSONRequestDTO{URL: takenURL})
+	assert.NoError(t, err)
+	newURL := fmt.Sprintf("http://example.com/new?test=%d", suffix)
+
+	router := chi.NewRouter()
+	router.Get("/{id}", handler.Redirect)
+	router.Patch("/api/user/urls/{id}", withAuth(handler.UpdateUserURL))
+
+	tests := []struct {
+		name        string
+		token       string
+		id          string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{name: "unauthorized", id: shortID, contentType: "application/json", body: `{"url":"` + newURL + `"}`, wantStatus: http.StatusUnauthorized},
+		{name: "wrong content type", token: ownerToken, id: shortID, contentType: "text/plain", body: `{"url":"` + newURL + `"}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid body", token: ownerToken, id: shortID, contentType: "application/json", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "empty URL", token: ownerToken, id: shortID, contentType: "application/json", body: `{"url":""}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid URL", token: ownerToken, id: shortID, contentType: "application/json", body: `{"url":"not a url"}`, wantStatus: http.StatusBadRequest},
+		{name: "URL of another user", token: otherToken, id: shortID, contentType: "application/json", body: `{"url":"` + newURL + `"}`, wantStatus: http.StatusNotFound},
+		{name: "destination already shortened", token: ownerToken, id: shortID, contentType: "application/json", body: `{"url":"` + takenURL + `"}`, wantStatus: http.StatusConflict},
+		{name: "own URL", token: ownerToken, id: shortID, contentType: "application/json", body: `{"url":"` + newURL + `"}`, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PATCH", "/api/user/urls/"+tt.id, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.token != "" {
+				req.AddCookie(&http.Cookie{Name: utils.NameCookieUserID, Value: tt.token})
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			res := w.Result()
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+
+			if tt.wantStatus == http.StatusOK {
+				var result dto.GetUserURLsResponse
+				assert.NoError(t, json.NewDecoder(res.Body).Decode(&result))
+				assert.Equal(t, fmt.Sprintf("%s/%s", cfg.BaseURL, shortID), result.ShortURL)
+				assert.Equal(t, newURL, result.OriginalURL)
+			}
+		})
+	}
+
+	redirect := httptest.NewRecorder()
+	router.ServeHTTP(redirect, httptest.NewRequest("GET", "/"+shortID, nil))
+	assert.Equal(t, http.StatusTemporaryRedirect, redirect.Code)
+	assert.Equal(t, newURL, redirect.Header().Get("Location"))
+}
+
+func TestURLHandlers_GetUserURLs(t *testing.T) {
+	ctx := context.Background()
+	services, cfg, err := setupURL(ctx)
+	if err != nil {
+		t.Fatalf("Failed to set up test: %v", err)
+	}
+	handler := NewURLHandlers(services.URLService, services.AnalyticsService, nil)
+
+	userID := fmt.Sprintf("list-user-%d", time.Now().UnixNano())
+	token, _ := utils.GenerateJWT(userID)
+	var urls []string
+	for i := 0; i < 3; i++ {
+		originalURL := fmt.Sprintf("http://example.com/list/%s/%d", userID, i)
+		_, err := services.URLService.Shorten(ctx, userID, dto.ShortenJSONRequestDTO{URL: originalURL})
+		assert.NoError(t, err)
+		urls = append(urls, originalURL)
+	}
+
+	get := func(query string) *http.Response {
+		req := httptest.NewRequest("GET", "/api/user/urls?"+query, nil)
+		req.AddCookie(&http.Cookie{Name: utils.NameCookieUserID, Value: token})
+		w := httptest.NewRecorder()
+		withUser(handler.GetUserURLs)(w, req)
+		return w.Result()
+	}
+
+	var listed []string
+	query := "sort=original_url&limit=2"
+	for page := 0; page < 2; page++ {
+		res := get(query)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		var result dto.GetUserURLsResponseDTO
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&result))
+		res.Body.Close()
+		for _, url := range result {
+			assert.True(t, strings.HasPrefix(url.ShortURL, cfg.BaseURL+"/"))
+			assert.False(t, url.CreatedAt.IsZero())
+			listed = append(listed, url.OriginalURL)
+		}
+		cursor := res.Header.Get(NextCursorHeader)
+		if page == 0 {
+			assert.NotEmpty(t, cursor)
+		} else {
+			assert.Empty(t, cursor)
+		}
+		query = "sort=original_url&limit=2&cursor=" + cursor
+	}
+	assert.Equal(t, urls, listed)
+
+	res := get("search=nothing-matches-this")
+	res.Body.Close()
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	for _, query := range []string{"limit=0", "sort=clicks", "status=archived", "created_from=yesterday", "cursor=%21"} {
+		res := get(query)
+		res.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, query)
+	}
+}
+
+func TestURLHandlers_GetURLStats(t *testing.T) {
+	ctx := context.Background()
+	services, _, err := setupURL(ctx)
+	if err != nil {
+		t.Fatalf("Failed to set up test: %v", err)
+	}
+	handler := NewURLHandlers(services.URLService, services.AnalyticsService, nil)
+
+	ownerToken, _ := utils.GenerateJWT("stats-owner")
+	otherToken, _ := utils.GenerateJWT("stats-other")
+	shortURL, err := services.URLService.Shorten(ctx, "stats-owner", dto.ShortenJSONRequestDTO{
+		URL: fmt.Sprintf("http://example.com/stats?test=%d", time.Now().UnixNano()),
+	})
+	assert.NoError(t, err)
+	shortID := shortURL[strings.LastIndex(shortURL, "/")+1:]
+
+	router := chi.NewRouter()
+	router.Get("/{id}", handler.Redirect)
+	router.Get("/api/user/urls/{id}/stats", withAuth(handler.GetURLStats))
+
+	redirect := httptest.NewRequest("GET", "/"+shortID, nil)
+	redirect.RemoteAddr = "192.0.2.1:1234"
+	router.ServeHTTP(httptest.NewRecorder(), redirect)
+	assert.NoError(t, services.AnalyticsService.Flush(ctx))
+
+	tests := []struct {
+		name       string
+		token      string
+		id         string
+		wantStatus int
+		wantClicks int64
+	}{
+		{name: "unauthorized", token: "", id: shortID, wantStatus: http.StatusUnauthorized},
+		{name: "URL of another user", token: otherToken, id: shortID, wantStatus: http.StatusNotFound},
+		{name: "unknown URL", token: ownerToken, id: "unknown1", wantStatus: http.StatusNotFound},
+		{name: "own URL", token: ownerToken, id: shortID, wantStatus: http.StatusOK, wantClicks: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/user/urls/"+tt.id+"/stats", nil)
+			if tt.token != "" {
+				req.AddCookie(&http.Cookie{Name: utils.NameCookieUserID, Value: tt.token})
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			res := w.Result()
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+
+			if tt.wantStatus == http.StatusOK {
+				var stats dto.URLStatsResponseDTO
+				assert.NoError(t, json.NewDecoder(res.Body).Decode(&stats))
+				assert.Equal(t, shortURL, stats.ShortURL)
+				assert.Equal(t, tt.wantClicks, stats.TotalClicks)
+				assert.Equal(t, int64(1), stats.UniqueVisitors)
+				assert.Len(t, stats.Daily, 1)
+			}
+		})
+	}
+}
+
+func TestURLHandlers_DeleteUserURLs(t *testing.T) {
+	ctx := context.Background()
+	services, _, err := setupURL(ctx)
+	if err != nil {
+		t.Fatalf("Failed to set up test: %v", err)
+	}
+	handler := NewURLHandlers(services.URLService, services.AnalyticsService, nil)
+
+	ownerToken, _ := utils.GenerateJWT("delete-owner")
+	otherToken, _ := utils.GenerateJWT("delete-other")
+	shortURL, err := services.URLService.Shorten(ctx, "delete-owner", dto.ShortenJSONRequestDTO{
+		URL: fmt.Sprintf("http://example.com/delete?test=%d", time.Now().UnixNano()),
+	})
+	require.NoError(t, err)
+	shortID := shortURL[strings.LastIndex(shortURL, "/")+1:]
+
+	router := chi.NewRouter()
+	router.Delete("/api/user/urls", withAuth(handler.DeleteUserURLs))
+	router.Get("/api/tasks/{id}", withAuth(handler.GetTask))
+	send := func(method, target, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if token != "" {
+			req.AddCookie(&http.Cookie{Name: utils.NameCookieUserID, Value: token})
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("DELETE", "/api/user/urls", ownerToken, `["`+shortID+`"]`)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var accepted dto.DeleteURLResponseDTO
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&accepted))
+	require.NotEmpty(t, accepted.TaskID)
+	location := w.Header().Get("Location")
+	assert.Equal(t, "/api/tasks/"+accepted.TaskID, location)
+
+	var task dto.TaskResponseDTO
+	require.Eventually(t, func() bool {
+		w := send("GET", location, ownerToken, "")
+		if w.Code != http.StatusOK {
+			return false
+		}
+		task = dto.TaskResponseDTO{}
+		return json.NewDecoder(w.Body).Decode(&task) == nil && task.State == model.TaskSucceeded
+	}, 5*time.Second, 10*time.Millisecond, "Expected the deletion to succeed")
+	assert.Equal(t, []dto.TaskItemResponseDTO{{ID: shortID, State: model.TaskItemSucceeded}}, task.Items)
+
+	tests := []struct {
+		name       string
+		token      string
+		target     string
+		wantStatus int
+	}{
+		{name: "unauthorized", target: location, wantStatus: http.StatusUnauthorized},
+		{name: "task of another user", token: otherToken, target: location, wantStatus: http.StatusNotFound},
+		{name: "unknown task", token: ownerToken, target: "/api/tasks/unknown", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantStatus, send("GET", tt.target, tt.token, "").Code)
+		})
+	}
+
+	t.Run("full queue", func(t *testing.T) {
+		log, _ := logger.NewLogger("info")
+		pool := taskmanager.NewWorkerPool(ctx, 1, 0)
+		defer pool.Shutdown()
+		busy := service.NewServiceFactory(ctx, cfgTest, log, pool, repository.NewRepositoryFactory(ctx, cfgTest, log), nil)
+		require.NoError(t, pool.Enqueue(ctx, taskmanager.DeleteTask{UserID: "other"}))
+		router := chi.NewRouter()
+		router.Delete("/api/user/urls", withAuth(NewURLHandlers(busy.URLService, busy.AnalyticsService, nil).DeleteUserURLs))
+		req := httptest.NewRequest("DELETE", "/api/user/urls", strings.NewReader(`["`+shortID+`"]`))
+		req.AddCookie(&http.Cookie{Name: utils.NameCookieUserID, Value: ownerToken})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	})
+}
+
+func TestURLHandlers_Metrics(t *testing.T) {
+	ctx := context.Background()
+	services, _, err := setupURL(ctx)
+	if err != nil {
+		t.Fatalf("Failed to set up test: %v", err)
+	}
+	m := metrics.New()
+	handler := NewURLHandlers(services.URLService, services.AnalyticsService, m)
+	router := chi.NewRouter()
+	router.Post("/", withUser(handler.Shorten))
+	router.Get("/{id}", handler.Redirect)
+
+	originalURL := fmt.Sprintf("http://example.com/metrics?test=%d", time.Now().UnixNano())
+	var shortURL string
+	for _, body := range []string{originalURL, originalURL, "invalid-url"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		if w.Code == http.StatusCreated {
+			shortURL = w.Body.String()
+		}
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", shortURL[strings.LastIndex(shortURL, "/"):], nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/unknown1", nil))
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	assert.Contains(t, body, `shlink_shorten_requests_total{result="success"} 1`)
+	assert.Contains(t, body, `shlink_shorten_requests_total{result="conflict"} 1`)
+	assert.Contains(t, body, `shlink_shorten_requests_total{result="invalid"} 1`)
+	assert.Contains(t, body, `shlink_redirects_total{result="found"} 1`)
+	assert.Contains(t, body, `shlink_redirects_total{result="invalid"} 1`)
+}
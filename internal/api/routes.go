@@ -5,26 +5,105 @@
 // Routes:
 // - POST /: Shortens a URL using the URLHandlers.Shorten handler.
 // - GET /{id}: Redirects to the original URL based on the provided ID using the URLHandlers.Redirect handler.
+// - GET /{id}/qr: Renders the short URL as a PNG or SVG QR code using the URLHandlers.QRCode handler.
+// - POST /{id}: Submits the password of a protected URL and redirects using the URLHandlers.Redirect handler.
 // - POST /api/shorten: Shortens a URL based on the JSON body using the URLHandlers.ShortenJSON handler.
 // - POST /api/shorten/batch: Shortens multiple URLs in batch using the URLHandlers.ShortenJSONBatch handler.
-// - GET /api/user/urls: Fetches all URLs associated with a user using the URLHandlers.GetUserURLs handler.
+// - GET /api/user/urls: Fetches a page of the URLs associated with a user using the URLHandlers.GetUserURLs handler.
 // - DELETE /api/user/urls: Deletes all URLs associated with a user using the URLHandlers.DeleteUserURLs handler.
+// - GET /api/tasks/{id}: Returns the state of a task of the user, such as a deletion, using the URLHandlers.GetTask handler.
+// - PATCH /api/user/urls/{id}: Changes the destination of a user's URL using the URLHandlers.UpdateUserURL handler.
+// - GET /api/user/urls/{id}/stats: Returns click statistics for a user's URL using the URLHandlers.GetURLStats handler.
+// - POST /api/user/keys: Creates an API key of the user using the APIKeyHandlers.CreateAPIKey handler.
+// - GET /api/user/keys: Lists the API keys of the user using the APIKeyHandlers.GetAPIKeys handler.
+// - DELETE /api/user/keys/{id}: Revokes an API key of the user using the APIKeyHandlers.RevokeAPIKey handler.
+// - GET /api/admin/urls/{id}: Looks up any URL with its owner using the AdminHandlers.LookupURL handler.
+// - POST /api/admin/urls/{id}/disable: Disables a URL using the AdminHandlers.DisableURL handler.
+// - POST /api/admin/urls/{id}/restore: Restores a disabled URL using the AdminHandlers.RestoreURL handler.
+// - GET /api/admin/users/{userID}/urls: Lists the URLs of a user using the AdminHandlers.ListUserURLs handler.
+// - POST /api/admin/users/{userID}/disable: Disables all URLs of a user using the AdminHandlers.DisableUserURLs handler.
+// - POST /api/admin/domains/{domain}/disable: Disables all URLs pointing to a domain using the AdminHandlers.DisableDomainURLs handler.
+// - GET /api/admin/audit: Lists the audit log of the admin actions using the AdminHandlers.AuditLog handler.
+// - GET /api/admin/tasks/dead: Lists the background tasks that ran out of attempts using the AdminHandlers.DeadTasks handler.
+// - POST /api/admin/tasks/dead/{id}/replay: Moves a dead task back to the queue using the AdminHandlers.ReplayDeadTask handler.
+// - GET /api/admin/tasks/stats: Returns the live statistics of the task workers using the AdminHandlers.TaskStats handler.
+// - PUT /api/admin/tasks/workers: Resizes the task workers and sets concurrency limits using the AdminHandlers.ResizeWorkers handler.
+// - POST /api/user/claim: Moves the links created anonymously to the signed-in user using the LoginHandlers.ClaimURLs handler.
+// - GET /auth/login: Redirects to the OpenID Connect provider to sign in using the LoginHandlers.Login handler.
+// - GET /auth/callback: Signs the user in when the provider redirects back using the LoginHandlers.Callback handler.
+// - GET, POST /auth/logout: Signs the user out using the LoginHandlers.Logout handler.
 // - GET /ping: Returns a health check status using the HealthHandlers.Ping handler.
+//
+// The sign-in routes are only set up when a provider is configured. All routes but /ping
+// and the /auth routes identify the user by an API key or the user cookie through the
+// authenticator; the shortening, URL listing and key creation routes give anonymous
+// clients a new user. The /api/admin routes are for administrators only. The shorten, batch, redirect and delete routes are rate limited by
+// the policies of the limiter.
 package api
 
 import (
 	"github.com/GlebRadaev/shlink/internal/api/handlers"
+	"github.com/GlebRadaev/shlink/internal/middleware/auth"
+	"github.com/GlebRadaev/shlink/internal/middleware/ratelimit"
 	"github.com/go-chi/chi/v5"
 )
 
-// Routes sets up API routes for URL shortening and health checking.
-func Routes(r *chi.Mux, urlHandlers *handlers.URLHandlers, healthHandlers *handlers.HealthHandlers) {
-	r.Post("/", urlHandlers.Shorten)
-	r.Get("/{id}", urlHandlers.Redirect)
-	r.Post("/api/shorten", urlHandlers.ShortenJSON)
-	r.Post("/api/shorten/batch", urlHandlers.ShortenJSONBatch)
-	r.Get("/api/user/urls", urlHandlers.GetUserURLs)
-	r.Delete("/api/user/urls", urlHandlers.DeleteUserURLs)
+// Routes sets up API routes for URL shortening, API keys, administration, sign-in and health checking.
+// A nil authenticator only accepts the user cookie, a nil limiter leaves the routes unlimited
+// and nil login handlers leave out the sign-in routes.
+func Routes(
+	r *chi.Mux,
+	urlHandlers *handlers.URLHandlers,
+	apiKeyHandlers *handlers.APIKeyHandlers,
+	adminHandlers *handlers.AdminHandlers,
+	loginHandlers *handlers.LoginHandlers,
+	healthHandlers *handlers.HealthHandlers,
+	authenticator *auth.Authenticator,
+	limiter *ratelimit.Limiter,
+) {
+	r.Group(func(r chi.Router) {
+		r.Use(authenticator.Middleware)
+		r.With(limiter.Shorten, auth.EnsureUser).Post("/", urlHandlers.Shorten)
+		r.With(limiter.Redirect).Get("/{id}", urlHandlers.Redirect)
+		r.With(limiter.Redirect).Post("/{id}", urlHandlers.Redirect)
+		r.Get("/{id}/qr", urlHandlers.QRCode)
+		r.With(limiter.Shorten, auth.EnsureUser).Post("/api/shorten", urlHandlers.ShortenJSON)
+		r.With(limiter.Batch, auth.EnsureUser).Post("/api/shorten/batch", urlHandlers.ShortenJSONBatch)
+		r.With(auth.EnsureUser).Get("/api/user/urls", urlHandlers.GetUserURLs)
+		r.With(limiter.Delete).Delete("/api/user/urls", urlHandlers.DeleteUserURLs)
+		r.Patch("/api/user/urls/{id}", urlHandlers.UpdateUserURL)
+		r.Get("/api/user/urls/{id}/stats", urlHandlers.GetURLStats)
+		r.Get("/api/tasks/{id}", urlHandlers.GetTask)
+
+		r.With(auth.EnsureUser).Post("/api/user/keys", apiKeyHandlers.CreateAPIKey)
+		r.Get("/api/user/keys", apiKeyHandlers.GetAPIKeys)
+		r.Delete("/api/user/keys/{id}", apiKeyHandlers.RevokeAPIKey)
+
+		r.Route("/api/admin", func(r chi.Router) {
+			r.Get("/urls/{id}", adminHandlers.LookupURL)
+			r.Post("/urls/{id}/disable", adminHandlers.DisableURL)
+			r.Post("/urls/{id}/restore", adminHandlers.RestoreURL)
+			r.Get("/users/{userID}/urls", adminHandlers.ListUserURLs)
+			r.Post("/users/{userID}/disable", adminHandlers.DisableUserURLs)
+			r.Post("/domains/{domain}/disable", adminHandlers.DisableDomainURLs)
+			r.Get("/audit", adminHandlers.AuditLog)
+			r.Get("/tasks/dead", adminHandlers.DeadTasks)
+			r.Post("/tasks/dead/{id}/replay", adminHandlers.ReplayDeadTask)
+			r.Get("/tasks/stats", adminHandlers.TaskStats)
+			r.Put("/tasks/workers", adminHandlers.ResizeWorkers)
+		})
+
+		if loginHandlers != nil {
+			r.Post("/api/user/claim", loginHandlers.ClaimURLs)
+		}
+	})
+
+	if loginHandlers != nil {
+		r.Get("/auth/login", loginHandlers.Login)
+		r.Get("/auth/callback", loginHandlers.Callback)
+		r.Get("/auth/logout", loginHandlers.Logout)
+		r.Post("/auth/logout", loginHandlers.Logout)
+	}
 
 	r.Get("/ping", healthHandlers.Ping)
 }
@@ -3,41 +3,86 @@ package api
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
+	"net/http/cookiejar"
 	"net/http/httptest"
+	"strings"
 	"time"
 
 	"testing"
 
 	"github.com/GlebRadaev/shlink/internal/api/handlers"
 	"github.com/GlebRadaev/shlink/internal/config"
+	"github.com/GlebRadaev/shlink/internal/dto"
 	"github.com/GlebRadaev/shlink/internal/logger"
+	"github.com/GlebRadaev/shlink/internal/middleware/auth"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/oidc"
+	"github.com/GlebRadaev/shlink/internal/oidc/oidctest"
 	"github.com/GlebRadaev/shlink/internal/repository"
 	"github.com/GlebRadaev/shlink/internal/service"
 	"github.com/GlebRadaev/shlink/internal/taskmanager"
+	"github.com/GlebRadaev/shlink/internal/utils"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func generateDynamicURL(baseURL string) string {
 	return baseURL + "?t=" + time.Now().Format(time.RFC3339Nano)
 }
 
-func TestRoutes(t *testing.T) {
+// cfgTest is the configuration of the tests, parsed once since the flags can only be defined once.
+var cfgTest *config.Config
+
+// setupRouter creates a router with the routes backed by in-memory services.
+func setupRouter(t *testing.T) *chi.Mux {
+	t.Helper()
+	return setupRouterWithLogin(t, nil)
+}
+
+// setupRouterWithLogin creates a router with the routes backed by in-memory services, letting
+// users sign in with the provider unless it is nil.
+func setupRouterWithLogin(t *testing.T, provider *oidc.Provider) *chi.Mux {
+	t.Helper()
+	return setupRouterWithAdmins(t, provider, nil)
+}
+
+// setupRouterWithAdmins creates a router like setupRouterWithLogin whose administrators are the
+// users with the adminUserIDs.
+func setupRouterWithAdmins(t *testing.T, provider *oidc.Provider, adminUserIDs []string) *chi.Mux {
+	t.Helper()
 	ctx := context.Background()
-	cfg, _ := config.ParseAndLoadConfig()
+	if cfgTest == nil {
+		var err error
+		cfgTest, err = config.ParseAndLoadConfig()
+		require.NoError(t, err)
+	}
 	logger, _ := logger.NewLogger("info")
 
 	pool := taskmanager.NewWorkerPool(ctx, 10, 1)
-	repositories := repository.NewRepositoryFactory(ctx, cfg, logger)
-	services := service.NewServiceFactory(ctx, cfg, logger, pool, repositories)
+	repositories := repository.NewRepositoryFactory(ctx, cfgTest, logger)
+	services := service.NewServiceFactory(ctx, cfgTest, logger, pool, repositories, nil)
 
 	healthHandlers := handlers.NewHealthHandlers(services.HealthService)
-	urlHandlers := handlers.NewURLHandlers(services.URLService)
+	urlHandlers := handlers.NewURLHandlers(services.URLService, services.AnalyticsService, nil)
+	apiKeyHandlers := handlers.NewAPIKeyHandlers(services.APIKeyService)
+	adminHandlers := handlers.NewAdminHandlers(services.AdminService)
+	authenticator := auth.NewAuthenticator(services.APIKeyService, adminUserIDs, logger.SugaredLogger)
+	var loginHandlers *handlers.LoginHandlers
+	if provider != nil {
+		loginHandlers = handlers.NewLoginHandlers(provider, services.AccountService, false)
+	}
 
 	r := chi.NewRouter()
-	Routes(r, urlHandlers, healthHandlers)
+	Routes(r, urlHandlers, apiKeyHandlers, adminHandlers, loginHandlers, healthHandlers, authenticator, nil)
+	return r
+}
+
+func TestRoutes(t *testing.T) {
+	r := setupRouter(t)
 
 	tests := []struct {
 		name       string
@@ -95,3 +140,195 @@ func TestRoutes(t *testing.T) {
 		})
 	}
 }
+
+func TestRoutes_APIKeys(t *testing.T) {
+	r := setupRouter(t)
+	send := func(method, url, body string, headers map[string]string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A backend without a cookie jar creates its key once, getting a user with it.
+	rec := send(http.MethodPost, "/api/user/keys", `{"name":"backend","scopes":["shorten"]}`, nil)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created dto.CreateAPIKeyResponseDTO
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	userCookie := cookies[0]
+
+	shorten := func(headers map[string]string) *httptest.ResponseRecorder {
+		return send(http.MethodPost, "/api/shorten", `{"url":"`+generateDynamicURL("http://example.com/key")+`"}`, headers)
+	}
+	rec = shorten(map[string]string{"Authorization": "Bearer " + created.Key})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Empty(t, rec.Result().Cookies(), "Expected no cookie for requests authenticated by an API key")
+	assert.Equal(t, http.StatusCreated, shorten(map[string]string{auth.APIKeyHeader: created.Key}).Code)
+	assert.Equal(t, http.StatusUnauthorized, shorten(map[string]string{"Authorization": "Bearer shl_unknown"}).Code)
+
+	rec = send(http.MethodGet, "/api/user/urls", "", nil, userCookie)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var urls dto.GetUserURLsResponseDTO
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&urls))
+	assert.Len(t, urls, 2, "Expected the links shortened with the key to belong to its user")
+
+	headers := map[string]string{auth.APIKeyHeader: created.Key}
+	assert.Equal(t, http.StatusForbidden, send(http.MethodGet, "/api/user/urls", "", headers).Code, "Expected the read scope to be required")
+	assert.Equal(t, http.StatusForbidden, send(http.MethodDelete, "/api/user/urls", `[]`, headers).Code, "Expected the delete scope to be required")
+	assert.Equal(t, http.StatusForbidden, send(http.MethodGet, "/api/user/keys", "", headers).Code, "Expected keys not to manage keys")
+
+	rec = send(http.MethodGet, "/api/user/keys", "", nil, userCookie)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), created.Key, "Expected listed keys not to contain the key")
+
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodDelete, "/api/user/keys/"+created.ID, "", nil).Code)
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/api/user/keys/"+created.ID, "", nil, userCookie).Code)
+	assert.Equal(t, http.StatusUnauthorized, shorten(headers).Code, "Expected the revoked key to be rejected")
+}
+
+func TestRoutes_Admin(t *testing.T) {
+	r := setupRouterWithAdmins(t, nil, []string{"routes-admin"})
+	send := func(method, url, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send(http.MethodPost, "/api/shorten", `{"url":"http://abuse.example.net/phish?`+generateDynamicURL("x")+`"}`, nil)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	userCookie := rec.Result().Cookies()[0]
+	var shortened dto.ShortenJSONResponseDTO
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&shortened))
+	shortID := shortened.Result[strings.LastIndex(shortened.Result, "/")+1:]
+	token, err := utils.GenerateJWT("routes-admin")
+	require.NoError(t, err)
+	adminCookie := &http.Cookie{Name: utils.NameCookieUserID, Value: token}
+
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodGet, "/api/admin/urls/"+shortID, "", nil).Code)
+	assert.Equal(t, http.StatusForbidden, send(http.MethodGet, "/api/admin/urls/"+shortID, "", userCookie).Code,
+		"Expected users to be kept out of the admin API")
+
+	rec = send(http.MethodGet, "/api/admin/urls/"+shortID, "", adminCookie)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var found dto.AdminURLResponseDTO
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&found))
+	assert.NotEmpty(t, found.UserID)
+	assert.False(t, found.IsDisabled)
+
+	rec = send(http.MethodPost, "/api/admin/urls/"+shortID+"/disable", `{"reason":"phishing report"}`, adminCookie)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"affected":1}`, rec.Body.String())
+	assert.Equal(t, http.StatusGone, send(http.MethodGet, "/"+shortID, "", nil).Code, "Expected disabled links not to redirect")
+	rec = send(http.MethodGet, "/api/admin/users/"+found.UserID+"/urls", "", adminCookie)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"is_disabled":true`)
+
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/api/admin/urls/"+shortID+"/restore", "", adminCookie).Code)
+	assert.Equal(t, http.StatusTemporaryRedirect, send(http.MethodGet, "/"+shortID, "", nil).Code)
+	rec = send(http.MethodPost, "/api/admin/domains/example.net/disable", "", adminCookie)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"affected":1}`, rec.Body.String())
+
+	rec = send(http.MethodGet, "/api/admin/audit?target="+shortID, "", adminCookie)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var audit dto.AuditLogResponseDTO
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&audit))
+	require.Len(t, audit.Entries, 3)
+	assert.Equal(t, model.AuditRestoreURL, audit.Entries[0].Action)
+	assert.Equal(t, model.AuditDisableURL, audit.Entries[1].Action)
+	assert.Equal(t, "phishing report", audit.Entries[1].Reason)
+	assert.Equal(t, "routes-admin", audit.Entries[1].ActorUserID)
+	assert.Equal(t, model.AuditLookupURL, audit.Entries[2].Action)
+}
+
+func TestRoutes_Login(t *testing.T) {
+	idp := oidctest.NewServer(t, "shlink", "secret")
+	idp.SignInAs("alice", "alice@example.com")
+	var router http.Handler
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { router.ServeHTTP(w, r) }))
+	t.Cleanup(srv.Close)
+	router = setupRouterWithLogin(t, oidc.NewProvider(oidc.Config{
+		IssuerURL:    idp.URL,
+		ClientID:     "shlink",
+		ClientSecret: "secret",
+		RedirectURL:  srv.URL + oidc.CallbackPath,
+		LogoutURL:    srv.URL + "/ping",
+		Scopes:       []string{"email"},
+	}, idp.Client()))
+
+	// newBrowser returns a client keeping cookies and following redirects, like a browser.
+	newBrowser := func() *http.Client {
+		jar, err := cookiejar.New(nil)
+		require.NoError(t, err)
+		return &http.Client{Jar: jar}
+	}
+	send := func(browser *http.Client, method, path, body string) *http.Response {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := browser.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	userURLs := func(browser *http.Client) dto.GetUserURLsResponseDTO {
+		resp := send(browser, http.MethodGet, "/api/user/urls", "")
+		if resp.StatusCode == http.StatusNoContent {
+			return nil
+		}
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var urls dto.GetUserURLsResponseDTO
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&urls))
+		return urls
+	}
+
+	// The user shortens a link anonymously, then signs in and claims it.
+	laptop := newBrowser()
+	resp := send(laptop, http.MethodPost, "/api/shorten", `{"url":"`+generateDynamicURL("http://example.com/login")+`"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp = send(laptop, http.MethodGet, "/auth/login?return_to=/ping", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/ping", resp.Request.URL.Path, "Expected to return to the requested path")
+	assert.Empty(t, userURLs(laptop), "Expected the anonymous links not to belong to the signed-in user before claiming")
+
+	resp = send(laptop, http.MethodPost, "/api/user/claim", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var claimed dto.ClaimURLsResponseDTO
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&claimed))
+	assert.Equal(t, int64(1), claimed.Claimed)
+	assert.Len(t, userURLs(laptop), 1)
+	assert.Equal(t, http.StatusBadRequest, send(laptop, http.MethodPost, "/api/user/claim", "").StatusCode,
+		"Expected the links to be claimed once")
+
+	// Signing in from another browser shows the same links.
+	phone := newBrowser()
+	resp = send(phone, http.MethodGet, "/auth/login?return_to=//evil.example.com", "")
+	assert.Equal(t, srv.URL+"/", resp.Request.URL.String(), "Expected other hosts not to be returned to")
+	assert.Len(t, userURLs(phone), 1)
+
+	// Another account does not see them.
+	idp.SignInAs("bob", "bob@example.com")
+	other := newBrowser()
+	send(other, http.MethodGet, "/auth/login", "")
+	assert.Empty(t, userURLs(other))
+
+	// Signing out removes the user cookie.
+	resp = send(phone, http.MethodPost, "/auth/logout", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/ping", resp.Request.URL.Path, "Expected the provider to redirect back")
+	assert.Empty(t, userURLs(phone), "Expected a new anonymous user after signing out")
+}
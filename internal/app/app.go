@@ -12,20 +12,31 @@ import (
 	"github.com/GlebRadaev/shlink/internal/api/handlers"
 	"github.com/GlebRadaev/shlink/internal/config"
 	"github.com/GlebRadaev/shlink/internal/logger"
+	"github.com/GlebRadaev/shlink/internal/metrics"
 	"github.com/GlebRadaev/shlink/internal/middleware"
+	"github.com/GlebRadaev/shlink/internal/middleware/auth"
+	"github.com/GlebRadaev/shlink/internal/middleware/ratelimit"
+	"github.com/GlebRadaev/shlink/internal/oidc"
 	"github.com/GlebRadaev/shlink/internal/repository"
 	"github.com/GlebRadaev/shlink/internal/service"
 	"github.com/GlebRadaev/shlink/internal/taskmanager"
+	"github.com/GlebRadaev/shlink/internal/tracing"
+	"github.com/GlebRadaev/shlink/internal/utils"
 )
 
 // Application is the main struct that encapsulates the application context, configurations, services, server, and worker pool.
 type Application struct {
-	Ctx        context.Context
-	Config     *config.Config
-	Logger     *logger.Logger
-	Services   *service.Services
-	Server     *http.Server
-	WorkerPool *taskmanager.WorkerPool
+	Ctx         context.Context
+	Config      *config.Config
+	Logger      *logger.Logger
+	Services    *service.Services
+	Server      *http.Server
+	AdminServer *http.Server // Separate listener for /metrics, nil if metrics are served by Server
+	WorkerPool  *taskmanager.WorkerPool
+	Metrics     *metrics.Metrics   // Prometheus metrics, nil if disabled
+	RateLimiter *ratelimit.Limiter // Rate limits of the routes, nil if disabled
+
+	shutdownTracing func(context.Context) error // Flushes the remaining spans, nil if tracing is not set up
 }
 
 // NewApplication creates a new instance of Application with the provided context.
@@ -52,9 +63,51 @@ func (app *Application) Init() error {
 		}
 	}
 
-	app.WorkerPool = taskmanager.NewWorkerPool(app.Ctx, 100, 10)
+	tokens, err := utils.LoadTokenManager(app.Config)
+	if err != nil {
+		return fmt.Errorf("failed to load JWT keys: %v", err)
+	}
+	utils.SetDefaultTokenManager(tokens)
+
+	app.shutdownTracing, err = tracing.Setup(app.Ctx, app.Config)
+	if err != nil {
+		app.Logger.Errorf("Failed to set up tracing, spans will not be exported: %v", err)
+	}
+
+	limits, err := taskmanager.ParseConcurrencyLimits(app.Config.TaskConcurrencyLimits)
+	if err != nil {
+		return fmt.Errorf("failed to parse task concurrency limits: %v", err)
+	}
 	repositories := repository.NewRepositoryFactory(app.Ctx, app.Config, app.Logger)
-	app.Services = service.NewServiceFactory(app.Ctx, app.Config, app.Logger, app.WorkerPool, repositories)
+	app.WorkerPool = taskmanager.NewDurableWorkerPool(app.Ctx, app.Config.TaskQueueSize, app.Config.TaskWorkers, repositories.TaskRepo, taskmanager.RetryPolicy{
+		MaxAttempts:  app.Config.TaskMaxAttempts,
+		BaseDelay:    app.Config.TaskRetryBaseDelay,
+		MaxDelay:     app.Config.TaskRetryMaxDelay,
+		PollInterval: app.Config.TaskPollInterval,
+		LeaseTimeout: app.Config.TaskLeaseTimeout,
+	})
+	app.WorkerPool.SetJobLocks(repositories.JobLockRepo)
+	if app.Config.MetricsEnabled {
+		app.Metrics = metrics.New()
+	}
+	app.Metrics.RegisterWorkerPool(app.WorkerPool)
+	app.Metrics.RegisterDBPool(repositories.DBPool)
+	app.Metrics.RegisterURLCache(repositories.URLCache)
+	app.Services = service.NewServiceFactory(app.Ctx, app.Config, app.Logger, app.WorkerPool, repositories, app.Metrics)
+	for taskType, limit := range limits {
+		if err := app.WorkerPool.SetConcurrencyLimit(taskType, limit); err != nil {
+			return fmt.Errorf("failed to limit the concurrency of %s: %v", taskType, err)
+		}
+	}
+	app.RateLimiter = ratelimit.New(app.Ctx, app.Config, app.Logger)
+	if app.Metrics != nil && app.Config.MetricsAddress != "" {
+		adminRouter := chi.NewRouter()
+		adminRouter.Handle("/metrics", app.Metrics.Handler())
+		app.AdminServer = &http.Server{
+			Addr:    app.Config.MetricsAddress,
+			Handler: adminRouter,
+		}
+	}
 	router := app.SetupRoutes()
 
 	app.Server = &http.Server{
@@ -72,6 +125,14 @@ func (app *Application) Start() error {
 		logger.Infoln("Base URL:", app.Config.BaseURL)
 		logger.Infoln("File storage path:", app.Config.FileStoragePath)
 		logger.Infoln("Database path:", app.Config.DatabaseDSN)
+		if app.AdminServer != nil {
+			go func() {
+				logger.Infoln("Serving metrics at", app.Config.MetricsAddress)
+				if err := app.AdminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					logger.Errorf("Metrics server error: %v", err)
+				}
+			}()
+		}
 		if app.Config.EnableHTTPS {
 			logger.Infoln("Starting server with HTTPS...")
 			if err := app.Server.ListenAndServeTLS(app.Config.CertPath, app.Config.KeyPath); err != nil && err != http.ErrServerClosed {
@@ -98,6 +159,11 @@ func (app *Application) Shutdown() error {
 	} else {
 		logger.Info("Server shutdown successfully")
 	}
+	if app.AdminServer != nil {
+		if err := app.AdminServer.Shutdown(shutdownCtx); err != nil {
+			logger.Errorf("Error during metrics server shutdown: %v", err)
+		}
+	}
 	saveCtx, saveCancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer saveCancel()
 	if err := app.Services.URLService.SaveData(saveCtx); err != nil {
@@ -105,17 +171,50 @@ func (app *Application) Shutdown() error {
 	} else {
 		logger.Info("Data successfully saved before shutdown")
 	}
+	if err := app.Services.AnalyticsService.Flush(saveCtx); err != nil {
+		logger.Errorf("Failed to flush clicks: %v", err)
+	}
 	app.WorkerPool.Shutdown()
 	logger.Info("Worker pool shutdown completed")
+	if err := app.Services.URLService.Close(); err != nil {
+		logger.Errorf("Failed to close journal: %v", err)
+	}
+	if err := app.RateLimiter.Close(); err != nil {
+		logger.Errorf("Failed to close rate limit store: %v", err)
+	}
+	if app.shutdownTracing != nil {
+		if err := app.shutdownTracing(saveCtx); err != nil {
+			logger.Errorf("Failed to flush spans: %v", err)
+		}
+	}
 	return nil
 }
 
-// SetupRoutes sets up the HTTP routes for the application.
+// SetupRoutes sets up the HTTP routes for the application. Metrics are served at /metrics
+// unless they have a separate admin listener. With tracing enabled each request is traced, and
+// with an OpenID Connect provider configured users can sign in.
 func (app *Application) SetupRoutes() *chi.Mux {
 	router := chi.NewRouter()
+	if app.Config.TracingExporter != "" {
+		router.Use(tracing.Middleware)
+	}
 	middleware.Middleware(router)
-	urlHandlers := handlers.NewURLHandlers(app.Services.URLService)
+	if app.Metrics != nil {
+		router.Use(app.Metrics.Middleware)
+		if app.AdminServer == nil {
+			router.Handle("/metrics", app.Metrics.Handler())
+		}
+	}
+	urlHandlers := handlers.NewURLHandlers(app.Services.URLService, app.Services.AnalyticsService, app.Metrics)
+	apiKeyHandlers := handlers.NewAPIKeyHandlers(app.Services.APIKeyService)
+	adminHandlers := handlers.NewAdminHandlers(app.Services.AdminService)
 	healthHandlers := handlers.NewHealthHandlers(app.Services.HealthService)
-	api.Routes(router, urlHandlers, healthHandlers)
+	var loginHandlers *handlers.LoginHandlers
+	if provider := oidc.New(app.Config, app.Logger); provider != nil {
+		secureCookies := app.Config.CookieSecure || app.Config.EnableHTTPS
+		loginHandlers = handlers.NewLoginHandlers(provider, app.Services.AccountService, secureCookies)
+	}
+	authenticator := auth.NewAuthenticator(app.Services.APIKeyService, app.Config.AdminUserIDs, app.Logger.Named("Authenticator"))
+	api.Routes(router, urlHandlers, apiKeyHandlers, adminHandlers, loginHandlers, healthHandlers, authenticator, app.RateLimiter)
 	return router
 }
@@ -26,6 +26,7 @@ func resetFlagsAndArgs() {
 func resetEnv() {
 	os.Unsetenv("SERVER_ADDRESS")
 	os.Unsetenv("BASE_URL")
+	os.Setenv("JWT_SECRET", "test_secret")
 }
 
 func TestNewApplication(t *testing.T) {
@@ -50,6 +51,18 @@ func TestApplicationInit(t *testing.T) {
 	assert.NotNil(t, application.Server)
 }
 
+func TestApplicationInit_NoJWTKeys(t *testing.T) {
+	resetFlagsAndArgs()
+	resetEnv()
+	os.Unsetenv("JWT_SECRET")
+	defer os.Setenv("JWT_SECRET", "test_secret")
+
+	application := app.NewApplication(context.Background())
+
+	err := application.Init()
+	assert.ErrorContains(t, err, "neither JWT_KEYS_FILE nor JWT_SECRET is set")
+}
+
 func TestApplicationStart(t *testing.T) {
 	resetFlagsAndArgs()
 	resetEnv()
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/caarlos0/env/v6"
 )
@@ -19,6 +20,76 @@ type Config struct {
 	CertPath        string `env:"CERT_PATH" envDefault:"./certs/cert.pem"`
 	KeyPath         string `env:"KEY_PATH" envDefault:"./certs/key.pem"`
 	ConfigPath      string `env:"CONFIG"`
+
+	JournalPath            string        `env:"JOURNAL_PATH" envDefault:""`                // Path to the write-ahead journal of the in-memory storage; disabled if empty
+	JournalSync            string        `env:"JOURNAL_SYNC" envDefault:"always"`          // When journal entries are fsync'd: always, interval or never
+	JournalSyncInterval    time.Duration `env:"JOURNAL_SYNC_INTERVAL" envDefault:"1s"`     // How often journal entries are fsync'd with the interval policy
+	JournalCompactInterval time.Duration `env:"JOURNAL_COMPACT_INTERVAL" envDefault:"10m"` // How often the journal is compacted into a fresh snapshot
+
+	URLCacheSize        int           `env:"URL_CACHE_SIZE" envDefault:"10000"`      // Maximum number of URLs cached in front of a database; disabled if 0
+	URLCacheTTL         time.Duration `env:"URL_CACHE_TTL" envDefault:"1m"`          // How long a found URL stays cached
+	URLCacheNegativeTTL time.Duration `env:"URL_CACHE_NEGATIVE_TTL" envDefault:"5s"` // How long an unknown short ID stays cached; disabled if 0
+
+	MetricsEnabled bool   `env:"METRICS_ENABLED" envDefault:"true"` // Whether Prometheus metrics are served at /metrics
+	MetricsAddress string `env:"METRICS_ADDRESS" envDefault:""`     // Address of a separate admin listener for /metrics; the main server is used if empty
+
+	TracingExporter    string  `env:"TRACING_EXPORTER" envDefault:""`                      // Exporter of trace spans: otlp, stdout or file; disabled if empty
+	TracingEndpoint    string  `env:"TRACING_ENDPOINT" envDefault:"http://localhost:4318"` // URL of the OTLP/HTTP collector used by the otlp exporter
+	TracingFile        string  `env:"TRACING_FILE" envDefault:"./traces.json"`             // File the spans are appended to by the file exporter
+	TracingSampleRatio float64 `env:"TRACING_SAMPLE_RATIO" envDefault:"1"`                 // Fraction of new traces that are sampled
+	TracingServiceName string  `env:"TRACING_SERVICE_NAME" envDefault:"shlink"`            // Service name reported with the spans
+
+	RateLimitEnabled  bool   `env:"RATE_LIMIT_ENABLED" envDefault:"true"`    // Whether the shorten, batch, redirect and delete routes are rate limited
+	RateLimitKey      string `env:"RATE_LIMIT_KEY" envDefault:"ip"`          // What a client is limited by: ip, user or ip_and_user
+	RateLimitRedisURL string `env:"RATE_LIMIT_REDIS_URL" envDefault:""`      // redis:// URL of a server sharing the limits between instances; in-process if empty
+	RateLimitShorten  string `env:"RATE_LIMIT_SHORTEN" envDefault:"60/1m"`   // Shorten requests per client as <requests>/<period>; unlimited if 0
+	RateLimitBatch    string `env:"RATE_LIMIT_BATCH" envDefault:"10/1m"`     // Batch shorten requests per client, a request taking a token per 64 KiB of body and its body being capped at a full bucket
+	RateLimitRedirect string `env:"RATE_LIMIT_REDIRECT" envDefault:"600/1m"` // Redirects followed per client
+	RateLimitDelete   string `env:"RATE_LIMIT_DELETE" envDefault:"30/1m"`    // Delete requests per client
+
+	JWTKeysFile      string        `env:"JWT_KEYS_FILE" envDefault:""`          // JSON file with the keys signing and verifying user tokens, for rotation and RS256/EdDSA
+	JWTSecret        string        `env:"JWT_SECRET" envDefault:""`             // HS256 secret signing user tokens; required unless a key file is set
+	JWTTokenTTL      time.Duration `env:"JWT_TOKEN_TTL" envDefault:"720h"`      // How long an issued user token and its cookie are valid
+	JWTRefreshBefore time.Duration `env:"JWT_REFRESH_BEFORE" envDefault:"168h"` // Tokens presented this close to expiry are re-issued
+	CookieSecure     bool          `env:"COOKIE_SECURE" envDefault:"false"`     // Whether the user cookie is only sent over HTTPS; always set with HTTPS enabled
+	CookieSameSite   string        `env:"COOKIE_SAME_SITE" envDefault:"lax"`    // SameSite attribute of the user cookie: lax, strict or none
+
+	OIDCIssuerURL    string `env:"OIDC_ISSUER_URL" envDefault:""`         // URL of the OpenID Connect provider users sign in with; sign-in is disabled if empty
+	OIDCClientID     string `env:"OIDC_CLIENT_ID" envDefault:""`          // Client ID registered with the provider
+	OIDCClientSecret string `env:"OIDC_CLIENT_SECRET" envDefault:""`      // Client secret registered with the provider; empty for a public client
+	OIDCRedirectURL  string `env:"OIDC_REDIRECT_URL" envDefault:""`       // Callback URL registered with the provider; BASE_URL/auth/callback if empty
+	OIDCScopes       string `env:"OIDC_SCOPES" envDefault:"openid email"` // Space separated scopes requested from the provider
+
+	AdminUserIDs []string `env:"ADMIN_USER_IDS" envSeparator:","` // Comma separated user IDs allowed to use the admin API
+
+	ExpiredSweepInterval time.Duration `env:"EXPIRED_SWEEP_INTERVAL" envDefault:"1m"` // How often expired links are soft deleted
+
+	TaskQueuePath      string        `env:"TASK_QUEUE_PATH" envDefault:""`         // File keeping the task queue of the in-memory storage across restarts; in memory only if empty
+	TaskMaxAttempts    int           `env:"TASK_MAX_ATTEMPTS" envDefault:"5"`      // Attempts of a queued task before it is moved to the dead letters
+	TaskRetryBaseDelay time.Duration `env:"TASK_RETRY_BASE_DELAY" envDefault:"1s"` // Delay before the first retry of a failed task, doubled for every further one
+	TaskRetryMaxDelay  time.Duration `env:"TASK_RETRY_MAX_DELAY" envDefault:"5m"`  // Longest delay between two attempts of a task
+	TaskPollInterval   time.Duration `env:"TASK_POLL_INTERVAL" envDefault:"1s"`    // How often idle workers look for due tasks in the queue
+	TaskLeaseTimeout   time.Duration `env:"TASK_LEASE_TIMEOUT" envDefault:"5m"`    // How long a claimed task is hidden from other workers, and a job lock lasts without renewal
+
+	TaskQueueSize         int    `env:"TASK_QUEUE_SIZE" envDefault:"1000"`     // Largest number of tasks waiting in the queue; enqueueing more is rejected as the queue being full
+	TaskWorkers           int    `env:"TASK_WORKERS" envDefault:"10"`          // Number of workers processing the tasks, changeable at runtime through the admin API
+	TaskConcurrencyLimits string `env:"TASK_CONCURRENCY_LIMITS" envDefault:""` // Largest number of tasks of a type processed at once as comma separated <type>:<limit> pairs
+
+	ClickFlushInterval time.Duration `env:"CLICK_FLUSH_INTERVAL" envDefault:"1s"` // How often buffered click events are written
+	ClickBatchSize     int           `env:"CLICK_BATCH_SIZE" envDefault:"100"`    // Maximum number of click events written at once
+	ClickBufferSize    int           `env:"CLICK_BUFFER_SIZE" envDefault:"10000"` // Maximum number of click events kept in memory before dropping
+
+	PasswordMaxAttempts   int           `env:"PASSWORD_MAX_ATTEMPTS" envDefault:"5"`     // Wrong password attempts allowed per link within the window
+	PasswordAttemptWindow time.Duration `env:"PASSWORD_ATTEMPT_WINDOW" envDefault:"15m"` // Window after which wrong password attempts are forgotten
+
+	IDStrategy    string `env:"ID_STRATEGY" envDefault:"random"`                                                         // Short ID generation strategy: random, counter or time
+	IDAlphabet    string `env:"ID_ALPHABET" envDefault:"abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"` // Characters used in generated short IDs
+	IDLength      int    `env:"ID_LENGTH" envDefault:"8"`                                                                // Length of generated short IDs
+	IDSalt        string `env:"ID_SALT" envDefault:""`                                                                   // Salt obfuscating counter based short IDs
+	IDMaxAttempts int    `env:"ID_MAX_ATTEMPTS" envDefault:"3"`                                                          // Attempts to store a generated short ID before giving up on collisions
+
+	UserURLsPageSize    int `env:"USER_URLS_PAGE_SIZE" envDefault:"100"`      // Number of user URLs listed per page by default
+	UserURLsMaxPageSize int `env:"USER_URLS_MAX_PAGE_SIZE" envDefault:"1000"` // Largest number of user URLs a client may request per page
 }
 
 // ParseAndLoadConfig reads configuration from environment variables and command-line flags.
@@ -90,4 +161,189 @@ func applyJSONConfig(cfg *Config, jsonData map[string]interface{}) {
 	if val, ok := jsonData["key_path"].(string); ok && val != "" {
 		cfg.KeyPath = val
 	}
+	if val, ok := jsonData["journal_path"].(string); ok && val != "" {
+		cfg.JournalPath = val
+	}
+	if val, ok := jsonData["journal_sync"].(string); ok && val != "" {
+		cfg.JournalSync = val
+	}
+	if val, ok := jsonData["journal_sync_interval"].(string); ok && val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			cfg.JournalSyncInterval = d
+		}
+	}
+	if val, ok := jsonData["journal_compact_interval"].(string); ok && val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			cfg.JournalCompactInterval = d
+		}
+	}
+	if val, ok := jsonData["url_cache_size"].(float64); ok && val >= 0 {
+		cfg.URLCacheSize = int(val)
+	}
+	if val, ok := jsonData["url_cache_ttl"].(string); ok && val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			cfg.URLCacheTTL = d
+		}
+	}
+	if val, ok := jsonData["url_cache_negative_ttl"].(string); ok && val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			cfg.URLCacheNegativeTTL = d
+		}
+	}
+	if val, ok := jsonData["metrics_enabled"].(bool); ok {
+		cfg.MetricsEnabled = val
+	}
+	if val, ok := jsonData["metrics_address"].(string); ok && val != "" {
+		cfg.MetricsAddress = val
+	}
+	if val, ok := jsonData["tracing_exporter"].(string); ok && val != "" {
+		cfg.TracingExporter = val
+	}
+	if val, ok := jsonData["tracing_endpoint"].(string); ok && val != "" {
+		cfg.TracingEndpoint = val
+	}
+	if val, ok := jsonData["tracing_file"].(string); ok && val != "" {
+		cfg.TracingFile = val
+	}
+	if val, ok := jsonData["tracing_sample_ratio"].(float64); ok && val >= 0 && val <= 1 {
+		cfg.TracingSampleRatio = val
+	}
+	if val, ok := jsonData["tracing_service_name"].(string); ok && val != "" {
+		cfg.TracingServiceName = val
+	}
+	if val, ok := jsonData["rate_limit_enabled"].(bool); ok {
+		cfg.RateLimitEnabled = val
+	}
+	if val, ok := jsonData["rate_limit_key"].(string); ok && val != "" {
+		cfg.RateLimitKey = val
+	}
+	if val, ok := jsonData["rate_limit_redis_url"].(string); ok && val != "" {
+		cfg.RateLimitRedisURL = val
+	}
+	if val, ok := jsonData["rate_limit_shorten"].(string); ok && val != "" {
+		cfg.RateLimitShorten = val
+	}
+	if val, ok := jsonData["rate_limit_batch"].(string); ok && val != "" {
+		cfg.RateLimitBatch = val
+	}
+	if val, ok := jsonData["rate_limit_redirect"].(string); ok && val != "" {
+		cfg.RateLimitRedirect = val
+	}
+	if val, ok := jsonData["rate_limit_delete"].(string); ok && val != "" {
+		cfg.RateLimitDelete = val
+	}
+	if val, ok := jsonData["jwt_keys_file"].(string); ok && val != "" {
+		cfg.JWTKeysFile = val
+	}
+	if val, ok := jsonData["jwt_secret"].(string); ok && val != "" {
+		cfg.JWTSecret = val
+	}
+	if val, ok := jsonData["jwt_token_ttl"].(string); ok && val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			cfg.JWTTokenTTL = d
+		}
+	}
+	if val, ok := jsonData["jwt_refresh_before"].(string); ok && val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			cfg.JWTRefreshBefore = d
+		}
+	}
+	if val, ok := jsonData["cookie_secure"].(bool); ok {
+		cfg.CookieSecure = val
+	}
+	if val, ok := jsonData["cookie_same_site"].(string); ok && val != "" {
+		cfg.CookieSameSite = val
+	}
+	if val, ok := jsonData["oidc_issuer_url"].(string); ok && val != "" {
+		cfg.OIDCIssuerURL = val
+	}
+	if val, ok := jsonData["oidc_client_id"].(string); ok && val != "" {
+		cfg.OIDCClientID = val
+	}
+	if val, ok := jsonData["oidc_client_secret"].(string); ok && val != "" {
+		cfg.OIDCClientSecret = val
+	}
+	if val, ok := jsonData["oidc_redirect_url"].(string); ok && val != "" {
+		cfg.OIDCRedirectURL = val
+	}
+	if val, ok := jsonData["oidc_scopes"].(string); ok && val != "" {
+		cfg.OIDCScopes = val
+	}
+	if val, ok := jsonData["admin_user_ids"].([]interface{}); ok && len(val) > 0 {
+		cfg.AdminUserIDs = cfg.AdminUserIDs[:0]
+		for _, id := range val {
+			if id, ok := id.(string); ok && id != "" {
+				cfg.AdminUserIDs = append(cfg.AdminUserIDs, id)
+			}
+		}
+	}
+	if val, ok := jsonData["expired_sweep_interval"].(string); ok && val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			cfg.ExpiredSweepInterval = d
+		}
+	}
+	if val, ok := jsonData["task_queue_path"].(string); ok && val != "" {
+		cfg.TaskQueuePath = val
+	}
+	if val, ok := jsonData["task_max_attempts"].(float64); ok && val > 0 {
+		cfg.TaskMaxAttempts = int(val)
+	}
+	if val, ok := jsonData["task_retry_base_delay"].(string); ok && val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			cfg.TaskRetryBaseDelay = d
+		}
+	}
+	if val, ok := jsonData["task_retry_max_delay"].(string); ok && val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			cfg.TaskRetryMaxDelay = d
+		}
+	}
+	if val, ok := jsonData["task_poll_interval"].(string); ok && val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			cfg.TaskPollInterval = d
+		}
+	}
+	if val, ok := jsonData["task_lease_timeout"].(string); ok && val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			cfg.TaskLeaseTimeout = d
+		}
+	}
+	if val, ok := jsonData["task_queue_size"].(float64); ok && val > 0 {
+		cfg.TaskQueueSize = int(val)
+	}
+	if val, ok := jsonData["task_workers"].(float64); ok && val > 0 {
+		cfg.TaskWorkers = int(val)
+	}
+	if val, ok := jsonData["task_concurrency_limits"].(string); ok && val != "" {
+		cfg.TaskConcurrencyLimits = val
+	}
+	if val, ok := jsonData["id_strategy"].(string); ok && val != "" {
+		cfg.IDStrategy = val
+	}
+	if val, ok := jsonData["id_alphabet"].(string); ok && val != "" {
+		cfg.IDAlphabet = val
+	}
+	if val, ok := jsonData["id_length"].(float64); ok && val > 0 {
+		cfg.IDLength = int(val)
+	}
+	if val, ok := jsonData["id_salt"].(string); ok && val != "" {
+		cfg.IDSalt = val
+	}
+	if val, ok := jsonData["id_max_attempts"].(float64); ok && val > 0 {
+		cfg.IDMaxAttempts = int(val)
+	}
+	if val, ok := jsonData["user_urls_page_size"].(float64); ok && val > 0 {
+		cfg.UserURLsPageSize = int(val)
+	}
+	if val, ok := jsonData["user_urls_max_page_size"].(float64); ok && val > 0 {
+		cfg.UserURLsMaxPageSize = int(val)
+	}
+	if val, ok := jsonData["password_max_attempts"].(float64); ok && val > 0 {
+		cfg.PasswordMaxAttempts = int(val)
+	}
+	if val, ok := jsonData["password_attempt_window"].(string); ok && val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			cfg.PasswordAttemptWindow = d
+		}
+	}
 }
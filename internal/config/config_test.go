@@ -4,6 +4,7 @@ import (
 	"flag"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/GlebRadaev/shlink/internal/config"
 
@@ -56,6 +57,48 @@ func TestParseAndLoadConfig_MissingEnvVarsAndArgs(t *testing.T) {
 	// Check that the configuration values are set to default values
 	assert.Equal(t, "localhost:8080", cfg.ServerAddress)
 	assert.Equal(t, "http://localhost:8080", cfg.BaseURL) // corrected to the right default value
+	assert.Equal(t, time.Minute, cfg.ExpiredSweepInterval)
+	assert.Equal(t, 5, cfg.PasswordMaxAttempts)
+	assert.Equal(t, 15*time.Minute, cfg.PasswordAttemptWindow)
+	assert.Equal(t, "random", cfg.IDStrategy)
+	assert.Equal(t, 8, cfg.IDLength)
+	assert.Equal(t, 3, cfg.IDMaxAttempts)
+	assert.Equal(t, "", cfg.JournalPath)
+	assert.Equal(t, "always", cfg.JournalSync)
+	assert.Equal(t, 10*time.Minute, cfg.JournalCompactInterval)
+	assert.Equal(t, 100, cfg.UserURLsPageSize)
+	assert.Equal(t, 1000, cfg.UserURLsMaxPageSize)
+	assert.Equal(t, 10000, cfg.URLCacheSize)
+	assert.Equal(t, time.Minute, cfg.URLCacheTTL)
+	assert.Equal(t, 5*time.Second, cfg.URLCacheNegativeTTL)
+	assert.True(t, cfg.MetricsEnabled)
+	assert.Equal(t, "", cfg.MetricsAddress)
+	assert.Equal(t, "", cfg.TracingExporter)
+	assert.Equal(t, "http://localhost:4318", cfg.TracingEndpoint)
+	assert.Equal(t, 1.0, cfg.TracingSampleRatio)
+	assert.Equal(t, "shlink", cfg.TracingServiceName)
+	assert.True(t, cfg.RateLimitEnabled)
+	assert.Equal(t, "ip", cfg.RateLimitKey)
+	assert.Equal(t, "60/1m", cfg.RateLimitShorten)
+	assert.Equal(t, "10/1m", cfg.RateLimitBatch)
+	assert.Equal(t, "", cfg.JWTKeysFile)
+	assert.Equal(t, "", cfg.JWTSecret)
+	assert.Equal(t, 720*time.Hour, cfg.JWTTokenTTL)
+	assert.Equal(t, 168*time.Hour, cfg.JWTRefreshBefore)
+	assert.False(t, cfg.CookieSecure)
+	assert.Equal(t, "lax", cfg.CookieSameSite)
+	assert.Empty(t, cfg.OIDCIssuerURL)
+	assert.Equal(t, "openid email", cfg.OIDCScopes)
+	assert.Empty(t, cfg.AdminUserIDs)
+	assert.Equal(t, "", cfg.TaskQueuePath)
+	assert.Equal(t, 5, cfg.TaskMaxAttempts)
+	assert.Equal(t, time.Second, cfg.TaskRetryBaseDelay)
+	assert.Equal(t, 5*time.Minute, cfg.TaskRetryMaxDelay)
+	assert.Equal(t, time.Second, cfg.TaskPollInterval)
+	assert.Equal(t, 5*time.Minute, cfg.TaskLeaseTimeout)
+	assert.Equal(t, 1000, cfg.TaskQueueSize)
+	assert.Equal(t, 10, cfg.TaskWorkers)
+	assert.Equal(t, "", cfg.TaskConcurrencyLimits)
 }
 
 func TestParseAndLoadConfig_CommandLineArgs(t *testing.T) {
@@ -88,7 +131,12 @@ func TestParseAndLoadConfig_ValidJSONConfig(t *testing.T) {
 		"server_address": "192.168.1.0:8080",
 		"base_url": "http://192.168.1.0:8080",
 		"file_storage_path": "./json_storage.txt",
-		"enable_https": true
+		"enable_https": true,
+		"admin_user_ids": ["admin1", "admin2"],
+		"task_max_attempts": 8,
+		"task_retry_max_delay": "1h",
+		"task_workers": 4,
+		"task_concurrency_limits": "delete_urls_task:2"
 	}`
 
 	tmpFile, err := os.CreateTemp("", "config-*.json")
@@ -108,6 +156,11 @@ func TestParseAndLoadConfig_ValidJSONConfig(t *testing.T) {
 	assert.Equal(t, "http://192.168.1.0:8080", cfg.BaseURL)
 	assert.Equal(t, "./json_storage.txt", cfg.FileStoragePath)
 	assert.True(t, cfg.EnableHTTPS)
+	assert.Equal(t, []string{"admin1", "admin2"}, cfg.AdminUserIDs)
+	assert.Equal(t, 8, cfg.TaskMaxAttempts)
+	assert.Equal(t, time.Hour, cfg.TaskRetryMaxDelay)
+	assert.Equal(t, 4, cfg.TaskWorkers)
+	assert.Equal(t, "delete_urls_task:2", cfg.TaskConcurrencyLimits)
 }
 
 func TestParseAndLoadConfig_InvalidJSON(t *testing.T) {
@@ -0,0 +1,6 @@
+package dto
+
+// ClaimURLsResponseDTO defines the structure of the response to claiming the anonymous links of a user.
+type ClaimURLsResponseDTO struct {
+	Claimed int64 `json:"claimed"` // Number of links moved to the signed-in user.
+}
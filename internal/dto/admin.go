@@ -0,0 +1,102 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AdminURLResponseDTO defines the structure of a URL looked up by an administrator, with its owner.
+type AdminURLResponseDTO struct {
+	ShortID     string     `json:"short_id"`              // Short ID or alias of the URL.
+	ShortURL    string     `json:"short_url"`             // The shortened URL.
+	OriginalURL string     `json:"original_url"`          // The original URL.
+	UserID      string     `json:"user_id"`               // Identifier of the user owning the URL.
+	OwnerEmail  string     `json:"owner_email,omitempty"` // Email of the owner's account, if the owner signed in with one.
+	CreatedAt   time.Time  `json:"created_at"`            // The moment the URL was shortened.
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`  // The moment the URL expires, if it does.
+	MaxClicks   *int       `json:"max_clicks,omitempty"`  // Click budget of the URL, if it has one.
+	Clicks      int        `json:"clicks"`                // Number of clicks counted against the budget.
+	HasPassword bool       `json:"has_password"`          // Whether the URL is password protected.
+	IsDeleted   bool       `json:"is_deleted"`            // Whether the URL is deleted.
+	IsDisabled  bool       `json:"is_disabled"`           // Whether the URL was disabled by an administrator.
+}
+
+// AdminActionRequestDTO defines the optional body of an administrator disabling or restoring URLs.
+type AdminActionRequestDTO struct {
+	Reason string `json:"reason"` // Explanation recorded in the audit log, such as the abuse report.
+}
+
+// AdminActionResponseDTO defines the structure of the response to disabling or restoring URLs.
+type AdminActionResponseDTO struct {
+	Affected int64 `json:"affected"` // Number of URLs whose state changed.
+}
+
+// AuditLogRequestDTO defines the filter and pagination options of an audit log listing, as given
+// in the query string.
+type AuditLogRequestDTO struct {
+	Target string // Only entries acting on this short ID, user ID or domain.
+	Before string // Only entries older than the entry with this ID, from next_before of the previous page.
+	Limit  string // Maximum number of entries on the page.
+}
+
+// AuditEntryResponseDTO defines the structure of an audit log entry.
+type AuditEntryResponseDTO struct {
+	ID          int64     `json:"id"`                   // Identifier of the entry, increasing with every entry.
+	ActorUserID string    `json:"actor_user_id"`        // Identifier of the administrator.
+	APIKeyID    string    `json:"api_key_id,omitempty"` // API key the administrator used, if any.
+	Action      string    `json:"action"`               // What the administrator did.
+	Target      string    `json:"target"`               // Short ID, user ID or domain acted on.
+	Reason      string    `json:"reason,omitempty"`     // Explanation given by the administrator.
+	Affected    int64     `json:"affected"`             // Number of URLs changed or returned.
+	CreatedAt   time.Time `json:"created_at"`           // The moment of the action.
+}
+
+// AuditLogResponseDTO defines the structure of a page of the audit log, newest entries first.
+type AuditLogResponseDTO struct {
+	Entries    []AuditEntryResponseDTO `json:"entries"`               // The entries on the page.
+	NextBefore int64                   `json:"next_before,omitempty"` // Before option of the next page; absent on the last page.
+}
+
+// DeadTaskResponseDTO defines the structure of a background task that ran out of attempts.
+type DeadTaskResponseDTO struct {
+	ID        int64           `json:"id"`         // Identifier of the dead letter, used to replay it.
+	Type      string          `json:"type"`       // Type of the task.
+	Payload   json.RawMessage `json:"payload"`    // The task as it was enqueued.
+	Attempts  int             `json:"attempts"`   // Number of times the task was tried.
+	LastError string          `json:"last_error"` // Error of the last attempt.
+	CreatedAt time.Time       `json:"created_at"` // The moment the task was enqueued.
+	FailedAt  time.Time       `json:"failed_at"`  // The moment the task gave up.
+}
+
+// ReplayTaskResponseDTO defines the structure of the response to replaying a dead letter.
+type ReplayTaskResponseDTO struct {
+	TaskID int64 `json:"task_id"` // Identifier of the task pushed back to the queue.
+}
+
+// WorkerPoolStatsResponseDTO defines the structure of the state of the workers processing the background tasks.
+type WorkerPoolStatsResponseDTO struct {
+	Workers       int                                 `json:"workers"`        // Number of workers.
+	ActiveWorkers int                                 `json:"active_workers"` // Number of workers processing a task.
+	Pending       int                                 `json:"pending"`        // Number of tasks waiting in the durable queue, the claimed ones included.
+	QueueLength   int                                 `json:"queue_length"`   // Number of tasks waiting in memory, such as the periodic ones.
+	QueueCapacity int                                 `json:"queue_capacity"` // Number of tasks the in-memory queue holds.
+	Processed     uint64                              `json:"processed"`      // Number of tasks processed since the start.
+	Errors        uint64                              `json:"errors"`         // Number of tasks that failed since the start.
+	Types         map[string]TaskTypeStatsResponseDTO `json:"types"`          // Statistics by task type.
+}
+
+// TaskTypeStatsResponseDTO defines the structure of the statistics of a task type.
+type TaskTypeStatsResponseDTO struct {
+	Running   int    `json:"running"`   // Number of tasks of the type being processed.
+	Queued    int    `json:"queued"`    // Number of tasks of the type waiting in memory.
+	Limit     int    `json:"limit"`     // Largest number of tasks of the type processed at once, zero if unlimited.
+	Processed uint64 `json:"processed"` // Number of tasks of the type processed since the start.
+	Errors    uint64 `json:"errors"`    // Number of tasks of the type that failed since the start.
+}
+
+// ResizeWorkersRequestDTO defines the structure of a request changing the workers processing the background tasks.
+type ResizeWorkersRequestDTO struct {
+	Workers int            `json:"workers,omitempty"` // New number of workers; unchanged if zero.
+	Limits  map[string]int `json:"limits,omitempty"`  // New concurrency limits by task type, zero removing the limit.
+	Reason  string         `json:"reason"`            // Explanation recorded in the audit log.
+}
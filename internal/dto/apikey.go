@@ -0,0 +1,26 @@
+package dto
+
+import "time"
+
+// CreateAPIKeyRequestDTO defines the structure of the request creating an API key.
+type CreateAPIKeyRequestDTO struct {
+	Name   string   `json:"name"`   // Label telling the key apart from the other keys of the user.
+	Scopes []string `json:"scopes"` // Operations the key is allowed to perform: shorten, read and delete.
+}
+
+// CreateAPIKeyResponseDTO defines the structure of a created API key. The key itself is
+// only returned here; afterwards it cannot be retrieved again.
+type CreateAPIKeyResponseDTO struct {
+	APIKeyResponseDTO
+	Key string `json:"key"` // The API key, sent in the Authorization or X-API-Key header.
+}
+
+// APIKeyResponseDTO defines the structure of an API key of the user, without the key itself.
+type APIKeyResponseDTO struct {
+	ID        string     `json:"id"`                   // Identifier of the key, used to revoke it.
+	Name      string     `json:"name"`                 // Label of the key.
+	Prefix    string     `json:"prefix"`               // Start of the key, telling keys apart.
+	Scopes    []string   `json:"scopes"`               // Operations the key is allowed to perform.
+	CreatedAt time.Time  `json:"created_at"`           // When the key was created.
+	RevokedAt *time.Time `json:"revoked_at,omitempty"` // When the key was revoked, if it was.
+}
@@ -0,0 +1,27 @@
+package dto
+
+import "time"
+
+// BackupHeaderDTO defines the first line of a versioned backup file, describing the records that follow it.
+type BackupHeaderDTO struct {
+	Format    string    `json:"format"`     // Format marker, distinguishing versioned backups from legacy ones.
+	Version   int       `json:"version"`    // Version of the record format.
+	CreatedAt time.Time `json:"created_at"` // Moment the backup was written.
+	Count     int       `json:"count"`      // Number of records following the header.
+	Checksum  string    `json:"checksum"`   // Hex encoded SHA-256 of the record lines, including their newlines.
+}
+
+// BackupRecordDTO defines a single URL stored in a versioned backup file.
+type BackupRecordDTO struct {
+	ID           int        `json:"id,omitempty"`            // Primary key of the URL record.
+	ShortID      string     `json:"short_id"`                // The unique identifier of the shortened URL.
+	OriginalURL  string     `json:"original_url"`            // The original URL.
+	UserID       string     `json:"user_id,omitempty"`       // Identifier of the user who created the URL.
+	CreatedAt    time.Time  `json:"created_at"`              // Moment the URL was created.
+	IsDeleted    bool       `json:"is_deleted,omitempty"`    // Whether the URL is marked as deleted.
+	IsDisabled   bool       `json:"is_disabled,omitempty"`   // Whether an administrator disabled the URL.
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`    // Optional moment after which the URL expires.
+	MaxClicks    *int       `json:"max_clicks,omitempty"`    // Optional number of redirects allowed for the URL.
+	Clicks       int        `json:"clicks,omitempty"`        // Number of redirects counted against MaxClicks.
+	PasswordHash string     `json:"password_hash,omitempty"` // Bcrypt hash of the optional password.
+}
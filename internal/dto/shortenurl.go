@@ -1,8 +1,14 @@
 package dto
 
+import "time"
+
 // ShortenJSONRequestDTO defines the structure of a single shorten URL request payload.
 type ShortenJSONRequestDTO struct {
-	URL string `json:"url"` // The original URL to be shortened.
+	URL       string     `json:"url"`                  // The original URL to be shortened.
+	Alias     string     `json:"alias,omitempty"`      // Optional user-chosen short ID.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Optional moment after which the link expires.
+	MaxClicks *int       `json:"max_clicks,omitempty"` // Optional number of redirects after which the link expires.
+	Password  string     `json:"password,omitempty"`   // Optional password required before redirecting.
 }
 
 // ShortenJSONResponseDTO defines the structure of the response for a single shorten URL request.
@@ -10,7 +16,8 @@ type ShortenJSONResponseDTO struct {
 	Result string `json:"result"` // The shortened URL.
 }
 
-// URLFileDataDTO defines the structure of URL data stored in the file.
+// URLFileDataDTO defines the structure of URL data stored in legacy backup files, written before
+// the versioned format of dto.BackupRecordDTO.
 type URLFileDataDTO struct {
 	UUID        string `json:"uuid"`         // Unique identifier for the URL.
 	ShortURL    string `json:"short_url"`    // The shortened URL.
@@ -19,8 +26,10 @@ type URLFileDataDTO struct {
 
 // BatchShortenRequest represents a single URL shorten request in a batch operation.
 type BatchShortenRequest struct {
-	CorrelationID string `json:"correlation_id"` // Identifier to correlate the request with the response.
-	OriginalURL   string `json:"original_url"`   // The original URL to be shortened.
+	CorrelationID string     `json:"correlation_id"`       // Identifier to correlate the request with the response.
+	OriginalURL   string     `json:"original_url"`         // The original URL to be shortened.
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"` // Optional moment after which the link expires.
+	MaxClicks     *int       `json:"max_clicks,omitempty"` // Optional number of redirects after which the link expires.
 }
 
 // BatchShortenRequestDTO represents a list of batch shorten requests.
@@ -37,12 +46,38 @@ type BatchShortenResponseDTO []BatchShortenResponse
 
 // GetUserURLsResponse defines the structure of a single user's shortened URL entry.
 type GetUserURLsResponse struct {
-	ShortURL    string `json:"short_url"`    // The shortened URL.
-	OriginalURL string `json:"original_url"` // The original URL.
+	ShortURL    string    `json:"short_url"`             // The shortened URL.
+	OriginalURL string    `json:"original_url"`          // The original URL.
+	CreatedAt   time.Time `json:"created_at"`            // The moment the URL was shortened.
+	IsDeleted   bool      `json:"is_deleted"`            // Whether the URL is deleted.
+	IsDisabled  bool      `json:"is_disabled,omitempty"` // Whether the URL was disabled by an administrator.
 }
 
 // GetUserURLsResponseDTO represents a list of user's shortened URL entries.
 type GetUserURLsResponseDTO []GetUserURLsResponse
 
+// GetUserURLsRequestDTO defines the pagination, filter and sort options of a user's URL listing,
+// as given in the query string.
+type GetUserURLsRequestDTO struct {
+	Limit       string // Maximum number of URLs on the page.
+	Cursor      string // Opaque position returned with the previous page.
+	CreatedFrom string // Only URLs created at or after this RFC 3339 time or date.
+	CreatedTo   string // Only URLs created before this RFC 3339 time, or on or before this date.
+	Status      string // Only active or deleted URLs; all if empty.
+	Search      string // Only URLs whose original URL contains this text, ignoring case.
+	Sort        string // Order of the URLs: created_at, -created_at, original_url or -original_url.
+}
+
+// GetUserURLsPageDTO represents a page of a user's URL listing.
+type GetUserURLsPageDTO struct {
+	URLs       GetUserURLsResponseDTO // The URLs on the page.
+	NextCursor string                 // Cursor of the next page; empty on the last page.
+}
+
+// UpdateURLRequestDTO defines the structure of a request changing the destination of a shortened URL.
+type UpdateURLRequestDTO struct {
+	URL string `json:"url"` // The new original URL.
+}
+
 // DeleteURLRequestDTO represents a list of shortened URL IDs to be deleted.
 type DeleteURLRequestDTO []string
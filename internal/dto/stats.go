@@ -0,0 +1,16 @@
+package dto
+
+// URLStatsResponseDTO defines the structure of the click statistics of a shortened URL.
+type URLStatsResponseDTO struct {
+	ShortURL       string           `json:"short_url"`       // The shortened URL.
+	TotalClicks    int64            `json:"total_clicks"`    // Total number of redirects.
+	UniqueVisitors int64            `json:"unique_visitors"` // Number of distinct visitors.
+	Daily          []DailyClicksDTO `json:"daily"`           // Per-day statistics ordered by date.
+}
+
+// DailyClicksDTO defines the structure of the click statistics for a single day.
+type DailyClicksDTO struct {
+	Date           string `json:"date"`            // The day in YYYY-MM-DD format (UTC).
+	Clicks         int64  `json:"clicks"`          // Number of redirects on that day.
+	UniqueVisitors int64  `json:"unique_visitors"` // Number of distinct visitors on that day.
+}
@@ -0,0 +1,26 @@
+package dto
+
+import "time"
+
+// DeleteURLResponseDTO defines the structure of the response to a request deleting URLs.
+type DeleteURLResponseDTO struct {
+	TaskID string `json:"task_id"` // Identifier of the task deleting the URLs, used to follow its progress.
+}
+
+// TaskItemResponseDTO defines the structure of the result of an item of a task.
+type TaskItemResponseDTO struct {
+	ID    string `json:"id"`              // Identifier of the item, such as a short ID.
+	State string `json:"state"`           // State of the item: pending, succeeded or failed.
+	Error string `json:"error,omitempty"` // Reason the item failed, if it did.
+}
+
+// TaskResponseDTO defines the structure of the state of a task enqueued by the user.
+type TaskResponseDTO struct {
+	ID        string                `json:"id"`              // Identifier of the task.
+	Type      string                `json:"type"`            // Type of the task.
+	State     string                `json:"state"`           // State of the task: queued, running, succeeded, failed or partially_failed.
+	Items     []TaskItemResponseDTO `json:"items"`           // Results of the items of the task.
+	Error     string                `json:"error,omitempty"` // Error of the last failed attempt, if any.
+	CreatedAt time.Time             `json:"created_at"`      // The moment the task was enqueued.
+	UpdatedAt time.Time             `json:"updated_at"`      // The moment the state last changed.
+}
@@ -0,0 +1,102 @@
+package idgen
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/bits"
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// CounterGenerator generates IDs from a sequential counter encoded in the alphabet.
+// Without a salt the IDs are the zero-padded counter value. With a salt the counter is
+// mapped through a salt-derived bijection and encoded in a salt-shuffled alphabet, so IDs
+// stay unique until the counter wraps around but do not reveal their order.
+type CounterGenerator struct {
+	alphabet   string
+	length     int
+	counter    atomic.Uint64
+	modulus    uint64 // Number of distinct IDs, or 0 if it exceeds the uint64 range.
+	multiplier uint64
+	offset     uint64
+}
+
+// NewCounterGenerator creates a counter based generator whose first ID encodes start.
+func NewCounterGenerator(alphabet string, length int, salt string, start uint64) (*CounterGenerator, error) {
+	if err := validate(alphabet, length); err != nil {
+		return nil, err
+	}
+	g := &CounterGenerator{alphabet: alphabet, length: length, modulus: capacity(len(alphabet), length), multiplier: 1}
+	if salt != "" {
+		sum := sha256.Sum256([]byte(salt))
+		seed1, seed2 := binary.BigEndian.Uint64(sum[0:8]), binary.BigEndian.Uint64(sum[8:16])
+		g.alphabet = shuffle(alphabet, seed1, seed2)
+		g.multiplier = binary.BigEndian.Uint64(sum[16:24]) | 1
+		g.offset = binary.BigEndian.Uint64(sum[24:32])
+		if g.modulus != 0 {
+			g.multiplier %= g.modulus
+			g.offset %= g.modulus
+			for gcd(g.multiplier, g.modulus) != 1 {
+				g.multiplier = (g.multiplier + 1) % g.modulus
+			}
+		}
+	}
+	g.counter.Store(start)
+	return g, nil
+}
+
+// Generate returns the ID for the next counter value.
+func (g *CounterGenerator) Generate() (string, error) {
+	value := g.counter.Add(1) - 1
+	id := make([]byte, g.length)
+	encode(id, g.permute(value), g.alphabet)
+	return string(id), nil
+}
+
+// IsValid reports whether the ID has the configured length and alphabet.
+func (g *CounterGenerator) IsValid(id string) bool {
+	return isValid(id, g.alphabet, g.length)
+}
+
+// permute maps the counter value to an ID value with (value*multiplier + offset) mod modulus,
+// which is a bijection because the multiplier is coprime with the modulus.
+func (g *CounterGenerator) permute(value uint64) uint64 {
+	if g.modulus == 0 {
+		return value*g.multiplier + g.offset
+	}
+	hi, lo := bits.Mul64(value%g.modulus, g.multiplier)
+	product := bits.Rem64(hi, lo, g.modulus)
+	sum, carry := bits.Add64(product, g.offset, 0)
+	return bits.Rem64(carry, sum, g.modulus)
+}
+
+// capacity returns base^length, or 0 if it does not fit into an uint64.
+func capacity(base, length int) uint64 {
+	result := uint64(1)
+	for i := 0; i < length; i++ {
+		hi, lo := bits.Mul64(result, uint64(base))
+		if hi != 0 {
+			return 0
+		}
+		result = lo
+	}
+	return result
+}
+
+// shuffle returns a permutation of the alphabet determined by the seeds.
+func shuffle(alphabet string, seed1, seed2 uint64) string {
+	chars := []byte(alphabet)
+	random := rand.New(rand.NewPCG(seed1, seed2))
+	random.Shuffle(len(chars), func(i, j int) {
+		chars[i], chars[j] = chars[j], chars[i]
+	})
+	return string(chars)
+}
+
+// gcd returns the greatest common divisor of a and b.
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,77 @@
+package idgen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterGenerator_Plain(t *testing.T) {
+	g, err := NewCounterGenerator(DefaultAlphabet, 6, "", 61)
+	require.NoError(t, err)
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := g.Generate()
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	assert.Equal(t, []string{"aaaaa9", "aaaaba", "aaaabb"}, ids)
+	assert.True(t, g.IsValid("aaaaba"))
+	assert.False(t, g.IsValid("aaaaaba"))
+}
+
+func TestCounterGenerator_Salted(t *testing.T) {
+	g, err := NewCounterGenerator(DefaultAlphabet, 6, "pepper", 0)
+	require.NoError(t, err)
+	other, err := NewCounterGenerator(DefaultAlphabet, 6, "salt", 0)
+	require.NoError(t, err)
+
+	seen := make(map[string]bool)
+	var previous string
+	for i := 0; i < 10000; i++ {
+		id, err := g.Generate()
+		require.NoError(t, err)
+		assert.True(t, g.IsValid(id))
+		assert.False(t, seen[id], "duplicate id %s", id)
+		seen[id] = true
+		if previous != "" {
+			assert.NotEqual(t, previous[:5], id[:5], "consecutive ids share a prefix")
+		}
+		previous = id
+	}
+
+	first, _ := NewCounterGenerator(DefaultAlphabet, 6, "pepper", 0)
+	id, _ := first.Generate()
+	otherID, _ := other.Generate()
+	assert.NotEqual(t, id, otherID, "salts give different ids")
+}
+
+func TestCounterGenerator_Bijection(t *testing.T) {
+	// 16^4 values fit exactly into the id space, so every id appears once.
+	alphabet := "0123456789abcdef"
+	g, err := NewCounterGenerator(alphabet, 4, "pepper", 0)
+	require.NoError(t, err)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 16*16*16*16; i++ {
+		id, _ := g.Generate()
+		seen[id] = true
+	}
+	assert.Len(t, seen, 16*16*16*16)
+}
+
+func TestCounterGenerator_Wide(t *testing.T) {
+	// 62^12 exceeds the uint64 range, so the counter wraps around at 2^64 instead.
+	g, err := NewCounterGenerator(DefaultAlphabet, 12, "pepper", ^uint64(0))
+	require.NoError(t, err)
+	assert.Zero(t, g.modulus)
+
+	last, _ := g.Generate()
+	first, _ := g.Generate()
+	assert.Len(t, last, 12)
+	assert.NotEqual(t, last, first)
+	assert.True(t, strings.Trim(last, g.alphabet) == "")
+}
@@ -0,0 +1,97 @@
+// Package idgen provides the strategies used to generate short IDs:
+//   - random: cryptographically random characters from the alphabet.
+//   - counter: a sequential counter encoded in the alphabet. With a salt the counter
+//     is obfuscated in the spirit of Hashids, so consecutive IDs do not look related.
+//   - time: a millisecond timestamp followed by random characters, so IDs sort by creation time.
+package idgen
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/config"
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+)
+
+// Names of the supported strategies.
+const (
+	StrategyRandom  = "random"
+	StrategyCounter = "counter"
+	StrategyTime    = "time"
+)
+
+// Defaults used when the configured generator cannot be created.
+const (
+	DefaultAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	DefaultLength   = 8
+)
+
+// Bounds of the ID length and alphabet size.
+const (
+	MinLength         = 4
+	MaxLength         = 32
+	MinAlphabetLength = 16
+)
+
+// allowedChars are the characters an alphabet may use, so IDs stay safe in URL paths.
+const allowedChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_"
+
+// New creates the generator selected by cfg.IDStrategy with the configured alphabet and length.
+func New(cfg *config.Config) (interfaces.IIDGenerator, error) {
+	switch cfg.IDStrategy {
+	case StrategyRandom, "":
+		return NewRandomGenerator(cfg.IDAlphabet, cfg.IDLength)
+	case StrategyCounter:
+		// Starting from the current time keeps restarts from reusing recently issued values.
+		return NewCounterGenerator(cfg.IDAlphabet, cfg.IDLength, cfg.IDSalt, uint64(time.Now().UnixMilli()))
+	case StrategyTime:
+		return NewTimeGenerator(cfg.IDAlphabet, cfg.IDLength)
+	default:
+		return nil, fmt.Errorf("unknown id strategy: %s", cfg.IDStrategy)
+	}
+}
+
+// validate checks that the alphabet has enough distinct URL-safe characters and the length is in bounds.
+func validate(alphabet string, length int) error {
+	if length < MinLength || length > MaxLength {
+		return fmt.Errorf("invalid id length %d, must be between %d and %d", length, MinLength, MaxLength)
+	}
+	if len(alphabet) < MinAlphabetLength {
+		return fmt.Errorf("id alphabet must have at least %d characters", MinAlphabetLength)
+	}
+	seen := make(map[rune]bool, len(alphabet))
+	for _, char := range alphabet {
+		if !strings.ContainsRune(allowedChars, char) {
+			return fmt.Errorf("invalid id alphabet character %q", char)
+		}
+		if seen[char] {
+			return errors.New("id alphabet characters must be unique")
+		}
+		seen[char] = true
+	}
+	return nil
+}
+
+// isValid reports whether the ID has the given length and uses only characters of the alphabet.
+func isValid(id, alphabet string, length int) bool {
+	if len(id) != length {
+		return false
+	}
+	for _, char := range id {
+		if !strings.ContainsRune(alphabet, char) {
+			return false
+		}
+	}
+	return true
+}
+
+// encode writes value in base len(alphabet) into buf, most significant digit first, padding with the zero digit.
+func encode(buf []byte, value uint64, alphabet string) {
+	base := uint64(len(alphabet))
+	for i := len(buf) - 1; i >= 0; i-- {
+		buf[i] = alphabet[value%base]
+		value /= base
+	}
+}
@@ -0,0 +1,66 @@
+package idgen
+
+import (
+	"testing"
+
+	"github.com/GlebRadaev/shlink/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		length   int
+		wantType interface{}
+		wantErr  string
+	}{
+		{name: "default strategy", strategy: "", length: 8, wantType: &RandomGenerator{}},
+		{name: "random", strategy: StrategyRandom, length: 8, wantType: &RandomGenerator{}},
+		{name: "counter", strategy: StrategyCounter, length: 8, wantType: &CounterGenerator{}},
+		{name: "time", strategy: StrategyTime, length: 12, wantType: &TimeGenerator{}},
+		{name: "time with too short length", strategy: StrategyTime, length: 8, wantErr: "id length 8 is too short for time-sortable ids, need at least 9"},
+		{name: "unknown strategy", strategy: "uuid", length: 8, wantErr: "unknown id strategy: uuid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{IDStrategy: tt.strategy, IDAlphabet: DefaultAlphabet, IDLength: tt.length}
+			got, err := New(cfg)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.IsType(t, tt.wantType, got)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		alphabet string
+		length   int
+		wantErr  string
+	}{
+		{name: "default alphabet", alphabet: DefaultAlphabet, length: DefaultLength},
+		{name: "alphabet with dash and underscore", alphabet: "0123456789abcdef-_", length: 10},
+		{name: "too short", alphabet: DefaultAlphabet, length: 3, wantErr: "invalid id length 3, must be between 4 and 32"},
+		{name: "too long", alphabet: DefaultAlphabet, length: 33, wantErr: "invalid id length 33, must be between 4 and 32"},
+		{name: "small alphabet", alphabet: "0123456789", length: 8, wantErr: "id alphabet must have at least 16 characters"},
+		{name: "unsafe character", alphabet: "0123456789abcdef/", length: 8, wantErr: `invalid id alphabet character '/'`},
+		{name: "duplicate character", alphabet: "0123456789abcdeff", length: 8, wantErr: "id alphabet characters must be unique"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate(tt.alphabet, tt.length)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
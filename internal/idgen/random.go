@@ -0,0 +1,58 @@
+package idgen
+
+import (
+	"crypto/rand"
+	"io"
+)
+
+// RandomGenerator generates IDs of cryptographically random characters.
+type RandomGenerator struct {
+	alphabet string
+	length   int
+	random   io.Reader
+}
+
+// NewRandomGenerator creates a generator of random IDs with the given alphabet and length.
+func NewRandomGenerator(alphabet string, length int) (*RandomGenerator, error) {
+	if err := validate(alphabet, length); err != nil {
+		return nil, err
+	}
+	return &RandomGenerator{alphabet: alphabet, length: length, random: rand.Reader}, nil
+}
+
+// Generate returns a new random ID.
+func (g *RandomGenerator) Generate() (string, error) {
+	id := make([]byte, g.length)
+	if err := randomChars(g.random, id, g.alphabet); err != nil {
+		return "", err
+	}
+	return string(id), nil
+}
+
+// IsValid reports whether the ID has the configured length and alphabet.
+func (g *RandomGenerator) IsValid(id string) bool {
+	return isValid(id, g.alphabet, g.length)
+}
+
+// randomChars fills buf with uniformly distributed characters of the alphabet.
+// Bytes above the largest multiple of the alphabet size are rejected to avoid modulo bias.
+func randomChars(random io.Reader, buf []byte, alphabet string) error {
+	limit := 256 - 256%len(alphabet)
+	chunk := make([]byte, len(buf)*2)
+	for filled := 0; filled < len(buf); {
+		if _, err := io.ReadFull(random, chunk); err != nil {
+			return err
+		}
+		for _, b := range chunk {
+			if int(b) >= limit {
+				continue
+			}
+			buf[filled] = alphabet[int(b)%len(alphabet)]
+			filled++
+			if filled == len(buf) {
+				break
+			}
+		}
+	}
+	return nil
+}
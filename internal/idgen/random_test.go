@@ -0,0 +1,44 @@
+package idgen
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRandomGenerator(t *testing.T) {
+	g, err := NewRandomGenerator(DefaultAlphabet, 10)
+	require.NoError(t, err)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id, err := g.Generate()
+		require.NoError(t, err)
+		assert.Len(t, id, 10)
+		assert.True(t, g.IsValid(id))
+		assert.False(t, seen[id], "duplicate id %s", id)
+		seen[id] = true
+	}
+
+	assert.False(t, g.IsValid("abc"), "wrong length")
+	assert.False(t, g.IsValid("abcdefgh-_"), "characters outside the alphabet")
+}
+
+func TestRandomGenerator_ReadError(t *testing.T) {
+	g, err := NewRandomGenerator(DefaultAlphabet, 8)
+	require.NoError(t, err)
+	g.random = bytes.NewReader(nil)
+
+	_, err = g.Generate()
+	assert.Error(t, err)
+}
+
+func TestRandomChars_RejectsBiasedBytes(t *testing.T) {
+	// With 62 characters bytes from 248 on are rejected.
+	buf := make([]byte, 3)
+	err := randomChars(bytes.NewReader([]byte{255, 0, 248, 61, 62, 9}), buf, DefaultAlphabet)
+	require.NoError(t, err)
+	assert.Equal(t, "a9a", string(buf))
+}
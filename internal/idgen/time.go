@@ -0,0 +1,65 @@
+package idgen
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// epoch is the moment time-sortable IDs count from; their timestamp covers epochSpan from it.
+var (
+	epoch     = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	epochSpan = 100 * 365 * 24 * time.Hour
+)
+
+// minRandomChars is the least number of random characters following the timestamp.
+const minRandomChars = 2
+
+// TimeGenerator generates IDs that start with the creation time in milliseconds,
+// followed by random characters. The alphabet is sorted, so IDs sort by creation time.
+type TimeGenerator struct {
+	alphabet  string
+	length    int
+	timeChars int
+	random    io.Reader
+	now       func() time.Time
+}
+
+// NewTimeGenerator creates a generator of time-sortable IDs with the given alphabet and length.
+// The length must leave room for the timestamp and at least two random characters.
+func NewTimeGenerator(alphabet string, length int) (*TimeGenerator, error) {
+	if err := validate(alphabet, length); err != nil {
+		return nil, err
+	}
+	chars := []byte(alphabet)
+	sort.Slice(chars, func(i, j int) bool { return chars[i] < chars[j] })
+	timeChars := 1
+	for span := uint64(epochSpan.Milliseconds()) / uint64(len(chars)); span > 0; span /= uint64(len(chars)) {
+		timeChars++
+	}
+	if length < timeChars+minRandomChars {
+		return nil, fmt.Errorf("id length %d is too short for time-sortable ids, need at least %d", length, timeChars+minRandomChars)
+	}
+	return &TimeGenerator{alphabet: string(chars), length: length, timeChars: timeChars, random: rand.Reader, now: time.Now}, nil
+}
+
+// Generate returns a new ID for the current time.
+func (g *TimeGenerator) Generate() (string, error) {
+	elapsed := g.now().Sub(epoch).Milliseconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	id := make([]byte, g.length)
+	encode(id[:g.timeChars], uint64(elapsed), g.alphabet)
+	if err := randomChars(g.random, id[g.timeChars:], g.alphabet); err != nil {
+		return "", err
+	}
+	return string(id), nil
+}
+
+// IsValid reports whether the ID has the configured length and alphabet.
+func (g *TimeGenerator) IsValid(id string) bool {
+	return isValid(id, g.alphabet, g.length)
+}
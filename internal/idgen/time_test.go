@@ -0,0 +1,44 @@
+package idgen
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeGenerator(t *testing.T) {
+	g, err := NewTimeGenerator(DefaultAlphabet, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 7, g.timeChars)
+
+	now := time.Date(2026, time.October, 16, 12, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return now }
+
+	var ids []string
+	for i := 0; i < 100; i++ {
+		now = now.Add(time.Duration(i%3) * time.Millisecond)
+		id, err := g.Generate()
+		require.NoError(t, err)
+		assert.Len(t, id, 10)
+		assert.True(t, g.IsValid(id))
+		ids = append(ids, id)
+	}
+	for i := 1; i < len(ids); i++ {
+		assert.LessOrEqual(t, ids[i-1][:g.timeChars], ids[i][:g.timeChars], "ids sort by creation time")
+	}
+	sorted := sort.SliceIsSorted(ids, func(i, j int) bool { return ids[i][:g.timeChars] < ids[j][:g.timeChars] })
+	assert.True(t, sorted)
+}
+
+func TestTimeGenerator_BeforeEpoch(t *testing.T) {
+	g, err := NewTimeGenerator(DefaultAlphabet, 9)
+	require.NoError(t, err)
+	g.now = func() time.Time { return epoch.Add(-time.Hour) }
+
+	id, err := g.Generate()
+	require.NoError(t, err)
+	assert.Equal(t, "0000000", id[:7])
+}
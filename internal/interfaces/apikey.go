@@ -0,0 +1,27 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// IAPIKeyRepository defines the interface for API key data access operations.
+type IAPIKeyRepository interface {
+	// Insert adds a new API key to the repository.
+	// Returns an error if the operation fails.
+	Insert(ctx context.Context, key *model.APIKey) error
+
+	// FindByHash retrieves an API key, revoked or not, by the hash of the key.
+	// Returns nil without an error if no key has the hash.
+	FindByHash(ctx context.Context, keyHash string) (*model.APIKey, error)
+
+	// FindListByUserID retrieves the API keys of a user, revoked ones included, ordered by creation time.
+	// Returns a slice of API key models or an error if retrieval fails.
+	FindListByUserID(ctx context.Context, userID string) ([]*model.APIKey, error)
+
+	// Revoke marks the active API key of the user with the given ID as revoked at the given moment.
+	// Returns false if the user has no such active key, or an error if the operation fails.
+	Revoke(ctx context.Context, userID, id string, revokedAt time.Time) (bool, error)
+}
@@ -0,0 +1,18 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// IAuditRepository defines the interface for the audit log of administrator actions.
+type IAuditRepository interface {
+	// Insert appends an entry to the audit log, setting its ID.
+	// Returns an error if the operation fails.
+	Insert(ctx context.Context, entry *model.AuditEntry) error
+
+	// FindList retrieves the entries matching the query, newest first.
+	// Returns a slice of audit entries or an error if retrieval fails.
+	FindList(ctx context.Context, query model.AuditQuery) ([]*model.AuditEntry, error)
+}
@@ -0,0 +1,18 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// IClickRepository defines the interface for click analytics data access operations.
+type IClickRepository interface {
+	// InsertList adds multiple click events to the repository in a single operation.
+	// Returns an error if the operation fails.
+	InsertList(ctx context.Context, clicks []*model.Click) error
+
+	// GetStats aggregates the click events of a shortened URL.
+	// Returns the statistics, which are empty if no clicks were recorded, or an error if retrieval fails.
+	GetStats(ctx context.Context, shortID string) (*model.ClickStats, error)
+}
@@ -0,0 +1,11 @@
+package interfaces
+
+import "errors"
+
+// Errors returned by repositories.
+var (
+	// ErrShortIDTaken is returned when a short ID is already used by another URL.
+	ErrShortIDTaken = errors.New("short ID already taken")
+	// ErrOriginalURLTaken is returned when an original URL is already shortened under another short ID.
+	ErrOriginalURLTaken = errors.New("original URL already taken")
+)
@@ -0,0 +1,18 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// IIdentityRepository defines the interface for accessing the accounts linked to users.
+type IIdentityRepository interface {
+	// FindOrInsert stores the identity unless its issuer and subject are already linked to a user.
+	// Returns the stored identity, which keeps the user ID of an existing link, or an error.
+	FindOrInsert(ctx context.Context, identity *model.UserIdentity) (*model.UserIdentity, error)
+
+	// FindByUserID retrieves an identity linked to the user.
+	// Returns nil without an error if no account is linked to the user.
+	FindByUserID(ctx context.Context, userID string) (*model.UserIdentity, error)
+}
@@ -0,0 +1,11 @@
+package interfaces
+
+// IIDGenerator generates short IDs for new URLs and recognizes IDs it could have generated.
+type IIDGenerator interface {
+	// Generate returns a new short ID. IDs are unique with high probability,
+	// so callers retry with a fresh ID when the stored one is already taken.
+	Generate() (string, error)
+
+	// IsValid reports whether the ID has the length and alphabet produced by the generator.
+	IsValid(id string) bool
+}
@@ -0,0 +1,22 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// IJournaledRepository is implemented by URL repositories that record their changes in a
+// write-ahead journal on top of a snapshot kept by the backup service.
+type IJournaledRepository interface {
+	// Restore replaces the repository content with the snapshot and replays the journal on top of it.
+	// Returns the number of replayed journal entries or an error.
+	Restore(ctx context.Context, snapshot []*model.URL) (int, error)
+
+	// Compact passes the repository content to save as a fresh snapshot and truncates the journal once it is saved.
+	// Returns an error if saving or truncating fails.
+	Compact(ctx context.Context, save func([]*model.URL) error) error
+
+	// Close syncs and closes the journal.
+	Close() error
+}
@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"context"
+	"time"
 
 	"github.com/GlebRadaev/shlink/internal/model"
 )
@@ -20,14 +21,40 @@ type IURLRepository interface {
 	// Returns the corresponding URL model or an error if not found.
 	FindByID(ctx context.Context, shortID string) (*model.URL, error)
 
-	// FindListByUserID retrieves all URL entries associated with a specific user.
+	// FindListByUserID retrieves the URL entries of a specific user that match the query filters,
+	// in the query order, starting after the query cursor and limited to the query limit.
 	// Returns a slice of URL models or an error if retrieval fails.
-	FindListByUserID(ctx context.Context, userID string) ([]*model.URL, error)
+	FindListByUserID(ctx context.Context, userID string, query model.URLListQuery) ([]*model.URL, error)
+
+	// UpdateOriginalURL changes the original URL of a non-deleted URL entry owned by the user.
+	// Returns false if no such entry exists, or an error wrapping ErrOriginalURLTaken
+	// if the new original URL is already shortened under another short identifier.
+	UpdateOriginalURL(ctx context.Context, userID, shortID, originalURL string) (bool, error)
 
 	// DeleteListByUserIDAndShortIDs removes multiple URL entries for a specific user
 	// based on their user ID and a list of short identifiers. Returns an error if the operation fails.
 	DeleteListByUserIDAndShortIDs(ctx context.Context, userID string, shortIDs []string) error
 
+	// ReassignUserID moves the non-deleted URL entries of one user to another user.
+	// Returns the number of moved entries or an error if the operation fails.
+	ReassignUserID(ctx context.Context, fromUserID, toUserID string) (int64, error)
+
+	// SetDisabled marks the URL entries with the short identifiers as disabled or enabled again.
+	// Returns the number of entries whose state changed or an error if the operation fails.
+	SetDisabled(ctx context.Context, shortIDs []string, disabled bool) (int64, error)
+
+	// FindListByDomain retrieves the non-deleted URL entries whose original URL points to the domain
+	// or one of its subdomains. Returns a slice of URL models or an error if retrieval fails.
+	FindListByDomain(ctx context.Context, domain string) ([]*model.URL, error)
+
+	// IncrementClicks counts a redirect against the click budget of the URL.
+	// Returns false if the URL has no clicks left, or an error if the operation fails.
+	IncrementClicks(ctx context.Context, shortID string) (bool, error)
+
+	// DeleteExpired soft deletes all URLs that expired by date or click budget at the given moment.
+	// Returns the number of URLs marked as deleted or an error if the operation fails.
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+
 	// List retrieves all URL entries from the repository.
 	// Returns a slice of URL models or an error if retrieval fails.
 	List(ctx context.Context) ([]*model.URL, error)
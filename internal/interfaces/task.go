@@ -0,0 +1,99 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// ITaskRepository defines the interface for the durable queue of background tasks and its dead letters.
+//
+// A claimed task is not removed from the queue: it is hidden from other claims until its lease
+// expires, so that a task claimed by a worker that crashed is claimed again. The lease is extended
+// while the task runs.
+type ITaskRepository interface {
+	// Push adds a task to the queue, setting its ID. A task with a fair key has its fair time moved
+	// forward by as much as the latest fair time of the queued tasks of the key is after its run time,
+	// so that the tasks of a key follow each other in fair time; the fair time is set to the result.
+	// Returns an error if the operation fails.
+	Push(ctx context.Context, task *model.QueuedTask) error
+
+	// Claim takes the task due at now of the highest priority and, among those, of the earliest fair
+	// time, skipping the tasks of the types in skipTypes and of the fair keys in skipKeys, counts the
+	// attempt and hides the task from other claims for the lease. Tasks without a fair key are never
+	// skipped by skipKeys.
+	// Returns nil if no task is due, or an error if the operation fails.
+	Claim(ctx context.Context, now time.Time, lease time.Duration, skipTypes, skipKeys []string) (*model.QueuedTask, error)
+
+	// Extend moves the end of the lease of a claimed task to until, for a task whose handler still runs.
+	// Returns whether the task is still in the queue, or an error if the operation fails.
+	Extend(ctx context.Context, id int64, until time.Time) (bool, error)
+
+	// Count returns the number of tasks in the queue, the claimed ones included.
+	// Returns an error if the operation fails.
+	Count(ctx context.Context) (int, error)
+
+	// Complete removes a claimed task from the queue.
+	// Returns an error if the operation fails.
+	Complete(ctx context.Context, id int64) error
+
+	// Retry releases a claimed task to be claimed again from runAt, recording the error of the attempt.
+	// Returns an error if the operation fails.
+	Retry(ctx context.Context, id int64, runAt time.Time, lastError string) error
+
+	// Release releases a claimed task to be claimed again from runAt without counting its attempt,
+	// for a task whose handler was interrupted rather than failed.
+	// Returns an error if the operation fails.
+	Release(ctx context.Context, id int64, runAt time.Time) error
+
+	// Bury moves a claimed task from the queue to the dead letters, recording the error of its last attempt
+	// and keeping its priority and fair key.
+	// Returns an error if the operation fails.
+	Bury(ctx context.Context, id int64, lastError string, failedAt time.Time) error
+
+	// FindDeadList retrieves the dead letters, newest first, at most limit of them unless limit is zero.
+	// Returns a slice of dead letters or an error if retrieval fails.
+	FindDeadList(ctx context.Context, limit int) ([]*model.DeadTask, error)
+
+	// Replay moves a dead letter back to the queue with no attempts, due from runAt, with its priority and
+	// fair key. Its fair time is runAt, moved forward as in Push.
+	// Returns the queued task, nil if no dead letter has the ID, or an error if the operation fails.
+	Replay(ctx context.Context, id int64, runAt time.Time) (*model.QueuedTask, error)
+}
+
+// ITaskStatusRepository defines the interface for the states of the tasks enqueued on behalf of users.
+type ITaskStatusRepository interface {
+	// Insert adds the status of a new task.
+	// Returns an error if the operation fails.
+	Insert(ctx context.Context, status *model.TaskStatus) error
+
+	// FindByID retrieves the status of a task by its ID.
+	// Returns the status, nil if no task has the ID, or an error if retrieval fails.
+	FindByID(ctx context.Context, id string) (*model.TaskStatus, error)
+
+	// Update replaces the state, items, error and update time of a task.
+	// Returns an error if the operation fails.
+	Update(ctx context.Context, status *model.TaskStatus) error
+}
+
+// IJobLockRepository defines the interface for the locks making sure that each run of a recurring
+// job is done by a single instance of the service, and that runs of a job never overlap.
+//
+// A lock is held for a lease, renewed while the job runs, so that the lock of an instance that
+// crashed expires.
+type IJobLockRepository interface {
+	// Acquire takes the lock of the job for owner until now plus the lease, for the run due at runAt.
+	// It fails if the lock is held by an owner whose lease has not expired, or if the run at runAt
+	// or a later one was already taken.
+	// Returns whether the lock was acquired, or an error if the operation fails.
+	Acquire(ctx context.Context, name, owner string, runAt, now time.Time, lease time.Duration) (bool, error)
+
+	// Extend moves the expiry of the lock of the job held by owner to until.
+	// Returns whether owner still held the lock, or an error if the operation fails.
+	Extend(ctx context.Context, name, owner string, until time.Time) (bool, error)
+
+	// Release releases the lock of the job held by owner, at now.
+	// Returns an error if the operation fails.
+	Release(ctx context.Context, name, owner string, now time.Time) error
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/service/backup"
+)
+
+// instrumentedBackup observes the duration of the operations of a backup service.
+type instrumentedBackup struct {
+	backup  backup.IBackupService
+	metrics *Metrics
+}
+
+// InstrumentBackup returns a backup service that records the durations of the loads and saves
+// of b. Without metrics b is returned as is.
+func (m *Metrics) InstrumentBackup(b backup.IBackupService) backup.IBackupService {
+	if m == nil {
+		return b
+	}
+	return &instrumentedBackup{backup: b, metrics: m}
+}
+
+// LoadData loads the backup and records how long it took.
+func (b *instrumentedBackup) LoadData() ([]*model.URL, error) {
+	start := time.Now()
+	urls, err := b.backup.LoadData()
+	b.metrics.ObserveBackup("load", time.Since(start), err)
+	return urls, err
+}
+
+// SaveData saves the backup and records how long it took.
+func (b *instrumentedBackup) SaveData(urls []*model.URL) error {
+	start := time.Now()
+	err := b.backup.SaveData(urls)
+	b.metrics.ObserveBackup("save", time.Since(start), err)
+	return err
+}
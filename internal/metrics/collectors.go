@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"github.com/GlebRadaev/shlink/internal/repository/cache"
+	"github.com/GlebRadaev/shlink/internal/taskmanager"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// WorkerPoolStats is implemented by the worker pool to report its monitoring data.
+type WorkerPoolStats interface {
+	Stats() taskmanager.MonitoringData
+}
+
+// RegisterWorkerPool adds the queue length, workers, active workers, processed tasks and task errors of the pool.
+func (m *Metrics) RegisterWorkerPool(pool WorkerPoolStats) {
+	if m == nil {
+		return
+	}
+	opts := func(name, help string) prometheus.Opts {
+		return prometheus.Opts{Namespace: Namespace, Subsystem: "worker_pool", Name: name, Help: help}
+	}
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts(opts("queue_length", "Number of tasks waiting in the queue.")),
+			func() float64 { return float64(pool.Stats().QueueLength) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts(opts("workers", "Number of workers in the pool.")),
+			func() float64 { return float64(pool.Stats().Workers) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts(opts("active_workers", "Number of workers processing a task.")),
+			func() float64 { return float64(pool.Stats().ActiveWorkers) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts(opts("tasks_processed_total", "Number of processed tasks.")),
+			func() float64 { return float64(pool.Stats().Processed) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts(opts("task_errors_total", "Number of tasks whose handler failed.")),
+			func() float64 { return float64(pool.Stats().Errors) }),
+	)
+}
+
+// RegisterDBPool adds the connection and acquire statistics of the pgx connection pool.
+func (m *Metrics) RegisterDBPool(pool *pgxpool.Pool) {
+	if m == nil || pool == nil {
+		return
+	}
+	opts := func(name, help string) prometheus.Opts {
+		return prometheus.Opts{Namespace: Namespace, Subsystem: "db_pool", Name: name, Help: help}
+	}
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts(opts("acquired_connections", "Number of connections in use.")),
+			func() float64 { return float64(pool.Stat().AcquiredConns()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts(opts("idle_connections", "Number of idle connections.")),
+			func() float64 { return float64(pool.Stat().IdleConns()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts(opts("total_connections", "Number of open connections.")),
+			func() float64 { return float64(pool.Stat().TotalConns()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts(opts("max_connections", "Maximum number of connections.")),
+			func() float64 { return float64(pool.Stat().MaxConns()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts(opts("acquires_total", "Number of connections acquired from the pool.")),
+			func() float64 { return float64(pool.Stat().AcquireCount()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts(opts("empty_acquires_total", "Number of acquires that waited for a connection.")),
+			func() float64 { return float64(pool.Stat().EmptyAcquireCount()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts(opts("canceled_acquires_total", "Number of acquires cancelled by their context.")),
+			func() float64 { return float64(pool.Stat().CanceledAcquireCount()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts(opts("acquire_duration_seconds_total", "Total time spent acquiring connections.")),
+			func() float64 { return pool.Stat().AcquireDuration().Seconds() }),
+	)
+}
+
+// RegisterURLCache adds the hit, miss and eviction counters and the size of the URL cache.
+func (m *Metrics) RegisterURLCache(urlCache *cache.URLRepository) {
+	if m == nil || urlCache == nil {
+		return
+	}
+	opts := func(name, help string) prometheus.Opts {
+		return prometheus.Opts{Namespace: Namespace, Subsystem: "url_cache", Name: name, Help: help}
+	}
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts(opts("hits_total", "Number of lookups answered from the cache.")),
+			func() float64 { return float64(urlCache.Stats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts(opts("misses_total", "Number of lookups passed on to the database.")),
+			func() float64 { return float64(urlCache.Stats().Misses) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts(opts("evictions_total", "Number of entries evicted to stay within the size.")),
+			func() float64 { return float64(urlCache.Stats().Evictions) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts(opts("size", "Number of cached entries.")),
+			func() float64 { return float64(urlCache.Stats().Size) }),
+	)
+}
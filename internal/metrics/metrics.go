@@ -0,0 +1,142 @@
+// Package metrics exposes Prometheus metrics of the service.
+//
+// Metrics holds its own registry with the HTTP request counters and latency histograms
+// per chi route pattern, redirect and shorten outcomes, backup durations, and the Go
+// runtime and process metrics. The worker pool, the pgx connection pool and the URL
+// cache are added with the Register methods and read when the registry is scraped.
+//
+// All methods of a nil *Metrics do nothing, so components can be used without metrics.
+//
+// Example usage:
+//
+//	m := metrics.New()
+//	m.RegisterWorkerPool(pool)
+//	router.Use(m.Middleware)
+//	router.Handle("/metrics", m.Handler())
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes the names of all metrics of the service.
+const Namespace = "shlink"
+
+// Outcomes of a redirect request, used as the result label of the redirects counter.
+const (
+	RedirectFound        = "found"
+	RedirectGone         = "gone"
+	RedirectUnauthorized = "unauthorized"
+	RedirectThrottled    = "throttled"
+	RedirectInvalid      = "invalid"
+)
+
+// Outcomes of a shorten request, used as the result label of the shorten counter.
+const (
+	ShortenSuccess  = "success"
+	ShortenConflict = "conflict"
+	ShortenInvalid  = "invalid"
+)
+
+// Metrics holds the registry and the collectors of the service.
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	redirects       *prometheus.CounterVec
+	shortens        *prometheus.CounterVec
+	backupDuration  *prometheus.HistogramVec
+}
+
+// New creates the metrics of the service in a new registry.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of HTTP requests by method and route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		redirects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "redirects_total",
+			Help:      "Number of redirect requests by result.",
+		}, []string{"result"}),
+		shortens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "shorten_requests_total",
+			Help:      "Number of shorten requests by result.",
+		}, []string{"result"}),
+		backupDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "backup_duration_seconds",
+			Help:      "Duration of loading and saving the backup file by operation and result.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+		}, []string{"operation", "result"}),
+	}
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.redirects,
+		m.shortens,
+		m.backupDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler returns the HTTP handler serving the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	if m == nil {
+		return http.NotFoundHandler()
+	}
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Registry returns the registry holding the metrics.
+func (m *Metrics) Registry() *prometheus.Registry {
+	if m == nil {
+		return nil
+	}
+	return m.registry
+}
+
+// ObserveRedirect counts a redirect request with one of the Redirect* results.
+func (m *Metrics) ObserveRedirect(result string) {
+	if m == nil {
+		return
+	}
+	m.redirects.WithLabelValues(result).Inc()
+}
+
+// ObserveShorten counts a shorten request with one of the Shorten* results.
+func (m *Metrics) ObserveShorten(result string) {
+	if m == nil {
+		return
+	}
+	m.shortens.WithLabelValues(result).Inc()
+}
+
+// ObserveBackup records how long loading or saving the backup took and whether it failed.
+func (m *Metrics) ObserveBackup(operation string, duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	m.backupDuration.WithLabelValues(operation, result).Observe(duration.Seconds())
+}
@@ -0,0 +1,134 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/metrics"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/repository/cache"
+	"github.com/GlebRadaev/shlink/internal/repository/inmemory"
+	"github.com/GlebRadaev/shlink/internal/service/backup"
+	"github.com/GlebRadaev/shlink/internal/taskmanager"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// scrape returns the metrics served by the handler of m in the text exposition format.
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+type stubPool struct {
+	stats taskmanager.MonitoringData
+}
+
+func (p stubPool) Stats() taskmanager.MonitoringData {
+	return p.stats
+}
+
+func TestMetrics_Middleware(t *testing.T) {
+	m := metrics.New()
+	router := chi.NewRouter()
+	router.Use(m.Middleware)
+	router.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	})
+	router.Get("/ping", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	for _, path := range []string{"/abc123", "/xyz789", "/ping", "/some/unknown/path"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `shlink_http_requests_total{method="GET",route="/{id}",status="307"} 2`)
+	assert.Contains(t, body, `shlink_http_requests_total{method="GET",route="/ping",status="200"} 1`)
+	assert.Contains(t, body, `shlink_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `shlink_http_request_duration_seconds_count{method="GET",route="/{id}"} 2`)
+	assert.Contains(t, body, "go_goroutines")
+}
+
+func TestMetrics_Outcomes(t *testing.T) {
+	m := metrics.New()
+	ctrl := gomock.NewController(t)
+	mockBackup := backup.NewMockIBackupService(ctrl)
+	mockBackup.EXPECT().LoadData().Return([]*model.URL{}, nil)
+	mockBackup.EXPECT().SaveData(gomock.Any()).Return(errors.New("disk full"))
+
+	m.ObserveRedirect(metrics.RedirectFound)
+	m.ObserveRedirect(metrics.RedirectFound)
+	m.ObserveRedirect(metrics.RedirectGone)
+	m.ObserveShorten(metrics.ShortenSuccess)
+	m.ObserveShorten(metrics.ShortenConflict)
+	m.ObserveShorten(metrics.ShortenInvalid)
+	instrumented := m.InstrumentBackup(mockBackup)
+	_, err := instrumented.LoadData()
+	require.NoError(t, err)
+	assert.EqualError(t, instrumented.SaveData(nil), "disk full")
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `shlink_redirects_total{result="found"} 2`)
+	assert.Contains(t, body, `shlink_redirects_total{result="gone"} 1`)
+	assert.Contains(t, body, `shlink_shorten_requests_total{result="success"} 1`)
+	assert.Contains(t, body, `shlink_shorten_requests_total{result="conflict"} 1`)
+	assert.Contains(t, body, `shlink_shorten_requests_total{result="invalid"} 1`)
+	assert.Contains(t, body, `shlink_backup_duration_seconds_count{operation="load",result="success"} 1`)
+	assert.Contains(t, body, `shlink_backup_duration_seconds_count{operation="save",result="error"} 1`)
+}
+
+func TestMetrics_Register(t *testing.T) {
+	m := metrics.New()
+	m.RegisterWorkerPool(stubPool{stats: taskmanager.MonitoringData{QueueLength: 3, Workers: 4, Processed: 10, Errors: 2, ActiveWorkers: 1}})
+	urlCache := cache.NewURLRepository(inmemory.NewMemoryStorage(), 10, time.Minute, time.Minute)
+	m.RegisterURLCache(urlCache)
+	m.RegisterDBPool(nil)
+
+	_, err := urlCache.FindByID(context.Background(), "abc123")
+	require.NoError(t, err)
+	_, err = urlCache.FindByID(context.Background(), "abc123")
+	require.NoError(t, err)
+
+	body := scrape(t, m)
+	assert.Contains(t, body, "shlink_worker_pool_queue_length 3")
+	assert.Contains(t, body, "shlink_worker_pool_active_workers 1")
+	assert.Contains(t, body, "shlink_worker_pool_workers 4")
+	assert.Contains(t, body, "shlink_worker_pool_tasks_processed_total 10")
+	assert.Contains(t, body, "shlink_worker_pool_task_errors_total 2")
+	assert.Contains(t, body, "shlink_url_cache_hits_total 1")
+	assert.Contains(t, body, "shlink_url_cache_misses_total 1")
+	assert.Contains(t, body, "shlink_url_cache_size 1")
+	assert.NotContains(t, body, "shlink_db_pool")
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	var m *metrics.Metrics
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	assert.NotPanics(t, func() {
+		m.ObserveRedirect(metrics.RedirectFound)
+		m.ObserveShorten(metrics.ShortenSuccess)
+		m.ObserveBackup("save", time.Second, nil)
+		m.RegisterWorkerPool(stubPool{})
+		m.Middleware(next)
+	})
+	b := backup.NewBackupService("storage.txt")
+	assert.Same(t, b, m.InstrumentBackup(b))
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,153 @@
+// Package auth identifies the user making a request, so that handlers read the user from
+// the request context instead of the cookie.
+//
+// The Authenticator middleware accepts an API key in the Authorization header as a Bearer
+// token or in the X-API-Key header. A request presenting a key that is unknown or revoked is
+// rejected with 401 Unauthorized; a valid key identifies the user it belongs to, limited to
+// the scopes of the key. Without a key the user is taken from the JWT cookie, which is
+// re-issued when it is about to expire. Requests with neither carry no identity; routes that
+// create anonymous users add EnsureUser, which sets a cookie with a new user ID.
+//
+// Users listed as administrators act as administrators with the cookie, and with API keys
+// granted the admin scope.
+//
+// Example usage:
+//
+//	authenticator := auth.NewAuthenticator(apiKeyService, cfg.AdminUserIDs, log)
+//	router.With(authenticator.Middleware, auth.EnsureUser).Post("/api/shorten", handler)
+//	identity, ok := auth.FromContext(r.Context())
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/service/apikey"
+	"github.com/GlebRadaev/shlink/internal/utils"
+	"go.uber.org/zap"
+)
+
+// APIKeyHeader is the header carrying an API key, as an alternative to the Authorization header.
+const APIKeyHeader = "X-API-Key"
+
+// bearerPrefix starts the Authorization header carrying an API key.
+const bearerPrefix = "Bearer "
+
+// Identity is the user making a request.
+type Identity struct {
+	UserID string        // Identifier of the user.
+	APIKey *model.APIKey // Key the request was authenticated with, nil if it was the cookie.
+	Admin  bool          // Whether the request may use the admin API.
+}
+
+// HasScope reports whether the request may perform the operations of the scope.
+// Requests authenticated by the cookie have all scopes.
+func (i Identity) HasScope(scope string) bool {
+	return i.APIKey == nil || i.APIKey.HasScope(scope)
+}
+
+// KeyAuthenticator looks up the active API key matching a presented key.
+type KeyAuthenticator interface {
+	// Authenticate returns the key, or an error wrapping apikey.ErrInvalidAPIKey if no active key matches.
+	Authenticate(ctx context.Context, key string) (*model.APIKey, error)
+}
+
+// Authenticator is the middleware identifying the user making a request.
+type Authenticator struct {
+	keys   KeyAuthenticator
+	admins map[string]bool
+	log    *zap.SugaredLogger
+}
+
+// NewAuthenticator creates an authenticator checking API keys with keys. The users with the
+// adminUserIDs are administrators.
+func NewAuthenticator(keys KeyAuthenticator, adminUserIDs []string, log *zap.SugaredLogger) *Authenticator {
+	admins := make(map[string]bool, len(adminUserIDs))
+	for _, userID := range adminUserIDs {
+		admins[userID] = true
+	}
+	return &Authenticator{keys: keys, admins: admins, log: log}
+}
+
+// identityKey is the context key of the identity.
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying the identity.
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// FromContext returns the identity of the request the context belongs to, if it has one.
+func FromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
+
+// Middleware identifies the user by the API key or the cookie of the request and stores the
+// identity in the request context. A nil *Authenticator only accepts the cookie.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key, ok := presentedKey(r); ok {
+			if a == nil || a.keys == nil {
+				unauthorized(w)
+				return
+			}
+			apiKey, err := a.keys.Authenticate(r.Context(), key)
+			if err != nil {
+				if errors.Is(err, apikey.ErrInvalidAPIKey) {
+					unauthorized(w)
+					return
+				}
+				a.log.Errorf("Failed to authenticate API key: %v", err)
+				http.Error(w, "Failed to authenticate", http.StatusInternalServerError)
+				return
+			}
+			admin := a.isAdmin(apiKey.UserID) && apiKey.HasScope(model.ScopeAdmin)
+			r = r.WithContext(WithIdentity(r.Context(), Identity{UserID: apiKey.UserID, APIKey: apiKey, Admin: admin}))
+		} else if userID, ok := utils.RefreshUserIDFromCookie(w, r); ok {
+			r = r.WithContext(WithIdentity(r.Context(), Identity{UserID: userID, Admin: a.isAdmin(userID)}))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isAdmin reports whether the user is an administrator.
+func (a *Authenticator) isAdmin(userID string) bool {
+	return a != nil && a.admins[userID]
+}
+
+// EnsureUser gives requests without an identity a new anonymous user, set in the cookie of the response.
+func EnsureUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := FromContext(r.Context()); !ok {
+			userID, err := utils.SetUserIDInCookie(w, r)
+			if err != nil {
+				http.Error(w, "Failed to set user ID", http.StatusInternalServerError)
+				return
+			}
+			r = r.WithContext(WithIdentity(r.Context(), Identity{UserID: userID}))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// presentedKey returns the API key of the request, from the Authorization or X-API-Key header.
+func presentedKey(r *http.Request) (string, bool) {
+	if header := r.Header.Get("Authorization"); len(header) > len(bearerPrefix) &&
+		strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return strings.TrimSpace(header[len(bearerPrefix):]), true
+	}
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key, true
+	}
+	return "", false
+}
+
+// unauthorized rejects a request presenting an invalid API key.
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	http.Error(w, "Invalid API key", http.StatusUnauthorized)
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/service/apikey"
+	"github.com/GlebRadaev/shlink/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// stubKeys accepts a single key.
+type stubKeys struct {
+	key    string
+	apiKey *model.APIKey
+	err    error
+}
+
+func (s stubKeys) Authenticate(_ context.Context, key string) (*model.APIKey, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if key != s.key {
+		return nil, apikey.ErrInvalidAPIKey
+	}
+	return s.apiKey, nil
+}
+
+// serve sends the request through the handler and returns the response and the identity the
+// handler saw.
+func serve(h func(http.Handler) http.Handler, r *http.Request) (*httptest.ResponseRecorder, *Identity) {
+	var seen *Identity
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if identity, ok := FromContext(r.Context()); ok {
+			seen = &identity
+		}
+	})
+	rec := httptest.NewRecorder()
+	h(next).ServeHTTP(rec, r)
+	return rec, seen
+}
+
+func TestAuthenticator_Middleware(t *testing.T) {
+	key := &model.APIKey{ID: "key1", UserID: "owner", Scopes: []string{model.ScopeRead}}
+	authenticator := NewAuthenticator(stubKeys{key: "shl_valid", apiKey: key}, nil, zap.NewNop().Sugar())
+	token, err := utils.GenerateJWT("cookie-user")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		header     [2]string
+		cookie     bool
+		wantStatus int
+		wantUserID string
+		wantAPIKey bool
+	}{
+		{name: "bearer", header: [2]string{"Authorization", "Bearer shl_valid"}, wantStatus: http.StatusOK, wantUserID: "owner", wantAPIKey: true},
+		{name: "lowercase bearer", header: [2]string{"Authorization", "bearer shl_valid"}, wantStatus: http.StatusOK, wantUserID: "owner", wantAPIKey: true},
+		{name: "X-API-Key", header: [2]string{APIKeyHeader, "shl_valid"}, wantStatus: http.StatusOK, wantUserID: "owner", wantAPIKey: true},
+		{name: "key wins over cookie", header: [2]string{APIKeyHeader, "shl_valid"}, cookie: true, wantStatus: http.StatusOK, wantUserID: "owner", wantAPIKey: true},
+		{name: "invalid key", header: [2]string{APIKeyHeader, "shl_invalid"}, cookie: true, wantStatus: http.StatusUnauthorized},
+		{name: "cookie", cookie: true, wantStatus: http.StatusOK, wantUserID: "cookie-user"},
+		{name: "anonymous", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header[0] != "" {
+				r.Header.Set(tt.header[0], tt.header[1])
+			}
+			if tt.cookie {
+				r.AddCookie(utils.CreateCookie(utils.NameCookieUserID, token))
+			}
+			rec, identity := serve(authenticator.Middleware, r)
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusUnauthorized {
+				assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+			}
+			if tt.wantUserID == "" {
+				assert.Nil(t, identity)
+				return
+			}
+			require.NotNil(t, identity)
+			assert.Equal(t, tt.wantUserID, identity.UserID)
+			assert.Equal(t, tt.wantAPIKey, identity.APIKey != nil)
+		})
+	}
+}
+
+func TestAuthenticator_Failures(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(APIKeyHeader, "shl_valid")
+
+	var nilAuthenticator *Authenticator
+	rec, identity := serve(nilAuthenticator.Middleware, r)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "Expected keys to be rejected without an authenticator")
+	assert.Nil(t, identity)
+
+	failing := NewAuthenticator(stubKeys{err: errors.New("db error")}, nil, zap.NewNop().Sugar())
+	rec, identity = serve(failing.Middleware, r)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Nil(t, identity)
+}
+
+func TestAuthenticator_Admin(t *testing.T) {
+	keys := map[string]*model.APIKey{
+		"shl_admin":       {ID: "key1", UserID: "admin", Scopes: []string{model.ScopeRead, model.ScopeAdmin}},
+		"shl_admin_read":  {ID: "key2", UserID: "admin", Scopes: []string{model.ScopeRead}},
+		"shl_former":      {ID: "key3", UserID: "former", Scopes: []string{model.ScopeAdmin}},
+		"shl_not_granted": {ID: "key4", UserID: "user", Scopes: []string{model.ScopeRead}},
+	}
+	tests := []struct {
+		name      string
+		key       string
+		cookie    string
+		wantAdmin bool
+	}{
+		{name: "admin cookie", cookie: "admin", wantAdmin: true},
+		{name: "user cookie", cookie: "user"},
+		{name: "admin key", key: "shl_admin", wantAdmin: true},
+		{name: "key without the admin scope", key: "shl_admin_read"},
+		{name: "key of a user no longer admin", key: "shl_former"},
+		{name: "user key", key: "shl_not_granted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator := NewAuthenticator(stubKeys{key: tt.key, apiKey: keys[tt.key]}, []string{"admin"}, zap.NewNop().Sugar())
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.key != "" {
+				r.Header.Set(APIKeyHeader, tt.key)
+			}
+			if tt.cookie != "" {
+				token, err := utils.GenerateJWT(tt.cookie)
+				require.NoError(t, err)
+				r.AddCookie(utils.CreateCookie(utils.NameCookieUserID, token))
+			}
+			_, identity := serve(authenticator.Middleware, r)
+			require.NotNil(t, identity)
+			assert.Equal(t, tt.wantAdmin, identity.Admin)
+		})
+	}
+}
+
+func TestEnsureUser(t *testing.T) {
+	rec, identity := serve(EnsureUser, httptest.NewRequest(http.MethodPost, "/", nil))
+	require.NotNil(t, identity)
+	assert.NotEmpty(t, identity.UserID)
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, utils.NameCookieUserID, cookies[0].Name)
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r = r.WithContext(WithIdentity(r.Context(), Identity{UserID: "known"}))
+	rec, identity = serve(EnsureUser, r)
+	require.NotNil(t, identity)
+	assert.Equal(t, "known", identity.UserID)
+	assert.Empty(t, rec.Result().Cookies(), "Expected no cookie for a known user")
+}
+
+func TestIdentity_HasScope(t *testing.T) {
+	assert.True(t, Identity{UserID: "u"}.HasScope(model.ScopeDelete), "Expected the cookie to have all scopes")
+	withKey := Identity{UserID: "u", APIKey: &model.APIKey{Scopes: []string{model.ScopeRead}}}
+	assert.True(t, withKey.HasScope(model.ScopeRead))
+	assert.False(t, withKey.HasScope(model.ScopeDelete))
+}
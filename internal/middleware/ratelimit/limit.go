@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket holding up to Burst tokens, refilled at Burst tokens per Period.
+// A zero Limit does not limit anything.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// ParseLimit parses a limit written as "<requests>/<period>", such as "60/1m" for a bucket of
+// 60 requests refilled at 60 requests per minute. An empty string or "0" means no limit.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" || s == "0" {
+		return Limit{}, nil
+	}
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected <requests>/<period>", s)
+	}
+	burst, err := strconv.Atoi(requests)
+	if err != nil || burst <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive integer", s)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: period must be a positive duration", s)
+	}
+	return Limit{Burst: burst, Period: d}, nil
+}
+
+// IsZero reports whether the limit lets every request through.
+func (l Limit) IsZero() bool {
+	return l.Burst <= 0 || l.Period <= 0
+}
+
+// String formats the limit the way ParseLimit reads it.
+func (l Limit) String() string {
+	if l.IsZero() {
+		return "0"
+	}
+	return fmt.Sprintf("%d/%s", l.Burst, l.Period)
+}
+
+// rate returns the number of tokens added to the bucket per second.
+func (l Limit) rate() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+// Result is the outcome of taking tokens from a bucket.
+type Result struct {
+	Allowed    bool          // Whether the tokens were taken and the request may proceed.
+	Limit      int           // Size of the bucket.
+	Remaining  int           // Whole tokens left in the bucket.
+	RetryAfter time.Duration // How long until the request could be allowed; zero if it was.
+	ResetAfter time.Duration // How long until the bucket is full again.
+}
+
+// refill returns the tokens in a bucket that held tokens elapsed ago, never more than the burst.
+func (l Limit) refill(tokens float64, elapsed time.Duration) float64 {
+	if elapsed > 0 {
+		tokens += elapsed.Seconds() * l.rate()
+	}
+	return math.Min(tokens, float64(l.Burst))
+}
+
+// result describes a bucket left with tokens after trying to take cost tokens.
+func (l Limit) result(tokens float64, cost int, allowed bool) Result {
+	res := Result{
+		Allowed:    allowed,
+		Limit:      l.Burst,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: l.duration(float64(l.Burst) - tokens),
+	}
+	if !allowed {
+		res.RetryAfter = l.duration(float64(cost) - tokens)
+	}
+	return res
+}
+
+// duration returns how long it takes to add the given number of tokens to the bucket.
+func (l Limit) duration(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(tokens / l.rate() * float64(time.Second)))
+}
@@ -0,0 +1,262 @@
+// Package ratelimit limits the request rate of clients with token buckets.
+//
+// The Limiter has a policy for each group of routes: shortening a URL, shortening a batch,
+// following a redirect and deleting URLs. Each policy has its own bucket per client, keyed
+// by the real IP of the client, by the user ID the auth middleware identified from the API
+// key or the JWT cookie, or by both, in which case a request must fit in both buckets.
+// Batch requests take a token for every started BatchBytesPerToken bytes of their body, so
+// huge payloads use up the limit sooner. A batch body of unknown length takes a full bucket,
+// and no more is read from a batch body than a full bucket pays for: larger bodies are
+// rejected with 413 Request Entity Too Large, or fail to be read if their length is unknown.
+//
+// Limited responses are 429 Too Many Requests with a Retry-After header. All responses of
+// limited routes carry the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy headers.
+//
+// The buckets live in a Store: in process memory by default, or on a server speaking the
+// Redis protocol to share the limits between instances. If the store fails, requests are
+// let through. All methods of a nil *Limiter let every request through.
+//
+// Example usage:
+//
+//	limiter := ratelimit.New(ctx, cfg, log)
+//	router.With(limiter.Shorten).Post("/api/shorten", handler)
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/config"
+	"github.com/GlebRadaev/shlink/internal/logger"
+	"github.com/GlebRadaev/shlink/internal/middleware/auth"
+	"github.com/GlebRadaev/shlink/internal/utils"
+	"go.uber.org/zap"
+)
+
+// Ways to key the bucket of a client, selected by config.Config.RateLimitKey.
+const (
+	KeyIP        = "ip"          // The real IP of the client.
+	KeyUser      = "user"        // The user ID of the request identity, or the real IP without one.
+	KeyIPAndUser = "ip_and_user" // Both: the request must fit in the bucket of the IP and of the user.
+)
+
+// Defaults used for policies whose configured limit cannot be parsed.
+const (
+	DefaultShortenLimit  = "60/1m"
+	DefaultBatchLimit    = "10/1m"
+	DefaultRedirectLimit = "600/1m"
+	DefaultDeleteLimit   = "30/1m"
+)
+
+// BatchBytesPerToken is the size of the request body a batch request takes one token for.
+const BatchBytesPerToken = 64 << 10
+
+// redisKeyPrefix prefixes the keys of the buckets kept on a Redis server.
+const redisKeyPrefix = "shlink:ratelimit:"
+
+// Policy is the limit applied to a group of routes.
+type Policy struct {
+	Name  string                    // Name of the policy, part of the bucket keys.
+	Limit Limit                     // Token bucket of each client.
+	Cost  func(r *http.Request) int // Tokens taken by a request; one if nil.
+	// MaxBody is the largest request body read, unlimited if zero. Requests declaring a larger
+	// body are rejected, and the body of the others is cut off past MaxBody.
+	MaxBody int64
+}
+
+// Policies holds the limits of the groups of routes.
+type Policies struct {
+	Shorten  Limit // Shortening a single URL.
+	Batch    Limit // Shortening a batch of URLs.
+	Redirect Limit // Following a short URL.
+	Delete   Limit // Deleting the URLs of a user.
+}
+
+// Limiter is the rate limiting middleware of the routes.
+type Limiter struct {
+	store    Store
+	key      string
+	log      *zap.SugaredLogger
+	shorten  Policy
+	batch    Policy
+	redirect Policy
+	delete   Policy
+}
+
+// NewLimiter creates a limiter keeping its buckets in store and keying them as given by key.
+func NewLimiter(store Store, key string, log *zap.SugaredLogger, policies Policies) *Limiter {
+	return &Limiter{
+		store:    store,
+		key:      key,
+		log:      log,
+		shorten:  Policy{Name: "shorten", Limit: policies.Shorten},
+		batch:    Policy{Name: "batch", Limit: policies.Batch, Cost: batchCost, MaxBody: int64(policies.Batch.Burst) * BatchBytesPerToken},
+		redirect: Policy{Name: "redirect", Limit: policies.Redirect},
+		delete:   Policy{Name: "delete", Limit: policies.Delete},
+	}
+}
+
+// New creates the limiter of the configuration, or returns nil if rate limiting is disabled.
+// Limits that cannot be parsed are replaced by the defaults, and the in-process store is used
+// if the Redis server cannot be reached.
+func New(ctx context.Context, cfg *config.Config, log *logger.Logger) *Limiter {
+	if !cfg.RateLimitEnabled {
+		return nil
+	}
+	logger := log.Named("RateLimiter")
+	parse := func(name, value, fallback string) Limit {
+		limit, err := ParseLimit(value)
+		if err != nil {
+			logger.Errorf("Invalid %s rate limit, using %s: %v", name, fallback, err)
+			limit, _ = ParseLimit(fallback)
+		}
+		return limit
+	}
+	policies := Policies{
+		Shorten:  parse("shorten", cfg.RateLimitShorten, DefaultShortenLimit),
+		Batch:    parse("batch", cfg.RateLimitBatch, DefaultBatchLimit),
+		Redirect: parse("redirect", cfg.RateLimitRedirect, DefaultRedirectLimit),
+		Delete:   parse("delete", cfg.RateLimitDelete, DefaultDeleteLimit),
+	}
+	key := cfg.RateLimitKey
+	if key != KeyIP && key != KeyUser && key != KeyIPAndUser {
+		logger.Errorf("Unknown rate limit key %q, limiting by IP", key)
+		key = KeyIP
+	}
+
+	var store Store = NewMemoryStore()
+	if cfg.RateLimitRedisURL != "" {
+		redisStore, err := OpenRedisStore(ctx, cfg.RateLimitRedisURL, redisKeyPrefix)
+		if err != nil {
+			logger.Errorf("Keeping rate limits in memory (failed to connect to Redis): %v", err)
+		} else {
+			logger.Info("Keeping rate limits in Redis.")
+			store = redisStore
+		}
+	}
+	return NewLimiter(store, key, logger, policies)
+}
+
+// Shorten limits the requests shortening a single URL.
+func (l *Limiter) Shorten(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return l.handler(l.shorten, next)
+}
+
+// Batch limits the requests shortening a batch of URLs.
+func (l *Limiter) Batch(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return l.handler(l.batch, next)
+}
+
+// Redirect limits the requests following a short URL.
+func (l *Limiter) Redirect(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return l.handler(l.redirect, next)
+}
+
+// Delete limits the requests deleting the URLs of a user.
+func (l *Limiter) Delete(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return l.handler(l.delete, next)
+}
+
+// Close closes the store of the limiter.
+func (l *Limiter) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.store.Close()
+}
+
+// handler applies the policy to the requests before passing them to next.
+func (l *Limiter) handler(policy Policy, next http.Handler) http.Handler {
+	if policy.Limit.IsZero() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if policy.MaxBody > 0 {
+			if r.ContentLength > policy.MaxBody {
+				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, policy.MaxBody)
+		}
+		cost := 1
+		if policy.Cost != nil {
+			cost = min(policy.Cost(r), policy.Limit.Burst)
+		}
+		var res Result
+		for _, key := range l.keys(r) {
+			var err error
+			res, err = l.store.Take(r.Context(), policy.Name+":"+key, policy.Limit, cost)
+			if err != nil {
+				l.log.Warnf("Letting request through, rate limit store failed: %v", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !res.Allowed {
+				break
+			}
+		}
+		setHeaders(w.Header(), policy.Limit, res)
+		if !res.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, seconds(res.RetryAfter))))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// keys returns the keys of the buckets of the client making the request.
+func (l *Limiter) keys(r *http.Request) []string {
+	ip := "ip:" + utils.ClientIP(r)
+	identity, ok := auth.FromContext(r.Context())
+	switch {
+	case l.key == KeyUser && ok:
+		return []string{"user:" + identity.UserID}
+	case l.key == KeyIPAndUser && ok:
+		return []string{ip, "user:" + identity.UserID}
+	default:
+		return []string{ip}
+	}
+}
+
+// batchCost takes a token for every started BatchBytesPerToken bytes of the body. A body of
+// unknown length, such as a chunked one, takes as many tokens as the largest body allowed,
+// the cost being capped at the burst of the policy.
+func batchCost(r *http.Request) int {
+	if r.ContentLength < 0 {
+		return math.MaxInt
+	}
+	if r.ContentLength == 0 {
+		return 1
+	}
+	return int((r.ContentLength + BatchBytesPerToken - 1) / BatchBytesPerToken)
+}
+
+// setHeaders sets the RateLimit headers describing the bucket of the client.
+func setHeaders(h http.Header, limit Limit, res Result) {
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(seconds(res.ResetAfter)))
+	h.Set("RateLimit-Policy", strconv.Itoa(limit.Burst)+";w="+strconv.Itoa(seconds(limit.Period)))
+}
+
+// seconds rounds the duration up to whole seconds.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,203 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/config"
+	"github.com/GlebRadaev/shlink/internal/logger"
+	"github.com/GlebRadaev/shlink/internal/middleware/auth"
+	"github.com/GlebRadaev/shlink/internal/utils"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+// newTestLimiter creates a limiter with an in-memory store on a fake clock.
+func newTestLimiter(t *testing.T, key string, policies Policies) (*Limiter, *fakeClock) {
+	t.Helper()
+	log, _ := logger.NewLogger("info")
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	store := NewMemoryStore()
+	store.now = clock.Now
+	return NewLimiter(store, key, log.SugaredLogger, policies), clock
+}
+
+// request sends a request from the given address through the auth middleware, with the cookie
+// of the user if userID is set.
+func request(t *testing.T, h http.Handler, method, remoteAddr, userID string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, "/", bytes.NewReader(body))
+	r.RemoteAddr = remoteAddr
+	if userID != "" {
+		token, err := utils.GenerateJWT(userID)
+		require.NoError(t, err)
+		r.AddCookie(utils.CreateCookie(utils.NameCookieUserID, token))
+	}
+	rec := httptest.NewRecorder()
+	auth.NewAuthenticator(nil, nil, nil).Middleware(h).ServeHTTP(rec, r)
+	return rec
+}
+
+func TestLimiter_Headers(t *testing.T) {
+	limiter, clock := newTestLimiter(t, KeyIP, Policies{Shorten: Limit{Burst: 2, Period: time.Minute}})
+	h := limiter.Shorten(okHandler)
+
+	rec := request(t, h, http.MethodPost, "10.0.0.1:1234", "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", rec.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "2;w=60", rec.Header().Get("RateLimit-Policy"))
+	assert.Empty(t, rec.Header().Get("Retry-After"))
+
+	request(t, h, http.MethodPost, "10.0.0.1:1234", "", nil)
+	rec = request(t, h, http.MethodPost, "10.0.0.1:1234", "", nil)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+
+	clock.Advance(30 * time.Second)
+	rec = request(t, h, http.MethodPost, "10.0.0.1:1234", "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestLimiter_Keys(t *testing.T) {
+	limit := Limit{Burst: 1, Period: time.Minute}
+	tests := []struct {
+		name     string
+		key      string
+		first    [2]string // remote address and user ID of the first request
+		second   [2]string // remote address and user ID of the second request
+		wantCode int
+	}{
+		{name: "ip: same IP, other user", key: KeyIP, first: [2]string{"10.0.0.1:1", "alice"}, second: [2]string{"10.0.0.1:2", "bob"}, wantCode: http.StatusTooManyRequests},
+		{name: "ip: other IP", key: KeyIP, first: [2]string{"10.0.0.1:1", ""}, second: [2]string{"10.0.0.2:1", ""}, wantCode: http.StatusOK},
+		{name: "user: same user, other IP", key: KeyUser, first: [2]string{"10.0.0.1:1", "alice"}, second: [2]string{"10.0.0.2:1", "alice"}, wantCode: http.StatusTooManyRequests},
+		{name: "user: other user, same IP", key: KeyUser, first: [2]string{"10.0.0.1:1", "alice"}, second: [2]string{"10.0.0.1:1", "bob"}, wantCode: http.StatusOK},
+		{name: "user: no cookie falls back to IP", key: KeyUser, first: [2]string{"10.0.0.1:1", ""}, second: [2]string{"10.0.0.1:2", ""}, wantCode: http.StatusTooManyRequests},
+		{name: "ip_and_user: same IP, other user", key: KeyIPAndUser, first: [2]string{"10.0.0.1:1", "alice"}, second: [2]string{"10.0.0.1:1", "bob"}, wantCode: http.StatusTooManyRequests},
+		{name: "ip_and_user: same user, other IP", key: KeyIPAndUser, first: [2]string{"10.0.0.1:1", "alice"}, second: [2]string{"10.0.0.2:1", "alice"}, wantCode: http.StatusTooManyRequests},
+		{name: "ip_and_user: other IP and user", key: KeyIPAndUser, first: [2]string{"10.0.0.1:1", "alice"}, second: [2]string{"10.0.0.2:1", "bob"}, wantCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, _ := newTestLimiter(t, tt.key, Policies{Delete: limit})
+			h := limiter.Delete(okHandler)
+
+			rec := request(t, h, http.MethodDelete, tt.first[0], tt.first[1], nil)
+			require.Equal(t, http.StatusOK, rec.Code)
+			rec = request(t, h, http.MethodDelete, tt.second[0], tt.second[1], nil)
+			assert.Equal(t, tt.wantCode, rec.Code)
+		})
+	}
+}
+
+func TestLimiter_Policies(t *testing.T) {
+	limiter, _ := newTestLimiter(t, KeyIP, Policies{
+		Shorten:  Limit{Burst: 1, Period: time.Minute},
+		Batch:    Limit{Burst: 4, Period: time.Minute},
+		Redirect: Limit{Burst: 1, Period: time.Minute},
+	})
+
+	assert.Equal(t, http.StatusOK, request(t, limiter.Shorten(okHandler), http.MethodPost, "10.0.0.1:1", "", nil).Code)
+	assert.Equal(t, http.StatusOK, request(t, limiter.Redirect(okHandler), http.MethodGet, "10.0.0.1:1", "", nil).Code,
+		"Expected each policy to have its own buckets")
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, request(t, limiter.Delete(okHandler), http.MethodDelete, "10.0.0.1:1", "", nil).Code,
+			"Expected a zero limit not to limit")
+	}
+
+	rec := request(t, limiter.Batch(okHandler), http.MethodPost, "10.0.0.1:1", "", make([]byte, 3*BatchBytesPerToken-1))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"), "Expected a batch to take a token per started 64 KiB")
+	rec = request(t, limiter.Batch(okHandler), http.MethodPost, "10.0.0.1:1", "", make([]byte, 2*BatchBytesPerToken))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	rec = request(t, limiter.Batch(okHandler), http.MethodPost, "10.0.0.1:1", "", []byte("[]"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestLimiter_BatchBody(t *testing.T) {
+	limiter, _ := newTestLimiter(t, KeyIP, Policies{Batch: Limit{Burst: 4, Period: time.Minute}})
+	var read int
+	var readErr error
+	h := limiter.Batch(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []byte
+		body, readErr = io.ReadAll(r.Body)
+		read = len(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := request(t, h, http.MethodPost, "10.0.0.1:1", "", make([]byte, 4*BatchBytesPerToken+1))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, "Expected a body larger than a full bucket to be rejected")
+	assert.Equal(t, "", rec.Header().Get("RateLimit-Remaining"), "Expected a rejected body to take no token")
+
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(make([]byte, 5*BatchBytesPerToken)))
+	r.RemoteAddr = "10.0.0.2:1"
+	r.ContentLength = -1
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"), "Expected a body of unknown length to take a full bucket")
+	assert.Equal(t, 4*BatchBytesPerToken, read, "Expected the body to be cut off past a full bucket")
+	var tooLarge *http.MaxBytesError
+	assert.ErrorAs(t, readErr, &tooLarge)
+}
+
+func TestLimiter_StoreFailure(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	store, server := newRedisStore(t, clock)
+	limiter := NewLimiter(store, KeyIP, log.SugaredLogger, Policies{Shorten: Limit{Burst: 1, Period: time.Minute}})
+	h := limiter.Shorten(okHandler)
+
+	assert.Equal(t, http.StatusOK, request(t, h, http.MethodPost, "10.0.0.1:1", "", nil).Code)
+	assert.Equal(t, http.StatusTooManyRequests, request(t, h, http.MethodPost, "10.0.0.1:1", "", nil).Code)
+	server.Close()
+	assert.Equal(t, http.StatusOK, request(t, h, http.MethodPost, "10.0.0.1:1", "", nil).Code,
+		"Expected requests to be let through when the store fails")
+}
+
+func TestNew(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+	ctx := context.Background()
+
+	t.Run("returns nil if disabled", func(t *testing.T) {
+		limiter := New(ctx, &config.Config{RateLimitEnabled: false}, log)
+		assert.Nil(t, limiter)
+		assert.Equal(t, http.StatusOK, request(t, limiter.Batch(okHandler), http.MethodPost, "10.0.0.1:1", "", nil).Code)
+		assert.NoError(t, limiter.Close())
+	})
+
+	t.Run("falls back to defaults and memory", func(t *testing.T) {
+		limiter := New(ctx, &config.Config{
+			RateLimitEnabled:  true,
+			RateLimitKey:      "cookie",
+			RateLimitRedisURL: "redis://127.0.0.1:1/0",
+			RateLimitShorten:  "lots",
+			RateLimitBatch:    "0",
+		}, log)
+		require.NotNil(t, limiter)
+		assert.IsType(t, &MemoryStore{}, limiter.store)
+		assert.Equal(t, KeyIP, limiter.key)
+		assert.Equal(t, Limit{Burst: 60, Period: time.Minute}, limiter.shorten.Limit)
+		assert.True(t, limiter.batch.Limit.IsZero())
+	})
+
+	t.Run("uses the Redis server", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		limiter := New(ctx, &config.Config{RateLimitEnabled: true, RateLimitKey: KeyUser, RateLimitRedisURL: "redis://" + server.Addr()}, log)
+		require.NotNil(t, limiter)
+		assert.IsType(t, &RedisStore{}, limiter.store)
+		assert.NoError(t, limiter.Close())
+	})
+}
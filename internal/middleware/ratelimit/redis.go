@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript refills and takes tokens from a bucket stored as a hash with the tokens and the
+// time of the last take in milliseconds. It runs atomically, so instances sharing the server
+// share the buckets. The hash expires once the bucket would be full again.
+//
+// KEYS[1]: bucket key; ARGV: burst, period in milliseconds, current time in milliseconds, cost.
+// Returns whether the tokens were taken and the tokens left, as a string to keep the fraction.
+var takeScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local cost = tonumber(ARGV[4])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(state[1])
+local last = tonumber(state[2])
+if tokens == nil or last == nil then
+	tokens = burst
+	last = now
+end
+if now > last then
+	tokens = math.min(burst, tokens + (now - last) * burst / period)
+end
+
+local allowed = 0
+if tokens >= cost then
+	tokens = tokens - cost
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', tostring(math.max(now, last)))
+redis.call('PEXPIRE', KEYS[1], period)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore keeps the token buckets on a server speaking the Redis protocol, so that
+// all instances of the service share the limits.
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+	now    func() time.Time
+}
+
+// NewRedisStore creates a store keeping the buckets under keys starting with prefix.
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix, now: time.Now}
+}
+
+// OpenRedisStore connects to the server at the redis:// URL and checks the connection.
+func OpenRedisStore(ctx context.Context, url, prefix string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	return NewRedisStore(client, prefix), nil
+}
+
+// Take takes cost tokens from the bucket of the key.
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit, cost int) (Result, error) {
+	reply, err := takeScript.Run(ctx, s.client, []string{s.prefix + key},
+		limit.Burst, limit.Period.Milliseconds(), s.now().UnixMilli(), cost).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take tokens: %w", err)
+	}
+	if len(reply) != 2 {
+		return Result{}, fmt.Errorf("unexpected reply from rate limit script: %v", reply)
+	}
+	allowed, _ := reply[0].(int64)
+	tokens, err := strconv.ParseFloat(fmt.Sprint(reply[1]), 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected tokens from rate limit script: %w", err)
+	}
+	return limit.result(tokens, cost, allowed == 1), nil
+}
+
+// Close closes the connection to the server.
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store keeps the token buckets of the rate limiter.
+type Store interface {
+	// Take takes cost tokens from the bucket of the key, which is created full if it does not exist.
+	// The tokens are only taken if the bucket holds enough of them.
+	Take(ctx context.Context, key string, limit Limit, cost int) (Result, error)
+
+	// Close releases the resources held by the store.
+	Close() error
+}
+
+// bucket is the state of a token bucket: the tokens it held at the time of the last take.
+type bucket struct {
+	tokens float64
+	last   time.Time
+	period time.Duration
+}
+
+// MemoryStore keeps the token buckets in process memory. Buckets that have refilled
+// completely are dropped from time to time, since a new bucket starts full anyway.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// sweepInterval is how often the buckets that have refilled are dropped.
+const sweepInterval = time.Minute
+
+// NewMemoryStore creates an empty in-process store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Take takes cost tokens from the bucket of the key.
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, cost int) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+	b.tokens = limit.refill(b.tokens, now.Sub(b.last))
+	b.last = now
+	b.period = limit.Period
+	allowed := b.tokens >= float64(cost)
+	if allowed {
+		b.tokens -= float64(cost)
+	}
+	return limit.result(b.tokens, cost, allowed), nil
+}
+
+// sweep drops the buckets that have not been used for longer than it takes them to refill.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.Sub(b.last) >= b.period {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// Len returns the number of buckets kept by the store.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
+
+// Close does nothing; the store holds no resources.
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,148 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a clock advanced by the tests.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// newRedisStore creates a store on a local stand-in for a Redis server.
+func newRedisStore(t *testing.T, clock *fakeClock) (*RedisStore, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	store := NewRedisStore(redis.NewClient(&redis.Options{Addr: server.Addr()}), "test:")
+	store.now = clock.Now
+	t.Cleanup(func() { store.Close() })
+	return store, server
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Limit
+		wantErr bool
+	}{
+		{input: "60/1m", want: Limit{Burst: 60, Period: time.Minute}},
+		{input: "5/1s", want: Limit{Burst: 5, Period: time.Second}},
+		{input: "", want: Limit{}},
+		{input: "0", want: Limit{}},
+		{input: "60", wantErr: true},
+		{input: "-1/1m", wantErr: true},
+		{input: "ten/1m", wantErr: true},
+		{input: "10/forever", wantErr: true},
+		{input: "10/0s", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseLimit(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStore_Take(t *testing.T) {
+	stores := map[string]func(t *testing.T, clock *fakeClock) Store{
+		"memory": func(t *testing.T, clock *fakeClock) Store {
+			store := NewMemoryStore()
+			store.now = clock.Now
+			return store
+		},
+		"redis": func(t *testing.T, clock *fakeClock) Store {
+			store, _ := newRedisStore(t, clock)
+			return store
+		},
+	}
+	limit := Limit{Burst: 2, Period: time.Second}
+	ctx := context.Background()
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Unix(1700000000, 0)}
+			store := newStore(t, clock)
+
+			res, err := store.Take(ctx, "client", limit, 1)
+			require.NoError(t, err)
+			assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1, ResetAfter: 500 * time.Millisecond}, res)
+
+			res, err = store.Take(ctx, "client", limit, 1)
+			require.NoError(t, err)
+			assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 0, ResetAfter: time.Second}, res)
+
+			res, err = store.Take(ctx, "client", limit, 1)
+			require.NoError(t, err)
+			assert.Equal(t, Result{Allowed: false, Limit: 2, Remaining: 0, RetryAfter: 500 * time.Millisecond, ResetAfter: time.Second}, res)
+
+			res, err = store.Take(ctx, "other", limit, 2)
+			require.NoError(t, err)
+			assert.True(t, res.Allowed, "Expected another client to have its own bucket")
+
+			clock.Advance(250 * time.Millisecond)
+			res, err = store.Take(ctx, "client", limit, 1)
+			require.NoError(t, err)
+			assert.False(t, res.Allowed)
+			assert.Equal(t, 250*time.Millisecond, res.RetryAfter)
+
+			clock.Advance(250 * time.Millisecond)
+			res, err = store.Take(ctx, "client", limit, 1)
+			require.NoError(t, err)
+			assert.True(t, res.Allowed)
+
+			clock.Advance(time.Hour)
+			res, err = store.Take(ctx, "client", limit, 2)
+			require.NoError(t, err)
+			assert.True(t, res.Allowed, "Expected the bucket to refill no further than the burst")
+			assert.Equal(t, 0, res.Remaining)
+		})
+	}
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	store := NewMemoryStore()
+	store.now = clock.Now
+	ctx := context.Background()
+
+	_, _ = store.Take(ctx, "short", Limit{Burst: 1, Period: time.Second}, 1)
+	_, _ = store.Take(ctx, "long", Limit{Burst: 1, Period: time.Hour}, 1)
+	assert.Equal(t, 2, store.Len())
+
+	clock.Advance(sweepInterval)
+	_, _ = store.Take(ctx, "long", Limit{Burst: 1, Period: time.Hour}, 1)
+	assert.Equal(t, 1, store.Len(), "Expected the refilled bucket to be dropped")
+}
+
+func TestRedisStore_Expiry(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	store, server := newRedisStore(t, clock)
+
+	_, err := store.Take(context.Background(), "client", Limit{Burst: 5, Period: time.Minute}, 1)
+	require.NoError(t, err)
+	assert.True(t, server.Exists("test:client"))
+	assert.Equal(t, time.Minute, server.TTL("test:client"))
+
+	server.Close()
+	_, err = store.Take(context.Background(), "client", Limit{Burst: 5, Period: time.Minute}, 1)
+	assert.Error(t, err)
+}
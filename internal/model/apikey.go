@@ -0,0 +1,42 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// Scopes granted to API keys. Requests authenticated by the user cookie have all of them.
+const (
+	ScopeShorten = "shorten" // Shortening URLs, alone or in batches.
+	ScopeRead    = "read"    // Listing the URLs of the user and reading their statistics.
+	ScopeDelete  = "delete"  // Deleting and changing the URLs of the user.
+)
+
+// Scopes lists all scopes an API key can be granted.
+var Scopes = []string{ScopeShorten, ScopeRead, ScopeDelete}
+
+// ScopeAdmin lets an API key use the admin API. Only administrators can grant it, and a key
+// with it acts as an administrator only while its user is one.
+const ScopeAdmin = "admin"
+
+// APIKey represents an API key acting on behalf of a user. Only the hash of the key is stored.
+type APIKey struct {
+	ID        string     `db:"id"`         // ID is the public identifier of the key, used to revoke it.
+	UserID    string     `db:"user_id"`    // UserID is the identifier of the user the key acts for.
+	Name      string     `db:"name"`       // Name is the label given to the key by its owner.
+	Prefix    string     `db:"prefix"`     // Prefix is the start of the key, shown to tell keys apart.
+	KeyHash   string     `db:"key_hash"`   // KeyHash is the hex SHA-256 hash of the key.
+	Scopes    []string   `db:"scopes"`     // Scopes are the operations the key is allowed to perform.
+	CreatedAt time.Time  `db:"created_at"` // CreatedAt is the timestamp when the key was created.
+	RevokedAt *time.Time `db:"revoked_at"` // RevokedAt is the moment the key was revoked, nil while it is active.
+}
+
+// IsRevoked reports whether the key has been revoked.
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+// HasScope reports whether the key is allowed to perform the operations of the scope.
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
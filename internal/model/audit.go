@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// Actions recorded in the audit log.
+const (
+	AuditLookupURL         = "url.lookup"     // An administrator looked up a URL and its owner.
+	AuditDisableURL        = "url.disable"    // An administrator disabled a URL.
+	AuditRestoreURL        = "url.restore"    // An administrator restored a disabled URL.
+	AuditListUserURLs      = "user.list"      // An administrator listed the URLs of a user.
+	AuditDisableUserURLs   = "user.disable"   // An administrator disabled all URLs of a user.
+	AuditDisableDomainURLs = "domain.disable" // An administrator disabled all URLs pointing to a domain.
+	AuditListDeadTasks     = "task.list_dead" // An administrator listed the dead letters of the task queue.
+	AuditReplayTask        = "task.replay"    // An administrator moved a dead letter back to the task queue.
+	AuditResizeWorkers     = "task.resize"    // An administrator changed the workers or concurrency limits of the task queue.
+)
+
+// AuditEntry records an action of an administrator.
+type AuditEntry struct {
+	ID          int64     `db:"id"`            // ID is the primary key, increasing with every entry.
+	ActorUserID string    `db:"actor_user_id"` // ActorUserID is the identifier of the administrator.
+	APIKeyID    string    `db:"api_key_id"`    // APIKeyID is the key the administrator used, empty for the cookie.
+	Action      string    `db:"action"`        // Action is one of the Audit constants.
+	Target      string    `db:"target"`        // Target is the short ID, user ID, domain or task ID acted on.
+	Reason      string    `db:"reason"`        // Reason is the explanation given by the administrator, if any.
+	Affected    int64     `db:"affected"`      // Affected is the number of URLs or tasks changed or returned by the action.
+	CreatedAt   time.Time `db:"created_at"`    // CreatedAt is the timestamp of the action.
+}
+
+// AuditQuery filters and limits an audit log listing, which is ordered from the newest entry.
+type AuditQuery struct {
+	Target string // Only entries acting on this target; all if empty.
+	Before int64  // Only entries with a lower ID; all if zero.
+	Limit  int    // Maximum number of entries; unlimited if zero.
+}
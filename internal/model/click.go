@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// Click represents a single redirect through a shortened URL.
+type Click struct {
+	ID        int64     `db:"id"`         // ID is the primary key for the click record.
+	ShortID   string    `db:"short_id"`   // ShortID is the identifier of the shortened URL that was opened.
+	ClickedAt time.Time `db:"clicked_at"` // ClickedAt is the timestamp of the redirect.
+	Referrer  string    `db:"referrer"`   // Referrer is the value of the Referer header, if any.
+	UserAgent string    `db:"user_agent"` // UserAgent is the value of the User-Agent header, if any.
+	IP        string    `db:"ip"`         // IP is the client address of the visitor.
+	Country   string    `db:"country"`    // Country is the ISO country code of the visitor, if known.
+}
+
+// ClickStats holds aggregated click statistics for a shortened URL.
+type ClickStats struct {
+	TotalClicks    int64         // TotalClicks is the number of recorded redirects.
+	UniqueVisitors int64         // UniqueVisitors is the number of distinct client addresses.
+	Daily          []DailyClicks // Daily is the per-day time series ordered by date.
+}
+
+// DailyClicks holds click statistics for a single day.
+type DailyClicks struct {
+	Date           time.Time // Date is the UTC day the statistics belong to.
+	Clicks         int64     // Clicks is the number of redirects on that day.
+	UniqueVisitors int64     // UniqueVisitors is the number of distinct client addresses on that day.
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// UserIdentity links the account of a person at an OpenID Connect provider to a user of the service.
+type UserIdentity struct {
+	Issuer    string    `db:"issuer"`     // Issuer is the URL identifying the provider.
+	Subject   string    `db:"subject"`    // Subject is the identifier of the account at the provider.
+	UserID    string    `db:"user_id"`    // UserID is the identifier of the user the account signs in as.
+	Email     string    `db:"email"`      // Email is the address of the account when it was linked, if the provider shared it.
+	CreatedAt time.Time `db:"created_at"` // CreatedAt is the timestamp when the account was linked.
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// QueuedTask is a background task kept in the durable task queue until it succeeds or
+// runs out of attempts.
+type QueuedTask struct {
+	ID          int64           `db:"id"`           // ID is the primary key, increasing with every task.
+	Type        string          `db:"type"`         // Type is the task type selecting the handler.
+	Payload     json.RawMessage `db:"payload"`      // Payload is the JSON encoding of the task.
+	TraceParent string          `db:"trace_parent"` // TraceParent is the W3C traceparent of the span the task was enqueued from, if any.
+	Attempts    int             `db:"attempts"`     // Attempts is the number of times the task was claimed by a worker.
+	RunAt       time.Time       `db:"run_at"`       // RunAt is the moment from which the task may be claimed.
+	LastError   string          `db:"last_error"`   // LastError is the error of the last failed attempt, if any.
+	CreatedAt   time.Time       `db:"created_at"`   // CreatedAt is the moment the task was enqueued.
+	Priority    int             `db:"priority"`     // Priority is the priority level of the task, higher levels being claimed first.
+	FairKey     string          `db:"fair_key"`     // FairKey is the key the task shares the workers by, such as its user, if any.
+	FairAt      time.Time       `db:"fair_at"`      // FairAt is the virtual finish time ordering the due tasks of a priority level.
+}
+
+// DeadTask is a task that failed its last allowed attempt, kept in the dead letters until
+// it is inspected and replayed.
+type DeadTask struct {
+	ID        int64           `db:"id"`         // ID is the primary key, increasing with every dead letter.
+	Type      string          `db:"type"`       // Type is the task type selecting the handler.
+	Payload   json.RawMessage `db:"payload"`    // Payload is the JSON encoding of the task.
+	Attempts  int             `db:"attempts"`   // Attempts is the number of times the task was tried.
+	LastError string          `db:"last_error"` // LastError is the error of the last attempt.
+	CreatedAt time.Time       `db:"created_at"` // CreatedAt is the moment the task was first enqueued.
+	FailedAt  time.Time       `db:"failed_at"`  // FailedAt is the moment the task was moved to the dead letters.
+	Priority  int             `db:"priority"`   // Priority is the priority level of the task, kept for its replay.
+	FairKey   string          `db:"fair_key"`   // FairKey is the key the task shares the workers by, kept for its replay.
+}
+
+// States of a tracked task.
+const (
+	TaskQueued          = "queued"           // The task waits for a worker.
+	TaskRunning         = "running"          // A worker is processing the task.
+	TaskSucceeded       = "succeeded"        // Every item of the task succeeded.
+	TaskFailed          = "failed"           // Every item of the last attempt failed, or the task failed as a whole.
+	TaskPartiallyFailed = "partially_failed" // Some items of the last attempt failed and the others succeeded.
+)
+
+// States of an item of a tracked task.
+const (
+	TaskItemPending   = "pending"   // The item was not processed yet.
+	TaskItemSucceeded = "succeeded" // The item was processed.
+	TaskItemFailed    = "failed"    // The item failed and is retried with the task.
+)
+
+// TaskStatus tracks the progress of a task enqueued on behalf of a user. A failed task keeps
+// the state of its last attempt while it waits to be retried.
+type TaskStatus struct {
+	ID        string     `db:"id"`         // ID is the public identifier of the task.
+	UserID    string     `db:"user_id"`    // UserID is the user who enqueued the task.
+	Type      string     `db:"type"`       // Type is the task type selecting the handler.
+	State     string     `db:"state"`      // State is one of the task states, such as TaskQueued.
+	Items     []TaskItem `db:"items"`      // Items are the results of the items the task processes.
+	Error     string     `db:"error"`      // Error is the error of the last failed attempt, if any.
+	CreatedAt time.Time  `db:"created_at"` // CreatedAt is the moment the task was enqueued.
+	UpdatedAt time.Time  `db:"updated_at"` // UpdatedAt is the moment the state last changed.
+}
+
+// TaskItem is the result of an item of a tracked task, such as a URL to delete.
+type TaskItem struct {
+	ID    string `json:"id"`              // ID identifies the item, such as a short ID.
+	State string `json:"state"`           // State is one of the item states, such as TaskItemPending.
+	Error string `json:"error,omitempty"` // Error is the reason the item failed, if it did.
+}
@@ -1,13 +1,47 @@
 package model
 
-import "time"
+import (
+	"net/url"
+	"strings"
+	"time"
+)
 
 // URL represents a shortened URL record in the database.
 type URL struct {
-	ID          int       `db:"id"`           // ID is the primary key for the URL record.
-	ShortID     string    `db:"short_id"`     // ShortID is the unique identifier for the shortened URL.
-	OriginalURL string    `db:"original_url"` // OriginalURL is the full URL before shortening.
-	UserID      string    `db:"user_id"`      // UserID is the identifier for the user who created the shortened URL.
-	CreatedAt   time.Time `db:"created_at"`   // CreatedAt is the timestamp when the shortened URL was created.
-	DeletedFlag bool      `db:"is_deleted"`   // DeletedFlag indicates if the URL is marked as deleted.
+	ID           int        `db:"id"`            // ID is the primary key for the URL record.
+	ShortID      string     `db:"short_id"`      // ShortID is the unique identifier for the shortened URL.
+	OriginalURL  string     `db:"original_url"`  // OriginalURL is the full URL before shortening.
+	UserID       string     `db:"user_id"`       // UserID is the identifier for the user who created the shortened URL.
+	CreatedAt    time.Time  `db:"created_at"`    // CreatedAt is the timestamp when the shortened URL was created.
+	DeletedFlag  bool       `db:"is_deleted"`    // DeletedFlag indicates if the URL is marked as deleted.
+	DisabledFlag bool       `db:"is_disabled"`   // DisabledFlag indicates if an administrator disabled the URL.
+	ExpiresAt    *time.Time `db:"expires_at"`    // ExpiresAt is the optional moment after which the URL stops redirecting.
+	MaxClicks    *int       `db:"max_clicks"`    // MaxClicks is the optional number of redirects allowed for the URL.
+	Clicks       int        `db:"clicks"`        // Clicks is the number of redirects counted against MaxClicks.
+	PasswordHash string     `db:"password_hash"` // PasswordHash is the bcrypt hash of the optional password protecting the URL.
+}
+
+// IsProtected reports whether the URL requires a password before redirecting.
+func (u *URL) IsProtected() bool {
+	return u.PasswordHash != ""
+}
+
+// IsExpired reports whether the URL has passed its expiration date or used up its click budget at the given moment.
+func (u *URL) IsExpired(now time.Time) bool {
+	if u.ExpiresAt != nil && !now.Before(*u.ExpiresAt) {
+		return true
+	}
+	return u.MaxClicks != nil && u.Clicks >= *u.MaxClicks
+}
+
+// InDomain reports whether the original URL points to the domain or one of its subdomains.
+// The domain is compared ignoring case.
+func (u *URL) InDomain(domain string) bool {
+	parsed, err := url.Parse(u.OriginalURL)
+	if err != nil || domain == "" {
+		return false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	domain = strings.ToLower(domain)
+	return host == domain || strings.HasSuffix(host, "."+domain)
 }
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// URLSort is the order of a URL listing. Ties are broken by short ID in the same direction.
+type URLSort string
+
+// Supported URL listing orders.
+const (
+	SortCreatedAtDesc   URLSort = "-created_at"
+	SortCreatedAtAsc    URLSort = "created_at"
+	SortOriginalURLAsc  URLSort = "original_url"
+	SortOriginalURLDesc URLSort = "-original_url"
+)
+
+// Descending reports whether the order is descending.
+func (s URLSort) Descending() bool {
+	return s == SortCreatedAtDesc || s == SortOriginalURLDesc || s == ""
+}
+
+// ByOriginalURL reports whether the order is by original URL rather than creation time.
+func (s URLSort) ByOriginalURL() bool {
+	return s == SortOriginalURLAsc || s == SortOriginalURLDesc
+}
+
+// URLCursor is the position of the last URL of a page; the next page starts right after it.
+type URLCursor struct {
+	CreatedAt   time.Time // CreatedAt of the last URL, used when sorting by creation time.
+	OriginalURL string    // OriginalURL of the last URL, used when sorting by original URL.
+	ShortID     string    // ShortID of the last URL, breaking ties.
+}
+
+// URLListQuery filters, sorts and limits a user's URL listing.
+type URLListQuery struct {
+	CreatedFrom *time.Time // Only URLs created at or after this moment.
+	CreatedTo   *time.Time // Only URLs created before this moment.
+	Deleted     *bool      // Only deleted or only active URLs; both if nil.
+	Search      string     // Only URLs whose original URL contains this text, ignoring case.
+	Sort        URLSort    // Order of the URLs; SortCreatedAtDesc if empty.
+	After       *URLCursor // Start after this position; from the beginning if nil.
+	Limit       int        // Maximum number of URLs; all if zero.
+}
+
+// Matches reports whether the URL passes the filters of the query, ignoring the cursor.
+func (q URLListQuery) Matches(url *URL) bool {
+	if q.CreatedFrom != nil && url.CreatedAt.Before(*q.CreatedFrom) {
+		return false
+	}
+	if q.CreatedTo != nil && !url.CreatedAt.Before(*q.CreatedTo) {
+		return false
+	}
+	if q.Deleted != nil && url.DeletedFlag != *q.Deleted {
+		return false
+	}
+	return q.Search == "" || strings.Contains(strings.ToLower(url.OriginalURL), strings.ToLower(q.Search))
+}
+
+// Less reports whether URL a comes before URL b in the order of the query.
+func (q URLListQuery) Less(a, b *URL) bool {
+	return q.compare(a.CreatedAt, a.OriginalURL, a.ShortID, b.CreatedAt, b.OriginalURL, b.ShortID) < 0
+}
+
+// IsAfterCursor reports whether the URL comes after the cursor of the query.
+func (q URLListQuery) IsAfterCursor(url *URL) bool {
+	if q.After == nil {
+		return true
+	}
+	return q.compare(url.CreatedAt, url.OriginalURL, url.ShortID, q.After.CreatedAt, q.After.OriginalURL, q.After.ShortID) > 0
+}
+
+// compare orders two positions by the sort key of the query and then by short ID.
+func (q URLListQuery) compare(aCreated time.Time, aURL, aID string, bCreated time.Time, bURL, bID string) int {
+	var result int
+	if q.Sort.ByOriginalURL() {
+		result = strings.Compare(aURL, bURL)
+	} else {
+		result = aCreated.Compare(bCreated)
+	}
+	if result == 0 {
+		result = strings.Compare(aID, bID)
+	}
+	if q.Sort.Descending() {
+		return -result
+	}
+	return result
+}
@@ -0,0 +1,34 @@
+package oidc
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWKSet_PublicKeys(t *testing.T) {
+	encode := base64.RawURLEncoding.EncodeToString
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	set := jwkSet{Keys: []jwk{
+		{Kty: "RSA", Kid: "rsa", Use: "sig", N: encode(rsaKey.N.Bytes()), E: encode(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{Kty: "OKP", Kid: "ed", Crv: "Ed25519", X: encode(edKey)},
+		{Kty: "RSA", Kid: "enc", Use: "enc", N: encode(rsaKey.N.Bytes()), E: "AQAB"},
+		{Kty: "EC", Kid: "secp256k1", Crv: "secp256k1", X: "AA", Y: "AA"},
+		{Kty: "oct", Kid: "secret"},
+	}}
+	keys := set.publicKeys()
+
+	require.Len(t, keys, 2, "Expected encryption and unsupported keys to be left out")
+	assert.True(t, rsaKey.PublicKey.Equal(keys["rsa"]))
+	assert.True(t, edKey.Equal(keys["ed"]))
+}
@@ -0,0 +1,371 @@
+// Package oidc signs users in with an OpenID Connect provider.
+//
+// Provider implements the authorization code flow with PKCE: AuthCodeURL sends the user to
+// the provider, and Exchange trades the code the provider redirects back with for an ID
+// token, whose signature, issuer, audience, expiry and nonce it verifies. The endpoints of
+// the provider are discovered from its /.well-known/openid-configuration document when they
+// are first needed, and the keys signing ID tokens are fetched from its JWKS endpoint, again
+// whenever a token is signed with a key that is not known yet.
+//
+// Example usage:
+//
+//	provider := oidc.New(cfg, log)
+//	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+//	// ... the provider redirects back to the callback with a code
+//	claims, err := provider.Exchange(ctx, code, verifier, nonce)
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/config"
+	"github.com/GlebRadaev/shlink/internal/logger"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// CallbackPath is the path of the callback the provider redirects back to, relative to the base URL.
+const CallbackPath = "/auth/callback"
+
+// requestTimeout bounds the requests sent to the provider.
+const requestTimeout = 10 * time.Second
+
+// keysRefreshInterval is the shortest time between two fetches of the keys of the provider,
+// so tokens signed with unknown keys cannot make the service hammer the provider.
+const keysRefreshInterval = time.Minute
+
+// clockSkew is the difference tolerated between the clocks of the provider and the service.
+const clockSkew = time.Minute
+
+// signingMethods are the algorithms accepted for ID tokens.
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// ErrInvalidIDToken is returned when the ID token of the provider cannot be verified.
+var ErrInvalidIDToken = errors.New("invalid ID token")
+
+// Config holds the settings of the client registered with the provider.
+type Config struct {
+	IssuerURL    string   // URL identifying the provider, under which its discovery document is published.
+	ClientID     string   // Identifier of the client at the provider.
+	ClientSecret string   // Secret authenticating the client; empty for a public client.
+	RedirectURL  string   // URL of the callback the provider redirects back to.
+	LogoutURL    string   // URL the provider sends users back to after signing them out.
+	Scopes       []string // Scopes requested from the provider; openid is always requested.
+}
+
+// Claims are the claims of an ID token used by the service.
+type Claims struct {
+	jwt.RegisteredClaims
+	Nonce           string `json:"nonce,omitempty"`
+	AuthorizedParty string `json:"azp,omitempty"`
+	Email           string `json:"email,omitempty"`
+	EmailVerified   bool   `json:"email_verified,omitempty"`
+}
+
+// metadata holds the parts of the discovery document of the provider used by the client.
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+// Provider is the client of an OpenID Connect provider.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+	now    func() time.Time
+
+	mu            sync.Mutex
+	metadata      *metadata      // Discovered endpoints, nil until discovery succeeds.
+	keys          map[string]any // Public keys of the provider by key ID.
+	keysFetchedAt time.Time      // When the keys were last fetched.
+}
+
+// NewProvider creates the client of the provider with the settings, sending requests with client.
+func NewProvider(cfg Config, client *http.Client) *Provider {
+	if !slices.Contains(cfg.Scopes, "openid") {
+		cfg.Scopes = append([]string{"openid"}, cfg.Scopes...)
+	}
+	return &Provider{cfg: cfg, client: client, now: time.Now}
+}
+
+// New creates the client of the provider of the configuration, or returns nil if sign-in is
+// disabled or the client is not configured.
+func New(cfg *config.Config, log *logger.Logger) *Provider {
+	if cfg.OIDCIssuerURL == "" {
+		return nil
+	}
+	logger := log.Named("OIDC")
+	if cfg.OIDCClientID == "" {
+		logger.Error("Sign-in is disabled: an OpenID Connect issuer is set without a client ID.")
+		return nil
+	}
+	redirectURL := cfg.OIDCRedirectURL
+	if redirectURL == "" {
+		redirectURL = strings.TrimSuffix(cfg.BaseURL, "/") + CallbackPath
+	}
+	logger.Infof("Signing users in with %s.", cfg.OIDCIssuerURL)
+	return NewProvider(Config{
+		IssuerURL:    cfg.OIDCIssuerURL,
+		ClientID:     cfg.OIDCClientID,
+		ClientSecret: cfg.OIDCClientSecret,
+		RedirectURL:  redirectURL,
+		LogoutURL:    cfg.BaseURL,
+		Scopes:       strings.Fields(cfg.OIDCScopes),
+	}, &http.Client{Timeout: requestTimeout})
+}
+
+// Issuer returns the URL identifying the provider, which scopes the subjects of its users.
+func (p *Provider) Issuer() string {
+	return p.cfg.IssuerURL
+}
+
+// AuthCodeURL returns the URL of the provider the user signs in at. The provider redirects
+// back with state, includes nonce in the ID token and only hands out the token to a client
+// presenting verifier.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	return withQuery(md.AuthorizationEndpoint, query), nil
+}
+
+// Exchange trades the authorization code for an ID token and returns its verified claims.
+// Tokens that cannot be verified or do not carry nonce are rejected with ErrInvalidIDToken.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Claims, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {verifier},
+		"client_id":     {p.cfg.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+	var tokens struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.doJSON(req, &tokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %v", err)
+	}
+	if status != http.StatusOK || tokens.Error != "" {
+		return nil, fmt.Errorf("failed to exchange code: status %d: %s %s", status, tokens.Error, tokens.ErrorDescription)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("%w: the token response has no ID token", ErrInvalidIDToken)
+	}
+	return p.VerifyIDToken(ctx, tokens.IDToken, nonce)
+}
+
+// VerifyIDToken verifies the ID token issued by the provider for the client and returns its claims.
+func (p *Provider) VerifyIDToken(ctx context.Context, rawToken, nonce string) (*Claims, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	claims := &Claims{}
+	_, err = jwt.ParseWithClaims(rawToken, claims, func(t *jwt.Token) (interface{}, error) {
+		return p.verificationKey(ctx, t)
+	},
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(md.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+		jwt.WithTimeFunc(p.now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.cfg.ClientID {
+		return nil, fmt.Errorf("%w: issued to %q", ErrInvalidIDToken, claims.AuthorizedParty)
+	}
+	if claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	return claims, nil
+}
+
+// LogoutURL returns the URL signing the user out at the provider, which sends the user back
+// to the service. It returns false if the provider does not publish such an endpoint.
+func (p *Provider) LogoutURL(ctx context.Context) (string, bool) {
+	md, err := p.discover(ctx)
+	if err != nil || md.EndSessionEndpoint == "" {
+		return "", false
+	}
+	query := url.Values{"client_id": {p.cfg.ClientID}}
+	if p.cfg.LogoutURL != "" {
+		query.Set("post_logout_redirect_uri", p.cfg.LogoutURL)
+	}
+	return withQuery(md.EndSessionEndpoint, query), true
+}
+
+// discover returns the endpoints of the provider, fetching its discovery document on first use.
+// A failed discovery is retried by the next call.
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+	issuer := strings.TrimSuffix(p.cfg.IssuerURL, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	md := &metadata{}
+	status, err := p.doJSON(req, md)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover provider: %v", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("failed to discover provider: status %d", status)
+	}
+	if strings.TrimSuffix(md.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("failed to discover provider: the document is for issuer %q", md.Issuer)
+	}
+	if md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" || md.JWKSURI == "" {
+		return nil, errors.New("failed to discover provider: the document lacks required endpoints")
+	}
+	p.metadata = md
+	return md, nil
+}
+
+// verificationKey returns the key of the provider verifying the token, fetching the keys of
+// the provider again if the token names a key that is not known yet.
+func (p *Provider) verificationKey(ctx context.Context, t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if !p.keysFetchedAt.IsZero() && p.now().Sub(p.keysFetchedAt) < keysRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if err := p.fetchKeys(ctx); err != nil {
+		return nil, err
+	}
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey returns the known key with the ID, or all known keys for a token without a key ID.
+// It must be called with the mutex held.
+func (p *Provider) lookupKey(kid string) (interface{}, bool) {
+	if kid != "" {
+		key, ok := p.keys[kid]
+		return key, ok
+	}
+	var set jwt.VerificationKeySet
+	for _, key := range p.keys {
+		set.Keys = append(set.Keys, key)
+	}
+	return set, len(set.Keys) > 0
+}
+
+// fetchKeys replaces the known keys with the keys published by the provider.
+// It must be called with the mutex held.
+func (p *Provider) fetchKeys(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.metadata.JWKSURI, nil)
+	if err != nil {
+		return err
+	}
+	var set jwkSet
+	status, err := p.doJSON(req, &set)
+	if err != nil {
+		return fmt.Errorf("failed to fetch signing keys: %v", err)
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("failed to fetch signing keys: status %d", status)
+	}
+	p.keys = set.publicKeys()
+	p.keysFetchedAt = p.now()
+	return nil
+}
+
+// doJSON sends the request and decodes the JSON body of the response into v.
+func (p *Provider) doJSON(req *http.Request, v any) (int, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if err := json.Unmarshal(body, v); err != nil && resp.StatusCode == http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("invalid response: %v", err)
+	}
+	return resp.StatusCode, nil
+}
+
+// withQuery adds the query parameters to the endpoint, which may already have some.
+func withQuery(endpoint string, query url.Values) string {
+	separator := "?"
+	if strings.Contains(endpoint, "?") {
+		separator = "&"
+	}
+	return endpoint + separator + query.Encode()
+}
+
+// RandomString returns a random URL-safe string suitable for a state, a nonce or a PKCE verifier.
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge returns the S256 PKCE challenge of the verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
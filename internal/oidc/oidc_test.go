@@ -0,0 +1,195 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/config"
+	"github.com/GlebRadaev/shlink/internal/logger"
+	"github.com/GlebRadaev/shlink/internal/oidc"
+	"github.com/GlebRadaev/shlink/internal/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const redirectURL = "http://shlink.test/auth/callback"
+
+// setup starts a stub provider and creates a client registered with it.
+func setup(t *testing.T) (*oidctest.Server, *oidc.Provider) {
+	idp := oidctest.NewServer(t, "shlink", "secret")
+	provider := oidc.NewProvider(oidc.Config{
+		IssuerURL:    idp.URL,
+		ClientID:     "shlink",
+		ClientSecret: "secret",
+		RedirectURL:  redirectURL,
+		LogoutURL:    "http://shlink.test",
+		Scopes:       []string{"email"},
+	}, idp.Client())
+	return idp, provider
+}
+
+// authorize sends the user to the authorization URL and returns the code the provider redirects back with.
+func authorize(t *testing.T, idp *oidctest.Server, authURL, wantState string) string {
+	t.Helper()
+	client := idp.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, redirectURL, location.Scheme+"://"+location.Host+location.Path)
+	assert.Equal(t, wantState, location.Query().Get("state"))
+	return location.Query().Get("code")
+}
+
+func TestProvider_Exchange(t *testing.T) {
+	ctx := context.Background()
+	idp, provider := setup(t)
+	idp.SignInAs("alice", "alice@example.com")
+
+	authURL, err := provider.AuthCodeURL(ctx, "state1", "nonce1", "verifier1")
+	require.NoError(t, err)
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, "openid email", parsed.Query().Get("scope"), "Expected openid to be requested")
+	assert.Equal(t, oidc.CodeChallenge("verifier1"), parsed.Query().Get("code_challenge"))
+
+	code := authorize(t, idp, authURL, "state1")
+	claims, err := provider.Exchange(ctx, code, "verifier1", "nonce1")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", claims.Subject)
+	assert.Equal(t, "alice@example.com", claims.Email)
+
+	_, err = provider.Exchange(ctx, code, "verifier1", "nonce1")
+	assert.Error(t, err, "Expected a code to be used once")
+
+	authURL, err = provider.AuthCodeURL(ctx, "state2", "nonce2", "verifier2")
+	require.NoError(t, err)
+	code = authorize(t, idp, authURL, "state2")
+	_, err = provider.Exchange(ctx, code, "wrong verifier", "nonce2")
+	assert.Error(t, err, "Expected the PKCE verifier to be checked")
+
+	authURL, err = provider.AuthCodeURL(ctx, "state3", "nonce3", "verifier3")
+	require.NoError(t, err)
+	code = authorize(t, idp, authURL, "state3")
+	_, err = provider.Exchange(ctx, code, "verifier3", "other nonce")
+	assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+}
+
+func TestProvider_VerifyIDToken(t *testing.T) {
+	ctx := context.Background()
+	idp, provider := setup(t)
+	other := oidctest.NewServer(t, "shlink", "secret")
+	now := time.Now()
+	valid := func() *oidc.Claims {
+		return &oidc.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    idp.URL,
+				Subject:   "alice",
+				Audience:  jwt.ClaimStrings{"shlink"},
+				IssuedAt:  jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			},
+			Nonce: "nonce",
+		}
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, idp.SignIDToken(valid()), "nonce")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", claims.Subject)
+
+	tests := []struct {
+		name   string
+		modify func(c *oidc.Claims)
+		signer *oidctest.Server
+	}{
+		{name: "other issuer", modify: func(c *oidc.Claims) { c.Issuer = other.URL }},
+		{name: "other audience", modify: func(c *oidc.Claims) { c.Audience = jwt.ClaimStrings{"other"} }},
+		{name: "other authorized party", modify: func(c *oidc.Claims) {
+			c.Audience = jwt.ClaimStrings{"shlink", "other"}
+			c.AuthorizedParty = "other"
+		}},
+		{name: "expired", modify: func(c *oidc.Claims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Hour)) }},
+		{name: "no expiry", modify: func(c *oidc.Claims) { c.ExpiresAt = nil }},
+		{name: "no subject", modify: func(c *oidc.Claims) { c.Subject = "" }},
+		{name: "other nonce", modify: func(c *oidc.Claims) { c.Nonce = "other" }},
+		{name: "signed by another provider", modify: func(c *oidc.Claims) {}, signer: other},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.modify(claims)
+			signer := idp
+			if tt.signer != nil {
+				signer = tt.signer
+			}
+			_, err := provider.VerifyIDToken(ctx, signer.SignIDToken(claims), "nonce")
+			assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+		})
+	}
+
+	t.Run("unsigned", func(t *testing.T) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodNone, valid()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+		require.NoError(t, err)
+		_, err = provider.VerifyIDToken(ctx, token, "nonce")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+
+	t.Run("HMAC signed with the client secret", func(t *testing.T) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, valid()).SignedString([]byte("secret"))
+		require.NoError(t, err)
+		_, err = provider.VerifyIDToken(ctx, token, "nonce")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, valid())
+		token.Header["kid"] = "rotated"
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		_, err = provider.VerifyIDToken(ctx, signed, "nonce")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+}
+
+func TestProvider_Discovery(t *testing.T) {
+	ctx := context.Background()
+	idp := oidctest.NewServer(t, "shlink", "")
+
+	provider := oidc.NewProvider(oidc.Config{IssuerURL: idp.URL + "/tenant", ClientID: "shlink"}, idp.Client())
+	_, err := provider.AuthCodeURL(ctx, "state", "nonce", "verifier")
+	assert.Error(t, err, "Expected a missing discovery document to fail")
+
+	provider = oidc.NewProvider(oidc.Config{IssuerURL: idp.URL + "/", ClientID: "shlink", LogoutURL: "http://shlink.test"}, idp.Client())
+	logoutURL, ok := provider.LogoutURL(ctx)
+	require.True(t, ok)
+	assert.Equal(t, idp.URL+"/logout?client_id=shlink&post_logout_redirect_uri=http%3A%2F%2Fshlink.test", logoutURL)
+}
+
+func TestNew(t *testing.T) {
+	log, _ := logger.NewLogger("info")
+
+	assert.Nil(t, oidc.New(&config.Config{}, log), "Expected sign-in to be disabled without an issuer")
+	assert.Nil(t, oidc.New(&config.Config{OIDCIssuerURL: "https://idp.example.com"}, log),
+		"Expected sign-in to be disabled without a client ID")
+
+	provider := oidc.New(&config.Config{
+		BaseURL:       "http://shlink.test/",
+		OIDCIssuerURL: "https://idp.example.com",
+		OIDCClientID:  "shlink",
+		OIDCScopes:    "openid email",
+	}, log)
+	require.NotNil(t, provider)
+	assert.Equal(t, "https://idp.example.com", provider.Issuer())
+}
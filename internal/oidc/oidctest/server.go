@@ -0,0 +1,235 @@
+// Package oidctest provides a stub OpenID Connect provider for tests.
+//
+// The Server publishes a discovery document, signs ID tokens with an ES256 key published
+// at its JWKS endpoint and signs in every user that reaches its authorization endpoint as
+// the account set with SignInAs, without showing a login page. It checks the client
+// credentials, the redirect URI and the PKCE verifier like a real provider does.
+//
+// Example usage:
+//
+//	idp := oidctest.NewServer(t, "client", "secret")
+//	idp.SignInAs("alice", "alice@example.com")
+//	provider := oidc.NewProvider(oidc.Config{IssuerURL: idp.URL, ClientID: "client", ...}, idp.Client())
+package oidctest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/oidc"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyID is the ID of the key signing the ID tokens.
+const KeyID = "stub-key"
+
+// authorization is a code handed out by the authorization endpoint.
+type authorization struct {
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	subject       string
+	email         string
+}
+
+// Server is a stub OpenID Connect provider.
+type Server struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	key   *ecdsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]authorization
+	sub   string
+	email string
+}
+
+// NewServer starts a provider accepting the client, which is closed when the test ends.
+// An empty secret registers a public client.
+func NewServer(t *testing.T, clientID, clientSecret string) *Server {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	s := &Server{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		codes:        make(map[string]authorization),
+		sub:          "subject",
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/logout", s.logout)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// SignInAs sets the account the following authorizations sign in as.
+func (s *Server) SignInAs(subject, email string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sub = subject
+	s.email = email
+}
+
+// SignIDToken signs the claims with the key of the provider, for tests of token verification.
+func (s *Server) SignIDToken(claims jwt.Claims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = KeyID
+	signed, err := token.SignedString(s.key)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+// discovery serves the discovery document.
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                s.URL,
+		"authorization_endpoint":                s.URL + "/authorize",
+		"token_endpoint":                        s.URL + "/token",
+		"jwks_uri":                              s.URL + "/jwks",
+		"end_session_endpoint":                  s.URL + "/logout",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"ES256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// authorize signs the user in and redirects back to the client with a code.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI := query.Get("redirect_uri")
+	if query.Get("client_id") != s.ClientID || redirectURI == "" {
+		http.Error(w, "unknown client", http.StatusBadRequest)
+		return
+	}
+	callback, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	params := callback.Query()
+	params.Set("state", query.Get("state"))
+	if query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" {
+		params.Set("error", "unsupported_response_type")
+	} else {
+		code, _ := oidc.RandomString()
+		s.mu.Lock()
+		s.codes[code] = authorization{
+			redirectURI:   redirectURI,
+			nonce:         query.Get("nonce"),
+			codeChallenge: query.Get("code_challenge"),
+			subject:       s.sub,
+			email:         s.email,
+		}
+		s.mu.Unlock()
+		params.Set("code", code)
+	}
+	callback.RawQuery = params.Encode()
+	http.Redirect(w, r, callback.String(), http.StatusFound)
+}
+
+// token trades a code for an ID token. Each code can be used once.
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID = r.PostForm.Get("client_id")
+	}
+	if clientID != s.ClientID || clientSecret != s.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	code := r.PostForm.Get("code")
+	s.mu.Lock()
+	auth, exists := s.codes[code]
+	delete(s.codes, code)
+	s.mu.Unlock()
+	if !exists || r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("redirect_uri") != auth.redirectURI ||
+		oidc.CodeChallenge(r.PostForm.Get("code_verifier")) != auth.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	now := time.Now()
+	idToken := s.SignIDToken(&oidc.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.URL,
+			Subject:   auth.subject,
+			Audience:  jwt.ClaimStrings{s.ClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+		Nonce:         auth.nonce,
+		Email:         auth.email,
+		EmailVerified: auth.email != "",
+	})
+	accessToken, _ := oidc.RandomString()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// jwks serves the public key signing the ID tokens.
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "EC",
+			"kid": KeyID,
+			"use": "sig",
+			"alg": "ES256",
+			"crv": "P-256",
+			"x":   encode(s.key.X.FillBytes(make([]byte, 32))),
+			"y":   encode(s.key.Y.FillBytes(make([]byte, 32))),
+		}},
+	})
+}
+
+// logout redirects back to the client.
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	if target := r.URL.Query().Get("post_logout_redirect_uri"); target != "" {
+		http.Redirect(w, r, target, http.StatusFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeJSON writes v as the JSON body of the response.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,102 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/interfaces/apikey.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/interfaces/apikey.go -destination=internal/repository/apikey_mock.go -package=repository
+//
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	model "github.com/GlebRadaev/shlink/internal/model"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockIAPIKeyRepository is a mock of IAPIKeyRepository interface.
+type MockIAPIKeyRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockIAPIKeyRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockIAPIKeyRepositoryMockRecorder is the mock recorder for MockIAPIKeyRepository.
+type MockIAPIKeyRepositoryMockRecorder struct {
+	mock *MockIAPIKeyRepository
+}
+
+// NewMockIAPIKeyRepository creates a new mock instance.
+func NewMockIAPIKeyRepository(ctrl *gomock.Controller) *MockIAPIKeyRepository {
+	mock := &MockIAPIKeyRepository{ctrl: ctrl}
+	mock.recorder = &MockIAPIKeyRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIAPIKeyRepository) EXPECT() *MockIAPIKeyRepositoryMockRecorder {
+	return m.recorder
+}
+
+// FindByHash mocks base method.
+func (m *MockIAPIKeyRepository) FindByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByHash", ctx, keyHash)
+	ret0, _ := ret[0].(*model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByHash indicates an expected call of FindByHash.
+func (mr *MockIAPIKeyRepositoryMockRecorder) FindByHash(ctx, keyHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByHash", reflect.TypeOf((*MockIAPIKeyRepository)(nil).FindByHash), ctx, keyHash)
+}
+
+// FindListByUserID mocks base method.
+func (m *MockIAPIKeyRepository) FindListByUserID(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindListByUserID", ctx, userID)
+	ret0, _ := ret[0].([]*model.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindListByUserID indicates an expected call of FindListByUserID.
+func (mr *MockIAPIKeyRepositoryMockRecorder) FindListByUserID(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindListByUserID", reflect.TypeOf((*MockIAPIKeyRepository)(nil).FindListByUserID), ctx, userID)
+}
+
+// Insert mocks base method.
+func (m *MockIAPIKeyRepository) Insert(ctx context.Context, key *model.APIKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockIAPIKeyRepositoryMockRecorder) Insert(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockIAPIKeyRepository)(nil).Insert), ctx, key)
+}
+
+// Revoke mocks base method.
+func (m *MockIAPIKeyRepository) Revoke(ctx context.Context, userID, id string, revokedAt time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, userID, id, revokedAt)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockIAPIKeyRepositoryMockRecorder) Revoke(ctx, userID, id, revokedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockIAPIKeyRepository)(nil).Revoke), ctx, userID, id, revokedAt)
+}
@@ -0,0 +1,71 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/interfaces/audit.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/interfaces/audit.go -destination=internal/repository/audit_mock.go -package=repository
+//
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/GlebRadaev/shlink/internal/model"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockIAuditRepository is a mock of IAuditRepository interface.
+type MockIAuditRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockIAuditRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockIAuditRepositoryMockRecorder is the mock recorder for MockIAuditRepository.
+type MockIAuditRepositoryMockRecorder struct {
+	mock *MockIAuditRepository
+}
+
+// NewMockIAuditRepository creates a new mock instance.
+func NewMockIAuditRepository(ctrl *gomock.Controller) *MockIAuditRepository {
+	mock := &MockIAuditRepository{ctrl: ctrl}
+	mock.recorder = &MockIAuditRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIAuditRepository) EXPECT() *MockIAuditRepositoryMockRecorder {
+	return m.recorder
+}
+
+// FindList mocks base method.
+func (m *MockIAuditRepository) FindList(ctx context.Context, query model.AuditQuery) ([]*model.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindList", ctx, query)
+	ret0, _ := ret[0].([]*model.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindList indicates an expected call of FindList.
+func (mr *MockIAuditRepositoryMockRecorder) FindList(ctx, query any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindList", reflect.TypeOf((*MockIAuditRepository)(nil).FindList), ctx, query)
+}
+
+// Insert mocks base method.
+func (m *MockIAuditRepository) Insert(ctx context.Context, entry *model.AuditEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockIAuditRepositoryMockRecorder) Insert(ctx, entry any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockIAuditRepository)(nil).Insert), ctx, entry)
+}
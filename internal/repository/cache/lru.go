@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// lruEntry is a cached lookup result. A nil url records that the short ID does not exist.
+type lruEntry struct {
+	key     string
+	url     *model.URL
+	expires time.Time
+}
+
+// lru is a size-bounded cache of lookup results that evicts the least recently used
+// entry when full and drops entries once their TTL has passed.
+type lru struct {
+	mu         sync.Mutex
+	size       int
+	items      map[string]*list.Element
+	order      *list.List // Front is the most recently used entry
+	generation uint64     // Incremented on every invalidation
+	evictions  uint64
+	now        func() time.Time
+}
+
+// newLRU creates an lru holding at most size entries.
+func newLRU(size int) *lru {
+	return &lru{
+		size:  size,
+		items: make(map[string]*list.Element, size),
+		order: list.New(),
+		now:   time.Now,
+	}
+}
+
+// get returns the cached URL for the key and whether the key was cached and not expired.
+// A cached nil URL means the key is known not to exist.
+func (c *lru) get(key string) (*model.URL, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if !c.now().Before(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.url, true
+}
+
+// put caches the URL for the key for the ttl, unless the cache was invalidated after
+// generation was read, in which case the URL may already be stale and is dropped.
+func (c *lru) put(key string, url *model.URL, ttl time.Duration, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	expires := c.now().Add(ttl)
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.url, entry.expires = url, expires
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, url: url, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+		c.evictions++
+	}
+}
+
+// currentGeneration returns the generation to pass to put for a lookup starting now.
+func (c *lru) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// remove drops the keys from the cache.
+func (c *lru) remove(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for _, key := range keys {
+		if elem, ok := c.items[key]; ok {
+			c.order.Remove(elem)
+			delete(c.items, key)
+		}
+	}
+}
+
+// purge drops all entries from the cache.
+func (c *lru) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.items = make(map[string]*list.Element, c.size)
+	c.order.Init()
+}
+
+// stats returns the number of cached entries and of evictions so far.
+func (c *lru) stats() (int, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len(), c.evictions
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU_Expiration(t *testing.T) {
+	now := time.Date(2024, time.November, 17, 12, 0, 0, 0, time.UTC)
+	cache := newLRU(10)
+	cache.now = func() time.Time { return now }
+
+	cache.put("abc", &model.URL{ShortID: "abc"}, time.Minute, cache.currentGeneration())
+	cache.put("missing", nil, time.Second, cache.currentGeneration())
+
+	url, ok := cache.get("abc")
+	assert.True(t, ok)
+	assert.Equal(t, "abc", url.ShortID)
+	url, ok = cache.get("missing")
+	assert.True(t, ok, "unknown short IDs are cached")
+	assert.Nil(t, url)
+
+	now = now.Add(time.Second)
+	_, ok = cache.get("missing")
+	assert.False(t, ok, "entries expire after their TTL")
+	_, ok = cache.get("abc")
+	assert.True(t, ok)
+	size, _ := cache.stats()
+	assert.Equal(t, 1, size, "expired entries are dropped")
+}
+
+func TestLRU_Eviction(t *testing.T) {
+	cache := newLRU(2)
+
+	cache.put("a", &model.URL{ShortID: "a"}, time.Minute, cache.currentGeneration())
+	cache.put("b", &model.URL{ShortID: "b"}, time.Minute, cache.currentGeneration())
+	_, ok := cache.get("a")
+	assert.True(t, ok)
+	cache.put("c", &model.URL{ShortID: "c"}, time.Minute, cache.currentGeneration())
+
+	_, ok = cache.get("b")
+	assert.False(t, ok, "the least recently used entry is evicted")
+	_, ok = cache.get("a")
+	assert.True(t, ok)
+	_, ok = cache.get("c")
+	assert.True(t, ok)
+	size, evictions := cache.stats()
+	assert.Equal(t, 2, size)
+	assert.Equal(t, uint64(1), evictions)
+}
+
+func TestLRU_Invalidation(t *testing.T) {
+	cache := newLRU(10)
+	cache.put("a", &model.URL{ShortID: "a"}, time.Minute, cache.currentGeneration())
+	cache.put("b", &model.URL{ShortID: "b"}, time.Minute, cache.currentGeneration())
+
+	generation := cache.currentGeneration()
+	cache.remove("a")
+	_, ok := cache.get("a")
+	assert.False(t, ok)
+	_, ok = cache.get("b")
+	assert.True(t, ok)
+
+	cache.put("a", &model.URL{ShortID: "a"}, time.Minute, generation)
+	_, ok = cache.get("a")
+	assert.False(t, ok, "loads started before an invalidation are not cached")
+
+	cache.purge()
+	_, ok = cache.get("b")
+	assert.False(t, ok)
+}
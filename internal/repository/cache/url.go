@@ -0,0 +1,215 @@
+// Package cache provides a read-through cache in front of an interfaces.IURLRepository.
+//
+// URLRepository keeps the results of FindByID, the lookup behind every redirect, in a
+// size-bounded LRU with a TTL. Unknown short IDs are cached as well, for a shorter TTL,
+// so scans for random IDs do not reach the database either. Concurrent lookups of the
+// same short ID are coalesced into a single query.
+//
+// Entries are invalidated when the URL is inserted, updated, deleted, disabled or clicked through
+// this decorator. Changes made by other instances sharing the database become visible
+// once the cached entry expires.
+//
+// Example usage:
+//
+//	repo := cache.NewURLRepository(database.NewURLRepository(pool), 10000, time.Minute, 5*time.Second)
+//	url, err := repo.FindByID(ctx, "abc123")
+//	stats := repo.Stats() // hits, misses and evictions so far
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"golang.org/x/sync/singleflight"
+)
+
+// Stats holds the counters of a URL cache.
+type Stats struct {
+	Hits      uint64 // Lookups answered from the cache, including unknown short IDs
+	Misses    uint64 // Lookups passed on to the repository
+	Evictions uint64 // Entries dropped to keep the cache within its size
+	Size      int    // Entries currently cached
+}
+
+// URLRepository is a caching decorator for an IURLRepository.
+type URLRepository struct {
+	repo        interfaces.IURLRepository
+	cache       *lru
+	ttl         time.Duration
+	negativeTTL time.Duration
+	group       singleflight.Group
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+}
+
+// NewURLRepository creates a cache holding up to size URLs of repo. Found URLs are cached
+// for ttl and unknown short IDs for negativeTTL; a non-positive negativeTTL disables
+// caching of unknown short IDs.
+func NewURLRepository(repo interfaces.IURLRepository, size int, ttl, negativeTTL time.Duration) *URLRepository {
+	return &URLRepository{
+		repo:        repo,
+		cache:       newLRU(size),
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}
+}
+
+// Stats returns the current counters of the cache.
+func (r *URLRepository) Stats() Stats {
+	size, evictions := r.cache.stats()
+	return Stats{
+		Hits:      r.hits.Load(),
+		Misses:    r.misses.Load(),
+		Evictions: evictions,
+		Size:      size,
+	}
+}
+
+// Insert inserts the URL into the repository and invalidates its short ID.
+func (r *URLRepository) Insert(ctx context.Context, url *model.URL) (*model.URL, error) {
+	requested := url.ShortID
+	result, err := r.repo.Insert(ctx, url)
+	r.cache.remove(requested, url.ShortID)
+	return result, err
+}
+
+// InsertList inserts the URLs into the repository and invalidates their short IDs.
+func (r *URLRepository) InsertList(ctx context.Context, urls []*model.URL) ([]*model.URL, error) {
+	shortIDs := make([]string, 0, 2*len(urls))
+	for _, url := range urls {
+		shortIDs = append(shortIDs, url.ShortID)
+	}
+	result, err := r.repo.InsertList(ctx, urls)
+	for _, url := range urls {
+		shortIDs = append(shortIDs, url.ShortID)
+	}
+	r.cache.remove(shortIDs...)
+	return result, err
+}
+
+// FindByID returns the URL with the short ID from the cache, or loads it from the
+// repository if it is not cached. Concurrent loads of the same short ID share one query.
+// The returned URL is a copy and may be modified by the caller.
+func (r *URLRepository) FindByID(ctx context.Context, shortID string) (*model.URL, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if url, ok := r.cache.get(shortID); ok {
+		r.hits.Add(1)
+		return copyURL(url), nil
+	}
+	r.misses.Add(1)
+
+	// The load is shared by all waiting callers, so it must not be cancelled with the first one.
+	loadCtx := context.WithoutCancel(ctx)
+	result := r.group.DoChan(shortID, func() (interface{}, error) {
+		generation := r.cache.currentGeneration()
+		url, err := r.repo.FindByID(loadCtx, shortID)
+		if err != nil {
+			return nil, err
+		}
+		if url != nil {
+			r.cache.put(shortID, url, r.ttl, generation)
+		} else if r.negativeTTL > 0 {
+			r.cache.put(shortID, nil, r.negativeTTL, generation)
+		}
+		return url, nil
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-result:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return copyURL(res.Val.(*model.URL)), nil
+	}
+}
+
+// FindListByUserID returns the URLs of the user from the repository, bypassing the cache.
+func (r *URLRepository) FindListByUserID(ctx context.Context, userID string, query model.URLListQuery) ([]*model.URL, error) {
+	return r.repo.FindListByUserID(ctx, userID, query)
+}
+
+// UpdateOriginalURL updates the URL in the repository and invalidates its short ID.
+func (r *URLRepository) UpdateOriginalURL(ctx context.Context, userID, shortID, originalURL string) (bool, error) {
+	updated, err := r.repo.UpdateOriginalURL(ctx, userID, shortID, originalURL)
+	r.cache.remove(shortID)
+	return updated, err
+}
+
+// DeleteListByUserIDAndShortIDs deletes the URLs in the repository and invalidates their short IDs.
+func (r *URLRepository) DeleteListByUserIDAndShortIDs(ctx context.Context, userID string, shortIDs []string) error {
+	err := r.repo.DeleteListByUserIDAndShortIDs(ctx, userID, shortIDs)
+	r.cache.remove(shortIDs...)
+	return err
+}
+
+// ReassignUserID moves the URLs in the repository and, if any were moved, clears the cache.
+func (r *URLRepository) ReassignUserID(ctx context.Context, fromUserID, toUserID string) (int64, error) {
+	count, err := r.repo.ReassignUserID(ctx, fromUserID, toUserID)
+	if count > 0 {
+		r.cache.purge()
+	}
+	return count, err
+}
+
+// SetDisabled changes the URLs in the repository and invalidates their short IDs.
+func (r *URLRepository) SetDisabled(ctx context.Context, shortIDs []string, disabled bool) (int64, error) {
+	count, err := r.repo.SetDisabled(ctx, shortIDs, disabled)
+	r.cache.remove(shortIDs...)
+	return count, err
+}
+
+// FindListByDomain returns the URLs pointing to the domain from the repository, bypassing the cache.
+func (r *URLRepository) FindListByDomain(ctx context.Context, domain string) ([]*model.URL, error) {
+	return r.repo.FindListByDomain(ctx, domain)
+}
+
+// IncrementClicks counts the click in the repository and invalidates the short ID,
+// whose cached click counter is out of date now.
+func (r *URLRepository) IncrementClicks(ctx context.Context, shortID string) (bool, error) {
+	ok, err := r.repo.IncrementClicks(ctx, shortID)
+	r.cache.remove(shortID)
+	return ok, err
+}
+
+// DeleteExpired deletes the expired URLs in the repository and, if any were deleted, clears the cache.
+func (r *URLRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	count, err := r.repo.DeleteExpired(ctx, now)
+	if count > 0 {
+		r.cache.purge()
+	}
+	return count, err
+}
+
+// List returns all URLs from the repository, bypassing the cache.
+func (r *URLRepository) List(ctx context.Context) ([]*model.URL, error) {
+	return r.repo.List(ctx)
+}
+
+// Ping checks the health of the repository.
+func (r *URLRepository) Ping(ctx context.Context) error {
+	return r.repo.Ping(ctx)
+}
+
+// copyURL returns a deep copy of the URL, so callers cannot modify the cached one, including
+// through its optional fields.
+func copyURL(url *model.URL) *model.URL {
+	if url == nil {
+		return nil
+	}
+	urlCopy := *url
+	if url.ExpiresAt != nil {
+		expiresAt := *url.ExpiresAt
+		urlCopy.ExpiresAt = &expiresAt
+	}
+	if url.MaxClicks != nil {
+		maxClicks := *url.MaxClicks
+		urlCopy.MaxClicks = &maxClicks
+	}
+	return &urlCopy
+}
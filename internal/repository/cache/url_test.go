@@ -0,0 +1,233 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/repository"
+	"github.com/GlebRadaev/shlink/internal/repository/cache"
+	"github.com/GlebRadaev/shlink/internal/repository/inmemory"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func setupCache(t *testing.T) (*cache.URLRepository, *repository.MockIURLRepository) {
+	ctrl := gomock.NewController(t)
+	repo := repository.NewMockIURLRepository(ctrl)
+	return cache.NewURLRepository(repo, 10, time.Minute, time.Minute), repo
+}
+
+func TestURLRepository_FindByID(t *testing.T) {
+	ctx := context.Background()
+	urlCache, repo := setupCache(t)
+	repo.EXPECT().FindByID(gomock.Any(), "abc123").Return(&model.URL{ShortID: "abc123", OriginalURL: "http://example.com"}, nil).Times(1)
+	repo.EXPECT().FindByID(gomock.Any(), "missing").Return(nil, nil).Times(1)
+
+	for i := 0; i < 3; i++ {
+		url, err := urlCache.FindByID(ctx, "abc123")
+		require.NoError(t, err)
+		assert.Equal(t, "http://example.com", url.OriginalURL)
+		url.OriginalURL = "http://modified.com"
+
+		url, err = urlCache.FindByID(ctx, "missing")
+		require.NoError(t, err)
+		assert.Nil(t, url)
+	}
+	assert.Equal(t, cache.Stats{Hits: 4, Misses: 2, Size: 2}, urlCache.Stats())
+}
+
+func TestURLRepository_FindByID_DeepCopy(t *testing.T) {
+	ctx := context.Background()
+	urlCache, repo := setupCache(t)
+	expiresAt := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	maxClicks := 10
+	repo.EXPECT().FindByID(gomock.Any(), "abc123").Return(&model.URL{ShortID: "abc123", OriginalURL: "http://example.com",
+		ExpiresAt: &expiresAt, MaxClicks: &maxClicks, PasswordHash: "hash"}, nil).Times(1)
+
+	for i := 0; i < 2; i++ {
+		url, err := urlCache.FindByID(ctx, "abc123")
+		require.NoError(t, err)
+		require.NotNil(t, url.ExpiresAt)
+		require.NotNil(t, url.MaxClicks)
+		assert.Equal(t, expiresAt, *url.ExpiresAt, "Expected the cached expiry to be left unchanged")
+		assert.Equal(t, 10, *url.MaxClicks, "Expected the cached click budget to be left unchanged")
+		assert.Equal(t, "hash", url.PasswordHash)
+		*url.ExpiresAt = expiresAt.Add(-time.Hour)
+		*url.MaxClicks = 0
+		url.PasswordHash = ""
+	}
+}
+
+func TestURLRepository_FindByID_Error(t *testing.T) {
+	ctx := context.Background()
+	urlCache, repo := setupCache(t)
+	repo.EXPECT().FindByID(gomock.Any(), "abc123").Return(nil, errors.New("connection refused")).Times(2)
+
+	for i := 0; i < 2; i++ {
+		_, err := urlCache.FindByID(ctx, "abc123")
+		assert.EqualError(t, err, "connection refused", "Expected errors not to be cached")
+	}
+}
+
+func TestURLRepository_FindByID_Coalescing(t *testing.T) {
+	ctx := context.Background()
+	urlCache, repo := setupCache(t)
+	release := make(chan struct{})
+	repo.EXPECT().FindByID(gomock.Any(), "abc123").DoAndReturn(func(context.Context, string) (*model.URL, error) {
+		<-release
+		return &model.URL{ShortID: "abc123", OriginalURL: "http://example.com"}, nil
+	}).Times(1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			url, err := urlCache.FindByID(ctx, "abc123")
+			if assert.NoError(t, err) {
+				assert.Equal(t, "http://example.com", url.OriginalURL)
+			}
+		}()
+	}
+	// Let the callers queue up on the load before it completes.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+}
+
+func TestURLRepository_FindByID_CancelledWhileWaiting(t *testing.T) {
+	urlCache, repo := setupCache(t)
+	release := make(chan struct{})
+	repo.EXPECT().FindByID(gomock.Any(), "abc123").DoAndReturn(func(ctx context.Context, _ string) (*model.URL, error) {
+		<-release
+		return &model.URL{ShortID: "abc123"}, ctx.Err()
+	}).Times(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := urlCache.FindByID(ctx, "abc123")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(release)
+	assert.Eventually(t, func() bool { return urlCache.Stats().Size == 1 }, time.Second, time.Millisecond,
+		"Expected the shared load to complete after the caller gave up")
+}
+
+func TestURLRepository_Invalidation(t *testing.T) {
+	ctx := context.Background()
+	stored := &model.URL{ShortID: "abc123", OriginalURL: "http://example.com", UserID: "user1"}
+
+	tests := []struct {
+		name   string
+		setup  func(repo *repository.MockIURLRepository)
+		change func(urlCache *cache.URLRepository) error
+	}{
+		{
+			name: "Insert",
+			setup: func(repo *repository.MockIURLRepository) {
+				repo.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(stored, nil)
+			},
+			change: func(urlCache *cache.URLRepository) error {
+				_, err := urlCache.Insert(ctx, &model.URL{ShortID: "abc123", OriginalURL: "http://example.com"})
+				return err
+			},
+		},
+		{
+			name: "InsertList",
+			setup: func(repo *repository.MockIURLRepository) {
+				repo.EXPECT().InsertList(gomock.Any(), gomock.Any()).Return([]*model.URL{stored}, nil)
+			},
+			change: func(urlCache *cache.URLRepository) error {
+				_, err := urlCache.InsertList(ctx, []*model.URL{{ShortID: "abc123", OriginalURL: "http://example.com"}})
+				return err
+			},
+		},
+		{
+			name: "UpdateOriginalURL",
+			setup: func(repo *repository.MockIURLRepository) {
+				repo.EXPECT().UpdateOriginalURL(gomock.Any(), "user1", "abc123", "http://new.com").Return(true, nil)
+			},
+			change: func(urlCache *cache.URLRepository) error {
+				_, err := urlCache.UpdateOriginalURL(ctx, "user1", "abc123", "http://new.com")
+				return err
+			},
+		},
+		{
+			name: "DeleteListByUserIDAndShortIDs",
+			setup: func(repo *repository.MockIURLRepository) {
+				repo.EXPECT().DeleteListByUserIDAndShortIDs(gomock.Any(), "user1", []string{"abc123"}).Return(nil)
+			},
+			change: func(urlCache *cache.URLRepository) error {
+				return urlCache.DeleteListByUserIDAndShortIDs(ctx, "user1", []string{"abc123"})
+			},
+		},
+		{
+			name: "IncrementClicks",
+			setup: func(repo *repository.MockIURLRepository) {
+				repo.EXPECT().IncrementClicks(gomock.Any(), "abc123").Return(true, nil)
+			},
+			change: func(urlCache *cache.URLRepository) error {
+				_, err := urlCache.IncrementClicks(ctx, "abc123")
+				return err
+			},
+		},
+		{
+			name: "DeleteExpired",
+			setup: func(repo *repository.MockIURLRepository) {
+				repo.EXPECT().DeleteExpired(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+			},
+			change: func(urlCache *cache.URLRepository) error {
+				_, err := urlCache.DeleteExpired(ctx, time.Now())
+				return err
+			},
+		},
+		{
+			name: "SetDisabled",
+			setup: func(repo *repository.MockIURLRepository) {
+				repo.EXPECT().SetDisabled(gomock.Any(), []string{"abc123"}, true).Return(int64(1), nil)
+			},
+			change: func(urlCache *cache.URLRepository) error {
+				_, err := urlCache.SetDisabled(ctx, []string{"abc123"}, true)
+				return err
+			},
+		},
+		{
+			name: "ReassignUserID",
+			setup: func(repo *repository.MockIURLRepository) {
+				repo.EXPECT().ReassignUserID(gomock.Any(), "anonymous", "user1").Return(int64(1), nil)
+			},
+			change: func(urlCache *cache.URLRepository) error {
+				_, err := urlCache.ReassignUserID(ctx, "anonymous", "user1")
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urlCache, repo := setupCache(t)
+			repo.EXPECT().FindByID(gomock.Any(), "abc123").Return(nil, nil).Times(2)
+			tt.setup(repo)
+
+			_, err := urlCache.FindByID(ctx, "abc123")
+			require.NoError(t, err)
+			require.NoError(t, tt.change(urlCache))
+			_, err = urlCache.FindByID(ctx, "abc123")
+			require.NoError(t, err)
+			assert.Equal(t, uint64(2), urlCache.Stats().Misses, "Expected the short ID to be loaded again")
+		})
+	}
+}
+
+func TestURLRepository_Conformance(t *testing.T) {
+	repotest.RunURLRepositorySuite(t, func(t *testing.T) interfaces.IURLRepository {
+		return cache.NewURLRepository(inmemory.NewMemoryStorage(), 10, time.Minute, time.Minute)
+	})
+}
@@ -0,0 +1,71 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/interfaces/click.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/interfaces/click.go -destination=internal/repository/click_mock.go -package=repository
+//
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/GlebRadaev/shlink/internal/model"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockIClickRepository is a mock of IClickRepository interface.
+type MockIClickRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockIClickRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockIClickRepositoryMockRecorder is the mock recorder for MockIClickRepository.
+type MockIClickRepositoryMockRecorder struct {
+	mock *MockIClickRepository
+}
+
+// NewMockIClickRepository creates a new mock instance.
+func NewMockIClickRepository(ctrl *gomock.Controller) *MockIClickRepository {
+	mock := &MockIClickRepository{ctrl: ctrl}
+	mock.recorder = &MockIClickRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIClickRepository) EXPECT() *MockIClickRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetStats mocks base method.
+func (m *MockIClickRepository) GetStats(ctx context.Context, shortID string) (*model.ClickStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStats", ctx, shortID)
+	ret0, _ := ret[0].(*model.ClickStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStats indicates an expected call of GetStats.
+func (mr *MockIClickRepositoryMockRecorder) GetStats(ctx, shortID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStats", reflect.TypeOf((*MockIClickRepository)(nil).GetStats), ctx, shortID)
+}
+
+// InsertList mocks base method.
+func (m *MockIClickRepository) InsertList(ctx context.Context, clicks []*model.Click) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertList", ctx, clicks)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertList indicates an expected call of InsertList.
+func (mr *MockIClickRepositoryMockRecorder) InsertList(ctx, clicks any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertList", reflect.TypeOf((*MockIClickRepository)(nil).InsertList), ctx, clicks)
+}
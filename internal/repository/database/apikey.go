@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// APIKeyRepository represents a repository for API keys in the database.
+type APIKeyRepository struct {
+	db interfaces.DBPool
+}
+
+// NewAPIKeyRepository creates a new instance of APIKeyRepository with the provided DBPool.
+func NewAPIKeyRepository(db interfaces.DBPool) interfaces.IAPIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// Insert inserts a new API key into the database.
+func (r *APIKeyRepository) Insert(ctx context.Context, key *model.APIKey) error {
+	query := `
+		INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := r.db.Exec(ctx, query, key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash, key.Scopes, key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert API key: %v", err)
+	}
+	return nil
+}
+
+// FindByHash finds an API key by the hash of the key. Returns the key if found, otherwise returns nil.
+func (r *APIKeyRepository) FindByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	query := `
+		SELECT id, user_id, name, prefix, key_hash, scopes, created_at, revoked_at FROM api_keys
+		WHERE key_hash = $1`
+	key := &model.APIKey{}
+	err := r.db.QueryRow(ctx, query, keyHash).Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash,
+		&key.Scopes, &key.CreatedAt, &key.RevokedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// FindListByUserID finds the API keys of a specific user ordered by creation time.
+func (r *APIKeyRepository) FindListByUserID(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	query := `
+		SELECT id, user_id, name, prefix, key_hash, scopes, created_at, revoked_at FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at, id`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find API keys: %v", err)
+	}
+	defer rows.Close()
+
+	var keys []*model.APIKey
+	for rows.Next() {
+		key := &model.APIKey{}
+		if err := rows.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash,
+			&key.Scopes, &key.CreatedAt, &key.RevokedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("failed to find API keys: error occurred during rows iteration: %v", rows.Err())
+	}
+	return keys, nil
+}
+
+// Revoke marks the active API key of the user with the given ID as revoked.
+func (r *APIKeyRepository) Revoke(ctx context.Context, userID, id string, revokedAt time.Time) (bool, error) {
+	query := `
+		UPDATE api_keys SET revoked_at = $3
+		WHERE user_id = $1 AND id = $2 AND revoked_at IS NULL`
+	tag, err := r.db.Exec(ctx, query, userID, id, revokedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API key: %v", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,94 @@
+package database_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/repository"
+	"github.com/GlebRadaev/shlink/internal/repository/database"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyRepository_Insert(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewAPIKeyRepository(mockDB)
+	key := &model.APIKey{ID: "key1", UserID: "user1", Name: "ci", Prefix: "shl_abcd", KeyHash: "hash",
+		Scopes: []string{model.ScopeShorten}, CreatedAt: time.Now()}
+
+	mockDB.ExpectExec(`INSERT INTO api_keys`).
+		WithArgs("key1", "user1", "ci", "shl_abcd", "hash", []string{model.ScopeShorten}, key.CreatedAt).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	assert.NoError(t, repo.Insert(ctx, key))
+
+	mockDB.ExpectExec(`INSERT INTO api_keys`).WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+		pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).WillReturnError(fmt.Errorf("insert error"))
+	assert.EqualError(t, repo.Insert(ctx, key), "failed to insert API key: insert error")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestAPIKeyRepository_FindByHash(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewAPIKeyRepository(mockDB)
+
+	mockDB.ExpectQuery(`SELECT (.+) FROM api_keys`).WithArgs("unknown").WillReturnError(pgx.ErrNoRows)
+	key, err := repo.FindByHash(ctx, "unknown")
+	assert.NoError(t, err)
+	assert.Nil(t, key)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestAPIKeyRepository_Revoke(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewAPIKeyRepository(mockDB)
+	now := time.Now()
+
+	mockDB.ExpectExec(`UPDATE api_keys SET revoked_at`).WithArgs("user1", "key1", now).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	revoked, err := repo.Revoke(ctx, "user1", "key1", now)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	mockDB.ExpectExec(`UPDATE api_keys SET revoked_at`).WithArgs("user1", "key2", now).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	revoked, err = repo.Revoke(ctx, "user1", "key2", now)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestAPIKeyRepository_Conformance(t *testing.T) {
+	dsn := os.Getenv(testDatabaseDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDatabaseDSNEnv)
+	}
+	ctx := context.Background()
+	require.NoError(t, repository.Migrate(ctx, dsn))
+	pool, err := pgxpool.New(ctx, dsn)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	repotest.RunAPIKeyRepositorySuite(t, func(t *testing.T) interfaces.IAPIKeyRepository {
+		_, err := pool.Exec(ctx, "TRUNCATE api_keys")
+		require.NoError(t, err)
+		return database.NewAPIKeyRepository(pool)
+	})
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// AuditRepository represents a repository for the audit log in the database.
+type AuditRepository struct {
+	db interfaces.DBPool
+}
+
+// NewAuditRepository creates a new instance of AuditRepository with the provided DBPool.
+func NewAuditRepository(db interfaces.DBPool) interfaces.IAuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Insert appends an entry to the audit log and sets its ID.
+func (r *AuditRepository) Insert(ctx context.Context, entry *model.AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (actor_user_id, api_key_id, action, target, reason, affected, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`
+	err := r.db.QueryRow(ctx, query, entry.ActorUserID, entry.APIKeyID, entry.Action, entry.Target,
+		entry.Reason, entry.Affected, entry.CreatedAt).Scan(&entry.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %v", err)
+	}
+	return nil
+}
+
+// FindList finds the audit entries matching the query, newest first.
+func (r *AuditRepository) FindList(ctx context.Context, q model.AuditQuery) ([]*model.AuditEntry, error) {
+	query := `
+		SELECT id, actor_user_id, api_key_id, action, target, reason, affected, created_at FROM audit_log
+		WHERE true`
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if q.Target != "" {
+		query += " AND target = " + arg(q.Target)
+	}
+	if q.Before > 0 {
+		query += " AND id < " + arg(q.Before)
+	}
+	query += " ORDER BY id DESC"
+	if q.Limit > 0 {
+		query += " LIMIT " + arg(q.Limit)
+	}
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find audit entries: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []*model.AuditEntry
+	for rows.Next() {
+		entry := &model.AuditEntry{}
+		if err := rows.Scan(&entry.ID, &entry.ActorUserID, &entry.APIKeyID, &entry.Action, &entry.Target,
+			&entry.Reason, &entry.Affected, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find audit entries: %v", err)
+	}
+	return entries, nil
+}
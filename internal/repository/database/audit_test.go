@@ -0,0 +1,82 @@
+package database_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/repository"
+	"github.com/GlebRadaev/shlink/internal/repository/database"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditRepository_Insert(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewAuditRepository(mockDB)
+	entry := &model.AuditEntry{ActorUserID: "admin1", Action: model.AuditDisableURL, Target: "short1",
+		Reason: "spam", Affected: 1, CreatedAt: time.Now()}
+
+	mockDB.ExpectQuery(`INSERT INTO audit_log`).
+		WithArgs("admin1", "", model.AuditDisableURL, "short1", "spam", int64(1), entry.CreatedAt).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(7)))
+	require.NoError(t, repo.Insert(ctx, entry))
+	assert.Equal(t, int64(7), entry.ID)
+
+	mockDB.ExpectQuery(`INSERT INTO audit_log`).WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+		pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).WillReturnError(fmt.Errorf("insert error"))
+	assert.EqualError(t, repo.Insert(ctx, entry), "failed to insert audit entry: insert error")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestAuditRepository_FindList(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewAuditRepository(mockDB)
+	now := time.Now()
+
+	mockDB.ExpectQuery(`SELECT (.+) FROM audit_log WHERE true AND target = \$1 AND id < \$2 ORDER BY id DESC LIMIT \$3`).
+		WithArgs("short1", int64(10), 2).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "actor_user_id", "api_key_id", "action", "target", "reason", "affected", "created_at"}).
+			AddRow(int64(9), "admin1", "key1", model.AuditLookupURL, "short1", "", int64(1), now))
+	entries, err := repo.FindList(ctx, model.AuditQuery{Target: "short1", Before: 10, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []*model.AuditEntry{{ID: 9, ActorUserID: "admin1", APIKeyID: "key1", Action: model.AuditLookupURL,
+		Target: "short1", Affected: 1, CreatedAt: now}}, entries)
+
+	mockDB.ExpectQuery(`SELECT (.+) FROM audit_log WHERE true ORDER BY id DESC`).
+		WillReturnError(fmt.Errorf("query error"))
+	_, err = repo.FindList(ctx, model.AuditQuery{})
+	assert.EqualError(t, err, "failed to find audit entries: query error")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestAuditRepository_Conformance(t *testing.T) {
+	dsn := os.Getenv(testDatabaseDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDatabaseDSNEnv)
+	}
+	ctx := context.Background()
+	require.NoError(t, repository.Migrate(ctx, dsn))
+	pool, err := pgxpool.New(ctx, dsn)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	repotest.RunAuditRepositorySuite(t, func(t *testing.T) interfaces.IAuditRepository {
+		_, err := pool.Exec(ctx, "TRUNCATE audit_log RESTART IDENTITY")
+		require.NoError(t, err)
+		return database.NewAuditRepository(pool)
+	})
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// ClickRepository represents a repository for click analytics data in the database.
+type ClickRepository struct {
+	db interfaces.DBPool
+}
+
+// NewClickRepository creates a new instance of ClickRepository with the provided DBPool.
+func NewClickRepository(db interfaces.DBPool) interfaces.IClickRepository {
+	return &ClickRepository{db: db}
+}
+
+// InsertList inserts a batch of click events into the database inside a single transaction.
+func (r *ClickRepository) InsertList(ctx context.Context, clicks []*model.Click) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	query := `
+		INSERT INTO clicks (short_id, clicked_at, referrer, user_agent, ip, country)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	for _, click := range clicks {
+		_, err := tx.Exec(ctx, query, click.ShortID, click.ClickedAt, click.Referrer, click.UserAgent, click.IP, click.Country)
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return fmt.Errorf("failed to insert click: %v", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		_ = tx.Rollback(ctx)
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// GetStats returns the total number of clicks, the number of unique visitors and a daily time series for a short ID.
+func (r *ClickRepository) GetStats(ctx context.Context, shortID string) (*model.ClickStats, error) {
+	stats := &model.ClickStats{}
+	totalQuery := `
+		SELECT COUNT(*), COUNT(DISTINCT ip) FROM clicks
+		WHERE short_id = $1`
+	if err := r.db.QueryRow(ctx, totalQuery, shortID).Scan(&stats.TotalClicks, &stats.UniqueVisitors); err != nil {
+		return nil, fmt.Errorf("failed to count clicks: %v", err)
+	}
+
+	dailyQuery := `
+		SELECT (clicked_at AT TIME ZONE 'UTC')::date AS day, COUNT(*), COUNT(DISTINCT ip) FROM clicks
+		WHERE short_id = $1
+		GROUP BY day
+		ORDER BY day`
+	rows, err := r.db.Query(ctx, dailyQuery, shortID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find daily clicks: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day model.DailyClicks
+		if err := rows.Scan(&day.Date, &day.Clicks, &day.UniqueVisitors); err != nil {
+			return nil, fmt.Errorf("failed to scan daily clicks: %v", err)
+		}
+		stats.Daily = append(stats.Daily, day)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("failed to find daily clicks: error occurred during rows iteration: %v", rows.Err())
+	}
+	return stats, nil
+}
@@ -0,0 +1,145 @@
+package database_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/repository/database"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClickRepository_InsertList(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewClickRepository(mockDB)
+	now := time.Now()
+
+	clicks := []*model.Click{
+		{ShortID: "short1", ClickedAt: now, Referrer: "https://ref.com", UserAgent: "agent", IP: "192.0.2.1", Country: "DE"},
+		{ShortID: "short1", ClickedAt: now, IP: "192.0.2.2"},
+	}
+
+	tests := []struct {
+		name          string
+		mockSetup     func()
+		expectedError string
+	}{
+		{
+			name: "Successful InsertList",
+			mockSetup: func() {
+				mockDB.ExpectBegin()
+				mockDB.ExpectExec(`INSERT INTO clicks`).
+					WithArgs("short1", now, "https://ref.com", "agent", "192.0.2.1", "DE").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mockDB.ExpectExec(`INSERT INTO clicks`).
+					WithArgs("short1", now, "", "", "192.0.2.2", "").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mockDB.ExpectCommit()
+			},
+		},
+		{
+			name: "Transaction Error",
+			mockSetup: func() {
+				mockDB.ExpectBegin().WillReturnError(fmt.Errorf("begin error"))
+			},
+			expectedError: "failed to begin transaction: begin error",
+		},
+		{
+			name: "Insert Error",
+			mockSetup: func() {
+				mockDB.ExpectBegin()
+				mockDB.ExpectExec(`INSERT INTO clicks`).
+					WithArgs("short1", now, "https://ref.com", "agent", "192.0.2.1", "DE").
+					WillReturnError(fmt.Errorf("insert error"))
+				mockDB.ExpectRollback()
+			},
+			expectedError: "failed to insert click: insert error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			err := repo.InsertList(ctx, clicks)
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mockDB.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestClickRepository_GetStats(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewClickRepository(mockDB)
+	day := time.Date(2024, time.November, 17, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		mockSetup     func()
+		expected      *model.ClickStats
+		expectedError string
+	}{
+		{
+			name: "Successful GetStats",
+			mockSetup: func() {
+				mockDB.ExpectQuery(`SELECT COUNT\(\*\), COUNT\(DISTINCT ip\) FROM clicks`).
+					WithArgs("short1").
+					WillReturnRows(pgxmock.NewRows([]string{"count", "count"}).AddRow(int64(3), int64(2)))
+				mockDB.ExpectQuery(`GROUP BY day`).
+					WithArgs("short1").
+					WillReturnRows(pgxmock.NewRows([]string{"day", "count", "count"}).AddRow(day, int64(3), int64(2)))
+			},
+			expected: &model.ClickStats{
+				TotalClicks:    3,
+				UniqueVisitors: 2,
+				Daily:          []model.DailyClicks{{Date: day, Clicks: 3, UniqueVisitors: 2}},
+			},
+		},
+		{
+			name: "Count Error",
+			mockSetup: func() {
+				mockDB.ExpectQuery(`SELECT COUNT\(\*\), COUNT\(DISTINCT ip\) FROM clicks`).
+					WithArgs("short1").
+					WillReturnError(fmt.Errorf("count error"))
+			},
+			expectedError: "failed to count clicks: count error",
+		},
+		{
+			name: "Daily Query Error",
+			mockSetup: func() {
+				mockDB.ExpectQuery(`SELECT COUNT\(\*\), COUNT\(DISTINCT ip\) FROM clicks`).
+					WithArgs("short1").
+					WillReturnRows(pgxmock.NewRows([]string{"count", "count"}).AddRow(int64(3), int64(2)))
+				mockDB.ExpectQuery(`GROUP BY day`).
+					WithArgs("short1").
+					WillReturnError(fmt.Errorf("query error"))
+			},
+			expectedError: "failed to find daily clicks: query error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			stats, err := repo.GetStats(ctx, "short1")
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, stats)
+			}
+		})
+	}
+}
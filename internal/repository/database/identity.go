@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// IdentityRepository represents a repository for the accounts linked to users in the database.
+type IdentityRepository struct {
+	db interfaces.DBPool
+}
+
+// NewIdentityRepository creates a new instance of IdentityRepository with the provided DBPool.
+func NewIdentityRepository(db interfaces.DBPool) interfaces.IIdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+// FindOrInsert inserts the identity unless its account is already linked and returns the stored identity.
+func (r *IdentityRepository) FindOrInsert(ctx context.Context, identity *model.UserIdentity) (*model.UserIdentity, error) {
+	query := `
+		INSERT INTO user_identities (issuer, subject, user_id, email, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (issuer, subject) DO NOTHING`
+	_, err := r.db.Exec(ctx, query, identity.Issuer, identity.Subject, identity.UserID, identity.Email, identity.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert identity: %v", err)
+	}
+	query = `
+		SELECT issuer, subject, user_id, email, created_at FROM user_identities
+		WHERE issuer = $1 AND subject = $2`
+	stored := &model.UserIdentity{}
+	err = r.db.QueryRow(ctx, query, identity.Issuer, identity.Subject).
+		Scan(&stored.Issuer, &stored.Subject, &stored.UserID, &stored.Email, &stored.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find identity: %v", err)
+	}
+	return stored, nil
+}
+
+// FindByUserID finds an identity linked to the user. Returns the identity if found, otherwise returns nil.
+func (r *IdentityRepository) FindByUserID(ctx context.Context, userID string) (*model.UserIdentity, error) {
+	query := `
+		SELECT issuer, subject, user_id, email, created_at FROM user_identities
+		WHERE user_id = $1
+		LIMIT 1`
+	identity := &model.UserIdentity{}
+	err := r.db.QueryRow(ctx, query, userID).
+		Scan(&identity.Issuer, &identity.Subject, &identity.UserID, &identity.Email, &identity.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return identity, nil
+}
@@ -0,0 +1,82 @@
+package database_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/repository"
+	"github.com/GlebRadaev/shlink/internal/repository/database"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityRepository_FindOrInsert(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewIdentityRepository(mockDB)
+	createdAt := time.Now()
+	identity := &model.UserIdentity{Issuer: "https://idp.example.com", Subject: "alice", UserID: "user2", CreatedAt: createdAt}
+
+	mockDB.ExpectExec(`INSERT INTO user_identities .* ON CONFLICT \(issuer, subject\) DO NOTHING`).
+		WithArgs("https://idp.example.com", "alice", "user2", "", createdAt).
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+	mockDB.ExpectQuery(`SELECT issuer, subject, user_id, email, created_at FROM user_identities`).
+		WithArgs("https://idp.example.com", "alice").
+		WillReturnRows(pgxmock.NewRows([]string{"issuer", "subject", "user_id", "email", "created_at"}).
+			AddRow("https://idp.example.com", "alice", "user1", "alice@example.com", createdAt))
+	stored, err := repo.FindOrInsert(ctx, identity)
+	require.NoError(t, err)
+	assert.Equal(t, "user1", stored.UserID, "Expected the user of the existing link")
+
+	mockDB.ExpectExec(`INSERT INTO user_identities`).
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnError(fmt.Errorf("insert error"))
+	_, err = repo.FindOrInsert(ctx, identity)
+	assert.EqualError(t, err, "failed to insert identity: insert error")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestIdentityRepository_FindByUserID(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewIdentityRepository(mockDB)
+
+	mockDB.ExpectQuery(`SELECT issuer, subject, user_id, email, created_at FROM user_identities`).
+		WithArgs("anonymous").
+		WillReturnError(pgx.ErrNoRows)
+	identity, err := repo.FindByUserID(ctx, "anonymous")
+	require.NoError(t, err)
+	assert.Nil(t, identity)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestIdentityRepository_Conformance(t *testing.T) {
+	dsn := os.Getenv(testDatabaseDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDatabaseDSNEnv)
+	}
+	ctx := context.Background()
+	require.NoError(t, repository.Migrate(ctx, dsn))
+	pool, err := pgxpool.New(ctx, dsn)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	repotest.RunIdentityRepositorySuite(t, func(t *testing.T) interfaces.IIdentityRepository {
+		_, err := pool.Exec(ctx, "TRUNCATE user_identities")
+		require.NoError(t, err)
+		return database.NewIdentityRepository(pool)
+	})
+}
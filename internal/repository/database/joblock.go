@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/jackc/pgx/v5"
+)
+
+// JobLockRepository represents a repository for the locks of the recurring jobs in the database.
+// A lock is a row of the job with a lease rather than an advisory lock, which would be tied to
+// a connection of the pool and outlive neither the connection nor the instance holding it.
+// The row is locked by the upsert acquiring it, so concurrent instances never both acquire it.
+type JobLockRepository struct {
+	db interfaces.DBPool
+}
+
+// NewJobLockRepository creates a new instance of JobLockRepository with the provided DBPool.
+func NewJobLockRepository(db interfaces.DBPool) interfaces.IJobLockRepository {
+	return &JobLockRepository{db: db}
+}
+
+// Acquire takes the lock of the job for the run at runAt unless it is held or the run was taken.
+func (r *JobLockRepository) Acquire(ctx context.Context, name, owner string, runAt, now time.Time, lease time.Duration) (bool, error) {
+	query := `
+		INSERT INTO scheduled_jobs (name, last_run_at, locked_by, locked_until)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO UPDATE
+		SET last_run_at = EXCLUDED.last_run_at, locked_by = EXCLUDED.locked_by, locked_until = EXCLUDED.locked_until
+		WHERE scheduled_jobs.last_run_at < EXCLUDED.last_run_at AND scheduled_jobs.locked_until <= $5
+		RETURNING name`
+	var acquired string
+	err := r.db.QueryRow(ctx, query, name, runAt, owner, now.Add(lease), now).Scan(&acquired)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to acquire job lock: %v", err)
+	}
+	return true, nil
+}
+
+// Extend moves the expiry of the lock of the job held by owner to until.
+func (r *JobLockRepository) Extend(ctx context.Context, name, owner string, until time.Time) (bool, error) {
+	query := `UPDATE scheduled_jobs SET locked_until = $3 WHERE name = $1 AND locked_by = $2`
+	tag, err := r.db.Exec(ctx, query, name, owner, until)
+	if err != nil {
+		return false, fmt.Errorf("failed to extend job lock: %v", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Release releases the lock of the job held by owner.
+func (r *JobLockRepository) Release(ctx context.Context, name, owner string, now time.Time) error {
+	query := `UPDATE scheduled_jobs SET locked_until = $3 WHERE name = $1 AND locked_by = $2`
+	if _, err := r.db.Exec(ctx, query, name, owner, now); err != nil {
+		return fmt.Errorf("failed to release job lock: %v", err)
+	}
+	return nil
+}
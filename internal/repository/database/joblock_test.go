@@ -0,0 +1,92 @@
+package database_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/repository"
+	"github.com/GlebRadaev/shlink/internal/repository/database"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobLockRepository_Acquire(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewJobLockRepository(mockDB)
+	now := time.Now()
+
+	mockDB.ExpectQuery(`INSERT INTO scheduled_jobs .* ON CONFLICT \(name\) DO UPDATE .* WHERE scheduled_jobs.last_run_at < EXCLUDED.last_run_at AND scheduled_jobs.locked_until <= \$5`).
+		WithArgs("job1", now, "owner1", now.Add(time.Minute), now).
+		WillReturnRows(pgxmock.NewRows([]string{"name"}).AddRow("job1"))
+	acquired, err := repo.Acquire(ctx, "job1", "owner1", now, now, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	mockDB.ExpectQuery(`INSERT INTO scheduled_jobs`).WithArgs("job1", now, "owner2", now.Add(time.Minute), now).
+		WillReturnError(pgx.ErrNoRows)
+	acquired, err = repo.Acquire(ctx, "job1", "owner2", now, now, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	mockDB.ExpectQuery(`INSERT INTO scheduled_jobs`).WithArgs("job1", now, "owner2", now.Add(time.Minute), now).
+		WillReturnError(fmt.Errorf("query error"))
+	_, err = repo.Acquire(ctx, "job1", "owner2", now, now, time.Minute)
+	assert.EqualError(t, err, "failed to acquire job lock: query error")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestJobLockRepository_ExtendAndRelease(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewJobLockRepository(mockDB)
+	now := time.Now()
+
+	mockDB.ExpectExec(`UPDATE scheduled_jobs SET locked_until = \$3 WHERE name = \$1 AND locked_by = \$2`).
+		WithArgs("job1", "owner1", now).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	extended, err := repo.Extend(ctx, "job1", "owner1", now)
+	require.NoError(t, err)
+	assert.True(t, extended)
+
+	mockDB.ExpectExec(`UPDATE scheduled_jobs`).WithArgs("job1", "owner2", now).WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	extended, err = repo.Extend(ctx, "job1", "owner2", now)
+	require.NoError(t, err)
+	assert.False(t, extended)
+
+	mockDB.ExpectExec(`UPDATE scheduled_jobs`).WithArgs("job1", "owner1", now).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	require.NoError(t, repo.Release(ctx, "job1", "owner1", now))
+
+	mockDB.ExpectExec(`UPDATE scheduled_jobs`).WithArgs("job1", "owner1", now).WillReturnError(fmt.Errorf("update error"))
+	assert.EqualError(t, repo.Release(ctx, "job1", "owner1", now), "failed to release job lock: update error")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestJobLockRepository_Conformance(t *testing.T) {
+	dsn := os.Getenv(testDatabaseDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDatabaseDSNEnv)
+	}
+	ctx := context.Background()
+	require.NoError(t, repository.Migrate(ctx, dsn))
+	pool, err := pgxpool.New(ctx, dsn)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	repotest.RunJobLockRepositorySuite(t, func(t *testing.T) interfaces.IJobLockRepository {
+		_, err := pool.Exec(ctx, "TRUNCATE scheduled_jobs")
+		require.NoError(t, err)
+		return database.NewJobLockRepository(pool)
+	})
+}
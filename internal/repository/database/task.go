@@ -0,0 +1,227 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// TaskRepository represents a repository for the durable task queue in the database.
+// Tasks are claimed with SELECT ... FOR UPDATE SKIP LOCKED, so that the workers of several
+// instances sharing the database never claim the same task.
+type TaskRepository struct {
+	db interfaces.DBPool
+}
+
+// NewTaskRepository creates a new instance of TaskRepository with the provided DBPool.
+func NewTaskRepository(db interfaces.DBPool) interfaces.ITaskRepository {
+	return &TaskRepository{db: db}
+}
+
+// Push adds a task to the queue and sets its ID and fair time, as insertTask does.
+func (r *TaskRepository) Push(ctx context.Context, task *model.QueuedTask) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := insertTask(ctx, tx, task); err != nil {
+		return fmt.Errorf("failed to push task: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// insertTask inserts the task and sets its ID and fair time. The fair time of a task with a fair key
+// is moved forward by as much as the latest fair time of the queued tasks of the key is after its
+// run time, in the INSERT itself. The inserts of a key hold its advisory lock, so that concurrent
+// inserts see each other's fair times.
+func insertTask(ctx context.Context, tx pgx.Tx, task *model.QueuedTask) error {
+	if err := lockFairKey(ctx, tx, task.FairKey); err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO tasks (type, payload, trace_parent, attempts, run_at, last_error, created_at, priority, fair_key, fair_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10::timestamptz + GREATEST(INTERVAL '0',
+			(SELECT MAX(fair_at) FROM tasks WHERE fair_key = $9 AND $9 <> '') - $5::timestamptz))
+		RETURNING id, fair_at`
+	return tx.QueryRow(ctx, query, task.Type, task.Payload, task.TraceParent, task.Attempts, task.RunAt,
+		task.LastError, task.CreatedAt, task.Priority, task.FairKey, task.FairAt).Scan(&task.ID, &task.FairAt)
+}
+
+// lockFairKey takes the advisory lock of the fair key until the end of the transaction. The INSERT
+// that follows runs once the inserts of the key holding the lock have committed, so its subquery
+// sees their fair times. Tasks without a fair key take no lock.
+func lockFairKey(ctx context.Context, tx pgx.Tx, key string) error {
+	if key == "" {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, key)
+	return err
+}
+
+// Claim takes the due task at now of the highest priority and the earliest fair time that is not of
+// one of skipTypes nor of one of skipKeys, skipping the tasks locked by concurrent claims, counts the
+// attempt and moves its run_at past the lease.
+func (r *TaskRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, skipTypes, skipKeys []string) (*model.QueuedTask, error) {
+	if skipTypes == nil {
+		skipTypes = []string{} // type <> ALL(NULL) would match no task.
+	}
+	if skipKeys == nil {
+		skipKeys = []string{}
+	}
+	query := `
+		UPDATE tasks SET attempts = attempts + 1, run_at = $2
+		WHERE id = (
+			SELECT id FROM tasks WHERE run_at <= $1 AND type <> ALL($3) AND fair_key <> ALL($4)
+			ORDER BY priority DESC, fair_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED)
+		RETURNING ` + taskFields
+	task, err := scanQueuedTask(r.db.QueryRow(ctx, query, now, now.Add(lease), skipTypes, skipKeys))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim task: %v", err)
+	}
+	return task, nil
+}
+
+// Extend sets the run_at of a claimed task to until.
+func (r *TaskRepository) Extend(ctx context.Context, id int64, until time.Time) (bool, error) {
+	tag, err := r.db.Exec(ctx, `UPDATE tasks SET run_at = $2 WHERE id = $1`, id, until)
+	if err != nil {
+		return false, fmt.Errorf("failed to extend task lease: %v", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Count returns the number of rows of the tasks table.
+func (r *TaskRepository) Count(ctx context.Context) (int, error) {
+	var count int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM tasks`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count tasks: %v", err)
+	}
+	return count, nil
+}
+
+// Complete deletes a claimed task.
+func (r *TaskRepository) Complete(ctx context.Context, id int64) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM tasks WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to complete task: %v", err)
+	}
+	return nil
+}
+
+// Retry sets the run_at of a claimed task to runAt and records the error of the attempt.
+func (r *TaskRepository) Retry(ctx context.Context, id int64, runAt time.Time, lastError string) error {
+	query := `UPDATE tasks SET run_at = $2, last_error = $3 WHERE id = $1`
+	if _, err := r.db.Exec(ctx, query, id, runAt, lastError); err != nil {
+		return fmt.Errorf("failed to retry task: %v", err)
+	}
+	return nil
+}
+
+// Release sets the run_at of a claimed task to runAt and takes back the attempt counted by its claim.
+func (r *TaskRepository) Release(ctx context.Context, id int64, runAt time.Time) error {
+	query := `UPDATE tasks SET run_at = $2, attempts = GREATEST(attempts - 1, 0) WHERE id = $1`
+	if _, err := r.db.Exec(ctx, query, id, runAt); err != nil {
+		return fmt.Errorf("failed to release task: %v", err)
+	}
+	return nil
+}
+
+// Bury deletes a claimed task and inserts it into the dead letters in a single statement.
+func (r *TaskRepository) Bury(ctx context.Context, id int64, lastError string, failedAt time.Time) error {
+	query := `
+		WITH task AS (
+			DELETE FROM tasks WHERE id = $1
+			RETURNING type, payload, attempts, created_at, priority, fair_key)
+		INSERT INTO dead_tasks (type, payload, attempts, last_error, created_at, failed_at, priority, fair_key)
+		SELECT type, payload, attempts, $2, created_at, $3, priority, fair_key FROM task`
+	if _, err := r.db.Exec(ctx, query, id, lastError, failedAt); err != nil {
+		return fmt.Errorf("failed to bury task: %v", err)
+	}
+	return nil
+}
+
+// FindDeadList finds the dead letters, newest first.
+func (r *TaskRepository) FindDeadList(ctx context.Context, limit int) ([]*model.DeadTask, error) {
+	query := `
+		SELECT id, type, payload, attempts, last_error, created_at, failed_at, priority, fair_key FROM dead_tasks
+		ORDER BY id DESC`
+	var args []any
+	if limit > 0 {
+		query += " LIMIT $1"
+		args = append(args, limit)
+	}
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find dead tasks: %v", err)
+	}
+	defer rows.Close()
+
+	var tasks []*model.DeadTask
+	for rows.Next() {
+		task := &model.DeadTask{}
+		if err := rows.Scan(&task.ID, &task.Type, &task.Payload, &task.Attempts, &task.LastError,
+			&task.CreatedAt, &task.FailedAt, &task.Priority, &task.FairKey); err != nil {
+			return nil, fmt.Errorf("failed to scan dead task: %v", err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find dead tasks: %v", err)
+	}
+	return tasks, nil
+}
+
+// Replay deletes a dead letter and inserts it back into the queue inside a single transaction. The
+// replayed task keeps its priority and fair key, and its fair time is its run time, moved forward as
+// in Push.
+func (r *TaskRepository) Replay(ctx context.Context, id int64, runAt time.Time) (*model.QueuedTask, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	task := &model.QueuedTask{RunAt: runAt, FairAt: runAt}
+	err = tx.QueryRow(ctx, `DELETE FROM dead_tasks WHERE id = $1 RETURNING type, payload, created_at, priority, fair_key`, id).
+		Scan(&task.Type, &task.Payload, &task.CreatedAt, &task.Priority, &task.FairKey)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to replay task: %v", err)
+	}
+	if err := insertTask(ctx, tx, task); err != nil {
+		return nil, fmt.Errorf("failed to replay task: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return task, nil
+}
+
+// taskFields are the columns of the tasks table read by scanQueuedTask.
+const taskFields = `id, type, payload, trace_parent, attempts, run_at, last_error, created_at, priority, fair_key, fair_at`
+
+// scanQueuedTask scans a row of the tasks table made of taskFields.
+func scanQueuedTask(row pgx.Row) (*model.QueuedTask, error) {
+	task := &model.QueuedTask{}
+	err := row.Scan(&task.ID, &task.Type, &task.Payload, &task.TraceParent, &task.Attempts, &task.RunAt,
+		&task.LastError, &task.CreatedAt, &task.Priority, &task.FairKey, &task.FairAt)
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
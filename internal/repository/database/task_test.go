@@ -0,0 +1,249 @@
+package database_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/repository"
+	"github.com/GlebRadaev/shlink/internal/repository/database"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// taskColumns are the columns of the tasks returned by the queries of the task repository.
+var taskColumns = []string{"id", "type", "payload", "trace_parent", "attempts", "run_at", "last_error", "created_at",
+	"priority", "fair_key", "fair_at"}
+
+func TestTaskRepository_Push(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewTaskRepository(mockDB)
+	now := time.Now()
+	task := &model.QueuedTask{Type: "delete_urls_task", Payload: json.RawMessage(`{}`), RunAt: now, CreatedAt: now,
+		Priority: 1, FairKey: "user1", FairAt: now.Add(time.Millisecond)}
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectExec(`SELECT pg_advisory_xact_lock\(hashtext\(\$1\)\)`).WithArgs("user1").
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mockDB.ExpectQuery(`INSERT INTO tasks (.+) GREATEST\(INTERVAL '0', \(SELECT MAX\(fair_at\) FROM tasks WHERE fair_key = \$9 AND \$9 <> ''\) - \$5::timestamptz\)\) RETURNING id, fair_at`).
+		WithArgs("delete_urls_task", task.Payload, "", 0, now, "", now, 1, "user1", now.Add(time.Millisecond)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "fair_at"}).AddRow(int64(3), now.Add(time.Second)))
+	mockDB.ExpectCommit()
+	require.NoError(t, repo.Push(ctx, task))
+	assert.Equal(t, int64(3), task.ID)
+	assert.Equal(t, now.Add(time.Second), task.FairAt, "Expected the fair time set by the database")
+
+	unkeyed := &model.QueuedTask{Type: "expire_urls_task", Payload: json.RawMessage(`{}`), RunAt: now, CreatedAt: now, FairAt: now}
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`INSERT INTO tasks`).WithArgs("expire_urls_task", unkeyed.Payload, "", 0, now, "", now, 0, "", now).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "fair_at"}).AddRow(int64(4), now))
+	mockDB.ExpectCommit()
+	require.NoError(t, repo.Push(ctx, unkeyed), "Expected a task without a fair key to take no lock")
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectExec(`SELECT pg_advisory_xact_lock`).WithArgs("user1").WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mockDB.ExpectQuery(`INSERT INTO tasks`).WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+		pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnError(fmt.Errorf("insert error"))
+	mockDB.ExpectRollback()
+	assert.EqualError(t, repo.Push(ctx, task), "failed to push task: insert error")
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectExec(`SELECT pg_advisory_xact_lock`).WithArgs("user1").WillReturnError(fmt.Errorf("lock error"))
+	mockDB.ExpectRollback()
+	assert.EqualError(t, repo.Push(ctx, task), "failed to push task: lock error")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestTaskRepository_Claim(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewTaskRepository(mockDB)
+	now := time.Now()
+
+	mockDB.ExpectQuery(`UPDATE tasks SET attempts = attempts \+ 1, run_at = \$2 WHERE id = \( SELECT id FROM tasks WHERE run_at <= \$1 AND type <> ALL\(\$3\) AND fair_key <> ALL\(\$4\) ORDER BY priority DESC, fair_at, id LIMIT 1 FOR UPDATE SKIP LOCKED\)`).
+		WithArgs(now, now.Add(time.Minute), []string{}, []string{}).
+		WillReturnRows(pgxmock.NewRows(taskColumns).
+			AddRow(int64(3), "delete_urls_task", []byte(`{}`), "", 1, now.Add(time.Minute), "", now, 1, "user1", now))
+	task, err := repo.Claim(ctx, now, time.Minute, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &model.QueuedTask{ID: 3, Type: "delete_urls_task", Payload: json.RawMessage(`{}`), Attempts: 1,
+		RunAt: now.Add(time.Minute), CreatedAt: now, Priority: 1, FairKey: "user1", FairAt: now}, task)
+
+	mockDB.ExpectQuery(`UPDATE tasks`).WithArgs(now, now.Add(time.Minute), []string{}, []string{}).WillReturnError(pgx.ErrNoRows)
+	task, err = repo.Claim(ctx, now, time.Minute, nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, task)
+
+	mockDB.ExpectQuery(`UPDATE tasks`).WithArgs(now, now.Add(time.Minute), []string{}, []string{}).WillReturnError(fmt.Errorf("query error"))
+	_, err = repo.Claim(ctx, now, time.Minute, nil, nil)
+	assert.EqualError(t, err, "failed to claim task: query error")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestTaskRepository_Extend(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewTaskRepository(mockDB)
+	now := time.Now()
+
+	mockDB.ExpectExec(`UPDATE tasks SET run_at = \$2 WHERE id = \$1`).
+		WithArgs(int64(3), now).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	extended, err := repo.Extend(ctx, 3, now)
+	require.NoError(t, err)
+	assert.True(t, extended)
+
+	mockDB.ExpectExec(`UPDATE tasks`).WithArgs(int64(4), now).WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	extended, err = repo.Extend(ctx, 4, now)
+	require.NoError(t, err)
+	assert.False(t, extended)
+
+	mockDB.ExpectExec(`UPDATE tasks`).WithArgs(int64(3), now).WillReturnError(fmt.Errorf("exec error"))
+	_, err = repo.Extend(ctx, 3, now)
+	assert.EqualError(t, err, "failed to extend task lease: exec error")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestTaskRepository_Count(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewTaskRepository(mockDB)
+
+	mockDB.ExpectQuery(`SELECT COUNT\(\*\) FROM tasks`).WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(3))
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	mockDB.ExpectQuery(`SELECT COUNT`).WillReturnError(fmt.Errorf("query error"))
+	_, err = repo.Count(ctx)
+	assert.EqualError(t, err, "failed to count tasks: query error")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestTaskRepository_CompleteRetryBury(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewTaskRepository(mockDB)
+	now := time.Now()
+
+	mockDB.ExpectExec(`DELETE FROM tasks WHERE id = \$1`).WithArgs(int64(3)).WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	require.NoError(t, repo.Complete(ctx, 3))
+	mockDB.ExpectExec(`UPDATE tasks SET run_at = \$2, last_error = \$3 WHERE id = \$1`).
+		WithArgs(int64(3), now, "failed").WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	require.NoError(t, repo.Retry(ctx, 3, now, "failed"))
+	mockDB.ExpectExec(`UPDATE tasks SET run_at = \$2, attempts = GREATEST\(attempts - 1, 0\) WHERE id = \$1`).
+		WithArgs(int64(3), now).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	require.NoError(t, repo.Release(ctx, 3, now))
+	mockDB.ExpectExec(`WITH task AS \( DELETE FROM tasks WHERE id = \$1 (.+)\) INSERT INTO dead_tasks`).
+		WithArgs(int64(3), "failed", now).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	require.NoError(t, repo.Bury(ctx, 3, "failed", now))
+
+	mockDB.ExpectExec(`DELETE FROM tasks`).WithArgs(int64(3)).WillReturnError(fmt.Errorf("exec error"))
+	assert.EqualError(t, repo.Complete(ctx, 3), "failed to complete task: exec error")
+	mockDB.ExpectExec(`UPDATE tasks`).WithArgs(int64(3), now, "failed").WillReturnError(fmt.Errorf("exec error"))
+	assert.EqualError(t, repo.Retry(ctx, 3, now, "failed"), "failed to retry task: exec error")
+	mockDB.ExpectExec(`UPDATE tasks`).WithArgs(int64(3), now).WillReturnError(fmt.Errorf("exec error"))
+	assert.EqualError(t, repo.Release(ctx, 3, now), "failed to release task: exec error")
+	mockDB.ExpectExec(`INSERT INTO dead_tasks`).WithArgs(int64(3), "failed", now).WillReturnError(fmt.Errorf("exec error"))
+	assert.EqualError(t, repo.Bury(ctx, 3, "failed", now), "failed to bury task: exec error")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestTaskRepository_FindDeadList(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewTaskRepository(mockDB)
+	now := time.Now()
+
+	mockDB.ExpectQuery(`SELECT (.+) FROM dead_tasks ORDER BY id DESC LIMIT \$1`).WithArgs(10).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "type", "payload", "attempts", "last_error", "created_at", "failed_at",
+			"priority", "fair_key"}).
+			AddRow(int64(4), "delete_urls_task", []byte(`{}`), 5, "failed", now, now, 0, "user1"))
+	tasks, err := repo.FindDeadList(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []*model.DeadTask{{ID: 4, Type: "delete_urls_task", Payload: json.RawMessage(`{}`), Attempts: 5,
+		LastError: "failed", CreatedAt: now, FailedAt: now, FairKey: "user1"}}, tasks)
+
+	mockDB.ExpectQuery(`SELECT (.+) FROM dead_tasks ORDER BY id DESC`).WillReturnError(fmt.Errorf("query error"))
+	_, err = repo.FindDeadList(ctx, 0)
+	assert.EqualError(t, err, "failed to find dead tasks: query error")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestTaskRepository_Replay(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewTaskRepository(mockDB)
+	now := time.Now()
+
+	deadColumns := []string{"type", "payload", "created_at", "priority", "fair_key"}
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`DELETE FROM dead_tasks WHERE id = \$1 RETURNING type, payload, created_at, priority, fair_key`).WithArgs(int64(4)).
+		WillReturnRows(pgxmock.NewRows(deadColumns).AddRow("delete_urls_task", []byte(`{}`), now, 1, "user1"))
+	mockDB.ExpectExec(`SELECT pg_advisory_xact_lock\(hashtext\(\$1\)\)`).WithArgs("user1").
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mockDB.ExpectQuery(`INSERT INTO tasks (.+) GREATEST\(INTERVAL '0', \(SELECT MAX\(fair_at\) FROM tasks WHERE fair_key = \$9 AND \$9 <> ''\) - \$5::timestamptz\)\) RETURNING id, fair_at`).
+		WithArgs("delete_urls_task", json.RawMessage(`{}`), "", 0, now, "", now, 1, "user1", now).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "fair_at"}).AddRow(int64(7), now.Add(time.Second)))
+	mockDB.ExpectCommit()
+	task, err := repo.Replay(ctx, 4, now)
+	require.NoError(t, err)
+	assert.Equal(t, &model.QueuedTask{ID: 7, Type: "delete_urls_task", Payload: json.RawMessage(`{}`), RunAt: now, CreatedAt: now,
+		Priority: 1, FairKey: "user1", FairAt: now.Add(time.Second)}, task, "Expected the fair time moved forward as in Push")
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`DELETE FROM dead_tasks`).WithArgs(int64(4)).WillReturnError(pgx.ErrNoRows)
+	mockDB.ExpectRollback()
+	task, err = repo.Replay(ctx, 4, now)
+	require.NoError(t, err)
+	assert.Nil(t, task)
+
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery(`DELETE FROM dead_tasks`).WithArgs(int64(4)).WillReturnError(fmt.Errorf("query error"))
+	mockDB.ExpectRollback()
+	_, err = repo.Replay(ctx, 4, now)
+	assert.EqualError(t, err, "failed to replay task: query error")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestTaskRepository_Conformance(t *testing.T) {
+	dsn := os.Getenv(testDatabaseDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDatabaseDSNEnv)
+	}
+	ctx := context.Background()
+	require.NoError(t, repository.Migrate(ctx, dsn))
+	pool, err := pgxpool.New(ctx, dsn)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	repotest.RunTaskRepositorySuite(t, func(t *testing.T) interfaces.ITaskRepository {
+		_, err := pool.Exec(ctx, "TRUNCATE tasks, dead_tasks RESTART IDENTITY")
+		require.NoError(t, err)
+		return database.NewTaskRepository(pool)
+	})
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// TaskStatusRepository represents a repository for the states of the tasks of users in the database.
+type TaskStatusRepository struct {
+	db interfaces.DBPool
+}
+
+// NewTaskStatusRepository creates a new instance of TaskStatusRepository with the provided DBPool.
+func NewTaskStatusRepository(db interfaces.DBPool) interfaces.ITaskStatusRepository {
+	return &TaskStatusRepository{db: db}
+}
+
+// Insert adds the status of a new task.
+func (r *TaskStatusRepository) Insert(ctx context.Context, status *model.TaskStatus) error {
+	query := `
+		INSERT INTO task_statuses (id, user_id, type, state, items, error, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := r.db.Exec(ctx, query, status.ID, status.UserID, status.Type, status.State, status.Items,
+		status.Error, status.CreatedAt, status.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert task status: %v", err)
+	}
+	return nil
+}
+
+// FindByID finds the status of a task by its ID. Returns the status if found, otherwise returns nil.
+func (r *TaskStatusRepository) FindByID(ctx context.Context, id string) (*model.TaskStatus, error) {
+	query := `
+		SELECT id, user_id, type, state, items, error, created_at, updated_at FROM task_statuses
+		WHERE id = $1`
+	status := &model.TaskStatus{}
+	err := r.db.QueryRow(ctx, query, id).Scan(&status.ID, &status.UserID, &status.Type, &status.State,
+		&status.Items, &status.Error, &status.CreatedAt, &status.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find task status: %v", err)
+	}
+	return status, nil
+}
+
+// Update replaces the state, items, error and update time of a task.
+func (r *TaskStatusRepository) Update(ctx context.Context, status *model.TaskStatus) error {
+	query := `UPDATE task_statuses SET state = $2, items = $3, error = $4, updated_at = $5 WHERE id = $1`
+	if _, err := r.db.Exec(ctx, query, status.ID, status.State, status.Items, status.Error, status.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to update task status: %v", err)
+	}
+	return nil
+}
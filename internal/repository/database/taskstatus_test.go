@@ -0,0 +1,111 @@
+package database_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/repository"
+	"github.com/GlebRadaev/shlink/internal/repository/database"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskStatusRepository_Insert(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewTaskStatusRepository(mockDB)
+	now := time.Now()
+	items := []model.TaskItem{{ID: "short1", State: model.TaskItemPending}}
+	status := &model.TaskStatus{ID: "task1", UserID: "user1", Type: "delete_urls_task", State: model.TaskQueued,
+		Items: items, CreatedAt: now, UpdatedAt: now}
+
+	mockDB.ExpectExec(`INSERT INTO task_statuses`).
+		WithArgs("task1", "user1", "delete_urls_task", model.TaskQueued, items, "", now, now).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	require.NoError(t, repo.Insert(ctx, status))
+
+	mockDB.ExpectExec(`INSERT INTO task_statuses`).WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+		pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnError(fmt.Errorf("insert error"))
+	assert.EqualError(t, repo.Insert(ctx, status), "failed to insert task status: insert error")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestTaskStatusRepository_FindByID(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewTaskStatusRepository(mockDB)
+	now := time.Now()
+	items := []model.TaskItem{{ID: "short1", State: model.TaskItemFailed, Error: "db error"}}
+
+	mockDB.ExpectQuery(`SELECT id, user_id, type, state, items, error, created_at, updated_at FROM task_statuses WHERE id = \$1`).
+		WithArgs("task1").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "user_id", "type", "state", "items", "error", "created_at", "updated_at"}).
+			AddRow("task1", "user1", "delete_urls_task", model.TaskFailed, items, "db error", now, now))
+	status, err := repo.FindByID(ctx, "task1")
+	require.NoError(t, err)
+	assert.Equal(t, &model.TaskStatus{ID: "task1", UserID: "user1", Type: "delete_urls_task", State: model.TaskFailed,
+		Items: items, Error: "db error", CreatedAt: now, UpdatedAt: now}, status)
+
+	mockDB.ExpectQuery(`SELECT id, user_id`).WithArgs("task2").WillReturnError(pgx.ErrNoRows)
+	status, err = repo.FindByID(ctx, "task2")
+	require.NoError(t, err)
+	assert.Nil(t, status)
+
+	mockDB.ExpectQuery(`SELECT id, user_id`).WithArgs("task3").WillReturnError(fmt.Errorf("query error"))
+	_, err = repo.FindByID(ctx, "task3")
+	assert.EqualError(t, err, "failed to find task status: query error")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestTaskStatusRepository_Update(t *testing.T) {
+	ctx := context.Background()
+	mockDB, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	repo := database.NewTaskStatusRepository(mockDB)
+	now := time.Now()
+	items := []model.TaskItem{{ID: "short1", State: model.TaskItemSucceeded}}
+	status := &model.TaskStatus{ID: "task1", State: model.TaskSucceeded, Items: items, UpdatedAt: now}
+
+	mockDB.ExpectExec(`UPDATE task_statuses SET state = \$2, items = \$3, error = \$4, updated_at = \$5 WHERE id = \$1`).
+		WithArgs("task1", model.TaskSucceeded, items, "", now).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	require.NoError(t, repo.Update(ctx, status))
+
+	mockDB.ExpectExec(`UPDATE task_statuses`).WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+		pgxmock.AnyArg(), pgxmock.AnyArg()).WillReturnError(fmt.Errorf("update error"))
+	assert.EqualError(t, repo.Update(ctx, status), "failed to update task status: update error")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestTaskStatusRepository_Conformance(t *testing.T) {
+	dsn := os.Getenv(testDatabaseDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDatabaseDSNEnv)
+	}
+	ctx := context.Background()
+	require.NoError(t, repository.Migrate(ctx, dsn))
+	pool, err := pgxpool.New(ctx, dsn)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	repotest.RunTaskStatusRepositorySuite(t, func(t *testing.T) interfaces.ITaskStatusRepository {
+		_, err := pool.Exec(ctx, "TRUNCATE task_statuses")
+		require.NoError(t, err)
+		return database.NewTaskStatusRepository(pool)
+	})
+}
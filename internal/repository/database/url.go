@@ -5,15 +5,25 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/GlebRadaev/shlink/internal/interfaces"
 	"github.com/GlebRadaev/shlink/internal/model"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/lib/pq"
 )
 
+// Postgres error code and constraint name reported when a short ID is already taken.
+const (
+	uniqueViolationCode   = "23505"
+	shortIDConstraint     = "urls_short_id_key"
+	originalURLConstraint = "urls_original_url_key"
+)
+
 // URLRepository represents a repository for URL data in the database.
 type URLRepository struct {
 	db interfaces.DBPool
@@ -24,54 +34,69 @@ func NewURLRepository(db interfaces.DBPool) interfaces.IURLRepository {
 	return &URLRepository{db: db}
 }
 
+// rowQuerier is implemented by both the connection pool and a transaction.
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
 // Insert inserts a new URL into the database, or updates the existing one based on the original URL.
 // If the URL already exists, it updates the short ID. Returns the inserted or updated URL.
+// If the short ID belongs to another URL, the returned error wraps interfaces.ErrShortIDTaken.
 func (r *URLRepository) Insert(ctx context.Context, url *model.URL) (*model.URL, error) {
-	query := `
-		INSERT INTO urls (short_id, original_url, user_id) 
-		VALUES ($1, $2, $3) 
-		ON CONFLICT (original_url) DO UPDATE 
-		SET short_id = urls.short_id 
-		RETURNING id, short_id, original_url, user_id, created_at`
-	err := r.db.QueryRow(ctx, query, url.ShortID, url.OriginalURL, url.UserID).
-		Scan(&url.ID, &url.ShortID, &url.OriginalURL, &url.UserID, &url.CreatedAt)
-	if err != nil {
-		return nil, fmt.Errorf("failed to insert URL: %v", err)
-	}
-	return url, nil
+	return r.insert(ctx, r.db, url)
 }
 
-// InsertList inserts a list of URLs into the database. It uses a transaction to insert URLs one by one and commits the transaction.
+// InsertList inserts a list of URLs into the database. It uses a transaction to insert URLs one by one,
+// so either all of them are stored or, if any insert fails, none of them.
 func (r *URLRepository) InsertList(ctx context.Context, urls []*model.URL) ([]*model.URL, error) {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %v", err)
 	}
+	defer func() { _ = tx.Rollback(ctx) }()
 
 	var result []*model.URL
 	for _, url := range urls {
-		insertedURL, err := r.Insert(ctx, url)
+		insertedURL, err := r.insert(ctx, tx, url)
 		if err != nil {
 			return nil, err
 		}
 		result = append(result, insertedURL)
 	}
-	_ = tx.Commit(ctx)
-	defer func() {
-		if err := tx.Rollback(ctx); err != nil {
-			log.Printf("Failed to rollback transaction: %v", err)
-		}
-	}()
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
 	return result, nil
 }
 
+// insert runs the insert of Insert with the given querier, so it can be part of a transaction.
+func (r *URLRepository) insert(ctx context.Context, q rowQuerier, url *model.URL) (*model.URL, error) {
+	query := `
+		INSERT INTO urls (short_id, original_url, user_id, expires_at, max_clicks, password_hash) 
+		VALUES ($1, $2, $3, $4, $5, $6) 
+		ON CONFLICT (original_url) DO UPDATE 
+		SET short_id = urls.short_id 
+		RETURNING id, short_id, original_url, user_id, created_at`
+	err := q.QueryRow(ctx, query, url.ShortID, url.OriginalURL, url.UserID, url.ExpiresAt, url.MaxClicks, url.PasswordHash).
+		Scan(&url.ID, &url.ShortID, &url.OriginalURL, &url.UserID, &url.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode && pgErr.ConstraintName == shortIDConstraint {
+			return nil, fmt.Errorf("failed to insert URL: %w", interfaces.ErrShortIDTaken)
+		}
+		return nil, fmt.Errorf("failed to insert URL: %v", err)
+	}
+	return url, nil
+}
+
 // FindByID finds a URL by its short ID. Returns the URL if found, otherwise returns nil.
 func (r *URLRepository) FindByID(ctx context.Context, shortID string) (*model.URL, error) {
 	query := `
-		SELECT id, short_id, original_url, user_id, created_at, is_deleted FROM urls 
+		SELECT id, short_id, original_url, user_id, created_at, is_deleted, is_disabled, expires_at, max_clicks, clicks, password_hash FROM urls 
 		WHERE short_id = $1`
 	url := &model.URL{}
-	err := r.db.QueryRow(ctx, query, shortID).Scan(&url.ID, &url.ShortID, &url.OriginalURL, &url.UserID, &url.CreatedAt, &url.DeletedFlag)
+	err := r.db.QueryRow(ctx, query, shortID).Scan(&url.ID, &url.ShortID, &url.OriginalURL, &url.UserID, &url.CreatedAt, &url.DeletedFlag,
+		&url.DisabledFlag, &url.ExpiresAt, &url.MaxClicks, &url.Clicks, &url.PasswordHash)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -81,12 +106,48 @@ func (r *URLRepository) FindByID(ctx context.Context, shortID string) (*model.UR
 	return url, nil
 }
 
-// FindListByUserID finds all URLs associated with a specific user. It returns a list of URLs.
-func (r *URLRepository) FindListByUserID(ctx context.Context, userID string) ([]*model.URL, error) {
+// FindListByUserID finds the URLs of a specific user matching the query. The filters, the cursor
+// and the order are translated into SQL, using keyset pagination on the sort key and short ID.
+func (r *URLRepository) FindListByUserID(ctx context.Context, userID string, q model.URLListQuery) ([]*model.URL, error) {
 	query := `
-		SELECT id, short_id, original_url, user_id, created_at FROM urls 
+		SELECT id, short_id, original_url, user_id, created_at, is_deleted, is_disabled FROM urls 
 		WHERE user_id = $1`
-	rows, err := r.db.Query(ctx, query, userID)
+	args := []any{userID}
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if q.CreatedFrom != nil {
+		query += " AND created_at >= " + arg(*q.CreatedFrom)
+	}
+	if q.CreatedTo != nil {
+		query += " AND created_at < " + arg(*q.CreatedTo)
+	}
+	if q.Deleted != nil {
+		query += " AND is_deleted = " + arg(*q.Deleted)
+	}
+	if q.Search != "" {
+		query += " AND strpos(lower(original_url), lower(" + arg(q.Search) + ")) > 0"
+	}
+	column, direction, operator := "created_at", "ASC", ">"
+	if q.Sort.ByOriginalURL() {
+		column = "original_url"
+	}
+	if q.Sort.Descending() {
+		direction, operator = "DESC", "<"
+	}
+	if q.After != nil {
+		var value any = q.After.CreatedAt
+		if q.Sort.ByOriginalURL() {
+			value = q.After.OriginalURL
+		}
+		query += fmt.Sprintf(" AND (%s, short_id) %s (%s, %s)", column, operator, arg(value), arg(q.After.ShortID))
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, short_id %s", column, direction, direction)
+	if q.Limit > 0 {
+		query += " LIMIT " + arg(q.Limit)
+	}
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -95,7 +156,7 @@ func (r *URLRepository) FindListByUserID(ctx context.Context, userID string) ([]
 	var urls []*model.URL
 	for rows.Next() {
 		url := &model.URL{}
-		err := rows.Scan(&url.ID, &url.ShortID, &url.OriginalURL, &url.UserID, &url.CreatedAt)
+		err := rows.Scan(&url.ID, &url.ShortID, &url.OriginalURL, &url.UserID, &url.CreatedAt, &url.DeletedFlag, &url.DisabledFlag)
 		if err != nil {
 			return nil, err
 		}
@@ -104,10 +165,113 @@ func (r *URLRepository) FindListByUserID(ctx context.Context, userID string) ([]
 	return urls, nil
 }
 
+// UpdateOriginalURL changes the original URL of a non-deleted URL owned by the user.
+// It returns false if no such URL exists. If the new original URL is already shortened
+// under another short ID, the returned error wraps interfaces.ErrOriginalURLTaken.
+func (r *URLRepository) UpdateOriginalURL(ctx context.Context, userID, shortID, originalURL string) (bool, error) {
+	query := `
+		UPDATE urls
+		SET original_url = $3
+		WHERE short_id = $1 AND user_id = $2 AND is_deleted = false`
+	tag, err := r.db.Exec(ctx, query, shortID, userID, originalURL)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode && pgErr.ConstraintName == originalURLConstraint {
+			return false, fmt.Errorf("failed to update URL: %w", interfaces.ErrOriginalURLTaken)
+		}
+		return false, fmt.Errorf("failed to update URL: %v", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ReassignUserID moves the non-deleted URLs of a user to another user and returns how many were moved.
+func (r *URLRepository) ReassignUserID(ctx context.Context, fromUserID, toUserID string) (int64, error) {
+	query := `
+		UPDATE urls
+		SET user_id = $2
+		WHERE user_id = $1 AND is_deleted = false`
+	tag, err := r.db.Exec(ctx, query, fromUserID, toUserID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reassign urls: %v", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// SetDisabled marks the URLs with the short IDs as disabled or enabled again and returns how many changed.
+func (r *URLRepository) SetDisabled(ctx context.Context, shortIDs []string, disabled bool) (int64, error) {
+	query := `
+		UPDATE urls
+		SET is_disabled = $2
+		WHERE short_id = ANY($1) AND is_disabled <> $2`
+	tag, err := r.db.Exec(ctx, query, pq.Array(shortIDs), disabled)
+	if err != nil {
+		return 0, fmt.Errorf("failed to set disabled: %v", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// FindListByDomain finds the non-deleted URLs whose original URL points to the domain or one of
+// its subdomains. The query narrows the URLs down by text; the host is checked with model.URL.InDomain.
+func (r *URLRepository) FindListByDomain(ctx context.Context, domain string) ([]*model.URL, error) {
+	query := `
+		SELECT id, short_id, original_url, user_id, created_at, is_deleted, is_disabled, expires_at, max_clicks, clicks, password_hash FROM urls
+		WHERE is_deleted = false AND strpos(lower(original_url), lower($1)) > 0`
+	rows, err := r.db.Query(ctx, query, domain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find URLs by domain: %v", err)
+	}
+	defer rows.Close()
+
+	var urls []*model.URL
+	for rows.Next() {
+		url := &model.URL{}
+		if err := rows.Scan(&url.ID, &url.ShortID, &url.OriginalURL, &url.UserID, &url.CreatedAt, &url.DeletedFlag, &url.DisabledFlag,
+			&url.ExpiresAt, &url.MaxClicks, &url.Clicks, &url.PasswordHash); err != nil {
+			return nil, fmt.Errorf("failed to scan URL data: %v", err)
+		}
+		if url.InDomain(domain) {
+			urls = append(urls, url)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find URLs by domain: %v", err)
+	}
+	return urls, nil
+}
+
+// IncrementClicks atomically increases the click counter of a URL unless its click budget is used up.
+// It returns false if no clicks are left for the URL.
+func (r *URLRepository) IncrementClicks(ctx context.Context, shortID string) (bool, error) {
+	query := `
+		UPDATE urls
+		SET clicks = clicks + 1
+		WHERE short_id = $1 AND (max_clicks IS NULL OR clicks < max_clicks)`
+	tag, err := r.db.Exec(ctx, query, shortID)
+	if err != nil {
+		return false, fmt.Errorf("failed to increment clicks: %v", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeleteExpired soft deletes URLs whose expiration date has passed or whose click budget is used up.
+func (r *URLRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	query := `
+		UPDATE urls
+		SET is_deleted = true
+		WHERE is_deleted = false
+		  AND ((expires_at IS NOT NULL AND expires_at <= $1)
+		    OR (max_clicks IS NOT NULL AND clicks >= max_clicks))`
+	tag, err := r.db.Exec(ctx, query, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired urls: %v", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
 // List retrieves all URLs in the database. It returns a list of all URLs stored.
 func (r *URLRepository) List(ctx context.Context) ([]*model.URL, error) {
 	query := `
-		SELECT id, short_id, original_url, created_at, user_id 
+		SELECT id, short_id, original_url, created_at, user_id, is_deleted, is_disabled, expires_at, max_clicks, clicks, password_hash 
 		FROM urls`
 	rows, err := r.db.Query(ctx, query)
 	if err != nil {
@@ -118,7 +282,8 @@ func (r *URLRepository) List(ctx context.Context) ([]*model.URL, error) {
 	var urls []*model.URL
 	for rows.Next() {
 		url := &model.URL{}
-		if err := rows.Scan(&url.ID, &url.ShortID, &url.OriginalURL, &url.CreatedAt, &url.UserID); err != nil {
+		if err := rows.Scan(&url.ID, &url.ShortID, &url.OriginalURL, &url.CreatedAt, &url.UserID, &url.DeletedFlag, &url.DisabledFlag,
+			&url.ExpiresAt, &url.MaxClicks, &url.Clicks, &url.PasswordHash); err != nil {
 			return nil, fmt.Errorf("failed to scan URL data: %v", err)
 		}
 		urls = append(urls, url)
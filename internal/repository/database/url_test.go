@@ -4,17 +4,33 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"regexp"
 	"testing"
 	"time"
 
 	"github.com/GlebRadaev/shlink/internal/interfaces"
 	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/repository"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/lib/pq"
 
 	"github.com/GlebRadaev/shlink/internal/repository/database"
 	"github.com/pashagolub/pgxmock/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDatabaseDSNEnv names the environment variable with the DSN of a disposable local PostgreSQL
+// database, such as the one from docker-compose.yaml, to run the conformance suite against.
+// Its urls table is truncated before every test.
+const testDatabaseDSNEnv = "TEST_DATABASE_DSN"
+
+var (
+	nilTime *time.Time
+	nilInt  *int
 )
 
 func setupMockRepository(t *testing.T) (interfaces.IURLRepository, pgxmock.PgxPoolIface) {
@@ -45,7 +61,7 @@ func TestURLRepository_Insert(t *testing.T) {
 			userID:      "user123",
 			mockSetup: func() {
 				mockDB.ExpectQuery(`INSERT INTO urls`).
-					WithArgs("abc123", "http://example1.com", "user123").
+					WithArgs("abc123", "http://example1.com", "user123", nilTime, nilInt, "").
 					WillReturnRows(pgxmock.NewRows([]string{"id", "short_id", "original_url", "user_id", "created_at"}).
 						AddRow(1, "abc123", "http://example1.com", "user123", time.Now()))
 			},
@@ -58,11 +74,23 @@ func TestURLRepository_Insert(t *testing.T) {
 			userID:      "user124",
 			mockSetup: func() {
 				mockDB.ExpectQuery(`INSERT INTO urls`).
-					WithArgs("abc123", "http://example2.com", "user124").
+					WithArgs("abc123", "http://example2.com", "user124", nilTime, nilInt, "").
 					WillReturnError(errors.New("insert error"))
 			},
 			expectedError: errors.New("failed to insert URL: insert error"),
 		},
+		{
+			name:        "Short ID Taken",
+			shortID:     "abc123",
+			originalURL: "http://example3.com",
+			userID:      "user125",
+			mockSetup: func() {
+				mockDB.ExpectQuery(`INSERT INTO urls`).
+					WithArgs("abc123", "http://example3.com", "user125", nilTime, nilInt, "").
+					WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "urls_short_id_key"})
+			},
+			expectedError: fmt.Errorf("failed to insert URL: %w", interfaces.ErrShortIDTaken),
+		},
 	}
 
 	for _, tt := range tests {
@@ -108,11 +136,11 @@ func TestURLRepository_InsertList(t *testing.T) {
 			mockSetup: func() {
 				mockDB.ExpectBegin()
 				mockDB.ExpectQuery(`INSERT INTO urls`).
-					WithArgs("abc123", "http://example3.com", "user123").
+					WithArgs("abc123", "http://example3.com", "user123", nilTime, nilInt, "").
 					WillReturnRows(pgxmock.NewRows([]string{"id", "short_id", "original_url", "user_id", "created_at"}).
 						AddRow(1, "abc123", "http://example3.com", "user123", time.Now()))
 				mockDB.ExpectQuery(`INSERT INTO urls`).
-					WithArgs("xyz789", "http://another-example3.com", "user124").
+					WithArgs("xyz789", "http://another-example3.com", "user124", nilTime, nilInt, "").
 					WillReturnRows(pgxmock.NewRows([]string{"id", "short_id", "original_url", "user_id", "created_at"}).
 						AddRow(2, "xyz789", "http://another-example3.com", "user124", time.Now()))
 				mockDB.ExpectCommit()
@@ -139,11 +167,11 @@ func TestURLRepository_InsertList(t *testing.T) {
 			mockSetup: func() {
 				mockDB.ExpectBegin()
 				mockDB.ExpectQuery(`INSERT INTO urls`).
-					WithArgs("abc123", "http://example4.com", "user125").
+					WithArgs("abc123", "http://example4.com", "user125", nilTime, nilInt, "").
 					WillReturnRows(pgxmock.NewRows([]string{"id", "short_id", "original_url", "user_id", "created_at"}).
 						AddRow(1, "abc123", "http://example4.com", "user125", time.Now()))
 				mockDB.ExpectQuery(`INSERT INTO urls`).
-					WithArgs("xyz789", "http://another-example4.com", "user126").
+					WithArgs("xyz789", "http://another-example4.com", "user126", nilTime, nilInt, "").
 					WillReturnError(fmt.Errorf("insert error"))
 				mockDB.ExpectRollback()
 			},
@@ -190,26 +218,27 @@ func TestURLRepository_FindByID(t *testing.T) {
 			mockSetup: func() {
 				fixedTime := time.Now()
 
-				mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT id, short_id, original_url, user_id, created_at, is_deleted FROM urls WHERE short_id = $1`)).
+				mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT id, short_id, original_url, user_id, created_at, is_deleted, is_disabled, expires_at, max_clicks, clicks, password_hash FROM urls WHERE short_id = $1`)).
 					WithArgs("12345678").
-					WillReturnRows(pgxmock.NewRows([]string{"id", "short_id", "original_url", "user_id", "created_at", "is_deleted"}).
-						AddRow(1, "12345678", "http://example.com", "user123", fixedTime, false))
+					WillReturnRows(pgxmock.NewRows([]string{"id", "short_id", "original_url", "user_id", "created_at", "is_deleted", "is_disabled", "expires_at", "max_clicks", "clicks", "password_hash"}).
+						AddRow(1, "12345678", "http://example.com", "user123", fixedTime, false, false, nil, nil, 0, "hash"))
 			},
 			expectedError: nil,
 			expectedURL: &model.URL{
-				ID:          1,
-				ShortID:     "12345678",
-				OriginalURL: "http://example.com",
-				UserID:      "user123",
+				ID:           1,
+				ShortID:      "12345678",
+				OriginalURL:  "http://example.com",
+				UserID:       "user123",
+				PasswordHash: "hash",
 			},
 		},
 		{
 			name:    "FindByID Error - No Rows",
 			shortID: "nonexistentID",
 			mockSetup: func() {
-				mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT id, short_id, original_url, user_id, created_at, is_deleted FROM urls WHERE short_id = $1`)).
+				mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT id, short_id, original_url, user_id, created_at, is_deleted, is_disabled, expires_at, max_clicks, clicks, password_hash FROM urls WHERE short_id = $1`)).
 					WithArgs("nonexistentID").
-					WillReturnRows(pgxmock.NewRows([]string{"id", "short_id", "original_url", "user_id", "created_at", "is_deleted"}))
+					WillReturnRows(pgxmock.NewRows([]string{"id", "short_id", "original_url", "user_id", "created_at", "is_deleted", "is_disabled", "expires_at", "max_clicks", "clicks", "password_hash"}))
 			},
 			expectedError: nil,
 			expectedURL:   nil,
@@ -218,7 +247,7 @@ func TestURLRepository_FindByID(t *testing.T) {
 			name:    "FindByID Error - DB Error",
 			shortID: "someID",
 			mockSetup: func() {
-				mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT id, short_id, original_url, user_id, created_at, is_deleted FROM urls WHERE short_id = $1`)).
+				mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT id, short_id, original_url, user_id, created_at, is_deleted, is_disabled, expires_at, max_clicks, clicks, password_hash FROM urls WHERE short_id = $1`)).
 					WithArgs("someID").
 					WillReturnError(fmt.Errorf("database connection error"))
 			},
@@ -243,6 +272,7 @@ func TestURLRepository_FindByID(t *testing.T) {
 				} else {
 					assert.Equal(t, tt.expectedURL.ShortID, foundURL.ShortID)
 					assert.Equal(t, tt.expectedURL.OriginalURL, foundURL.OriginalURL)
+					assert.Equal(t, tt.expectedURL.PasswordHash, foundURL.PasswordHash)
 					assert.WithinDuration(t, foundURL.CreatedAt, time.Now(), 1*time.Second)
 				}
 			}
@@ -255,6 +285,7 @@ func TestURLRepository_List(t *testing.T) {
 	repo, mockDB := setupMockRepository(t)
 	defer mockDB.Close()
 	fixedTime := time.Date(2024, time.November, 17, 12, 0, 0, 0, time.UTC)
+	maxClicks := 5
 
 	tests := []struct {
 		name          string
@@ -265,21 +296,22 @@ func TestURLRepository_List(t *testing.T) {
 		{
 			name: "Successful List",
 			mockSetup: func() {
-				mockDB.ExpectQuery(`SELECT id, short_id, original_url, created_at, user_id FROM urls`).
-					WillReturnRows(pgxmock.NewRows([]string{"id", "short_id", "original_url", "created_at", "user_id"}).
-						AddRow(1, "abc123", "http://example.com", fixedTime, "user123").
-						AddRow(2, "xyz789", "http://another-example.com", fixedTime, "user456"))
+				mockDB.ExpectQuery(`SELECT id, short_id, original_url, created_at, user_id, is_deleted, is_disabled, expires_at, max_clicks, clicks, password_hash FROM urls`).
+					WillReturnRows(pgxmock.NewRows([]string{"id", "short_id", "original_url", "created_at", "user_id", "is_deleted", "is_disabled", "expires_at", "max_clicks", "clicks", "password_hash"}).
+						AddRow(1, "abc123", "http://example.com", fixedTime, "user123", false, false, nilTime, nilInt, 0, "").
+						AddRow(2, "xyz789", "http://another-example.com", fixedTime, "user456", true, true, &fixedTime, &maxClicks, 2, "hash"))
 			},
 			expectedURLs: []*model.URL{
 				{ID: 1, ShortID: "abc123", OriginalURL: "http://example.com", CreatedAt: fixedTime, UserID: "user123"},
-				{ID: 2, ShortID: "xyz789", OriginalURL: "http://another-example.com", CreatedAt: fixedTime, UserID: "user456"},
+				{ID: 2, ShortID: "xyz789", OriginalURL: "http://another-example.com", CreatedAt: fixedTime, UserID: "user456",
+					DeletedFlag: true, DisabledFlag: true, ExpiresAt: &fixedTime, MaxClicks: &maxClicks, Clicks: 2, PasswordHash: "hash"},
 			},
 			expectedError: nil,
 		},
 		{
 			name: "List Error",
 			mockSetup: func() {
-				mockDB.ExpectQuery(`SELECT id, short_id, original_url, created_at, user_id FROM urls`).
+				mockDB.ExpectQuery(`SELECT id, short_id, original_url, created_at, user_id, is_deleted, is_disabled, expires_at, max_clicks, clicks, password_hash FROM urls`).
 					WillReturnError(fmt.Errorf("error fetching URLs"))
 			},
 			expectedURLs:  nil,
@@ -288,10 +320,10 @@ func TestURLRepository_List(t *testing.T) {
 		{
 			name: "List Scan Error",
 			mockSetup: func() {
-				mockDB.ExpectQuery(`SELECT id, short_id, original_url, created_at, user_id FROM urls`).
-					WillReturnRows(pgxmock.NewRows([]string{"id", "short_id", "original_url", "created_at", "user_id"}).
-						AddRow(1, "abc123", "http://example.com", fixedTime, "user123").
-						AddRow(2, "xyz789", "http://another-example.com", fixedTime, "user456").
+				mockDB.ExpectQuery(`SELECT id, short_id, original_url, created_at, user_id, is_deleted, is_disabled, expires_at, max_clicks, clicks, password_hash FROM urls`).
+					WillReturnRows(pgxmock.NewRows([]string{"id", "short_id", "original_url", "created_at", "user_id", "is_deleted", "is_disabled", "expires_at", "max_clicks", "clicks", "password_hash"}).
+						AddRow(1, "abc123", "http://example.com", fixedTime, "user123", false, false, nilTime, nilInt, 0, "").
+						AddRow(2, "xyz789", "http://another-example.com", fixedTime, "user456", true, true, &fixedTime, &maxClicks, 2, "hash").
 						RowError(1, fmt.Errorf("failed to scan URL data")))
 			},
 			expectedURLs:  nil,
@@ -311,10 +343,7 @@ func TestURLRepository_List(t *testing.T) {
 			} else {
 				assert.NoError(t, err)
 				assert.Len(t, urls, len(tt.expectedURLs))
-				for i, url := range urls {
-					assert.Equal(t, tt.expectedURLs[i].ShortID, url.ShortID)
-					assert.Equal(t, tt.expectedURLs[i].OriginalURL, url.OriginalURL)
-				}
+				assert.Equal(t, tt.expectedURLs, urls)
 			}
 		})
 	}
@@ -371,31 +400,82 @@ func TestURLRepository_ListByUserID(t *testing.T) {
 	repo, mockDB := setupMockRepository(t)
 	defer mockDB.Close()
 
+	from := time.Date(2024, time.November, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, time.December, 1, 0, 0, 0, 0, time.UTC)
+	active := false
+	columns := []string{"id", "short_id", "original_url", "user_id", "created_at", "is_deleted", "is_disabled"}
+
 	tests := []struct {
 		name          string
 		mockSetup     func()
 		userID        string
+		query         model.URLListQuery
 		expectedURLs  []*model.URL
 		expectedError error
 	}{
 		{
 			name: "Successful List By UserID",
 			mockSetup: func() {
-				mockDB.ExpectQuery(`SELECT id, short_id, original_url, user_id, created_at FROM urls WHERE user_id = \$1`).
+				mockDB.ExpectQuery(regexp.QuoteMeta(`SELECT id, short_id, original_url, user_id, created_at, is_deleted, is_disabled FROM urls 
+		WHERE user_id = $1 ORDER BY created_at DESC, short_id DESC`)).
 					WithArgs("user123").
-					WillReturnRows(pgxmock.NewRows([]string{"id", "short_id", "original_url", "user_id", "created_at"}).
-						AddRow(1, "abc123", "http://example.com", "user123", time.Now()))
+					WillReturnRows(pgxmock.NewRows(columns).
+						AddRow(1, "abc123", "http://example.com", "user123", time.Now(), true, false))
+			},
+			userID: "user123",
+			expectedURLs: []*model.URL{
+				{ID: 1, ShortID: "abc123", OriginalURL: "http://example.com", CreatedAt: time.Now(), UserID: "user123", DeletedFlag: true},
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Filtered List By UserID",
+			mockSetup: func() {
+				mockDB.ExpectQuery(regexp.QuoteMeta(`WHERE user_id = $1 AND created_at >= $2 AND created_at < $3`+
+					` AND is_deleted = $4 AND strpos(lower(original_url), lower($5)) > 0`+
+					` ORDER BY created_at ASC, short_id ASC LIMIT $6`)).
+					WithArgs("user123", from, to, false, "Example", 10).
+					WillReturnRows(pgxmock.NewRows(columns).
+						AddRow(1, "abc123", "http://example.com", "user123", from, false, false))
 			},
 			userID: "user123",
+			query: model.URLListQuery{
+				CreatedFrom: &from,
+				CreatedTo:   &to,
+				Deleted:     &active,
+				Search:      "Example",
+				Sort:        model.SortCreatedAtAsc,
+				Limit:       10,
+			},
 			expectedURLs: []*model.URL{
-				{ID: 1, ShortID: "abc123", OriginalURL: "http://example.com", CreatedAt: time.Now(), UserID: "user123"},
+				{ID: 1, ShortID: "abc123", OriginalURL: "http://example.com", UserID: "user123"},
+			},
+			expectedError: nil,
+		},
+		{
+			name: "List By UserID After Cursor",
+			mockSetup: func() {
+				mockDB.ExpectQuery(regexp.QuoteMeta(`WHERE user_id = $1 AND (original_url, short_id) < ($2, $3)`+
+					` ORDER BY original_url DESC, short_id DESC LIMIT $4`)).
+					WithArgs("user123", "http://b.com", "abc123", 2).
+					WillReturnRows(pgxmock.NewRows(columns).
+						AddRow(2, "def456", "http://a.com", "user123", from, false, false))
+			},
+			userID: "user123",
+			query: model.URLListQuery{
+				Sort:  model.SortOriginalURLDesc,
+				After: &model.URLCursor{OriginalURL: "http://b.com", ShortID: "abc123"},
+				Limit: 2,
+			},
+			expectedURLs: []*model.URL{
+				{ID: 2, ShortID: "def456", OriginalURL: "http://a.com", UserID: "user123"},
 			},
 			expectedError: nil,
 		},
 		{
 			name: "List By UserID Error",
 			mockSetup: func() {
-				mockDB.ExpectQuery(`SELECT id, short_id, original_url, user_id, created_at FROM urls WHERE user_id = \$1`).
+				mockDB.ExpectQuery(`SELECT id, short_id, original_url, user_id, created_at, is_deleted, is_disabled FROM urls WHERE user_id = \$1`).
 					WithArgs("user123").
 					WillReturnError(fmt.Errorf("error fetching URLs for user 123"))
 			},
@@ -409,7 +489,7 @@ func TestURLRepository_ListByUserID(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockSetup()
 
-			urls, err := repo.FindListByUserID(ctx, tt.userID)
+			urls, err := repo.FindListByUserID(ctx, tt.userID, tt.query)
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
@@ -421,11 +501,14 @@ func TestURLRepository_ListByUserID(t *testing.T) {
 					assert.Equal(t, tt.expectedURLs[i].ShortID, url.ShortID)
 					assert.Equal(t, tt.expectedURLs[i].OriginalURL, url.OriginalURL)
 					assert.Equal(t, tt.expectedURLs[i].UserID, url.UserID)
+					assert.Equal(t, tt.expectedURLs[i].DeletedFlag, url.DeletedFlag)
 				}
 			}
+			assert.NoError(t, mockDB.ExpectationsWereMet())
 		})
 	}
 }
+
 func TestURLRepository_DeleteListByUserIDAndShortIDs(t *testing.T) {
 	ctx := context.Background()
 	repo, mockDB := setupMockRepository(t)
@@ -503,3 +586,263 @@ func TestURLRepository_DeleteListByUserIDAndShortIDs(t *testing.T) {
 		})
 	}
 }
+
+func TestURLRepository_UpdateOriginalURL(t *testing.T) {
+	ctx := context.Background()
+	repo, mockDB := setupMockRepository(t)
+	defer mockDB.Close()
+	query := `UPDATE urls SET original_url = \$3 WHERE short_id = \$1 AND user_id = \$2 AND is_deleted = false`
+
+	tests := []struct {
+		name          string
+		mockSetup     func()
+		shortID       string
+		expectedOK    bool
+		expectedError error
+	}{
+		{
+			name: "Successful Update",
+			mockSetup: func() {
+				mockDB.ExpectExec(query).
+					WithArgs("short1", "user1", "http://new.com").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+			},
+			shortID:    "short1",
+			expectedOK: true,
+		},
+		{
+			name: "No Matching URL",
+			mockSetup: func() {
+				mockDB.ExpectExec(query).
+					WithArgs("short2", "user1", "http://new.com").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+			},
+			shortID:    "short2",
+			expectedOK: false,
+		},
+		{
+			name: "Original URL Taken",
+			mockSetup: func() {
+				mockDB.ExpectExec(query).
+					WithArgs("short3", "user1", "http://new.com").
+					WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "urls_original_url_key"})
+			},
+			shortID:       "short3",
+			expectedError: fmt.Errorf("failed to update URL: %w", interfaces.ErrOriginalURLTaken),
+		},
+		{
+			name: "SQL Execution Error",
+			mockSetup: func() {
+				mockDB.ExpectExec(query).
+					WithArgs("short4", "user1", "http://new.com").
+					WillReturnError(fmt.Errorf("SQL execution error"))
+			},
+			shortID:       "short4",
+			expectedError: fmt.Errorf("failed to update URL: SQL execution error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			ok, err := repo.UpdateOriginalURL(ctx, "user1", tt.shortID, "http://new.com")
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				if errors.Is(tt.expectedError, interfaces.ErrOriginalURLTaken) {
+					assert.ErrorIs(t, err, interfaces.ErrOriginalURLTaken)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedOK, ok)
+			}
+			assert.NoError(t, mockDB.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestURLRepository_IncrementClicks(t *testing.T) {
+	ctx := context.Background()
+	repo, mockDB := setupMockRepository(t)
+	defer mockDB.Close()
+
+	tests := []struct {
+		name          string
+		mockSetup     func()
+		shortID       string
+		expectedOK    bool
+		expectedError string
+	}{
+		{
+			name: "Click counted",
+			mockSetup: func() {
+				mockDB.ExpectExec(`UPDATE urls SET clicks = clicks \+ 1 WHERE short_id = \$1`).
+					WithArgs("short1").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+			},
+			shortID:    "short1",
+			expectedOK: true,
+		},
+		{
+			name: "Click budget used up",
+			mockSetup: func() {
+				mockDB.ExpectExec(`UPDATE urls SET clicks = clicks \+ 1 WHERE short_id = \$1`).
+					WithArgs("short2").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+			},
+			shortID:    "short2",
+			expectedOK: false,
+		},
+		{
+			name: "SQL Execution Error",
+			mockSetup: func() {
+				mockDB.ExpectExec(`UPDATE urls SET clicks = clicks \+ 1 WHERE short_id = \$1`).
+					WithArgs("short3").
+					WillReturnError(fmt.Errorf("SQL execution error"))
+			},
+			shortID:       "short3",
+			expectedError: "failed to increment clicks: SQL execution error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			ok, err := repo.IncrementClicks(ctx, tt.shortID)
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedOK, ok)
+			}
+		})
+	}
+}
+
+func TestURLRepository_DeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	repo, mockDB := setupMockRepository(t)
+	defer mockDB.Close()
+	now := time.Date(2024, time.November, 17, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		mockSetup     func()
+		expectedCount int64
+		expectedError string
+	}{
+		{
+			name: "Successful Deletion",
+			mockSetup: func() {
+				mockDB.ExpectExec(`UPDATE urls SET is_deleted = true WHERE is_deleted = false`).
+					WithArgs(now).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 3))
+			},
+			expectedCount: 3,
+		},
+		{
+			name: "SQL Execution Error",
+			mockSetup: func() {
+				mockDB.ExpectExec(`UPDATE urls SET is_deleted = true WHERE is_deleted = false`).
+					WithArgs(now).
+					WillReturnError(fmt.Errorf("SQL execution error"))
+			},
+			expectedError: "failed to delete expired urls: SQL execution error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			count, err := repo.DeleteExpired(ctx, now)
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedCount, count)
+			}
+		})
+	}
+}
+
+func TestURLRepository_Conformance(t *testing.T) {
+	dsn := os.Getenv(testDatabaseDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDatabaseDSNEnv)
+	}
+	ctx := context.Background()
+	require.NoError(t, repository.Migrate(ctx, dsn))
+	pool, err := pgxpool.New(ctx, dsn)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	repotest.RunURLRepositorySuite(t, func(t *testing.T) interfaces.IURLRepository {
+		_, err := pool.Exec(ctx, "TRUNCATE urls RESTART IDENTITY")
+		require.NoError(t, err)
+		return database.NewURLRepository(pool)
+	})
+}
+
+func TestURLRepository_ReassignUserID(t *testing.T) {
+	ctx := context.Background()
+	repo, mockDB := setupMockRepository(t)
+	defer mockDB.Close()
+
+	mockDB.ExpectExec(`UPDATE urls SET user_id = \$2 WHERE user_id = \$1 AND is_deleted = false`).
+		WithArgs("anonymous", "user1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+	count, err := repo.ReassignUserID(ctx, "anonymous", "user1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	mockDB.ExpectExec(`UPDATE urls SET user_id`).
+		WithArgs("anonymous", "user1").
+		WillReturnError(fmt.Errorf("SQL execution error"))
+	_, err = repo.ReassignUserID(ctx, "anonymous", "user1")
+	assert.EqualError(t, err, "failed to reassign urls: SQL execution error")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestURLRepository_SetDisabled(t *testing.T) {
+	ctx := context.Background()
+	repo, mockDB := setupMockRepository(t)
+	defer mockDB.Close()
+	shortIDs := []string{"short1", "short2"}
+
+	mockDB.ExpectExec(`UPDATE urls SET is_disabled = \$2 WHERE short_id = ANY\(\$1\) AND is_disabled <> \$2`).
+		WithArgs(pq.Array(shortIDs), true).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+	count, err := repo.SetDisabled(ctx, shortIDs, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	mockDB.ExpectExec(`UPDATE urls SET is_disabled`).
+		WithArgs(pq.Array(shortIDs), false).
+		WillReturnError(fmt.Errorf("SQL execution error"))
+	_, err = repo.SetDisabled(ctx, shortIDs, false)
+	assert.EqualError(t, err, "failed to set disabled: SQL execution error")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestURLRepository_FindListByDomain(t *testing.T) {
+	ctx := context.Background()
+	repo, mockDB := setupMockRepository(t)
+	defer mockDB.Close()
+	now := time.Now()
+	columns := []string{"id", "short_id", "original_url", "user_id", "created_at", "is_deleted", "is_disabled",
+		"expires_at", "max_clicks", "clicks", "password_hash"}
+
+	mockDB.ExpectQuery(`SELECT (.+) FROM urls WHERE is_deleted = false AND strpos\(lower\(original_url\), lower\(\$1\)\) > 0`).
+		WithArgs("example.com").
+		WillReturnRows(pgxmock.NewRows(columns).
+			AddRow(1, "short1", "http://sub.example.com/1", "user1", now, false, false, nilTime, nilInt, 0, "").
+			AddRow(2, "short2", "http://other.com/?to=example.com", "user1", now, false, false, nilTime, nilInt, 0, ""))
+	urls, err := repo.FindListByDomain(ctx, "example.com")
+	require.NoError(t, err)
+	require.Len(t, urls, 1, "Expected URLs only mentioning the domain to be filtered out")
+	assert.Equal(t, "short1", urls[0].ShortID)
+
+	mockDB.ExpectQuery(`SELECT (.+) FROM urls`).WithArgs("example.com").WillReturnError(fmt.Errorf("query error"))
+	_, err = repo.FindListByDomain(ctx, "example.com")
+	assert.EqualError(t, err, "failed to find URLs by domain: query error")
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
@@ -0,0 +1,72 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/interfaces/identity.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/interfaces/identity.go -destination=internal/repository/identity_mock.go -package=repository
+//
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/GlebRadaev/shlink/internal/model"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockIIdentityRepository is a mock of IIdentityRepository interface.
+type MockIIdentityRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockIIdentityRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockIIdentityRepositoryMockRecorder is the mock recorder for MockIIdentityRepository.
+type MockIIdentityRepositoryMockRecorder struct {
+	mock *MockIIdentityRepository
+}
+
+// NewMockIIdentityRepository creates a new mock instance.
+func NewMockIIdentityRepository(ctrl *gomock.Controller) *MockIIdentityRepository {
+	mock := &MockIIdentityRepository{ctrl: ctrl}
+	mock.recorder = &MockIIdentityRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIIdentityRepository) EXPECT() *MockIIdentityRepositoryMockRecorder {
+	return m.recorder
+}
+
+// FindByUserID mocks base method.
+func (m *MockIIdentityRepository) FindByUserID(ctx context.Context, userID string) (*model.UserIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByUserID", ctx, userID)
+	ret0, _ := ret[0].(*model.UserIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByUserID indicates an expected call of FindByUserID.
+func (mr *MockIIdentityRepositoryMockRecorder) FindByUserID(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByUserID", reflect.TypeOf((*MockIIdentityRepository)(nil).FindByUserID), ctx, userID)
+}
+
+// FindOrInsert mocks base method.
+func (m *MockIIdentityRepository) FindOrInsert(ctx context.Context, identity *model.UserIdentity) (*model.UserIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOrInsert", ctx, identity)
+	ret0, _ := ret[0].(*model.UserIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOrInsert indicates an expected call of FindOrInsert.
+func (mr *MockIIdentityRepositoryMockRecorder) FindOrInsert(ctx, identity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrInsert", reflect.TypeOf((*MockIIdentityRepository)(nil).FindOrInsert), ctx, identity)
+}
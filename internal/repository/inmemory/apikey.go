@@ -0,0 +1,103 @@
+package inmemory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// APIKeyStorage is an in-memory storage implementation of IAPIKeyRepository.
+// It keeps the API keys by the hash of the key.
+type APIKeyStorage struct {
+	data map[string]model.APIKey // Map of key hash to its API key
+	mu   sync.RWMutex            // Read/Write mutex for synchronization
+}
+
+// NewAPIKeyStorage creates a new instance of APIKeyStorage that implements
+// the IAPIKeyRepository interface.
+func NewAPIKeyStorage() interfaces.IAPIKeyRepository {
+	return &APIKeyStorage{
+		data: make(map[string]model.APIKey),
+	}
+}
+
+// Insert stores a copy of the API key in memory.
+func (s *APIKeyStorage) Insert(ctx context.Context, key *model.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	stored := *key
+	stored.Scopes = slices.Clone(key.Scopes)
+	s.data[key.KeyHash] = stored
+	return nil
+}
+
+// FindByHash retrieves an API key by the hash of the key. Returns nil if no key has the hash.
+func (s *APIKeyStorage) FindByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	key, exists := s.data[keyHash]
+	if !exists {
+		return nil, nil
+	}
+	return copyAPIKey(key), nil
+}
+
+// FindListByUserID retrieves the API keys of the user ordered by creation time.
+func (s *APIKeyStorage) FindListByUserID(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var keys []*model.APIKey
+	for _, key := range s.data {
+		if key.UserID == userID {
+			keys = append(keys, copyAPIKey(key))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+// Revoke marks the active API key of the user with the given ID as revoked.
+func (s *APIKeyStorage) Revoke(ctx context.Context, userID, id string, revokedAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	for hash, key := range s.data {
+		if key.ID == id && key.UserID == userID && !key.IsRevoked() {
+			key.RevokedAt = &revokedAt
+			s.data[hash] = key
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// copyAPIKey returns a copy of the stored key that callers may modify.
+func copyAPIKey(key model.APIKey) *model.APIKey {
+	key.Scopes = slices.Clone(key.Scopes)
+	if key.RevokedAt != nil {
+		revokedAt := *key.RevokedAt
+		key.RevokedAt = &revokedAt
+	}
+	return &key
+}
@@ -0,0 +1,37 @@
+package inmemory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/repository/inmemory"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyStorage_Copies(t *testing.T) {
+	ctx := context.Background()
+	storage := inmemory.NewAPIKeyStorage()
+	key := &model.APIKey{ID: "key1", UserID: "user1", KeyHash: "hash", Scopes: []string{model.ScopeRead}, CreatedAt: time.Now()}
+	require.NoError(t, storage.Insert(ctx, key))
+	key.Scopes[0] = model.ScopeDelete
+
+	found, err := storage.FindByHash(ctx, "hash")
+	require.NoError(t, err)
+	assert.Equal(t, []string{model.ScopeRead}, found.Scopes, "Expected the stored key not to change with the inserted one")
+	found.Scopes[0] = model.ScopeDelete
+
+	found, err = storage.FindByHash(ctx, "hash")
+	require.NoError(t, err)
+	assert.Equal(t, []string{model.ScopeRead}, found.Scopes, "Expected the stored key not to change with the returned one")
+}
+
+func TestAPIKeyStorage_Conformance(t *testing.T) {
+	repotest.RunAPIKeyRepositorySuite(t, func(t *testing.T) interfaces.IAPIKeyRepository {
+		return inmemory.NewAPIKeyStorage()
+	})
+}
@@ -0,0 +1,55 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// AuditStorage is an in-memory storage implementation of IAuditRepository.
+// It keeps the entries in insertion order.
+type AuditStorage struct {
+	entries []model.AuditEntry // Entries ordered by ID
+	mu      sync.RWMutex       // Read/Write mutex for synchronization
+}
+
+// NewAuditStorage creates a new instance of AuditStorage that implements
+// the IAuditRepository interface.
+func NewAuditStorage() interfaces.IAuditRepository {
+	return &AuditStorage{}
+}
+
+// Insert appends a copy of the entry, numbering it after the last one.
+func (s *AuditStorage) Insert(ctx context.Context, entry *model.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	entry.ID = int64(len(s.entries)) + 1
+	s.entries = append(s.entries, *entry)
+	return nil
+}
+
+// FindList retrieves the entries matching the query, newest first.
+func (s *AuditStorage) FindList(ctx context.Context, query model.AuditQuery) ([]*model.AuditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var result []*model.AuditEntry
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if query.Limit > 0 && len(result) == query.Limit {
+			break
+		}
+		entry := s.entries[i]
+		if (query.Before > 0 && entry.ID >= query.Before) || (query.Target != "" && entry.Target != query.Target) {
+			continue
+		}
+		result = append(result, &entry)
+	}
+	return result, nil
+}
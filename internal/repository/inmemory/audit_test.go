@@ -0,0 +1,15 @@
+package inmemory_test
+
+import (
+	"testing"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/repository/inmemory"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+)
+
+func TestAuditStorage_Conformance(t *testing.T) {
+	repotest.RunAuditRepositorySuite(t, func(t *testing.T) interfaces.IAuditRepository {
+		return inmemory.NewAuditStorage()
+	})
+}
@@ -0,0 +1,75 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// ClickStorage is an in-memory storage implementation of IClickRepository.
+// It keeps click events grouped by short ID.
+type ClickStorage struct {
+	data map[string][]model.Click // Map of shortID to its click events
+	mu   sync.RWMutex             // Read/Write mutex for synchronization
+}
+
+// NewClickStorage creates a new instance of ClickStorage that implements
+// the IClickRepository interface.
+func NewClickStorage() interfaces.IClickRepository {
+	return &ClickStorage{
+		data: make(map[string][]model.Click),
+	}
+}
+
+// InsertList stores a batch of click events in memory.
+func (s *ClickStorage) InsertList(ctx context.Context, clicks []*model.Click) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, click := range clicks {
+		s.data[click.ShortID] = append(s.data[click.ShortID], *click)
+	}
+	return nil
+}
+
+// GetStats aggregates the stored click events of a short ID into totals,
+// unique visitors and a daily time series ordered by date.
+func (s *ClickStorage) GetStats(ctx context.Context, shortID string) (*model.ClickStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	stats := &model.ClickStats{}
+	visitors := make(map[string]struct{})
+	days := make(map[time.Time]*model.DailyClicks)
+	dayVisitors := make(map[time.Time]map[string]struct{})
+	for _, click := range s.data[shortID] {
+		stats.TotalClicks++
+		visitors[click.IP] = struct{}{}
+
+		day := click.ClickedAt.UTC().Truncate(24 * time.Hour)
+		if _, exists := days[day]; !exists {
+			days[day] = &model.DailyClicks{Date: day}
+			dayVisitors[day] = make(map[string]struct{})
+		}
+		days[day].Clicks++
+		dayVisitors[day][click.IP] = struct{}{}
+	}
+	stats.UniqueVisitors = int64(len(visitors))
+	for day, daily := range days {
+		daily.UniqueVisitors = int64(len(dayVisitors[day]))
+		stats.Daily = append(stats.Daily, *daily)
+	}
+	sort.Slice(stats.Daily, func(i, j int) bool {
+		return stats.Daily[i].Date.Before(stats.Daily[j].Date)
+	})
+	return stats, nil
+}
@@ -0,0 +1,69 @@
+package inmemory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/repository/inmemory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClickStorage_GetStats(t *testing.T) {
+	storage := inmemory.NewClickStorage()
+	ctx := context.Background()
+	day1 := time.Date(2024, time.November, 17, 10, 0, 0, 0, time.UTC)
+	day2 := time.Date(2024, time.November, 18, 10, 0, 0, 0, time.UTC)
+
+	clicks := []*model.Click{
+		{ShortID: "short1", ClickedAt: day2, IP: "192.0.2.1"},
+		{ShortID: "short1", ClickedAt: day1, IP: "192.0.2.1"},
+		{ShortID: "short1", ClickedAt: day1.Add(time.Hour), IP: "192.0.2.2"},
+		{ShortID: "short1", ClickedAt: day1.Add(2 * time.Hour), IP: "192.0.2.1"},
+		{ShortID: "short2", ClickedAt: day1, IP: "192.0.2.3"},
+	}
+	assert.NoError(t, storage.InsertList(ctx, clicks))
+
+	tests := []struct {
+		name     string
+		shortID  string
+		expected *model.ClickStats
+	}{
+		{
+			name:    "clicks grouped by day",
+			shortID: "short1",
+			expected: &model.ClickStats{
+				TotalClicks:    4,
+				UniqueVisitors: 2,
+				Daily: []model.DailyClicks{
+					{Date: time.Date(2024, time.November, 17, 0, 0, 0, 0, time.UTC), Clicks: 3, UniqueVisitors: 2},
+					{Date: time.Date(2024, time.November, 18, 0, 0, 0, 0, time.UTC), Clicks: 1, UniqueVisitors: 1},
+				},
+			},
+		},
+		{
+			name:     "no clicks",
+			shortID:  "unknown",
+			expected: &model.ClickStats{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, err := storage.GetStats(ctx, tt.shortID)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, stats)
+		})
+	}
+}
+
+func TestClickStorage_ContextCancelled(t *testing.T) {
+	storage := inmemory.NewClickStorage()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, storage.InsertList(ctx, []*model.Click{{ShortID: "short1"}}), context.Canceled)
+	_, err := storage.GetStats(ctx, "short1")
+	assert.ErrorIs(t, err, context.Canceled)
+}
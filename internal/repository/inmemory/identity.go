@@ -0,0 +1,61 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// identityKey identifies an account at a provider.
+type identityKey struct {
+	issuer  string
+	subject string
+}
+
+// IdentityStorage is an in-memory storage implementation of IIdentityRepository.
+type IdentityStorage struct {
+	data map[identityKey]model.UserIdentity // Map of issuer and subject to the linked identity
+	mu   sync.RWMutex                       // Read/Write mutex for synchronization
+}
+
+// NewIdentityStorage creates a new instance of IdentityStorage that implements
+// the IIdentityRepository interface.
+func NewIdentityStorage() interfaces.IIdentityRepository {
+	return &IdentityStorage{
+		data: make(map[identityKey]model.UserIdentity),
+	}
+}
+
+// FindOrInsert stores a copy of the identity unless its account is already linked,
+// and returns a copy of the stored identity.
+func (s *IdentityStorage) FindOrInsert(ctx context.Context, identity *model.UserIdentity) (*model.UserIdentity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	key := identityKey{issuer: identity.Issuer, subject: identity.Subject}
+	stored, exists := s.data[key]
+	if !exists {
+		stored = *identity
+		s.data[key] = stored
+	}
+	return &stored, nil
+}
+
+// FindByUserID retrieves an identity linked to the user. Returns nil if there is none.
+func (s *IdentityStorage) FindByUserID(ctx context.Context, userID string) (*model.UserIdentity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, identity := range s.data {
+		if identity.UserID == userID {
+			return &identity, nil
+		}
+	}
+	return nil, nil
+}
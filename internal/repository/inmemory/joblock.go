@@ -0,0 +1,68 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+)
+
+// jobLock is the lock of a recurring job.
+type jobLock struct {
+	lastRunAt   time.Time // Time of the last run that was taken
+	lockedBy    string    // Owner of the lock
+	lockedUntil time.Time // Expiry of the lease of the owner
+}
+
+// JobLockStorage is an in-memory storage implementation of IJobLockRepository, for pools sharing
+// a process.
+type JobLockStorage struct {
+	data map[string]jobLock // Map of job names to their lock
+	mu   sync.Mutex         // Mutex for synchronization
+}
+
+// NewJobLockStorage creates a new instance of JobLockStorage that implements
+// the IJobLockRepository interface.
+func NewJobLockStorage() interfaces.IJobLockRepository {
+	return &JobLockStorage{
+		data: make(map[string]jobLock),
+	}
+}
+
+// Acquire takes the lock of the job for the run at runAt unless it is held or the run was taken.
+func (s *JobLockStorage) Acquire(ctx context.Context, name, owner string, runAt, now time.Time, lease time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	lock, exists := s.data[name]
+	if exists && (!lock.lastRunAt.Before(runAt) || lock.lockedUntil.After(now)) {
+		return false, nil
+	}
+	s.data[name] = jobLock{lastRunAt: runAt, lockedBy: owner, lockedUntil: now.Add(lease)}
+	return true, nil
+}
+
+// Extend moves the expiry of the lock of the job held by owner to until.
+func (s *JobLockStorage) Extend(ctx context.Context, name, owner string, until time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	lock, exists := s.data[name]
+	if !exists || lock.lockedBy != owner {
+		return false, nil
+	}
+	lock.lockedUntil = until
+	s.data[name] = lock
+	return true, nil
+}
+
+// Release releases the lock of the job held by owner.
+func (s *JobLockStorage) Release(ctx context.Context, name, owner string, now time.Time) error {
+	_, err := s.Extend(ctx, name, owner, now)
+	return err
+}
@@ -0,0 +1,15 @@
+package inmemory_test
+
+import (
+	"testing"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/repository/inmemory"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+)
+
+func TestJobLockStorage_Conformance(t *testing.T) {
+	repotest.RunJobLockRepositorySuite(t, func(t *testing.T) interfaces.IJobLockRepository {
+		return inmemory.NewJobLockStorage()
+	})
+}
@@ -0,0 +1,237 @@
+package inmemory
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// Policies deciding when journal writes are flushed to disk with fsync.
+const (
+	SyncAlways   = "always"   // Every entry is synced before the change is applied.
+	SyncInterval = "interval" // Entries are synced periodically; a crash of the machine may lose the last interval.
+	SyncNever    = "never"    // Syncing is left to the operating system.
+)
+
+// Operations recorded in the journal.
+const (
+	opInsert   = "insert"
+	opDelete   = "delete"
+	opUpdate   = "update"
+	opReassign = "reassign"
+	opDisable  = "disable"
+	opClick    = "click"
+	opPurge    = "purge"
+)
+
+// journalEntry is a single change recorded in the journal.
+type journalEntry struct {
+	Op          string       `json:"op"`
+	URLs        []journalURL `json:"urls,omitempty"`
+	UserID      string       `json:"user_id,omitempty"`
+	ShortID     string       `json:"short_id,omitempty"`
+	ShortIDs    []string     `json:"short_ids,omitempty"`
+	OriginalURL string       `json:"original_url,omitempty"`
+	ToUserID    string       `json:"to_user_id,omitempty"`
+	Disabled    bool         `json:"disabled,omitempty"`
+}
+
+// journalURL is a URL stored in an insert entry of the journal.
+type journalURL struct {
+	ID           int        `json:"id,omitempty"`
+	ShortID      string     `json:"short_id"`
+	OriginalURL  string     `json:"original_url"`
+	UserID       string     `json:"user_id,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DeletedFlag  bool       `json:"is_deleted,omitempty"`
+	DisabledFlag bool       `json:"is_disabled,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	MaxClicks    *int       `json:"max_clicks,omitempty"`
+	Clicks       int        `json:"clicks,omitempty"`
+	PasswordHash string     `json:"password_hash,omitempty"`
+}
+
+// Journal is an append-only write-ahead log of the changes made to a MemoryStorage.
+// Each entry is a JSON line; the journal is replayed on top of the last snapshot on
+// startup and truncated once a fresh snapshot has been saved.
+type Journal struct {
+	mu     sync.Mutex
+	file   *os.File
+	policy string
+	dirty  bool          // Whether entries were written since the last sync.
+	done   chan struct{} // Closed when the journal is closed, stopping the sync loop.
+}
+
+// OpenJournal opens the journal at path, creating it if needed. With the interval policy
+// the journal is synced every interval until ctx is done or the journal is closed.
+func OpenJournal(ctx context.Context, path, policy string, interval time.Duration) (*Journal, error) {
+	switch policy {
+	case SyncAlways, SyncNever:
+	case SyncInterval:
+		if interval <= 0 {
+			return nil, fmt.Errorf("invalid journal sync interval: %v", interval)
+		}
+	default:
+		return nil, fmt.Errorf("unknown journal sync policy: %s", policy)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	j := &Journal{file: file, policy: policy, done: make(chan struct{})}
+	if policy == SyncInterval {
+		go j.syncEvery(ctx, interval)
+	}
+	return j, nil
+}
+
+// syncEvery syncs pending entries every interval until ctx is done or the journal is closed.
+func (j *Journal) syncEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			_ = j.Sync()
+			return
+		case <-j.done:
+			return
+		case <-ticker.C:
+			_ = j.Sync()
+		}
+	}
+}
+
+// write appends the entry at the end of the journal, syncing it with the always policy.
+func (j *Journal) write(entry journalEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	if j.policy != SyncAlways {
+		j.dirty = true
+		return nil
+	}
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal: %w", err)
+	}
+	return nil
+}
+
+// replay calls apply for every entry of the journal in order and returns the number of entries.
+// A partially written last entry, left by a crash during write, is discarded.
+func (j *Journal) replay(apply func(journalEntry)) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	reader := bufio.NewReader(j.file)
+	var offset int64
+	count := 0
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				return count, j.file.Truncate(offset)
+			}
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+		var entry journalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return count, fmt.Errorf("journal entry %d: %w", count+1, err)
+		}
+		apply(entry)
+		offset += int64(len(line))
+		count++
+	}
+}
+
+// Truncate removes all entries from the journal, once they are covered by a snapshot.
+func (j *Journal) Truncate() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.file.Truncate(0); err != nil {
+		return err
+	}
+	j.dirty = false
+	return j.file.Sync()
+}
+
+// Sync flushes the entries written since the last sync to disk.
+func (j *Journal) Sync() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !j.dirty {
+		return nil
+	}
+	j.dirty = false
+	return j.file.Sync()
+}
+
+// Close syncs and closes the journal.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	select {
+	case <-j.done:
+		return nil
+	default:
+		close(j.done)
+	}
+	if err := j.file.Sync(); err != nil {
+		j.file.Close()
+		return err
+	}
+	return j.file.Close()
+}
+
+// toJournalURL converts a URL into its journal form.
+func toJournalURL(url *model.URL) journalURL {
+	return journalURL{
+		ID:           url.ID,
+		ShortID:      url.ShortID,
+		OriginalURL:  url.OriginalURL,
+		UserID:       url.UserID,
+		CreatedAt:    url.CreatedAt,
+		DeletedFlag:  url.DeletedFlag,
+		DisabledFlag: url.DisabledFlag,
+		ExpiresAt:    url.ExpiresAt,
+		MaxClicks:    url.MaxClicks,
+		Clicks:       url.Clicks,
+		PasswordHash: url.PasswordHash,
+	}
+}
+
+// toURL converts a journal URL back into a URL.
+func (u journalURL) toURL() model.URL {
+	return model.URL{
+		ID:           u.ID,
+		ShortID:      u.ShortID,
+		OriginalURL:  u.OriginalURL,
+		UserID:       u.UserID,
+		CreatedAt:    u.CreatedAt,
+		DeletedFlag:  u.DeletedFlag,
+		DisabledFlag: u.DisabledFlag,
+		ExpiresAt:    u.ExpiresAt,
+		MaxClicks:    u.MaxClicks,
+		Clicks:       u.Clicks,
+		PasswordHash: u.PasswordHash,
+	}
+}
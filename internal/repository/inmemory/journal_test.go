@@ -0,0 +1,211 @@
+package inmemory_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/repository/inmemory"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openJournaledStorage(t *testing.T, path, policy string) interfaces.IJournaledRepository {
+	journal, err := inmemory.OpenJournal(context.Background(), path, policy, 10*time.Millisecond)
+	require.NoError(t, err)
+	storage := inmemory.NewJournaledMemoryStorage(journal).(interfaces.IJournaledRepository)
+	t.Cleanup(func() { _ = storage.Close() })
+	return storage
+}
+
+func listByShortID(t *testing.T, repo interfaces.IJournaledRepository) map[string]model.URL {
+	urls, err := repo.(interfaces.IURLRepository).List(context.Background())
+	require.NoError(t, err)
+	result := make(map[string]model.URL, len(urls))
+	for _, url := range urls {
+		result[url.ShortID] = *url
+	}
+	return result
+}
+
+func TestOpenJournal_InvalidPolicy(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "storage.journal")
+
+	_, err := inmemory.OpenJournal(ctx, path, "sometimes", time.Second)
+	assert.EqualError(t, err, "unknown journal sync policy: sometimes")
+	_, err = inmemory.OpenJournal(ctx, path, inmemory.SyncInterval, 0)
+	assert.EqualError(t, err, "invalid journal sync interval: 0s")
+}
+
+func TestMemoryStorage_JournalReplay(t *testing.T) {
+	for _, policy := range []string{inmemory.SyncAlways, inmemory.SyncInterval, inmemory.SyncNever} {
+		t.Run(policy, func(t *testing.T) {
+			ctx := context.Background()
+			path := filepath.Join(t.TempDir(), "storage.journal")
+			created := time.Date(2024, time.November, 17, 12, 0, 0, 0, time.UTC)
+			maxClicks := 3
+
+			storage := openJournaledStorage(t, path, policy)
+			repo := storage.(interfaces.IURLRepository)
+			_, err := storage.Restore(ctx, []*model.URL{
+				{ShortID: "snap1", OriginalURL: "http://example.com/snapshot", UserID: "user1", CreatedAt: created},
+			})
+			require.NoError(t, err)
+			_, err = repo.Insert(ctx, &model.URL{
+				ShortID: "abc123", OriginalURL: "http://example.com/1", UserID: "user1", CreatedAt: created,
+				MaxClicks: &maxClicks, PasswordHash: "hash",
+			})
+			require.NoError(t, err)
+			_, err = repo.InsertList(ctx, []*model.URL{
+				{ShortID: "def456", OriginalURL: "http://example.com/2", UserID: "user1", CreatedAt: created},
+				{ShortID: "ghi789", OriginalURL: "http://example.com/1", UserID: "user1", CreatedAt: created},
+			})
+			require.NoError(t, err)
+			updated, err := repo.UpdateOriginalURL(ctx, "user1", "snap1", "http://example.com/edited")
+			require.NoError(t, err)
+			assert.True(t, updated)
+			require.NoError(t, repo.DeleteListByUserIDAndShortIDs(ctx, "user1", []string{"def456"}))
+			disabled, err := repo.SetDisabled(ctx, []string{"abc123"}, true)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), disabled)
+			reassigned, err := repo.ReassignUserID(ctx, "user1", "user2")
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), reassigned)
+			want := listByShortID(t, storage)
+			require.NoError(t, storage.Close())
+
+			restored := openJournaledStorage(t, path, policy)
+			replayed, err := restored.Restore(ctx, []*model.URL{
+				{ShortID: "snap1", OriginalURL: "http://example.com/snapshot", UserID: "user1", CreatedAt: created},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, 6, replayed)
+			assert.Equal(t, want, listByShortID(t, restored))
+			assert.Equal(t, "http://example.com/edited", want["snap1"].OriginalURL)
+			assert.Equal(t, "user2", want["abc123"].UserID)
+			assert.True(t, want["abc123"].DisabledFlag)
+			assert.Equal(t, "user1", want["def456"].UserID, "Deleted URLs should not be reassigned")
+			assert.NotContains(t, want, "ghi789", "Duplicate original URLs should not be journaled")
+		})
+	}
+}
+
+func TestMemoryStorage_JournalClicks(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "storage.journal")
+	maxClicks := 2
+
+	storage := openJournaledStorage(t, path, inmemory.SyncAlways)
+	repo := storage.(interfaces.IURLRepository)
+	_, err := repo.InsertList(ctx, []*model.URL{
+		{ShortID: "abc123", OriginalURL: "http://example.com/1", MaxClicks: &maxClicks},
+		{ShortID: "def456", OriginalURL: "http://example.com/2"},
+	})
+	require.NoError(t, err)
+	for _, shortID := range []string{"abc123", "abc123", "def456"} {
+		ok, err := repo.IncrementClicks(ctx, shortID)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+	ok, err := repo.IncrementClicks(ctx, "abc123")
+	require.NoError(t, err)
+	assert.False(t, ok, "Clicks over the budget should not be journaled")
+	purged, err := repo.DeleteExpired(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	// The storage is not closed, as if the process crashed after the last write.
+	restored := openJournaledStorage(t, path, inmemory.SyncAlways)
+	replayed, err := restored.Restore(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 5, replayed)
+	urls := listByShortID(t, restored)
+	assert.Equal(t, 2, urls["abc123"].Clicks)
+	assert.True(t, urls["abc123"].DeletedFlag)
+	assert.Equal(t, 1, urls["def456"].Clicks)
+	assert.False(t, urls["def456"].DeletedFlag)
+}
+
+func TestMemoryStorage_JournalTornTail(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "storage.journal")
+
+	storage := openJournaledStorage(t, path, inmemory.SyncAlways)
+	_, err := storage.(interfaces.IURLRepository).Insert(ctx, &model.URL{ShortID: "abc123", OriginalURL: "http://example.com/1"})
+	require.NoError(t, err)
+	require.NoError(t, storage.Close())
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"op":"insert","urls":[{"short_id":"def4`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	restored := openJournaledStorage(t, path, inmemory.SyncAlways)
+	replayed, err := restored.Restore(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, replayed)
+	_, err = restored.(interfaces.IURLRepository).Insert(ctx, &model.URL{ShortID: "xyz789", OriginalURL: "http://example.com/2"})
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, lines, 2, "The torn entry should be discarded before appending")
+}
+
+func TestMemoryStorage_JournalCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.journal")
+	require.NoError(t, os.WriteFile(path, []byte("not json\n"), 0644))
+
+	storage := openJournaledStorage(t, path, inmemory.SyncAlways)
+	_, err := storage.Restore(context.Background(), nil)
+	assert.ErrorContains(t, err, "journal entry 1")
+}
+
+func TestMemoryStorage_Compact(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "storage.journal")
+
+	storage := openJournaledStorage(t, path, inmemory.SyncAlways)
+	_, err := storage.(interfaces.IURLRepository).Insert(ctx, &model.URL{ShortID: "abc123", OriginalURL: "http://example.com/1"})
+	require.NoError(t, err)
+
+	err = storage.Compact(ctx, func([]*model.URL) error { return errors.New("disk full") })
+	assert.EqualError(t, err, "disk full")
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Size(), "The journal should be kept if the snapshot was not saved")
+
+	var snapshot []*model.URL
+	require.NoError(t, storage.Compact(ctx, func(urls []*model.URL) error {
+		snapshot = urls
+		return nil
+	}))
+	require.Len(t, snapshot, 1)
+	assert.Equal(t, "abc123", snapshot[0].ShortID)
+	info, err = os.Stat(path)
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+
+	restored := openJournaledStorage(t, path, inmemory.SyncAlways)
+	replayed, err := restored.Restore(ctx, snapshot)
+	require.NoError(t, err)
+	assert.Zero(t, replayed)
+	assert.Contains(t, listByShortID(t, restored), "abc123")
+}
+
+func TestMemoryStorage_JournaledConformance(t *testing.T) {
+	repotest.RunURLRepositorySuite(t, func(t *testing.T) interfaces.IURLRepository {
+		storage := openJournaledStorage(t, filepath.Join(t.TempDir(), "storage.journal"), inmemory.SyncNever)
+		return storage.(interfaces.IURLRepository)
+	})
+}
@@ -6,6 +6,9 @@
 // access to it. It supports operations like Insert, InsertList, FindByID, and
 // FindListByUserID, and it can delete a list of URLs based on user ID and short IDs.
 //
+// A MemoryStorage created with NewJournaledMemoryStorage records every change in a
+// Journal before applying it, so the changes made since the last snapshot survive a crash.
+//
 // Example usage:
 //
 //	storage := inmemory.NewMemoryStorage()
@@ -18,18 +21,20 @@ package inmemory
 
 import (
 	"context"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/GlebRadaev/shlink/internal/interfaces"
 	"github.com/GlebRadaev/shlink/internal/model"
-	"github.com/GlebRadaev/shlink/internal/utils"
 )
 
 // MemoryStorage is an in-memory storage implementation of IURLRepository.
 // It uses a map for storage and a mutex for thread-safe access.
 type MemoryStorage struct {
-	data map[string]model.URL // Map of shortID to URL
-	mu   sync.RWMutex         // Read/Write mutex for synchronization
+	data    map[string]model.URL // Map of shortID to URL
+	mu      sync.RWMutex         // Read/Write mutex for synchronization
+	journal *Journal             // Write-ahead journal of the changes, nil if not journaled
 }
 
 // NewMemoryStorage creates a new instance of MemoryStorage that implements
@@ -40,8 +45,18 @@ func NewMemoryStorage() interfaces.IURLRepository {
 	}
 }
 
+// NewJournaledMemoryStorage creates a MemoryStorage that records its changes in the
+// journal. The storage starts empty; Restore loads the snapshot and replays the journal.
+func NewJournaledMemoryStorage(journal *Journal) interfaces.IURLRepository {
+	return &MemoryStorage{
+		data:    make(map[string]model.URL),
+		journal: journal,
+	}
+}
+
 // Insert stores a URL in memory or returns the existing one if the original
-// URL is already in the storage. It generates a new ShortID if needed.
+// URL is already in the storage. If the ShortID is used by another URL it
+// returns interfaces.ErrShortIDTaken.
 func (s *MemoryStorage) Insert(ctx context.Context, url *model.URL) (*model.URL, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -55,34 +70,65 @@ func (s *MemoryStorage) Insert(ctx context.Context, url *model.URL) (*model.URL,
 			return url, nil
 		}
 	}
-	// Ensure unique ShortID
 	if _, exists := s.data[url.ShortID]; exists {
-		url.ShortID = utils.Generate(8)
+		return nil, interfaces.ErrShortIDTaken
+	}
+	if url.CreatedAt.IsZero() {
+		url.CreatedAt = time.Now()
+	}
+	if err := s.record(journalEntry{Op: opInsert, URLs: []journalURL{toJournalURL(url)}}); err != nil {
+		return nil, err
 	}
 	s.data[url.ShortID] = *url
 	return url, nil
 }
 
 // InsertList stores a list of URLs in memory, reusing ShortID for duplicate
-// original URLs and generating new ShortIDs if needed.
+// original URLs. If the ShortID of a new URL is already taken, nothing is
+// stored and interfaces.ErrShortIDTaken is returned.
 func (s *MemoryStorage) InsertList(ctx context.Context, urls []*model.URL) ([]*model.URL, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
+	existing := make(map[string]string, len(s.data))
+	for _, storedURL := range s.data {
+		existing[storedURL.OriginalURL] = storedURL.ShortID
+	}
+	taken := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		if _, exists := existing[url.OriginalURL]; exists {
+			continue
+		}
+		if _, exists := s.data[url.ShortID]; exists || taken[url.ShortID] {
+			return nil, interfaces.ErrShortIDTaken
+		}
+		taken[url.ShortID] = true
+	}
+	var inserted []*model.URL
 	for _, url := range urls {
-		// Check for existing URLs and reuse their ShortID
-		for _, storedURL := range s.data {
-			if storedURL.OriginalURL == url.OriginalURL {
-				url.ShortID = storedURL.ShortID
-				break
-			}
+		// Reuse the ShortID of an already shortened URL
+		if shortID, exists := existing[url.OriginalURL]; exists {
+			url.ShortID = shortID
+			continue
 		}
-		// Ensure unique ShortID
-		if _, exists := s.data[url.ShortID]; exists {
-			url.ShortID = utils.Generate(8)
+		if url.CreatedAt.IsZero() {
+			url.CreatedAt = time.Now()
 		}
+		inserted = append(inserted, url)
+		existing[url.OriginalURL] = url.ShortID
+	}
+	if len(inserted) > 0 {
+		entry := journalEntry{Op: opInsert}
+		for _, url := range inserted {
+			entry.URLs = append(entry.URLs, toJournalURL(url))
+		}
+		if err := s.record(entry); err != nil {
+			return nil, err
+		}
+	}
+	for _, url := range inserted {
 		s.data[url.ShortID] = *url
 	}
 	return urls, nil
@@ -103,9 +149,9 @@ func (s *MemoryStorage) FindByID(ctx context.Context, shortID string) (*model.UR
 	return &url, nil
 }
 
-// FindListByUserID retrieves a list of URLs associated with a specific user
-// by their UserID.
-func (s *MemoryStorage) FindListByUserID(ctx context.Context, userID string) ([]*model.URL, error) {
+// FindListByUserID retrieves the URLs of a specific user that match the
+// query, sorted and paginated the same way as the database repository.
+func (s *MemoryStorage) FindListByUserID(ctx context.Context, userID string, query model.URLListQuery) ([]*model.URL, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	if err := ctx.Err(); err != nil {
@@ -114,11 +160,17 @@ func (s *MemoryStorage) FindListByUserID(ctx context.Context, userID string) ([]
 
 	var result []*model.URL
 	for _, storedURL := range s.data {
-		if storedURL.UserID == userID {
+		if storedURL.UserID == userID && query.Matches(&storedURL) && query.IsAfterCursor(&storedURL) {
 			urlCopy := storedURL
 			result = append(result, &urlCopy)
 		}
 	}
+	sort.Slice(result, func(i, j int) bool {
+		return query.Less(result[i], result[j])
+	})
+	if query.Limit > 0 && len(result) > query.Limit {
+		result = result[:query.Limit]
+	}
 	return result, nil
 }
 
@@ -133,18 +185,127 @@ func (s *MemoryStorage) List(ctx context.Context) ([]*model.URL, error) {
 
 	var result []*model.URL
 	for _, storedURL := range s.data {
-		urlCopy := model.URL{
-			ID:          storedURL.ID,
-			ShortID:     storedURL.ShortID,
-			OriginalURL: storedURL.OriginalURL,
-			UserID:      storedURL.UserID,
-			CreatedAt:   storedURL.CreatedAt,
-		}
+		urlCopy := storedURL
 		result = append(result, &urlCopy)
 	}
 	return result, nil
 }
 
+// UpdateOriginalURL changes the original URL of a non-deleted URL owned by
+// the user. It returns false if no such URL exists and interfaces.ErrOriginalURLTaken
+// if the new original URL is already stored under another ShortID.
+func (s *MemoryStorage) UpdateOriginalURL(ctx context.Context, userID, shortID, originalURL string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	url, exists := s.data[shortID]
+	if !exists || url.UserID != userID || url.DeletedFlag {
+		return false, nil
+	}
+	for _, storedURL := range s.data {
+		if storedURL.OriginalURL == originalURL && storedURL.ShortID != shortID {
+			return false, interfaces.ErrOriginalURLTaken
+		}
+	}
+	if err := s.record(journalEntry{Op: opUpdate, UserID: userID, ShortID: shortID, OriginalURL: originalURL}); err != nil {
+		return false, err
+	}
+	s.updateOriginalURL(shortID, originalURL)
+	return true, nil
+}
+
+// ReassignUserID moves the non-deleted URLs of a user to another user and
+// returns how many were moved.
+func (s *MemoryStorage) ReassignUserID(ctx context.Context, fromUserID, toUserID string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := s.record(journalEntry{Op: opReassign, UserID: fromUserID, ToUserID: toUserID}); err != nil {
+		return 0, err
+	}
+	return s.reassign(fromUserID, toUserID), nil
+}
+
+// SetDisabled marks the URLs with the ShortIDs as disabled or enabled again and
+// returns how many changed.
+func (s *MemoryStorage) SetDisabled(ctx context.Context, shortIDs []string, disabled bool) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := s.record(journalEntry{Op: opDisable, ShortIDs: shortIDs, Disabled: disabled}); err != nil {
+		return 0, err
+	}
+	return s.setDisabled(shortIDs, disabled), nil
+}
+
+// FindListByDomain retrieves the non-deleted URLs whose original URL points to
+// the domain or one of its subdomains.
+func (s *MemoryStorage) FindListByDomain(ctx context.Context, domain string) ([]*model.URL, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var result []*model.URL
+	for _, storedURL := range s.data {
+		if !storedURL.DeletedFlag && storedURL.InDomain(domain) {
+			urlCopy := storedURL
+			result = append(result, &urlCopy)
+		}
+	}
+	return result, nil
+}
+
+// IncrementClicks increases the click counter of a URL unless its click budget
+// is used up. It returns false if no clicks are left for the URL.
+func (s *MemoryStorage) IncrementClicks(ctx context.Context, shortID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	url, exists := s.data[shortID]
+	if !exists || (url.MaxClicks != nil && url.Clicks >= *url.MaxClicks) {
+		return false, nil
+	}
+	if err := s.record(journalEntry{Op: opClick, ShortID: shortID}); err != nil {
+		return false, err
+	}
+	s.incrementClicks(shortID)
+	return true, nil
+}
+
+// DeleteExpired marks as deleted all URLs that expired by date or click
+// budget at the given moment and returns how many were marked.
+func (s *MemoryStorage) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	var expired []string
+	for shortID, url := range s.data {
+		if !url.DeletedFlag && url.IsExpired(now) {
+			expired = append(expired, shortID)
+		}
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+	if err := s.record(journalEntry{Op: opPurge, ShortIDs: expired}); err != nil {
+		return 0, err
+	}
+	s.purge(expired)
+	return int64(len(expired)), nil
+}
+
 // Ping checks if the storage is accessible. This can be used to verify
 // the health of the storage.
 func (s *MemoryStorage) Ping(ctx context.Context) error {
@@ -156,18 +317,163 @@ func (s *MemoryStorage) Ping(ctx context.Context) error {
 	return nil
 }
 
-// DeleteListByUserIDAndShortIDs deletes a list of URLs from memory by
-// UserID and their associated ShortIDs. It updates the URLs' state in memory.
+// DeleteListByUserIDAndShortIDs soft deletes a list of URLs by UserID and
+// their associated ShortIDs, marking them as deleted. URLs of other users are kept.
 func (s *MemoryStorage) DeleteListByUserIDAndShortIDs(ctx context.Context, userID string, shortIDs []string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if err := ctx.Err(); err != nil {
 		return err
 	}
+	if err := s.record(journalEntry{Op: opDelete, UserID: userID, ShortIDs: shortIDs}); err != nil {
+		return err
+	}
+	s.deleteList(userID, shortIDs)
+	return nil
+}
+
+// updateOriginalURL changes the original URL stored under the ShortID.
+func (s *MemoryStorage) updateOriginalURL(shortID, originalURL string) {
+	if url, exists := s.data[shortID]; exists {
+		url.OriginalURL = originalURL
+		s.data[shortID] = url
+	}
+}
+
+// deleteList marks as deleted the URLs of the user with the given ShortIDs.
+func (s *MemoryStorage) deleteList(userID string, shortIDs []string) {
 	for _, shortID := range shortIDs {
 		if url, exists := s.data[shortID]; exists && url.UserID == userID {
+			url.DeletedFlag = true
 			s.data[shortID] = url
 		}
 	}
-	return nil
+}
+
+// incrementClicks increases the click counter of the URL with the ShortID.
+func (s *MemoryStorage) incrementClicks(shortID string) {
+	if url, exists := s.data[shortID]; exists {
+		url.Clicks++
+		s.data[shortID] = url
+	}
+}
+
+// purge marks as deleted the URLs with the ShortIDs, whoever owns them.
+func (s *MemoryStorage) purge(shortIDs []string) {
+	for _, shortID := range shortIDs {
+		if url, exists := s.data[shortID]; exists {
+			url.DeletedFlag = true
+			s.data[shortID] = url
+		}
+	}
+}
+
+// reassign moves the non-deleted URLs of a user to another user.
+func (s *MemoryStorage) reassign(fromUserID, toUserID string) int64 {
+	var count int64
+	for shortID, url := range s.data {
+		if url.UserID == fromUserID && !url.DeletedFlag {
+			url.UserID = toUserID
+			s.data[shortID] = url
+			count++
+		}
+	}
+	return count
+}
+
+// setDisabled sets the disabled flag of the URLs with the ShortIDs and returns how many changed.
+func (s *MemoryStorage) setDisabled(shortIDs []string, disabled bool) int64 {
+	var count int64
+	for _, shortID := range shortIDs {
+		if url, exists := s.data[shortID]; exists && url.DisabledFlag != disabled {
+			url.DisabledFlag = disabled
+			s.data[shortID] = url
+			count++
+		}
+	}
+	return count
+}
+
+// record writes the change to the journal, if the storage has one. It must be
+// called with the write lock held, before the change is applied.
+func (s *MemoryStorage) record(entry journalEntry) error {
+	if s.journal == nil {
+		return nil
+	}
+	return s.journal.write(entry)
+}
+
+// apply applies a change read from the journal.
+func (s *MemoryStorage) apply(entry journalEntry) {
+	switch entry.Op {
+	case opInsert:
+		for _, url := range entry.URLs {
+			s.data[url.ShortID] = url.toURL()
+		}
+	case opUpdate:
+		s.updateOriginalURL(entry.ShortID, entry.OriginalURL)
+	case opDelete:
+		s.deleteList(entry.UserID, entry.ShortIDs)
+	case opReassign:
+		s.reassign(entry.UserID, entry.ToUserID)
+	case opDisable:
+		s.setDisabled(entry.ShortIDs, entry.Disabled)
+	case opClick:
+		s.incrementClicks(entry.ShortID)
+	case opPurge:
+		s.purge(entry.ShortIDs)
+	}
+}
+
+// Restore replaces the content of the storage with the snapshot and replays the
+// journal on top of it. It returns the number of replayed journal entries.
+func (s *MemoryStorage) Restore(ctx context.Context, snapshot []*model.URL) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.data = make(map[string]model.URL, len(snapshot))
+	for _, url := range snapshot {
+		if url.CreatedAt.IsZero() {
+			url.CreatedAt = time.Now()
+		}
+		s.data[url.ShortID] = *url
+	}
+	if s.journal == nil {
+		return 0, nil
+	}
+	return s.journal.replay(s.apply)
+}
+
+// Compact passes the content of the storage to save as a fresh snapshot and,
+// once it is saved, truncates the journal. Changes wait until it completes.
+func (s *MemoryStorage) Compact(ctx context.Context, save func([]*model.URL) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	snapshot := make([]*model.URL, 0, len(s.data))
+	for _, storedURL := range s.data {
+		urlCopy := storedURL
+		snapshot = append(snapshot, &urlCopy)
+	}
+	if err := save(snapshot); err != nil {
+		return err
+	}
+	if s.journal == nil {
+		return nil
+	}
+	return s.journal.Truncate()
+}
+
+// Close closes the journal of the storage, if it has one.
+func (s *MemoryStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.journal == nil {
+		return nil
+	}
+	return s.journal.Close()
 }
@@ -5,8 +5,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/GlebRadaev/shlink/internal/interfaces"
 	"github.com/GlebRadaev/shlink/internal/model"
 	"github.com/GlebRadaev/shlink/internal/repository/inmemory"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -44,6 +46,16 @@ func TestMemoryStorage_Insert(t *testing.T) {
 			expectedUserID:    "user2",
 			expectedCreatedAt: time.Now(),
 		},
+		{
+			name:              "shortID taken by another URL",
+			shortID:           "abc123",
+			originalURL:       "http://another.com",
+			userID:            "user2",
+			expectedError:     interfaces.ErrShortIDTaken,
+			expectedShort:     "",
+			expectedUserID:    "",
+			expectedCreatedAt: time.Time{},
+		},
 		{
 			name:              "context cancelled",
 			shortID:           "abc123",
@@ -179,6 +191,36 @@ func TestMemoryStorage_InsertList(t *testing.T) {
 	}
 }
 
+func TestMemoryStorage_InsertList_ShortIDTaken(t *testing.T) {
+	storage := inmemory.NewMemoryStorage()
+	ctx := context.Background()
+
+	_, err := storage.Insert(ctx, &model.URL{ShortID: "abc123", OriginalURL: "http://example.com"})
+	assert.NoError(t, err)
+
+	_, err = storage.InsertList(ctx, []*model.URL{
+		{ShortID: "new123", OriginalURL: "http://new.com"},
+		{ShortID: "abc123", OriginalURL: "http://another.com"},
+	})
+	assert.ErrorIs(t, err, interfaces.ErrShortIDTaken)
+
+	url, err := storage.FindByID(ctx, "new123")
+	assert.NoError(t, err)
+	assert.Nil(t, url, "nothing is stored when a ShortID is taken")
+
+	urls, err := storage.InsertList(ctx, []*model.URL{
+		{ShortID: "dup123", OriginalURL: "http://example.com"},
+		{ShortID: "twin01", OriginalURL: "http://twin.com"},
+		{ShortID: "twin02", OriginalURL: "http://twin.com"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", urls[0].ShortID, "already shortened URL keeps its ShortID")
+	assert.Equal(t, "twin01", urls[2].ShortID, "duplicates within the batch share a ShortID")
+	url, err = storage.FindByID(ctx, "twin02")
+	assert.NoError(t, err)
+	assert.Nil(t, url)
+}
+
 func TestMemoryStorage_FindById(t *testing.T) {
 	storage := inmemory.NewMemoryStorage()
 	ctx := context.Background()
@@ -336,7 +378,7 @@ func TestMemoryStorage_FindListByUserID(t *testing.T) {
 				}
 			}
 
-			foundURLs, err := storage.FindListByUserID(ctx, tt.userID)
+			foundURLs, err := storage.FindListByUserID(ctx, tt.userID, model.URLListQuery{})
 			if tt.wantErr != nil {
 				assert.ErrorIs(t, err, tt.wantErr)
 			} else {
@@ -351,10 +393,69 @@ func TestMemoryStorage_FindListByUserID(t *testing.T) {
 	}
 }
 
+func TestMemoryStorage_FindListByUserID_Query(t *testing.T) {
+	storage := inmemory.NewMemoryStorage()
+	ctx := context.Background()
+	day := time.Date(2024, time.November, 1, 0, 0, 0, 0, time.UTC)
+	for _, url := range []model.URL{
+		{ShortID: "a1", OriginalURL: "http://b.example.com", UserID: "user123", CreatedAt: day},
+		{ShortID: "a2", OriginalURL: "http://a.example.com", UserID: "user123", CreatedAt: day.AddDate(0, 0, 1)},
+		{ShortID: "a3", OriginalURL: "http://c.other.com", UserID: "user123", CreatedAt: day.AddDate(0, 0, 2)},
+		{ShortID: "a4", OriginalURL: "http://d.example.com", UserID: "user123", CreatedAt: day.AddDate(0, 0, 2)},
+		{ShortID: "b1", OriginalURL: "http://e.example.com", UserID: "user456", CreatedAt: day},
+	} {
+		_, err := storage.Insert(ctx, &url)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, storage.DeleteListByUserIDAndShortIDs(ctx, "user123", []string{"a2"}))
+	from, to := day.AddDate(0, 0, 1), day.AddDate(0, 0, 2)
+	active, deleted := false, true
+
+	tests := []struct {
+		name  string
+		query model.URLListQuery
+		want  []string
+	}{
+		{name: "Default order", query: model.URLListQuery{}, want: []string{"a4", "a3", "a2", "a1"}},
+		{name: "Created at ascending", query: model.URLListQuery{Sort: model.SortCreatedAtAsc}, want: []string{"a1", "a2", "a3", "a4"}},
+		{name: "Original URL ascending", query: model.URLListQuery{Sort: model.SortOriginalURLAsc}, want: []string{"a2", "a1", "a3", "a4"}},
+		{name: "Original URL descending", query: model.URLListQuery{Sort: model.SortOriginalURLDesc}, want: []string{"a4", "a3", "a1", "a2"}},
+		{name: "Created range", query: model.URLListQuery{CreatedFrom: &from, CreatedTo: &to}, want: []string{"a2"}},
+		{name: "Active only", query: model.URLListQuery{Deleted: &active}, want: []string{"a4", "a3", "a1"}},
+		{name: "Deleted only", query: model.URLListQuery{Deleted: &deleted}, want: []string{"a2"}},
+		{name: "Search ignores case", query: model.URLListQuery{Search: "EXAMPLE"}, want: []string{"a4", "a2", "a1"}},
+		{name: "Limit", query: model.URLListQuery{Limit: 2}, want: []string{"a4", "a3"}},
+		{
+			name:  "After cursor with tie on created at",
+			query: model.URLListQuery{After: &model.URLCursor{CreatedAt: day.AddDate(0, 0, 2), ShortID: "a4"}, Limit: 2},
+			want:  []string{"a3", "a2"},
+		},
+		{
+			name:  "After cursor by original URL",
+			query: model.URLListQuery{Sort: model.SortOriginalURLAsc, After: &model.URLCursor{OriginalURL: "http://b.example.com", ShortID: "a1"}},
+			want:  []string{"a3", "a4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls, err := storage.FindListByUserID(ctx, "user123", tt.query)
+			assert.NoError(t, err)
+			got := make([]string, 0, len(urls))
+			for _, url := range urls {
+				got = append(got, url.ShortID)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func TestMemoryStorage_List(t *testing.T) {
 	storage := inmemory.NewMemoryStorage()
 	ctx := context.Background()
 
+	createdAt := time.Date(2024, time.November, 1, 0, 0, 0, 0, time.UTC)
+
 	tests := []struct {
 		name          string
 		shortID       string
@@ -371,13 +472,13 @@ func TestMemoryStorage_List(t *testing.T) {
 					ShortID:     "abc123",
 					OriginalURL: "http://example.com",
 					UserID:      "user1",
-					CreatedAt:   time.Time{},
+					CreatedAt:   createdAt,
 				},
 				{
 					ShortID:     "xyz789",
 					OriginalURL: "http://another-example.com",
 					UserID:      "user2",
-					CreatedAt:   time.Time{},
+					CreatedAt:   createdAt,
 				},
 			},
 			expectedError: nil,
@@ -391,10 +492,10 @@ func TestMemoryStorage_List(t *testing.T) {
 		},
 	}
 
-	if _, err := storage.Insert(ctx, &model.URL{ShortID: "abc123", OriginalURL: "http://example.com", UserID: "user1"}); err != nil {
+	if _, err := storage.Insert(ctx, &model.URL{ShortID: "abc123", OriginalURL: "http://example.com", UserID: "user1", CreatedAt: createdAt}); err != nil {
 		t.Errorf("Insert() returned an error: %v", err)
 	}
-	if _, err := storage.Insert(ctx, &model.URL{ShortID: "xyz789", OriginalURL: "http://another-example.com", UserID: "user2"}); err != nil {
+	if _, err := storage.Insert(ctx, &model.URL{ShortID: "xyz789", OriginalURL: "http://another-example.com", UserID: "user2", CreatedAt: createdAt}); err != nil {
 		t.Errorf("Insert() returned an error: %v", err)
 	}
 
@@ -416,6 +517,125 @@ func TestMemoryStorage_List(t *testing.T) {
 	}
 }
 
+func TestMemoryStorage_UpdateOriginalURL(t *testing.T) {
+	storage := inmemory.NewMemoryStorage()
+	ctx := context.Background()
+
+	seed := []*model.URL{
+		{ShortID: "owned", OriginalURL: "http://owned.com", UserID: "user1"},
+		{ShortID: "other", OriginalURL: "http://other.com", UserID: "user1"},
+		{ShortID: "deleted", OriginalURL: "http://deleted.com", UserID: "user1", DeletedFlag: true},
+	}
+	for _, url := range seed {
+		if _, err := storage.Insert(ctx, url); err != nil {
+			t.Fatalf("Insert() returned an error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		userID        string
+		shortID       string
+		newURL        string
+		expectedOK    bool
+		expectedError error
+	}{
+		{name: "destination updated", userID: "user1", shortID: "owned", newURL: "http://new.com", expectedOK: true},
+		{name: "destination taken by another link", userID: "user1", shortID: "owned", newURL: "http://other.com", expectedError: interfaces.ErrOriginalURLTaken},
+		{name: "link of another user", userID: "user2", shortID: "owned", newURL: "http://foreign.com", expectedOK: false},
+		{name: "deleted link", userID: "user1", shortID: "deleted", newURL: "http://revived.com", expectedOK: false},
+		{name: "unknown shortID", userID: "user1", shortID: "unknown", newURL: "http://unknown.com", expectedOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := storage.UpdateOriginalURL(ctx, tt.userID, tt.shortID, tt.newURL)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedOK, ok)
+		})
+	}
+
+	url, err := storage.FindByID(ctx, "owned")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://new.com", url.OriginalURL)
+}
+
+func TestMemoryStorage_IncrementClicks(t *testing.T) {
+	storage := inmemory.NewMemoryStorage()
+	ctx := context.Background()
+	maxClicks := 1
+
+	if _, err := storage.Insert(ctx, &model.URL{ShortID: "limited", OriginalURL: "http://limited.com", MaxClicks: &maxClicks}); err != nil {
+		t.Fatalf("Insert() returned an error: %v", err)
+	}
+	if _, err := storage.Insert(ctx, &model.URL{ShortID: "unlimited", OriginalURL: "http://unlimited.com"}); err != nil {
+		t.Fatalf("Insert() returned an error: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		shortID        string
+		expectedOK     bool
+		expectedClicks int
+	}{
+		{name: "first click within budget", shortID: "limited", expectedOK: true, expectedClicks: 1},
+		{name: "click budget used up", shortID: "limited", expectedOK: false, expectedClicks: 1},
+		{name: "no click budget", shortID: "unlimited", expectedOK: true, expectedClicks: 1},
+		{name: "unknown shortID", shortID: "unknown", expectedOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := storage.IncrementClicks(ctx, tt.shortID)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedOK, ok)
+			if url, _ := storage.FindByID(ctx, tt.shortID); url != nil {
+				assert.Equal(t, tt.expectedClicks, url.Clicks)
+			}
+		})
+	}
+}
+
+func TestMemoryStorage_DeleteExpired(t *testing.T) {
+	storage := inmemory.NewMemoryStorage()
+	ctx := context.Background()
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+	maxClicks := 1
+
+	urls := []*model.URL{
+		{ShortID: "expired", OriginalURL: "http://expired.com", ExpiresAt: &past},
+		{ShortID: "active", OriginalURL: "http://active.com", ExpiresAt: &future},
+		{ShortID: "usedup", OriginalURL: "http://usedup.com", MaxClicks: &maxClicks, Clicks: 1},
+		{ShortID: "plain", OriginalURL: "http://plain.com"},
+	}
+	for _, url := range urls {
+		if _, err := storage.Insert(ctx, url); err != nil {
+			t.Fatalf("Insert() returned an error: %v", err)
+		}
+	}
+
+	count, err := storage.DeleteExpired(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	expectedDeleted := map[string]bool{"expired": true, "active": false, "usedup": true, "plain": false}
+	for shortID, deleted := range expectedDeleted {
+		url, err := storage.FindByID(ctx, shortID)
+		assert.NoError(t, err)
+		assert.Equal(t, deleted, url.DeletedFlag, "unexpected deleted flag for %s", shortID)
+	}
+
+	count, err = storage.DeleteExpired(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count, "already deleted URLs should not be counted again")
+}
+
 func TestMemoryStorage_Ping(t *testing.T) {
 	storage := inmemory.NewMemoryStorage()
 	ctx := context.Background()
@@ -448,3 +668,9 @@ func TestMemoryStorage_Ping(t *testing.T) {
 		})
 	}
 }
+
+func TestMemoryStorage_Conformance(t *testing.T) {
+	repotest.RunURLRepositorySuite(t, func(t *testing.T) interfaces.IURLRepository {
+		return inmemory.NewMemoryStorage()
+	})
+}
@@ -0,0 +1,319 @@
+package inmemory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// TaskStorage is an in-memory storage implementation of ITaskRepository.
+// If it is backed by a file, every change rewrites the file, so that the queue and its dead
+// letters survive a restart of the process.
+type TaskStorage struct {
+	tasks  map[int64]model.QueuedTask // Queued tasks by ID
+	dead   []model.DeadTask           // Dead letters ordered by ID
+	nextID int64                      // ID of the last task or dead letter
+	path   string                     // File the storage is saved to, empty if it is not persisted
+	mu     sync.Mutex                 // Mutex for synchronization
+}
+
+// taskFile is the content of the file of a TaskStorage.
+type taskFile struct {
+	NextID int64              `json:"next_id"`
+	Tasks  []model.QueuedTask `json:"tasks"`
+	Dead   []model.DeadTask   `json:"dead"`
+}
+
+// NewTaskStorage creates a new instance of TaskStorage that implements
+// the ITaskRepository interface, keeping the tasks in memory only.
+func NewTaskStorage() interfaces.ITaskRepository {
+	return &TaskStorage{tasks: make(map[int64]model.QueuedTask)}
+}
+
+// OpenTaskStorage creates a TaskStorage saved to the file at path, loading the tasks left in
+// it by the previous process. Tasks that were claimed when the process stopped are claimed
+// again once their lease expires.
+func OpenTaskStorage(path string) (interfaces.ITaskRepository, error) {
+	s := &TaskStorage{tasks: make(map[int64]model.QueuedTask), path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, s.save()
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file taskFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode task file: %w", err)
+	}
+	s.nextID = file.NextID
+	for _, task := range file.Tasks {
+		s.tasks[task.ID] = task
+	}
+	s.dead = file.Dead
+	return s, nil
+}
+
+// Push adds a copy of the task, numbering it after the last task or dead letter, as insert does.
+func (s *TaskStorage) Push(ctx context.Context, task *model.QueuedTask) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.insert(task)
+	if err := s.save(); err != nil {
+		delete(s.tasks, task.ID)
+		return err
+	}
+	return nil
+}
+
+// insert adds a copy of the task and sets its ID and fair time. The fair time of a task with a fair
+// key is moved forward by as much as the latest fair time of the queued tasks of the key is after
+// its run time. The caller must hold mu.
+func (s *TaskStorage) insert(task *model.QueuedTask) {
+	if task.FairKey != "" {
+		task.FairAt = task.FairAt.Add(max(0, s.lastFairAt(task.FairKey).Sub(task.RunAt)))
+	}
+	s.nextID++
+	task.ID = s.nextID
+	s.tasks[task.ID] = *task
+}
+
+// lastFairAt returns the latest fair time of the queued tasks of the fair key, the zero time if it
+// has none. The caller must hold mu.
+func (s *TaskStorage) lastFairAt(key string) time.Time {
+	var last time.Time
+	for _, queued := range s.tasks {
+		if queued.FairKey == key && queued.FairAt.After(last) {
+			last = queued.FairAt
+		}
+	}
+	return last
+}
+
+// Claim takes the due task at now of the highest priority and the earliest fair time that is not of
+// one of skipTypes nor of one of skipKeys, counts the attempt and moves its run time past the lease.
+func (s *TaskStorage) Claim(ctx context.Context, now time.Time, lease time.Duration, skipTypes, skipKeys []string) (*model.QueuedTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var claimed *model.QueuedTask
+	for _, task := range s.tasks {
+		if task.RunAt.After(now) || slices.Contains(skipTypes, task.Type) || slices.Contains(skipKeys, task.FairKey) {
+			continue
+		}
+		if claimed == nil || claimsBefore(task, *claimed) {
+			task := task
+			claimed = &task
+		}
+	}
+	if claimed == nil {
+		return nil, nil
+	}
+	claimed.Attempts++
+	claimed.RunAt = now.Add(lease)
+	s.tasks[claimed.ID] = *claimed
+	return claimed, s.save()
+}
+
+// claimsBefore reports whether the task a is claimed before b: a task of a higher priority goes
+// first, then the one with the earliest fair time.
+func claimsBefore(a, b model.QueuedTask) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	if !a.FairAt.Equal(b.FairAt) {
+		return a.FairAt.Before(b.FairAt)
+	}
+	return a.ID < b.ID
+}
+
+// Extend sets the run time of a claimed task to until.
+func (s *TaskStorage) Extend(ctx context.Context, id int64, until time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	task, ok := s.tasks[id]
+	if !ok {
+		return false, nil
+	}
+	task.RunAt = until
+	s.tasks[id] = task
+	return true, s.save()
+}
+
+// Count returns the number of queued tasks.
+func (s *TaskStorage) Count(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return len(s.tasks), nil
+}
+
+// Complete removes a claimed task.
+func (s *TaskStorage) Complete(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	delete(s.tasks, id)
+	return s.save()
+}
+
+// Retry sets the run time of a claimed task to runAt and records the error of the attempt.
+func (s *TaskStorage) Retry(ctx context.Context, id int64, runAt time.Time, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil
+	}
+	task.RunAt = runAt
+	task.LastError = lastError
+	s.tasks[id] = task
+	return s.save()
+}
+
+// Release sets the run time of a claimed task to runAt and takes back the attempt counted by its claim.
+func (s *TaskStorage) Release(ctx context.Context, id int64, runAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil
+	}
+	task.RunAt = runAt
+	if task.Attempts > 0 {
+		task.Attempts--
+	}
+	s.tasks[id] = task
+	return s.save()
+}
+
+// Bury moves a claimed task to the dead letters.
+func (s *TaskStorage) Bury(ctx context.Context, id int64, lastError string, failedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil
+	}
+	delete(s.tasks, id)
+	s.nextID++
+	s.dead = append(s.dead, model.DeadTask{
+		ID:        s.nextID,
+		Type:      task.Type,
+		Payload:   task.Payload,
+		Attempts:  task.Attempts,
+		LastError: lastError,
+		CreatedAt: task.CreatedAt,
+		FailedAt:  failedAt,
+		Priority:  task.Priority,
+		FairKey:   task.FairKey,
+	})
+	return s.save()
+}
+
+// FindDeadList retrieves the dead letters, newest first.
+func (s *TaskStorage) FindDeadList(ctx context.Context, limit int) ([]*model.DeadTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var result []*model.DeadTask
+	for i := len(s.dead) - 1; i >= 0; i-- {
+		if limit > 0 && len(result) == limit {
+			break
+		}
+		task := s.dead[i]
+		result = append(result, &task)
+	}
+	return result, nil
+}
+
+// Replay moves a dead letter back to the queue with no attempts, keeping its priority and fair key.
+// Its fair time is its run time, moved forward as in Push.
+func (s *TaskStorage) Replay(ctx context.Context, id int64, runAt time.Time) (*model.QueuedTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	i := sort.Search(len(s.dead), func(i int) bool { return s.dead[i].ID >= id })
+	if i == len(s.dead) || s.dead[i].ID != id {
+		return nil, nil
+	}
+	dead := s.dead[i]
+	s.dead = append(s.dead[:i], s.dead[i+1:]...)
+	task := &model.QueuedTask{Type: dead.Type, Payload: dead.Payload, RunAt: runAt, CreatedAt: dead.CreatedAt,
+		Priority: dead.Priority, FairKey: dead.FairKey, FairAt: runAt}
+	s.insert(task)
+	return task, s.save()
+}
+
+// save writes the storage to a temporary file and renames it over the file of the storage, so
+// that a crash leaves either the old or the new content. It does nothing if the storage is not
+// backed by a file.
+func (s *TaskStorage) save() error {
+	if s.path == "" {
+		return nil
+	}
+	file := taskFile{NextID: s.nextID, Tasks: make([]model.QueuedTask, 0, len(s.tasks)), Dead: s.dead}
+	for _, task := range s.tasks {
+		file.Tasks = append(file.Tasks, task)
+	}
+	sort.Slice(file.Tasks, func(i, j int) bool { return file.Tasks[i].ID < file.Tasks[j].ID })
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save task file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save task file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save task file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save task file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to save task file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package inmemory_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/repository/inmemory"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskStorage_Conformance(t *testing.T) {
+	repotest.RunTaskRepositorySuite(t, func(t *testing.T) interfaces.ITaskRepository {
+		return inmemory.NewTaskStorage()
+	})
+}
+
+func TestTaskStorage_FileConformance(t *testing.T) {
+	repotest.RunTaskRepositorySuite(t, func(t *testing.T) interfaces.ITaskRepository {
+		storage, err := inmemory.OpenTaskStorage(filepath.Join(t.TempDir(), "tasks.json"))
+		require.NoError(t, err)
+		return storage
+	})
+}
+
+func TestOpenTaskStorage(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	now := time.Now().UTC()
+	storage, err := inmemory.OpenTaskStorage(path)
+	require.NoError(t, err)
+	for _, taskType := range []string{"task1", "task2"} {
+		require.NoError(t, storage.Push(ctx, &model.QueuedTask{Type: taskType, Payload: json.RawMessage(`{}`), RunAt: now, CreatedAt: now}))
+	}
+	claimed, err := storage.Claim(ctx, now, time.Minute, nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	require.NoError(t, storage.Bury(ctx, claimed.ID, "failed", now))
+
+	reopened, err := inmemory.OpenTaskStorage(path)
+	require.NoError(t, err)
+	dead, err := reopened.FindDeadList(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, dead, 1, "Expected the dead letters to survive a restart")
+	assert.Equal(t, "task1", dead[0].Type)
+	claimed, err = reopened.Claim(ctx, now, time.Minute, nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, claimed, "Expected the queued tasks to survive a restart")
+	assert.Equal(t, "task2", claimed.Type)
+	require.NoError(t, reopened.Push(ctx, &model.QueuedTask{Type: "task3", Payload: json.RawMessage(`{}`), RunAt: now, CreatedAt: now}))
+	replayed, err := reopened.Replay(ctx, dead[0].ID, now)
+	require.NoError(t, err)
+	require.NotNil(t, replayed)
+	assert.Greater(t, replayed.ID, claimed.ID, "Expected IDs to keep increasing after a restart")
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+	_, err = inmemory.OpenTaskStorage(path)
+	assert.Error(t, err, "Expected a corrupt file to be reported")
+}
@@ -0,0 +1,71 @@
+package inmemory
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// TaskStatusStorage is an in-memory storage implementation of ITaskStatusRepository.
+type TaskStatusStorage struct {
+	data map[string]model.TaskStatus // Map of task IDs to their status
+	mu   sync.RWMutex                // Read/Write mutex for synchronization
+}
+
+// NewTaskStatusStorage creates a new instance of TaskStatusStorage that implements
+// the ITaskStatusRepository interface.
+func NewTaskStatusStorage() interfaces.ITaskStatusRepository {
+	return &TaskStatusStorage{
+		data: make(map[string]model.TaskStatus),
+	}
+}
+
+// Insert stores a copy of the status of a new task.
+func (s *TaskStatusStorage) Insert(ctx context.Context, status *model.TaskStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	stored := *status
+	stored.Items = slices.Clone(status.Items)
+	s.data[status.ID] = stored
+	return nil
+}
+
+// FindByID retrieves a copy of the status of a task. Returns nil if there is none.
+func (s *TaskStatusStorage) FindByID(ctx context.Context, id string) (*model.TaskStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	stored, exists := s.data[id]
+	if !exists {
+		return nil, nil
+	}
+	stored.Items = slices.Clone(stored.Items)
+	return &stored, nil
+}
+
+// Update replaces the state, items, error and update time of a stored task.
+func (s *TaskStatusStorage) Update(ctx context.Context, status *model.TaskStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	stored, exists := s.data[status.ID]
+	if !exists {
+		return nil
+	}
+	stored.State = status.State
+	stored.Items = slices.Clone(status.Items)
+	stored.Error = status.Error
+	stored.UpdatedAt = status.UpdatedAt
+	s.data[status.ID] = stored
+	return nil
+}
@@ -0,0 +1,15 @@
+package inmemory_test
+
+import (
+	"testing"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/repository/inmemory"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+)
+
+func TestTaskStatusStorage_Conformance(t *testing.T) {
+	repotest.RunTaskStatusRepositorySuite(t, func(t *testing.T) interfaces.ITaskStatusRepository {
+		return inmemory.NewTaskStatusStorage()
+	})
+}
@@ -1,59 +1,202 @@
 // Package repository provides a collection of repositories responsible for managing URL data.
 // It is responsible for abstracting data access, with support for both in-memory storage
-// and database storage using PostgreSQL.
+// and database storage using PostgreSQL or SQLite.
 //
 // The package includes functionality for establishing database connections, applying migrations,
-// and choosing the appropriate repository implementation based on the configuration, either
-// using in-memory storage or a PostgreSQL database.
+// and choosing the appropriate repository implementation based on the configuration: in-memory
+// storage without a DSN, an SQLite database for a DSN such as sqlite:///var/lib/shlink.db,
+// and a PostgreSQL database otherwise.
 //
 // Repositories:
 //   - URLRepo: The interface responsible for interacting with URL data. It could be backed by either
-//     an in-memory repository or a PostgreSQL database, depending on the configuration provided.
+//     an in-memory repository, an SQLite database or a PostgreSQL database, depending on the configuration provided.
+//   - ClickRepo: The interface responsible for storing click analytics, backed by the same storage as URLRepo.
+//   - APIKeyRepo: The interface responsible for storing the hashed API keys of users, backed by the same storage as URLRepo.
+//   - IdentityRepo: The interface responsible for storing the OpenID Connect accounts linked to users, backed by the same storage as URLRepo.
+//   - AuditRepo: The interface responsible for storing the audit log of administrator actions, backed by the same storage as URLRepo.
+//   - TaskRepo: The interface responsible for the durable queue of background tasks and its dead letters, backed by the same
+//     storage as URLRepo. In-memory storage keeps the queue in cfg.TaskQueuePath, or in memory only if it is empty.
+//   - TaskStatusRepo: The interface responsible for the states of the tasks enqueued on behalf of users, backed by the
+//     same storage as URLRepo. In-memory storage keeps them in memory only.
+//   - JobLockRepo: The interface responsible for the locks letting a single instance run each recurring job, backed by the
+//     same storage as URLRepo. In-memory storage only protects the pools of the process.
+//
+// Lookups of a database-backed URLRepo go through a read-through LRU cache unless cfg.URLCacheSize is 0.
+// With tracing enabled the statements of database-backed repositories and the queries sent to
+// PostgreSQL are traced; cache hits, the task queue, which idle workers poll, and the job locks are not.
 package repository
 
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 
 	"github.com/GlebRadaev/shlink/internal/config"
 	"github.com/GlebRadaev/shlink/internal/interfaces"
 	"github.com/GlebRadaev/shlink/internal/logger"
+	"github.com/GlebRadaev/shlink/internal/repository/cache"
 	"github.com/GlebRadaev/shlink/internal/repository/database"
 	"github.com/GlebRadaev/shlink/internal/repository/inmemory"
+	"github.com/GlebRadaev/shlink/internal/repository/sqlite"
+	"github.com/GlebRadaev/shlink/internal/tracing"
 	"github.com/GlebRadaev/shlink/migrations"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pressly/goose/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/mattn/go-sqlite3"
 )
 
+// SQLiteScheme is the DSN scheme selecting the SQLite database, followed by the path of the database file.
+const SQLiteScheme = "sqlite://"
+
 // Repositories represents a collection of repositories for managing URL data.
 type Repositories struct {
-	URLRepo interfaces.IURLRepository // Repository for managing URL data.
+	URLRepo        interfaces.IURLRepository        // Repository for managing URL data.
+	ClickRepo      interfaces.IClickRepository      // Repository for managing click analytics.
+	APIKeyRepo     interfaces.IAPIKeyRepository     // Repository for managing API keys.
+	IdentityRepo   interfaces.IIdentityRepository   // Repository for managing the accounts linked to users.
+	AuditRepo      interfaces.IAuditRepository      // Repository for managing the audit log of administrator actions.
+	TaskRepo       interfaces.ITaskRepository       // Repository for managing the queue of background tasks.
+	TaskStatusRepo interfaces.ITaskStatusRepository // Repository for managing the states of the tasks of users.
+	JobLockRepo    interfaces.IJobLockRepository    // Repository for managing the locks of the recurring jobs.
+	URLCache       *cache.URLRepository             // Cache in front of a database URLRepo, nil if URLs are not cached.
+	DBPool         *pgxpool.Pool                    // Connection pool of the PostgreSQL database, nil if another storage is used.
 }
 
 // NewRepositoryFactory creates a new instance of Repositories based on configuration and logger.
 func NewRepositoryFactory(ctx context.Context, cfg *config.Config, log *logger.Logger) *Repositories {
-	var urlRepo interfaces.IURLRepository
+	var repos *Repositories
 	logger := log.Named("RepositoryFactory")
-	if cfg.DatabaseDSN != "" {
-		pool, err := pgxpool.New(ctx, cfg.DatabaseDSN)
+	if path, ok := strings.CutPrefix(cfg.DatabaseDSN, SQLiteScheme); ok {
+		db, err := OpenSQLite(ctx, path)
+		if err == nil {
+			logger.Infof("Connected to SQLite database %s.", path)
+			repos = withTracing(cfg, &Repositories{
+				URLRepo:        sqlite.NewURLRepository(db),
+				ClickRepo:      sqlite.NewClickRepository(db),
+				APIKeyRepo:     sqlite.NewAPIKeyRepository(db),
+				IdentityRepo:   sqlite.NewIdentityRepository(db),
+				AuditRepo:      sqlite.NewAuditRepository(db),
+				TaskRepo:       sqlite.NewTaskRepository(db),
+				TaskStatusRepo: sqlite.NewTaskStatusRepository(db),
+				JobLockRepo:    sqlite.NewJobLockRepository(db),
+			}, tracing.SystemSQLite)
+			repos.URLRepo, repos.URLCache = withURLCache(cfg, repos.URLRepo)
+		} else {
+			logger.Errorf("Connected to in-memory storage (failed to open SQLite database): %v", err)
+			repos = newMemoryRepositories(ctx, cfg, logger)
+		}
+	} else if cfg.DatabaseDSN != "" {
+		pool, err := newPgxPool(ctx, cfg)
 		if err == nil {
 			logger.Info("Connected to database.")
 			if err := Migrate(ctx, cfg.DatabaseDSN); err != nil {
 				logger.Error("Failed to run migrations: %v", err)
 			}
-			urlRepo = database.NewURLRepository(pool)
+			repos = withTracing(cfg, &Repositories{
+				URLRepo:        database.NewURLRepository(pool),
+				ClickRepo:      database.NewClickRepository(pool),
+				APIKeyRepo:     database.NewAPIKeyRepository(pool),
+				IdentityRepo:   database.NewIdentityRepository(pool),
+				AuditRepo:      database.NewAuditRepository(pool),
+				TaskRepo:       database.NewTaskRepository(pool),
+				TaskStatusRepo: database.NewTaskStatusRepository(pool),
+				JobLockRepo:    database.NewJobLockRepository(pool),
+				DBPool:         pool,
+			}, tracing.SystemPostgreSQL)
+			repos.URLRepo, repos.URLCache = withURLCache(cfg, repos.URLRepo)
 		} else {
 			logger.Info("Connected to in-memory storage (failed to connect to database): %v", err)
-			urlRepo = inmemory.NewMemoryStorage()
+			repos = newMemoryRepositories(ctx, cfg, logger)
 		}
 	} else {
 		logger.Info("Connected to in-memory storage.")
-		urlRepo = inmemory.NewMemoryStorage()
+		repos = newMemoryRepositories(ctx, cfg, logger)
+	}
+	return repos
+}
+
+// newPgxPool creates the PostgreSQL connection pool, tracing its queries if tracing is enabled.
+func newPgxPool(ctx context.Context, cfg *config.Config) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.DatabaseDSN)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.TracingExporter != "" {
+		poolConfig.ConnConfig.Tracer = tracing.NewQueryTracer()
+	}
+	return pgxpool.NewWithConfig(ctx, poolConfig)
+}
+
+// withTracing wraps the database repositories to start a span for each statement, unless tracing is disabled.
+func withTracing(cfg *config.Config, repos *Repositories, system attribute.KeyValue) *Repositories {
+	if cfg.TracingExporter == "" {
+		return repos
+	}
+	repos.URLRepo = tracing.NewURLRepository(repos.URLRepo, system)
+	repos.ClickRepo = tracing.NewClickRepository(repos.ClickRepo, system)
+	repos.APIKeyRepo = tracing.NewAPIKeyRepository(repos.APIKeyRepo, system)
+	repos.IdentityRepo = tracing.NewIdentityRepository(repos.IdentityRepo, system)
+	repos.AuditRepo = tracing.NewAuditRepository(repos.AuditRepo, system)
+	repos.TaskStatusRepo = tracing.NewTaskStatusRepository(repos.TaskStatusRepo, system)
+	return repos
+}
+
+// withURLCache puts a cache in front of the database URL repository, unless caching is disabled.
+func withURLCache(cfg *config.Config, repo interfaces.IURLRepository) (interfaces.IURLRepository, *cache.URLRepository) {
+	if cfg.URLCacheSize <= 0 {
+		return repo, nil
+	}
+	urlCache := cache.NewURLRepository(repo, cfg.URLCacheSize, cfg.URLCacheTTL, cfg.URLCacheNegativeTTL)
+	return urlCache, urlCache
+}
+
+// newMemoryRepositories creates the in-memory repositories.
+func newMemoryRepositories(ctx context.Context, cfg *config.Config, logger *zap.SugaredLogger) *Repositories {
+	return &Repositories{
+		URLRepo:        newMemoryStorage(ctx, cfg, logger),
+		ClickRepo:      inmemory.NewClickStorage(),
+		APIKeyRepo:     inmemory.NewAPIKeyStorage(),
+		IdentityRepo:   inmemory.NewIdentityStorage(),
+		AuditRepo:      inmemory.NewAuditStorage(),
+		TaskRepo:       newTaskStorage(cfg, logger),
+		TaskStatusRepo: inmemory.NewTaskStatusStorage(),
+		JobLockRepo:    inmemory.NewJobLockStorage(),
 	}
+}
+
+// newTaskStorage creates the in-memory task queue, saved to a file if a task queue path is configured.
+// If the file cannot be opened, the queue is kept in memory only.
+func newTaskStorage(cfg *config.Config, logger *zap.SugaredLogger) interfaces.ITaskRepository {
+	if cfg.TaskQueuePath == "" {
+		return inmemory.NewTaskStorage()
+	}
+	storage, err := inmemory.OpenTaskStorage(cfg.TaskQueuePath)
+	if err != nil {
+		logger.Errorf("Failed to open task queue, queued tasks will be lost on restart: %v", err)
+		return inmemory.NewTaskStorage()
+	}
+	logger.Infof("Keeping queued tasks in %s.", cfg.TaskQueuePath)
+	return storage
+}
 
-	return &Repositories{URLRepo: urlRepo}
+// newMemoryStorage creates the in-memory URL storage, journaled if a journal path is configured.
+// If the journal cannot be opened, the storage works without it.
+func newMemoryStorage(ctx context.Context, cfg *config.Config, logger *zap.SugaredLogger) interfaces.IURLRepository {
+	if cfg.JournalPath == "" {
+		return inmemory.NewMemoryStorage()
+	}
+	journal, err := inmemory.OpenJournal(ctx, cfg.JournalPath, cfg.JournalSync, cfg.JournalSyncInterval)
+	if err != nil {
+		logger.Errorf("Failed to open journal, changes will not be journaled: %v", err)
+		return inmemory.NewMemoryStorage()
+	}
+	logger.Infof("Journaling in-memory storage changes to %s.", cfg.JournalPath)
+	return inmemory.NewJournaledMemoryStorage(journal)
 }
 
 // Migrate runs database migrations using Goose on the provided DSN.
@@ -67,3 +210,29 @@ func Migrate(ctx context.Context, dsn string) error {
 	goose.SetBaseFS(migrations.Migrations)
 	return goose.RunContext(ctx, "up", db, ".")
 }
+
+// OpenSQLite opens the SQLite database file at path, creating it if needed, and runs the migrations.
+// A single connection is used, so writes never fail because another connection holds the lock.
+func OpenSQLite(ctx context.Context, path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate", path))
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if err := MigrateSQLite(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+	return db, nil
+}
+
+// MigrateSQLite runs the database migrations on an SQLite database, using the SQLite
+// versions of the migrations that need dialect-specific SQL.
+func MigrateSQLite(ctx context.Context, db *sql.DB) error {
+	provider, err := goose.NewProvider(goose.DialectSQLite3, db, migrations.ForDialect(migrations.DialectSQLite))
+	if err != nil {
+		return err
+	}
+	_, err = provider.Up(ctx)
+	return err
+}
@@ -12,6 +12,7 @@ package repository
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	model "github.com/GlebRadaev/shlink/internal/model"
 	gomock "go.uber.org/mock/gomock"
@@ -41,6 +42,21 @@ func (m *MockIURLRepository) EXPECT() *MockIURLRepositoryMockRecorder {
 	return m.recorder
 }
 
+// DeleteExpired mocks base method.
+func (m *MockIURLRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpired", ctx, now)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpired indicates an expected call of DeleteExpired.
+func (mr *MockIURLRepositoryMockRecorder) DeleteExpired(ctx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpired", reflect.TypeOf((*MockIURLRepository)(nil).DeleteExpired), ctx, now)
+}
+
 // DeleteListByUserIDAndShortIDs mocks base method.
 func (m *MockIURLRepository) DeleteListByUserIDAndShortIDs(ctx context.Context, userID string, shortIDs []string) error {
 	m.ctrl.T.Helper()
@@ -70,19 +86,49 @@ func (mr *MockIURLRepositoryMockRecorder) FindByID(ctx, shortID any) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByID", reflect.TypeOf((*MockIURLRepository)(nil).FindByID), ctx, shortID)
 }
 
+// FindListByDomain mocks base method.
+func (m *MockIURLRepository) FindListByDomain(ctx context.Context, domain string) ([]*model.URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindListByDomain", ctx, domain)
+	ret0, _ := ret[0].([]*model.URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindListByDomain indicates an expected call of FindListByDomain.
+func (mr *MockIURLRepositoryMockRecorder) FindListByDomain(ctx, domain any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindListByDomain", reflect.TypeOf((*MockIURLRepository)(nil).FindListByDomain), ctx, domain)
+}
+
 // FindListByUserID mocks base method.
-func (m *MockIURLRepository) FindListByUserID(ctx context.Context, userID string) ([]*model.URL, error) {
+func (m *MockIURLRepository) FindListByUserID(ctx context.Context, userID string, query model.URLListQuery) ([]*model.URL, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindListByUserID", ctx, userID)
+	ret := m.ctrl.Call(m, "FindListByUserID", ctx, userID, query)
 	ret0, _ := ret[0].([]*model.URL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // FindListByUserID indicates an expected call of FindListByUserID.
-func (mr *MockIURLRepositoryMockRecorder) FindListByUserID(ctx, userID any) *gomock.Call {
+func (mr *MockIURLRepositoryMockRecorder) FindListByUserID(ctx, userID, query any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindListByUserID", reflect.TypeOf((*MockIURLRepository)(nil).FindListByUserID), ctx, userID, query)
+}
+
+// IncrementClicks mocks base method.
+func (m *MockIURLRepository) IncrementClicks(ctx context.Context, shortID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementClicks", ctx, shortID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrementClicks indicates an expected call of IncrementClicks.
+func (mr *MockIURLRepositoryMockRecorder) IncrementClicks(ctx, shortID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindListByUserID", reflect.TypeOf((*MockIURLRepository)(nil).FindListByUserID), ctx, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementClicks", reflect.TypeOf((*MockIURLRepository)(nil).IncrementClicks), ctx, shortID)
 }
 
 // Insert mocks base method.
@@ -143,3 +189,48 @@ func (mr *MockIURLRepositoryMockRecorder) Ping(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockIURLRepository)(nil).Ping), ctx)
 }
+
+// ReassignUserID mocks base method.
+func (m *MockIURLRepository) ReassignUserID(ctx context.Context, fromUserID, toUserID string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReassignUserID", ctx, fromUserID, toUserID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReassignUserID indicates an expected call of ReassignUserID.
+func (mr *MockIURLRepositoryMockRecorder) ReassignUserID(ctx, fromUserID, toUserID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReassignUserID", reflect.TypeOf((*MockIURLRepository)(nil).ReassignUserID), ctx, fromUserID, toUserID)
+}
+
+// SetDisabled mocks base method.
+func (m *MockIURLRepository) SetDisabled(ctx context.Context, shortIDs []string, disabled bool) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDisabled", ctx, shortIDs, disabled)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetDisabled indicates an expected call of SetDisabled.
+func (mr *MockIURLRepositoryMockRecorder) SetDisabled(ctx, shortIDs, disabled any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDisabled", reflect.TypeOf((*MockIURLRepository)(nil).SetDisabled), ctx, shortIDs, disabled)
+}
+
+// UpdateOriginalURL mocks base method.
+func (m *MockIURLRepository) UpdateOriginalURL(ctx context.Context, userID, shortID, originalURL string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOriginalURL", ctx, userID, shortID, originalURL)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateOriginalURL indicates an expected call of UpdateOriginalURL.
+func (mr *MockIURLRepositoryMockRecorder) UpdateOriginalURL(ctx, userID, shortID, originalURL any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOriginalURL", reflect.TypeOf((*MockIURLRepository)(nil).UpdateOriginalURL), ctx, userID, shortID, originalURL)
+}
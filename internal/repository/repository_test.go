@@ -2,12 +2,16 @@ package repository
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 
 	"github.com/GlebRadaev/shlink/internal/config"
 	"github.com/GlebRadaev/shlink/internal/logger"
+	"github.com/GlebRadaev/shlink/internal/repository/cache"
 	"github.com/GlebRadaev/shlink/internal/repository/database"
 	"github.com/GlebRadaev/shlink/internal/repository/inmemory"
+	"github.com/GlebRadaev/shlink/internal/repository/sqlite"
+	"github.com/GlebRadaev/shlink/internal/tracing"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -47,4 +51,50 @@ func TestNewRepositoryFactory(t *testing.T) {
 		_, isDatabaseRepo := repos.URLRepo.(*database.URLRepository)
 		assert.False(t, isDatabaseRepo, "Expected not to be a database repository")
 	})
+
+	t.Run("creates SQLite URLRepository if DSN has the sqlite scheme", func(t *testing.T) {
+		cfg := &config.Config{DatabaseDSN: SQLiteScheme + filepath.Join(t.TempDir(), "shlink.db")}
+		repos := NewRepositoryFactory(ctx, cfg, log)
+
+		_, ok := repos.URLRepo.(*sqlite.URLRepository)
+		assert.True(t, ok, "Expected an SQLite URLRepository instance")
+		_, ok = repos.ClickRepo.(*sqlite.ClickRepository)
+		assert.True(t, ok, "Expected an SQLite ClickRepository instance")
+		_, ok = repos.APIKeyRepo.(*sqlite.APIKeyRepository)
+		assert.True(t, ok, "Expected an SQLite APIKeyRepository instance")
+		_, ok = repos.IdentityRepo.(*sqlite.IdentityRepository)
+		assert.True(t, ok, "Expected an SQLite IdentityRepository instance")
+		assert.NoError(t, repos.URLRepo.Ping(ctx))
+	})
+
+	t.Run("caches database URLRepository if URLCacheSize is set", func(t *testing.T) {
+		cfg := &config.Config{DatabaseDSN: SQLiteScheme + filepath.Join(t.TempDir(), "shlink.db"), URLCacheSize: 10}
+		repos := NewRepositoryFactory(ctx, cfg, log)
+
+		urlCache, ok := repos.URLRepo.(*cache.URLRepository)
+		assert.True(t, ok, "Expected a cache URLRepository instance")
+		assert.Same(t, urlCache, repos.URLCache)
+	})
+
+	t.Run("traces database repositories if TracingExporter is set", func(t *testing.T) {
+		cfg := &config.Config{DatabaseDSN: SQLiteScheme + filepath.Join(t.TempDir(), "shlink.db"), TracingExporter: "stdout"}
+		repos := NewRepositoryFactory(ctx, cfg, log)
+
+		_, ok := repos.URLRepo.(*tracing.URLRepository)
+		assert.True(t, ok, "Expected a tracing URLRepository instance")
+		_, ok = repos.ClickRepo.(*tracing.ClickRepository)
+		assert.True(t, ok, "Expected a tracing ClickRepository instance")
+		_, ok = repos.APIKeyRepo.(*tracing.APIKeyRepository)
+		assert.True(t, ok, "Expected a tracing APIKeyRepository instance")
+		_, ok = repos.IdentityRepo.(*tracing.IdentityRepository)
+		assert.True(t, ok, "Expected a tracing IdentityRepository instance")
+	})
+
+	t.Run("creates in-memory MemoryStorage if SQLite database cannot be opened", func(t *testing.T) {
+		cfg := &config.Config{DatabaseDSN: SQLiteScheme + filepath.Join(t.TempDir(), "missing", "shlink.db")}
+		repos := NewRepositoryFactory(ctx, cfg, log)
+
+		_, ok := repos.URLRepo.(*inmemory.MemoryStorage)
+		assert.True(t, ok, "Expected a MemoryStorage instance")
+	})
 }
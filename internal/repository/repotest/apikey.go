@@ -0,0 +1,98 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunAPIKeyRepositorySuite runs the conformance tests on the API key repositories created by newRepo.
+// newRepo is called once for every test and must return an empty repository.
+func RunAPIKeyRepositorySuite(t *testing.T, newRepo func(t *testing.T) interfaces.IAPIKeyRepository) {
+	t.Run("InsertAndFind", func(t *testing.T) { testAPIKeyInsertAndFind(t, newRepo(t)) })
+	t.Run("ListByUser", func(t *testing.T) { testAPIKeyListByUser(t, newRepo(t)) })
+	t.Run("OwnershipScopedRevoke", func(t *testing.T) { testAPIKeyOwnershipScopedRevoke(t, newRepo(t)) })
+}
+
+// apiKeyCreatedAt is the creation time of the first key inserted by the tests.
+var apiKeyCreatedAt = time.Date(2024, time.November, 17, 12, 0, 0, 0, time.UTC)
+
+func newAPIKey(id, userID string, createdAt time.Time) *model.APIKey {
+	return &model.APIKey{
+		ID:        id,
+		UserID:    userID,
+		Name:      "key " + id,
+		Prefix:    "shl_" + id,
+		KeyHash:   "hash-" + id,
+		Scopes:    []string{model.ScopeShorten, model.ScopeRead},
+		CreatedAt: createdAt,
+	}
+}
+
+func testAPIKeyInsertAndFind(t *testing.T, repo interfaces.IAPIKeyRepository) {
+	ctx := context.Background()
+	key := newAPIKey("key1", "user1", apiKeyCreatedAt)
+	require.NoError(t, repo.Insert(ctx, key))
+
+	found, err := repo.FindByHash(ctx, "hash-key1")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, key.ID, found.ID)
+	assert.Equal(t, key.UserID, found.UserID)
+	assert.Equal(t, key.Name, found.Name)
+	assert.Equal(t, key.Prefix, found.Prefix)
+	assert.Equal(t, key.Scopes, found.Scopes)
+	assert.True(t, key.CreatedAt.Equal(found.CreatedAt), "Expected %v, got %v", key.CreatedAt, found.CreatedAt)
+	assert.Nil(t, found.RevokedAt)
+
+	found, err = repo.FindByHash(ctx, "hash-unknown")
+	require.NoError(t, err)
+	assert.Nil(t, found, "Expected nil for an unknown hash")
+}
+
+func testAPIKeyListByUser(t *testing.T, repo interfaces.IAPIKeyRepository) {
+	ctx := context.Background()
+	require.NoError(t, repo.Insert(ctx, newAPIKey("key2", "user1", apiKeyCreatedAt.Add(time.Minute))))
+	require.NoError(t, repo.Insert(ctx, newAPIKey("key1", "user1", apiKeyCreatedAt)))
+	require.NoError(t, repo.Insert(ctx, newAPIKey("key3", "user2", apiKeyCreatedAt)))
+
+	keys, err := repo.FindListByUserID(ctx, "user1")
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "key1", keys[0].ID, "Expected the keys in creation order")
+	assert.Equal(t, "key2", keys[1].ID)
+
+	keys, err = repo.FindListByUserID(ctx, "user3")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func testAPIKeyOwnershipScopedRevoke(t *testing.T, repo interfaces.IAPIKeyRepository) {
+	ctx := context.Background()
+	require.NoError(t, repo.Insert(ctx, newAPIKey("key1", "user1", apiKeyCreatedAt)))
+	revokedAt := apiKeyCreatedAt.Add(time.Hour)
+
+	revoked, err := repo.Revoke(ctx, "user2", "key1", revokedAt)
+	require.NoError(t, err)
+	assert.False(t, revoked, "Expected another user not to revoke the key")
+
+	revoked, err = repo.Revoke(ctx, "user1", "key1", revokedAt)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = repo.Revoke(ctx, "user1", "key1", revokedAt.Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, revoked, "Expected a revoked key not to be revoked again")
+
+	found, err := repo.FindByHash(ctx, "hash-key1")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	require.NotNil(t, found.RevokedAt)
+	assert.True(t, revokedAt.Equal(*found.RevokedAt), "Expected %v, got %v", revokedAt, *found.RevokedAt)
+	assert.True(t, found.IsRevoked())
+}
@@ -0,0 +1,84 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunAuditRepositorySuite runs the conformance tests on the audit repositories created by newRepo.
+// newRepo is called once for every test and must return an empty repository.
+func RunAuditRepositorySuite(t *testing.T, newRepo func(t *testing.T) interfaces.IAuditRepository) {
+	t.Run("InsertAndFind", func(t *testing.T) { testAuditInsertAndFind(t, newRepo(t)) })
+	t.Run("FindListFilters", func(t *testing.T) { testAuditFindListFilters(t, newRepo(t)) })
+}
+
+// auditCreatedAt is the time of the entries inserted by the tests.
+var auditCreatedAt = time.Date(2024, time.November, 17, 12, 0, 0, 0, time.UTC)
+
+func testAuditInsertAndFind(t *testing.T, repo interfaces.IAuditRepository) {
+	ctx := context.Background()
+	entry := &model.AuditEntry{
+		ActorUserID: "admin1",
+		APIKeyID:    "key1",
+		Action:      model.AuditDisableUserURLs,
+		Target:      "user1",
+		Reason:      "phishing",
+		Affected:    3,
+		CreatedAt:   auditCreatedAt,
+	}
+	require.NoError(t, repo.Insert(ctx, entry))
+	assert.NotZero(t, entry.ID, "Expected the ID to be set")
+
+	entries, err := repo.FindList(ctx, model.AuditQuery{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	found := entries[0]
+	assert.Equal(t, entry.ID, found.ID)
+	assert.Equal(t, entry.ActorUserID, found.ActorUserID)
+	assert.Equal(t, entry.APIKeyID, found.APIKeyID)
+	assert.Equal(t, entry.Action, found.Action)
+	assert.Equal(t, entry.Target, found.Target)
+	assert.Equal(t, entry.Reason, found.Reason)
+	assert.Equal(t, entry.Affected, found.Affected)
+	assert.True(t, entry.CreatedAt.Equal(found.CreatedAt), "Expected %v, got %v", entry.CreatedAt, found.CreatedAt)
+}
+
+func testAuditFindListFilters(t *testing.T, repo interfaces.IAuditRepository) {
+	ctx := context.Background()
+	var ids []int64
+	for i, target := range []string{"short1", "short2", "short1", "short1"} {
+		entry := &model.AuditEntry{ActorUserID: "admin1", Action: model.AuditLookupURL, Target: target,
+			CreatedAt: auditCreatedAt.Add(time.Duration(i) * time.Minute)}
+		require.NoError(t, repo.Insert(ctx, entry))
+		ids = append(ids, entry.ID)
+	}
+	entryIDs := func(entries []*model.AuditEntry) []int64 {
+		result := make([]int64, 0, len(entries))
+		for _, entry := range entries {
+			result = append(result, entry.ID)
+		}
+		return result
+	}
+
+	entries, err := repo.FindList(ctx, model.AuditQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ids[3], ids[2], ids[1], ids[0]}, entryIDs(entries), "Expected the newest entries first")
+
+	entries, err = repo.FindList(ctx, model.AuditQuery{Target: "short1", Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ids[3], ids[2]}, entryIDs(entries))
+
+	entries, err = repo.FindList(ctx, model.AuditQuery{Target: "short1", Before: ids[2]})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ids[0]}, entryIDs(entries))
+
+	entries, err = repo.FindList(ctx, model.AuditQuery{Target: "unknown"})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
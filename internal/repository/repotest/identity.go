@@ -0,0 +1,88 @@
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunIdentityRepositorySuite runs the conformance tests on the identity repositories created by newRepo.
+// newRepo is called once for every test and must return an empty repository.
+func RunIdentityRepositorySuite(t *testing.T, newRepo func(t *testing.T) interfaces.IIdentityRepository) {
+	t.Run("FindOrInsert", func(t *testing.T) { testIdentityFindOrInsert(t, newRepo(t)) })
+	t.Run("FindByUserID", func(t *testing.T) { testIdentityFindByUserID(t, newRepo(t)) })
+	t.Run("ConcurrentFindOrInsert", func(t *testing.T) { testIdentityConcurrentFindOrInsert(t, newRepo(t)) })
+}
+
+// identityCreatedAt is the link time of the identities inserted by the tests.
+var identityCreatedAt = time.Date(2024, time.November, 17, 12, 0, 0, 0, time.UTC)
+
+func newIdentity(issuer, subject, userID string) *model.UserIdentity {
+	return &model.UserIdentity{
+		Issuer:    issuer,
+		Subject:   subject,
+		UserID:    userID,
+		Email:     subject + "@example.com",
+		CreatedAt: identityCreatedAt,
+	}
+}
+
+func testIdentityFindOrInsert(t *testing.T, repo interfaces.IIdentityRepository) {
+	ctx := context.Background()
+
+	stored, err := repo.FindOrInsert(ctx, newIdentity("https://idp.example.com", "alice", "user1"))
+	require.NoError(t, err)
+	assert.Equal(t, "user1", stored.UserID)
+	assert.Equal(t, "alice@example.com", stored.Email)
+	assert.True(t, identityCreatedAt.Equal(stored.CreatedAt))
+
+	stored, err = repo.FindOrInsert(ctx, newIdentity("https://idp.example.com", "alice", "user2"))
+	require.NoError(t, err)
+	assert.Equal(t, "user1", stored.UserID, "Expected the account to keep its user")
+
+	stored, err = repo.FindOrInsert(ctx, newIdentity("https://other.example.com", "alice", "user3"))
+	require.NoError(t, err)
+	assert.Equal(t, "user3", stored.UserID, "Expected the subject to be scoped to the issuer")
+}
+
+func testIdentityFindByUserID(t *testing.T, repo interfaces.IIdentityRepository) {
+	ctx := context.Background()
+	_, err := repo.FindOrInsert(ctx, newIdentity("https://idp.example.com", "alice", "user1"))
+	require.NoError(t, err)
+
+	identity, err := repo.FindByUserID(ctx, "user1")
+	require.NoError(t, err)
+	require.NotNil(t, identity)
+	assert.Equal(t, "alice", identity.Subject)
+
+	identity, err = repo.FindByUserID(ctx, "anonymous")
+	require.NoError(t, err)
+	assert.Nil(t, identity)
+}
+
+func testIdentityConcurrentFindOrInsert(t *testing.T, repo interfaces.IIdentityRepository) {
+	ctx := context.Background()
+	userIDs := make([]string, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			stored, err := repo.FindOrInsert(ctx, newIdentity("https://idp.example.com", "alice", fmt.Sprintf("user%d", i)))
+			if assert.NoError(t, err) {
+				userIDs[i] = stored.UserID
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, userID := range userIDs {
+		assert.Equal(t, userIDs[0], userID, "Expected concurrent first sign-ins to agree on the user")
+	}
+}
@@ -0,0 +1,103 @@
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunJobLockRepositorySuite runs the conformance tests on the job lock repositories created by newRepo.
+// newRepo is called once for every test and must return an empty repository.
+func RunJobLockRepositorySuite(t *testing.T, newRepo func(t *testing.T) interfaces.IJobLockRepository) {
+	t.Run("AcquireOncePerRun", func(t *testing.T) { testJobLockAcquireOncePerRun(t, newRepo(t)) })
+	t.Run("LeaseAndRelease", func(t *testing.T) { testJobLockLeaseAndRelease(t, newRepo(t)) })
+	t.Run("ConcurrentAcquire", func(t *testing.T) { testJobLockConcurrentAcquire(t, newRepo(t)) })
+}
+
+func testJobLockAcquireOncePerRun(t *testing.T, repo interfaces.IJobLockRepository) {
+	ctx := context.Background()
+	acquired, err := repo.Acquire(ctx, "job1", "owner1", taskTime, taskTime, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired, "Expected the first run to be acquired")
+	require.NoError(t, repo.Release(ctx, "job1", "owner1", taskTime.Add(time.Second)))
+
+	acquired, err = repo.Acquire(ctx, "job1", "owner2", taskTime, taskTime.Add(2*time.Second), time.Minute)
+	require.NoError(t, err)
+	assert.False(t, acquired, "Expected a run to be taken once")
+	acquired, err = repo.Acquire(ctx, "job1", "owner2", taskTime.Add(-time.Hour), taskTime.Add(2*time.Second), time.Minute)
+	require.NoError(t, err)
+	assert.False(t, acquired, "Expected an earlier run not to be taken after a later one")
+
+	acquired, err = repo.Acquire(ctx, "job2", "owner2", taskTime, taskTime, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired, "Expected the jobs to be locked separately")
+	acquired, err = repo.Acquire(ctx, "job1", "owner2", taskTime.Add(time.Hour), taskTime.Add(time.Hour), time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired, "Expected the next run to be acquired")
+}
+
+func testJobLockLeaseAndRelease(t *testing.T, repo interfaces.IJobLockRepository) {
+	ctx := context.Background()
+	acquired, err := repo.Acquire(ctx, "job1", "owner1", taskTime, taskTime, time.Minute)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	next := taskTime.Add(30 * time.Second)
+	acquired, err = repo.Acquire(ctx, "job1", "owner2", next, next, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, acquired, "Expected a held lock not to be acquired by the next run")
+
+	extended, err := repo.Extend(ctx, "job1", "owner1", taskTime.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.True(t, extended)
+	extended, err = repo.Extend(ctx, "job1", "owner2", taskTime.Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, extended, "Expected only the owner to extend the lock")
+
+	acquired, err = repo.Acquire(ctx, "job1", "owner2", next, taskTime.Add(90*time.Second), time.Minute)
+	require.NoError(t, err)
+	assert.False(t, acquired, "Expected the extended lease to hold the lock")
+	acquired, err = repo.Acquire(ctx, "job1", "owner2", next, taskTime.Add(2*time.Minute), time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired, "Expected an expired lease to release the lock")
+
+	extended, err = repo.Extend(ctx, "job1", "owner1", taskTime.Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, extended, "Expected the lock to be lost once another owner acquired it")
+	require.NoError(t, repo.Release(ctx, "job1", "owner1", taskTime.Add(2*time.Minute)))
+	later := taskTime.Add(150 * time.Second)
+	acquired, err = repo.Acquire(ctx, "job1", "owner1", later, later, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, acquired, "Expected a former owner not to release the lock")
+
+	require.NoError(t, repo.Release(ctx, "job1", "owner2", later))
+	acquired, err = repo.Acquire(ctx, "job1", "owner1", later, later, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired, "Expected a released lock to be acquired")
+}
+
+func testJobLockConcurrentAcquire(t *testing.T, repo interfaces.IJobLockRepository) {
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	var acquired atomic.Int64
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ok, err := repo.Acquire(ctx, "job1", fmt.Sprintf("owner%d", i), taskTime, taskTime, time.Minute)
+			assert.NoError(t, err)
+			if ok {
+				acquired.Add(1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, int64(1), acquired.Load(), "Expected a single owner to acquire the run")
+}
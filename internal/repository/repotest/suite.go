@@ -0,0 +1,332 @@
+// Package repotest provides a conformance test suite for implementations of
+// interfaces.IURLRepository, interfaces.IAPIKeyRepository and interfaces.IIdentityRepository,
+// so that every storage backend behaves the same way.
+//
+// Example usage:
+//
+//	func TestMemoryStorage_Conformance(t *testing.T) {
+//	    repotest.RunURLRepositorySuite(t, func(t *testing.T) interfaces.IURLRepository {
+//	        return inmemory.NewMemoryStorage()
+//	    })
+//	}
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// concurrency is the number of goroutines used by the concurrent access tests.
+const concurrency = 20
+
+// RunURLRepositorySuite runs the conformance tests on the repositories created by newRepo.
+// newRepo is called once for every test and must return an empty repository.
+func RunURLRepositorySuite(t *testing.T, newRepo func(t *testing.T) interfaces.IURLRepository) {
+	t.Run("InsertDedupe", func(t *testing.T) { testInsertDedupe(t, newRepo(t)) })
+	t.Run("InsertList", func(t *testing.T) { testInsertList(t, newRepo(t)) })
+	t.Run("InsertListAtomic", func(t *testing.T) { testInsertListAtomic(t, newRepo(t)) })
+	t.Run("OwnershipScopedDelete", func(t *testing.T) { testOwnershipScopedDelete(t, newRepo(t)) })
+	t.Run("ReassignUserID", func(t *testing.T) { testReassignUserID(t, newRepo(t)) })
+	t.Run("SoftDeleteVisibility", func(t *testing.T) { testSoftDeleteVisibility(t, newRepo(t)) })
+	t.Run("SetDisabled", func(t *testing.T) { testSetDisabled(t, newRepo(t)) })
+	t.Run("FindListByDomain", func(t *testing.T) { testFindListByDomain(t, newRepo(t)) })
+	t.Run("ContextCancellation", func(t *testing.T) { testContextCancellation(t, newRepo(t)) })
+	t.Run("ConcurrentInsert", func(t *testing.T) { testConcurrentInsert(t, newRepo(t)) })
+	t.Run("ConcurrentIncrementClicks", func(t *testing.T) { testConcurrentIncrementClicks(t, newRepo(t)) })
+}
+
+func testInsertDedupe(t *testing.T, repo interfaces.IURLRepository) {
+	ctx := context.Background()
+
+	url, err := repo.Insert(ctx, &model.URL{ShortID: "short1", OriginalURL: "http://example.com/1", UserID: "user1"})
+	require.NoError(t, err)
+	assert.Equal(t, "short1", url.ShortID)
+	assert.False(t, url.CreatedAt.IsZero(), "Expected the creation time to be set")
+
+	url, err = repo.Insert(ctx, &model.URL{ShortID: "short2", OriginalURL: "http://example.com/1", UserID: "user1"})
+	require.NoError(t, err)
+	assert.Equal(t, "short1", url.ShortID, "Expected the short ID of the already shortened URL")
+
+	_, err = repo.Insert(ctx, &model.URL{ShortID: "short1", OriginalURL: "http://example.com/2", UserID: "user1"})
+	assert.ErrorIs(t, err, interfaces.ErrShortIDTaken)
+
+	assert.ElementsMatch(t, []string{"short1"}, shortIDs(t, repo))
+	found, err := repo.FindByID(ctx, "short2")
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func testInsertList(t *testing.T, repo interfaces.IURLRepository) {
+	ctx := context.Background()
+	_, err := repo.Insert(ctx, &model.URL{ShortID: "short1", OriginalURL: "http://example.com/1", UserID: "user1"})
+	require.NoError(t, err)
+
+	urls, err := repo.InsertList(ctx, []*model.URL{
+		{ShortID: "short2", OriginalURL: "http://example.com/2", UserID: "user1"},
+		{ShortID: "short3", OriginalURL: "http://example.com/1", UserID: "user1"},
+		{ShortID: "short4", OriginalURL: "http://example.com/2", UserID: "user1"},
+	})
+	require.NoError(t, err)
+	require.Len(t, urls, 3)
+	assert.Equal(t, "short2", urls[0].ShortID)
+	assert.Equal(t, "short1", urls[1].ShortID, "Expected the short ID of the already shortened URL")
+	assert.Equal(t, "short2", urls[2].ShortID, "Expected the short ID of the URL shortened earlier in the batch")
+	assert.ElementsMatch(t, []string{"short1", "short2"}, shortIDs(t, repo))
+}
+
+func testInsertListAtomic(t *testing.T, repo interfaces.IURLRepository) {
+	ctx := context.Background()
+	_, err := repo.Insert(ctx, &model.URL{ShortID: "short1", OriginalURL: "http://example.com/1", UserID: "user1"})
+	require.NoError(t, err)
+
+	_, err = repo.InsertList(ctx, []*model.URL{
+		{ShortID: "short2", OriginalURL: "http://example.com/2", UserID: "user1"},
+		{ShortID: "short1", OriginalURL: "http://example.com/3", UserID: "user1"},
+	})
+	assert.ErrorIs(t, err, interfaces.ErrShortIDTaken)
+	assert.ElementsMatch(t, []string{"short1"}, shortIDs(t, repo), "Expected nothing of the failed batch to be stored")
+}
+
+func testOwnershipScopedDelete(t *testing.T, repo interfaces.IURLRepository) {
+	ctx := context.Background()
+	_, err := repo.InsertList(ctx, []*model.URL{
+		{ShortID: "short1", OriginalURL: "http://example.com/1", UserID: "user1"},
+		{ShortID: "short2", OriginalURL: "http://example.com/2", UserID: "user2"},
+		{ShortID: "short3", OriginalURL: "http://example.com/3", UserID: "user1"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.DeleteListByUserIDAndShortIDs(ctx, "user1", []string{"short1", "short2", "missing"}))
+
+	assert.True(t, findByID(t, repo, "short1").DeletedFlag)
+	assert.False(t, findByID(t, repo, "short2").DeletedFlag, "Expected URLs of other users to be kept")
+	assert.False(t, findByID(t, repo, "short3").DeletedFlag, "Expected URLs not in the list to be kept")
+}
+
+func testReassignUserID(t *testing.T, repo interfaces.IURLRepository) {
+	ctx := context.Background()
+	_, err := repo.InsertList(ctx, []*model.URL{
+		{ShortID: "short1", OriginalURL: "http://example.com/1", UserID: "anonymous"},
+		{ShortID: "short2", OriginalURL: "http://example.com/2", UserID: "anonymous"},
+		{ShortID: "short3", OriginalURL: "http://example.com/3", UserID: "user2"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteListByUserIDAndShortIDs(ctx, "anonymous", []string{"short2"}))
+
+	count, err := repo.ReassignUserID(ctx, "anonymous", "user1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.Equal(t, "user1", findByID(t, repo, "short1").UserID)
+	assert.Equal(t, "anonymous", findByID(t, repo, "short2").UserID, "Expected deleted URLs to be kept")
+	assert.Equal(t, "user2", findByID(t, repo, "short3").UserID, "Expected URLs of other users to be kept")
+
+	count, err = repo.ReassignUserID(ctx, "anonymous", "user1")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func testSoftDeleteVisibility(t *testing.T, repo interfaces.IURLRepository) {
+	ctx := context.Background()
+	_, err := repo.InsertList(ctx, []*model.URL{
+		{ShortID: "short1", OriginalURL: "http://example.com/1", UserID: "user1"},
+		{ShortID: "short2", OriginalURL: "http://example.com/2", UserID: "user1"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteListByUserIDAndShortIDs(ctx, "user1", []string{"short1"}))
+
+	deleted := findByID(t, repo, "short1")
+	assert.True(t, deleted.DeletedFlag)
+	assert.Equal(t, "http://example.com/1", deleted.OriginalURL)
+
+	urls, err := repo.List(ctx)
+	require.NoError(t, err)
+	flags := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		flags[url.ShortID] = url.DeletedFlag
+	}
+	assert.Equal(t, map[string]bool{"short1": true, "short2": false}, flags, "Expected List to keep the deleted flag")
+
+	urls, err = repo.FindListByUserID(ctx, "user1", model.URLListQuery{Sort: model.SortOriginalURLAsc})
+	require.NoError(t, err)
+	require.Len(t, urls, 2)
+	assert.True(t, urls[0].DeletedFlag)
+	assert.False(t, urls[1].DeletedFlag)
+
+	active := false
+	urls, err = repo.FindListByUserID(ctx, "user1", model.URLListQuery{Deleted: &active})
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, "short2", urls[0].ShortID)
+
+	updated, err := repo.UpdateOriginalURL(ctx, "user1", "short1", "http://example.com/new")
+	require.NoError(t, err)
+	assert.False(t, updated, "Expected deleted URLs not to be updated")
+}
+
+func testSetDisabled(t *testing.T, repo interfaces.IURLRepository) {
+	ctx := context.Background()
+	_, err := repo.InsertList(ctx, []*model.URL{
+		{ShortID: "short1", OriginalURL: "http://example.com/1", UserID: "user1"},
+		{ShortID: "short2", OriginalURL: "http://example.com/2", UserID: "user2"},
+		{ShortID: "short3", OriginalURL: "http://example.com/3", UserID: "user1"},
+	})
+	require.NoError(t, err)
+
+	count, err := repo.SetDisabled(ctx, []string{"short1", "short2", "missing"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	assert.True(t, findByID(t, repo, "short1").DisabledFlag)
+	assert.True(t, findByID(t, repo, "short2").DisabledFlag)
+	assert.False(t, findByID(t, repo, "short3").DisabledFlag, "Expected URLs not in the list to be kept")
+
+	count, err = repo.SetDisabled(ctx, []string{"short1", "short3"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "Expected URLs already disabled not to be counted")
+
+	count, err = repo.SetDisabled(ctx, []string{"short1"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.False(t, findByID(t, repo, "short1").DisabledFlag)
+
+	urls, err := repo.FindListByUserID(ctx, "user1", model.URLListQuery{})
+	require.NoError(t, err)
+	flags := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		flags[url.ShortID] = url.DisabledFlag
+	}
+	assert.Equal(t, map[string]bool{"short1": false, "short3": true}, flags)
+}
+
+func testFindListByDomain(t *testing.T, repo interfaces.IURLRepository) {
+	ctx := context.Background()
+	_, err := repo.InsertList(ctx, []*model.URL{
+		{ShortID: "short1", OriginalURL: "http://example.com/1", UserID: "user1"},
+		{ShortID: "short2", OriginalURL: "https://WWW.Example.com/2", UserID: "user2"},
+		{ShortID: "short3", OriginalURL: "http://notexample.com/?next=example.com", UserID: "user1"},
+		{ShortID: "short4", OriginalURL: "http://example.com.evil.org/4", UserID: "user1"},
+		{ShortID: "short5", OriginalURL: "http://example.com/5", UserID: "user1"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteListByUserIDAndShortIDs(ctx, "user1", []string{"short5"}))
+
+	urls, err := repo.FindListByDomain(ctx, "example.com")
+	require.NoError(t, err)
+	found := make([]string, 0, len(urls))
+	for _, url := range urls {
+		found = append(found, url.ShortID)
+	}
+	assert.ElementsMatch(t, []string{"short1", "short2"}, found,
+		"Expected the domain and its subdomains, without deleted URLs")
+
+	urls, err = repo.FindListByDomain(ctx, "other.com")
+	require.NoError(t, err)
+	assert.Empty(t, urls)
+}
+
+func testContextCancellation(t *testing.T, repo interfaces.IURLRepository) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.Insert(ctx, &model.URL{ShortID: "short1", OriginalURL: "http://example.com/1", UserID: "user1"})
+	assert.Error(t, err, "Insert")
+	_, err = repo.InsertList(ctx, []*model.URL{{ShortID: "short2", OriginalURL: "http://example.com/2", UserID: "user1"}})
+	assert.Error(t, err, "InsertList")
+	_, err = repo.FindByID(ctx, "short1")
+	assert.Error(t, err, "FindByID")
+	_, err = repo.FindListByUserID(ctx, "user1", model.URLListQuery{})
+	assert.Error(t, err, "FindListByUserID")
+	_, err = repo.UpdateOriginalURL(ctx, "user1", "short1", "http://example.com/new")
+	assert.Error(t, err, "UpdateOriginalURL")
+	assert.Error(t, repo.DeleteListByUserIDAndShortIDs(ctx, "user1", []string{"short1"}), "DeleteListByUserIDAndShortIDs")
+	_, err = repo.IncrementClicks(ctx, "short1")
+	assert.Error(t, err, "IncrementClicks")
+	_, err = repo.DeleteExpired(ctx, time.Now())
+	assert.Error(t, err, "DeleteExpired")
+	_, err = repo.List(ctx)
+	assert.Error(t, err, "List")
+	assert.Error(t, repo.Ping(ctx), "Ping")
+
+	assert.Empty(t, shortIDs(t, repo), "Expected nothing to be stored with a cancelled context")
+}
+
+func testConcurrentInsert(t *testing.T, repo interfaces.IURLRepository) {
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	shared := make([]string, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := repo.Insert(ctx, &model.URL{ShortID: fmt.Sprintf("own%d", i), OriginalURL: fmt.Sprintf("http://example.com/%d", i), UserID: "user1"})
+			assert.NoError(t, err)
+			url, err := repo.Insert(ctx, &model.URL{ShortID: fmt.Sprintf("shared%d", i), OriginalURL: "http://example.com/shared", UserID: "user1"})
+			if assert.NoError(t, err) {
+				shared[i] = url.ShortID
+			}
+			_, err = repo.FindListByUserID(ctx, "user1", model.URLListQuery{})
+			assert.NoError(t, err)
+			_, err = repo.List(ctx)
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, shortID := range shared {
+		assert.Equal(t, shared[0], shortID, "Expected every insert of the same URL to get the same short ID")
+	}
+	assert.Len(t, shortIDs(t, repo), concurrency+1)
+}
+
+func testConcurrentIncrementClicks(t *testing.T, repo interfaces.IURLRepository) {
+	ctx := context.Background()
+	maxClicks := concurrency / 2
+	_, err := repo.Insert(ctx, &model.URL{ShortID: "short1", OriginalURL: "http://example.com/1", UserID: "user1", MaxClicks: &maxClicks})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var counted atomic.Int64
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := repo.IncrementClicks(ctx, "short1")
+			if assert.NoError(t, err) && ok {
+				counted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(maxClicks), counted.Load(), "Expected clicks to stop at the click budget")
+	assert.Equal(t, maxClicks, findByID(t, repo, "short1").Clicks)
+}
+
+// shortIDs returns the short IDs of all URLs in the repository.
+func shortIDs(t *testing.T, repo interfaces.IURLRepository) []string {
+	t.Helper()
+	urls, err := repo.List(context.Background())
+	require.NoError(t, err)
+	result := make([]string, 0, len(urls))
+	for _, url := range urls {
+		result = append(result, url.ShortID)
+	}
+	return result
+}
+
+// findByID returns the URL with the short ID and fails the test if it does not exist.
+func findByID(t *testing.T, repo interfaces.IURLRepository, shortID string) *model.URL {
+	t.Helper()
+	url, err := repo.FindByID(context.Background(), shortID)
+	require.NoError(t, err)
+	require.NotNil(t, url, "URL %s not found", shortID)
+	return url
+}
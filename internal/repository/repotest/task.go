@@ -0,0 +1,319 @@
+package repotest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunTaskRepositorySuite runs the conformance tests on the task repositories created by newRepo.
+// newRepo is called once for every test and must return an empty repository.
+func RunTaskRepositorySuite(t *testing.T, newRepo func(t *testing.T) interfaces.ITaskRepository) {
+	t.Run("PushAndClaim", func(t *testing.T) { testTaskPushAndClaim(t, newRepo(t)) })
+	t.Run("ClaimLease", func(t *testing.T) { testTaskClaimLease(t, newRepo(t)) })
+	t.Run("Extend", func(t *testing.T) { testTaskExtend(t, newRepo(t)) })
+	t.Run("ClaimSkipTypes", func(t *testing.T) { testTaskClaimSkipTypes(t, newRepo(t)) })
+	t.Run("ClaimSkipKeys", func(t *testing.T) { testTaskClaimSkipKeys(t, newRepo(t)) })
+	t.Run("ClaimFairness", func(t *testing.T) { testTaskClaimFairness(t, newRepo(t)) })
+	t.Run("Retry", func(t *testing.T) { testTaskRetry(t, newRepo(t)) })
+	t.Run("Release", func(t *testing.T) { testTaskRelease(t, newRepo(t)) })
+	t.Run("BuryAndReplay", func(t *testing.T) { testTaskBuryAndReplay(t, newRepo(t)) })
+	t.Run("ReplayFairness", func(t *testing.T) { testTaskReplayFairness(t, newRepo(t)) })
+}
+
+// taskTime is the time the tasks inserted by the tests are due.
+var taskTime = time.Date(2024, time.November, 17, 12, 0, 0, 0, time.UTC)
+
+// pushTask pushes a task of the type due at runAt.
+func pushTask(t *testing.T, repo interfaces.ITaskRepository, taskType string, runAt time.Time) *model.QueuedTask {
+	t.Helper()
+	task := &model.QueuedTask{
+		Type:        taskType,
+		Payload:     json.RawMessage(`{"user_id":"user1","urls":["short1"]}`),
+		TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		RunAt:       runAt,
+		CreatedAt:   taskTime,
+		FairAt:      runAt,
+	}
+	require.NoError(t, repo.Push(context.Background(), task))
+	assert.NotZero(t, task.ID, "Expected the ID to be set")
+	return task
+}
+
+// pushFairTask pushes a task due at taskTime with the priority and fair key, whose fair time is its
+// run time plus cost, and returns it with the fair time set by the repository.
+func pushFairTask(t *testing.T, repo interfaces.ITaskRepository, priority int, key string, cost time.Duration) *model.QueuedTask {
+	t.Helper()
+	task := &model.QueuedTask{
+		Type:      "task1",
+		Payload:   json.RawMessage(`{}`),
+		RunAt:     taskTime,
+		CreatedAt: taskTime,
+		Priority:  priority,
+		FairKey:   key,
+		FairAt:    taskTime.Add(cost),
+	}
+	require.NoError(t, repo.Push(context.Background(), task))
+	return task
+}
+
+func testTaskPushAndClaim(t *testing.T, repo interfaces.ITaskRepository) {
+	ctx := context.Background()
+	later := pushTask(t, repo, "task2", taskTime.Add(time.Minute))
+	first := pushTask(t, repo, "task1", taskTime)
+
+	claimed, err := repo.Claim(ctx, taskTime, time.Minute, nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, first.ID, claimed.ID, "Expected the task due first to be claimed first")
+	assert.Equal(t, "task1", claimed.Type)
+	assert.JSONEq(t, string(first.Payload), string(claimed.Payload))
+	assert.Equal(t, first.TraceParent, claimed.TraceParent)
+	assert.Equal(t, 1, claimed.Attempts)
+	assert.True(t, taskTime.Equal(claimed.CreatedAt), "Expected %v, got %v", taskTime, claimed.CreatedAt)
+
+	claimed, err = repo.Claim(ctx, taskTime, time.Minute, nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, claimed, "Expected no task before the next one is due")
+	require.NoError(t, repo.Complete(ctx, first.ID))
+
+	claimed, err = repo.Claim(ctx, taskTime.Add(time.Minute), time.Minute, nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, later.ID, claimed.ID)
+	require.NoError(t, repo.Complete(ctx, claimed.ID))
+
+	claimed, err = repo.Claim(ctx, taskTime.Add(time.Hour), time.Minute, nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, claimed, "Expected completed tasks to be removed")
+}
+
+func testTaskClaimLease(t *testing.T, repo interfaces.ITaskRepository) {
+	ctx := context.Background()
+	task := pushTask(t, repo, "task1", taskTime)
+
+	claimed, err := repo.Claim(ctx, taskTime, time.Minute, nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	claimed, err = repo.Claim(ctx, taskTime.Add(30*time.Second), time.Minute, nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, claimed, "Expected a claimed task to be hidden during its lease")
+
+	claimed, err = repo.Claim(ctx, taskTime.Add(time.Minute), time.Minute, nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, claimed, "Expected the task to be claimed again once its lease expired")
+	assert.Equal(t, task.ID, claimed.ID)
+	assert.Equal(t, 2, claimed.Attempts)
+}
+
+func testTaskExtend(t *testing.T, repo interfaces.ITaskRepository) {
+	ctx := context.Background()
+	task := pushTask(t, repo, "task1", taskTime)
+	claimed, err := repo.Claim(ctx, taskTime, time.Minute, nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+
+	extended, err := repo.Extend(ctx, task.ID, taskTime.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.True(t, extended)
+	claimed, err = repo.Claim(ctx, taskTime.Add(time.Minute), time.Minute, nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, claimed, "Expected the task to be hidden during its extended lease")
+	claimed, err = repo.Claim(ctx, taskTime.Add(2*time.Minute), time.Minute, nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, claimed, "Expected the task to be claimed again once its extended lease expired")
+	assert.Equal(t, 2, claimed.Attempts)
+
+	require.NoError(t, repo.Complete(ctx, task.ID))
+	extended, err = repo.Extend(ctx, task.ID, taskTime.Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, extended, "Expected no lease to extend once the task left the queue")
+}
+
+func testTaskClaimSkipTypes(t *testing.T, repo interfaces.ITaskRepository) {
+	ctx := context.Background()
+	pushTask(t, repo, "task1", taskTime)
+	other := pushTask(t, repo, "task2", taskTime.Add(time.Second))
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	claimed, err := repo.Claim(ctx, taskTime.Add(time.Minute), time.Minute, []string{"task1", "task3"}, nil)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, other.ID, claimed.ID, "Expected the tasks of the skipped types to be left")
+	claimed, err = repo.Claim(ctx, taskTime.Add(time.Minute), time.Minute, []string{"task1"}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, claimed)
+
+	count, err = repo.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "Expected claimed tasks to be counted")
+	require.NoError(t, repo.Complete(ctx, other.ID))
+	count, err = repo.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func testTaskClaimSkipKeys(t *testing.T, repo interfaces.ITaskRepository) {
+	ctx := context.Background()
+	pushFairTask(t, repo, 0, "user1", time.Millisecond)
+	other := pushFairTask(t, repo, 0, "user2", time.Second)
+	unkeyed := pushFairTask(t, repo, 0, "", time.Hour)
+
+	claimed, err := repo.Claim(ctx, taskTime, time.Minute, nil, []string{"user1"})
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, other.ID, claimed.ID, "Expected the tasks of the skipped keys to be left")
+	claimed, err = repo.Claim(ctx, taskTime, time.Minute, nil, []string{"user1", "user2"})
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, unkeyed.ID, claimed.ID, "Expected the tasks without a fair key never to be skipped")
+	claimed, err = repo.Claim(ctx, taskTime, time.Minute, nil, []string{"user1"})
+	require.NoError(t, err)
+	assert.Nil(t, claimed)
+}
+
+func testTaskClaimFairness(t *testing.T, repo interfaces.ITaskRepository) {
+	ctx := context.Background()
+	large := pushFairTask(t, repo, 0, "user1", 100*time.Millisecond)
+	next := pushFairTask(t, repo, 0, "user1", time.Millisecond)
+	assert.True(t, taskTime.Add(101*time.Millisecond).Equal(next.FairAt),
+		"Expected the fair time to follow the queued tasks of the key, got %v", next.FairAt)
+	small := pushFairTask(t, repo, 0, "user2", time.Millisecond)
+	assert.True(t, taskTime.Add(time.Millisecond).Equal(small.FairAt), "Expected the fair time of another key to be left")
+	unkeyed := pushFairTask(t, repo, 0, "", time.Millisecond)
+	urgent := pushFairTask(t, repo, 1, "", time.Hour)
+
+	for _, want := range []*model.QueuedTask{urgent, small, unkeyed, large, next} {
+		claimed, err := repo.Claim(ctx, taskTime, time.Minute, nil, nil)
+		require.NoError(t, err)
+		require.NotNil(t, claimed)
+		assert.Equal(t, want.ID, claimed.ID, "Expected the higher priority, then the earliest fair time")
+		assert.Equal(t, want.Priority, claimed.Priority)
+		assert.Equal(t, want.FairKey, claimed.FairKey)
+		assert.True(t, want.FairAt.Equal(claimed.FairAt), "Expected %v, got %v", want.FairAt, claimed.FairAt)
+	}
+}
+
+func testTaskRetry(t *testing.T, repo interfaces.ITaskRepository) {
+	ctx := context.Background()
+	task := pushTask(t, repo, "task1", taskTime)
+	claimed, err := repo.Claim(ctx, taskTime, time.Hour, nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+
+	require.NoError(t, repo.Retry(ctx, task.ID, taskTime.Add(10*time.Second), "temporary failure"))
+	claimed, err = repo.Claim(ctx, taskTime.Add(5*time.Second), time.Hour, nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, claimed, "Expected the task to wait for its retry")
+
+	claimed, err = repo.Claim(ctx, taskTime.Add(10*time.Second), time.Hour, nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, 2, claimed.Attempts)
+	assert.Equal(t, "temporary failure", claimed.LastError)
+}
+
+func testTaskRelease(t *testing.T, repo interfaces.ITaskRepository) {
+	ctx := context.Background()
+	task := pushTask(t, repo, "task1", taskTime)
+	claimed, err := repo.Claim(ctx, taskTime, time.Hour, nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+
+	require.NoError(t, repo.Release(ctx, task.ID, taskTime))
+	claimed, err = repo.Claim(ctx, taskTime, time.Hour, nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, claimed, "Expected the released task to be claimed again")
+	assert.Equal(t, 1, claimed.Attempts, "Expected the released attempt not to be counted")
+}
+
+func testTaskBuryAndReplay(t *testing.T, repo interfaces.ITaskRepository) {
+	ctx := context.Background()
+	first := pushTask(t, repo, "task1", taskTime)
+	second := pushTask(t, repo, "task2", taskTime)
+	for i := 0; i < 2; i++ {
+		claimed, err := repo.Claim(ctx, taskTime, time.Hour, nil, nil)
+		require.NoError(t, err)
+		require.NotNil(t, claimed)
+	}
+	failedAt := taskTime.Add(time.Minute)
+	require.NoError(t, repo.Bury(ctx, first.ID, "permanent failure", failedAt))
+	require.NoError(t, repo.Bury(ctx, second.ID, "another failure", failedAt))
+
+	dead, err := repo.FindDeadList(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, dead, 2)
+	assert.Equal(t, "task2", dead[0].Type, "Expected the newest dead letters first")
+	found := dead[1]
+	assert.Equal(t, "task1", found.Type)
+	assert.JSONEq(t, string(first.Payload), string(found.Payload))
+	assert.Equal(t, 1, found.Attempts)
+	assert.Equal(t, "permanent failure", found.LastError)
+	assert.True(t, taskTime.Equal(found.CreatedAt), "Expected %v, got %v", taskTime, found.CreatedAt)
+	assert.True(t, failedAt.Equal(found.FailedAt), "Expected %v, got %v", failedAt, found.FailedAt)
+
+	dead, err = repo.FindDeadList(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, dead, 1)
+
+	claimed, err := repo.Claim(ctx, taskTime.Add(2*time.Hour), time.Hour, nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, claimed, "Expected buried tasks to leave the queue")
+
+	replayed, err := repo.Replay(ctx, found.ID, taskTime.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, replayed)
+	assert.Equal(t, "task1", replayed.Type)
+	assert.Zero(t, replayed.Attempts, "Expected the attempts to be reset")
+	assert.True(t, taskTime.Add(2*time.Hour).Equal(replayed.FairAt), "Expected the run time as fair time, got %v", replayed.FairAt)
+	dead, err = repo.FindDeadList(ctx, 0)
+	require.NoError(t, err)
+	assert.Len(t, dead, 1, "Expected the replayed task to leave the dead letters")
+
+	claimed, err = repo.Claim(ctx, taskTime.Add(2*time.Hour), time.Hour, nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, replayed.ID, claimed.ID)
+	assert.JSONEq(t, string(first.Payload), string(claimed.Payload))
+
+	replayed, err = repo.Replay(ctx, found.ID, taskTime)
+	require.NoError(t, err)
+	assert.Nil(t, replayed, "Expected nil for an unknown dead letter")
+}
+
+func testTaskReplayFairness(t *testing.T, repo interfaces.ITaskRepository) {
+	ctx := context.Background()
+	failed := pushFairTask(t, repo, 1, "user1", time.Millisecond)
+	claimed, err := repo.Claim(ctx, taskTime, time.Hour, nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	require.NoError(t, repo.Bury(ctx, failed.ID, "failure", taskTime))
+
+	dead, err := repo.FindDeadList(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, 1, dead[0].Priority, "Expected the priority to be kept in the dead letters")
+	assert.Equal(t, "user1", dead[0].FairKey, "Expected the fair key to be kept in the dead letters")
+
+	queued := pushFairTask(t, repo, 0, "user1", time.Hour)
+	replayed, err := repo.Replay(ctx, dead[0].ID, taskTime)
+	require.NoError(t, err)
+	require.NotNil(t, replayed)
+	assert.Equal(t, 1, replayed.Priority)
+	assert.Equal(t, "user1", replayed.FairKey)
+	assert.True(t, queued.FairAt.Equal(replayed.FairAt),
+		"Expected the fair time to follow the queued tasks of the key, got %v", replayed.FairAt)
+
+	claimed, err = repo.Claim(ctx, taskTime, time.Hour, nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, replayed.ID, claimed.ID, "Expected the replayed task to keep its priority")
+}
@@ -0,0 +1,87 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunTaskStatusRepositorySuite runs the conformance tests on the task status repositories created by newRepo.
+// newRepo is called once for every test and must return an empty repository.
+func RunTaskStatusRepositorySuite(t *testing.T, newRepo func(t *testing.T) interfaces.ITaskStatusRepository) {
+	t.Run("InsertAndFind", func(t *testing.T) { testTaskStatusInsertAndFind(t, newRepo(t)) })
+	t.Run("Update", func(t *testing.T) { testTaskStatusUpdate(t, newRepo(t)) })
+}
+
+// newTaskStatus returns a queued task of the user with pending items.
+func newTaskStatus(id, userID string, items ...string) *model.TaskStatus {
+	status := &model.TaskStatus{
+		ID:        id,
+		UserID:    userID,
+		Type:      "delete_urls_task",
+		State:     model.TaskQueued,
+		CreatedAt: taskTime,
+		UpdatedAt: taskTime,
+	}
+	for _, item := range items {
+		status.Items = append(status.Items, model.TaskItem{ID: item, State: model.TaskItemPending})
+	}
+	return status
+}
+
+func testTaskStatusInsertAndFind(t *testing.T, repo interfaces.ITaskStatusRepository) {
+	ctx := context.Background()
+	status := newTaskStatus("a1b2c3d4-0000-0000-0000-000000000001", "user1", "short1", "short2")
+	require.NoError(t, repo.Insert(ctx, status))
+
+	found, err := repo.FindByID(ctx, status.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, "user1", found.UserID)
+	assert.Equal(t, "delete_urls_task", found.Type)
+	assert.Equal(t, model.TaskQueued, found.State)
+	assert.Equal(t, status.Items, found.Items)
+	assert.Empty(t, found.Error)
+	assert.True(t, taskTime.Equal(found.CreatedAt), "Expected %v, got %v", taskTime, found.CreatedAt)
+	assert.True(t, taskTime.Equal(found.UpdatedAt), "Expected %v, got %v", taskTime, found.UpdatedAt)
+
+	found, err = repo.FindByID(ctx, "a1b2c3d4-0000-0000-0000-000000000002")
+	require.NoError(t, err)
+	assert.Nil(t, found, "Expected nil for an unknown task")
+}
+
+func testTaskStatusUpdate(t *testing.T, repo interfaces.ITaskStatusRepository) {
+	ctx := context.Background()
+	status := newTaskStatus("a1b2c3d4-0000-0000-0000-000000000001", "user1", "short1", "short2")
+	require.NoError(t, repo.Insert(ctx, status))
+	other := newTaskStatus("a1b2c3d4-0000-0000-0000-000000000002", "user2", "short3")
+	require.NoError(t, repo.Insert(ctx, other))
+
+	updatedAt := taskTime.Add(time.Minute)
+	status.State = model.TaskPartiallyFailed
+	status.Items[0].State = model.TaskItemSucceeded
+	status.Items[1] = model.TaskItem{ID: "short2", State: model.TaskItemFailed, Error: "db error"}
+	status.Error = "1 of 2 items failed"
+	status.UpdatedAt = updatedAt
+	require.NoError(t, repo.Update(ctx, status))
+
+	found, err := repo.FindByID(ctx, status.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, model.TaskPartiallyFailed, found.State)
+	assert.Equal(t, status.Items, found.Items)
+	assert.Equal(t, "1 of 2 items failed", found.Error)
+	assert.True(t, taskTime.Equal(found.CreatedAt), "Expected the creation time to be kept, got %v", found.CreatedAt)
+	assert.True(t, updatedAt.Equal(found.UpdatedAt), "Expected %v, got %v", updatedAt, found.UpdatedAt)
+
+	found, err = repo.FindByID(ctx, other.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, model.TaskQueued, found.State, "Expected the other task to be left alone")
+	assert.Equal(t, other.Items, found.Items)
+}
@@ -0,0 +1,119 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// APIKeyRepository represents a repository for API keys in an SQLite database.
+// The scopes of a key are stored as a comma separated list.
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+// NewAPIKeyRepository creates a new instance of APIKeyRepository with the provided database.
+func NewAPIKeyRepository(db *sql.DB) interfaces.IAPIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// Insert inserts a new API key into the database.
+func (r *APIKeyRepository) Insert(ctx context.Context, key *model.APIKey) error {
+	query := `
+		INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7)`
+	_, err := r.db.ExecContext(ctx, query, key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash,
+		strings.Join(key.Scopes, ","), formatTime(key.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to insert API key: %v", err)
+	}
+	return nil
+}
+
+// FindByHash finds an API key by the hash of the key. Returns the key if found, otherwise returns nil.
+func (r *APIKeyRepository) FindByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	query := `
+		SELECT id, user_id, name, prefix, key_hash, scopes, created_at, revoked_at FROM api_keys
+		WHERE key_hash = ?1`
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, query, keyHash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// FindListByUserID finds the API keys of a specific user ordered by creation time.
+func (r *APIKeyRepository) FindListByUserID(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	query := `
+		SELECT id, user_id, name, prefix, key_hash, scopes, created_at, revoked_at FROM api_keys
+		WHERE user_id = ?1
+		ORDER BY created_at, id`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find API keys: %v", err)
+	}
+	defer rows.Close()
+
+	var keys []*model.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find API keys: error occurred during rows iteration: %v", err)
+	}
+	return keys, nil
+}
+
+// Revoke marks the active API key of the user with the given ID as revoked.
+func (r *APIKeyRepository) Revoke(ctx context.Context, userID, id string, revokedAt time.Time) (bool, error) {
+	query := `
+		UPDATE api_keys SET revoked_at = ?3
+		WHERE user_id = ?1 AND id = ?2 AND revoked_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, userID, id, formatTime(revokedAt))
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API key: %v", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API key: %v", err)
+	}
+	return rows > 0, nil
+}
+
+// scanAPIKey scans a row with all API key columns in table order.
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (*model.APIKey, error) {
+	key := &model.APIKey{}
+	var scopes, createdAt string
+	var revokedAt sql.NullString
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &scopes, &createdAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	if scopes != "" {
+		key.Scopes = strings.Split(scopes, ",")
+	}
+	if key.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		t, err := parseTime(revokedAt.String)
+		if err != nil {
+			return nil, err
+		}
+		key.RevokedAt = &t
+	}
+	return key, nil
+}
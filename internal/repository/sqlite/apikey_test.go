@@ -0,0 +1,15 @@
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+	"github.com/GlebRadaev/shlink/internal/repository/sqlite"
+)
+
+func TestAPIKeyRepository_Conformance(t *testing.T) {
+	repotest.RunAPIKeyRepositorySuite(t, func(t *testing.T) interfaces.IAPIKeyRepository {
+		return sqlite.NewAPIKeyRepository(setupDB(t))
+	})
+}
@@ -0,0 +1,15 @@
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+	"github.com/GlebRadaev/shlink/internal/repository/sqlite"
+)
+
+func TestAuditRepository_Conformance(t *testing.T) {
+	repotest.RunAuditRepositorySuite(t, func(t *testing.T) interfaces.IAuditRepository {
+		return sqlite.NewAuditRepository(setupDB(t))
+	})
+}
@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// ClickRepository represents a repository for click analytics data in an SQLite database.
+type ClickRepository struct {
+	db *sql.DB
+}
+
+// NewClickRepository creates a new instance of ClickRepository with the provided database.
+func NewClickRepository(db *sql.DB) interfaces.IClickRepository {
+	return &ClickRepository{db: db}
+}
+
+// InsertList inserts a batch of click events into the database inside a single transaction.
+func (r *ClickRepository) InsertList(ctx context.Context, clicks []*model.Click) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `
+		INSERT INTO clicks (short_id, clicked_at, referrer, user_agent, ip, country)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6)`
+	for _, click := range clicks {
+		_, err := tx.ExecContext(ctx, query, click.ShortID, formatTime(click.ClickedAt), click.Referrer, click.UserAgent, click.IP, click.Country)
+		if err != nil {
+			return fmt.Errorf("failed to insert click: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// GetStats returns the total number of clicks, the number of unique visitors and a daily time series for a short ID.
+func (r *ClickRepository) GetStats(ctx context.Context, shortID string) (*model.ClickStats, error) {
+	stats := &model.ClickStats{}
+	totalQuery := `
+		SELECT COUNT(*), COUNT(DISTINCT ip) FROM clicks
+		WHERE short_id = ?1`
+	if err := r.db.QueryRowContext(ctx, totalQuery, shortID).Scan(&stats.TotalClicks, &stats.UniqueVisitors); err != nil {
+		return nil, fmt.Errorf("failed to count clicks: %v", err)
+	}
+
+	dailyQuery := `
+		SELECT date(clicked_at) AS day, COUNT(*), COUNT(DISTINCT ip) FROM clicks
+		WHERE short_id = ?1
+		GROUP BY day
+		ORDER BY day`
+	rows, err := r.db.QueryContext(ctx, dailyQuery, shortID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find daily clicks: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day model.DailyClicks
+		var date string
+		if err := rows.Scan(&date, &day.Clicks, &day.UniqueVisitors); err != nil {
+			return nil, fmt.Errorf("failed to scan daily clicks: %v", err)
+		}
+		if day.Date, err = time.Parse(time.DateOnly, date); err != nil {
+			return nil, fmt.Errorf("failed to scan daily clicks: %v", err)
+		}
+		stats.Daily = append(stats.Daily, day)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("failed to find daily clicks: error occurred during rows iteration: %v", rows.Err())
+	}
+	return stats, nil
+}
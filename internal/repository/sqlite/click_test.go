@@ -0,0 +1,40 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/repository/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickRepository_GetStats(t *testing.T) {
+	ctx := context.Background()
+	repo := sqlite.NewClickRepository(setupDB(t))
+	day := time.Date(2024, time.November, 17, 23, 30, 0, 0, time.UTC)
+
+	stats, err := repo.GetStats(ctx, "short1")
+	require.NoError(t, err)
+	assert.Equal(t, &model.ClickStats{}, stats)
+
+	require.NoError(t, repo.InsertList(ctx, []*model.Click{
+		{ShortID: "short1", ClickedAt: day, IP: "192.0.2.1", Referrer: "https://ref.com", UserAgent: "agent", Country: "DE"},
+		{ShortID: "short1", ClickedAt: day.Add(10 * time.Minute), IP: "192.0.2.1"},
+		{ShortID: "short1", ClickedAt: day.Add(time.Hour), IP: "192.0.2.2"},
+		{ShortID: "short2", ClickedAt: day, IP: "192.0.2.3"},
+	}))
+
+	stats, err = repo.GetStats(ctx, "short1")
+	require.NoError(t, err)
+	assert.Equal(t, &model.ClickStats{
+		TotalClicks:    3,
+		UniqueVisitors: 2,
+		Daily: []model.DailyClicks{
+			{Date: time.Date(2024, time.November, 17, 0, 0, 0, 0, time.UTC), Clicks: 2, UniqueVisitors: 1},
+			{Date: time.Date(2024, time.November, 18, 0, 0, 0, 0, time.UTC), Clicks: 1, UniqueVisitors: 1},
+		},
+	}, stats)
+}
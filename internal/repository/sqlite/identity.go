@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// IdentityRepository represents a repository for the accounts linked to users in an SQLite database.
+type IdentityRepository struct {
+	db *sql.DB
+}
+
+// NewIdentityRepository creates a new instance of IdentityRepository with the provided database.
+func NewIdentityRepository(db *sql.DB) interfaces.IIdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+// FindOrInsert inserts the identity unless its account is already linked and returns the stored identity.
+func (r *IdentityRepository) FindOrInsert(ctx context.Context, identity *model.UserIdentity) (*model.UserIdentity, error) {
+	query := `
+		INSERT INTO user_identities (issuer, subject, user_id, email, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5)
+		ON CONFLICT (issuer, subject) DO NOTHING`
+	_, err := r.db.ExecContext(ctx, query, identity.Issuer, identity.Subject, identity.UserID, identity.Email,
+		formatTime(identity.CreatedAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert identity: %v", err)
+	}
+	query = `
+		SELECT issuer, subject, user_id, email, created_at FROM user_identities
+		WHERE issuer = ?1 AND subject = ?2`
+	stored, err := scanIdentity(r.db.QueryRowContext(ctx, query, identity.Issuer, identity.Subject))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find identity: %v", err)
+	}
+	return stored, nil
+}
+
+// FindByUserID finds an identity linked to the user. Returns the identity if found, otherwise returns nil.
+func (r *IdentityRepository) FindByUserID(ctx context.Context, userID string) (*model.UserIdentity, error) {
+	query := `
+		SELECT issuer, subject, user_id, email, created_at FROM user_identities
+		WHERE user_id = ?1
+		LIMIT 1`
+	identity, err := scanIdentity(r.db.QueryRowContext(ctx, query, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return identity, nil
+}
+
+// scanIdentity scans a row with all identity columns in table order.
+func scanIdentity(row interface{ Scan(dest ...any) error }) (*model.UserIdentity, error) {
+	identity := &model.UserIdentity{}
+	var createdAt string
+	if err := row.Scan(&identity.Issuer, &identity.Subject, &identity.UserID, &identity.Email, &createdAt); err != nil {
+		return nil, err
+	}
+	var err error
+	if identity.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
@@ -0,0 +1,15 @@
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+	"github.com/GlebRadaev/shlink/internal/repository/sqlite"
+)
+
+func TestIdentityRepository_Conformance(t *testing.T) {
+	repotest.RunIdentityRepositorySuite(t, func(t *testing.T) interfaces.IIdentityRepository {
+		return sqlite.NewIdentityRepository(setupDB(t))
+	})
+}
@@ -0,0 +1,65 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+)
+
+// JobLockRepository represents a repository for the locks of the recurring jobs in an SQLite database.
+type JobLockRepository struct {
+	db *sql.DB
+}
+
+// NewJobLockRepository creates a new instance of JobLockRepository with the provided database.
+func NewJobLockRepository(db *sql.DB) interfaces.IJobLockRepository {
+	return &JobLockRepository{db: db}
+}
+
+// Acquire takes the lock of the job for the run at runAt unless it is held or the run was taken.
+func (r *JobLockRepository) Acquire(ctx context.Context, name, owner string, runAt, now time.Time, lease time.Duration) (bool, error) {
+	query := `
+		INSERT INTO scheduled_jobs (name, last_run_at, locked_by, locked_until)
+		VALUES (?1, ?2, ?3, ?4)
+		ON CONFLICT (name) DO UPDATE
+		SET last_run_at = excluded.last_run_at, locked_by = excluded.locked_by, locked_until = excluded.locked_until
+		WHERE scheduled_jobs.last_run_at < excluded.last_run_at AND scheduled_jobs.locked_until <= ?5
+		RETURNING name`
+	var acquired string
+	err := r.db.QueryRowContext(ctx, query, name, formatTime(runAt), owner, formatTime(now.Add(lease)),
+		formatTime(now)).Scan(&acquired)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to acquire job lock: %v", err)
+	}
+	return true, nil
+}
+
+// Extend moves the expiry of the lock of the job held by owner to until.
+func (r *JobLockRepository) Extend(ctx context.Context, name, owner string, until time.Time) (bool, error) {
+	query := `UPDATE scheduled_jobs SET locked_until = ?3 WHERE name = ?1 AND locked_by = ?2`
+	result, err := r.db.ExecContext(ctx, query, name, owner, formatTime(until))
+	if err != nil {
+		return false, fmt.Errorf("failed to extend job lock: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to extend job lock: %v", err)
+	}
+	return affected > 0, nil
+}
+
+// Release releases the lock of the job held by owner.
+func (r *JobLockRepository) Release(ctx context.Context, name, owner string, now time.Time) error {
+	query := `UPDATE scheduled_jobs SET locked_until = ?3 WHERE name = ?1 AND locked_by = ?2`
+	if _, err := r.db.ExecContext(ctx, query, name, owner, formatTime(now)); err != nil {
+		return fmt.Errorf("failed to release job lock: %v", err)
+	}
+	return nil
+}
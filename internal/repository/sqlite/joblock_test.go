@@ -0,0 +1,15 @@
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+	"github.com/GlebRadaev/shlink/internal/repository/sqlite"
+)
+
+func TestJobLockRepository_Conformance(t *testing.T) {
+	repotest.RunJobLockRepositorySuite(t, func(t *testing.T) interfaces.IJobLockRepository {
+		return sqlite.NewJobLockRepository(setupDB(t))
+	})
+}
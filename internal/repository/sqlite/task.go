@@ -0,0 +1,274 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// TaskRepository represents a repository for the durable task queue in an SQLite database.
+// SQLite serializes writes, so a task is claimed by a single UPDATE without row locks.
+type TaskRepository struct {
+	db *sql.DB
+}
+
+// NewTaskRepository creates a new instance of TaskRepository with the provided database.
+func NewTaskRepository(db *sql.DB) interfaces.ITaskRepository {
+	return &TaskRepository{db: db}
+}
+
+// Push adds a task to the queue and sets its ID and fair time, as insertTask does.
+func (r *TaskRepository) Push(ctx context.Context, task *model.QueuedTask) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertTask(ctx, tx, task); err != nil {
+		return fmt.Errorf("failed to push task: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// insertTask inserts the task and sets its ID and fair time. The fair time of a task with a fair key
+// is moved forward by as much as the latest fair time of the queued tasks of the key is after its
+// run time.
+func insertTask(ctx context.Context, tx *sql.Tx, task *model.QueuedTask) error {
+	if task.FairKey != "" {
+		var last sql.NullString
+		err := tx.QueryRowContext(ctx, `SELECT MAX(fair_at) FROM tasks WHERE fair_key = ?1`, task.FairKey).Scan(&last)
+		if err != nil {
+			return err
+		}
+		if last.Valid {
+			lastAt, err := parseTime(last.String)
+			if err != nil {
+				return err
+			}
+			task.FairAt = task.FairAt.Add(max(0, lastAt.Sub(task.RunAt)))
+		}
+	}
+	query := `
+		INSERT INTO tasks (type, payload, trace_parent, attempts, run_at, last_error, created_at, priority, fair_key, fair_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10)
+		RETURNING id`
+	return tx.QueryRowContext(ctx, query, task.Type, string(task.Payload), task.TraceParent, task.Attempts,
+		formatTime(task.RunAt), task.LastError, formatTime(task.CreatedAt), task.Priority, task.FairKey,
+		formatTime(task.FairAt)).Scan(&task.ID)
+}
+
+// Claim takes the due task at now of the highest priority and the earliest fair time that is not of
+// one of skipTypes nor of one of skipKeys, counts the attempt and moves its run_at past the lease. The
+// skipped types and keys are passed as JSON arrays read by json_each.
+func (r *TaskRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, skipTypes, skipKeys []string) (*model.QueuedTask, error) {
+	if skipTypes == nil {
+		skipTypes = []string{}
+	}
+	if skipKeys == nil {
+		skipKeys = []string{}
+	}
+	skippedTypes, err := json.Marshal(skipTypes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim task: %v", err)
+	}
+	skippedKeys, err := json.Marshal(skipKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim task: %v", err)
+	}
+	query := `
+		UPDATE tasks SET attempts = attempts + 1, run_at = ?2
+		WHERE id = (
+			SELECT id FROM tasks WHERE run_at <= ?1 AND type NOT IN (SELECT value FROM json_each(?3))
+				AND fair_key NOT IN (SELECT value FROM json_each(?4))
+			ORDER BY priority DESC, fair_at, id
+			LIMIT 1)
+		RETURNING ` + taskFields
+	task, err := scanQueuedTask(r.db.QueryRowContext(ctx, query, formatTime(now), formatTime(now.Add(lease)),
+		string(skippedTypes), string(skippedKeys)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim task: %v", err)
+	}
+	return task, nil
+}
+
+// Count returns the number of rows of the tasks table.
+func (r *TaskRepository) Count(ctx context.Context) (int, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tasks`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count tasks: %v", err)
+	}
+	return count, nil
+}
+
+// Extend sets the run_at of a claimed task to until.
+func (r *TaskRepository) Extend(ctx context.Context, id int64, until time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `UPDATE tasks SET run_at = ?2 WHERE id = ?1`, id, formatTime(until))
+	if err != nil {
+		return false, fmt.Errorf("failed to extend task lease: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to extend task lease: %v", err)
+	}
+	return affected > 0, nil
+}
+
+// Complete deletes a claimed task.
+func (r *TaskRepository) Complete(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM tasks WHERE id = ?1`, id); err != nil {
+		return fmt.Errorf("failed to complete task: %v", err)
+	}
+	return nil
+}
+
+// Retry sets the run_at of a claimed task to runAt and records the error of the attempt.
+func (r *TaskRepository) Retry(ctx context.Context, id int64, runAt time.Time, lastError string) error {
+	query := `UPDATE tasks SET run_at = ?2, last_error = ?3 WHERE id = ?1`
+	if _, err := r.db.ExecContext(ctx, query, id, formatTime(runAt), lastError); err != nil {
+		return fmt.Errorf("failed to retry task: %v", err)
+	}
+	return nil
+}
+
+// Release sets the run_at of a claimed task to runAt and takes back the attempt counted by its claim.
+func (r *TaskRepository) Release(ctx context.Context, id int64, runAt time.Time) error {
+	query := `UPDATE tasks SET run_at = ?2, attempts = MAX(attempts - 1, 0) WHERE id = ?1`
+	if _, err := r.db.ExecContext(ctx, query, id, formatTime(runAt)); err != nil {
+		return fmt.Errorf("failed to release task: %v", err)
+	}
+	return nil
+}
+
+// Bury copies a claimed task into the dead letters and deletes it inside a single transaction.
+func (r *TaskRepository) Bury(ctx context.Context, id int64, lastError string, failedAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO dead_tasks (type, payload, attempts, last_error, created_at, failed_at, priority, fair_key)
+		SELECT type, payload, attempts, ?2, created_at, ?3, priority, fair_key FROM tasks WHERE id = ?1`
+	if _, err := tx.ExecContext(ctx, query, id, lastError, formatTime(failedAt)); err != nil {
+		return fmt.Errorf("failed to bury task: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM tasks WHERE id = ?1`, id); err != nil {
+		return fmt.Errorf("failed to bury task: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// FindDeadList finds the dead letters, newest first.
+func (r *TaskRepository) FindDeadList(ctx context.Context, limit int) ([]*model.DeadTask, error) {
+	query := `
+		SELECT id, type, payload, attempts, last_error, created_at, failed_at, priority, fair_key FROM dead_tasks
+		ORDER BY id DESC`
+	var args []any
+	if limit > 0 {
+		query += " LIMIT ?1"
+		args = append(args, limit)
+	}
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find dead tasks: %v", err)
+	}
+	defer rows.Close()
+
+	var tasks []*model.DeadTask
+	for rows.Next() {
+		task := &model.DeadTask{}
+		var payload, createdAt, failedAt string
+		if err := rows.Scan(&task.ID, &task.Type, &payload, &task.Attempts, &task.LastError, &createdAt, &failedAt,
+			&task.Priority, &task.FairKey); err != nil {
+			return nil, fmt.Errorf("failed to scan dead task: %v", err)
+		}
+		task.Payload = []byte(payload)
+		if task.CreatedAt, err = parseTime(createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan dead task: %v", err)
+		}
+		if task.FailedAt, err = parseTime(failedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan dead task: %v", err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find dead tasks: %v", err)
+	}
+	return tasks, nil
+}
+
+// Replay deletes a dead letter and inserts it back into the queue inside a single transaction. The
+// replayed task keeps its priority and fair key, and its fair time is its run time, moved forward as
+// in Push.
+func (r *TaskRepository) Replay(ctx context.Context, id int64, runAt time.Time) (*model.QueuedTask, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	task := &model.QueuedTask{RunAt: runAt, FairAt: runAt}
+	var payload, createdAt string
+	err = tx.QueryRowContext(ctx, `DELETE FROM dead_tasks WHERE id = ?1 RETURNING type, payload, created_at, priority, fair_key`, id).
+		Scan(&task.Type, &payload, &createdAt, &task.Priority, &task.FairKey)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to replay task: %v", err)
+	}
+	task.Payload = []byte(payload)
+	if task.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, fmt.Errorf("failed to replay task: %v", err)
+	}
+	if err := insertTask(ctx, tx, task); err != nil {
+		return nil, fmt.Errorf("failed to replay task: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return task, nil
+}
+
+// taskFields are the columns of the tasks table read by scanQueuedTask.
+const taskFields = `id, type, payload, trace_parent, attempts, run_at, last_error, created_at, priority, fair_key, fair_at`
+
+// scanQueuedTask scans a row of the tasks table made of taskFields, returning sql.ErrNoRows if there
+// is none.
+func scanQueuedTask(row *sql.Row) (*model.QueuedTask, error) {
+	task := &model.QueuedTask{}
+	var payload, runAt, createdAt, fairAt string
+	err := row.Scan(&task.ID, &task.Type, &payload, &task.TraceParent, &task.Attempts, &runAt, &task.LastError,
+		&createdAt, &task.Priority, &task.FairKey, &fairAt)
+	if err != nil {
+		return nil, err
+	}
+	task.Payload = []byte(payload)
+	if task.RunAt, err = parseTime(runAt); err != nil {
+		return nil, err
+	}
+	if task.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if task.FairAt, err = parseTime(fairAt); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
@@ -0,0 +1,15 @@
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+	"github.com/GlebRadaev/shlink/internal/repository/sqlite"
+)
+
+func TestTaskRepository_Conformance(t *testing.T) {
+	repotest.RunTaskRepositorySuite(t, func(t *testing.T) interfaces.ITaskRepository {
+		return sqlite.NewTaskRepository(setupDB(t))
+	})
+}
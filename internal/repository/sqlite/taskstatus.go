@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// TaskStatusRepository represents a repository for the states of the tasks of users in an SQLite database.
+// The items of a task are stored as a JSON array.
+type TaskStatusRepository struct {
+	db *sql.DB
+}
+
+// NewTaskStatusRepository creates a new instance of TaskStatusRepository with the provided database.
+func NewTaskStatusRepository(db *sql.DB) interfaces.ITaskStatusRepository {
+	return &TaskStatusRepository{db: db}
+}
+
+// Insert adds the status of a new task.
+func (r *TaskStatusRepository) Insert(ctx context.Context, status *model.TaskStatus) error {
+	items, err := json.Marshal(status.Items)
+	if err != nil {
+		return fmt.Errorf("failed to insert task status: %v", err)
+	}
+	query := `
+		INSERT INTO task_statuses (id, user_id, type, state, items, error, created_at, updated_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8)`
+	_, err = r.db.ExecContext(ctx, query, status.ID, status.UserID, status.Type, status.State, string(items),
+		status.Error, formatTime(status.CreatedAt), formatTime(status.UpdatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to insert task status: %v", err)
+	}
+	return nil
+}
+
+// FindByID finds the status of a task by its ID. Returns the status if found, otherwise returns nil.
+func (r *TaskStatusRepository) FindByID(ctx context.Context, id string) (*model.TaskStatus, error) {
+	query := `
+		SELECT id, user_id, type, state, items, error, created_at, updated_at FROM task_statuses
+		WHERE id = ?1`
+	status := &model.TaskStatus{}
+	var items, createdAt, updatedAt string
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&status.ID, &status.UserID, &status.Type, &status.State,
+		&items, &status.Error, &createdAt, &updatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find task status: %v", err)
+	}
+	if err := json.Unmarshal([]byte(items), &status.Items); err != nil {
+		return nil, fmt.Errorf("failed to scan task status: %v", err)
+	}
+	if status.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, fmt.Errorf("failed to scan task status: %v", err)
+	}
+	if status.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return nil, fmt.Errorf("failed to scan task status: %v", err)
+	}
+	return status, nil
+}
+
+// Update replaces the state, items, error and update time of a task.
+func (r *TaskStatusRepository) Update(ctx context.Context, status *model.TaskStatus) error {
+	items, err := json.Marshal(status.Items)
+	if err != nil {
+		return fmt.Errorf("failed to update task status: %v", err)
+	}
+	query := `UPDATE task_statuses SET state = ?2, items = ?3, error = ?4, updated_at = ?5 WHERE id = ?1`
+	_, err = r.db.ExecContext(ctx, query, status.ID, status.State, string(items), status.Error, formatTime(status.UpdatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to update task status: %v", err)
+	}
+	return nil
+}
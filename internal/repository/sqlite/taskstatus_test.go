@@ -0,0 +1,15 @@
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+	"github.com/GlebRadaev/shlink/internal/repository/sqlite"
+)
+
+func TestTaskStatusRepository_Conformance(t *testing.T) {
+	repotest.RunTaskStatusRepositorySuite(t, func(t *testing.T) interfaces.ITaskStatusRepository {
+		return sqlite.NewTaskStatusRepository(setupDB(t))
+	})
+}
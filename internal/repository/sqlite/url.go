@@ -0,0 +1,392 @@
+// Package sqlite implements the database logic for managing URL data in an SQLite database.
+// It mirrors the PostgreSQL repositories of the database package for single-node deployments,
+// using database/sql with the go-sqlite3 driver.
+//
+// Timestamps are stored as UTC text in a fixed layout, so they compare and sort correctly as strings.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/mattn/go-sqlite3"
+)
+
+// timeLayout is the layout of stored timestamps; the fixed fraction keeps them sortable as text.
+const timeLayout = "2006-01-02 15:04:05.000000000"
+
+// Columns reported in unique constraint violations.
+const (
+	shortIDColumn     = "urls.short_id"
+	originalURLColumn = "urls.original_url"
+)
+
+// querier is the part of *sql.DB and *sql.Tx used by the repositories.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// URLRepository represents a repository for URL data in an SQLite database.
+type URLRepository struct {
+	db *sql.DB
+}
+
+// NewURLRepository creates a new instance of URLRepository with the provided database.
+func NewURLRepository(db *sql.DB) interfaces.IURLRepository {
+	return &URLRepository{db: db}
+}
+
+// Insert inserts a new URL into the database, or returns the existing one based on the original URL.
+// If the short ID belongs to another URL, the returned error wraps interfaces.ErrShortIDTaken.
+func (r *URLRepository) Insert(ctx context.Context, url *model.URL) (*model.URL, error) {
+	return r.insert(ctx, r.db, url)
+}
+
+// insert inserts a URL using the given database or transaction.
+func (r *URLRepository) insert(ctx context.Context, q querier, url *model.URL) (*model.URL, error) {
+	query := `
+		INSERT INTO urls (short_id, original_url, user_id, created_at, expires_at, max_clicks, password_hash)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7)
+		ON CONFLICT (original_url) DO UPDATE
+		SET short_id = urls.short_id
+		RETURNING id, short_id, original_url, user_id, created_at`
+	createdAt := url.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	var created string
+	err := q.QueryRowContext(ctx, query, url.ShortID, url.OriginalURL, url.UserID, formatTime(createdAt),
+		formatNullTime(url.ExpiresAt), url.MaxClicks, url.PasswordHash).
+		Scan(&url.ID, &url.ShortID, &url.OriginalURL, &url.UserID, &created)
+	if err != nil {
+		if isUniqueViolation(err, shortIDColumn) {
+			return nil, fmt.Errorf("failed to insert URL: %w", interfaces.ErrShortIDTaken)
+		}
+		return nil, fmt.Errorf("failed to insert URL: %v", err)
+	}
+	if url.CreatedAt, err = parseTime(created); err != nil {
+		return nil, fmt.Errorf("failed to insert URL: %v", err)
+	}
+	return url, nil
+}
+
+// InsertList inserts a list of URLs into the database inside a single transaction.
+func (r *URLRepository) InsertList(ctx context.Context, urls []*model.URL) ([]*model.URL, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var result []*model.URL
+	for _, url := range urls {
+		insertedURL, err := r.insert(ctx, tx, url)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, insertedURL)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return result, nil
+}
+
+// FindByID finds a URL by its short ID. Returns the URL if found, otherwise returns nil.
+func (r *URLRepository) FindByID(ctx context.Context, shortID string) (*model.URL, error) {
+	query := `
+		SELECT id, short_id, original_url, user_id, created_at, is_deleted, is_disabled, expires_at, max_clicks, clicks, password_hash FROM urls
+		WHERE short_id = ?1`
+	url, err := scanURL(r.db.QueryRowContext(ctx, query, shortID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return url, nil
+}
+
+// FindListByUserID finds the URLs of a specific user matching the query. The filters, the cursor
+// and the order are translated into SQL, using keyset pagination on the sort key and short ID.
+func (r *URLRepository) FindListByUserID(ctx context.Context, userID string, q model.URLListQuery) ([]*model.URL, error) {
+	query := `
+		SELECT id, short_id, original_url, user_id, created_at, is_deleted, is_disabled, expires_at, max_clicks, clicks, password_hash FROM urls
+		WHERE user_id = ?1`
+	args := []any{userID}
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("?%d", len(args))
+	}
+	if q.CreatedFrom != nil {
+		query += " AND created_at >= " + arg(formatTime(*q.CreatedFrom))
+	}
+	if q.CreatedTo != nil {
+		query += " AND created_at < " + arg(formatTime(*q.CreatedTo))
+	}
+	if q.Deleted != nil {
+		query += " AND is_deleted = " + arg(*q.Deleted)
+	}
+	if q.Search != "" {
+		query += " AND instr(lower(original_url), lower(" + arg(q.Search) + ")) > 0"
+	}
+	column, direction, operator := "created_at", "ASC", ">"
+	if q.Sort.ByOriginalURL() {
+		column = "original_url"
+	}
+	if q.Sort.Descending() {
+		direction, operator = "DESC", "<"
+	}
+	if q.After != nil {
+		var value any = formatTime(q.After.CreatedAt)
+		if q.Sort.ByOriginalURL() {
+			value = q.After.OriginalURL
+		}
+		query += fmt.Sprintf(" AND (%s, short_id) %s (%s, %s)", column, operator, arg(value), arg(q.After.ShortID))
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, short_id %s", column, direction, direction)
+	if q.Limit > 0 {
+		query += " LIMIT " + arg(q.Limit)
+	}
+	return r.queryURLs(ctx, query, args...)
+}
+
+// UpdateOriginalURL changes the original URL of a non-deleted URL owned by the user.
+// It returns false if no such URL exists. If the new original URL is already shortened
+// under another short ID, the returned error wraps interfaces.ErrOriginalURLTaken.
+func (r *URLRepository) UpdateOriginalURL(ctx context.Context, userID, shortID, originalURL string) (bool, error) {
+	query := `
+		UPDATE urls
+		SET original_url = ?3
+		WHERE short_id = ?1 AND user_id = ?2 AND is_deleted = false`
+	result, err := r.db.ExecContext(ctx, query, shortID, userID, originalURL)
+	if err != nil {
+		if isUniqueViolation(err, originalURLColumn) {
+			return false, fmt.Errorf("failed to update URL: %w", interfaces.ErrOriginalURLTaken)
+		}
+		return false, fmt.Errorf("failed to update URL: %v", err)
+	}
+	return rowsAffected(result) > 0, nil
+}
+
+// ReassignUserID moves the non-deleted URLs of a user to another user and returns how many were moved.
+func (r *URLRepository) ReassignUserID(ctx context.Context, fromUserID, toUserID string) (int64, error) {
+	query := `
+		UPDATE urls
+		SET user_id = ?2
+		WHERE user_id = ?1 AND is_deleted = false`
+	result, err := r.db.ExecContext(ctx, query, fromUserID, toUserID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reassign urls: %v", err)
+	}
+	return rowsAffected(result), nil
+}
+
+// SetDisabled marks the URLs with the short IDs as disabled or enabled again and returns how many changed.
+func (r *URLRepository) SetDisabled(ctx context.Context, shortIDs []string, disabled bool) (int64, error) {
+	if len(shortIDs) == 0 {
+		return 0, nil
+	}
+	args := []any{disabled}
+	placeholders := make([]string, len(shortIDs))
+	for i, shortID := range shortIDs {
+		args = append(args, shortID)
+		placeholders[i] = fmt.Sprintf("?%d", len(args))
+	}
+	query := `
+		UPDATE urls
+		SET is_disabled = ?1
+		WHERE is_disabled <> ?1 AND short_id IN (` + strings.Join(placeholders, ", ") + `)`
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to set disabled: %v", err)
+	}
+	return rowsAffected(result), nil
+}
+
+// FindListByDomain finds the non-deleted URLs whose original URL points to the domain or one of
+// its subdomains. The query narrows the URLs down by text; the host is checked with model.URL.InDomain.
+func (r *URLRepository) FindListByDomain(ctx context.Context, domain string) ([]*model.URL, error) {
+	query := `
+		SELECT id, short_id, original_url, user_id, created_at, is_deleted, is_disabled, expires_at, max_clicks, clicks, password_hash FROM urls
+		WHERE is_deleted = false AND instr(lower(original_url), lower(?1)) > 0`
+	urls, err := r.queryURLs(ctx, query, domain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find URLs by domain: %v", err)
+	}
+	return filterDomain(urls, domain), nil
+}
+
+// IncrementClicks atomically increases the click counter of a URL unless its click budget is used up.
+// It returns false if no clicks are left for the URL.
+func (r *URLRepository) IncrementClicks(ctx context.Context, shortID string) (bool, error) {
+	query := `
+		UPDATE urls
+		SET clicks = clicks + 1
+		WHERE short_id = ?1 AND (max_clicks IS NULL OR clicks < max_clicks)`
+	result, err := r.db.ExecContext(ctx, query, shortID)
+	if err != nil {
+		return false, fmt.Errorf("failed to increment clicks: %v", err)
+	}
+	return rowsAffected(result) > 0, nil
+}
+
+// DeleteExpired soft deletes URLs whose expiration date has passed or whose click budget is used up.
+func (r *URLRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	query := `
+		UPDATE urls
+		SET is_deleted = true
+		WHERE is_deleted = false
+		  AND ((expires_at IS NOT NULL AND expires_at <= ?1)
+		    OR (max_clicks IS NOT NULL AND clicks >= max_clicks))`
+	result, err := r.db.ExecContext(ctx, query, formatTime(now))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired urls: %v", err)
+	}
+	return rowsAffected(result), nil
+}
+
+// List retrieves all URLs in the database. It returns a list of all URLs stored.
+func (r *URLRepository) List(ctx context.Context) ([]*model.URL, error) {
+	query := `
+		SELECT id, short_id, original_url, user_id, created_at, is_deleted, is_disabled, expires_at, max_clicks, clicks, password_hash
+		FROM urls`
+	urls, err := r.queryURLs(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find URLs: %v", err)
+	}
+	return urls, nil
+}
+
+// Ping checks if the database is reachable by executing a simple query.
+func (r *URLRepository) Ping(ctx context.Context) error {
+	var result int
+	return r.db.QueryRowContext(ctx, `SELECT 1`).Scan(&result)
+}
+
+// DeleteListByUserIDAndShortIDs soft deletes URLs by marking them as deleted based on userID and shortID list.
+func (r *URLRepository) DeleteListByUserIDAndShortIDs(ctx context.Context, userID string, shortIDs []string) error {
+	if len(shortIDs) == 0 {
+		return nil
+	}
+	placeholders := make([]string, len(shortIDs))
+	args := make([]any, 0, len(shortIDs)+1)
+	args = append(args, userID)
+	for i, shortID := range shortIDs {
+		args = append(args, shortID)
+		placeholders[i] = fmt.Sprintf("?%d", len(args))
+	}
+	query := `
+		UPDATE urls
+		SET is_deleted = true
+		WHERE user_id = ?1 AND short_id IN (` + strings.Join(placeholders, ", ") + `)`
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to delete short urls for user: %w", err)
+	}
+	return nil
+}
+
+// queryURLs runs a query selecting all URL columns and scans the resulting rows.
+func (r *URLRepository) queryURLs(ctx context.Context, query string, args ...any) ([]*model.URL, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var urls []*model.URL
+	for rows.Next() {
+		url, err := scanURL(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan URL data: %v", err)
+		}
+		urls = append(urls, url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+// scanURL scans a row with all URL columns in table order.
+func scanURL(row interface{ Scan(dest ...any) error }) (*model.URL, error) {
+	url := &model.URL{}
+	var createdAt string
+	var expiresAt sql.NullString
+	var maxClicks sql.NullInt64
+	err := row.Scan(&url.ID, &url.ShortID, &url.OriginalURL, &url.UserID, &createdAt, &url.DeletedFlag, &url.DisabledFlag,
+		&expiresAt, &maxClicks, &url.Clicks, &url.PasswordHash)
+	if err != nil {
+		return nil, err
+	}
+	if url.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		t, err := parseTime(expiresAt.String)
+		if err != nil {
+			return nil, err
+		}
+		url.ExpiresAt = &t
+	}
+	if maxClicks.Valid {
+		value := int(maxClicks.Int64)
+		url.MaxClicks = &value
+	}
+	return url, nil
+}
+
+// filterDomain returns the URLs pointing to the domain or one of its subdomains.
+func filterDomain(urls []*model.URL, domain string) []*model.URL {
+	result := urls[:0]
+	for _, url := range urls {
+		if url.InDomain(domain) {
+			result = append(result, url)
+		}
+	}
+	return result
+}
+
+// formatTime formats a timestamp for storage.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+// formatNullTime formats an optional timestamp for storage, using NULL if it is missing.
+func formatNullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+// parseTime parses a stored timestamp, including the ones set by CURRENT_TIMESTAMP.
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range []string{timeLayout, time.DateTime, time.RFC3339Nano} {
+		if t, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+}
+
+// isUniqueViolation reports whether the error is a violation of the unique constraint on the column.
+func isUniqueViolation(err error, column string) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique &&
+		strings.Contains(sqliteErr.Error(), column)
+}
+
+// rowsAffected returns the number of rows changed by a statement; the SQLite driver always reports it.
+func rowsAffected(result sql.Result) int64 {
+	count, _ := result.RowsAffected()
+	return count
+}
@@ -0,0 +1,269 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/repository"
+	"github.com/GlebRadaev/shlink/internal/repository/repotest"
+	"github.com/GlebRadaev/shlink/internal/repository/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupDB(t *testing.T) *sql.DB {
+	db, err := repository.OpenSQLite(context.Background(), filepath.Join(t.TempDir(), "shlink.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func setupRepository(t *testing.T) interfaces.IURLRepository {
+	return sqlite.NewURLRepository(setupDB(t))
+}
+
+func TestURLRepository_Insert(t *testing.T) {
+	ctx := context.Background()
+	repo := setupRepository(t)
+	expires := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	maxClicks := 10
+
+	url, err := repo.Insert(ctx, &model.URL{
+		ShortID: "abc123", OriginalURL: "http://example.com", UserID: "user123",
+		ExpiresAt: &expires, MaxClicks: &maxClicks, PasswordHash: "hash",
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, url.ID)
+	assert.WithinDuration(t, time.Now(), url.CreatedAt, time.Minute)
+
+	existing, err := repo.Insert(ctx, &model.URL{ShortID: "def456", OriginalURL: "http://example.com", UserID: "user123"})
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", existing.ShortID, "Expected the short ID of the already shortened URL")
+
+	_, err = repo.Insert(ctx, &model.URL{ShortID: "abc123", OriginalURL: "http://another.com", UserID: "user123"})
+	assert.ErrorIs(t, err, interfaces.ErrShortIDTaken)
+
+	found, err := repo.FindByID(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com", found.OriginalURL)
+	assert.Equal(t, "user123", found.UserID)
+	assert.Equal(t, expires, *found.ExpiresAt)
+	assert.Equal(t, maxClicks, *found.MaxClicks)
+	assert.Equal(t, "hash", found.PasswordHash)
+	assert.Equal(t, url.CreatedAt, found.CreatedAt)
+}
+
+func TestURLRepository_InsertList(t *testing.T) {
+	ctx := context.Background()
+	repo := setupRepository(t)
+
+	urls, err := repo.InsertList(ctx, []*model.URL{
+		{ShortID: "short1", OriginalURL: "http://example1.com", UserID: "user123"},
+		{ShortID: "short2", OriginalURL: "http://example2.com", UserID: "user123"},
+	})
+	require.NoError(t, err)
+	assert.Len(t, urls, 2)
+
+	_, err = repo.InsertList(ctx, []*model.URL{
+		{ShortID: "short3", OriginalURL: "http://example3.com", UserID: "user123"},
+		{ShortID: "short1", OriginalURL: "http://example4.com", UserID: "user123"},
+	})
+	assert.ErrorIs(t, err, interfaces.ErrShortIDTaken)
+	found, err := repo.FindByID(ctx, "short3")
+	require.NoError(t, err)
+	assert.Nil(t, found, "Expected the failed batch to be rolled back")
+}
+
+func TestURLRepository_FindByID(t *testing.T) {
+	ctx := context.Background()
+	repo := setupRepository(t)
+
+	found, err := repo.FindByID(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func TestURLRepository_List(t *testing.T) {
+	ctx := context.Background()
+	repo := setupRepository(t)
+
+	_, err := repo.InsertList(ctx, []*model.URL{
+		{ShortID: "abc123", OriginalURL: "http://example.com", UserID: "user123"},
+		{ShortID: "xyz789", OriginalURL: "http://another-example.com", UserID: "user456"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteListByUserIDAndShortIDs(ctx, "user456", []string{"xyz789"}))
+
+	urls, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, urls, 2)
+	deleted := map[string]bool{}
+	for _, url := range urls {
+		deleted[url.ShortID] = url.DeletedFlag
+	}
+	assert.Equal(t, map[string]bool{"abc123": false, "xyz789": true}, deleted)
+}
+
+func TestURLRepository_Ping(t *testing.T) {
+	ctx := context.Background()
+	db := setupDB(t)
+	repo := sqlite.NewURLRepository(db)
+
+	assert.NoError(t, repo.Ping(ctx))
+	db.Close()
+	assert.Error(t, repo.Ping(ctx))
+}
+
+func TestURLRepository_ListByUserID(t *testing.T) {
+	ctx := context.Background()
+	repo := setupRepository(t)
+	day := time.Date(2024, time.November, 1, 0, 0, 0, 0, time.UTC)
+	_, err := repo.InsertList(ctx, []*model.URL{
+		{ShortID: "a1", OriginalURL: "http://b.example.com", UserID: "user123", CreatedAt: day},
+		{ShortID: "a2", OriginalURL: "http://a.example.com", UserID: "user123", CreatedAt: day.AddDate(0, 0, 1)},
+		{ShortID: "a3", OriginalURL: "http://c.other.com", UserID: "user123", CreatedAt: day.AddDate(0, 0, 2)},
+		{ShortID: "a4", OriginalURL: "http://d.example.com", UserID: "user123", CreatedAt: day.AddDate(0, 0, 2)},
+		{ShortID: "b1", OriginalURL: "http://e.example.com", UserID: "user456", CreatedAt: day},
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteListByUserIDAndShortIDs(ctx, "user123", []string{"a2"}))
+	from, to := day.AddDate(0, 0, 1), day.AddDate(0, 0, 2)
+	active, deleted := false, true
+
+	tests := []struct {
+		name  string
+		query model.URLListQuery
+		want  []string
+	}{
+		{name: "Default order", query: model.URLListQuery{}, want: []string{"a4", "a3", "a2", "a1"}},
+		{name: "Created at ascending", query: model.URLListQuery{Sort: model.SortCreatedAtAsc}, want: []string{"a1", "a2", "a3", "a4"}},
+		{name: "Original URL ascending", query: model.URLListQuery{Sort: model.SortOriginalURLAsc}, want: []string{"a2", "a1", "a3", "a4"}},
+		{name: "Original URL descending", query: model.URLListQuery{Sort: model.SortOriginalURLDesc}, want: []string{"a4", "a3", "a1", "a2"}},
+		{name: "Created range", query: model.URLListQuery{CreatedFrom: &from, CreatedTo: &to}, want: []string{"a2"}},
+		{name: "Active only", query: model.URLListQuery{Deleted: &active}, want: []string{"a4", "a3", "a1"}},
+		{name: "Deleted only", query: model.URLListQuery{Deleted: &deleted}, want: []string{"a2"}},
+		{name: "Search ignores case", query: model.URLListQuery{Search: "EXAMPLE"}, want: []string{"a4", "a2", "a1"}},
+		{name: "Limit", query: model.URLListQuery{Limit: 2}, want: []string{"a4", "a3"}},
+		{
+			name:  "After cursor with tie on created at",
+			query: model.URLListQuery{After: &model.URLCursor{CreatedAt: day.AddDate(0, 0, 2), ShortID: "a4"}, Limit: 2},
+			want:  []string{"a3", "a2"},
+		},
+		{
+			name:  "After cursor by original URL",
+			query: model.URLListQuery{Sort: model.SortOriginalURLAsc, After: &model.URLCursor{OriginalURL: "http://b.example.com", ShortID: "a1"}},
+			want:  []string{"a3", "a4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls, err := repo.FindListByUserID(ctx, "user123", tt.query)
+			require.NoError(t, err)
+			got := make([]string, 0, len(urls))
+			for _, url := range urls {
+				got = append(got, url.ShortID)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestURLRepository_DeleteListByUserIDAndShortIDs(t *testing.T) {
+	ctx := context.Background()
+	repo := setupRepository(t)
+	_, err := repo.InsertList(ctx, []*model.URL{
+		{ShortID: "short1", OriginalURL: "http://example1.com", UserID: "user123"},
+		{ShortID: "short2", OriginalURL: "http://example2.com", UserID: "user456"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.DeleteListByUserIDAndShortIDs(ctx, "user123", []string{"short1", "short2"}))
+	require.NoError(t, repo.DeleteListByUserIDAndShortIDs(ctx, "user123", nil))
+
+	short1, err := repo.FindByID(ctx, "short1")
+	require.NoError(t, err)
+	assert.True(t, short1.DeletedFlag)
+	short2, err := repo.FindByID(ctx, "short2")
+	require.NoError(t, err)
+	assert.False(t, short2.DeletedFlag, "Expected URLs of other users to be kept")
+}
+
+func TestURLRepository_UpdateOriginalURL(t *testing.T) {
+	ctx := context.Background()
+	repo := setupRepository(t)
+	_, err := repo.InsertList(ctx, []*model.URL{
+		{ShortID: "short1", OriginalURL: "http://example1.com", UserID: "user123"},
+		{ShortID: "short2", OriginalURL: "http://example2.com", UserID: "user123"},
+	})
+	require.NoError(t, err)
+
+	updated, err := repo.UpdateOriginalURL(ctx, "user123", "short1", "http://new.com")
+	require.NoError(t, err)
+	assert.True(t, updated)
+	found, err := repo.FindByID(ctx, "short1")
+	require.NoError(t, err)
+	assert.Equal(t, "http://new.com", found.OriginalURL)
+
+	updated, err = repo.UpdateOriginalURL(ctx, "user456", "short1", "http://other.com")
+	require.NoError(t, err)
+	assert.False(t, updated, "Expected URLs of other users not to be updated")
+
+	_, err = repo.UpdateOriginalURL(ctx, "user123", "short1", "http://example2.com")
+	assert.ErrorIs(t, err, interfaces.ErrOriginalURLTaken)
+
+	require.NoError(t, repo.DeleteListByUserIDAndShortIDs(ctx, "user123", []string{"short2"}))
+	updated, err = repo.UpdateOriginalURL(ctx, "user123", "short2", "http://deleted.com")
+	require.NoError(t, err)
+	assert.False(t, updated, "Expected deleted URLs not to be updated")
+}
+
+func TestURLRepository_IncrementClicks(t *testing.T) {
+	ctx := context.Background()
+	repo := setupRepository(t)
+	maxClicks := 1
+	_, err := repo.Insert(ctx, &model.URL{ShortID: "short1", OriginalURL: "http://example1.com", MaxClicks: &maxClicks})
+	require.NoError(t, err)
+
+	ok, err := repo.IncrementClicks(ctx, "short1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = repo.IncrementClicks(ctx, "short1")
+	require.NoError(t, err)
+	assert.False(t, ok, "Expected the click budget to be used up")
+	ok, err = repo.IncrementClicks(ctx, "missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestURLRepository_DeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	repo := setupRepository(t)
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+	maxClicks := 1
+	_, err := repo.InsertList(ctx, []*model.URL{
+		{ShortID: "expired", OriginalURL: "http://example1.com", ExpiresAt: &past},
+		{ShortID: "active", OriginalURL: "http://example2.com", ExpiresAt: &future},
+		{ShortID: "clicked", OriginalURL: "http://example3.com", MaxClicks: &maxClicks},
+	})
+	require.NoError(t, err)
+	_, err = repo.IncrementClicks(ctx, "clicked")
+	require.NoError(t, err)
+
+	count, err := repo.DeleteExpired(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	active, err := repo.FindByID(ctx, "active")
+	require.NoError(t, err)
+	assert.False(t, active.DeletedFlag)
+}
+
+func TestURLRepository_Conformance(t *testing.T) {
+	repotest.RunURLRepositorySuite(t, setupRepository)
+}
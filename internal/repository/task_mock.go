@@ -0,0 +1,323 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/interfaces/task.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/interfaces/task.go -destination=internal/repository/task_mock.go -package=repository
+//
+
+// Package repository is a generated GoMock package.
+package repository
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	model "github.com/GlebRadaev/shlink/internal/model"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockITaskRepository is a mock of ITaskRepository interface.
+type MockITaskRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockITaskRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockITaskRepositoryMockRecorder is the mock recorder for MockITaskRepository.
+type MockITaskRepositoryMockRecorder struct {
+	mock *MockITaskRepository
+}
+
+// NewMockITaskRepository creates a new mock instance.
+func NewMockITaskRepository(ctrl *gomock.Controller) *MockITaskRepository {
+	mock := &MockITaskRepository{ctrl: ctrl}
+	mock.recorder = &MockITaskRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockITaskRepository) EXPECT() *MockITaskRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Bury mocks base method.
+func (m *MockITaskRepository) Bury(ctx context.Context, id int64, lastError string, failedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Bury", ctx, id, lastError, failedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Bury indicates an expected call of Bury.
+func (mr *MockITaskRepositoryMockRecorder) Bury(ctx, id, lastError, failedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Bury", reflect.TypeOf((*MockITaskRepository)(nil).Bury), ctx, id, lastError, failedAt)
+}
+
+// Claim mocks base method.
+func (m *MockITaskRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, skipTypes, skipKeys []string) (*model.QueuedTask, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Claim", ctx, now, lease, skipTypes, skipKeys)
+	ret0, _ := ret[0].(*model.QueuedTask)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Claim indicates an expected call of Claim.
+func (mr *MockITaskRepositoryMockRecorder) Claim(ctx, now, lease, skipTypes, skipKeys any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Claim", reflect.TypeOf((*MockITaskRepository)(nil).Claim), ctx, now, lease, skipTypes, skipKeys)
+}
+
+// Complete mocks base method.
+func (m *MockITaskRepository) Complete(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Complete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Complete indicates an expected call of Complete.
+func (mr *MockITaskRepositoryMockRecorder) Complete(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Complete", reflect.TypeOf((*MockITaskRepository)(nil).Complete), ctx, id)
+}
+
+// Count mocks base method.
+func (m *MockITaskRepository) Count(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockITaskRepositoryMockRecorder) Count(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockITaskRepository)(nil).Count), ctx)
+}
+
+// Extend mocks base method.
+func (m *MockITaskRepository) Extend(ctx context.Context, id int64, until time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Extend", ctx, id, until)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Extend indicates an expected call of Extend.
+func (mr *MockITaskRepositoryMockRecorder) Extend(ctx, id, until any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Extend", reflect.TypeOf((*MockITaskRepository)(nil).Extend), ctx, id, until)
+}
+
+// FindDeadList mocks base method.
+func (m *MockITaskRepository) FindDeadList(ctx context.Context, limit int) ([]*model.DeadTask, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDeadList", ctx, limit)
+	ret0, _ := ret[0].([]*model.DeadTask)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindDeadList indicates an expected call of FindDeadList.
+func (mr *MockITaskRepositoryMockRecorder) FindDeadList(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDeadList", reflect.TypeOf((*MockITaskRepository)(nil).FindDeadList), ctx, limit)
+}
+
+// Push mocks base method.
+func (m *MockITaskRepository) Push(ctx context.Context, task *model.QueuedTask) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Push", ctx, task)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Push indicates an expected call of Push.
+func (mr *MockITaskRepositoryMockRecorder) Push(ctx, task any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Push", reflect.TypeOf((*MockITaskRepository)(nil).Push), ctx, task)
+}
+
+// Release mocks base method.
+func (m *MockITaskRepository) Release(ctx context.Context, id int64, runAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx, id, runAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockITaskRepositoryMockRecorder) Release(ctx, id, runAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockITaskRepository)(nil).Release), ctx, id, runAt)
+}
+
+// Replay mocks base method.
+func (m *MockITaskRepository) Replay(ctx context.Context, id int64, runAt time.Time) (*model.QueuedTask, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replay", ctx, id, runAt)
+	ret0, _ := ret[0].(*model.QueuedTask)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Replay indicates an expected call of Replay.
+func (mr *MockITaskRepositoryMockRecorder) Replay(ctx, id, runAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replay", reflect.TypeOf((*MockITaskRepository)(nil).Replay), ctx, id, runAt)
+}
+
+// Retry mocks base method.
+func (m *MockITaskRepository) Retry(ctx context.Context, id int64, runAt time.Time, lastError string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Retry", ctx, id, runAt, lastError)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Retry indicates an expected call of Retry.
+func (mr *MockITaskRepositoryMockRecorder) Retry(ctx, id, runAt, lastError any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Retry", reflect.TypeOf((*MockITaskRepository)(nil).Retry), ctx, id, runAt, lastError)
+}
+
+// MockITaskStatusRepository is a mock of ITaskStatusRepository interface.
+type MockITaskStatusRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockITaskStatusRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockITaskStatusRepositoryMockRecorder is the mock recorder for MockITaskStatusRepository.
+type MockITaskStatusRepositoryMockRecorder struct {
+	mock *MockITaskStatusRepository
+}
+
+// NewMockITaskStatusRepository creates a new mock instance.
+func NewMockITaskStatusRepository(ctrl *gomock.Controller) *MockITaskStatusRepository {
+	mock := &MockITaskStatusRepository{ctrl: ctrl}
+	mock.recorder = &MockITaskStatusRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockITaskStatusRepository) EXPECT() *MockITaskStatusRepositoryMockRecorder {
+	return m.recorder
+}
+
+// FindByID mocks base method.
+func (m *MockITaskStatusRepository) FindByID(ctx context.Context, id string) (*model.TaskStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByID", ctx, id)
+	ret0, _ := ret[0].(*model.TaskStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByID indicates an expected call of FindByID.
+func (mr *MockITaskStatusRepositoryMockRecorder) FindByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByID", reflect.TypeOf((*MockITaskStatusRepository)(nil).FindByID), ctx, id)
+}
+
+// Insert mocks base method.
+func (m *MockITaskStatusRepository) Insert(ctx context.Context, status *model.TaskStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockITaskStatusRepositoryMockRecorder) Insert(ctx, status any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockITaskStatusRepository)(nil).Insert), ctx, status)
+}
+
+// Update mocks base method.
+func (m *MockITaskStatusRepository) Update(ctx context.Context, status *model.TaskStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockITaskStatusRepositoryMockRecorder) Update(ctx, status any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockITaskStatusRepository)(nil).Update), ctx, status)
+}
+
+// MockIJobLockRepository is a mock of IJobLockRepository interface.
+type MockIJobLockRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockIJobLockRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockIJobLockRepositoryMockRecorder is the mock recorder for MockIJobLockRepository.
+type MockIJobLockRepositoryMockRecorder struct {
+	mock *MockIJobLockRepository
+}
+
+// NewMockIJobLockRepository creates a new mock instance.
+func NewMockIJobLockRepository(ctrl *gomock.Controller) *MockIJobLockRepository {
+	mock := &MockIJobLockRepository{ctrl: ctrl}
+	mock.recorder = &MockIJobLockRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIJobLockRepository) EXPECT() *MockIJobLockRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Acquire mocks base method.
+func (m *MockIJobLockRepository) Acquire(ctx context.Context, name, owner string, runAt, now time.Time, lease time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Acquire", ctx, name, owner, runAt, now, lease)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Acquire indicates an expected call of Acquire.
+func (mr *MockIJobLockRepositoryMockRecorder) Acquire(ctx, name, owner, runAt, now, lease any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Acquire", reflect.TypeOf((*MockIJobLockRepository)(nil).Acquire), ctx, name, owner, runAt, now, lease)
+}
+
+// Extend mocks base method.
+func (m *MockIJobLockRepository) Extend(ctx context.Context, name, owner string, until time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Extend", ctx, name, owner, until)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Extend indicates an expected call of Extend.
+func (mr *MockIJobLockRepositoryMockRecorder) Extend(ctx, name, owner, until any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Extend", reflect.TypeOf((*MockIJobLockRepository)(nil).Extend), ctx, name, owner, until)
+}
+
+// Release mocks base method.
+func (m *MockIJobLockRepository) Release(ctx context.Context, name, owner string, now time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx, name, owner, now)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockIJobLockRepositoryMockRecorder) Release(ctx, name, owner, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockIJobLockRepository)(nil).Release), ctx, name, owner, now)
+}
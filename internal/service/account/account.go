@@ -0,0 +1,100 @@
+// Package account provides the service signing users in with the accounts they have at an
+// OpenID Connect provider.
+//
+// The first sign-in with an account links it to a new user ID; later sign-ins, from any
+// browser, return the same user ID, so the user sees the same links everywhere. Links the
+// user created anonymously before signing in can be claimed, moving them to the signed-in user.
+package account
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/logger"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+// Errors returned by the account service.
+var (
+	// ErrNotSignedIn is returned when links would be claimed by a user that has not signed in with an account.
+	ErrNotSignedIn = errors.New("user is not signed in")
+	// ErrNotAnonymous is returned when links would be claimed from a user that signs in with an account.
+	ErrNotAnonymous = errors.New("user is not anonymous")
+)
+
+// AccountService links the accounts of users at a provider to user IDs.
+type AccountService struct {
+	log        *zap.SugaredLogger             // Logger for the service
+	identities interfaces.IIdentityRepository // Repository for the links between accounts and users
+	urls       interfaces.IURLRepository      // Repository for the URLs claimed by users
+	now        func() time.Time               // Clock setting the link times
+}
+
+// NewAccountService creates a new instance of AccountService.
+func NewAccountService(log *logger.Logger, identities interfaces.IIdentityRepository, urls interfaces.IURLRepository) *AccountService {
+	return &AccountService{
+		log:        log.Named("AccountService"),
+		identities: identities,
+		urls:       urls,
+		now:        time.Now,
+	}
+}
+
+// SignIn returns the user ID of the account with the subject at the issuer, linking the
+// account to a new user ID on its first sign-in.
+func (s *AccountService) SignIn(ctx context.Context, issuer, subject, email string) (string, error) {
+	identity, err := s.identities.FindOrInsert(ctx, &model.UserIdentity{
+		Issuer:    issuer,
+		Subject:   subject,
+		UserID:    utils.GenerateUUID(),
+		Email:     email,
+		CreatedAt: s.now().UTC(),
+	})
+	if err != nil {
+		return "", err
+	}
+	s.log.Infof("Signed in userID=%s with subject %s of %s", identity.UserID, subject, issuer)
+	return identity.UserID, nil
+}
+
+// IsSignedIn reports whether the user signs in with an account rather than being anonymous.
+func (s *AccountService) IsSignedIn(ctx context.Context, userID string) (bool, error) {
+	identity, err := s.identities.FindByUserID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return identity != nil, nil
+}
+
+// ClaimURLs moves the links of the anonymous user to the signed-in user and returns how many
+// were moved.
+func (s *AccountService) ClaimURLs(ctx context.Context, anonymousUserID, userID string) (int64, error) {
+	if anonymousUserID == userID {
+		return 0, nil
+	}
+	signedIn, err := s.IsSignedIn(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if !signedIn {
+		return 0, ErrNotSignedIn
+	}
+	linked, err := s.IsSignedIn(ctx, anonymousUserID)
+	if err != nil {
+		return 0, err
+	}
+	if linked {
+		return 0, ErrNotAnonymous
+	}
+	count, err := s.urls.ReassignUserID(ctx, anonymousUserID, userID)
+	if err != nil {
+		return 0, err
+	}
+	s.log.Infof("userID=%s claimed %d URLs of anonymous userID=%s", userID, count, anonymousUserID)
+	return count, nil
+}
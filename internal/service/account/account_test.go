@@ -0,0 +1,75 @@
+package account_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/logger"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/repository/inmemory"
+	"github.com/GlebRadaev/shlink/internal/service/account"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const issuer = "https://idp.example.com"
+
+func setup(t *testing.T) (*account.AccountService, interfaces.IURLRepository) {
+	log, _ := logger.NewLogger("info")
+	urls := inmemory.NewMemoryStorage()
+	return account.NewAccountService(log, inmemory.NewIdentityStorage(), urls), urls
+}
+
+func TestAccountService_SignIn(t *testing.T) {
+	ctx := context.Background()
+	service, _ := setup(t)
+
+	alice, err := service.SignIn(ctx, issuer, "alice", "alice@example.com")
+	require.NoError(t, err)
+	assert.NotEmpty(t, alice)
+	again, err := service.SignIn(ctx, issuer, "alice", "")
+	require.NoError(t, err)
+	assert.Equal(t, alice, again, "Expected the account to keep its user ID")
+	bob, err := service.SignIn(ctx, issuer, "bob", "")
+	require.NoError(t, err)
+	assert.NotEqual(t, alice, bob)
+
+	signedIn, err := service.IsSignedIn(ctx, alice)
+	require.NoError(t, err)
+	assert.True(t, signedIn)
+	signedIn, err = service.IsSignedIn(ctx, "anonymous")
+	require.NoError(t, err)
+	assert.False(t, signedIn)
+}
+
+func TestAccountService_ClaimURLs(t *testing.T) {
+	ctx := context.Background()
+	service, urls := setup(t)
+	alice, err := service.SignIn(ctx, issuer, "alice", "")
+	require.NoError(t, err)
+	bob, err := service.SignIn(ctx, issuer, "bob", "")
+	require.NoError(t, err)
+	_, err = urls.InsertList(ctx, []*model.URL{
+		{ShortID: "anon1", OriginalURL: "http://example.com/1", UserID: "anonymous"},
+		{ShortID: "anon2", OriginalURL: "http://example.com/2", UserID: "anonymous"},
+		{ShortID: "bob1", OriginalURL: "http://example.com/3", UserID: bob},
+	})
+	require.NoError(t, err)
+
+	_, err = service.ClaimURLs(ctx, "anonymous", "other-anonymous")
+	assert.ErrorIs(t, err, account.ErrNotSignedIn)
+	_, err = service.ClaimURLs(ctx, bob, alice)
+	assert.ErrorIs(t, err, account.ErrNotAnonymous, "Expected links of signed-in users not to be claimable")
+
+	count, err := service.ClaimURLs(ctx, "anonymous", alice)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	claimed, err := urls.FindListByUserID(ctx, alice, model.URLListQuery{})
+	require.NoError(t, err)
+	assert.Len(t, claimed, 2)
+
+	count, err = service.ClaimURLs(ctx, alice, alice)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
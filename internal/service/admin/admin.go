@@ -0,0 +1,420 @@
+// Package admin provides the service letting administrators deal with abuse across users.
+//
+// Administrators look up any short link and its owner, disable and restore links, list the
+// links of a user and disable all links of a user or pointing to a domain at once. Disabled
+// links stop redirecting but keep their data, so they can be restored. Administrators also
+// inspect the background tasks that ran out of attempts and replay them, watch the workers
+// processing the tasks and resize them. Every action is written to the audit log, which
+// administrators read back page by page.
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/config"
+	"github.com/GlebRadaev/shlink/internal/dto"
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/logger"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/service/url"
+	"github.com/GlebRadaev/shlink/internal/taskmanager"
+
+	"go.uber.org/zap"
+)
+
+// MaxReasonLength is the longest reason an administrator may give for an action.
+const MaxReasonLength = 500
+
+// Page sizes of the audit log.
+const (
+	DefaultAuditPageSize = 50  // Number of entries listed when no limit is requested.
+	MaxAuditPageSize     = 500 // Largest number of entries a client may request per page.
+)
+
+// Sizes of the dead letter listing.
+const (
+	DefaultDeadTaskLimit = 50  // Number of dead letters listed when no limit is requested.
+	MaxDeadTaskLimit     = 500 // Largest number of dead letters a client may request.
+)
+
+// MaxTaskWorkers is the largest number of workers an administrator may set.
+const MaxTaskWorkers = 1000
+
+// Errors returned by the admin service.
+var (
+	// ErrURLNotFound is returned when no URL has the short ID.
+	ErrURLNotFound = errors.New("URL not found")
+	// ErrInvalidReason is returned when the reason given for an action is too long.
+	ErrInvalidReason = errors.New("invalid reason")
+	// ErrInvalidDomain is returned when the domain to disable is not a host name with at least two labels.
+	ErrInvalidDomain = errors.New("invalid domain")
+	// ErrInvalidAuditQuery is returned when the audit log listing options cannot be parsed.
+	ErrInvalidAuditQuery = errors.New("invalid audit query")
+	// ErrTaskNotFound is returned when no dead letter has the ID.
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrInvalidTaskQuery is returned when the dead letter ID or listing options cannot be parsed.
+	ErrInvalidTaskQuery = errors.New("invalid task query")
+	// ErrInvalidWorkers is returned when the number of workers or a concurrency limit cannot be set.
+	ErrInvalidWorkers = errors.New("invalid workers")
+)
+
+// Actor is the administrator performing an action, as recorded in the audit log.
+type Actor struct {
+	UserID   string // Identifier of the administrator.
+	APIKeyID string // Key the administrator used, empty for the cookie.
+}
+
+// AdminService performs the actions of administrators and records them in the audit log.
+type AdminService struct {
+	config     *config.Config                 // Configuration with the base URL of the short links
+	log        *zap.SugaredLogger             // Logger for the service
+	urls       interfaces.IURLRepository      // Repository for the URLs of all users
+	identities interfaces.IIdentityRepository // Repository for the accounts of the owners
+	audit      interfaces.IAuditRepository    // Repository for the audit log
+	urlService *url.URLService                // Service listing the URLs of a user
+	tasks      *taskmanager.WorkerPool        // Worker pool holding the task queue and its dead letters
+	now        func() time.Time               // Clock setting the audit entry times
+}
+
+// NewAdminService creates a new instance of AdminService.
+func NewAdminService(cfg *config.Config, log *logger.Logger, urls interfaces.IURLRepository, identities interfaces.IIdentityRepository,
+	audit interfaces.IAuditRepository, urlService *url.URLService, tasks *taskmanager.WorkerPool) *AdminService {
+	return &AdminService{
+		config:     cfg,
+		log:        log.Named("AdminService"),
+		urls:       urls,
+		identities: identities,
+		audit:      audit,
+		urlService: urlService,
+		tasks:      tasks,
+		now:        time.Now,
+	}
+}
+
+// LookupURL returns the URL with the short ID, deleted or not, with its owner.
+func (s *AdminService) LookupURL(ctx context.Context, actor Actor, shortID string) (*dto.AdminURLResponseDTO, error) {
+	url, err := s.urls.FindByID(ctx, shortID)
+	if err != nil {
+		return nil, err
+	}
+	if url == nil {
+		return nil, ErrURLNotFound
+	}
+	identity, err := s.identities.FindByUserID(ctx, url.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(ctx, actor, model.AuditLookupURL, shortID, "", 1); err != nil {
+		return nil, err
+	}
+	response := &dto.AdminURLResponseDTO{
+		ShortID:     url.ShortID,
+		ShortURL:    fmt.Sprintf("%s/%s", s.config.BaseURL, url.ShortID),
+		OriginalURL: url.OriginalURL,
+		UserID:      url.UserID,
+		CreatedAt:   url.CreatedAt,
+		ExpiresAt:   url.ExpiresAt,
+		MaxClicks:   url.MaxClicks,
+		Clicks:      url.Clicks,
+		HasPassword: url.IsProtected(),
+		IsDeleted:   url.DeletedFlag,
+		IsDisabled:  url.DisabledFlag,
+	}
+	if identity != nil {
+		response.OwnerEmail = identity.Email
+	}
+	return response, nil
+}
+
+// SetURLDisabled disables the URL with the short ID, or restores it if disabled is false, and
+// returns how many URLs changed: zero if it already was in that state.
+func (s *AdminService) SetURLDisabled(ctx context.Context, actor Actor, shortID string, disabled bool, reason string) (int64, error) {
+	reason, err := checkReason(reason)
+	if err != nil {
+		return 0, err
+	}
+	url, err := s.urls.FindByID(ctx, shortID)
+	if err != nil {
+		return 0, err
+	}
+	if url == nil {
+		return 0, ErrURLNotFound
+	}
+	count, err := s.urls.SetDisabled(ctx, []string{shortID}, disabled)
+	if err != nil {
+		return 0, err
+	}
+	action := model.AuditDisableURL
+	if !disabled {
+		action = model.AuditRestoreURL
+	}
+	return count, s.record(ctx, actor, action, shortID, reason, count)
+}
+
+// ListUserURLs returns a page of the URLs of the user, with the options of the user's own listing.
+func (s *AdminService) ListUserURLs(ctx context.Context, actor Actor, userID string, params dto.GetUserURLsRequestDTO) (dto.GetUserURLsPageDTO, error) {
+	page, err := s.urlService.GetUserURLs(ctx, userID, params)
+	if err != nil {
+		return dto.GetUserURLsPageDTO{}, err
+	}
+	return page, s.record(ctx, actor, model.AuditListUserURLs, userID, "", int64(len(page.URLs)))
+}
+
+// DisableUserURLs disables all URLs of the user that are not deleted and returns how many changed.
+func (s *AdminService) DisableUserURLs(ctx context.Context, actor Actor, userID, reason string) (int64, error) {
+	reason, err := checkReason(reason)
+	if err != nil {
+		return 0, err
+	}
+	active := false
+	urls, err := s.urls.FindListByUserID(ctx, userID, model.URLListQuery{Deleted: &active})
+	if err != nil {
+		return 0, err
+	}
+	count, err := s.disable(ctx, urls)
+	if err != nil {
+		return 0, err
+	}
+	return count, s.record(ctx, actor, model.AuditDisableUserURLs, userID, reason, count)
+}
+
+// DisableDomainURLs disables all URLs that are not deleted and point to the domain or one of its
+// subdomains, and returns how many changed.
+func (s *AdminService) DisableDomainURLs(ctx context.Context, actor Actor, domain, reason string) (int64, error) {
+	reason, err := checkReason(reason)
+	if err != nil {
+		return 0, err
+	}
+	domain, err = normalizeDomain(domain)
+	if err != nil {
+		return 0, err
+	}
+	urls, err := s.urls.FindListByDomain(ctx, domain)
+	if err != nil {
+		return 0, err
+	}
+	count, err := s.disable(ctx, urls)
+	if err != nil {
+		return 0, err
+	}
+	return count, s.record(ctx, actor, model.AuditDisableDomainURLs, domain, reason, count)
+}
+
+// AuditLog returns a page of the audit log, newest entries first. The next page is requested
+// with the returned NextBefore, which is zero on the last page.
+func (s *AdminService) AuditLog(ctx context.Context, params dto.AuditLogRequestDTO) (dto.AuditLogResponseDTO, error) {
+	query := model.AuditQuery{Target: params.Target, Limit: DefaultAuditPageSize}
+	if params.Limit != "" {
+		limit, err := strconv.Atoi(params.Limit)
+		if err != nil || limit < 1 || limit > MaxAuditPageSize {
+			return dto.AuditLogResponseDTO{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidAuditQuery, MaxAuditPageSize)
+		}
+		query.Limit = limit
+	}
+	if params.Before != "" {
+		before, err := strconv.ParseInt(params.Before, 10, 64)
+		if err != nil || before < 1 {
+			return dto.AuditLogResponseDTO{}, fmt.Errorf("%w: invalid before", ErrInvalidAuditQuery)
+		}
+		query.Before = before
+	}
+	limit := query.Limit
+	query.Limit = limit + 1
+	entries, err := s.audit.FindList(ctx, query)
+	if err != nil {
+		return dto.AuditLogResponseDTO{}, err
+	}
+	response := dto.AuditLogResponseDTO{Entries: make([]dto.AuditEntryResponseDTO, 0, len(entries))}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		response.NextBefore = entries[limit-1].ID
+	}
+	for _, entry := range entries {
+		response.Entries = append(response.Entries, dto.AuditEntryResponseDTO{
+			ID:          entry.ID,
+			ActorUserID: entry.ActorUserID,
+			APIKeyID:    entry.APIKeyID,
+			Action:      entry.Action,
+			Target:      entry.Target,
+			Reason:      entry.Reason,
+			Affected:    entry.Affected,
+			CreatedAt:   entry.CreatedAt,
+		})
+	}
+	return response, nil
+}
+
+// DeadTasks returns the background tasks that ran out of attempts, newest first, at most limit
+// of them or DefaultDeadTaskLimit if limit is empty.
+func (s *AdminService) DeadTasks(ctx context.Context, actor Actor, limit string) ([]dto.DeadTaskResponseDTO, error) {
+	count := DefaultDeadTaskLimit
+	if limit != "" {
+		var err error
+		count, err = strconv.Atoi(limit)
+		if err != nil || count < 1 || count > MaxDeadTaskLimit {
+			return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidTaskQuery, MaxDeadTaskLimit)
+		}
+	}
+	tasks, err := s.tasks.DeadLetters(ctx, count)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(ctx, actor, model.AuditListDeadTasks, "", "", int64(len(tasks))); err != nil {
+		return nil, err
+	}
+	response := make([]dto.DeadTaskResponseDTO, 0, len(tasks))
+	for _, task := range tasks {
+		response = append(response, dto.DeadTaskResponseDTO{
+			ID:        task.ID,
+			Type:      task.Type,
+			Payload:   task.Payload,
+			Attempts:  task.Attempts,
+			LastError: task.LastError,
+			CreatedAt: task.CreatedAt,
+			FailedAt:  task.FailedAt,
+		})
+	}
+	return response, nil
+}
+
+// ReplayDeadTask moves the dead letter with the ID back to the task queue with no attempts, to be
+// tried again as many times as a new task.
+func (s *AdminService) ReplayDeadTask(ctx context.Context, actor Actor, id, reason string) (dto.ReplayTaskResponseDTO, error) {
+	reason, err := checkReason(reason)
+	if err != nil {
+		return dto.ReplayTaskResponseDTO{}, err
+	}
+	taskID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || taskID < 1 {
+		return dto.ReplayTaskResponseDTO{}, fmt.Errorf("%w: invalid task ID %q", ErrInvalidTaskQuery, id)
+	}
+	task, err := s.tasks.Replay(ctx, taskID)
+	if errors.Is(err, taskmanager.ErrTaskNotFound) {
+		return dto.ReplayTaskResponseDTO{}, ErrTaskNotFound
+	}
+	if err != nil {
+		return dto.ReplayTaskResponseDTO{}, err
+	}
+	return dto.ReplayTaskResponseDTO{TaskID: task.ID}, s.record(ctx, actor, model.AuditReplayTask, id, reason, 1)
+}
+
+// TaskStats returns the live statistics of the workers processing the background tasks.
+func (s *AdminService) TaskStats(ctx context.Context) (dto.WorkerPoolStatsResponseDTO, error) {
+	pending, err := s.tasks.Pending(ctx)
+	if err != nil {
+		return dto.WorkerPoolStatsResponseDTO{}, err
+	}
+	stats := s.tasks.Stats()
+	response := dto.WorkerPoolStatsResponseDTO{
+		Workers:       stats.Workers,
+		ActiveWorkers: stats.ActiveWorkers,
+		Pending:       pending,
+		QueueLength:   stats.QueueLength,
+		QueueCapacity: stats.QueueCapacity,
+		Processed:     stats.Processed,
+		Errors:        stats.Errors,
+		Types:         make(map[string]dto.TaskTypeStatsResponseDTO, len(stats.Types)),
+	}
+	for taskType, typeStats := range stats.Types {
+		response.Types[taskType] = dto.TaskTypeStatsResponseDTO{
+			Running:   typeStats.Running,
+			Queued:    typeStats.Queued,
+			Limit:     typeStats.Limit,
+			Processed: typeStats.Processed,
+			Errors:    typeStats.Errors,
+		}
+	}
+	return response, nil
+}
+
+// ResizeWorkers changes the number of workers processing the background tasks, unless it is zero
+// in the request, and the concurrency limits of the task types in the request. The whole request
+// is checked before anything is changed; if resizing fails once the limits are set, the limits
+// are kept and recorded in the audit log.
+func (s *AdminService) ResizeWorkers(ctx context.Context, actor Actor, request dto.ResizeWorkersRequestDTO) (dto.WorkerPoolStatsResponseDTO, error) {
+	reason, err := checkReason(request.Reason)
+	if err != nil {
+		return dto.WorkerPoolStatsResponseDTO{}, err
+	}
+	if request.Workers < 0 || request.Workers > MaxTaskWorkers {
+		return dto.WorkerPoolStatsResponseDTO{}, fmt.Errorf("%w: workers must be between 1 and %d, or 0 to keep them", ErrInvalidWorkers, MaxTaskWorkers)
+	}
+	for taskType, limit := range request.Limits {
+		if limit < 0 || limit > MaxTaskWorkers {
+			return dto.WorkerPoolStatsResponseDTO{}, fmt.Errorf("%w: limit of %s must be between 0 and %d", ErrInvalidWorkers, taskType, MaxTaskWorkers)
+		}
+	}
+	if err := s.tasks.SetConcurrencyLimits(request.Limits); err != nil {
+		return dto.WorkerPoolStatsResponseDTO{}, fmt.Errorf("%w: %v", ErrInvalidWorkers, err)
+	}
+	if request.Workers > 0 {
+		if err := s.tasks.Resize(request.Workers); err != nil {
+			if len(request.Limits) > 0 {
+				partial := fmt.Sprintf("%s (limits set, workers not resized: %v)", reason, err)
+				_ = s.record(ctx, actor, model.AuditResizeWorkers, "", partial, int64(s.tasks.Stats().Workers))
+			}
+			return dto.WorkerPoolStatsResponseDTO{}, err
+		}
+	}
+	stats, err := s.TaskStats(ctx)
+	if err != nil {
+		return dto.WorkerPoolStatsResponseDTO{}, err
+	}
+	return stats, s.record(ctx, actor, model.AuditResizeWorkers, "", reason, int64(stats.Workers))
+}
+
+// disable disables the URLs and returns how many changed.
+func (s *AdminService) disable(ctx context.Context, urls []*model.URL) (int64, error) {
+	if len(urls) == 0 {
+		return 0, nil
+	}
+	shortIDs := make([]string, 0, len(urls))
+	for _, url := range urls {
+		shortIDs = append(shortIDs, url.ShortID)
+	}
+	return s.urls.SetDisabled(ctx, shortIDs, true)
+}
+
+// record writes an action of the administrator to the audit log.
+func (s *AdminService) record(ctx context.Context, actor Actor, action, target, reason string, affected int64) error {
+	entry := &model.AuditEntry{
+		ActorUserID: actor.UserID,
+		APIKeyID:    actor.APIKeyID,
+		Action:      action,
+		Target:      target,
+		Reason:      reason,
+		Affected:    affected,
+		CreatedAt:   s.now().UTC(),
+	}
+	if err := s.audit.Insert(ctx, entry); err != nil {
+		s.log.Errorf("Failed to record %s of %s by userID=%s: %v", action, target, actor.UserID, err)
+		return err
+	}
+	s.log.Infof("userID=%s performed %s on %s affecting %d URLs", actor.UserID, action, target, affected)
+	return nil
+}
+
+// checkReason returns the reason without surrounding spaces if it is not too long.
+func checkReason(reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if len(reason) > MaxReasonLength {
+		return "", fmt.Errorf("%w: longer than %d characters", ErrInvalidReason, MaxReasonLength)
+	}
+	return reason, nil
+}
+
+// normalizeDomain returns the domain in lower case without a trailing dot. A domain must have at
+// least two labels, so that a single request cannot disable every link under a top-level domain.
+func normalizeDomain(domain string) (string, error) {
+	normalized := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if !strings.Contains(normalized, ".") || strings.HasPrefix(normalized, ".") || strings.Contains(normalized, "..") ||
+		strings.ContainsAny(normalized, "/:?#@[] ") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidDomain, domain)
+	}
+	return normalized, nil
+}
@@ -0,0 +1,322 @@
+package admin_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/config"
+	"github.com/GlebRadaev/shlink/internal/dto"
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/logger"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/repository"
+	"github.com/GlebRadaev/shlink/internal/repository/inmemory"
+	"github.com/GlebRadaev/shlink/internal/service/admin"
+	"github.com/GlebRadaev/shlink/internal/service/url"
+	"github.com/GlebRadaev/shlink/internal/taskmanager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+var cfg *config.Config
+
+// moderator is the administrator performing the actions of the tests.
+var moderator = admin.Actor{UserID: "moderator", APIKeyID: "key1"}
+
+// setup creates an admin service over in-memory repositories holding the URLs and an account
+// of "alice", and returns it with the URL and audit repositories.
+func setup(t *testing.T, audit interfaces.IAuditRepository) (*admin.AdminService, interfaces.IURLRepository, interfaces.IAuditRepository) {
+	t.Helper()
+	pool := taskmanager.NewWorkerPool(context.Background(), 1, 1)
+	t.Cleanup(pool.Shutdown)
+	return setupWithPool(t, audit, pool)
+}
+
+// setupWithPool is setup with the worker pool of the test.
+func setupWithPool(t *testing.T, audit interfaces.IAuditRepository, pool *taskmanager.WorkerPool) (*admin.AdminService, interfaces.IURLRepository, interfaces.IAuditRepository) {
+	t.Helper()
+	ctx := context.Background()
+	if cfg == nil {
+		var err error
+		cfg, err = config.ParseAndLoadConfig()
+		require.NoError(t, err)
+	}
+	log, _ := logger.NewLogger("info")
+	urls := inmemory.NewMemoryStorage()
+	identities := inmemory.NewIdentityStorage()
+	if audit == nil {
+		audit = inmemory.NewAuditStorage()
+	}
+	_, err := urls.InsertList(ctx, []*model.URL{
+		{ShortID: "alice001", OriginalURL: "http://phish.example.com/login", UserID: "alice"},
+		{ShortID: "alice002", OriginalURL: "http://example.org/2", UserID: "alice"},
+		{ShortID: "alice003", OriginalURL: "http://example.org/3", UserID: "alice"},
+		{ShortID: "bob00001", OriginalURL: "http://EXAMPLE.com/bob", UserID: "bob"},
+		{ShortID: "bob00002", OriginalURL: "http://notexample.com/bob", UserID: "bob"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, urls.DeleteListByUserIDAndShortIDs(ctx, "alice", []string{"alice003"}))
+	_, err = identities.FindOrInsert(ctx, &model.UserIdentity{Issuer: "https://idp.example.com", Subject: "alice",
+		UserID: "alice", Email: "alice@example.com", CreatedAt: time.Now()})
+	require.NoError(t, err)
+
+	urlService := url.NewURLService(cfg, log, pool, nil, urls, inmemory.NewTaskStatusStorage())
+	return admin.NewAdminService(cfg, log, urls, identities, audit, urlService, pool), urls, audit
+}
+
+// auditLog returns the actions, targets and reasons of the audit log, oldest first.
+func auditLog(t *testing.T, audit interfaces.IAuditRepository) []string {
+	t.Helper()
+	entries, err := audit.FindList(context.Background(), model.AuditQuery{})
+	require.NoError(t, err)
+	result := make([]string, len(entries))
+	for i, entry := range entries {
+		assert.Equal(t, moderator.UserID, entry.ActorUserID)
+		assert.Equal(t, moderator.APIKeyID, entry.APIKeyID)
+		result[len(entries)-1-i] = strings.Join([]string{entry.Action, entry.Target, entry.Reason}, " ")
+	}
+	return result
+}
+
+func TestAdminService_LookupURL(t *testing.T) {
+	ctx := context.Background()
+	service, _, audit := setup(t, nil)
+
+	found, err := service.LookupURL(ctx, moderator, "alice003")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", found.UserID)
+	assert.Equal(t, "alice@example.com", found.OwnerEmail)
+	assert.Equal(t, cfg.BaseURL+"/alice003", found.ShortURL)
+	assert.True(t, found.IsDeleted, "Expected deleted URLs to be found")
+
+	found, err = service.LookupURL(ctx, moderator, "bob00001")
+	require.NoError(t, err)
+	assert.Empty(t, found.OwnerEmail, "Expected no email for an anonymous owner")
+
+	_, err = service.LookupURL(ctx, moderator, "unknown1")
+	assert.ErrorIs(t, err, admin.ErrURLNotFound)
+	assert.Equal(t, []string{"url.lookup alice003 ", "url.lookup bob00001 "}, auditLog(t, audit))
+}
+
+func TestAdminService_SetURLDisabled(t *testing.T) {
+	ctx := context.Background()
+	service, urls, audit := setup(t, nil)
+
+	count, err := service.SetURLDisabled(ctx, moderator, "alice001", true, " phishing ")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	url, err := urls.FindByID(ctx, "alice001")
+	require.NoError(t, err)
+	assert.True(t, url.DisabledFlag)
+
+	count, err = service.SetURLDisabled(ctx, moderator, "alice001", true, "")
+	require.NoError(t, err)
+	assert.Zero(t, count, "Expected disabling twice to change nothing")
+	count, err = service.SetURLDisabled(ctx, moderator, "alice001", false, "false report")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	_, err = service.SetURLDisabled(ctx, moderator, "unknown1", true, "")
+	assert.ErrorIs(t, err, admin.ErrURLNotFound)
+	_, err = service.SetURLDisabled(ctx, moderator, "alice001", true, strings.Repeat("a", admin.MaxReasonLength+1))
+	assert.ErrorIs(t, err, admin.ErrInvalidReason)
+	assert.Equal(t, []string{"url.disable alice001 phishing", "url.disable alice001 ", "url.restore alice001 false report"},
+		auditLog(t, audit))
+}
+
+func TestAdminService_ListUserURLs(t *testing.T) {
+	ctx := context.Background()
+	service, _, audit := setup(t, nil)
+
+	page, err := service.ListUserURLs(ctx, moderator, "alice", dto.GetUserURLsRequestDTO{})
+	require.NoError(t, err)
+	assert.Len(t, page.URLs, 3, "Expected deleted URLs to be listed")
+	_, err = service.ListUserURLs(ctx, moderator, "alice", dto.GetUserURLsRequestDTO{Sort: "invalid"})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"user.list alice "}, auditLog(t, audit))
+}
+
+func TestAdminService_DisableUserURLs(t *testing.T) {
+	ctx := context.Background()
+	service, urls, audit := setup(t, nil)
+
+	count, err := service.DisableUserURLs(ctx, moderator, "alice", "spam")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count, "Expected deleted URLs to be left alone")
+	for _, shortID := range []string{"alice001", "alice002"} {
+		url, err := urls.FindByID(ctx, shortID)
+		require.NoError(t, err)
+		assert.True(t, url.DisabledFlag, shortID)
+	}
+	url, err := urls.FindByID(ctx, "bob00001")
+	require.NoError(t, err)
+	assert.False(t, url.DisabledFlag, "Expected URLs of other users to be kept")
+
+	count, err = service.DisableUserURLs(ctx, moderator, "nobody", "")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	assert.Equal(t, []string{"user.disable alice spam", "user.disable nobody "}, auditLog(t, audit))
+}
+
+func TestAdminService_DisableDomainURLs(t *testing.T) {
+	ctx := context.Background()
+	service, urls, audit := setup(t, nil)
+
+	count, err := service.DisableDomainURLs(ctx, moderator, " Example.COM. ", "malware")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	disabled, err := urls.FindListByUserID(ctx, "bob", model.URLListQuery{})
+	require.NoError(t, err)
+	flags := make(map[string]bool)
+	for _, url := range disabled {
+		flags[url.ShortID] = url.DisabledFlag
+	}
+	assert.Equal(t, map[string]bool{"bob00001": true, "bob00002": false}, flags)
+
+	for _, domain := range []string{"", "com", ".example.com", "example..com", "example.com/path", "user@example.com"} {
+		_, err = service.DisableDomainURLs(ctx, moderator, domain, "")
+		assert.ErrorIs(t, err, admin.ErrInvalidDomain, "domain %q", domain)
+	}
+	assert.Equal(t, []string{"domain.disable example.com malware"}, auditLog(t, audit))
+}
+
+func TestAdminService_AuditLog(t *testing.T) {
+	ctx := context.Background()
+	service, _, _ := setup(t, nil)
+	for _, shortID := range []string{"alice001", "alice002", "alice001"} {
+		_, err := service.LookupURL(ctx, moderator, shortID)
+		require.NoError(t, err)
+	}
+
+	page, err := service.AuditLog(ctx, dto.AuditLogRequestDTO{Limit: "2"})
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 2)
+	assert.Equal(t, int64(3), page.Entries[0].ID, "Expected the newest entries first")
+	assert.Equal(t, int64(2), page.NextBefore)
+	page, err = service.AuditLog(ctx, dto.AuditLogRequestDTO{Limit: "2", Before: "2"})
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 1)
+	assert.Zero(t, page.NextBefore, "Expected no next page on the last page")
+
+	page, err = service.AuditLog(ctx, dto.AuditLogRequestDTO{Target: "alice001"})
+	require.NoError(t, err)
+	assert.Len(t, page.Entries, 2)
+
+	for _, params := range []dto.AuditLogRequestDTO{{Limit: "0"}, {Limit: "x"}, {Limit: "501"}, {Before: "-1"}} {
+		_, err = service.AuditLog(ctx, params)
+		assert.ErrorIs(t, err, admin.ErrInvalidAuditQuery, "%+v", params)
+	}
+}
+
+func TestAdminService_AuditFailure(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	audit := repository.NewMockIAuditRepository(ctrl)
+	audit.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(errors.New("db error")).Times(2)
+	service, _, _ := setup(t, audit)
+
+	_, err := service.LookupURL(ctx, moderator, "alice001")
+	assert.Error(t, err, "Expected URLs not to be shown without an audit entry")
+	_, err = service.SetURLDisabled(ctx, moderator, "alice001", true, "")
+	assert.Error(t, err)
+}
+
+func TestAdminService_DeadTasks(t *testing.T) {
+	ctx := context.Background()
+	_, urls, audit := setup(t, nil)
+	log, _ := logger.NewLogger("info")
+	queue := inmemory.NewTaskStorage()
+	pool := taskmanager.NewDurableWorkerPool(ctx, 1, 1, queue, taskmanager.RetryPolicy{PollInterval: 5 * time.Millisecond})
+	t.Cleanup(pool.Shutdown)
+	service := admin.NewAdminService(cfg, log, urls, inmemory.NewIdentityStorage(), audit, url.NewURLService(cfg, log, pool, nil, urls, inmemory.NewTaskStatusStorage()), pool)
+
+	failedAt := time.Now().Add(time.Hour)
+	require.NoError(t, queue.Push(ctx, &model.QueuedTask{Type: taskmanager.DeleteTask{}.TaskType(),
+		Payload: []byte(`{"user_id":"alice","urls":["alice001"]}`), RunAt: failedAt, CreatedAt: time.Now()}))
+	claimed, err := queue.Claim(ctx, failedAt, time.Minute, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, queue.Bury(ctx, claimed.ID, "db error", failedAt))
+
+	dead, err := service.DeadTasks(ctx, moderator, "")
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, "db error", dead[0].LastError)
+	assert.JSONEq(t, `{"user_id":"alice","urls":["alice001"]}`, string(dead[0].Payload))
+	for _, limit := range []string{"0", "x", "501"} {
+		_, err = service.DeadTasks(ctx, moderator, limit)
+		assert.ErrorIs(t, err, admin.ErrInvalidTaskQuery, "limit %q", limit)
+	}
+
+	id := strconv.FormatInt(dead[0].ID, 10)
+	replayed, err := service.ReplayDeadTask(ctx, moderator, id, "database is back")
+	require.NoError(t, err)
+	assert.NotZero(t, replayed.TaskID)
+	assert.Eventually(t, func() bool {
+		url, err := urls.FindByID(ctx, "alice001")
+		return err == nil && url.DeletedFlag
+	}, time.Second, 5*time.Millisecond, "Expected the replayed task to delete the URL")
+
+	_, err = service.ReplayDeadTask(ctx, moderator, id, "")
+	assert.ErrorIs(t, err, admin.ErrTaskNotFound)
+	_, err = service.ReplayDeadTask(ctx, moderator, "x", "")
+	assert.ErrorIs(t, err, admin.ErrInvalidTaskQuery)
+	assert.Equal(t, []string{"task.list_dead  ", "task.replay " + id + " database is back"}, auditLog(t, audit))
+}
+
+func TestAdminService_ResizeWorkers(t *testing.T) {
+	ctx := context.Background()
+	service, _, audit := setup(t, nil)
+
+	stats, err := service.TaskStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Workers)
+	assert.Equal(t, 1, stats.QueueCapacity)
+
+	stats, err = service.ResizeWorkers(ctx, moderator, dto.ResizeWorkersRequestDTO{
+		Workers: 3,
+		Limits:  map[string]int{taskmanager.DeleteTask{}.TaskType(): 2},
+		Reason:  "large deletions",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Workers)
+	assert.Equal(t, 2, stats.Types[taskmanager.DeleteTask{}.TaskType()].Limit)
+
+	for _, request := range []dto.ResizeWorkersRequestDTO{
+		{Workers: -1},
+		{Workers: admin.MaxTaskWorkers + 1},
+		{Limits: map[string]int{taskmanager.DeleteTask{}.TaskType(): -1}},
+		{Limits: map[string]int{"unknown_task": 1}},
+		{Workers: 2, Limits: map[string]int{taskmanager.DeleteTask{}.TaskType(): 1, "unknown_task": 1}},
+	} {
+		_, err = service.ResizeWorkers(ctx, moderator, request)
+		assert.ErrorIs(t, err, admin.ErrInvalidWorkers, "request %+v", request)
+	}
+	stats, err = service.TaskStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Workers, "An invalid request should change nothing")
+	assert.Equal(t, 2, stats.Types[taskmanager.DeleteTask{}.TaskType()].Limit, "An invalid request should change nothing")
+	assert.Equal(t, []string{"task.resize  large deletions"}, auditLog(t, audit))
+}
+
+func TestAdminService_ResizeWorkersPartial(t *testing.T) {
+	ctx := context.Background()
+	pool := taskmanager.NewWorkerPool(ctx, 1, 1)
+	service, _, audit := setupWithPool(t, nil, pool)
+	pool.Shutdown()
+
+	_, err := service.ResizeWorkers(ctx, moderator, dto.ResizeWorkersRequestDTO{
+		Workers: 2,
+		Limits:  map[string]int{taskmanager.DeleteTask{}.TaskType(): 1},
+		Reason:  "large deletions",
+	})
+	require.Error(t, err)
+	assert.Equal(t, 1, pool.Stats().Types[taskmanager.DeleteTask{}.TaskType()].Limit)
+	assert.Equal(t, []string{
+		"task.resize  large deletions (limits set, workers not resized: " + err.Error() + ")",
+	}, auditLog(t, audit))
+}
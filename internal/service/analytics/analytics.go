@@ -0,0 +1,155 @@
+// Package analytics provides services for recording redirects through shortened URLs
+// and aggregating them into per-link statistics.
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/config"
+	"github.com/GlebRadaev/shlink/internal/dto"
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/logger"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/taskmanager"
+
+	"go.uber.org/zap"
+)
+
+// ErrURLNotFound is returned when the URL does not exist or belongs to another user.
+var ErrURLNotFound = errors.New("URL not found")
+
+// AnalyticsService records click events in memory and writes them to the repository
+// in batches through the worker pool, so that redirects are not slowed down by storage.
+type AnalyticsService struct {
+	log       *zap.SugaredLogger          // Logger for the service
+	config    *config.Config              // Configuration settings for the service
+	taskPool  *taskmanager.WorkerPool     // Worker pool for handling tasks
+	clickRepo interfaces.IClickRepository // Repository for storing click events
+	urlRepo   interfaces.IURLRepository   // Repository for checking URL ownership
+	mu        sync.Mutex                  // Mutex guarding the buffer
+	buffer    []*model.Click              // Click events waiting to be written
+}
+
+// NewAnalyticsService creates a new instance of AnalyticsService and registers the task handler
+// for flushing buffered click events.
+func NewAnalyticsService(
+	config *config.Config,
+	log *logger.Logger,
+	pool *taskmanager.WorkerPool,
+	clickRepo interfaces.IClickRepository,
+	urlRepo interfaces.IURLRepository,
+) *AnalyticsService {
+	service := &AnalyticsService{
+		log:       log.Named("AnalyticsService"),
+		config:    config,
+		taskPool:  pool,
+		clickRepo: clickRepo,
+		urlRepo:   urlRepo,
+	}
+	pool.RegisterHandler("flush_clicks_task", service.ProcessFlushClicksTask)
+	return service
+}
+
+// RecordClick buffers a click event for the given short ID. It never blocks on storage;
+// if the buffer is full the event is dropped.
+func (s *AnalyticsService) RecordClick(shortID, referrer, userAgent, ip, country string) {
+	click := &model.Click{
+		ShortID:   shortID,
+		ClickedAt: time.Now().UTC(),
+		Referrer:  referrer,
+		UserAgent: userAgent,
+		IP:        ip,
+		Country:   country,
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.buffer) >= s.config.ClickBufferSize {
+		s.log.Warnf("Click buffer is full, dropping click for ID %s", shortID)
+		return
+	}
+	s.buffer = append(s.buffer, click)
+}
+
+// ProcessFlushClicksTask processes a task that writes buffered click events to the repository.
+func (s *AnalyticsService) ProcessFlushClicksTask(ctx context.Context, task taskmanager.Task) error {
+	if _, ok := task.(taskmanager.FlushClicksTask); !ok {
+		return fmt.Errorf("invalid task type: expected FlushClicksTask")
+	}
+	return s.Flush(ctx)
+}
+
+// Flush writes all buffered click events to the repository in batches of the configured size.
+// Events of a batch that failed to be written are put back into the buffer.
+func (s *AnalyticsService) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	clicks := s.buffer
+	s.buffer = nil
+	s.mu.Unlock()
+
+	batchSize := s.config.ClickBatchSize
+	if batchSize <= 0 {
+		batchSize = len(clicks)
+	}
+	for i := 0; i < len(clicks); i += batchSize {
+		end := i + batchSize
+		if end > len(clicks) {
+			end = len(clicks)
+		}
+		if err := s.clickRepo.InsertList(ctx, clicks[i:end]); err != nil {
+			s.log.Errorf("Failed to write %d clicks: %v", len(clicks)-i, err)
+			s.requeue(clicks[i:])
+			return err
+		}
+	}
+	if len(clicks) > 0 {
+		s.log.Infof("Written %d clicks", len(clicks))
+	}
+	return nil
+}
+
+// requeue puts click events that failed to be written back in front of the buffer,
+// keeping the buffer within its configured size.
+func (s *AnalyticsService) requeue(clicks []*model.Click) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buffer = append(clicks, s.buffer...)
+	if len(s.buffer) > s.config.ClickBufferSize {
+		s.buffer = s.buffer[:s.config.ClickBufferSize]
+	}
+}
+
+// GetURLStats returns click statistics for a URL owned by the given user.
+func (s *AnalyticsService) GetURLStats(ctx context.Context, userID, shortID string) (*dto.URLStatsResponseDTO, error) {
+	url, err := s.urlRepo.FindByID(ctx, shortID)
+	if err != nil {
+		s.log.Errorf("Error retrieving URL for ID %s: %v", shortID, err)
+		return nil, err
+	}
+	if url == nil || url.UserID != userID {
+		s.log.Warnf("URL not found for ID %s and user ID %s", shortID, userID)
+		return nil, ErrURLNotFound
+	}
+	stats, err := s.clickRepo.GetStats(ctx, shortID)
+	if err != nil {
+		s.log.Errorf("Error getting stats for ID %s: %v", shortID, err)
+		return nil, err
+	}
+	response := &dto.URLStatsResponseDTO{
+		ShortURL:       fmt.Sprintf("%s/%s", s.config.BaseURL, shortID),
+		TotalClicks:    stats.TotalClicks,
+		UniqueVisitors: stats.UniqueVisitors,
+		Daily:          make([]dto.DailyClicksDTO, 0, len(stats.Daily)),
+	}
+	for _, day := range stats.Daily {
+		response.Daily = append(response.Daily, dto.DailyClicksDTO{
+			Date:           day.Date.Format(time.DateOnly),
+			Clicks:         day.Clicks,
+			UniqueVisitors: day.UniqueVisitors,
+		})
+	}
+	return response, nil
+}
@@ -0,0 +1,190 @@
+package analytics_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/config"
+	"github.com/GlebRadaev/shlink/internal/dto"
+	"github.com/GlebRadaev/shlink/internal/logger"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/repository"
+	"github.com/GlebRadaev/shlink/internal/service/analytics"
+	"github.com/GlebRadaev/shlink/internal/taskmanager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func setup(t *testing.T) (*repository.MockIClickRepository, *repository.MockIURLRepository, *analytics.AnalyticsService) {
+	cfg := &config.Config{
+		BaseURL:         "http://localhost:8080",
+		ClickBatchSize:  2,
+		ClickBufferSize: 3,
+	}
+	log, _ := logger.NewLogger("info")
+	ctrl := gomock.NewController(t)
+	pool := taskmanager.NewWorkerPool(context.Background(), 10, 1)
+	t.Cleanup(pool.Shutdown)
+	mockClickRepo := repository.NewMockIClickRepository(ctrl)
+	mockURLRepo := repository.NewMockIURLRepository(ctrl)
+	service := analytics.NewAnalyticsService(cfg, log, pool, mockClickRepo, mockURLRepo)
+	return mockClickRepo, mockURLRepo, service
+}
+
+func TestAnalyticsService_Flush(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		clicks     int
+		setupMock  func(mockClickRepo *repository.MockIClickRepository)
+		wantErr    error
+		wantRetain int
+	}{
+		{
+			name:   "clicks are written in batches",
+			clicks: 3,
+			setupMock: func(mockClickRepo *repository.MockIClickRepository) {
+				gomock.InOrder(
+					mockClickRepo.EXPECT().InsertList(gomock.Any(), gomock.Len(2)).Return(nil),
+					mockClickRepo.EXPECT().InsertList(gomock.Any(), gomock.Len(1)).Return(nil),
+				)
+			},
+			wantErr:    nil,
+			wantRetain: 0,
+		},
+		{
+			name:   "clicks over buffer size are dropped",
+			clicks: 5,
+			setupMock: func(mockClickRepo *repository.MockIClickRepository) {
+				gomock.InOrder(
+					mockClickRepo.EXPECT().InsertList(gomock.Any(), gomock.Len(2)).Return(nil),
+					mockClickRepo.EXPECT().InsertList(gomock.Any(), gomock.Len(1)).Return(nil),
+				)
+			},
+			wantErr:    nil,
+			wantRetain: 0,
+		},
+		{
+			name:   "failed batch is kept for the next flush",
+			clicks: 3,
+			setupMock: func(mockClickRepo *repository.MockIClickRepository) {
+				gomock.InOrder(
+					mockClickRepo.EXPECT().InsertList(gomock.Any(), gomock.Len(2)).Return(nil),
+					mockClickRepo.EXPECT().InsertList(gomock.Any(), gomock.Len(1)).Return(errors.New("db error")),
+				)
+			},
+			wantErr:    errors.New("db error"),
+			wantRetain: 1,
+		},
+		{
+			name:       "nothing to flush",
+			clicks:     0,
+			setupMock:  func(mockClickRepo *repository.MockIClickRepository) {},
+			wantErr:    nil,
+			wantRetain: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClickRepo, _, service := setup(t)
+			for i := 0; i < tt.clicks; i++ {
+				service.RecordClick("short1", "https://referrer.com", "agent", "192.0.2.1", "DE")
+			}
+			tt.setupMock(mockClickRepo)
+
+			err := service.ProcessFlushClicksTask(ctx, taskmanager.FlushClicksTask{})
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if tt.wantRetain > 0 {
+				mockClickRepo.EXPECT().InsertList(gomock.Any(), gomock.Len(tt.wantRetain)).Return(nil)
+			}
+			require.NoError(t, service.Flush(ctx))
+		})
+	}
+}
+
+func TestAnalyticsService_ProcessFlushClicksTask_InvalidTask(t *testing.T) {
+	_, _, service := setup(t)
+	err := service.ProcessFlushClicksTask(context.Background(), taskmanager.DeleteTask{})
+	assert.ErrorContains(t, err, "invalid task type")
+}
+
+func TestAnalyticsService_GetURLStats(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2024, time.November, 17, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		userID    string
+		setupMock func(mockClickRepo *repository.MockIClickRepository, mockURLRepo *repository.MockIURLRepository)
+		want      *dto.URLStatsResponseDTO
+		wantErr   error
+	}{
+		{
+			name:   "stats for own URL",
+			userID: "user1",
+			setupMock: func(mockClickRepo *repository.MockIClickRepository, mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "short1").Return(&model.URL{ShortID: "short1", UserID: "user1"}, nil)
+				mockClickRepo.EXPECT().GetStats(gomock.Any(), "short1").Return(&model.ClickStats{
+					TotalClicks:    3,
+					UniqueVisitors: 2,
+					Daily:          []model.DailyClicks{{Date: day, Clicks: 3, UniqueVisitors: 2}},
+				}, nil)
+			},
+			want: &dto.URLStatsResponseDTO{
+				ShortURL:       "http://localhost:8080/short1",
+				TotalClicks:    3,
+				UniqueVisitors: 2,
+				Daily:          []dto.DailyClicksDTO{{Date: "2024-11-17", Clicks: 3, UniqueVisitors: 2}},
+			},
+		},
+		{
+			name:   "URL of another user",
+			userID: "user2",
+			setupMock: func(mockClickRepo *repository.MockIClickRepository, mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "short1").Return(&model.URL{ShortID: "short1", UserID: "user1"}, nil)
+			},
+			wantErr: analytics.ErrURLNotFound,
+		},
+		{
+			name:   "unknown URL",
+			userID: "user1",
+			setupMock: func(mockClickRepo *repository.MockIClickRepository, mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "short1").Return(nil, nil)
+			},
+			wantErr: analytics.ErrURLNotFound,
+		},
+		{
+			name:   "stats error",
+			userID: "user1",
+			setupMock: func(mockClickRepo *repository.MockIClickRepository, mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "short1").Return(&model.URL{ShortID: "short1", UserID: "user1"}, nil)
+				mockClickRepo.EXPECT().GetStats(gomock.Any(), "short1").Return(nil, errors.New("db error"))
+			},
+			wantErr: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClickRepo, mockURLRepo, service := setup(t)
+			tt.setupMock(mockClickRepo, mockURLRepo)
+			got, err := service.GetURLStats(ctx, tt.userID, "short1")
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+// Package apikey provides the service managing the API keys that let server-to-server
+// clients act on behalf of a user without the user cookie.
+//
+// A key is a random string starting with KeyPrefix. Only its SHA-256 hash is stored: the
+// keys are long enough random strings that a fast hash is safe, and it lets a key be looked
+// up by its hash on every request. The key itself is returned once, when it is created.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/dto"
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/logger"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+// KeyPrefix starts every API key, so that leaked keys are easy to recognize.
+const KeyPrefix = "shl_"
+
+// keyBytes is the number of random bytes of an API key.
+const keyBytes = 32
+
+// displayedPrefixLength is the number of leading characters of a key kept to tell keys apart.
+const displayedPrefixLength = len(KeyPrefix) + 8
+
+// MaxNameLength is the longest name an API key may have.
+const MaxNameLength = 100
+
+// Errors returned by the API key service.
+var (
+	// ErrInvalidScope is returned when a key would be created without scopes or with an unknown one.
+	ErrInvalidScope = errors.New("invalid scope")
+	// ErrInvalidName is returned when the name of a new key is too long.
+	ErrInvalidName = errors.New("invalid name")
+	// ErrAPIKeyNotFound is returned when the user has no active key with the ID.
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrInvalidAPIKey is returned when a presented key is unknown or revoked.
+	ErrInvalidAPIKey = errors.New("invalid API key")
+)
+
+// APIKeyService creates, lists, revokes and authenticates the API keys of users.
+type APIKeyService struct {
+	log  *zap.SugaredLogger           // Logger for the service
+	repo interfaces.IAPIKeyRepository // Repository for storing the hashed keys
+	now  func() time.Time             // Clock setting the creation and revocation times
+}
+
+// NewAPIKeyService creates a new instance of APIKeyService.
+func NewAPIKeyService(log *logger.Logger, repo interfaces.IAPIKeyRepository) *APIKeyService {
+	return &APIKeyService{
+		log:  log.Named("APIKeyService"),
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+// Create creates an API key of the user with the requested scopes and returns it, including
+// the key itself, which cannot be retrieved afterwards.
+func (s *APIKeyService) Create(ctx context.Context, userID string, req dto.CreateAPIKeyRequestDTO) (*dto.CreateAPIKeyResponseDTO, error) {
+	name := strings.TrimSpace(req.Name)
+	if len(name) > MaxNameLength {
+		return nil, fmt.Errorf("%w: longer than %d characters", ErrInvalidName, MaxNameLength)
+	}
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := generateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %v", err)
+	}
+	key := &model.APIKey{
+		ID:        utils.GenerateUUID(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    secret[:displayedPrefixLength],
+		KeyHash:   HashKey(secret),
+		Scopes:    scopes,
+		CreatedAt: s.now().UTC(),
+	}
+	if err := s.repo.Insert(ctx, key); err != nil {
+		return nil, err
+	}
+	s.log.Infof("Created API key %s for userID=%s with scopes %v", key.ID, userID, scopes)
+	return &dto.CreateAPIKeyResponseDTO{APIKeyResponseDTO: toResponse(key), Key: secret}, nil
+}
+
+// List returns the API keys of the user, revoked ones included, in creation order.
+func (s *APIKeyService) List(ctx context.Context, userID string) ([]dto.APIKeyResponseDTO, error) {
+	keys, err := s.repo.FindListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.APIKeyResponseDTO, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, toResponse(key))
+	}
+	return result, nil
+}
+
+// Revoke revokes the active API key of the user with the ID, so that it is no longer accepted.
+func (s *APIKeyService) Revoke(ctx context.Context, userID, id string) error {
+	revoked, err := s.repo.Revoke(ctx, userID, id, s.now().UTC())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	s.log.Infof("Revoked API key %s of userID=%s", id, userID)
+	return nil
+}
+
+// Authenticate returns the active API key matching the presented key, or ErrInvalidAPIKey.
+func (s *APIKeyService) Authenticate(ctx context.Context, secret string) (*model.APIKey, error) {
+	if !strings.HasPrefix(secret, KeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	key, err := s.repo.FindByHash(ctx, HashKey(secret))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find API key: %w", err)
+	}
+	if key == nil || key.IsRevoked() {
+		return nil, ErrInvalidAPIKey
+	}
+	return key, nil
+}
+
+// HashKey returns the hex SHA-256 hash under which the key is stored.
+func HashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateKey returns a new random API key.
+func generateKey() (string, error) {
+	b := make([]byte, keyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return KeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// normalizeScopes checks the requested scopes and returns them without duplicates, in the
+// order of model.Scopes followed by model.ScopeAdmin. Whether the user may grant the admin
+// scope is checked by the caller.
+func normalizeScopes(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, fmt.Errorf("%w: at least one of %s is required", ErrInvalidScope, strings.Join(model.Scopes, ", "))
+	}
+	known := append(slices.Clone(model.Scopes), model.ScopeAdmin)
+	for _, scope := range requested {
+		if !slices.Contains(known, scope) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+	}
+	var scopes []string
+	for _, scope := range known {
+		if slices.Contains(requested, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// toResponse converts the key to its response, without the hash.
+func toResponse(key *model.APIKey) dto.APIKeyResponseDTO {
+	return dto.APIKeyResponseDTO{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt,
+		RevokedAt: key.RevokedAt,
+	}
+}
@@ -0,0 +1,100 @@
+package apikey_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/GlebRadaev/shlink/internal/dto"
+	"github.com/GlebRadaev/shlink/internal/logger"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/repository"
+	"github.com/GlebRadaev/shlink/internal/repository/inmemory"
+	"github.com/GlebRadaev/shlink/internal/service/apikey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func setup(t *testing.T) *apikey.APIKeyService {
+	log, _ := logger.NewLogger("info")
+	return apikey.NewAPIKeyService(log, inmemory.NewAPIKeyStorage())
+}
+
+func TestAPIKeyService_Create(t *testing.T) {
+	ctx := context.Background()
+	service := setup(t)
+
+	tests := []struct {
+		name       string
+		req        dto.CreateAPIKeyRequestDTO
+		wantScopes []string
+		wantErr    error
+	}{
+		{name: "scopes are ordered and deduplicated", req: dto.CreateAPIKeyRequestDTO{Name: " ci ", Scopes: []string{"delete", "shorten", "delete"}}, wantScopes: []string{model.ScopeShorten, model.ScopeDelete}},
+		{name: "no scopes", req: dto.CreateAPIKeyRequestDTO{Name: "ci"}, wantErr: apikey.ErrInvalidScope},
+		{name: "admin scope comes last", req: dto.CreateAPIKeyRequestDTO{Name: "ci", Scopes: []string{"admin", "read"}}, wantScopes: []string{model.ScopeRead, model.ScopeAdmin}},
+		{name: "unknown scope", req: dto.CreateAPIKeyRequestDTO{Scopes: []string{"read", "superuser"}}, wantErr: apikey.ErrInvalidScope},
+		{name: "name too long", req: dto.CreateAPIKeyRequestDTO{Name: strings.Repeat("a", apikey.MaxNameLength+1), Scopes: []string{"read"}}, wantErr: apikey.ErrInvalidName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created, err := service.Create(ctx, "user1", tt.req)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantScopes, created.Scopes)
+			assert.Equal(t, "ci", created.Name)
+			assert.True(t, strings.HasPrefix(created.Key, apikey.KeyPrefix))
+			assert.True(t, strings.HasPrefix(created.Key, created.Prefix))
+			assert.Len(t, created.Prefix, len(apikey.KeyPrefix)+8)
+
+			key, err := service.Authenticate(ctx, created.Key)
+			require.NoError(t, err)
+			assert.Equal(t, "user1", key.UserID)
+			assert.Equal(t, apikey.HashKey(created.Key), key.KeyHash)
+		})
+	}
+}
+
+func TestAPIKeyService_Revoke(t *testing.T) {
+	ctx := context.Background()
+	service := setup(t)
+	created, err := service.Create(ctx, "user1", dto.CreateAPIKeyRequestDTO{Scopes: []string{"read"}})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, service.Revoke(ctx, "user2", created.ID), apikey.ErrAPIKeyNotFound, "Expected only the owner to revoke the key")
+	require.NoError(t, service.Revoke(ctx, "user1", created.ID))
+	assert.ErrorIs(t, service.Revoke(ctx, "user1", created.ID), apikey.ErrAPIKeyNotFound)
+
+	_, err = service.Authenticate(ctx, created.Key)
+	assert.ErrorIs(t, err, apikey.ErrInvalidAPIKey)
+
+	keys, err := service.List(ctx, "user1")
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.NotNil(t, keys[0].RevokedAt)
+}
+
+func TestAPIKeyService_Authenticate(t *testing.T) {
+	ctx := context.Background()
+	log, _ := logger.NewLogger("info")
+	ctrl := gomock.NewController(t)
+	mockRepo := repository.NewMockIAPIKeyRepository(ctrl)
+	service := apikey.NewAPIKeyService(log, mockRepo)
+
+	_, err := service.Authenticate(ctx, "not-a-key")
+	assert.ErrorIs(t, err, apikey.ErrInvalidAPIKey, "Expected a key without the prefix not to be looked up")
+
+	mockRepo.EXPECT().FindByHash(gomock.Any(), apikey.HashKey("shl_unknown")).Return(nil, nil)
+	_, err = service.Authenticate(ctx, "shl_unknown")
+	assert.ErrorIs(t, err, apikey.ErrInvalidAPIKey)
+
+	mockRepo.EXPECT().FindByHash(gomock.Any(), gomock.Any()).Return(nil, errors.New("db error"))
+	_, err = service.Authenticate(ctx, "shl_failing")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, apikey.ErrInvalidAPIKey, "Expected store errors not to reject the key")
+}
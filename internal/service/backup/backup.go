@@ -1,93 +1,272 @@
 // Package backup provides functionality for managing backups of URL data.
 // It defines an interface `IBackupService` and implements a service `BackupService`
 // that can load and save data to a backup file.
+//
+// A backup file is a sequence of JSON lines. The first line is a dto.BackupHeaderDTO with
+// the format version, the creation time, the number of records and a checksum of the
+// record lines; every following line is a dto.BackupRecordDTO holding all fields of a URL.
+// Files written before versioning consist of dto.URLFileDataDTO lines only; they are
+// still read and are rewritten in the current format on the next save.
 package backup
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"time"
 
 	"github.com/GlebRadaev/shlink/internal/dto"
-	"github.com/GlebRadaev/shlink/internal/utils"
+	"github.com/GlebRadaev/shlink/internal/model"
 )
 
+// Format is the marker written to the header of versioned backup files.
+const Format = "shlink-backup"
+
+// Versions of the backup format.
+const (
+	LegacyVersion  = 1 // Lines of dto.URLFileDataDTO without a header.
+	CurrentVersion = 2 // Header followed by dto.BackupRecordDTO lines.
+)
+
+// maxLineSize is the longest line the backup file may contain.
+const maxLineSize = 1 << 20
+
 // IBackupService defines the methods for loading and saving backup data.
 type IBackupService interface {
 	// LoadData loads backup data from a file.
-	// It returns a map where the key is the short URL and the value is the original URL.
-	LoadData() (map[string]string, error)
+	// It returns the stored URLs, migrating legacy backups to the current model.
+	LoadData() ([]*model.URL, error)
 
-	// SaveData saves a map of short URLs and original URLs to a backup file.
-	// It will overwrite existing data in the backup file.
-	SaveData(data map[string]string) error
+	// SaveData merges the URLs into the backup file, replacing stored URLs with the same short ID.
+	// The file is rewritten in the current format.
+	SaveData(urls []*model.URL) error
 }
 
 // BackupService provides the implementation of IBackupService.
 type BackupService struct {
 	// filename is the path to the backup file.
 	filename string
+	// now returns the current time, used to stamp the header.
+	now func() time.Time
 }
 
 // NewBackupService creates a new instance of BackupService.
 func NewBackupService(filename string) *BackupService {
-	return &BackupService{filename: filename}
+	return &BackupService{filename: filename, now: time.Now}
 }
 
-// LoadData loads backup data from the backup file.
-func (b *BackupService) LoadData() (map[string]string, error) {
+// LoadData loads backup data from the backup file. A missing file is an empty backup.
+func (b *BackupService) LoadData() ([]*model.URL, error) {
 	file, err := os.Open(b.filename)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			return make(map[string]string), nil
+			return []*model.URL{}, nil
 		}
 		return nil, err
 	}
 	defer file.Close()
 
-	data := make(map[string]string)
 	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		var url dto.URLFileDataDTO
-		if err := json.Unmarshal(scanner.Bytes(), &url); err != nil {
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineSize)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
 			return nil, err
 		}
-		data[url.ShortURL] = url.OriginalURL
+		return []*model.URL{}, nil
+	}
+	var header dto.BackupHeaderDTO
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Format != Format {
+		return loadLegacy(scanner)
+	}
+	if header.Version != CurrentVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", header.Version)
+	}
+	return loadRecords(scanner, header)
+}
+
+// loadRecords reads the records following the header and checks them against it.
+func loadRecords(scanner *bufio.Scanner, header dto.BackupHeaderDTO) ([]*model.URL, error) {
+	urls := make([]*model.URL, 0, header.Count)
+	hash := sha256.New()
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		hash.Write(line)
+		hash.Write([]byte("\n"))
+		var record dto.BackupRecordDTO
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, fmt.Errorf("backup record %d: %w", len(urls)+1, err)
+		}
+		urls = append(urls, fromRecord(record))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(urls) != header.Count {
+		return nil, fmt.Errorf("backup record count mismatch: header has %d, file has %d", header.Count, len(urls))
+	}
+	if checksum := hex.EncodeToString(hash.Sum(nil)); checksum != header.Checksum {
+		return nil, errors.New("backup checksum mismatch")
+	}
+	return urls, nil
+}
+
+// loadLegacy reads a backup written before versioning, starting from the line already scanned.
+// Only the short ID and the original URL are known for such records.
+func loadLegacy(scanner *bufio.Scanner) ([]*model.URL, error) {
+	var urls []*model.URL
+	for {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			var legacy dto.URLFileDataDTO
+			if err := json.Unmarshal(line, &legacy); err != nil {
+				return nil, err
+			}
+			urls = append(urls, &model.URL{ShortID: legacy.ShortURL, OriginalURL: legacy.OriginalURL})
+		}
+		if !scanner.Scan() {
+			break
+		}
 	}
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
-	return data, nil
+	return urls, nil
 }
 
-// SaveData saves a map of short URLs to original URLs in the backup file.
-func (b *BackupService) SaveData(data map[string]string) error {
+// SaveData merges the URLs with the existing backup and writes the result to the backup file.
+// Records are written in short ID order, so unchanged data produces identical records.
+func (b *BackupService) SaveData(urls []*model.URL) error {
 	existingData, err := b.LoadData()
 	if err != nil {
 		return err
 	}
 
-	for shortURL, originalURL := range data {
-		existingData[shortURL] = originalURL
+	merged := make(map[string]*model.URL, len(existingData)+len(urls))
+	for _, url := range existingData {
+		merged[url.ShortID] = url
 	}
-
-	file, err := os.OpenFile(b.filename, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0644)
-	if err != nil {
-		return err
+	for _, url := range urls {
+		merged[url.ShortID] = url
 	}
-	defer file.Close()
+	shortIDs := make([]string, 0, len(merged))
+	for shortID := range merged {
+		shortIDs = append(shortIDs, shortID)
+	}
+	sort.Strings(shortIDs)
 
-	writer := bufio.NewWriter(file)
-	for shortURL, originalURL := range data {
-		urlDTO := dto.URLFileDataDTO{UUID: utils.GenerateUUID(), ShortURL: shortURL, OriginalURL: originalURL}
-		jsonData, err := json.Marshal(urlDTO)
+	var records bytes.Buffer
+	for _, shortID := range shortIDs {
+		jsonData, err := json.Marshal(toRecord(merged[shortID]))
 		if err != nil {
 			return err
 		}
-		if _, err := writer.WriteString(string(jsonData) + "\n"); err != nil {
+		records.Write(jsonData)
+		records.WriteByte('\n')
+	}
+	checksum := sha256.Sum256(records.Bytes())
+	header, err := json.Marshal(dto.BackupHeaderDTO{
+		Format:    Format,
+		Version:   CurrentVersion,
+		CreatedAt: b.now().UTC(),
+		Count:     len(shortIDs),
+		Checksum:  hex.EncodeToString(checksum[:]),
+	})
+	if err != nil {
+		return err
+	}
+
+	return b.replace(func(writer io.Writer) error {
+		if _, err := writer.Write(append(header, '\n')); err != nil {
 			return err
 		}
+		_, err := io.Copy(writer, &records)
+		return err
+	})
+}
+
+// replace atomically replaces the backup file with the content written by write.
+// The content goes to a temporary file in the same directory, which is synced and
+// renamed over the backup file, so a crash leaves either the old or the new backup.
+func (b *BackupService) replace(write func(io.Writer) error) error {
+	dir := filepath.Dir(b.filename)
+	file, err := os.CreateTemp(dir, filepath.Base(b.filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+
+	writer := bufio.NewWriter(file)
+	if err := write(writer); err != nil {
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if err := file.Chmod(0644); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(file.Name(), b.filename); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir flushes the directory entry changes, such as a rename, to disk.
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return file.Sync()
+}
+
+// toRecord converts a URL into its backup record.
+func toRecord(url *model.URL) dto.BackupRecordDTO {
+	return dto.BackupRecordDTO{
+		ID:           url.ID,
+		ShortID:      url.ShortID,
+		OriginalURL:  url.OriginalURL,
+		UserID:       url.UserID,
+		CreatedAt:    url.CreatedAt,
+		IsDeleted:    url.DeletedFlag,
+		IsDisabled:   url.DisabledFlag,
+		ExpiresAt:    url.ExpiresAt,
+		MaxClicks:    url.MaxClicks,
+		Clicks:       url.Clicks,
+		PasswordHash: url.PasswordHash,
+	}
+}
+
+// fromRecord converts a backup record back into a URL.
+func fromRecord(record dto.BackupRecordDTO) *model.URL {
+	return &model.URL{
+		ID:           record.ID,
+		ShortID:      record.ShortID,
+		OriginalURL:  record.OriginalURL,
+		UserID:       record.UserID,
+		CreatedAt:    record.CreatedAt,
+		DeletedFlag:  record.IsDeleted,
+		DisabledFlag: record.IsDisabled,
+		ExpiresAt:    record.ExpiresAt,
+		MaxClicks:    record.MaxClicks,
+		Clicks:       record.Clicks,
+		PasswordHash: record.PasswordHash,
 	}
-	return writer.Flush()
 }
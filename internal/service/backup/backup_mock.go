@@ -12,6 +12,7 @@ package backup
 import (
 	reflect "reflect"
 
+	model "github.com/GlebRadaev/shlink/internal/model"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -40,10 +41,10 @@ func (m *MockIBackupService) EXPECT() *MockIBackupServiceMockRecorder {
 }
 
 // LoadData mocks base method.
-func (m *MockIBackupService) LoadData() (map[string]string, error) {
+func (m *MockIBackupService) LoadData() ([]*model.URL, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "LoadData")
-	ret0, _ := ret[0].(map[string]string)
+	ret0, _ := ret[0].([]*model.URL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -55,15 +56,15 @@ func (mr *MockIBackupServiceMockRecorder) LoadData() *gomock.Call {
 }
 
 // SaveData mocks base method.
-func (m *MockIBackupService) SaveData(data map[string]string) error {
+func (m *MockIBackupService) SaveData(urls []*model.URL) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveData", data)
+	ret := m.ctrl.Call(m, "SaveData", urls)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SaveData indicates an expected call of SaveData.
-func (mr *MockIBackupServiceMockRecorder) SaveData(data any) *gomock.Call {
+func (mr *MockIBackupServiceMockRecorder) SaveData(urls any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveData", reflect.TypeOf((*MockIBackupService)(nil).SaveData), data)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveData", reflect.TypeOf((*MockIBackupService)(nil).SaveData), urls)
 }
@@ -1,12 +1,17 @@
 package backup_test
 
 import (
+	"bufio"
 	"encoding/json"
 	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/GlebRadaev/shlink/internal/dto"
+	"github.com/GlebRadaev/shlink/internal/model"
 	"github.com/GlebRadaev/shlink/internal/service/backup"
 	"github.com/stretchr/testify/assert"
 )
@@ -27,26 +32,45 @@ func setupBackupServiceTest(t *testing.T, content string) (*backup.BackupService
 	return service, file.Name()
 }
 
+func readBackupFile(t *testing.T, filename string) (dto.BackupHeaderDTO, []string) {
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("Failed to open the backup file: %v", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	var header dto.BackupHeaderDTO
+	var lines []string
+	for scanner.Scan() {
+		if header.Format == "" {
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), &header))
+			continue
+		}
+		lines = append(lines, scanner.Text())
+	}
+	return header, lines
+}
+
 func TestBackupService_LoadData(t *testing.T) {
 	tests := []struct {
 		name          string
 		fileContent   string
 		setupFunc     func(filename string) // Функция для настройки условий теста
-		expectedData  map[string]string
+		expectedData  []*model.URL
 		expectedError error
 	}{
 		{
 			name:        "Load valid data",
 			fileContent: `{"uuid":"1730235510864975546","short_url":"vgWhy9ow","original_url":"http://example.com"}` + "\n",
-			expectedData: map[string]string{
-				"vgWhy9ow": "http://example.com",
+			expectedData: []*model.URL{
+				{ShortID: "vgWhy9ow", OriginalURL: "http://example.com"},
 			},
 			expectedError: nil,
 		},
 		{
 			name:          "File not found",
 			setupFunc:     func(filename string) {}, // Не создаем файл для теста отсутствующего файла
-			expectedData:  map[string]string{},
+			expectedData:  []*model.URL{},
 			expectedError: nil,
 		},
 		{
@@ -55,6 +79,28 @@ func TestBackupService_LoadData(t *testing.T) {
 			expectedData:  nil,
 			expectedError: errors.New("invalid character 'i' looking for beginning of value"),
 		},
+		{
+			name: "Unsupported version",
+			fileContent: `{"format":"shlink-backup","version":3,"created_at":"2024-11-17T12:00:00Z","count":0,` +
+				`"checksum":"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"}` + "\n",
+			expectedData:  nil,
+			expectedError: errors.New("unsupported backup version 3"),
+		},
+		{
+			name: "Record count mismatch",
+			fileContent: `{"format":"shlink-backup","version":2,"created_at":"2024-11-17T12:00:00Z","count":1,` +
+				`"checksum":"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"}` + "\n",
+			expectedData:  nil,
+			expectedError: errors.New("backup record count mismatch: header has 1, file has 0"),
+		},
+		{
+			name: "Checksum mismatch",
+			fileContent: `{"format":"shlink-backup","version":2,"created_at":"2024-11-17T12:00:00Z","count":1,` +
+				`"checksum":"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"}` + "\n" +
+				`{"short_id":"vgWhy9ow","original_url":"http://example.com","created_at":"2024-11-17T12:00:00Z"}` + "\n",
+			expectedData:  nil,
+			expectedError: errors.New("backup checksum mismatch"),
+		},
 	}
 
 	for _, tt := range tests {
@@ -88,22 +134,22 @@ func TestBackupService_LoadData(t *testing.T) {
 func TestBackupService_SaveData(t *testing.T) {
 	tests := []struct {
 		name          string
-		inputData     map[string]string
+		inputData     []*model.URL
 		expectedError error
 	}{
 		{
 			name: "Successful save",
-			inputData: map[string]string{
-				"short1": "https://example.com",
+			inputData: []*model.URL{
+				{ShortID: "short1", OriginalURL: "https://example.com"},
 			},
 			expectedError: nil,
 		},
 		{
 			name: "Failed save (invalid path)",
-			inputData: map[string]string{
-				"short1": "https://example.com",
+			inputData: []*model.URL{
+				{ShortID: "short1", OriginalURL: "https://example.com"},
 			},
-			expectedError: errors.New("open /invalid_path/file.txt: no such file or directory"),
+			expectedError: errors.New("open /invalid_path/file.txt.tmp-"),
 		},
 	}
 
@@ -123,13 +169,118 @@ func TestBackupService_SaveData(t *testing.T) {
 				assert.Contains(t, err.Error(), tt.expectedError.Error())
 			} else {
 				assert.NoError(t, err)
-				content, _ := os.ReadFile(filename)
-				var urlDTO dto.URLFileDataDTO
-				err = json.Unmarshal(content[:len(content)-1], &urlDTO)
-				assert.NoError(t, err)
-				assert.Equal(t, "short1", urlDTO.ShortURL)
-				assert.Equal(t, "https://example.com", urlDTO.OriginalURL)
+				header, lines := readBackupFile(t, filename)
+				assert.Equal(t, backup.Format, header.Format)
+				assert.Equal(t, backup.CurrentVersion, header.Version)
+				assert.Equal(t, 1, header.Count)
+				assert.False(t, header.CreatedAt.IsZero())
+				var record dto.BackupRecordDTO
+				assert.Len(t, lines, 1)
+				assert.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+				assert.Equal(t, "short1", record.ShortID)
+				assert.Equal(t, "https://example.com", record.OriginalURL)
 			}
 		})
 	}
 }
+
+func TestBackupService_RoundTrip(t *testing.T) {
+	service, filename := setupBackupServiceTest(t, "")
+	created := time.Date(2024, time.November, 17, 12, 0, 0, 0, time.UTC)
+	expires := created.Add(24 * time.Hour)
+	maxClicks := 10
+	urls := []*model.URL{
+		{
+			ID:           7,
+			ShortID:      "short2",
+			OriginalURL:  "https://example.com/protected",
+			UserID:       "user1",
+			CreatedAt:    created,
+			ExpiresAt:    &expires,
+			MaxClicks:    &maxClicks,
+			Clicks:       3,
+			PasswordHash: "$2a$10$hash",
+		},
+		{ShortID: "short1", OriginalURL: "https://example.com/deleted", UserID: "user2", CreatedAt: created, DeletedFlag: true},
+	}
+
+	assert.NoError(t, service.SaveData(urls))
+	loaded, err := service.LoadData()
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.URL{urls[1], urls[0]}, loaded)
+
+	_, first := readBackupFile(t, filename)
+	assert.NoError(t, service.SaveData(urls))
+	_, second := readBackupFile(t, filename)
+	assert.Equal(t, first, second, "Saving the same data should produce the same records")
+}
+
+func TestBackupService_SaveDataReplacesFile(t *testing.T) {
+	service, filename := setupBackupServiceTest(t, "")
+	assert.NoError(t, service.SaveData([]*model.URL{{ShortID: "short1", OriginalURL: "https://example.com/1"}}))
+
+	before, err := os.Open(filename)
+	assert.NoError(t, err)
+	defer before.Close()
+	assert.NoError(t, service.SaveData([]*model.URL{{ShortID: "short2", OriginalURL: "https://example.com/2"}}))
+
+	_, lines := readBackupFile(t, filename)
+	assert.Len(t, lines, 2)
+	old := bufio.NewScanner(before)
+	assert.True(t, old.Scan())
+	assert.True(t, old.Scan())
+	assert.False(t, old.Scan(), "The previous backup should be left untouched")
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1, "No temporary files should be left behind")
+	info, err := os.Stat(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+}
+
+func TestBackupService_SaveDataMergesExisting(t *testing.T) {
+	service, _ := setupBackupServiceTest(t, "")
+	created := time.Date(2024, time.November, 17, 12, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, service.SaveData([]*model.URL{
+		{ShortID: "short1", OriginalURL: "https://example.com/1", CreatedAt: created},
+		{ShortID: "short2", OriginalURL: "https://example.com/2", CreatedAt: created},
+	}))
+	assert.NoError(t, service.SaveData([]*model.URL{
+		{ShortID: "short2", OriginalURL: "https://example.com/2", CreatedAt: created, DeletedFlag: true},
+		{ShortID: "short3", OriginalURL: "https://example.com/3", CreatedAt: created},
+	}))
+
+	loaded, err := service.LoadData()
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.URL{
+		{ShortID: "short1", OriginalURL: "https://example.com/1", CreatedAt: created},
+		{ShortID: "short2", OriginalURL: "https://example.com/2", CreatedAt: created, DeletedFlag: true},
+		{ShortID: "short3", OriginalURL: "https://example.com/3", CreatedAt: created},
+	}, loaded)
+}
+
+func TestBackupService_MigratesLegacy(t *testing.T) {
+	legacy := strings.Join([]string{
+		`{"uuid":"1","short_url":"short1","original_url":"https://example.com/1"}`,
+		`{"uuid":"2","short_url":"short2","original_url":"https://example.com/2"}`,
+	}, "\n") + "\n"
+	service, filename := setupBackupServiceTest(t, legacy)
+	created := time.Date(2024, time.November, 17, 12, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, service.SaveData([]*model.URL{
+		{ShortID: "short3", OriginalURL: "https://example.com/3", UserID: "user1", CreatedAt: created},
+	}))
+
+	header, lines := readBackupFile(t, filename)
+	assert.Equal(t, backup.CurrentVersion, header.Version)
+	assert.Equal(t, 3, header.Count)
+	assert.Len(t, lines, 3)
+	loaded, err := service.LoadData()
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.URL{
+		{ShortID: "short1", OriginalURL: "https://example.com/1"},
+		{ShortID: "short2", OriginalURL: "https://example.com/2"},
+		{ShortID: "short3", OriginalURL: "https://example.com/3", UserID: "user1", CreatedAt: created},
+	}, loaded)
+}
@@ -8,14 +8,24 @@
 // - URLService: Handles the business logic for URL shortening, management, and retrieval.
 // - BackupService: Manages data backup and restoration, including saving and loading URL data.
 // - HealthService: Provides health check endpoints for monitoring service status.
+// - AnalyticsService: Records redirects and aggregates them into per-link statistics.
+// - APIKeyService: Manages the API keys that let server-to-server clients act on behalf of a user.
+// - AccountService: Signs users in with their OpenID Connect accounts and lets them claim their anonymous links.
+// - AdminService: Lets administrators look up, disable and restore the links of any user, with an audit log.
 package service
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/GlebRadaev/shlink/internal/config"
 	"github.com/GlebRadaev/shlink/internal/logger"
+	"github.com/GlebRadaev/shlink/internal/metrics"
 	"github.com/GlebRadaev/shlink/internal/repository"
+	"github.com/GlebRadaev/shlink/internal/service/account"
+	"github.com/GlebRadaev/shlink/internal/service/admin"
+	"github.com/GlebRadaev/shlink/internal/service/analytics"
+	"github.com/GlebRadaev/shlink/internal/service/apikey"
 	"github.com/GlebRadaev/shlink/internal/service/backup"
 	"github.com/GlebRadaev/shlink/internal/service/health"
 	"github.com/GlebRadaev/shlink/internal/service/url"
@@ -25,9 +35,13 @@ import (
 // Services aggregates the primary services in the application: URLService, BackupService, and HealthService.
 // It is used to interact with the core functionalities of URL shortening, backup management, and health checks.
 type Services struct {
-	URLService    *url.URLService       // Service for shortening URLs and managing URL data.
-	BackupService *backup.BackupService // Service for performing data backup and restoration.
-	HealthService *health.HealthService // Service for monitoring the application's health.
+	URLService       *url.URLService             // Service for shortening URLs and managing URL data.
+	BackupService    *backup.BackupService       // Service for performing data backup and restoration.
+	HealthService    *health.HealthService       // Service for monitoring the application's health.
+	AnalyticsService *analytics.AnalyticsService // Service for recording clicks and building link statistics.
+	APIKeyService    *apikey.APIKeyService       // Service for managing and authenticating API keys.
+	AccountService   *account.AccountService     // Service for signing users in with their accounts.
+	AdminService     *admin.AdminService         // Service for the cross-user actions of administrators.
 }
 
 // URLService is an alias for url.URLService, providing the URL service functionalities.
@@ -36,17 +50,48 @@ type URLService = url.URLService
 // HealthService is an alias for health.HealthService, providing health check functionalities.
 type HealthService = health.HealthService
 
+// AnalyticsService is an alias for analytics.AnalyticsService, providing click analytics functionalities.
+type AnalyticsService = analytics.AnalyticsService
+
+// APIKeyService is an alias for apikey.APIKeyService, providing API key functionalities.
+type APIKeyService = apikey.APIKeyService
+
+// AccountService is an alias for account.AccountService, providing sign-in functionalities.
+type AccountService = account.AccountService
+
+// AdminService is an alias for admin.AdminService, providing administration functionalities.
+type AdminService = admin.AdminService
+
 // NewServiceFactory initializes and returns an instance of Services, containing all core services
-// needed to operate the system.
-func NewServiceFactory(ctx context.Context, cfg *config.Config, log *logger.Logger, pool *taskmanager.WorkerPool, repos *repository.Repositories) *Services {
+// needed to operate the system. Backup durations are recorded in m, which may be nil.
+func NewServiceFactory(ctx context.Context, cfg *config.Config, log *logger.Logger, pool *taskmanager.WorkerPool, repos *repository.Repositories, m *metrics.Metrics) *Services {
 	logger := log.Named("ServiceFactory")
 
 	backupService := backup.NewBackupService(cfg.FileStoragePath)
 	logger.Info("Backup service up.")
-	urlService := url.NewURLService(cfg, log, pool, backupService, repos.URLRepo)
+	urlService := url.NewURLService(cfg, log, pool, m.InstrumentBackup(backupService), repos.URLRepo, repos.TaskStatusRepo)
 	logger.Info("URL service up.")
+	if err := pool.RegisterJob("expire_urls", fmt.Sprintf("@every %s", cfg.ExpiredSweepInterval), taskmanager.ExpireTask{}); err != nil {
+		logger.Errorf("Failed to schedule expired links sweep: %v", err)
+	}
+	if urlService.IsJournaled() {
+		if err := pool.EnqueueEvery(cfg.JournalCompactInterval, taskmanager.CompactJournalTask{}); err != nil {
+			logger.Errorf("Failed to schedule journal compaction: %v", err)
+		}
+	}
 	healthService := health.NewHealthService(cfg, log, repos.URLRepo)
 	logger.Info("Health service up.")
+	analyticsService := analytics.NewAnalyticsService(cfg, log, pool, repos.ClickRepo, repos.URLRepo)
+	if err := pool.EnqueueEvery(cfg.ClickFlushInterval, taskmanager.FlushClicksTask{}); err != nil {
+		logger.Errorf("Failed to schedule click flushing: %v", err)
+	}
+	logger.Info("Analytics service up.")
+	apiKeyService := apikey.NewAPIKeyService(log, repos.APIKeyRepo)
+	logger.Info("API key service up.")
+	accountService := account.NewAccountService(log, repos.IdentityRepo, repos.URLRepo)
+	logger.Info("Account service up.")
+	adminService := admin.NewAdminService(cfg, log, repos.URLRepo, repos.IdentityRepo, repos.AuditRepo, urlService, pool)
+	logger.Info("Admin service up.")
 
 	if err := urlService.LoadData(ctx); err != nil {
 		logger.Errorf("Failed to load data: %v", err)
@@ -55,8 +100,12 @@ func NewServiceFactory(ctx context.Context, cfg *config.Config, log *logger.Logg
 	}
 
 	return &Services{
-		URLService:    urlService,
-		BackupService: backupService,
-		HealthService: healthService,
+		URLService:       urlService,
+		BackupService:    backupService,
+		HealthService:    healthService,
+		AnalyticsService: analyticsService,
+		APIKeyService:    apiKeyService,
+		AccountService:   accountService,
+		AdminService:     adminService,
 	}
 }
@@ -0,0 +1,82 @@
+package url
+
+import (
+	"sync"
+	"time"
+)
+
+// attemptWindow holds the number of failed attempts made since start.
+type attemptWindow struct {
+	start    time.Time
+	failures int
+}
+
+// attemptLimiter counts failed password attempts per key in fixed windows
+// and blocks further attempts once the limit is reached.
+type attemptLimiter struct {
+	mu          sync.Mutex
+	maxAttempts int
+	window      time.Duration
+	windows     map[string]*attemptWindow
+	lastPrune   time.Time
+	now         func() time.Time
+}
+
+// newAttemptLimiter creates a limiter allowing maxAttempts failures per key within the window.
+// A non-positive maxAttempts disables limiting.
+func newAttemptLimiter(maxAttempts int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		maxAttempts: maxAttempts,
+		window:      window,
+		windows:     make(map[string]*attemptWindow),
+		now:         time.Now,
+	}
+}
+
+// Allow reports whether another attempt may be made for the key.
+func (l *attemptLimiter) Allow(key string) bool {
+	if l.maxAttempts <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	w, ok := l.windows[key]
+	if !ok {
+		return true
+	}
+	if l.now().Sub(w.start) >= l.window {
+		delete(l.windows, key)
+		return true
+	}
+	return w.failures < l.maxAttempts
+}
+
+// Fail records a failed attempt for the key.
+func (l *attemptLimiter) Fail(key string) {
+	if l.maxAttempts <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.prune(now)
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &attemptWindow{start: now}
+		l.windows[key] = w
+	}
+	w.failures++
+}
+
+// prune drops windows that have run out, at most once per window length.
+func (l *attemptLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < l.window {
+		return
+	}
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, key)
+		}
+	}
+	l.lastPrune = now
+}
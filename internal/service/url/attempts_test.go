@@ -0,0 +1,37 @@
+package url
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttemptLimiter(t *testing.T) {
+	now := time.Date(2024, time.November, 17, 12, 0, 0, 0, time.UTC)
+	limiter := newAttemptLimiter(2, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	assert.True(t, limiter.Allow("abc"))
+	limiter.Fail("abc")
+	assert.True(t, limiter.Allow("abc"))
+	limiter.Fail("abc")
+	assert.False(t, limiter.Allow("abc"))
+	assert.True(t, limiter.Allow("other"), "limits are kept per key")
+
+	now = now.Add(time.Minute)
+	assert.True(t, limiter.Allow("abc"), "failures are forgotten after the window")
+
+	limiter.Fail("other")
+	now = now.Add(2 * time.Minute)
+	limiter.Fail("abc")
+	assert.Len(t, limiter.windows, 1, "expired windows are pruned")
+}
+
+func TestAttemptLimiter_Disabled(t *testing.T) {
+	limiter := newAttemptLimiter(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		limiter.Fail("abc")
+	}
+	assert.True(t, limiter.Allow("abc"))
+}
@@ -0,0 +1,113 @@
+package url
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/dto"
+	"github.com/GlebRadaev/shlink/internal/model"
+)
+
+// dateLayout is the layout of date-only bounds of the creation date filter.
+const dateLayout = "2006-01-02"
+
+// cursorData is the encoded form of a listing cursor. The sort is kept so a cursor
+// cannot be used with a different order than the one it was created for.
+type cursorData struct {
+	Sort        model.URLSort `json:"s"`
+	CreatedAt   time.Time     `json:"t,omitempty"`
+	OriginalURL string        `json:"u,omitempty"`
+	ShortID     string        `json:"id"`
+}
+
+// parseListQuery validates the listing options and converts them into a repository query.
+func (s *URLService) parseListQuery(params dto.GetUserURLsRequestDTO) (model.URLListQuery, error) {
+	query := model.URLListQuery{Sort: model.SortCreatedAtDesc, Limit: s.config.UserURLsPageSize, Search: params.Search}
+	if params.Limit != "" {
+		limit, err := strconv.Atoi(params.Limit)
+		if err != nil || limit < 1 || limit > s.config.UserURLsMaxPageSize {
+			return query, errors.New("invalid limit")
+		}
+		query.Limit = limit
+	}
+	switch sort := model.URLSort(params.Sort); sort {
+	case "":
+	case model.SortCreatedAtDesc, model.SortCreatedAtAsc, model.SortOriginalURLAsc, model.SortOriginalURLDesc:
+		query.Sort = sort
+	default:
+		return query, errors.New("invalid sort")
+	}
+	switch params.Status {
+	case "", "all":
+	case "active", "deleted":
+		deleted := params.Status == "deleted"
+		query.Deleted = &deleted
+	default:
+		return query, errors.New("invalid status")
+	}
+	if params.CreatedFrom != "" {
+		from, _, err := parseTimeOrDate(params.CreatedFrom)
+		if err != nil {
+			return query, errors.New("invalid created_from")
+		}
+		query.CreatedFrom = &from
+	}
+	if params.CreatedTo != "" {
+		to, dateOnly, err := parseTimeOrDate(params.CreatedTo)
+		if err != nil {
+			return query, errors.New("invalid created_to")
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		query.CreatedTo = &to
+	}
+	if params.Cursor != "" {
+		cursor, err := decodeCursor(params.Cursor, query.Sort)
+		if err != nil {
+			return query, err
+		}
+		query.After = cursor
+	}
+	return query, nil
+}
+
+// parseTimeOrDate parses an RFC 3339 time or a date, reporting whether only a date was given.
+func parseTimeOrDate(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.Parse(dateLayout, value)
+	return t, true, err
+}
+
+// encodeCursor returns the cursor pointing right after the URL in the given order.
+func encodeCursor(sort model.URLSort, url *model.URL) string {
+	data := cursorData{Sort: sort, ShortID: url.ShortID}
+	if sort.ByOriginalURL() {
+		data.OriginalURL = url.OriginalURL
+	} else {
+		data.CreatedAt = url.CreatedAt
+	}
+	raw, _ := json.Marshal(data)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor parses a cursor created by encodeCursor for the given order.
+func decodeCursor(cursor string, sort model.URLSort) (*model.URLCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var data cursorData
+	if err := json.Unmarshal(raw, &data); err != nil || data.ShortID == "" {
+		return nil, errors.New("invalid cursor")
+	}
+	if data.Sort != sort {
+		return nil, errors.New("cursor does not match sort")
+	}
+	return &model.URLCursor{CreatedAt: data.CreatedAt, OriginalURL: data.OriginalURL, ShortID: data.ShortID}, nil
+}
@@ -6,32 +6,78 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/GlebRadaev/shlink/internal/config"
 	"github.com/GlebRadaev/shlink/internal/dto"
+	"github.com/GlebRadaev/shlink/internal/idgen"
 	"github.com/GlebRadaev/shlink/internal/interfaces"
 	"github.com/GlebRadaev/shlink/internal/logger"
 	"github.com/GlebRadaev/shlink/internal/model"
 	"github.com/GlebRadaev/shlink/internal/service/backup"
 	"github.com/GlebRadaev/shlink/internal/taskmanager"
+	"github.com/GlebRadaev/shlink/internal/tracing"
 	"github.com/GlebRadaev/shlink/internal/utils"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 )
 
-// MaxIDLength defines the maximum length for a shortened URL ID.
+// MinAliasLength and MaxAliasLength bound the length of a user-chosen alias.
+// MaxPasswordLength bounds the length of a link password.
 const (
-	MaxIDLength = 8
+	MinAliasLength = 3
+	MaxAliasLength = 32
+
+	// MaxPasswordLength is the longest password bcrypt can hash without truncation.
+	MaxPasswordLength = 72
+)
+
+// Errors returned by URLService.
+var (
+	// ErrAliasTaken is returned when the requested alias is already used by another URL.
+	ErrAliasTaken = errors.New("conflict: alias already taken")
+	// ErrURLExpired is returned when the URL has passed its expiration date or used up its click budget.
+	ErrURLExpired = errors.New("URL is expired")
+	// ErrInvalidID is returned when the short ID is neither a generated ID nor a valid alias.
+	ErrInvalidID = errors.New("invalid ID")
+	// ErrURLNotFound is returned when the URL does not exist or belongs to another user.
+	ErrURLNotFound = errors.New("URL not found")
+	// ErrURLDeleted is returned when the URL is marked as deleted.
+	ErrURLDeleted = errors.New("URL is deleted")
+	// ErrURLDisabled is returned when the URL was disabled by an administrator.
+	ErrURLDisabled = errors.New("URL is disabled")
+	// ErrTargetTaken is returned when the new original URL is already shortened under another short ID.
+	ErrTargetTaken = errors.New("conflict: URL already shortened")
+	// ErrPasswordRequired is returned when the URL is password protected and no password was given.
+	ErrPasswordRequired = errors.New("password required")
+	// ErrWrongPassword is returned when the given password does not match the URL password.
+	ErrWrongPassword = errors.New("wrong password")
+	// ErrTooManyAttempts is returned when too many wrong passwords were given for the URL recently.
+	ErrTooManyAttempts = errors.New("too many password attempts")
+	// ErrTaskNotFound is returned when the task does not exist or was enqueued by another user.
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrQueueFull is returned when the task queue has no room for a task, until the workers catch up.
+	ErrQueueFull = errors.New("task queue is full, try again later")
 )
 
+// tracer starts a span for each URLService method and each batch of a delete task.
+var tracer = otel.Tracer("github.com/GlebRadaev/shlink/internal/service/url")
+
 // URLService handles the business logic for shortening URLs
 // and interacts with repositories, backups, and tasks related to URL management.
 type URLService struct {
-	log      *zap.SugaredLogger        // Logger for the service
-	config   *config.Config            // Configuration settings for the service
-	taskPool *taskmanager.WorkerPool   // Worker pool for handling tasks
-	backup   backup.IBackupService     // Backup service for saving and loading URL data
-	urlRepo  interfaces.IURLRepository // Repository for interacting with stored URLs
+	log      *zap.SugaredLogger               // Logger for the service
+	config   *config.Config                   // Configuration settings for the service
+	taskPool *taskmanager.WorkerPool          // Worker pool for handling tasks
+	backup   backup.IBackupService            // Backup service for saving and loading URL data
+	urlRepo  interfaces.IURLRepository        // Repository for interacting with stored URLs
+	statuses interfaces.ITaskStatusRepository // Repository for the states of the delete tasks
+	attempts *attemptLimiter                  // Limiter for wrong password attempts per URL
+	ids      interfaces.IIDGenerator          // Generator of short IDs for new URLs
 }
 
 // NewURLService creates a new instance of URLService with the specified configurations
@@ -42,139 +88,375 @@ func NewURLService(
 	pool *taskmanager.WorkerPool,
 	backup backup.IBackupService,
 	urlRepo interfaces.IURLRepository,
+	statuses interfaces.ITaskStatusRepository,
 ) *URLService {
 	service := &URLService{
 		log:      log.Named("URLService"),
 		config:   config,
 		backup:   backup,
 		urlRepo:  urlRepo,
+		statuses: statuses,
 		taskPool: pool,
+		attempts: newAttemptLimiter(config.PasswordMaxAttempts, config.PasswordAttemptWindow),
 	}
+	ids, err := idgen.New(config)
+	if err != nil {
+		service.log.Errorf("Failed to create %s id generator, using random ids: %v", config.IDStrategy, err)
+		ids, _ = idgen.NewRandomGenerator(idgen.DefaultAlphabet, idgen.DefaultLength)
+	}
+	service.ids = ids
 	pool.RegisterHandler("delete_urls_task", service.ProcessDeleteURLsTask)
+	pool.RegisterHandler("expire_urls_task", service.ProcessExpireURLsTask)
+	pool.RegisterHandler("compact_journal_task", service.ProcessCompactJournalTask)
 	return service
 }
 
 // LoadData loads previously backed-up URL data and inserts them into the repository.
-func (s *URLService) LoadData(ctx context.Context) error {
-	data, err := s.backup.LoadData()
+// A journaled repository is restored from the backup and replays its journal on top of it.
+func (s *URLService) LoadData(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "URLService.LoadData")
+	defer tracing.End(span, &err)
+	urls, err := s.backup.LoadData()
 	if err != nil {
 		return err
 	}
-	for shortURL, originalURL := range data {
-		modelURL := &model.URL{
-			ShortID:     shortURL,
-			OriginalURL: originalURL,
+	if repo, ok := s.urlRepo.(interfaces.IJournaledRepository); ok {
+		replayed, err := repo.Restore(ctx, urls)
+		if err != nil {
+			return err
 		}
-		_, _ = s.urlRepo.Insert(ctx, modelURL)
+		s.log.Infof("Restored %d URLs and replayed %d journal entries", len(urls), replayed)
+		return nil
+	}
+	for _, url := range urls {
+		_, _ = s.urlRepo.Insert(ctx, url)
 	}
 	return nil
 }
 
 // SaveData retrieves all URLs and backs them up to persistent storage.
-func (s *URLService) SaveData(ctx context.Context) error {
+// The journal of a journaled repository is compacted into the backup.
+func (s *URLService) SaveData(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "URLService.SaveData")
+	defer tracing.End(span, &err)
+	if repo, ok := s.urlRepo.(interfaces.IJournaledRepository); ok {
+		return repo.Compact(ctx, s.backup.SaveData)
+	}
 	urls, err := s.urlRepo.List(ctx)
 	if err != nil {
 		return err
 	}
-	data := make(map[string]string)
-	for _, url := range urls {
-		data[url.ShortID] = url.OriginalURL
-	}
-	if err := s.backup.SaveData(data); err != nil {
+	if err := s.backup.SaveData(urls); err != nil {
 		return err
 	}
 	return nil
 }
 
 // ProcessDeleteURLsTask processes a task that deletes a list of URLs for a specific user.
-func (s *URLService) ProcessDeleteURLsTask(ctx context.Context, task taskmanager.Task) error {
+// Every batch of URLs is deleted even if another one fails, and the result of each URL is
+// recorded in the status of the task. The URLs that already succeeded in a previous attempt
+// are skipped, and an error is returned if any URL failed, so that the task is retried.
+func (s *URLService) ProcessDeleteURLsTask(ctx context.Context, task taskmanager.Task) (err error) {
+	ctx, span := tracer.Start(ctx, "URLService.ProcessDeleteURLsTask")
+	defer tracing.End(span, &err)
 	deleteTask, ok := task.(taskmanager.DeleteTask)
 	if !ok {
 		return fmt.Errorf("invalid task type: expected DeleteTask")
 	}
 	s.log.Infof("Starting delete task for userID=%s with %d URLs", deleteTask.UserID, len(deleteTask.URLs))
+	span.SetAttributes(attribute.Int("urls.count", len(deleteTask.URLs)))
+
+	status, err := s.startDeleteTask(ctx, deleteTask)
+	if err != nil {
+		return err
+	}
+	pending := make([]string, 0, len(status.Items))
+	for _, item := range status.Items {
+		if item.State != model.TaskItemSucceeded {
+			pending = append(pending, item.ID)
+		}
+	}
 
 	const batchSize = 10
+	var batches [][]string
+	for i := 0; i < len(pending); i += batchSize {
+		batches = append(batches, pending[i:min(i+batchSize, len(pending))])
+	}
+	errs := make([]error, len(batches))
 	var wg sync.WaitGroup
-	errChan := make(chan error, len(deleteTask.URLs)/batchSize+1)
-	successChan := make(chan string, len(deleteTask.URLs)/batchSize+1)
-	for i := 0; i < len(deleteTask.URLs); i += batchSize {
-		end := i + batchSize
-		if end > len(deleteTask.URLs) {
-			end = len(deleteTask.URLs)
-		}
-		batch := deleteTask.URLs[i:end]
+	for i, batch := range batches {
 		s.log.Infof("Processing batch for userID=%s: %v", deleteTask.UserID, batch)
-
 		wg.Add(1)
-		go func(batch []string) {
+		go func(i int, batch []string) {
 			defer wg.Done()
+			ctx, span := tracer.Start(ctx, "URLService.ProcessDeleteURLsTask.batch", trace.WithAttributes(attribute.Int("batch.size", len(batch))))
 			err := s.urlRepo.DeleteListByUserIDAndShortIDs(ctx, deleteTask.UserID, batch)
-			if err != nil {
-				errChan <- fmt.Errorf("error deleting batch for userID=%s: %v", deleteTask.UserID, err)
-			} else {
-				successChan <- fmt.Sprintf("batch deleted for userID=%s: %v", deleteTask.UserID, batch)
-			}
-		}(batch)
+			tracing.End(span, &err)
+			errs[i] = err
+		}(i, batch)
 	}
+	wg.Wait()
 
-	go func() {
-		wg.Wait()
-		close(errChan)
-		close(successChan)
-	}()
-
-	for {
-		select {
-		case err, ok := <-errChan:
-			if ok && err != nil {
-				s.log.Errorf("Error in delete task for userID=%s: %v", deleteTask.UserID, err)
-				return err
-			}
-		case success, ok := <-successChan:
-			if ok {
-				s.log.Infof("Success: %s", success)
-			}
+	results := make(map[string]error, len(pending))
+	var failed []error
+	for i, batch := range batches {
+		if errs[i] != nil {
+			s.log.Errorf("Error deleting batch for userID=%s: %v", deleteTask.UserID, errs[i])
+			failed = append(failed, errs[i])
 		}
-		if len(errChan) == 0 && len(successChan) == 0 {
-			break
+		for _, shortID := range batch {
+			results[shortID] = errs[i]
 		}
 	}
+	if err := s.finishDeleteTask(ctx, status, results); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to delete %d of %d batches for userID=%s: %w", len(failed), len(batches),
+			deleteTask.UserID, errors.Join(failed...))
+	}
 	s.log.Infof("Completed delete task for userID=%s", deleteTask.UserID)
 	return nil
 }
 
+// startDeleteTask marks the status of a delete task as running and returns it. A task without
+// a status, such as one enqueued before the statuses were tracked, gets an untracked status
+// with all of its URLs pending.
+func (s *URLService) startDeleteTask(ctx context.Context, task taskmanager.DeleteTask) (*model.TaskStatus, error) {
+	var status *model.TaskStatus
+	if task.TaskID != "" {
+		var err error
+		if status, err = s.statuses.FindByID(ctx, task.TaskID); err != nil {
+			return nil, err
+		}
+	}
+	if status == nil {
+		status = &model.TaskStatus{UserID: task.UserID, Type: task.TaskType()}
+		for _, shortID := range task.URLs {
+			status.Items = append(status.Items, model.TaskItem{ID: shortID, State: model.TaskItemPending})
+		}
+		return status, nil
+	}
+	status.State = model.TaskRunning
+	status.UpdatedAt = time.Now()
+	if err := s.statuses.Update(ctx, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// finishDeleteTask records the results of the URLs processed by an attempt of a delete task
+// and the state of the task they add up to. Untracked statuses are not saved.
+func (s *URLService) finishDeleteTask(ctx context.Context, status *model.TaskStatus, results map[string]error) error {
+	failed := 0
+	for i, item := range status.Items {
+		if err, ok := results[item.ID]; ok && err != nil {
+			status.Items[i] = model.TaskItem{ID: item.ID, State: model.TaskItemFailed, Error: err.Error()}
+		} else if ok {
+			status.Items[i] = model.TaskItem{ID: item.ID, State: model.TaskItemSucceeded}
+		}
+		if status.Items[i].State == model.TaskItemFailed {
+			failed++
+		}
+	}
+	switch {
+	case failed == 0:
+		status.State, status.Error = model.TaskSucceeded, ""
+	case failed == len(status.Items):
+		status.State, status.Error = model.TaskFailed, fmt.Sprintf("failed to delete %d URLs", failed)
+	default:
+		status.State, status.Error = model.TaskPartiallyFailed, fmt.Sprintf("failed to delete %d of %d URLs", failed, len(status.Items))
+	}
+	if status.ID == "" {
+		return nil
+	}
+	status.UpdatedAt = time.Now()
+	return s.statuses.Update(ctx, status)
+}
+
+// ProcessCompactJournalTask processes a task that compacts the journal of the repository into a fresh backup.
+func (s *URLService) ProcessCompactJournalTask(ctx context.Context, task taskmanager.Task) (err error) {
+	ctx, span := tracer.Start(ctx, "URLService.ProcessCompactJournalTask")
+	defer tracing.End(span, &err)
+	if _, ok := task.(taskmanager.CompactJournalTask); !ok {
+		return fmt.Errorf("invalid task type: expected CompactJournalTask")
+	}
+	if err := s.SaveData(ctx); err != nil {
+		s.log.Errorf("Error in compact journal task: %v", err)
+		return err
+	}
+	s.log.Info("Journal compacted into a fresh backup")
+	return nil
+}
+
+// IsJournaled reports whether the repository records its changes in a journal that needs compacting.
+func (s *URLService) IsJournaled() bool {
+	_, ok := s.urlRepo.(interfaces.IJournaledRepository)
+	return ok
+}
+
+// Close closes the journal of a journaled repository.
+func (s *URLService) Close() error {
+	if repo, ok := s.urlRepo.(interfaces.IJournaledRepository); ok {
+		return repo.Close()
+	}
+	return nil
+}
+
+// ProcessExpireURLsTask processes a task that soft deletes URLs which expired by date or click budget.
+func (s *URLService) ProcessExpireURLsTask(ctx context.Context, task taskmanager.Task) (err error) {
+	ctx, span := tracer.Start(ctx, "URLService.ProcessExpireURLsTask")
+	defer tracing.End(span, &err)
+	if _, ok := task.(taskmanager.ExpireTask); !ok {
+		return fmt.Errorf("invalid task type: expected ExpireTask")
+	}
+	count, err := s.urlRepo.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		s.log.Errorf("Error in expire task: %v", err)
+		return err
+	}
+	if count > 0 {
+		s.log.Infof("Marked %d expired URLs as deleted", count)
+	}
+	return nil
+}
+
 // Shorten shortens a given URL and returns the corresponding short version.
-func (s *URLService) Shorten(ctx context.Context, userID string, url string) (string, error) {
+// If an alias is provided, it is used as the short ID instead of a generated one.
+func (s *URLService) Shorten(ctx context.Context, userID string, data dto.ShortenJSONRequestDTO) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "URLService.Shorten")
+	defer tracing.End(span, &err)
+	url := data.URL
 	s.log.Infof("Attempting to shorten URL: %s", url)
-	_, err := utils.ValidateURL(url)
+	_, err = utils.ValidateURL(url)
 	if err != nil {
 		s.log.Warnf("Invalid URL: %s, error: %v", url, err)
 		return "", err
 	}
-	generateID := utils.Generate(MaxIDLength)
-	modelURL := model.URL{
-		ShortID:     generateID,
-		OriginalURL: url,
-		UserID:      userID,
+	if err := validateExpiration(data.ExpiresAt, data.MaxClicks); err != nil {
+		s.log.Warnf("Invalid expiration for URL: %s, error: %v", url, err)
+		return "", err
+	}
+	if data.Alias != "" {
+		if err := s.checkAlias(ctx, data.Alias, url); err != nil {
+			return "", err
+		}
+	}
+	passwordHash, err := hashPassword(data.Password)
+	if err != nil {
+		s.log.Warnf("Invalid password for URL: %s, error: %v", url, err)
+		return "", err
+	}
+	var newURL *model.URL
+	var shortID string
+	for attempt := 1; ; attempt++ {
+		shortID = data.Alias
+		if shortID == "" {
+			if shortID, err = s.ids.Generate(); err != nil {
+				s.log.Errorf("Failed to generate short ID: %v", err)
+				return "", err
+			}
+		}
+		modelURL := model.URL{
+			ShortID:     shortID,
+			OriginalURL: url,
+			UserID:      userID,
+			ExpiresAt:   data.ExpiresAt,
+			MaxClicks:   data.MaxClicks,
+
+			PasswordHash: passwordHash,
+		}
+		newURL, err = s.urlRepo.Insert(ctx, &modelURL)
+		if errors.Is(err, interfaces.ErrShortIDTaken) {
+			if data.Alias != "" {
+				s.log.Warnf("Alias already taken: %s", data.Alias)
+				return "", ErrAliasTaken
+			}
+			if attempt < s.config.IDMaxAttempts {
+				s.log.Warnf("Short ID collision on %s, regenerating", shortID)
+				continue
+			}
+		}
+		break
 	}
-	newURL, err := s.urlRepo.Insert(ctx, &modelURL)
 	if err != nil {
 		s.log.Errorf("Failed to add URL to memory repository: %v", err)
 		return "", err
 	}
-	if newURL.ShortID != generateID {
+	if newURL.ShortID != shortID {
 		s.log.Infof("URL already exists: %s -> %s", newURL.OriginalURL, newURL.ShortID)
 		return fmt.Sprintf("%s/%s", s.config.BaseURL, newURL.ShortID), errors.New("conflict: URL already shortened")
 	}
 	s.log.Infof("Successfully shortened URL: %s -> %s", newURL.OriginalURL, newURL.ShortID)
-	shortID := fmt.Sprintf("%s/%s", s.config.BaseURL, newURL.ShortID)
-	return shortID, nil
+	return fmt.Sprintf("%s/%s", s.config.BaseURL, newURL.ShortID), nil
+}
+
+// checkPassword verifies the password of a protected URL and records wrong attempts.
+func (s *URLService) checkPassword(url *model.URL, password string) error {
+	if password == "" {
+		return ErrPasswordRequired
+	}
+	if !s.attempts.Allow(url.ShortID) {
+		s.log.Warnf("Too many password attempts for ID %s", url.ShortID)
+		return ErrTooManyAttempts
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(url.PasswordHash), []byte(password)); err != nil {
+		s.log.Warnf("Wrong password for ID %s", url.ShortID)
+		s.attempts.Fail(url.ShortID)
+		return ErrWrongPassword
+	}
+	return nil
+}
+
+// hashPassword returns the bcrypt hash of the password, or an empty string if no password is set.
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	if len(password) > MaxPasswordLength {
+		return "", errors.New("password is too long")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// validateExpiration checks that the optional expiration date is in the future and the click budget is positive.
+func validateExpiration(expiresAt *time.Time, maxClicks *int) error {
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return errors.New("expiration date must be in the future")
+	}
+	if maxClicks != nil && *maxClicks <= 0 {
+		return errors.New("max clicks must be positive")
+	}
+	return nil
+}
+
+// checkAlias validates a user-chosen alias and makes sure it is not used by another URL.
+func (s *URLService) checkAlias(ctx context.Context, alias, url string) error {
+	if err := utils.ValidateAlias(alias, MinAliasLength, MaxAliasLength); err != nil {
+		s.log.Warnf("Invalid alias: %s, error: %v", alias, err)
+		return err
+	}
+	existing, err := s.urlRepo.FindByID(ctx, alias)
+	if err != nil {
+		s.log.Errorf("Error checking alias %s: %v", alias, err)
+		return err
+	}
+	if existing != nil && existing.OriginalURL != url {
+		s.log.Warnf("Alias already taken: %s", alias)
+		return ErrAliasTaken
+	}
+	return nil
 }
 
 // ShortenList shortens a batch of URLs and returns the corresponding short versions.
-func (s *URLService) ShortenList(ctx context.Context, userID string, data dto.BatchShortenRequestDTO) (dto.BatchShortenResponseDTO, error) {
-	resultData := make([]dto.BatchShortenResponse, 0, len(data))
+func (s *URLService) ShortenList(ctx context.Context, userID string, data dto.BatchShortenRequestDTO) (_ dto.BatchShortenResponseDTO, err error) {
+	ctx, span := tracer.Start(ctx, "URLService.ShortenList")
+	defer tracing.End(span, &err)
+	correlationIDs := make([]string, 0, len(data))
 	insertData := make([]*model.URL, 0, len(data))
 	for _, dataInfo := range data {
 		_, err := utils.ValidateURL(dataInfo.OriginalURL)
@@ -182,88 +464,288 @@ func (s *URLService) ShortenList(ctx context.Context, userID string, data dto.Ba
 			s.log.Warnf("Invalid URL: %s, error: %v", dataInfo.OriginalURL, err)
 			continue
 		}
-		modelURL := model.URL{
-			ShortID:     utils.Generate(MaxIDLength),
+		if err := validateExpiration(dataInfo.ExpiresAt, dataInfo.MaxClicks); err != nil {
+			s.log.Warnf("Invalid expiration for URL: %s, error: %v", dataInfo.OriginalURL, err)
+			continue
+		}
+		insertData = append(insertData, &model.URL{
 			OriginalURL: dataInfo.OriginalURL,
 			UserID:      userID,
-		}
-		insertData = append(insertData, &modelURL)
-		resultData = append(resultData, dto.BatchShortenResponse{
-			CorrelationID: dataInfo.CorrelationID,
-			ShortURL:      fmt.Sprintf("%s/%s", s.config.BaseURL, modelURL.ShortID),
+			ExpiresAt:   dataInfo.ExpiresAt,
+			MaxClicks:   dataInfo.MaxClicks,
 		})
+		correlationIDs = append(correlationIDs, dataInfo.CorrelationID)
 	}
 	if len(insertData) > 0 {
-		_, err := s.urlRepo.InsertList(ctx, insertData)
-		if err != nil {
-			s.log.Errorf("Failed to add URL to memory repository: %v", err)
-			return nil, err
+		for attempt := 1; ; attempt++ {
+			for _, modelURL := range insertData {
+				shortID, err := s.ids.Generate()
+				if err != nil {
+					s.log.Errorf("Failed to generate short ID: %v", err)
+					return nil, err
+				}
+				modelURL.ShortID = shortID
+			}
+			_, err := s.urlRepo.InsertList(ctx, insertData)
+			if errors.Is(err, interfaces.ErrShortIDTaken) && attempt < s.config.IDMaxAttempts {
+				s.log.Warnf("Short ID collision in batch, regenerating")
+				continue
+			}
+			if err != nil {
+				s.log.Errorf("Failed to add URL to memory repository: %v", err)
+				return nil, err
+			}
+			break
 		}
 	}
+	resultData := make([]dto.BatchShortenResponse, 0, len(insertData))
+	for i, modelURL := range insertData {
+		resultData = append(resultData, dto.BatchShortenResponse{
+			CorrelationID: correlationIDs[i],
+			ShortURL:      fmt.Sprintf("%s/%s", s.config.BaseURL, modelURL.ShortID),
+		})
+	}
 	return resultData, nil
 }
 
 // GetOriginal retrieves the original URL associated with the given short ID.
 func (s *URLService) GetOriginal(ctx context.Context, id string) (string, error) {
+	return s.GetOriginalWithPassword(ctx, id, "")
+}
+
+// GetOriginalWithPassword retrieves the original URL by its short ID, checking the password
+// of a protected URL first. Wrong passwords are limited per URL and return ErrTooManyAttempts
+// once the limit is reached.
+func (s *URLService) GetOriginalWithPassword(ctx context.Context, id, password string) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "URLService.GetOriginalWithPassword")
+	defer tracing.End(span, &err)
 	s.log.Infof("Retrieving original URL for ID: %s", id)
-	if !utils.IsValidID(id, MaxIDLength) {
+	url, err := s.findActive(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if url.IsProtected() {
+		if err := s.checkPassword(url, password); err != nil {
+			return "", err
+		}
+	}
+	if url.MaxClicks != nil {
+		ok, err := s.urlRepo.IncrementClicks(ctx, id)
+		if err != nil {
+			s.log.Errorf("Error counting click for ID %s: %v", id, err)
+			return "", err
+		}
+		if !ok {
+			s.log.Warnf("Click budget used up for ID %s", id)
+			return "", ErrURLExpired
+		}
+	}
+	s.log.Infof("Found URL for ID %s: %s", id, url.OriginalURL)
+	return url.OriginalURL, nil
+}
+
+// GetShortURL returns the short URL for the given short ID if it would redirect,
+// without counting a click or asking for a password.
+func (s *URLService) GetShortURL(ctx context.Context, id string) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "URLService.GetShortURL")
+	defer tracing.End(span, &err)
+	if _, err := s.findActive(ctx, id); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s", s.config.BaseURL, id), nil
+}
+
+// findActive looks up a URL by its short ID and checks that it is neither deleted, disabled nor expired.
+func (s *URLService) findActive(ctx context.Context, id string) (*model.URL, error) {
+	if !s.ids.IsValid(id) && utils.ValidateAlias(id, MinAliasLength, MaxAliasLength) != nil {
 		s.log.Warnf("Invalid ID: %s", id)
-		return "", errors.New("invalid ID")
+		return nil, ErrInvalidID
 	}
 	url, err := s.urlRepo.FindByID(ctx, id)
 	if err != nil {
 		s.log.Errorf("Error retrieving URL for ID %s: %v", id, err)
-		return "", err
+		return nil, err
 	}
 	if url == nil {
 		s.log.Errorf("URL not found for ID %s", id)
-		return "", errors.New("URL not found")
+		return nil, ErrURLNotFound
 	}
 	if url.DeletedFlag {
 		s.log.Errorf("URL is deleted for ID %s", id)
-		return "", errors.New("URL is deleted")
+		return nil, ErrURLDeleted
 	}
-	s.log.Infof("Found URL for ID %s: %s", id, url.OriginalURL)
-	return url.OriginalURL, nil
+	if url.DisabledFlag {
+		s.log.Warnf("URL is disabled for ID %s", id)
+		return nil, ErrURLDisabled
+	}
+	if url.IsExpired(time.Now()) {
+		s.log.Warnf("URL is expired for ID %s", id)
+		return nil, ErrURLExpired
+	}
+	return url, nil
+}
+
+// UpdateOriginalURL changes the destination of a URL owned by the user while keeping its short ID.
+// The new destination must not be shortened under another short ID already.
+func (s *URLService) UpdateOriginalURL(ctx context.Context, userID, shortID, newURL string) (_ dto.GetUserURLsResponse, err error) {
+	ctx, span := tracer.Start(ctx, "URLService.UpdateOriginalURL")
+	defer tracing.End(span, &err)
+	s.log.Infof("Attempting to update URL for ID %s: %s", shortID, newURL)
+	if _, err := utils.ValidateURL(newURL); err != nil {
+		s.log.Warnf("Invalid URL: %s, error: %v", newURL, err)
+		return dto.GetUserURLsResponse{}, err
+	}
+	url, err := s.urlRepo.FindByID(ctx, shortID)
+	if err != nil {
+		s.log.Errorf("Error retrieving URL for ID %s: %v", shortID, err)
+		return dto.GetUserURLsResponse{}, err
+	}
+	if url == nil || url.UserID != userID {
+		s.log.Warnf("URL not found for ID %s and user ID %s", shortID, userID)
+		return dto.GetUserURLsResponse{}, ErrURLNotFound
+	}
+	if url.DeletedFlag {
+		s.log.Warnf("URL is deleted for ID %s", shortID)
+		return dto.GetUserURLsResponse{}, ErrURLDeleted
+	}
+	response := dto.GetUserURLsResponse{
+		ShortURL:    fmt.Sprintf("%s/%s", s.config.BaseURL, shortID),
+		OriginalURL: newURL,
+		CreatedAt:   url.CreatedAt,
+	}
+	if url.OriginalURL == newURL {
+		return response, nil
+	}
+	updated, err := s.urlRepo.UpdateOriginalURL(ctx, userID, shortID, newURL)
+	if err != nil {
+		if errors.Is(err, interfaces.ErrOriginalURLTaken) {
+			s.log.Warnf("URL already shortened under another ID: %s", newURL)
+			return dto.GetUserURLsResponse{}, ErrTargetTaken
+		}
+		s.log.Errorf("Error updating URL for ID %s: %v", shortID, err)
+		return dto.GetUserURLsResponse{}, err
+	}
+	if !updated {
+		s.log.Warnf("URL for ID %s was deleted concurrently", shortID)
+		return dto.GetUserURLsResponse{}, ErrURLNotFound
+	}
+	s.log.Infof("Successfully updated URL: %s -> %s", shortID, newURL)
+	return response, nil
 }
 
-// GetUserURLs retrieves all URLs shortened by a user.
-func (s *URLService) GetUserURLs(ctx context.Context, userID string) (dto.GetUserURLsResponseDTO, error) {
-	urls, err := s.urlRepo.FindListByUserID(ctx, userID)
+// GetUserURLs retrieves a page of the URLs shortened by a user, filtered and sorted by the request options.
+// The next page is requested with the returned cursor, which is empty on the last page.
+func (s *URLService) GetUserURLs(ctx context.Context, userID string, params dto.GetUserURLsRequestDTO) (_ dto.GetUserURLsPageDTO, err error) {
+	ctx, span := tracer.Start(ctx, "URLService.GetUserURLs")
+	defer tracing.End(span, &err)
+	query, err := s.parseListQuery(params)
+	if err != nil {
+		s.log.Warnf("Invalid URL listing options for user ID %s: %v", userID, err)
+		return dto.GetUserURLsPageDTO{}, err
+	}
+	limit := query.Limit
+	query.Limit = limit + 1
+	urls, err := s.urlRepo.FindListByUserID(ctx, userID, query)
 	if err != nil {
 		s.log.Errorf("Error getting URLs for user ID %s: %v", userID, err)
-		return nil, err
+		return dto.GetUserURLsPageDTO{}, err
+	}
+	var page dto.GetUserURLsPageDTO
+	if len(urls) > limit {
+		urls = urls[:limit]
+		page.NextCursor = encodeCursor(query.Sort, urls[limit-1])
 	}
 	if len(urls) == 0 {
 		s.log.Errorf("URL not found for user ID %s", userID)
-		return dto.GetUserURLsResponseDTO{}, nil
+		return page, nil
 	}
-	var responseDTO dto.GetUserURLsResponseDTO
 	for _, url := range urls {
-		responseDTO = append(responseDTO, dto.GetUserURLsResponse{
+		page.URLs = append(page.URLs, dto.GetUserURLsResponse{
 			ShortURL:    fmt.Sprintf("%s/%s", s.config.BaseURL, url.ShortID),
 			OriginalURL: url.OriginalURL,
+			CreatedAt:   url.CreatedAt,
+			IsDeleted:   url.DeletedFlag,
+			IsDisabled:  url.DisabledFlag,
 		})
 	}
-	return responseDTO, nil
+	return page, nil
 }
 
-// DeleteUserURLs schedules a task to delete multiple URLs for a specific user.
-func (s *URLService) DeleteUserURLs(ctx context.Context, userID string, urls []string) error {
-	if len(urls) == 0 {
-		return nil
-	}
+// DeleteUserURLs schedules a task to delete multiple URLs for a specific user and returns the ID
+// of the task, whose progress is given by GetTask. An error is returned if the task could not be
+// enqueued, in which case the URLs will not be deleted; it is ErrQueueFull if the queue is full.
+func (s *URLService) DeleteUserURLs(ctx context.Context, userID string, urls []string) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "URLService.DeleteUserURLs")
+	defer tracing.End(span, &err)
 	task := taskmanager.DeleteTask{
+		TaskID: utils.GenerateUUID(),
 		UserID: userID,
 		URLs:   urls,
 	}
-	err := s.taskPool.Enqueue(ctx, task)
-	if err != nil {
+	now := time.Now()
+	status := &model.TaskStatus{
+		ID:        task.TaskID,
+		UserID:    userID,
+		Type:      task.TaskType(),
+		State:     model.TaskQueued,
+		Items:     make([]model.TaskItem, 0, len(urls)),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for _, shortID := range urls {
+		status.Items = append(status.Items, model.TaskItem{ID: shortID, State: model.TaskItemPending})
+	}
+	if len(urls) == 0 {
+		status.State = model.TaskSucceeded
+	}
+	if err := s.statuses.Insert(ctx, status); err != nil {
+		s.log.Errorf("Failed to track delete task: %v", err)
+		return "", err
+	}
+	if len(urls) == 0 {
+		return status.ID, nil
+	}
+	if err := s.taskPool.Enqueue(ctx, task); err != nil {
 		s.log.Errorf("Failed to enqueue task: %v", err)
+		status.State, status.Error, status.UpdatedAt = model.TaskFailed, err.Error(), time.Now()
+		if err := s.statuses.Update(context.WithoutCancel(ctx), status); err != nil {
+			s.log.Errorf("Failed to record failed delete task %s: %v", status.ID, err)
+		}
+		if errors.Is(err, taskmanager.ErrQueueFull) {
+			return "", ErrQueueFull
+		}
+		return "", err
 	}
 
 	s.log.Infof("Starting delete task for userID=%s with %d URLs", task.UserID, len(task.URLs))
-	return nil
+	return status.ID, nil
+}
+
+// GetTask returns the state of a task enqueued by the user, with the results of its items.
+// ErrTaskNotFound is returned if the task does not exist or was enqueued by another user.
+func (s *URLService) GetTask(ctx context.Context, userID, id string) (_ *dto.TaskResponseDTO, err error) {
+	ctx, span := tracer.Start(ctx, "URLService.GetTask")
+	defer tracing.End(span, &err)
+	status, err := s.statuses.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if status == nil || status.UserID != userID {
+		return nil, ErrTaskNotFound
+	}
+	result := &dto.TaskResponseDTO{
+		ID:        status.ID,
+		Type:      status.Type,
+		State:     status.State,
+		Items:     make([]dto.TaskItemResponseDTO, 0, len(status.Items)),
+		Error:     status.Error,
+		CreatedAt: status.CreatedAt,
+		UpdatedAt: status.UpdatedAt,
+	}
+	for _, item := range status.Items {
+		result.Items = append(result.Items, dto.TaskItemResponseDTO{ID: item.ID, State: item.State, Error: item.Error})
+	}
+	return result, nil
 }
 
 // const batchSize = 10
@@ -4,19 +4,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/GlebRadaev/shlink/internal/config"
 	"github.com/GlebRadaev/shlink/internal/dto"
+	"github.com/GlebRadaev/shlink/internal/interfaces"
 	"github.com/GlebRadaev/shlink/internal/logger"
 	"github.com/GlebRadaev/shlink/internal/model"
 	"github.com/GlebRadaev/shlink/internal/repository"
+	"github.com/GlebRadaev/shlink/internal/repository/inmemory"
 	"github.com/GlebRadaev/shlink/internal/service/backup"
 	"github.com/GlebRadaev/shlink/internal/service/url"
 	"github.com/GlebRadaev/shlink/internal/taskmanager"
 	"github.com/GlebRadaev/shlink/internal/utils"
 	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -38,8 +43,7 @@ func setup(t *testing.T, ctx context.Context) (*repository.MockIURLRepository, *
 	defer pool.Shutdown()
 	mockURLRepo := repository.NewMockIURLRepository(ctrl)
 	mockBackupService := backup.NewMockIBackupService(ctrl)
-	urlService := url.NewURLService(cfg, log, pool, mockBackupService, mockURLRepo)
-	defer ctrl.Finish()
+	urlService := url.NewURLService(cfg, log, pool, mockBackupService, mockURLRepo, inmemory.NewTaskStatusStorage())
 
 	return mockURLRepo, urlService, mockBackupService, cfg, pool, nil
 }
@@ -59,7 +63,7 @@ func TestURLService_LoadData(t *testing.T) {
 		{
 			name: "LoadData success",
 			setupMock: func() {
-				mockData := map[string]string{"testID": "http://example1.com"}
+				mockData := []*model.URL{{ShortID: "testID", OriginalURL: "http://example1.com", UserID: "user1"}}
 				mockBackupService.EXPECT().LoadData().Return(mockData, nil)
 				mockURLRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(&model.URL{ShortID: "testID", OriginalURL: "http://example1.com"}, nil)
 			},
@@ -137,6 +141,34 @@ func TestURLService_SaveData(t *testing.T) {
 	}
 }
 
+func TestURLService_JournaledData(t *testing.T) {
+	ctx := context.Background()
+	_, _, _, cfg, _, err := setup(t, ctx)
+	require.NoError(t, err)
+	log, _ := logger.NewLogger("info")
+	pool := taskmanager.NewWorkerPool(ctx, 10, 1)
+	defer pool.Shutdown()
+	mockBackupService := backup.NewMockIBackupService(gomock.NewController(t))
+	journal, err := inmemory.OpenJournal(ctx, filepath.Join(t.TempDir(), "storage.journal"), inmemory.SyncAlways, 0)
+	require.NoError(t, err)
+	storage := inmemory.NewJournaledMemoryStorage(journal)
+	urlService := url.NewURLService(cfg, log, pool, mockBackupService, storage, inmemory.NewTaskStatusStorage())
+	defer urlService.Close()
+	assert.True(t, urlService.IsJournaled())
+
+	snapshot := []*model.URL{{ShortID: "snap1", OriginalURL: "http://example.com/snapshot", UserID: "user1"}}
+	mockBackupService.EXPECT().LoadData().Return(snapshot, nil)
+	require.NoError(t, urlService.LoadData(ctx))
+	_, err = storage.Insert(ctx, &model.URL{ShortID: "abc123", OriginalURL: "http://example.com/1", UserID: "user1"})
+	require.NoError(t, err)
+
+	mockBackupService.EXPECT().SaveData(gomock.Len(2)).Return(nil)
+	assert.NoError(t, urlService.ProcessCompactJournalTask(ctx, taskmanager.CompactJournalTask{}))
+	mockBackupService.EXPECT().SaveData(gomock.Any()).Return(errors.New("save data error"))
+	assert.EqualError(t, urlService.SaveData(ctx), "save data error")
+	assert.EqualError(t, urlService.ProcessCompactJournalTask(ctx, taskmanager.ExpireTask{}), "invalid task type: expected CompactJournalTask")
+}
+
 func TestURLService_processDeleteURLsTask(t *testing.T) {
 	ctx := context.Background()
 	mockURLRepo, urlService, _, _, _, err := setup(t, ctx)
@@ -199,19 +231,171 @@ func TestURLService_processDeleteURLsTask(t *testing.T) {
 	}
 }
 
-func TestURLService_Shorten(t *testing.T) {
+func TestURLService_DeleteTaskStatus(t *testing.T) {
 	ctx := context.Background()
-	mockURLRepo, urlService, _, cfg, _, err := setup(t, ctx)
+	_, _, _, cfg, _, err := setup(t, ctx)
+	require.NoError(t, err)
+	log, _ := logger.NewLogger("info")
+	pool := taskmanager.NewWorkerPool(ctx, 10, 1)
+	defer pool.Shutdown()
+	mockURLRepo := repository.NewMockIURLRepository(gomock.NewController(t))
+	statuses := inmemory.NewTaskStatusStorage()
+	urlService := url.NewURLService(cfg, log, pool, nil, mockURLRepo, statuses)
+
+	var urls []string
+	for i := 0; i < 12; i++ {
+		urls = append(urls, fmt.Sprintf("short%d", i))
+	}
+	now := time.Now()
+	status := &model.TaskStatus{ID: "task1", UserID: "user1", Type: taskmanager.DeleteTask{}.TaskType(),
+		State: model.TaskQueued, CreatedAt: now, UpdatedAt: now}
+	for _, shortID := range urls {
+		status.Items = append(status.Items, model.TaskItem{ID: shortID, State: model.TaskItemPending})
+	}
+	require.NoError(t, statuses.Insert(ctx, status))
+	task := taskmanager.DeleteTask{TaskID: "task1", UserID: "user1", URLs: urls}
+
+	mockURLRepo.EXPECT().DeleteListByUserIDAndShortIDs(gomock.Any(), "user1", urls[:10]).Return(nil)
+	mockURLRepo.EXPECT().DeleteListByUserIDAndShortIDs(gomock.Any(), "user1", urls[10:]).Return(errors.New("db error"))
+	err = urlService.ProcessDeleteURLsTask(ctx, task)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "db error")
+
+	found, err := urlService.GetTask(ctx, "user1", "task1")
+	require.NoError(t, err)
+	assert.Equal(t, model.TaskPartiallyFailed, found.State, "Expected every batch to be processed despite the failure")
+	assert.Equal(t, "failed to delete 2 of 12 URLs", found.Error)
+	require.Len(t, found.Items, 12)
+	assert.Equal(t, dto.TaskItemResponseDTO{ID: "short0", State: model.TaskItemSucceeded}, found.Items[0])
+	assert.Equal(t, dto.TaskItemResponseDTO{ID: "short11", State: model.TaskItemFailed, Error: "db error"}, found.Items[11])
+
+	_, err = urlService.GetTask(ctx, "user2", "task1")
+	assert.ErrorIs(t, err, url.ErrTaskNotFound, "Expected the task to be hidden from other users")
+	_, err = urlService.GetTask(ctx, "user1", "unknown")
+	assert.ErrorIs(t, err, url.ErrTaskNotFound)
+
+	mockURLRepo.EXPECT().DeleteListByUserIDAndShortIDs(gomock.Any(), "user1", urls[10:]).Return(nil)
+	require.NoError(t, urlService.ProcessDeleteURLsTask(ctx, task), "Expected the retry to skip the deleted URLs")
+	found, err = urlService.GetTask(ctx, "user1", "task1")
+	require.NoError(t, err)
+	assert.Equal(t, model.TaskSucceeded, found.State)
+	assert.Empty(t, found.Error)
+	assert.Equal(t, dto.TaskItemResponseDTO{ID: "short11", State: model.TaskItemSucceeded}, found.Items[11])
+
+	mockURLRepo.EXPECT().DeleteListByUserIDAndShortIDs(gomock.Any(), "user1", urls[:1]).Return(errors.New("db error"))
+	err = urlService.ProcessDeleteURLsTask(ctx, taskmanager.DeleteTask{UserID: "user1", URLs: urls[:1]})
+	assert.Error(t, err, "Expected an untracked task to be processed")
+}
+
+func TestURLService_DeleteUserURLs(t *testing.T) {
+	ctx := context.Background()
+	_, _, _, cfg, _, err := setup(t, ctx)
+	require.NoError(t, err)
+	log, _ := logger.NewLogger("info")
+	policy := taskmanager.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond,
+		PollInterval: 10 * time.Millisecond, LeaseTimeout: time.Minute}
+	pool := taskmanager.NewDurableWorkerPool(ctx, 10, 1, inmemory.NewTaskStorage(), policy)
+	defer pool.Shutdown()
+	mockURLRepo := repository.NewMockIURLRepository(gomock.NewController(t))
+	urlService := url.NewURLService(cfg, log, pool, nil, mockURLRepo, inmemory.NewTaskStatusStorage())
+
+	gomock.InOrder(
+		mockURLRepo.EXPECT().DeleteListByUserIDAndShortIDs(gomock.Any(), "user1", []string{"short1", "short2"}).Return(errors.New("db error")),
+		mockURLRepo.EXPECT().DeleteListByUserIDAndShortIDs(gomock.Any(), "user1", []string{"short1", "short2"}).Return(nil),
+	)
+	taskID, err := urlService.DeleteUserURLs(ctx, "user1", []string{"short1", "short2"})
+	require.NoError(t, err)
+	require.NotEmpty(t, taskID)
+	var found *dto.TaskResponseDTO
+	require.Eventually(t, func() bool {
+		found, err = urlService.GetTask(ctx, "user1", taskID)
+		require.NoError(t, err)
+		return found.State == model.TaskSucceeded
+	}, 5*time.Second, 10*time.Millisecond, "Expected the failed attempt to be retried")
+	assert.Equal(t, []dto.TaskItemResponseDTO{
+		{ID: "short1", State: model.TaskItemSucceeded},
+		{ID: "short2", State: model.TaskItemSucceeded},
+	}, found.Items)
+
+	taskID, err = urlService.DeleteUserURLs(ctx, "user1", nil)
+	require.NoError(t, err)
+	found, err = urlService.GetTask(ctx, "user1", taskID)
+	require.NoError(t, err)
+	assert.Equal(t, model.TaskSucceeded, found.State, "Expected an empty deletion to succeed at once")
+	assert.Empty(t, found.Items)
+
+	full := taskmanager.NewWorkerPool(ctx, 1, 0)
+	defer full.Shutdown()
+	busy := url.NewURLService(cfg, log, full, nil, mockURLRepo, inmemory.NewTaskStatusStorage())
+	require.NoError(t, full.Enqueue(ctx, taskmanager.DeleteTask{UserID: "user2"}))
+	_, err = busy.DeleteUserURLs(ctx, "user1", []string{"short1"})
+	assert.ErrorIs(t, err, url.ErrQueueFull, "Expected a full queue to be reported")
+}
+
+func TestURLService_ProcessExpireURLsTask(t *testing.T) {
+	ctx := context.Background()
+	mockURLRepo, urlService, _, _, _, err := setup(t, ctx)
 	if err != nil {
 		t.Fatalf("Failed to set up test: %v", err)
 	}
+
+	testCases := []struct {
+		name        string
+		task        taskmanager.Task
+		setupMock   func(mockURLRepo *repository.MockIURLRepository)
+		expectedErr string
+	}{
+		{
+			name: "successfully processes expire task",
+			task: taskmanager.ExpireTask{},
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().DeleteExpired(gomock.Any(), gomock.Any()).Return(int64(2), nil)
+			},
+			expectedErr: "",
+		},
+		{
+			name:        "invalid task type",
+			task:        taskmanager.DeleteTask{},
+			setupMock:   func(mockURLRepo *repository.MockIURLRepository) {},
+			expectedErr: "invalid task type",
+		},
+		{
+			name: "repository error",
+			task: taskmanager.ExpireTask{},
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().DeleteExpired(gomock.Any(), gomock.Any()).Return(int64(0), errors.New("db error"))
+			},
+			expectedErr: "db error",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMock(mockURLRepo)
+			err := urlService.ProcessExpireURLsTask(ctx, tc.task)
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestURLService_Shorten(t *testing.T) {
+	ctx := context.Background()
 	type args struct {
-		url string
+		url       string
+		alias     string
+		password  string
+		expiresAt time.Time
 	}
 	tests := []struct {
 		name      string
 		setupMock func(mockURLRepo *repository.MockIURLRepository)
 		args      args
+		want      string
 		wantErr   error
 	}{
 		{
@@ -223,36 +407,115 @@ func TestURLService_Shorten(t *testing.T) {
 			wantErr: errors.New("error inserting URL"),
 		},
 		{
-			name: "invalid URL scheme",
-			args: args{url: "httpsss://example.com"},
+			name:      "invalid URL scheme",
+			args:      args{url: "httpsss://example.com"},
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {},
+			wantErr:   errors.New("invalid URL scheme"),
+		},
+		{
+			name:      "invalid URL format (URL too long)",
+			args:      args{url: string(make([]byte, 2048+1))},
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {},
+			wantErr:   errors.New("invalid URL format"),
+		},
+		{
+			name: "generated ID collision is retried",
+			args: args{url: "https://example.com/retry"},
 			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
-				mockURLRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(nil, errors.New("invalid URL scheme"))
+				gomock.InOrder(
+					mockURLRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(nil, interfaces.ErrShortIDTaken),
+					mockURLRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, u *model.URL) (*model.URL, error) {
+						assert.Equal(t, "https://example.com/retry", u.OriginalURL)
+						return u, nil
+					}),
+				)
 			},
-			wantErr: errors.New("invalid URL scheme"),
+			wantErr: nil,
 		},
 		{
-			name: "invalid URL format (URL too long)",
-			args: args{url: string(make([]byte, 2048+1))},
+			name: "valid alias",
+			args: args{url: "https://example.com/spring", alias: "spring-sale"},
 			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
-				mockURLRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(nil, errors.New("invalid URL format"))
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "spring-sale").Return(nil, nil)
+				mockURLRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, u *model.URL) (*model.URL, error) {
+					return u, nil
+				})
 			},
-			wantErr: errors.New("invalid URL format"),
+			want:    "/spring-sale",
+			wantErr: nil,
+		},
+		{
+			name: "alias taken by another URL",
+			args: args{url: "https://example.com/autumn", alias: "spring-sale"},
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "spring-sale").Return(&model.URL{ShortID: "spring-sale", OriginalURL: "https://example.com/spring"}, nil)
+			},
+			wantErr: url.ErrAliasTaken,
+		},
+		{
+			name: "alias taken concurrently",
+			args: args{url: "https://example.com/autumn", alias: "autumn-sale"},
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "autumn-sale").Return(nil, nil)
+				mockURLRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(nil, interfaces.ErrShortIDTaken)
+			},
+			wantErr: url.ErrAliasTaken,
+		},
+		{
+			name:      "expiration date in the past",
+			args:      args{url: "https://example.com/past", expiresAt: time.Now().Add(-time.Hour)},
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {},
+			wantErr:   errors.New("expiration date must be in the future"),
+		},
+		{
+			name: "password is stored hashed",
+			args: args{url: "https://example.com/internal", alias: "internal-docs", password: "s3cret"},
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "internal-docs").Return(nil, nil)
+				mockURLRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, u *model.URL) (*model.URL, error) {
+					assert.NotEqual(t, "s3cret", u.PasswordHash)
+					assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte("s3cret")))
+					return u, nil
+				})
+			},
+			want:    "/internal-docs",
+			wantErr: nil,
+		},
+		{
+			name:      "password too long",
+			args:      args{url: "https://example.com/internal", password: strings.Repeat("p", url.MaxPasswordLength+1)},
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {},
+			wantErr:   errors.New("password is too long"),
+		},
+		{
+			name:      "invalid alias",
+			args:      args{url: "https://example.com/autumn", alias: "ping"},
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {},
+			wantErr:   errors.New("alias is reserved"),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			mockURLRepo, urlService, _, cfg, _, err := setup(t, ctx)
+			if err != nil {
+				t.Fatalf("Failed to set up test: %v", err)
+			}
 			tt.setupMock(mockURLRepo)
-			got, err := urlService.Shorten(ctx, "user123", tt.args.url)
+			data := dto.ShortenJSONRequestDTO{URL: tt.args.url, Alias: tt.args.alias, Password: tt.args.password}
+			if !tt.args.expiresAt.IsZero() {
+				data.ExpiresAt = &tt.args.expiresAt
+			}
+			got, err := urlService.Shorten(ctx, "user123", data)
 			if tt.wantErr != nil {
 				assert.EqualError(t, err, tt.wantErr.Error())
+			} else if tt.want != "" {
+				assert.NoError(t, err)
+				assert.Equal(t, cfg.BaseURL+tt.want, got)
 			} else {
 				assert.NoError(t, err)
 				expectedLength := len(cfg.BaseURL) + 1 + 8
 				assert.Equal(t, expectedLength, len(got), "Expected ID length to be %d, but got %d", expectedLength, len(got))
-				shortID := strings.Split(got, "/")[len(strings.Split(got, "/"))-1]
-				storedURL, _ := mockURLRepo.FindByID(ctx, shortID)
-				assert.Equal(t, tt.args.url, storedURL.OriginalURL, "Stored URL mismatch in memoryRepo: got %v, want %v", storedURL.OriginalURL, tt.args.url)
 			}
 		})
 	}
@@ -279,7 +542,7 @@ func TestURLService_ShortenList(t *testing.T) {
 				{CorrelationID: "2", OriginalURL: "https://example2.com"},
 			},
 			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
-				mockURLRepo.EXPECT().InsertList(ctx, gomock.Any()).Return([]*model.URL{
+				mockURLRepo.EXPECT().InsertList(gomock.Any(), gomock.Any()).Return([]*model.URL{
 					{ShortID: "short1", OriginalURL: "http://example1.com"},
 					{ShortID: "short2", OriginalURL: "https://example2.com"},
 				}, nil)
@@ -294,20 +557,48 @@ func TestURLService_ShortenList(t *testing.T) {
 				{CorrelationID: "2", OriginalURL: "https://example2.com"},
 			},
 			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
-				mockURLRepo.EXPECT().InsertList(ctx, gomock.Any()).Return([]*model.URL{
+				mockURLRepo.EXPECT().InsertList(gomock.Any(), gomock.Any()).Return([]*model.URL{
 					{ShortID: "short2", OriginalURL: "https://example2.com"},
 				}, nil)
 			},
 			wantErr:     nil,
 			expectedLen: 1,
 		},
+		{
+			name: "Batch shorten retries short ID collision",
+			data: dto.BatchShortenRequestDTO{
+				{CorrelationID: "1", OriginalURL: "http://example1.com"},
+				{CorrelationID: "2", OriginalURL: "https://example2.com"},
+			},
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				gomock.InOrder(
+					mockURLRepo.EXPECT().InsertList(gomock.Any(), gomock.Any()).Return(nil, interfaces.ErrShortIDTaken),
+					mockURLRepo.EXPECT().InsertList(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, urls []*model.URL) ([]*model.URL, error) {
+						return urls, nil
+					}),
+				)
+			},
+			wantErr:     nil,
+			expectedLen: 2,
+		},
+		{
+			name: "Batch shorten gives up after repeated collisions",
+			data: dto.BatchShortenRequestDTO{
+				{CorrelationID: "1", OriginalURL: "http://example1.com"},
+			},
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().InsertList(gomock.Any(), gomock.Any()).Return(nil, interfaces.ErrShortIDTaken).Times(cfg.IDMaxAttempts)
+			},
+			wantErr:     interfaces.ErrShortIDTaken,
+			expectedLen: 0,
+		},
 		{
 			name: "Batch shorten repository error",
 			data: dto.BatchShortenRequestDTO{
 				{CorrelationID: "1", OriginalURL: "http://example1.com"},
 			},
 			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
-				mockURLRepo.EXPECT().InsertList(ctx, gomock.Any()).Return(nil, errors.New("repository error"))
+				mockURLRepo.EXPECT().InsertList(gomock.Any(), gomock.Any()).Return(nil, errors.New("repository error"))
 			},
 			wantErr:     errors.New("repository error"),
 			expectedLen: 0,
@@ -332,6 +623,7 @@ func TestURLService_ShortenList(t *testing.T) {
 					if i < len(tt.data) && utils.IsValidID(res.ShortURL, 8) {
 						assert.Equal(t, tt.data[i].CorrelationID, res.CorrelationID, "CorrelationID should match")
 					}
+					assert.Len(t, strings.TrimPrefix(res.ShortURL, cfg.BaseURL+"/"), cfg.IDLength)
 				}
 			}
 		})
@@ -365,7 +657,7 @@ func TestURLService_GetOriginal(t *testing.T) {
 		},
 		{
 			name:      "invalid ID length",
-			args:      args{id: "short"},
+			args:      args{id: "ab"},
 			setupMock: func(mockURLRepo *repository.MockIURLRepository) {},
 			want:      "",
 			wantErr:   errors.New("invalid ID"),
@@ -395,6 +687,38 @@ func TestURLService_GetOriginal(t *testing.T) {
 			want:    "",
 			wantErr: errors.New("URL not found"),
 		},
+		{
+			name: "expired by date",
+			args: args{id: "expiredD"},
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				expiresAt := time.Now().Add(-time.Minute)
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), gomock.Eq("expiredD")).Return(&model.URL{ShortID: "expiredD", OriginalURL: "http://example.com", ExpiresAt: &expiresAt}, nil)
+			},
+			want:    "",
+			wantErr: url.ErrURLExpired,
+		},
+		{
+			name: "click counted within budget",
+			args: args{id: "budgetOK"},
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				maxClicks := 2
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), gomock.Eq("budgetOK")).Return(&model.URL{ShortID: "budgetOK", OriginalURL: "http://example.com", MaxClicks: &maxClicks, Clicks: 1}, nil)
+				mockURLRepo.EXPECT().IncrementClicks(gomock.Any(), gomock.Eq("budgetOK")).Return(true, nil)
+			},
+			want:    "http://example.com",
+			wantErr: nil,
+		},
+		{
+			name: "click budget used up concurrently",
+			args: args{id: "budgetNO"},
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				maxClicks := 2
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), gomock.Eq("budgetNO")).Return(&model.URL{ShortID: "budgetNO", OriginalURL: "http://example.com", MaxClicks: &maxClicks, Clicks: 1}, nil)
+				mockURLRepo.EXPECT().IncrementClicks(gomock.Any(), gomock.Eq("budgetNO")).Return(false, nil)
+			},
+			want:    "",
+			wantErr: url.ErrURLExpired,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -410,62 +734,401 @@ func TestURLService_GetOriginal(t *testing.T) {
 	}
 }
 
+func TestURLService_GetOriginalWithPassword(t *testing.T) {
+	ctx := context.Background()
+	mockURLRepo, urlService, _, cfg, _, err := setup(t, ctx)
+	if err != nil {
+		t.Fatalf("Failed to set up test: %v", err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	protected := func(id string) *model.URL {
+		return &model.URL{ShortID: id, OriginalURL: "http://example.com/internal", PasswordHash: string(hash)}
+	}
+
+	tests := []struct {
+		name      string
+		id        string
+		password  string
+		setupMock func(mockURLRepo *repository.MockIURLRepository)
+		want      string
+		wantErr   error
+	}{
+		{
+			name: "password required",
+			id:   "locked01",
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "locked01").Return(protected("locked01"), nil)
+			},
+			wantErr: url.ErrPasswordRequired,
+		},
+		{
+			name:     "wrong password",
+			id:       "locked01",
+			password: "guess",
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "locked01").Return(protected("locked01"), nil)
+			},
+			wantErr: url.ErrWrongPassword,
+		},
+		{
+			name:     "right password",
+			id:       "locked01",
+			password: "s3cret",
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "locked01").Return(protected("locked01"), nil)
+			},
+			want: "http://example.com/internal",
+		},
+		{
+			name:     "password ignored for public URL",
+			id:       "public01",
+			password: "anything",
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "public01").Return(&model.URL{ShortID: "public01", OriginalURL: "http://example.com"}, nil)
+			},
+			want: "http://example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock(mockURLRepo)
+			got, err := urlService.GetOriginalWithPassword(ctx, tt.id, tt.password)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+
+	t.Run("wrong attempts are limited per link", func(t *testing.T) {
+		mockURLRepo.EXPECT().FindByID(gomock.Any(), "locked02").Return(protected("locked02"), nil).AnyTimes()
+		for i := 0; i < cfg.PasswordMaxAttempts; i++ {
+			_, err := urlService.GetOriginalWithPassword(ctx, "locked02", "guess")
+			assert.ErrorIs(t, err, url.ErrWrongPassword)
+		}
+		_, err := urlService.GetOriginalWithPassword(ctx, "locked02", "s3cret")
+		assert.ErrorIs(t, err, url.ErrTooManyAttempts)
+
+		mockURLRepo.EXPECT().FindByID(gomock.Any(), "locked03").Return(protected("locked03"), nil)
+		got, err := urlService.GetOriginalWithPassword(ctx, "locked03", "s3cret")
+		assert.NoError(t, err)
+		assert.Equal(t, "http://example.com/internal", got)
+	})
+}
+
+func TestURLService_GetShortURL(t *testing.T) {
+	ctx := context.Background()
+	mockURLRepo, urlService, _, cfg, _, err := setup(t, ctx)
+	if err != nil {
+		t.Fatalf("Failed to set up test: %v", err)
+	}
+	maxClicks := 1
+	tests := []struct {
+		name      string
+		id        string
+		setupMock func(mockURLRepo *repository.MockIURLRepository)
+		want      string
+		wantErr   error
+	}{
+		{
+			name: "active URL without counting a click",
+			id:   "qrcode01",
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "qrcode01").Return(&model.URL{ShortID: "qrcode01", OriginalURL: "http://example.com", MaxClicks: &maxClicks}, nil)
+			},
+			want: cfg.BaseURL + "/qrcode01",
+		},
+		{
+			name: "password protected URL",
+			id:   "qrcode02",
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "qrcode02").Return(&model.URL{ShortID: "qrcode02", OriginalURL: "http://example.com", PasswordHash: "hash"}, nil)
+			},
+			want: cfg.BaseURL + "/qrcode02",
+		},
+		{
+			name:      "invalid ID",
+			id:        "ab",
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {},
+			wantErr:   url.ErrInvalidID,
+		},
+		{
+			name: "unknown URL",
+			id:   "qrcode03",
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "qrcode03").Return(nil, nil)
+			},
+			wantErr: url.ErrURLNotFound,
+		},
+		{
+			name: "deleted URL",
+			id:   "qrcode04",
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "qrcode04").Return(&model.URL{ShortID: "qrcode04", DeletedFlag: true}, nil)
+			},
+			wantErr: url.ErrURLDeleted,
+		},
+		{
+			name: "disabled URL",
+			id:   "qrcode06",
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "qrcode06").Return(&model.URL{ShortID: "qrcode06", DisabledFlag: true}, nil)
+			},
+			wantErr: url.ErrURLDisabled,
+		},
+		{
+			name: "click budget used up",
+			id:   "qrcode05",
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "qrcode05").Return(&model.URL{ShortID: "qrcode05", MaxClicks: &maxClicks, Clicks: 1}, nil)
+			},
+			wantErr: url.ErrURLExpired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock(mockURLRepo)
+			got, err := urlService.GetShortURL(ctx, tt.id)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestURLService_UpdateOriginalURL(t *testing.T) {
+	ctx := context.Background()
+	mockURLRepo, urlService, _, cfg, _, err := setup(t, ctx)
+	if err != nil {
+		t.Fatalf("Failed to set up test: %v", err)
+	}
+	tests := []struct {
+		name      string
+		userID    string
+		shortID   string
+		newURL    string
+		setupMock func(mockURLRepo *repository.MockIURLRepository)
+		want      dto.GetUserURLsResponse
+		wantErr   error
+	}{
+		{
+			name:    "destination updated",
+			userID:  "user1",
+			shortID: "short1",
+			newURL:  "http://new.com",
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "short1").Return(&model.URL{ShortID: "short1", OriginalURL: "http://old.com", UserID: "user1"}, nil)
+				mockURLRepo.EXPECT().UpdateOriginalURL(gomock.Any(), "user1", "short1", "http://new.com").Return(true, nil)
+			},
+			want: dto.GetUserURLsResponse{ShortURL: fmt.Sprintf("%s/short1", cfg.BaseURL), OriginalURL: "http://new.com"},
+		},
+		{
+			name:    "same destination",
+			userID:  "user1",
+			shortID: "short1",
+			newURL:  "http://old.com",
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "short1").Return(&model.URL{ShortID: "short1", OriginalURL: "http://old.com", UserID: "user1"}, nil)
+			},
+			want: dto.GetUserURLsResponse{ShortURL: fmt.Sprintf("%s/short1", cfg.BaseURL), OriginalURL: "http://old.com"},
+		},
+		{
+			name:      "invalid URL",
+			userID:    "user1",
+			shortID:   "short1",
+			newURL:    "not a url",
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {},
+			wantErr:   errors.New("invalid URL format"),
+		},
+		{
+			name:    "URL of another user",
+			userID:  "user2",
+			shortID: "short1",
+			newURL:  "http://new.com",
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "short1").Return(&model.URL{ShortID: "short1", OriginalURL: "http://old.com", UserID: "user1"}, nil)
+			},
+			wantErr: url.ErrURLNotFound,
+		},
+		{
+			name:    "unknown URL",
+			userID:  "user1",
+			shortID: "unknown",
+			newURL:  "http://new.com",
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "unknown").Return(nil, nil)
+			},
+			wantErr: url.ErrURLNotFound,
+		},
+		{
+			name:    "deleted URL",
+			userID:  "user1",
+			shortID: "short1",
+			newURL:  "http://new.com",
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "short1").Return(&model.URL{ShortID: "short1", OriginalURL: "http://old.com", UserID: "user1", DeletedFlag: true}, nil)
+			},
+			wantErr: url.ErrURLDeleted,
+		},
+		{
+			name:    "destination already shortened",
+			userID:  "user1",
+			shortID: "short1",
+			newURL:  "http://taken.com",
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "short1").Return(&model.URL{ShortID: "short1", OriginalURL: "http://old.com", UserID: "user1"}, nil)
+				mockURLRepo.EXPECT().UpdateOriginalURL(gomock.Any(), "user1", "short1", "http://taken.com").
+					Return(false, fmt.Errorf("failed to update URL: %w", interfaces.ErrOriginalURLTaken))
+			},
+			wantErr: url.ErrTargetTaken,
+		},
+		{
+			name:    "deleted concurrently",
+			userID:  "user1",
+			shortID: "short1",
+			newURL:  "http://new.com",
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "short1").Return(&model.URL{ShortID: "short1", OriginalURL: "http://old.com", UserID: "user1"}, nil)
+				mockURLRepo.EXPECT().UpdateOriginalURL(gomock.Any(), "user1", "short1", "http://new.com").Return(false, nil)
+			},
+			wantErr: url.ErrURLNotFound,
+		},
+		{
+			name:    "repository error",
+			userID:  "user1",
+			shortID: "short1",
+			newURL:  "http://new.com",
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				mockURLRepo.EXPECT().FindByID(gomock.Any(), "short1").Return(nil, errors.New("db error"))
+			},
+			wantErr: errors.New("db error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMock(mockURLRepo)
+			got, err := urlService.UpdateOriginalURL(ctx, tt.userID, tt.shortID, tt.newURL)
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
 func TestURLService_GetUserURLs(t *testing.T) {
 	ctx := context.Background()
 	mockURLRepo, urlService, _, cfg, _, err := setup(t, ctx)
 	if err != nil {
 		t.Fatalf("Failed to set up test: %v", err)
 	}
+	created := time.Date(2024, time.November, 17, 12, 0, 0, 0, time.UTC)
 
 	tests := []struct {
 		name      string
 		userID    string
+		params    dto.GetUserURLsRequestDTO
 		setupMock func(mockURLRepo *repository.MockIURLRepository)
-		want      dto.GetUserURLsResponseDTO
+		want      dto.GetUserURLsPageDTO
 		wantErr   error
 	}{
 		{
 			name:   "success",
 			userID: "testUser",
 			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
-				mockURLRepo.EXPECT().FindListByUserID(gomock.Any(), gomock.Eq("testUser")).Return([]*model.URL{
-					{ShortID: "short1", OriginalURL: "http://example1.com", UserID: "testUser"},
-					{ShortID: "short2", OriginalURL: "https://example2.com", UserID: "testUser"},
+				mockURLRepo.EXPECT().FindListByUserID(gomock.Any(), gomock.Eq("testUser"), gomock.Any()).Return([]*model.URL{
+					{ShortID: "short1", OriginalURL: "http://example1.com", UserID: "testUser", CreatedAt: created},
+					{ShortID: "short2", OriginalURL: "https://example2.com", UserID: "testUser", CreatedAt: created, DeletedFlag: true},
 				}, nil)
 			},
-			want: dto.GetUserURLsResponseDTO{
-				{ShortURL: fmt.Sprintf("%s/short1", cfg.BaseURL), OriginalURL: "http://example1.com"},
-				{ShortURL: fmt.Sprintf("%s/short2", cfg.BaseURL), OriginalURL: "https://example2.com"},
-			},
+			want: dto.GetUserURLsPageDTO{URLs: dto.GetUserURLsResponseDTO{
+				{ShortURL: fmt.Sprintf("%s/short1", cfg.BaseURL), OriginalURL: "http://example1.com", CreatedAt: created},
+				{ShortURL: fmt.Sprintf("%s/short2", cfg.BaseURL), OriginalURL: "https://example2.com", CreatedAt: created, IsDeleted: true},
+			}},
 			wantErr: nil,
 		},
 		{
 			name:   "not found",
 			userID: "notFoundUser",
 			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
-				mockURLRepo.EXPECT().FindListByUserID(gomock.Any(), gomock.Eq("notFoundUser")).Return([]*model.URL{}, nil)
+				mockURLRepo.EXPECT().FindListByUserID(gomock.Any(), gomock.Eq("notFoundUser"), gomock.Any()).Return([]*model.URL{}, nil)
 			},
-			want:    dto.GetUserURLsResponseDTO{},
+			want:    dto.GetUserURLsPageDTO{},
 			wantErr: nil,
 		},
 		{
 			name:   "repository error",
 			userID: "errorUser",
 			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
-				mockURLRepo.EXPECT().FindListByUserID(gomock.Any(), gomock.Eq("errorUser")).Return(nil, errors.New("repository error"))
+				mockURLRepo.EXPECT().FindListByUserID(gomock.Any(), gomock.Eq("errorUser"), gomock.Any()).Return(nil, errors.New("repository error"))
 			},
-			want:    nil,
 			wantErr: errors.New("repository error"),
 		},
+		{
+			name:   "more URLs than the limit",
+			userID: "pagedUser",
+			params: dto.GetUserURLsRequestDTO{Limit: "1", Status: "active", Search: "example", Sort: "original_url"},
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				active := false
+				query := model.URLListQuery{Deleted: &active, Search: "example", Sort: model.SortOriginalURLAsc, Limit: 2}
+				mockURLRepo.EXPECT().FindListByUserID(gomock.Any(), gomock.Eq("pagedUser"), gomock.Eq(query)).Return([]*model.URL{
+					{ShortID: "short1", OriginalURL: "http://example1.com", CreatedAt: created},
+					{ShortID: "short2", OriginalURL: "https://example2.com", CreatedAt: created},
+				}, nil)
+			},
+			want: dto.GetUserURLsPageDTO{
+				URLs: dto.GetUserURLsResponseDTO{
+					{ShortURL: fmt.Sprintf("%s/short1", cfg.BaseURL), OriginalURL: "http://example1.com", CreatedAt: created},
+				},
+				NextCursor: "eyJzIjoib3JpZ2luYWxfdXJsIiwidCI6IjAwMDEtMDEtMDFUMDA6MDA6MDBaIiwidSI6Imh0dHA6Ly9leGFtcGxlMS5jb20iLCJpZCI6InNob3J0MSJ9",
+			},
+		},
+		{
+			name:   "next page",
+			userID: "pagedUser",
+			params: dto.GetUserURLsRequestDTO{
+				Limit:  "1",
+				Sort:   "original_url",
+				Cursor: "eyJzIjoib3JpZ2luYWxfdXJsIiwidCI6IjAwMDEtMDEtMDFUMDA6MDA6MDBaIiwidSI6Imh0dHA6Ly9leGFtcGxlMS5jb20iLCJpZCI6InNob3J0MSJ9",
+			},
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {
+				query := model.URLListQuery{
+					Sort:  model.SortOriginalURLAsc,
+					Limit: 2,
+					After: &model.URLCursor{OriginalURL: "http://example1.com", ShortID: "short1"},
+				}
+				mockURLRepo.EXPECT().FindListByUserID(gomock.Any(), gomock.Eq("pagedUser"), gomock.Eq(query)).Return([]*model.URL{
+					{ShortID: "short2", OriginalURL: "https://example2.com", CreatedAt: created},
+				}, nil)
+			},
+			want: dto.GetUserURLsPageDTO{URLs: dto.GetUserURLsResponseDTO{
+				{ShortURL: fmt.Sprintf("%s/short2", cfg.BaseURL), OriginalURL: "https://example2.com", CreatedAt: created},
+			}},
+		},
+		{
+			name:      "invalid options",
+			userID:    "testUser",
+			params:    dto.GetUserURLsRequestDTO{Limit: "0"},
+			setupMock: func(mockURLRepo *repository.MockIURLRepository) {},
+			wantErr:   errors.New("invalid limit"),
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.setupMock(mockURLRepo)
-			got, err := urlService.GetUserURLs(ctx, tt.userID)
+			got, err := urlService.GetUserURLs(ctx, tt.userID, tt.params)
 			if tt.wantErr != nil {
 				assert.EqualError(t, err, tt.wantErr.Error())
-				assert.Nil(t, got)
+				assert.Empty(t, got.URLs)
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, tt.want, got)
@@ -0,0 +1,186 @@
+package taskmanager
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule decides when a recurring job runs.
+type Schedule interface {
+	// Next returns the first time after t at which the job runs, or the zero time if it never does.
+	Next(t time.Time) time.Time
+}
+
+// descriptors are the schedules that can be given by name instead of a cron expression.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// monthNames and dayNames are the names that can be used in the month and day of week fields.
+var (
+	monthNames = map[string]int{"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12}
+	dayNames = map[string]int{"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6}
+)
+
+// ParseSchedule parses a schedule given as a cron expression of five fields (minute, hour, day of
+// month, month and day of week), as one of the descriptors @yearly, @monthly, @weekly, @daily and
+// @hourly, or as "@every <duration>". A field is a comma-separated list of values, ranges such as
+// 1-5, and steps such as */15 or 0-30/10; months and days of week may also be given by their
+// three-letter English names. As in cron, a day matches if either of the day fields matches when
+// both are restricted. Cron expressions follow the time zone of the times given to Next; @every
+// schedules run at the multiples of the duration since the zero time, so that all instances of
+// the service agree on the times of the runs.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %v", spec, err)
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("invalid schedule %q: interval must be positive", spec)
+		}
+		return everySchedule{interval: interval}, nil
+	}
+	expression := spec
+	if strings.HasPrefix(spec, "@") {
+		var ok bool
+		if expression, ok = descriptors[spec]; !ok {
+			return nil, fmt.Errorf("invalid schedule %q: unknown descriptor", spec)
+		}
+	}
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields, got %d", spec, len(fields))
+	}
+	s := &cronSchedule{}
+	var err error
+	if s.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: minute: %v", spec, err)
+	}
+	if s.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: hour: %v", spec, err)
+	}
+	if s.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: day of month: %v", spec, err)
+	}
+	if s.month, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: month: %v", spec, err)
+	}
+	if s.dow, err = parseField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: day of week: %v", spec, err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1 // Both 0 and 7 are Sunday.
+	}
+	s.anyDOM = fields[2] == "*"
+	s.anyDOW = fields[4] == "*"
+	return s, nil
+}
+
+// parseField parses a field of a cron expression into a set of values between min and max,
+// bit i being set for value i.
+func parseField(field string, min, max int, names map[string]int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		span, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepText)
+			}
+		}
+		from, to := min, max
+		if span != "*" {
+			first, last, isRange := strings.Cut(span, "-")
+			var err error
+			if from, err = parseValue(first, min, max, names); err != nil {
+				return 0, err
+			}
+			switch {
+			case isRange:
+				if to, err = parseValue(last, min, max, names); err != nil {
+					return 0, err
+				}
+				if to < from {
+					return 0, fmt.Errorf("invalid range %q", span)
+				}
+			case !hasStep:
+				to = from
+			}
+		}
+		for value := from; value <= to; value += step {
+			set |= 1 << uint(value)
+		}
+	}
+	return set, nil
+}
+
+// parseValue parses a value of a field, given as a number between min and max or as one of names.
+func parseValue(text string, min, max int, names map[string]int) (int, error) {
+	if value, ok := names[strings.ToUpper(text)]; ok {
+		return value, nil
+	}
+	value, err := strconv.Atoi(text)
+	if err != nil || value < min || value > max {
+		return 0, fmt.Errorf("invalid value %q, expected %d-%d", text, min, max)
+	}
+	return value, nil
+}
+
+// cronSchedule is a schedule given by a cron expression, each field being a set of values.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	anyDOM, anyDOW                bool // Whether the day fields are unrestricted.
+}
+
+// Next returns the first minute after t matching the expression, looking up to five years ahead.
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether the day of t matches the day fields.
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.anyDOM || s.anyDOW {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// everySchedule is a schedule running at the multiples of an interval since the zero time.
+type everySchedule struct {
+	interval time.Duration
+}
+
+// Next returns the first multiple of the interval after t.
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Truncate(s.interval).Add(s.interval)
+}
@@ -0,0 +1,89 @@
+package taskmanager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	from := time.Date(2026, time.October, 17, 10, 7, 30, 0, time.UTC) // A Saturday.
+	tests := []struct {
+		spec string
+		want []time.Time
+	}{
+		{spec: "* * * * *", want: []time.Time{
+			time.Date(2026, time.October, 17, 10, 8, 0, 0, time.UTC),
+			time.Date(2026, time.October, 17, 10, 9, 0, 0, time.UTC),
+		}},
+		{spec: "*/15 * * * *", want: []time.Time{
+			time.Date(2026, time.October, 17, 10, 15, 0, 0, time.UTC),
+			time.Date(2026, time.October, 17, 10, 30, 0, 0, time.UTC),
+		}},
+		{spec: "5,50 9-10 * * *", want: []time.Time{
+			time.Date(2026, time.October, 17, 10, 50, 0, 0, time.UTC),
+			time.Date(2026, time.October, 18, 9, 5, 0, 0, time.UTC),
+		}},
+		{spec: "30 3 * * MON-FRI", want: []time.Time{
+			time.Date(2026, time.October, 19, 3, 30, 0, 0, time.UTC),
+			time.Date(2026, time.October, 20, 3, 30, 0, 0, time.UTC),
+		}},
+		{spec: "0 0 1,15 * 7", want: []time.Time{
+			time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, time.October, 25, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC),
+		}},
+		{spec: "0 12 31 feb,apr,dec *", want: []time.Time{
+			time.Date(2026, time.December, 31, 12, 0, 0, 0, time.UTC),
+		}},
+		{spec: "@daily", want: []time.Time{
+			time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+		}},
+		{spec: "@monthly", want: []time.Time{
+			time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC),
+		}},
+		{spec: "@every 10m", want: []time.Time{
+			time.Date(2026, time.October, 17, 10, 10, 0, 0, time.UTC),
+			time.Date(2026, time.October, 17, 10, 20, 0, 0, time.UTC),
+		}},
+		{spec: "0 0 30 2 *", want: []time.Time{{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.spec)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			next := from
+			for _, want := range tt.want {
+				next = schedule.Next(next)
+				if !next.Equal(want) {
+					t.Fatalf("Expected %v, got %v", want, next)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@fortnightly",
+		"@every",
+		"@every soon",
+		"@every -1m",
+	} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package taskmanager
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fairKeyLimit is the largest number of tasks of a fair key processed at once by a pool. Fair times
+// only order the queued tasks, so without it a key with a few large tasks could still take every
+// worker while they run.
+const fairKeyLimit = 1
+
+// typeStats holds the statistics and the concurrency limit of a task type.
+type typeStats struct {
+	limit     int    // Largest number of tasks of the type processed at once, unlimited if zero.
+	running   int    // Number of tasks of the type being processed.
+	processed uint64 // Number of tasks of the type processed so far.
+	errors    uint64 // Number of tasks of the type whose handler returned an error.
+}
+
+// ParseConcurrencyLimits parses concurrency limits given as comma-separated <type>:<limit> pairs,
+// such as "delete_urls_task:2,expire_urls_task:1". An empty spec has no limits.
+func ParseConcurrencyLimits(spec string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		taskType, value, ok := strings.Cut(pair, ":")
+		taskType = strings.TrimSpace(taskType)
+		if !ok || taskType == "" {
+			return nil, fmt.Errorf("invalid concurrency limit %q, expected <type>:<limit>", pair)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid concurrency limit %q, expected a non-negative number", pair)
+		}
+		limits[taskType] = limit
+	}
+	return limits, nil
+}
+
+// SetConcurrencyLimit sets the largest number of tasks of the type processed at once by the workers
+// of the pool, removing the limit if limit is zero. The tasks of the type beyond the limit wait in
+// the queue while the workers go on with the tasks of other types. Jobs are counted but never held
+// back, and the limit is not shared with other instances claiming from the same durable queue. The
+// limit can be changed while the pool runs; a claim racing with another may briefly exceed it.
+func (p *WorkerPool) SetConcurrencyLimit(taskType string, limit int) error {
+	return p.SetConcurrencyLimits(map[string]int{taskType: limit})
+}
+
+// SetConcurrencyLimits sets the concurrency limits of several task types, as SetConcurrencyLimit
+// does. All limits are checked before any is set, so an invalid one leaves every limit unchanged.
+func (p *WorkerPool) SetConcurrencyLimits(limits map[string]int) error {
+	types := make([]string, 0, len(limits))
+	for taskType := range limits {
+		types = append(types, taskType)
+	}
+	sort.Strings(types)
+	for _, taskType := range types {
+		if limit := limits[taskType]; limit < 0 {
+			return fmt.Errorf("invalid concurrency limit for task type %s: %d", taskType, limit)
+		}
+		if p.handler(taskType) == nil {
+			return fmt.Errorf("no handler registered for task type: %s", taskType)
+		}
+	}
+	p.typesMu.Lock()
+	for taskType, limit := range limits {
+		p.typeStats(taskType).limit = limit
+	}
+	p.typesMu.Unlock()
+
+	p.taskQueue.signal(p.taskQueue.len())
+	if p.queue != nil {
+		p.notify()
+	}
+	return nil
+}
+
+// typeStats returns the statistics of the task type, adding them if there are none yet. The caller
+// must hold typesMu.
+func (p *WorkerPool) typeStats(taskType string) *typeStats {
+	stats, ok := p.types[taskType]
+	if !ok {
+		stats = &typeStats{}
+		p.types[taskType] = stats
+	}
+	return stats
+}
+
+// admit counts a task of the type and fair key as running unless the type is at its concurrency
+// limit or the key at fairKeyLimit, in which case the task is left in the task queue until a task
+// of the type or key is done. It reports whether the task may be processed.
+func (p *WorkerPool) admit(taskType, key string) bool {
+	p.typesMu.Lock()
+	defer p.typesMu.Unlock()
+	stats := p.typeStats(taskType)
+	if stats.limit > 0 && stats.running >= stats.limit {
+		return false
+	}
+	if key != "" && p.keys[key] >= fairKeyLimit {
+		return false
+	}
+	p.startLocked(taskType, key)
+	return true
+}
+
+// start counts a task of the type and fair key as running, whatever the limits of the type and key.
+func (p *WorkerPool) start(taskType, key string) {
+	p.typesMu.Lock()
+	defer p.typesMu.Unlock()
+	p.startLocked(taskType, key)
+}
+
+// startLocked counts a task of the type and fair key as running. The caller must hold typesMu.
+func (p *WorkerPool) startLocked(taskType, key string) {
+	p.typeStats(taskType).running++
+	if key != "" {
+		p.keys[key]++
+	}
+	p.active.Add(1)
+}
+
+// finish counts a processed task of the type and fair key with the error of its handler. If the type
+// has a concurrency limit or the task has a fair key, an idle worker is woken to take the tasks of
+// the type or key left in the queue.
+func (p *WorkerPool) finish(taskType, key string, err error) {
+	p.processed.Add(1)
+	if err != nil {
+		p.errors.Add(1)
+	}
+	p.typesMu.Lock()
+	stats := p.typeStats(taskType)
+	stats.running--
+	stats.processed++
+	if err != nil {
+		stats.errors++
+	}
+	if key != "" {
+		if p.keys[key] <= 1 {
+			delete(p.keys, key)
+		} else {
+			p.keys[key]--
+		}
+	}
+	limited := stats.limit > 0 || key != ""
+	p.typesMu.Unlock()
+	p.active.Add(-1)
+	if limited {
+		p.taskQueue.signal(1)
+	}
+}
+
+// saturated returns the task types at their concurrency limit and the fair keys at fairKeyLimit,
+// whose tasks are not to be claimed.
+func (p *WorkerPool) saturated() (types, keys []string) {
+	p.typesMu.Lock()
+	defer p.typesMu.Unlock()
+	for taskType, stats := range p.types {
+		if stats.limit > 0 && stats.running >= stats.limit {
+			types = append(types, taskType)
+		}
+	}
+	for key, running := range p.keys {
+		if running >= fairKeyLimit {
+			keys = append(keys, key)
+		}
+	}
+	return types, keys
+}
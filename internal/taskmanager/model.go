@@ -1,6 +1,11 @@
 // Package taskmanager defines the structure and behavior of tasks that can be managed within a worker pool system.
 package taskmanager
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // Task represents a task interface that defines the TaskType method.
 type Task interface {
 	// TaskType returns the type of the task as a string.
@@ -8,16 +13,136 @@ type Task interface {
 	TaskType() string
 }
 
+// Priority is the priority level of a task. The tasks of a higher priority are processed first,
+// and those of the same priority fairly across the keys they are run for.
+type Priority int
+
+// Priority levels of the tasks.
+const (
+	PriorityLow    Priority = -1 // Housekeeping that can wait behind the other tasks.
+	PriorityNormal Priority = 0  // The priority of the tasks that do not set one.
+	PriorityHigh   Priority = 1  // Tasks that must not wait behind the others, such as flushing buffers.
+)
+
+// PrioritizedTask is a task with a priority other than PriorityNormal.
+type PrioritizedTask interface {
+	Task
+	// Priority returns the priority level of the task.
+	Priority() Priority
+}
+
+// FairTask is a task sharing the workers fairly with the tasks of other keys, such as the tasks of
+// other users. The tasks of a key are spread out by their cost, so that a key with many or large
+// tasks yields to the small tasks of the other keys while still making progress, and a pool runs a
+// single task of a key at a time.
+type FairTask interface {
+	Task
+	// FairKey returns the key the task shares the workers by, such as the user it is run for.
+	FairKey() string
+	// Cost returns the amount of work of the task, such as its number of items, at least 1.
+	Cost() int
+}
+
+// fairQuantum is the fair time a unit of cost of a task takes up.
+const fairQuantum = time.Millisecond
+
+// priorityOf returns the priority level of the task.
+func priorityOf(task Task) Priority {
+	if prioritized, ok := task.(PrioritizedTask); ok {
+		return prioritized.Priority()
+	}
+	return PriorityNormal
+}
+
+// fairnessOf returns the fair key of the task, empty if it has none, and the fair time it takes up.
+func fairnessOf(task Task) (string, time.Duration) {
+	fair, ok := task.(FairTask)
+	if !ok {
+		return "", fairQuantum
+	}
+	return fair.FairKey(), time.Duration(max(fair.Cost(), 1)) * fairQuantum
+}
+
 // DeleteTask represents a task that involves deleting URLs associated with a specific user.
 type DeleteTask struct {
+	// TaskID is the identifier of the status tracking the progress of the task, if it is tracked.
+	TaskID string `json:"task_id,omitempty"`
+
 	// UserID is the unique identifier of the user who is associated with the URLs to be deleted.
-	UserID string
+	UserID string `json:"user_id"`
 
 	// URLs is a slice of URLs to be deleted for the user.
-	URLs []string
+	URLs []string `json:"urls"`
 }
 
 // TaskType returns the task type identifier for the DeleteTask.
 func (DeleteTask) TaskType() string {
 	return "delete_urls_task"
 }
+
+// FairKey returns the user the URLs are deleted for, so that the deletions of a user do not starve
+// those of the others.
+func (t DeleteTask) FairKey() string {
+	return t.UserID
+}
+
+// Cost returns the number of URLs to delete.
+func (t DeleteTask) Cost() int {
+	return max(len(t.URLs), 1)
+}
+
+// ExpireTask represents a task that soft deletes URLs which expired by date or click budget.
+type ExpireTask struct{}
+
+// TaskType returns the task type identifier for the ExpireTask.
+func (ExpireTask) TaskType() string {
+	return "expire_urls_task"
+}
+
+// Priority returns PriorityLow, expired URLs being swept whenever the workers are free.
+func (ExpireTask) Priority() Priority {
+	return PriorityLow
+}
+
+// FlushClicksTask represents a task that writes buffered click events to the repository.
+type FlushClicksTask struct{}
+
+// TaskType returns the task type identifier for the FlushClicksTask.
+func (FlushClicksTask) TaskType() string {
+	return "flush_clicks_task"
+}
+
+// Priority returns PriorityHigh, the buffer of click events growing while the task waits.
+func (FlushClicksTask) Priority() Priority {
+	return PriorityHigh
+}
+
+// CompactJournalTask represents a task that compacts the journal of the in-memory storage into a fresh snapshot.
+type CompactJournalTask struct{}
+
+// TaskType returns the task type identifier for the CompactJournalTask.
+func (CompactJournalTask) TaskType() string {
+	return "compact_journal_task"
+}
+
+// Priority returns PriorityLow, the journal being compacted whenever the workers are free.
+func (CompactJournalTask) Priority() Priority {
+	return PriorityLow
+}
+
+// taskDecoders decodes the JSON payloads of the task types that can be kept in a durable queue.
+var taskDecoders = map[string]func(json.RawMessage) (Task, error){
+	DeleteTask{}.TaskType():         decodeTask[DeleteTask],
+	ExpireTask{}.TaskType():         decodeTask[ExpireTask],
+	FlushClicksTask{}.TaskType():    decodeTask[FlushClicksTask],
+	CompactJournalTask{}.TaskType(): decodeTask[CompactJournalTask],
+}
+
+// decodeTask decodes the JSON payload of a task of type T.
+func decodeTask[T Task](payload json.RawMessage) (Task, error) {
+	var task T
+	if err := json.Unmarshal(payload, &task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
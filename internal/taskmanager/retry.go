@@ -0,0 +1,94 @@
+package taskmanager
+
+import (
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy decides how the tasks of a durable queue are retried after a failed attempt.
+type RetryPolicy struct {
+	MaxAttempts  int           // Attempts of a task before it is moved to the dead letters.
+	BaseDelay    time.Duration // Delay before the first retry, doubled for every further retry.
+	MaxDelay     time.Duration // Longest delay between two attempts.
+	PollInterval time.Duration // How often idle workers look for due tasks in the queue.
+	LeaseTimeout time.Duration // How long a claimed task is hidden from other workers, and a job lock is held, unless extended while they run.
+}
+
+// DefaultRetryPolicy returns the policy used for the fields of a RetryPolicy that are not set.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:  5,
+		BaseDelay:    time.Second,
+		MaxDelay:     5 * time.Minute,
+		PollInterval: time.Second,
+		LeaseTimeout: 5 * time.Minute,
+	}
+}
+
+// withDefaults returns the policy with the fields that are not set taken from DefaultRetryPolicy.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	defaults := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaults.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = defaults.BaseDelay
+	}
+	if p.MaxDelay < p.BaseDelay {
+		p.MaxDelay = max(defaults.MaxDelay, p.BaseDelay)
+	}
+	if p.PollInterval <= 0 {
+		p.PollInterval = defaults.PollInterval
+	}
+	if p.LeaseTimeout <= 0 {
+		p.LeaseTimeout = defaults.LeaseTimeout
+	}
+	return p
+}
+
+// Backoff returns the delay before retrying a task whose attempt number attempt failed. The delay
+// doubles with every attempt up to MaxDelay, and a random half of it is jittered away so that
+// tasks failing together are not retried together.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxDelay)
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return delay - half + rand.N(half+1)
+}
+
+// permanentError is an error of a task handler that is not worth retrying.
+type permanentError struct {
+	err error
+}
+
+// Error returns the message of the wrapped error.
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks an error returned by a task handler as permanent: a task of a durable queue
+// failing with it is moved to the dead letters at once instead of being retried.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// isPermanent reports whether the error was marked with Permanent.
+func isPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
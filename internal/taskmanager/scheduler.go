@@ -0,0 +1,200 @@
+package taskmanager
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// scheduler is the bounded task queue of a pool. Its tasks are taken by priority, then by fair time:
+// each task takes up fair time in proportion to its cost after the queued tasks of its fair key, so
+// that a key with a large backlog is served in turns with the other keys instead of ahead of them.
+// A key with no queued task starts again from the current time, which keeps its tasks from waiting
+// behind the other keys longer than their own cost.
+type scheduler struct {
+	mu       sync.Mutex
+	tasks    taskHeap            // Queued tasks, the next one to take first.
+	capacity int                 // Largest number of queued tasks.
+	keys     map[string]*fairKey // State of the fair keys with queued tasks.
+	seq      uint64              // Number of tasks pushed so far, ordering the tasks of the same rank.
+	closed   bool                // Whether the scheduler is closed.
+	ready    chan struct{}       // Receives a signal for every task that may be taken, waking idle workers.
+	space    chan struct{}       // Closed, then replaced, when a task leaves the full queue.
+	done     chan struct{}       // Closed once the scheduler is closed.
+}
+
+// fairKey is the state of a fair key with queued tasks.
+type fairKey struct {
+	queued int       // Number of queued tasks of the key.
+	last   time.Time // Latest fair time of the queued tasks of the key.
+}
+
+// scheduledTask is a queued task with its rank in the scheduler.
+type scheduledTask struct {
+	queuedTask
+	priority Priority
+	key      string
+	fairAt   time.Time
+	seq      uint64
+}
+
+// newScheduler creates a scheduler holding up to capacity tasks.
+func newScheduler(capacity int) *scheduler {
+	return &scheduler{
+		capacity: capacity,
+		keys:     make(map[string]*fairKey),
+		ready:    make(chan struct{}, max(capacity, 1)),
+		space:    make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// push adds a task to the scheduler and reports whether it did. If the scheduler is full, the task is
+// not added and the returned channel is closed once a task leaves it. errShutDown is returned if the
+// scheduler is closed.
+func (s *scheduler) push(queued queuedTask) (bool, <-chan struct{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false, nil, errShutDown
+	}
+	if len(s.tasks) >= s.capacity {
+		return false, s.space, nil
+	}
+	key, cost := fairnessOf(queued.task)
+	fairAt := time.Now()
+	if key != "" {
+		state, ok := s.keys[key]
+		if !ok {
+			state = &fairKey{}
+			s.keys[key] = state
+		}
+		if state.last.After(fairAt) {
+			fairAt = state.last
+		}
+		fairAt = fairAt.Add(cost)
+		state.queued++
+		state.last = fairAt
+	} else {
+		fairAt = fairAt.Add(cost)
+	}
+	s.seq++
+	heap.Push(&s.tasks, &scheduledTask{
+		queuedTask: queued,
+		priority:   priorityOf(queued.task),
+		key:        key,
+		fairAt:     fairAt,
+		seq:        s.seq,
+	})
+	s.signal(1)
+	return true, nil, nil
+}
+
+// pop takes the first task whose type and fair key are admitted by admit, which is called with the
+// lock of the scheduler held. The tasks that are not admitted are left in the queue.
+func (s *scheduler) pop(admit func(taskType, key string) bool) (queuedTask, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	type rank struct{ taskType, key string }
+	var skipped []*scheduledTask
+	rejected := make(map[rank]bool)
+	var found *scheduledTask
+	for len(s.tasks) > 0 {
+		next := heap.Pop(&s.tasks).(*scheduledTask)
+		r := rank{next.task.TaskType(), next.key}
+		if !rejected[r] && admit(r.taskType, r.key) {
+			found = next
+			break
+		}
+		rejected[r] = true
+		skipped = append(skipped, next)
+	}
+	for _, task := range skipped {
+		heap.Push(&s.tasks, task)
+	}
+	if found == nil {
+		return queuedTask{}, false
+	}
+	if found.key != "" {
+		if state := s.keys[found.key]; state.queued == 1 {
+			delete(s.keys, found.key)
+		} else {
+			state.queued--
+		}
+	}
+	if len(s.tasks)+1 == s.capacity {
+		close(s.space)
+		s.space = make(chan struct{})
+	}
+	return found.queuedTask, true
+}
+
+// signal wakes up to n idle workers to take the queued tasks, such as after a concurrency limit
+// let more tasks through. Signals beyond those the workers have yet to receive are dropped.
+func (s *scheduler) signal(n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case s.ready <- struct{}{}:
+		default:
+			return
+		}
+	}
+}
+
+// len returns the number of queued tasks.
+func (s *scheduler) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.tasks)
+}
+
+// counts returns the number of queued tasks by task type.
+func (s *scheduler) counts() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[string]int)
+	for _, task := range s.tasks {
+		counts[task.task.TaskType()]++
+	}
+	return counts
+}
+
+// close closes the scheduler: tasks are no longer pushed and the workers return. The tasks still
+// queued are dropped.
+func (s *scheduler) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.done)
+}
+
+// taskHeap is a heap of scheduled tasks implementing heap.Interface, ordered by decreasing priority,
+// then by fair time and push order.
+type taskHeap []*scheduledTask
+
+func (h taskHeap) Len() int { return len(h) }
+
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	if !h[i].fairAt.Equal(h[j].fairAt) {
+		return h[i].fairAt.Before(h[j].fairAt)
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h taskHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *taskHeap) Push(x any) { *h = append(*h, x.(*scheduledTask)) }
+
+func (h *taskHeap) Pop() any {
+	old := *h
+	task := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return task
+}
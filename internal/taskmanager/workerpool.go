@@ -1,14 +1,74 @@
 // Package taskmanager defines a worker pool system for processing tasks asynchronously.
 // The pool manages task handlers and the distribution of tasks to worker goroutines.
+// Each task is processed in a span continuing the trace of the context it was enqueued with.
+//
+// A pool created with a durable queue keeps the enqueued tasks in it until they succeed, so
+// that tasks still waiting at shutdown or after a crash are processed once the pool is back.
+// A failed task is retried with an exponential backoff until it runs out of attempts and is
+// moved to the dead letters, where it can be inspected and replayed. Periodic tasks are
+// always kept in memory: a missed tick is followed by the next one anyway.
+//
+// A task can be delayed with EnqueueAt and EnqueueAfter; without a durable queue, the delayed
+// tasks still waiting at shutdown are lost. Recurring jobs registered with RegisterJob run on a
+// cron schedule in goroutines of their own, a run being skipped while the previous one is still
+// going. With the job locks of a storage shared by several instances, each run of a job is done
+// by a single instance.
+//
+// The task queue is bounded: Enqueue returns ErrQueueFull when it has no room for the task, so that
+// callers shed load instead of piling up work. The number of workers can be changed while the pool
+// runs with Resize, and SetConcurrencyLimit keeps a task type from occupying more than a given number
+// of workers, its other tasks waiting while the workers go on with the tasks of other types.
+//
+// Tasks are taken by priority, see PrioritizedTask, and those of a priority level fairly across the
+// keys they are run for, see FairTask: each task takes up fair time in proportion to its cost after
+// the waiting tasks of its key, so that a user deleting many links does not starve the others while
+// the deletion still makes progress. The durable queue orders its tasks the same way. Since a running
+// task cannot be interrupted, a pool also processes a single task of a key at a time, so that the
+// large tasks of a key leave the other workers to the other keys.
 package taskmanager
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"os"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the name of the tracer starting the spans of the processed tasks.
+const tracerName = "github.com/GlebRadaev/shlink/internal/taskmanager"
+
+// settleTimeout bounds the time spent recording the outcome of a task in the durable queue,
+// which is done even if the pool is shutting down.
+const settleTimeout = 5 * time.Second
+
+// defaultQueueSize is the queue size of the pools created with a size that is not positive, the
+// same as the default TASK_QUEUE_SIZE.
+const defaultQueueSize = 1000
+
+var (
+	// ErrTaskNotFound is returned by Replay when no dead letter has the ID.
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrQueueFull is returned by Enqueue when the task queue has no room for the task.
+	ErrQueueFull = errors.New("task queue is full")
 )
 
+// errShutDown is returned when a task is enqueued or the workers are resized after Shutdown.
+var errShutDown = errors.New("worker pool is shut down")
+
 // IWorkerPool is an interface for managing a worker pool that processes tasks.
 type IWorkerPool interface {
 	// RegisterHandler registers a handler for a specific task type.
@@ -17,8 +77,47 @@ type IWorkerPool interface {
 
 	// Enqueue adds a task to the worker pool's task queue.
 	// The task must have a registered handler, and the pool will attempt to process the task.
+	// The span of ctx, if any, becomes the parent of the span in which the task is processed.
+	// ErrQueueFull is returned if the queue has no room for the task by the deadline of ctx.
 	Enqueue(ctx context.Context, task Task) error
 
+	// EnqueueAt adds a task to the queue to be processed from at, or at once if at has passed.
+	// The task must have a registered handler.
+	EnqueueAt(ctx context.Context, task Task, at time.Time) error
+
+	// EnqueueAfter adds a task to the queue to be processed once delay has elapsed.
+	// The task must have a registered handler.
+	EnqueueAfter(ctx context.Context, task Task, delay time.Duration) error
+
+	// EnqueueEvery periodically adds a task to the queue with the given interval until the pool is shut down.
+	// The task must have a registered handler.
+	EnqueueEvery(interval time.Duration, task Task) error
+
+	// RegisterJob runs the task on the cron schedule spec, as parsed by ParseSchedule, until the pool
+	// is shut down. The name identifies the job across the instances sharing the job locks, and the
+	// task must have a registered handler.
+	RegisterJob(name, spec string, task Task) error
+
+	// DeadLetters returns the tasks of the durable queue that ran out of attempts, newest first,
+	// at most limit of them unless limit is zero.
+	DeadLetters(ctx context.Context, limit int) ([]*model.DeadTask, error)
+
+	// Replay moves a dead letter back to the durable queue with no attempts, returning ErrTaskNotFound
+	// if no dead letter has the ID.
+	Replay(ctx context.Context, id int64) (*model.QueuedTask, error)
+
+	// Stats returns the current monitoring data of the pool.
+	Stats() MonitoringData
+
+	// Resize starts or stops workers to have n of them.
+	Resize(n int) error
+
+	// SetConcurrencyLimit sets the largest number of tasks of the type processed at once, zero for no limit.
+	SetConcurrencyLimit(taskType string, limit int) error
+
+	// SetConcurrencyLimits sets the limits of several task types, leaving all unchanged if one is invalid.
+	SetConcurrencyLimits(limits map[string]int) error
+
 	// Shutdown gracefully shuts down the worker pool, stopping all active workers and closing the task queue.
 	Shutdown()
 }
@@ -27,84 +126,694 @@ type IWorkerPool interface {
 type WorkerPool struct {
 	ctx        context.Context                              // The context that controls the lifetime of the pool.
 	cancel     context.CancelFunc                           // The cancel function to signal shutdown.
-	taskQueue  chan Task                                    // Channel holding the tasks to be processed.
+	taskQueue  *scheduler                                   // Queue holding the tasks to be processed in memory.
 	handlers   map[string]func(context.Context, Task) error // Registered task handlers.
+	handlersMu sync.RWMutex                                 // Guards handlers, jobs and locks.
+	jobs       map[string]bool                              // Names of the registered jobs.
+	locks      interfaces.IJobLockRepository                // Job locks shared with other instances, or nil.
+	owner      string                                       // Identifier of the pool as the owner of job locks.
 	wg         sync.WaitGroup                               // Wait group to track workers and ensure graceful shutdown.
-	numWorkers int                                          // The number of workers in the pool.
+	workersMu  sync.Mutex                                   // Guards workers and nextWorker; held by Shutdown.
+	workers    []chan struct{}                              // Channels stopping the running workers.
+	nextWorker int                                          // ID of the next worker started.
+	typesMu    sync.Mutex                                   // Guards types and keys.
+	types      map[string]*typeStats                        // Statistics and concurrency limits by task type.
+	keys       map[string]int                               // Number of running tasks by fair key.
 	shutdown   sync.Once                                    // Ensures that shutdown occurs once.
+	processed  atomic.Uint64                                // Number of tasks processed so far.
+	errors     atomic.Uint64                                // Number of tasks whose handler returned an error.
+	active     atomic.Int64                                 // Number of workers currently processing a task.
+	tracer     trace.Tracer                                 // Tracer starting the spans of the processed tasks.
+	queue      interfaces.ITaskRepository                   // Durable queue of the tasks, nil to use taskQueue.
+	queueSize  int                                          // Largest number of tasks in the durable queue.
+	policy     RetryPolicy                                  // Retries of the tasks of the durable queue.
+	wake       chan struct{}                                // Wakes an idle worker on a durable push.
+	now        func() time.Time                             // Clock of the task times in the durable queue.
+}
+
+// queuedTask is a task waiting in the queue together with the span context it was enqueued from.
+type queuedTask struct {
+	task     Task
+	parent   trace.SpanContext
+	enqueued time.Time
 }
 
 // MonitoringData holds statistics about the worker pool's state, such as task queue length,
 // number of processed tasks, number of errors, and active workers.
 type MonitoringData struct {
-	QueueLength   int    // The current number of tasks in the queue.
-	Processed     uint64 // Total number of tasks that have been processed.
-	Errors        uint64 // Total number of errors encountered during task processing.
-	ActiveWorkers int    // The number of active workers currently processing tasks.
+	QueueLength   int                  // The current number of tasks in the queue.
+	QueueCapacity int                  // The number of tasks the queue holds.
+	Workers       int                  // The number of workers in the pool.
+	Processed     uint64               // Total number of tasks that have been processed.
+	Errors        uint64               // Total number of errors encountered during task processing.
+	ActiveWorkers int                  // The number of active workers currently processing tasks.
+	Types         map[string]TypeStats // Statistics of the queued, processed or limited types.
+}
+
+// TypeStats holds statistics about the tasks of a type.
+type TypeStats struct {
+	Running   int    // The number of tasks of the type being processed.
+	Queued    int    // The number of tasks of the type waiting in the queue.
+	Limit     int    // The largest number of tasks of the type processed at once, zero if unlimited.
+	Processed uint64 // Total number of tasks of the type that have been processed.
+	Errors    uint64 // Total number of tasks of the type whose handler returned an error.
+}
+
+// Stats returns the current monitoring data of the pool. The tasks waiting in a durable queue are
+// not included in the queue length, see Pending.
+func (p *WorkerPool) Stats() MonitoringData {
+	p.workersMu.Lock()
+	workers := len(p.workers)
+	p.workersMu.Unlock()
+	queued := p.taskQueue.counts()
+	data := MonitoringData{
+		QueueCapacity: p.taskQueue.capacity,
+		Workers:       workers,
+		Processed:     p.processed.Load(),
+		Errors:        p.errors.Load(),
+		ActiveWorkers: int(p.active.Load()),
+		Types:         make(map[string]TypeStats),
+	}
+	p.typesMu.Lock()
+	defer p.typesMu.Unlock()
+	for taskType, stats := range p.types {
+		data.Types[taskType] = TypeStats{
+			Running:   stats.running,
+			Limit:     stats.limit,
+			Processed: stats.processed,
+			Errors:    stats.errors,
+		}
+	}
+	for taskType, count := range queued {
+		stats := data.Types[taskType]
+		stats.Queued = count
+		data.Types[taskType] = stats
+		data.QueueLength += count
+	}
+	return data
+}
+
+// Pending returns the number of tasks waiting to be processed: those of the durable queue, the
+// claimed ones included, or those of the task queue if there is no durable queue.
+func (p *WorkerPool) Pending(ctx context.Context) (int, error) {
+	if p.queue == nil {
+		return p.Stats().QueueLength, nil
+	}
+	return p.queue.Count(ctx)
 }
 
 // NewWorkerPool creates a new WorkerPool instance with the specified parameters:
 // - ctx: The context used to control the lifetime of the pool.
-// - queueSize: The size of the task queue.
+// - queueSize: The size of the task queue, 1000 if not positive.
 // - numWorkers: The number of workers in the pool.
 func NewWorkerPool(ctx context.Context, queueSize, numWorkers int) *WorkerPool {
+	return NewDurableWorkerPool(ctx, queueSize, numWorkers, nil, RetryPolicy{})
+}
+
+// NewDurableWorkerPool creates a new WorkerPool instance keeping the enqueued tasks in queue and
+// retrying them with policy, whose unset fields are taken from DefaultRetryPolicy. The task queue
+// of queueSize tasks only holds the periodic tasks, and no more than queueSize tasks are enqueued
+// in the durable queue. A queueSize that is not positive is taken as 1000, since a queue holding no
+// task would never take one. With a nil queue the pool is the same as one created by NewWorkerPool.
+func NewDurableWorkerPool(ctx context.Context, queueSize, numWorkers int, queue interfaces.ITaskRepository,
+	policy RetryPolicy) *WorkerPool {
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
 	ctx, cancel := context.WithCancel(ctx)
 	pool := &WorkerPool{
-		ctx:        ctx,
-		cancel:     cancel,
-		taskQueue:  make(chan Task, queueSize),
-		handlers:   make(map[string]func(context.Context, Task) error),
-		numWorkers: numWorkers,
+		ctx:       ctx,
+		cancel:    cancel,
+		taskQueue: newScheduler(queueSize),
+		handlers:  make(map[string]func(context.Context, Task) error),
+		jobs:      make(map[string]bool),
+		owner:     newOwner(),
+		types:     make(map[string]*typeStats),
+		keys:      make(map[string]int),
+		tracer:    otel.Tracer(tracerName),
+		queue:     queue,
+		queueSize: queueSize,
+		policy:    policy.withDefaults(),
+		now:       time.Now,
 	}
+	if queue != nil {
+		pool.wake = make(chan struct{}, max(numWorkers, 1))
+	}
+	pool.workersMu.Lock()
+	defer pool.workersMu.Unlock()
 	for i := 0; i < numWorkers; i++ {
-		pool.wg.Add(1)
-		go pool.worker(i)
+		pool.startWorker()
 	}
 	return pool
 }
 
+// Resize starts or stops workers to have n of them. A stopped worker finishes the task it is
+// processing first. It returns an error if n is not positive or the pool is shut down.
+func (p *WorkerPool) Resize(n int) error {
+	if n < 1 {
+		return fmt.Errorf("invalid number of workers: %d", n)
+	}
+	p.workersMu.Lock()
+	defer p.workersMu.Unlock()
+	if p.ctx.Err() != nil {
+		return errShutDown
+	}
+	for len(p.workers) < n {
+		p.startWorker()
+	}
+	for len(p.workers) > n {
+		last := len(p.workers) - 1
+		close(p.workers[last])
+		p.workers = p.workers[:last]
+	}
+	log.Printf("Worker pool resized to %d workers", n)
+	return nil
+}
+
+// startWorker starts a worker goroutine. The caller must hold workersMu.
+func (p *WorkerPool) startWorker() {
+	stop := make(chan struct{})
+	p.workers = append(p.workers, stop)
+	p.wg.Add(1)
+	go p.worker(p.nextWorker, stop)
+	p.nextWorker++
+}
+
 // RegisterHandler registers a handler function for a specific task type.
 func (p *WorkerPool) RegisterHandler(taskType string, handler func(context.Context, Task) error) {
+	p.handlersMu.Lock()
+	defer p.handlersMu.Unlock()
 	p.handlers[taskType] = handler
 }
 
-// Enqueue adds a task to the task queue for processing by the workers.
+// handler returns the handler registered for the task type, nil if there is none.
+func (p *WorkerPool) handler(taskType string) func(context.Context, Task) error {
+	p.handlersMu.RLock()
+	defer p.handlersMu.RUnlock()
+	return p.handlers[taskType]
+}
+
+// Enqueue adds a task to the task queue for processing by the workers. With a durable queue the
+// task is pushed to it, and the error of the push is returned. ErrQueueFull is returned if the queue
+// has no room for the task by the deadline of ctx.
 func (p *WorkerPool) Enqueue(ctx context.Context, task Task) error {
-	if _, exists := p.handlers[task.TaskType()]; !exists {
+	return p.EnqueueAt(ctx, task, time.Time{})
+}
+
+// EnqueueAt adds a task to the task queue to be processed from at, or at once if at has passed.
+// With a durable queue the task is pushed to it due at at. Without one, a goroutine waits until
+// at to add the task to the task queue, unless the pool is shut down first.
+func (p *WorkerPool) EnqueueAt(ctx context.Context, task Task, at time.Time) error {
+	if p.handler(task.TaskType()) == nil {
 		return fmt.Errorf("no handler registered for task type: %s", task.TaskType())
 	}
-	p.taskQueue <- task
+	if p.queue != nil {
+		return p.push(ctx, task, at)
+	}
+	queued := queuedTask{task: task, parent: trace.SpanContextFromContext(ctx), enqueued: time.Now()}
+	if delay := time.Until(at); delay > 0 {
+		return p.enqueueLater(queued, delay)
+	}
+	return p.send(ctx, queued)
+}
+
+// send adds a task to the task queue. If the queue is full, it waits for a free slot until the
+// deadline of ctx and returns ErrQueueFull if there is none by then; without a deadline it does
+// not wait. The error of ctx is returned if it is canceled meanwhile.
+func (p *WorkerPool) send(ctx context.Context, queued queuedTask) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p.ctx.Err() != nil {
+		return errShutDown
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		pushed, _, err := p.taskQueue.push(queued)
+		if err == nil && !pushed {
+			return fmt.Errorf("failed to enqueue task of type %s: %w", queued.task.TaskType(), ErrQueueFull)
+		}
+		return err
+	}
+	err := p.put(ctx, queued)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("failed to enqueue task of type %s: %w", queued.task.TaskType(), ErrQueueFull)
+	}
+	return err
+}
+
+// put adds a task to the task queue, waiting for a free slot until ctx is done, whose error is then
+// returned, or the pool is shut down.
+func (p *WorkerPool) put(ctx context.Context, queued queuedTask) error {
+	for {
+		pushed, space, err := p.taskQueue.push(queued)
+		if err != nil || pushed {
+			return err
+		}
+		select {
+		case <-space:
+		case <-p.ctx.Done():
+			return errShutDown
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// EnqueueAfter adds a task to the task queue to be processed once delay has elapsed.
+func (p *WorkerPool) EnqueueAfter(ctx context.Context, task Task, delay time.Duration) error {
+	return p.EnqueueAt(ctx, task, p.now().Add(delay))
+}
+
+// enqueueLater starts a goroutine adding the task to the task queue after delay.
+func (p *WorkerPool) enqueueLater(queued queuedTask, delay time.Duration) error {
+	if p.ctx.Err() != nil {
+		return errShutDown
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-timer.C:
+		}
+		_ = p.put(p.ctx, queued)
+	}()
+	return nil
+}
+
+// push adds a task to the durable queue, due at at or at once if at has passed, with the traceparent
+// of the span of ctx and the priority and fairness of the task. ErrQueueFull is returned if the queue
+// already holds queueSize tasks; the bound is checked before the push, so that concurrent pushes may
+// slightly exceed it.
+func (p *WorkerPool) push(ctx context.Context, task Task, at time.Time) error {
+	if _, ok := taskDecoders[task.TaskType()]; !ok {
+		return fmt.Errorf("task type %s cannot be kept in the queue", task.TaskType())
+	}
+	count, err := p.queue.Count(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task of type %s: %v", task.TaskType(), err)
+	}
+	if count >= p.queueSize {
+		return fmt.Errorf("failed to enqueue task of type %s: %w", task.TaskType(), ErrQueueFull)
+	}
+	payload, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to encode task of type %s: %v", task.TaskType(), err)
+	}
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	now := p.now()
+	key, cost := fairnessOf(task)
+	stored := &model.QueuedTask{
+		Type:        task.TaskType(),
+		Payload:     payload,
+		TraceParent: carrier.Get("traceparent"),
+		RunAt:       now,
+		CreatedAt:   now,
+		Priority:    int(priorityOf(task)),
+		FairKey:     key,
+	}
+	if at.After(now) {
+		stored.RunAt = at
+	}
+	stored.FairAt = stored.RunAt.Add(cost)
+	if err := p.queue.Push(ctx, stored); err != nil {
+		return fmt.Errorf("failed to enqueue task of type %s: %v", task.TaskType(), err)
+	}
+	p.notify()
 	return nil
 }
 
+// notify wakes an idle worker to claim a task pushed to the durable queue; if all workers are
+// busy, the task is claimed by the first one done.
+func (p *WorkerPool) notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// DeadLetters returns the tasks of the durable queue that ran out of attempts, newest first.
+// A pool without a durable queue has no dead letters.
+func (p *WorkerPool) DeadLetters(ctx context.Context, limit int) ([]*model.DeadTask, error) {
+	if p.queue == nil {
+		return nil, nil
+	}
+	return p.queue.FindDeadList(ctx, limit)
+}
+
+// Replay moves a dead letter back to the durable queue, due at once and with no attempts.
+func (p *WorkerPool) Replay(ctx context.Context, id int64) (*model.QueuedTask, error) {
+	if p.queue == nil {
+		return nil, ErrTaskNotFound
+	}
+	task, err := p.queue.Replay(ctx, id, p.now())
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return nil, ErrTaskNotFound
+	}
+	p.notify()
+	return task, nil
+}
+
+// EnqueueEvery starts a goroutine that adds the task to the queue every interval until the pool is shut down.
+// A tick is skipped if the previous one is still waiting for a free slot in the queue.
+func (p *WorkerPool) EnqueueEvery(interval time.Duration, task Task) error {
+	if p.handler(task.TaskType()) == nil {
+		return fmt.Errorf("no handler registered for task type: %s", task.TaskType())
+	}
+	if interval <= 0 {
+		return fmt.Errorf("invalid interval for task type %s: %v", task.TaskType(), interval)
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-ticker.C:
+				if err := p.put(p.ctx, queuedTask{task: task, enqueued: time.Now()}); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// job is a recurring task run on a schedule.
+type job struct {
+	name     string
+	schedule Schedule
+	task     Task
+}
+
+// SetJobLocks makes the jobs of the pool take a lock of locks for each of their runs, so that each
+// run is done by a single one of the instances sharing them. It must be called before the jobs are
+// registered; without locks a job is only kept from overlapping with itself in the pool.
+func (p *WorkerPool) SetJobLocks(locks interfaces.IJobLockRepository) {
+	p.handlersMu.Lock()
+	defer p.handlersMu.Unlock()
+	p.locks = locks
+}
+
+// RegisterJob starts a goroutine running the task on the cron schedule spec until the pool is shut
+// down. The task is processed by the goroutine itself rather than a worker, so that a run is never
+// delayed by the task queue and a run still going when the next one is due makes that one skipped.
+func (p *WorkerPool) RegisterJob(name, spec string, task Task) error {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return fmt.Errorf("invalid schedule for job %s: %v", name, err)
+	}
+	p.handlersMu.Lock()
+	defer p.handlersMu.Unlock()
+	if p.handlers[task.TaskType()] == nil {
+		return fmt.Errorf("no handler registered for task type: %s", task.TaskType())
+	}
+	if p.jobs[name] {
+		return fmt.Errorf("job %s is already registered", name)
+	}
+	p.jobs[name] = true
+	p.wg.Add(1)
+	go p.runJob(job{name: name, schedule: schedule, task: task}, p.locks)
+	return nil
+}
+
+// runJob waits for each run of the job on its schedule and does it, until the pool is shut down.
+// The runs that fell due while a run was going are skipped.
+func (p *WorkerPool) runJob(j job, locks interfaces.IJobLockRepository) {
+	defer p.wg.Done()
+	next := j.schedule.Next(p.now())
+	for !next.IsZero() {
+		timer := time.NewTimer(next.Sub(p.now()))
+		select {
+		case <-p.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		p.runJobOnce(j, locks, next)
+		after := next
+		if now := p.now(); now.After(after) {
+			if skipped := j.schedule.Next(after); !skipped.After(now) {
+				log.Printf("Job %s ran past its run at %v, skipping the runs due meanwhile", j.name, skipped)
+			}
+			after = now
+		}
+		next = j.schedule.Next(after)
+	}
+}
+
+// runJobOnce does the run of the job due at runAt. With job locks, the run is only done if the
+// lock of the job is acquired for it, and the lock is extended while the run goes on.
+func (p *WorkerPool) runJobOnce(j job, locks interfaces.IJobLockRepository, runAt time.Time) {
+	if locks != nil {
+		acquired, err := locks.Acquire(p.ctx, j.name, p.owner, runAt, p.now(), p.policy.LeaseTimeout)
+		if err != nil {
+			if p.ctx.Err() == nil {
+				log.Printf("Failed to lock job %s: %v", j.name, err)
+			}
+			return
+		}
+		if !acquired {
+			return
+		}
+		stop := p.extendJobLock(locks, j.name)
+		defer func() {
+			stop()
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(p.ctx), settleTimeout)
+			defer cancel()
+			if err := locks.Release(ctx, j.name, p.owner, p.now()); err != nil {
+				log.Printf("Failed to unlock job %s: %v", j.name, err)
+			}
+		}()
+	}
+	p.start(j.task.TaskType(), "")
+	p.finish(j.task.TaskType(), "", p.process(queuedTask{task: j.task, enqueued: runAt}))
+}
+
+// extendJobLock starts a goroutine extending the lease of the lock of the job every third of the
+// lease. It returns a function stopping the goroutine and waiting for it to return, so that the
+// lock is not extended once it is released.
+func (p *WorkerPool) extendJobLock(locks interfaces.IJobLockRepository, name string) func() {
+	return p.renewLease(func(until time.Time) {
+		held, err := locks.Extend(p.ctx, name, p.owner, until)
+		if err != nil && p.ctx.Err() == nil {
+			log.Printf("Failed to extend the lock of job %s: %v", name, err)
+		} else if err == nil && !held {
+			log.Printf("Lost the lock of job %s, its run may overlap with another one", name)
+		}
+	})
+}
+
+// extendTaskLease starts a goroutine extending the lease of a claimed task every third of the
+// lease, so that a task running longer than the lease is not claimed again meanwhile. It returns a
+// function stopping the goroutine and waiting for it to return, so that the lease is not extended
+// once the outcome of the task is recorded.
+func (p *WorkerPool) extendTaskLease(stored *model.QueuedTask) func() {
+	return p.renewLease(func(until time.Time) {
+		queued, err := p.queue.Extend(p.ctx, stored.ID, until)
+		if err != nil && p.ctx.Err() == nil {
+			log.Printf("Failed to extend the lease of task %d: %v", stored.ID, err)
+		} else if err == nil && !queued {
+			log.Printf("Task %d left the queue while running", stored.ID)
+		}
+	})
+}
+
+// renewLease starts a goroutine calling renew with the end of a new lease every third of the lease.
+// It returns a function stopping the goroutine and waiting for it to return.
+func (p *WorkerPool) renewLease(renew func(until time.Time)) func() {
+	lease := p.policy.LeaseTimeout
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(max(lease/3, time.Millisecond))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				renew(p.now().Add(lease))
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// newOwner returns an identifier of the pool as the owner of job locks, made of the host name, the
+// process ID and a random suffix telling apart the pools of a process.
+func newOwner() string {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%x", host, os.Getpid(), suffix)
+}
+
 // Shutdown gracefully shuts down the worker pool by signaling the workers to stop.
 func (p *WorkerPool) Shutdown() {
 	p.shutdown.Do(func() {
+		p.workersMu.Lock()
 		p.cancel()
+		p.workersMu.Unlock()
 		p.wg.Wait()
-		close(p.taskQueue)
+		p.taskQueue.close()
 	})
 }
 
-// worker is a goroutine that listens for tasks in the pool's task queue and processes them using the appropriate handler.
-func (p *WorkerPool) worker(workerID int) {
+// worker is a goroutine that takes the tasks of the pool's task queue and processes them using the
+// appropriate handler. With a durable queue the worker also claims the due tasks of the queue, looking
+// for them whenever it is woken up by a push and every poll interval. The worker returns once stop is
+// closed by Resize.
+func (p *WorkerPool) worker(workerID int, stop <-chan struct{}) {
 	defer p.wg.Done()
 	log.Printf("Worker %d started", workerID)
 
+	var poll <-chan time.Time
+	busy := make(chan time.Time)
+	close(busy)
+	if p.queue != nil {
+		ticker := time.NewTicker(p.policy.PollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
 	for {
+		// After processing a task the worker does not wait, taking turns between the task queue and
+		// the durable queue.
+		wait := poll
+		if queued, ok := p.taskQueue.pop(p.admit); ok {
+			key, _ := fairnessOf(queued.task)
+			p.finish(queued.task.TaskType(), key, p.process(queued))
+			wait = busy
+		}
+		if p.queue != nil && p.claim() {
+			wait = busy
+		}
 		select {
 		case <-p.ctx.Done():
 			log.Printf("Worker %d received shutdown signal", workerID)
 			return
-		case task, ok := <-p.taskQueue:
-			if !ok {
-				log.Printf("Worker %d: task queue is closed, stopping", workerID)
-				return
-			}
-			handler := p.handlers[task.TaskType()]
-			if err := handler(p.ctx, task); err != nil {
-				log.Printf("Error processing task of type %s: %v", task.TaskType(), err)
-			}
+		case <-stop:
+			log.Printf("Worker %d stopped by a resize of the pool", workerID)
+			return
+		case <-p.taskQueue.done:
+			log.Printf("Worker %d: task queue is closed, stopping", workerID)
+			return
+		case <-p.taskQueue.ready:
+		case <-p.wake:
+		case <-wait:
 		}
 	}
 }
+
+// claim claims a due task of the durable queue, processes it and records the outcome in the queue.
+// The lease of the task is extended while it is processed. The tasks of the types at their
+// concurrency limit are left to the next claims. It reports whether a task was claimed.
+func (p *WorkerPool) claim() bool {
+	skipTypes, skipKeys := p.saturated()
+	stored, err := p.queue.Claim(p.ctx, p.now(), p.policy.LeaseTimeout, skipTypes, skipKeys)
+	if err != nil {
+		if p.ctx.Err() == nil {
+			log.Printf("Failed to claim task: %v", err)
+		}
+		return false
+	}
+	if stored == nil {
+		return false
+	}
+	p.start(stored.Type, stored.FairKey)
+	stop := p.extendTaskLease(stored)
+	err = p.processStored(stored)
+	stop()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(p.ctx), settleTimeout)
+	p.settle(ctx, stored, err)
+	cancel()
+	p.finish(stored.Type, stored.FairKey, err)
+	return true
+}
+
+// processStored decodes a task claimed from the durable queue and processes it in a span continuing
+// the trace the task was enqueued from. A task that cannot be decoded fails permanently; one without
+// a handler is retried, since the queue may be shared with instances registering other handlers and
+// tasks left from before a restart are claimed as soon as the pool is created.
+func (p *WorkerPool) processStored(stored *model.QueuedTask) error {
+	decode, ok := taskDecoders[stored.Type]
+	if !ok {
+		return Permanent(fmt.Errorf("unknown task type: %s", stored.Type))
+	}
+	if p.handler(stored.Type) == nil {
+		return fmt.Errorf("no handler registered for task type: %s", stored.Type)
+	}
+	task, err := decode(stored.Payload)
+	if err != nil {
+		return Permanent(fmt.Errorf("failed to decode task of type %s: %v", stored.Type, err))
+	}
+	carrier := propagation.MapCarrier{"traceparent": stored.TraceParent}
+	ctx := propagation.TraceContext{}.Extract(context.Background(), carrier)
+	return p.process(queuedTask{task: task, parent: trace.SpanContextFromContext(ctx), enqueued: stored.CreatedAt})
+}
+
+// settle records the outcome of a claimed task: a task that succeeded is removed from the queue,
+// a failed one is retried after a backoff, and one that failed permanently or ran out of attempts
+// is moved to the dead letters. A task that failed while the pool was shutting down was most likely
+// interrupted, so it is released at once without counting the attempt. If the outcome cannot be
+// recorded, the task is claimed again once its lease expires.
+func (p *WorkerPool) settle(ctx context.Context, stored *model.QueuedTask, err error) {
+	now := p.now()
+	switch {
+	case err == nil:
+		err = p.queue.Complete(ctx, stored.ID)
+	case p.ctx.Err() != nil && !isPermanent(err):
+		log.Printf("Task %d of type %s was interrupted by the shutdown, releasing it: %v",
+			stored.ID, stored.Type, err)
+		err = p.queue.Release(ctx, stored.ID, now)
+	case isPermanent(err) || stored.Attempts >= p.policy.MaxAttempts:
+		log.Printf("Task %d of type %s failed after %d attempts, moving it to the dead letters: %v",
+			stored.ID, stored.Type, stored.Attempts, err)
+		err = p.queue.Bury(ctx, stored.ID, err.Error(), now)
+	default:
+		delay := p.policy.Backoff(stored.Attempts)
+		log.Printf("Task %d of type %s failed on attempt %d, retrying in %v: %v",
+			stored.ID, stored.Type, stored.Attempts, delay, err)
+		err = p.queue.Retry(ctx, stored.ID, now.Add(delay), err.Error())
+	}
+	if err != nil {
+		log.Printf("Failed to record the outcome of task %d: %v", stored.ID, err)
+	}
+}
+
+// process runs the handler of the task in a span that is a child of the span the task was enqueued from.
+// The time the task waited in the queue is recorded on the span. The error of the handler is returned.
+func (p *WorkerPool) process(queued queuedTask) error {
+	taskType := queued.task.TaskType()
+	ctx := trace.ContextWithSpanContext(p.ctx, queued.parent)
+	ctx, span := p.tracer.Start(ctx, "Task "+taskType,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("task.type", taskType),
+			attribute.Int64("task.queue_wait_ms", time.Since(queued.enqueued).Milliseconds()),
+		),
+	)
+	defer span.End()
+	err := p.handler(taskType)(ctx, queued.task)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		log.Printf("Error processing task of type %s: %v", taskType, err)
+	}
+	return err
+}
@@ -12,7 +12,9 @@ package taskmanager
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
+	model "github.com/GlebRadaev/shlink/internal/model"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -40,6 +42,21 @@ func (m *MockIWorkerPool) EXPECT() *MockIWorkerPoolMockRecorder {
 	return m.recorder
 }
 
+// DeadLetters mocks base method.
+func (m *MockIWorkerPool) DeadLetters(ctx context.Context, limit int) ([]*model.DeadTask, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeadLetters", ctx, limit)
+	ret0, _ := ret[0].([]*model.DeadTask)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeadLetters indicates an expected call of DeadLetters.
+func (mr *MockIWorkerPoolMockRecorder) DeadLetters(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeadLetters", reflect.TypeOf((*MockIWorkerPool)(nil).DeadLetters), ctx, limit)
+}
+
 // Enqueue mocks base method.
 func (m *MockIWorkerPool) Enqueue(ctx context.Context, task Task) error {
 	m.ctrl.T.Helper()
@@ -54,6 +71,48 @@ func (mr *MockIWorkerPoolMockRecorder) Enqueue(ctx, task any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enqueue", reflect.TypeOf((*MockIWorkerPool)(nil).Enqueue), ctx, task)
 }
 
+// EnqueueAfter mocks base method.
+func (m *MockIWorkerPool) EnqueueAfter(ctx context.Context, task Task, delay time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnqueueAfter", ctx, task, delay)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnqueueAfter indicates an expected call of EnqueueAfter.
+func (mr *MockIWorkerPoolMockRecorder) EnqueueAfter(ctx, task, delay any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnqueueAfter", reflect.TypeOf((*MockIWorkerPool)(nil).EnqueueAfter), ctx, task, delay)
+}
+
+// EnqueueAt mocks base method.
+func (m *MockIWorkerPool) EnqueueAt(ctx context.Context, task Task, at time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnqueueAt", ctx, task, at)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnqueueAt indicates an expected call of EnqueueAt.
+func (mr *MockIWorkerPoolMockRecorder) EnqueueAt(ctx, task, at any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnqueueAt", reflect.TypeOf((*MockIWorkerPool)(nil).EnqueueAt), ctx, task, at)
+}
+
+// EnqueueEvery mocks base method.
+func (m *MockIWorkerPool) EnqueueEvery(interval time.Duration, task Task) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnqueueEvery", interval, task)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnqueueEvery indicates an expected call of EnqueueEvery.
+func (mr *MockIWorkerPoolMockRecorder) EnqueueEvery(interval, task any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnqueueEvery", reflect.TypeOf((*MockIWorkerPool)(nil).EnqueueEvery), interval, task)
+}
+
 // RegisterHandler mocks base method.
 func (m *MockIWorkerPool) RegisterHandler(taskType string, handler func(context.Context, Task) error) {
 	m.ctrl.T.Helper()
@@ -66,52 +125,99 @@ func (mr *MockIWorkerPoolMockRecorder) RegisterHandler(taskType, handler any) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterHandler", reflect.TypeOf((*MockIWorkerPool)(nil).RegisterHandler), taskType, handler)
 }
 
-// Shutdown mocks base method.
-func (m *MockIWorkerPool) Shutdown() {
+// RegisterJob mocks base method.
+func (m *MockIWorkerPool) RegisterJob(name, spec string, task Task) error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "Shutdown")
+	ret := m.ctrl.Call(m, "RegisterJob", name, spec, task)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// Shutdown indicates an expected call of Shutdown.
-func (mr *MockIWorkerPoolMockRecorder) Shutdown() *gomock.Call {
+// RegisterJob indicates an expected call of RegisterJob.
+func (mr *MockIWorkerPoolMockRecorder) RegisterJob(name, spec, task any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Shutdown", reflect.TypeOf((*MockIWorkerPool)(nil).Shutdown))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterJob", reflect.TypeOf((*MockIWorkerPool)(nil).RegisterJob), name, spec, task)
 }
 
-// MockTask is a mock of Task interface.
-type MockTask struct {
-	ctrl     *gomock.Controller
-	recorder *MockTaskMockRecorder
-	isgomock struct{}
+// Replay mocks base method.
+func (m *MockIWorkerPool) Replay(ctx context.Context, id int64) (*model.QueuedTask, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replay", ctx, id)
+	ret0, _ := ret[0].(*model.QueuedTask)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// MockTaskMockRecorder is the mock recorder for MockTask.
-type MockTaskMockRecorder struct {
-	mock *MockTask
+// Replay indicates an expected call of Replay.
+func (mr *MockIWorkerPoolMockRecorder) Replay(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replay", reflect.TypeOf((*MockIWorkerPool)(nil).Replay), ctx, id)
 }
 
-// NewMockTask creates a new mock instance.
-func NewMockTask(ctrl *gomock.Controller) *MockTask {
-	mock := &MockTask{ctrl: ctrl}
-	mock.recorder = &MockTaskMockRecorder{mock}
-	return mock
+// Resize mocks base method.
+func (m *MockIWorkerPool) Resize(n int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Resize", n)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockTask) EXPECT() *MockTaskMockRecorder {
-	return m.recorder
+// Resize indicates an expected call of Resize.
+func (mr *MockIWorkerPoolMockRecorder) Resize(n any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resize", reflect.TypeOf((*MockIWorkerPool)(nil).Resize), n)
+}
+
+// SetConcurrencyLimit mocks base method.
+func (m *MockIWorkerPool) SetConcurrencyLimit(taskType string, limit int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetConcurrencyLimit", taskType, limit)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetConcurrencyLimit indicates an expected call of SetConcurrencyLimit.
+func (mr *MockIWorkerPoolMockRecorder) SetConcurrencyLimit(taskType, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetConcurrencyLimit", reflect.TypeOf((*MockIWorkerPool)(nil).SetConcurrencyLimit), taskType, limit)
+}
+
+// SetConcurrencyLimits mocks base method.
+func (m *MockIWorkerPool) SetConcurrencyLimits(limits map[string]int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetConcurrencyLimits", limits)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetConcurrencyLimits indicates an expected call of SetConcurrencyLimits.
+func (mr *MockIWorkerPoolMockRecorder) SetConcurrencyLimits(limits any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetConcurrencyLimits", reflect.TypeOf((*MockIWorkerPool)(nil).SetConcurrencyLimits), limits)
+}
+
+// Shutdown mocks base method.
+func (m *MockIWorkerPool) Shutdown() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Shutdown")
+}
+
+// Shutdown indicates an expected call of Shutdown.
+func (mr *MockIWorkerPoolMockRecorder) Shutdown() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Shutdown", reflect.TypeOf((*MockIWorkerPool)(nil).Shutdown))
 }
 
-// TaskType mocks base method.
-func (m *MockTask) TaskType() string {
+// Stats mocks base method.
+func (m *MockIWorkerPool) Stats() MonitoringData {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "TaskType")
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(MonitoringData)
 	return ret0
 }
 
-// TaskType indicates an expected call of TaskType.
-func (mr *MockTaskMockRecorder) TaskType() *gomock.Call {
+// Stats indicates an expected call of Stats.
+func (mr *MockIWorkerPoolMockRecorder) Stats() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TaskType", reflect.TypeOf((*MockTask)(nil).TaskType))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockIWorkerPool)(nil).Stats))
 }
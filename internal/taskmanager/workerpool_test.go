@@ -2,10 +2,19 @@ package taskmanager
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/repository/inmemory"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 type DummyTask struct {
@@ -180,7 +189,7 @@ func TestWorkerPool_worker(t *testing.T) {
 			}
 
 			if tt.name == "Channel closed" {
-				close(pool.taskQueue)
+				pool.taskQueue.close()
 				time.Sleep(100 * time.Millisecond)
 				return
 			}
@@ -200,3 +209,749 @@ func TestWorkerPool_worker(t *testing.T) {
 		})
 	}
 }
+
+func TestWorkerPool_EnqueueEvery(t *testing.T) {
+	tests := []struct {
+		name        string
+		register    bool
+		interval    time.Duration
+		expectError bool
+		expectRuns  bool
+	}{
+		{
+			name:        "Periodic task is enqueued",
+			register:    true,
+			interval:    10 * time.Millisecond,
+			expectError: false,
+			expectRuns:  true,
+		},
+		{
+			name:        "Task without registered handler",
+			register:    false,
+			interval:    10 * time.Millisecond,
+			expectError: true,
+		},
+		{
+			name:        "Invalid interval",
+			register:    true,
+			interval:    0,
+			expectError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := NewWorkerPool(context.Background(), 10, 1)
+			runs := uint64(0)
+			if tt.register {
+				pool.RegisterHandler("periodic_task", func(ctx context.Context, task Task) error {
+					atomic.AddUint64(&runs, 1)
+					return nil
+				})
+			}
+			err := pool.EnqueueEvery(tt.interval, &DummyTask{Type: "periodic_task"})
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Expected error: %v, got: %v", tt.expectError, err)
+			}
+			time.Sleep(100 * time.Millisecond)
+			pool.Shutdown()
+			if tt.expectRuns && atomic.LoadUint64(&runs) < 2 {
+				t.Fatalf("Expected periodic task to run several times, got %d", runs)
+			}
+		})
+	}
+}
+
+func TestWorkerPool_Stats(t *testing.T) {
+	pool := NewWorkerPool(context.Background(), 10, 1)
+	defer pool.Shutdown()
+	release := make(chan struct{})
+	pool.RegisterHandler("blocking_task", func(ctx context.Context, task Task) error {
+		<-release
+		return nil
+	})
+	pool.RegisterHandler("failing_task", func(ctx context.Context, task Task) error {
+		return fmt.Errorf("failed")
+	})
+
+	_ = pool.Enqueue(context.Background(), &DummyTask{Type: "blocking_task"})
+	_ = pool.Enqueue(context.Background(), &DummyTask{Type: "failing_task"})
+	time.Sleep(50 * time.Millisecond)
+	want := MonitoringData{QueueLength: 1, QueueCapacity: 10, Workers: 1, ActiveWorkers: 1,
+		Types: map[string]TypeStats{"blocking_task": {Running: 1}, "failing_task": {Queued: 1}}}
+	if stats := pool.Stats(); !reflect.DeepEqual(stats, want) {
+		t.Fatalf("Expected one active worker and one queued task, got %+v", stats)
+	}
+
+	close(release)
+	time.Sleep(50 * time.Millisecond)
+	want = MonitoringData{QueueCapacity: 10, Workers: 1, Processed: 2, Errors: 1, Types: map[string]TypeStats{
+		"blocking_task": {Processed: 1},
+		"failing_task":  {Processed: 1, Errors: 1},
+	}}
+	if stats := pool.Stats(); !reflect.DeepEqual(stats, want) {
+		t.Fatalf("Expected two processed tasks and one error, got %+v", stats)
+	}
+}
+
+func TestWorkerPool_EnqueueQueueFull(t *testing.T) {
+	pool := NewWorkerPool(context.Background(), 1, 1)
+	defer pool.Shutdown()
+	release := make(chan struct{})
+	pool.RegisterHandler("blocking_task", func(ctx context.Context, task Task) error {
+		<-release
+		return nil
+	})
+	if err := pool.Enqueue(context.Background(), &DummyTask{Type: "blocking_task"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	waitFor(t, func() bool { return pool.Stats().ActiveWorkers == 1 }, "Expected the worker to take the task")
+	if err := pool.Enqueue(context.Background(), &DummyTask{Type: "blocking_task"}); err != nil {
+		t.Fatalf("Expected the task to fill the queue, got %v", err)
+	}
+
+	if err := pool.Enqueue(context.Background(), &DummyTask{Type: "blocking_task"}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Expected ErrQueueFull without waiting, got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if err := pool.Enqueue(ctx, &DummyTask{Type: "blocking_task"}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Expected ErrQueueFull at the deadline, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed < 50*time.Millisecond {
+		t.Fatalf("Expected Enqueue to wait until the deadline, returned after %v", elapsed)
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := pool.Enqueue(canceled, &DummyTask{Type: "blocking_task"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the error of the canceled context, got %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	if err := pool.Enqueue(ctx, &DummyTask{Type: "blocking_task"}); err != nil {
+		t.Fatalf("Expected the task to be enqueued once a slot is free, got %v", err)
+	}
+}
+
+func TestWorkerPool_DefaultQueueSize(t *testing.T) {
+	pool := NewWorkerPool(context.Background(), 0, 1)
+	defer pool.Shutdown()
+	if capacity := pool.Stats().QueueCapacity; capacity != defaultQueueSize {
+		t.Fatalf("Expected a queue of %d tasks, got %d", defaultQueueSize, capacity)
+	}
+	done := make(chan struct{})
+	pool.RegisterHandler("test_task", func(ctx context.Context, task Task) error {
+		close(done)
+		return nil
+	})
+	if err := pool.Enqueue(context.Background(), &DummyTask{Type: "test_task"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the task to be processed")
+	}
+}
+
+func TestWorkerPool_EnqueueDurableQueueFull(t *testing.T) {
+	pool := NewDurableWorkerPool(context.Background(), 1, 1, inmemory.NewTaskStorage(), testPolicy)
+	defer pool.Shutdown()
+	release := make(chan struct{})
+	pool.RegisterHandler(DeleteTask{}.TaskType(), func(ctx context.Context, task Task) error {
+		<-release
+		return nil
+	})
+	if err := pool.Enqueue(context.Background(), DeleteTask{UserID: "user1"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	waitFor(t, func() bool { return pool.Stats().ActiveWorkers == 1 }, "Expected the worker to claim the task")
+	if err := pool.Enqueue(context.Background(), DeleteTask{UserID: "user2"}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Expected ErrQueueFull while the claimed task is in the queue, got %v", err)
+	}
+	if pending, err := pool.Pending(context.Background()); err != nil || pending != 1 {
+		t.Fatalf("Expected one pending task, got %d, %v", pending, err)
+	}
+	close(release)
+	waitFor(t, func() bool { return pool.Enqueue(context.Background(), DeleteTask{UserID: "user2"}) == nil },
+		"Expected a task to be enqueued once the queue has room")
+}
+
+func TestWorkerPool_Resize(t *testing.T) {
+	pool := NewWorkerPool(context.Background(), 10, 1)
+	defer pool.Shutdown()
+	release := make(chan struct{})
+	pool.RegisterHandler("blocking_task", func(ctx context.Context, task Task) error {
+		<-release
+		return nil
+	})
+
+	if err := pool.Resize(3); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		if err := pool.Enqueue(context.Background(), &DummyTask{Type: "blocking_task"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	waitFor(t, func() bool { return pool.Stats().ActiveWorkers == 3 }, "Expected the added workers to process tasks")
+	if stats := pool.Stats(); stats.Workers != 3 || stats.QueueLength != 1 {
+		t.Fatalf("Expected 3 workers and one queued task, got %+v", stats)
+	}
+
+	if err := pool.Resize(1); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	close(release)
+	waitFor(t, func() bool { return pool.Stats().Processed == 4 }, "Expected the stopped workers to finish their tasks")
+	if workers := pool.Stats().Workers; workers != 1 {
+		t.Fatalf("Expected 1 worker, got %d", workers)
+	}
+
+	if err := pool.Resize(0); err == nil {
+		t.Fatal("Expected an error for no workers")
+	}
+	pool.Shutdown()
+	if err := pool.Resize(2); err == nil {
+		t.Fatal("Expected an error for a pool that is shut down")
+	}
+}
+
+func TestWorkerPool_SetConcurrencyLimit(t *testing.T) {
+	durable := NewDurableWorkerPool(context.Background(), 10, 3, inmemory.NewTaskStorage(), testPolicy)
+	defer durable.Shutdown()
+	pools := map[string]*WorkerPool{"memory": NewWorkerPool(context.Background(), 10, 3), "durable": durable}
+	for name, pool := range pools {
+		t.Run(name, func(t *testing.T) {
+			defer pool.Shutdown()
+			release := make(chan struct{})
+			running, most := atomic.Int64{}, atomic.Int64{}
+			pool.RegisterHandler(DeleteTask{}.TaskType(), func(ctx context.Context, task Task) error {
+				n := running.Add(1)
+				defer running.Add(-1)
+				for m := most.Load(); n > m && !most.CompareAndSwap(m, n); m = most.Load() {
+				}
+				<-release
+				return nil
+			})
+			expired := atomic.Int64{}
+			pool.RegisterHandler(ExpireTask{}.TaskType(), func(ctx context.Context, task Task) error {
+				expired.Add(1)
+				return nil
+			})
+			if err := pool.SetConcurrencyLimit(DeleteTask{}.TaskType(), 1); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			for i := 0; i < 4; i++ {
+				if err := pool.Enqueue(context.Background(), DeleteTask{UserID: fmt.Sprintf("user%d", i)}); err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+			}
+			if err := pool.Enqueue(context.Background(), ExpireTask{}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			waitFor(t, func() bool { return expired.Load() == 1 }, "Expected other task types to go on while the limited one is held back")
+			if stats := pool.Stats().Types[DeleteTask{}.TaskType()]; stats.Running != 1 || stats.Limit != 1 {
+				t.Fatalf("Expected one running limited task, got %+v", stats)
+			}
+
+			if err := pool.SetConcurrencyLimit(DeleteTask{}.TaskType(), 2); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			waitFor(t, func() bool { return running.Load() == 2 }, "Expected a raised limit to let a held task through")
+			close(release)
+			waitFor(t, func() bool { return pool.Stats().Types[DeleteTask{}.TaskType()].Processed == 4 }, "Expected all limited tasks to be processed")
+			if m := most.Load(); m != 2 {
+				t.Fatalf("Expected at most 2 limited tasks at once, got %d", m)
+			}
+			if stats := pool.Stats(); stats.QueueLength != 0 || stats.ActiveWorkers != 0 {
+				t.Fatalf("Expected no held or active task, got %+v", stats)
+			}
+		})
+	}
+
+	pool := NewWorkerPool(context.Background(), 10, 1)
+	defer pool.Shutdown()
+	if err := pool.SetConcurrencyLimit("unknown_task", 1); err == nil {
+		t.Fatal("Expected an error for a task type without a handler")
+	}
+	pool.RegisterHandler("test_task", func(ctx context.Context, task Task) error { return nil })
+	if err := pool.SetConcurrencyLimit("test_task", -1); err == nil {
+		t.Fatal("Expected an error for a negative limit")
+	}
+	if err := pool.SetConcurrencyLimits(map[string]int{"test_task": 3, "unknown_task": 1}); err == nil {
+		t.Fatal("Expected an error for a task type without a handler")
+	}
+	if limit := pool.Stats().Types["test_task"].Limit; limit != 0 {
+		t.Fatalf("Expected no limit to be set by an invalid batch, got %d", limit)
+	}
+}
+
+func TestParseConcurrencyLimits(t *testing.T) {
+	limits, err := ParseConcurrencyLimits(" delete_urls_task:2, expire_urls_task : 0,")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if want := map[string]int{"delete_urls_task": 2, "expire_urls_task": 0}; !reflect.DeepEqual(limits, want) {
+		t.Fatalf("Expected %v, got %v", want, limits)
+	}
+	if limits, err := ParseConcurrencyLimits(""); err != nil || len(limits) != 0 {
+		t.Fatalf("Expected no limits, got %v, %v", limits, err)
+	}
+	for _, spec := range []string{"delete_urls_task", ":2", "delete_urls_task:x", "delete_urls_task:-1"} {
+		if _, err := ParseConcurrencyLimits(spec); err == nil {
+			t.Fatalf("Expected an error for %q", spec)
+		}
+	}
+}
+
+func TestWorkerPool_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	pool := NewWorkerPool(context.Background(), 10, 1)
+	pool.tracer = provider.Tracer("test")
+	pool.RegisterHandler("failing_task", func(ctx context.Context, task Task) error {
+		return fmt.Errorf("failed")
+	})
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	if err := pool.Enqueue(ctx, &DummyTask{Type: "failing_task"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	parent.End()
+	time.Sleep(50 * time.Millisecond)
+	pool.Shutdown()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected the request and the task span, got %d spans", len(spans))
+	}
+	var task sdktrace.ReadOnlySpan
+	for _, span := range spans {
+		if span.Name() == "Task failing_task" {
+			task = span
+		}
+	}
+	if task == nil {
+		t.Fatal("Expected a span for the task")
+	}
+	if task.Parent().SpanID() != parent.SpanContext().SpanID() || task.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+		t.Fatalf("Expected the task span to continue the trace of the request")
+	}
+	if task.Status().Code != codes.Error {
+		t.Fatalf("Expected the task span to record the error, got %v", task.Status())
+	}
+}
+
+func TestWorkerPool_FairScheduling(t *testing.T) {
+	durable := NewDurableWorkerPool(context.Background(), 10, 1, inmemory.NewTaskStorage(), testPolicy)
+	defer durable.Shutdown()
+	pools := map[string]*WorkerPool{"memory": NewWorkerPool(context.Background(), 10, 1), "durable": durable}
+	for name, pool := range pools {
+		t.Run(name, func(t *testing.T) {
+			defer pool.Shutdown()
+			release := make(chan struct{})
+			var mu sync.Mutex
+			var order []string
+			record := func(name string) {
+				mu.Lock()
+				defer mu.Unlock()
+				order = append(order, name)
+			}
+			pool.RegisterHandler(DeleteTask{}.TaskType(), func(ctx context.Context, task Task) error {
+				deletion := task.(DeleteTask)
+				if deletion.UserID == "blocker" {
+					<-release
+					return nil
+				}
+				record(fmt.Sprintf("%s:%d", deletion.UserID, len(deletion.URLs)))
+				return nil
+			})
+			pool.RegisterHandler(FlushClicksTask{}.TaskType(), func(ctx context.Context, task Task) error {
+				record("flush")
+				return nil
+			})
+			pool.RegisterHandler(CompactJournalTask{}.TaskType(), func(ctx context.Context, task Task) error {
+				record("compact")
+				return nil
+			})
+
+			ctx := context.Background()
+			if err := pool.Enqueue(ctx, DeleteTask{UserID: "blocker"}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			waitFor(t, func() bool { return pool.Stats().ActiveWorkers == 1 }, "Expected the worker to take the blocking task")
+			large := make([]string, 100)
+			tasks := []Task{
+				CompactJournalTask{},
+				DeleteTask{UserID: "user1", URLs: large},
+				DeleteTask{UserID: "user1", URLs: large[:50]},
+				DeleteTask{UserID: "user1", URLs: large[:1]},
+				DeleteTask{UserID: "user2", URLs: large[:1]},
+				FlushClicksTask{},
+			}
+			for _, task := range tasks {
+				if err := pool.Enqueue(ctx, task); err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+			}
+			close(release)
+			waitFor(t, func() bool { return pool.Stats().Processed == uint64(len(tasks)+1) }, "Expected all tasks to be processed")
+
+			want := []string{"flush", "user2:1", "user1:100", "user1:50", "user1:1", "compact"}
+			mu.Lock()
+			defer mu.Unlock()
+			if !reflect.DeepEqual(order, want) {
+				t.Fatalf("Expected the higher priorities first and the small task of user2 before the backlog of user1, got %v", order)
+			}
+		})
+	}
+}
+
+func TestWorkerPool_FairKeyLimit(t *testing.T) {
+	durable := NewDurableWorkerPool(context.Background(), 10, 2, inmemory.NewTaskStorage(), testPolicy)
+	defer durable.Shutdown()
+	pools := map[string]*WorkerPool{"memory": NewWorkerPool(context.Background(), 10, 2), "durable": durable}
+	for name, pool := range pools {
+		t.Run(name, func(t *testing.T) {
+			defer pool.Shutdown()
+			release := make(chan struct{})
+			defer close(release)
+			running := atomic.Int64{}
+			done := make(chan struct{})
+			pool.RegisterHandler(DeleteTask{}.TaskType(), func(ctx context.Context, task Task) error {
+				if task.(DeleteTask).UserID == "user2" {
+					close(done)
+					return nil
+				}
+				running.Add(1)
+				defer running.Add(-1)
+				<-release
+				return nil
+			})
+
+			ctx := context.Background()
+			large := make([]string, 100)
+			for i := 0; i < 5; i++ {
+				if err := pool.Enqueue(ctx, DeleteTask{UserID: "user1", URLs: large}); err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+			}
+			waitFor(t, func() bool { return running.Load() > 0 }, "Expected a task of user1 to be taken")
+			if err := pool.Enqueue(ctx, DeleteTask{UserID: "user2", URLs: large[:1]}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("Expected the task of user2 to run while the backlog of user1 is running")
+			}
+			if n := running.Load(); n != 1 {
+				t.Fatalf("Expected a single running task of user1, got %d", n)
+			}
+		})
+	}
+}
+
+// testPolicy retries the tasks of the durable queues of the tests quickly.
+var testPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond, PollInterval: 5 * time.Millisecond}
+
+// waitFor fails the test if condition does not become true within a second.
+func waitFor(t *testing.T, condition func() bool, message string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal(message)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// deadLetters returns the dead letters of the queue.
+func deadLetters(t *testing.T, pool *WorkerPool) int {
+	t.Helper()
+	dead, err := pool.DeadLetters(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return len(dead)
+}
+
+func TestWorkerPool_DurableQueue(t *testing.T) {
+	pool := NewDurableWorkerPool(context.Background(), 10, 2, inmemory.NewTaskStorage(), testPolicy)
+	defer pool.Shutdown()
+	calls := atomic.Int64{}
+	var received atomic.Value
+	pool.RegisterHandler(DeleteTask{}.TaskType(), func(ctx context.Context, task Task) error {
+		received.Store(task)
+		if calls.Add(1) < 3 {
+			return fmt.Errorf("temporary failure")
+		}
+		return nil
+	})
+
+	task := DeleteTask{UserID: "user1", URLs: []string{"short1", "short2"}}
+	if err := pool.Enqueue(context.Background(), task); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	waitFor(t, func() bool { return pool.Stats().Processed == 3 }, "Expected the task to be retried until it succeeded")
+	if !reflect.DeepEqual(received.Load(), task) {
+		t.Fatalf("Expected the handler to receive %+v, got %+v", task, received.Load())
+	}
+	claimed, err := pool.queue.Claim(context.Background(), time.Now().Add(time.Hour), time.Minute, nil, nil)
+	if err != nil || claimed != nil {
+		t.Fatalf("Expected the succeeded task to leave the queue, got %+v, %v", claimed, err)
+	}
+	if deadLetters(t, pool) != 0 {
+		t.Fatal("Expected no dead letters")
+	}
+
+	pool.RegisterHandler("test_task", func(ctx context.Context, task Task) error {
+		return nil
+	})
+	if err := pool.Enqueue(context.Background(), &DummyTask{Type: "test_task"}); err == nil {
+		t.Fatal("Expected an error for a task that cannot be kept in the queue")
+	}
+}
+
+func TestWorkerPool_DeadLetters(t *testing.T) {
+	pool := NewDurableWorkerPool(context.Background(), 10, 1, inmemory.NewTaskStorage(), testPolicy)
+	defer pool.Shutdown()
+	var fail atomic.Pointer[error]
+	setFailure := func(err error) { fail.Store(&err) }
+	setFailure(fmt.Errorf("failure"))
+	pool.RegisterHandler(DeleteTask{}.TaskType(), func(ctx context.Context, task Task) error {
+		return *fail.Load()
+	})
+
+	if err := pool.Enqueue(context.Background(), DeleteTask{UserID: "user1"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	waitFor(t, func() bool { return deadLetters(t, pool) == 1 }, "Expected the task to be moved to the dead letters")
+	if processed := pool.Stats().Processed; processed != uint64(testPolicy.MaxAttempts) {
+		t.Fatalf("Expected %d attempts, got %d", testPolicy.MaxAttempts, processed)
+	}
+	dead, _ := pool.DeadLetters(context.Background(), 1)
+	if dead[0].Type != (DeleteTask{}).TaskType() || dead[0].LastError != "failure" || dead[0].Attempts != testPolicy.MaxAttempts {
+		t.Fatalf("Unexpected dead letter %+v", dead[0])
+	}
+
+	setFailure(Permanent(fmt.Errorf("permanent failure")))
+	if err := pool.Enqueue(context.Background(), DeleteTask{UserID: "user2"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	waitFor(t, func() bool { return deadLetters(t, pool) == 2 }, "Expected a permanent failure to be moved to the dead letters")
+	if processed := pool.Stats().Processed; processed != uint64(testPolicy.MaxAttempts)+1 {
+		t.Fatalf("Expected a permanent failure not to be retried, got %d attempts", processed)
+	}
+
+	setFailure(nil)
+	replayed, err := pool.Replay(context.Background(), dead[0].ID)
+	if err != nil || replayed.Attempts != 0 {
+		t.Fatalf("Expected the dead letter to be replayed, got %+v, %v", replayed, err)
+	}
+	waitFor(t, func() bool { return pool.Stats().Processed == uint64(testPolicy.MaxAttempts)+2 }, "Expected the replayed task to be processed")
+	if deadLetters(t, pool) != 1 {
+		t.Fatal("Expected the replayed task to leave the dead letters")
+	}
+	if _, err := pool.Replay(context.Background(), dead[0].ID); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("Expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestWorkerPool_DurableQueueSurvivesShutdown(t *testing.T) {
+	queue := inmemory.NewTaskStorage()
+	newPool := func(numWorkers int, queue interfaces.ITaskRepository) (*WorkerPool, *atomic.Int64) {
+		pool := NewDurableWorkerPool(context.Background(), 10, numWorkers, queue, testPolicy)
+		calls := &atomic.Int64{}
+		pool.RegisterHandler(DeleteTask{}.TaskType(), func(ctx context.Context, task Task) error {
+			calls.Add(1)
+			return nil
+		})
+		return pool, calls
+	}
+	stopped, _ := newPool(0, queue)
+	if err := stopped.Enqueue(context.Background(), DeleteTask{UserID: "user1"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	stopped.Shutdown()
+
+	restarted, calls := newPool(1, queue)
+	defer restarted.Shutdown()
+	waitFor(t, func() bool { return calls.Load() == 1 }, "Expected the task queued at shutdown to be processed after a restart")
+}
+
+func TestWorkerPool_ExtendsTaskLease(t *testing.T) {
+	queue := inmemory.NewTaskStorage()
+	policy := RetryPolicy{MaxAttempts: 5, PollInterval: 5 * time.Millisecond, LeaseTimeout: 30 * time.Millisecond}
+	calls := atomic.Int64{}
+	done := atomic.Int64{}
+	var pools []*WorkerPool
+	for i := 0; i < 2; i++ {
+		pool := NewDurableWorkerPool(context.Background(), 10, 1, queue, policy)
+		defer pool.Shutdown()
+		pool.RegisterHandler(DeleteTask{}.TaskType(), func(ctx context.Context, task Task) error {
+			calls.Add(1)
+			time.Sleep(10 * policy.LeaseTimeout)
+			done.Add(1)
+			return nil
+		})
+		pools = append(pools, pool)
+	}
+
+	if err := pools[0].Enqueue(context.Background(), DeleteTask{UserID: "user1"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	waitFor(t, func() bool { return done.Load() > 0 }, "Expected the task to be processed")
+	waitFor(t, func() bool {
+		count, err := queue.Count(context.Background())
+		return err == nil && count == 0
+	}, "Expected the task to leave the queue")
+	if c := calls.Load(); c != 1 {
+		t.Fatalf("Expected a task running past its lease to be processed once, got %d calls", c)
+	}
+}
+
+func TestWorkerPool_ShutdownReleasesTasks(t *testing.T) {
+	queue := inmemory.NewTaskStorage()
+	pool := NewDurableWorkerPool(context.Background(), 10, 1, queue, RetryPolicy{MaxAttempts: 1, PollInterval: 5 * time.Millisecond})
+	started := make(chan struct{})
+	pool.RegisterHandler(DeleteTask{}.TaskType(), func(ctx context.Context, task Task) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err := pool.Enqueue(context.Background(), DeleteTask{UserID: "user1"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	<-started
+	pool.Shutdown()
+
+	if deadLetters(t, pool) != 0 {
+		t.Fatal("Expected the interrupted task not to be moved to the dead letters")
+	}
+	claimed, err := queue.Claim(context.Background(), time.Now(), time.Minute, nil, nil)
+	if err != nil || claimed == nil {
+		t.Fatalf("Expected the interrupted task to be released at once, got %+v, %v", claimed, err)
+	}
+	if claimed.Attempts != 1 {
+		t.Fatalf("Expected the interrupted attempt not to be counted, got %d attempts", claimed.Attempts)
+	}
+}
+
+func TestWorkerPool_EnqueueAfter(t *testing.T) {
+	durable := NewDurableWorkerPool(context.Background(), 10, 1, inmemory.NewTaskStorage(), testPolicy)
+	defer durable.Shutdown()
+	pools := map[string]*WorkerPool{"memory": NewWorkerPool(context.Background(), 10, 1), "durable": durable}
+	for name, pool := range pools {
+		t.Run(name, func(t *testing.T) {
+			defer pool.Shutdown()
+			calls := atomic.Int64{}
+			pool.RegisterHandler(DeleteTask{}.TaskType(), func(ctx context.Context, task Task) error {
+				calls.Add(1)
+				return nil
+			})
+			enqueued := time.Now()
+			if err := pool.EnqueueAfter(context.Background(), DeleteTask{UserID: "user1"}, 100*time.Millisecond); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if err := pool.EnqueueAt(context.Background(), DeleteTask{UserID: "user2"}, enqueued.Add(-time.Minute)); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			waitFor(t, func() bool { return calls.Load() == 1 }, "Expected a task due in the past to be processed at once")
+			waitFor(t, func() bool { return calls.Load() == 2 }, "Expected the delayed task to be processed")
+			if elapsed := time.Since(enqueued); elapsed < 100*time.Millisecond {
+				t.Fatalf("Expected the task to be delayed, processed after %v", elapsed)
+			}
+		})
+	}
+
+	pool := NewWorkerPool(context.Background(), 10, 1)
+	pool.RegisterHandler(DeleteTask{}.TaskType(), func(ctx context.Context, task Task) error { return nil })
+	if err := pool.EnqueueAfter(context.Background(), DeleteTask{}, time.Hour); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	pool.Shutdown()
+	if err := pool.EnqueueAfter(context.Background(), DeleteTask{}, time.Hour); err == nil {
+		t.Fatal("Expected an error for a pool that is shut down")
+	}
+}
+
+func TestWorkerPool_RegisterJob(t *testing.T) {
+	locks := inmemory.NewJobLockStorage()
+	var running, overlaps, runs atomic.Int64
+	newPool := func() *WorkerPool {
+		pool := NewWorkerPool(context.Background(), 10, 1)
+		pool.SetJobLocks(locks)
+		pool.RegisterHandler(ExpireTask{}.TaskType(), func(ctx context.Context, task Task) error {
+			if running.Add(1) > 1 {
+				overlaps.Add(1)
+			}
+			time.Sleep(30 * time.Millisecond)
+			running.Add(-1)
+			runs.Add(1)
+			return nil
+		})
+		if err := pool.RegisterJob("expire_urls", "@every 10ms", ExpireTask{}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return pool
+	}
+	first, second := newPool(), newPool()
+	waitFor(t, func() bool { return runs.Load() >= 5 }, "Expected the job to run on its schedule")
+	first.Shutdown()
+	second.Shutdown()
+	if overlaps.Load() != 0 {
+		t.Fatalf("Expected the runs of the job never to overlap across the pools, got %d overlaps", overlaps.Load())
+	}
+	if first.Stats().Processed == 0 && second.Stats().Processed == 0 {
+		t.Fatal("Expected the runs to be counted as processed")
+	}
+
+	pool := NewWorkerPool(context.Background(), 10, 1)
+	defer pool.Shutdown()
+	if err := pool.RegisterJob("expire_urls", "@hourly", ExpireTask{}); err == nil {
+		t.Fatal("Expected an error for a task without a handler")
+	}
+	pool.RegisterHandler(ExpireTask{}.TaskType(), func(ctx context.Context, task Task) error { return nil })
+	if err := pool.RegisterJob("expire_urls", "every hour", ExpireTask{}); err == nil {
+		t.Fatal("Expected an error for an invalid schedule")
+	}
+	if err := pool.RegisterJob("expire_urls", "@hourly", ExpireTask{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := pool.RegisterJob("expire_urls", "@daily", ExpireTask{}); err == nil {
+		t.Fatal("Expected an error for a job registered twice")
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{attempt: 1, max: time.Second},
+		{attempt: 2, max: 2 * time.Second},
+		{attempt: 3, max: 4 * time.Second},
+		{attempt: 5, max: 10 * time.Second},
+		{attempt: 100, max: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if delay := policy.Backoff(tt.attempt); delay < tt.max/2 || delay > tt.max {
+				t.Fatalf("Expected the delay of attempt %d between %v and %v, got %v", tt.attempt, tt.max/2, tt.max, delay)
+			}
+		}
+	}
+	if defaults := (RetryPolicy{}).withDefaults(); defaults != DefaultRetryPolicy() {
+		t.Fatalf("Expected the default policy, got %+v", defaults)
+	}
+}
@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueryTracer is a pgx.QueryTracer starting a span for each query sent to PostgreSQL,
+// with the SQL text as the db.query.text attribute. Set it as the Tracer of the connection config.
+type QueryTracer struct {
+	tracer trace.Tracer
+}
+
+// NewQueryTracer creates a QueryTracer using the global tracer provider.
+func NewQueryTracer() *QueryTracer {
+	return &QueryTracer{tracer: otel.Tracer(TracerName)}
+}
+
+// TraceQueryStart starts the span of the query, named after its SQL command such as "SELECT".
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = t.tracer.Start(ctx, sqlCommand(data.SQL),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBQueryText(data.SQL)),
+	)
+	return ctx
+}
+
+// TraceQueryEnd ends the span of the query, recording its error, if any.
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	}
+	span.End()
+}
+
+// sqlCommand returns the first keyword of the SQL statement in upper case.
+func sqlCommand(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "SQL"
+	}
+	return strings.ToUpper(fields[0])
+}
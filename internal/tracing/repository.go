@@ -0,0 +1,293 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/interfaces"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Database systems reported by the repository wrappers.
+var (
+	SystemPostgreSQL = semconv.DBSystemPostgreSQL
+	SystemSQLite     = semconv.DBSystemSqlite
+)
+
+// URLRepository wraps a database URL repository and starts a client span named after the
+// statement for each call, such as "URLRepository.FindByID".
+type URLRepository struct {
+	repo   interfaces.IURLRepository
+	system attribute.KeyValue
+	tracer trace.Tracer
+}
+
+// NewURLRepository returns repo with a span around each call, reporting system as the database system.
+func NewURLRepository(repo interfaces.IURLRepository, system attribute.KeyValue) *URLRepository {
+	return &URLRepository{repo: repo, system: system, tracer: otel.Tracer(TracerName)}
+}
+
+// start starts the span of the statement.
+func (r *URLRepository) start(ctx context.Context, statement string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return startStatement(ctx, r.tracer, "URLRepository."+statement, r.system, attrs...)
+}
+
+// Insert adds a new URL.
+func (r *URLRepository) Insert(ctx context.Context, url *model.URL) (_ *model.URL, err error) {
+	ctx, span := r.start(ctx, "Insert")
+	defer End(span, &err)
+	return r.repo.Insert(ctx, url)
+}
+
+// InsertList adds the URLs in a single operation.
+func (r *URLRepository) InsertList(ctx context.Context, urls []*model.URL) (_ []*model.URL, err error) {
+	ctx, span := r.start(ctx, "InsertList", attribute.Int("db.batch.size", len(urls)))
+	defer End(span, &err)
+	return r.repo.InsertList(ctx, urls)
+}
+
+// FindByID retrieves a URL by its short ID.
+func (r *URLRepository) FindByID(ctx context.Context, shortID string) (_ *model.URL, err error) {
+	ctx, span := r.start(ctx, "FindByID")
+	defer End(span, &err)
+	return r.repo.FindByID(ctx, shortID)
+}
+
+// FindListByUserID retrieves a page of the URLs of the user.
+func (r *URLRepository) FindListByUserID(ctx context.Context, userID string, query model.URLListQuery) (_ []*model.URL, err error) {
+	ctx, span := r.start(ctx, "FindListByUserID")
+	defer End(span, &err)
+	return r.repo.FindListByUserID(ctx, userID, query)
+}
+
+// UpdateOriginalURL changes the original URL of a URL owned by the user.
+func (r *URLRepository) UpdateOriginalURL(ctx context.Context, userID, shortID, originalURL string) (_ bool, err error) {
+	ctx, span := r.start(ctx, "UpdateOriginalURL")
+	defer End(span, &err)
+	return r.repo.UpdateOriginalURL(ctx, userID, shortID, originalURL)
+}
+
+// DeleteListByUserIDAndShortIDs soft deletes the URLs of the user with the given short IDs.
+func (r *URLRepository) DeleteListByUserIDAndShortIDs(ctx context.Context, userID string, shortIDs []string) (err error) {
+	ctx, span := r.start(ctx, "DeleteListByUserIDAndShortIDs", attribute.Int("db.batch.size", len(shortIDs)))
+	defer End(span, &err)
+	return r.repo.DeleteListByUserIDAndShortIDs(ctx, userID, shortIDs)
+}
+
+// ReassignUserID moves the non-deleted URLs of a user to another user.
+func (r *URLRepository) ReassignUserID(ctx context.Context, fromUserID, toUserID string) (_ int64, err error) {
+	ctx, span := r.start(ctx, "ReassignUserID")
+	defer End(span, &err)
+	return r.repo.ReassignUserID(ctx, fromUserID, toUserID)
+}
+
+// SetDisabled marks the URLs with the given short IDs as disabled or enabled again.
+func (r *URLRepository) SetDisabled(ctx context.Context, shortIDs []string, disabled bool) (_ int64, err error) {
+	ctx, span := r.start(ctx, "SetDisabled", attribute.Int("db.batch.size", len(shortIDs)))
+	defer End(span, &err)
+	return r.repo.SetDisabled(ctx, shortIDs, disabled)
+}
+
+// FindListByDomain retrieves the non-deleted URLs pointing to the domain.
+func (r *URLRepository) FindListByDomain(ctx context.Context, domain string) (_ []*model.URL, err error) {
+	ctx, span := r.start(ctx, "FindListByDomain")
+	defer End(span, &err)
+	return r.repo.FindListByDomain(ctx, domain)
+}
+
+// IncrementClicks counts a redirect against the click budget of the URL.
+func (r *URLRepository) IncrementClicks(ctx context.Context, shortID string) (_ bool, err error) {
+	ctx, span := r.start(ctx, "IncrementClicks")
+	defer End(span, &err)
+	return r.repo.IncrementClicks(ctx, shortID)
+}
+
+// DeleteExpired soft deletes the URLs that expired at the given moment.
+func (r *URLRepository) DeleteExpired(ctx context.Context, now time.Time) (_ int64, err error) {
+	ctx, span := r.start(ctx, "DeleteExpired")
+	defer End(span, &err)
+	return r.repo.DeleteExpired(ctx, now)
+}
+
+// List retrieves all URLs.
+func (r *URLRepository) List(ctx context.Context) (_ []*model.URL, err error) {
+	ctx, span := r.start(ctx, "List")
+	defer End(span, &err)
+	return r.repo.List(ctx)
+}
+
+// Ping checks the connection to the database.
+func (r *URLRepository) Ping(ctx context.Context) (err error) {
+	ctx, span := r.start(ctx, "Ping")
+	defer End(span, &err)
+	return r.repo.Ping(ctx)
+}
+
+// ClickRepository wraps a database click repository and starts a client span named after
+// the statement for each call, such as "ClickRepository.GetStats".
+type ClickRepository struct {
+	repo   interfaces.IClickRepository
+	system attribute.KeyValue
+	tracer trace.Tracer
+}
+
+// NewClickRepository returns repo with a span around each call, reporting system as the database system.
+func NewClickRepository(repo interfaces.IClickRepository, system attribute.KeyValue) *ClickRepository {
+	return &ClickRepository{repo: repo, system: system, tracer: otel.Tracer(TracerName)}
+}
+
+// InsertList adds the click events in a single operation.
+func (r *ClickRepository) InsertList(ctx context.Context, clicks []*model.Click) (err error) {
+	ctx, span := startStatement(ctx, r.tracer, "ClickRepository.InsertList", r.system, attribute.Int("db.batch.size", len(clicks)))
+	defer End(span, &err)
+	return r.repo.InsertList(ctx, clicks)
+}
+
+// GetStats aggregates the click events of a URL.
+func (r *ClickRepository) GetStats(ctx context.Context, shortID string) (_ *model.ClickStats, err error) {
+	ctx, span := startStatement(ctx, r.tracer, "ClickRepository.GetStats", r.system)
+	defer End(span, &err)
+	return r.repo.GetStats(ctx, shortID)
+}
+
+// APIKeyRepository wraps a database API key repository and starts a client span named after
+// the statement for each call, such as "APIKeyRepository.FindByHash".
+type APIKeyRepository struct {
+	repo   interfaces.IAPIKeyRepository
+	system attribute.KeyValue
+	tracer trace.Tracer
+}
+
+// NewAPIKeyRepository returns repo with a span around each call, reporting system as the database system.
+func NewAPIKeyRepository(repo interfaces.IAPIKeyRepository, system attribute.KeyValue) *APIKeyRepository {
+	return &APIKeyRepository{repo: repo, system: system, tracer: otel.Tracer(TracerName)}
+}
+
+// Insert adds a new API key.
+func (r *APIKeyRepository) Insert(ctx context.Context, key *model.APIKey) (err error) {
+	ctx, span := startStatement(ctx, r.tracer, "APIKeyRepository.Insert", r.system)
+	defer End(span, &err)
+	return r.repo.Insert(ctx, key)
+}
+
+// FindByHash retrieves an API key by the hash of the key.
+func (r *APIKeyRepository) FindByHash(ctx context.Context, keyHash string) (_ *model.APIKey, err error) {
+	ctx, span := startStatement(ctx, r.tracer, "APIKeyRepository.FindByHash", r.system)
+	defer End(span, &err)
+	return r.repo.FindByHash(ctx, keyHash)
+}
+
+// FindListByUserID retrieves the API keys of the user.
+func (r *APIKeyRepository) FindListByUserID(ctx context.Context, userID string) (_ []*model.APIKey, err error) {
+	ctx, span := startStatement(ctx, r.tracer, "APIKeyRepository.FindListByUserID", r.system)
+	defer End(span, &err)
+	return r.repo.FindListByUserID(ctx, userID)
+}
+
+// Revoke marks an API key of the user as revoked.
+func (r *APIKeyRepository) Revoke(ctx context.Context, userID, id string, revokedAt time.Time) (_ bool, err error) {
+	ctx, span := startStatement(ctx, r.tracer, "APIKeyRepository.Revoke", r.system)
+	defer End(span, &err)
+	return r.repo.Revoke(ctx, userID, id, revokedAt)
+}
+
+// IdentityRepository wraps a database identity repository and starts a client span named after
+// the statement for each call, such as "IdentityRepository.FindOrInsert".
+type IdentityRepository struct {
+	repo   interfaces.IIdentityRepository
+	system attribute.KeyValue
+	tracer trace.Tracer
+}
+
+// NewIdentityRepository returns repo with a span around each call, reporting system as the database system.
+func NewIdentityRepository(repo interfaces.IIdentityRepository, system attribute.KeyValue) *IdentityRepository {
+	return &IdentityRepository{repo: repo, system: system, tracer: otel.Tracer(TracerName)}
+}
+
+// FindOrInsert links the account to a user unless it is already linked.
+func (r *IdentityRepository) FindOrInsert(ctx context.Context, identity *model.UserIdentity) (_ *model.UserIdentity, err error) {
+	ctx, span := startStatement(ctx, r.tracer, "IdentityRepository.FindOrInsert", r.system)
+	defer End(span, &err)
+	return r.repo.FindOrInsert(ctx, identity)
+}
+
+// FindByUserID retrieves an identity linked to the user.
+func (r *IdentityRepository) FindByUserID(ctx context.Context, userID string) (_ *model.UserIdentity, err error) {
+	ctx, span := startStatement(ctx, r.tracer, "IdentityRepository.FindByUserID", r.system)
+	defer End(span, &err)
+	return r.repo.FindByUserID(ctx, userID)
+}
+
+// AuditRepository wraps a database audit repository and starts a client span named after
+// the statement for each call, such as "AuditRepository.Insert".
+type AuditRepository struct {
+	repo   interfaces.IAuditRepository
+	system attribute.KeyValue
+	tracer trace.Tracer
+}
+
+// NewAuditRepository returns repo with a span around each call, reporting system as the database system.
+func NewAuditRepository(repo interfaces.IAuditRepository, system attribute.KeyValue) *AuditRepository {
+	return &AuditRepository{repo: repo, system: system, tracer: otel.Tracer(TracerName)}
+}
+
+// Insert appends an entry to the audit log.
+func (r *AuditRepository) Insert(ctx context.Context, entry *model.AuditEntry) (err error) {
+	ctx, span := startStatement(ctx, r.tracer, "AuditRepository.Insert", r.system)
+	defer End(span, &err)
+	return r.repo.Insert(ctx, entry)
+}
+
+// FindList retrieves the audit entries matching the query.
+func (r *AuditRepository) FindList(ctx context.Context, query model.AuditQuery) (_ []*model.AuditEntry, err error) {
+	ctx, span := startStatement(ctx, r.tracer, "AuditRepository.FindList", r.system)
+	defer End(span, &err)
+	return r.repo.FindList(ctx, query)
+}
+
+// TaskStatusRepository wraps a database task status repository and starts a client span named
+// after the statement for each call, such as "TaskStatusRepository.FindByID".
+type TaskStatusRepository struct {
+	repo   interfaces.ITaskStatusRepository
+	system attribute.KeyValue
+	tracer trace.Tracer
+}
+
+// NewTaskStatusRepository returns repo with a span around each call, reporting system as the database system.
+func NewTaskStatusRepository(repo interfaces.ITaskStatusRepository, system attribute.KeyValue) *TaskStatusRepository {
+	return &TaskStatusRepository{repo: repo, system: system, tracer: otel.Tracer(TracerName)}
+}
+
+// Insert adds the status of a new task.
+func (r *TaskStatusRepository) Insert(ctx context.Context, status *model.TaskStatus) (err error) {
+	ctx, span := startStatement(ctx, r.tracer, "TaskStatusRepository.Insert", r.system)
+	defer End(span, &err)
+	return r.repo.Insert(ctx, status)
+}
+
+// FindByID retrieves the status of a task by its ID.
+func (r *TaskStatusRepository) FindByID(ctx context.Context, id string) (_ *model.TaskStatus, err error) {
+	ctx, span := startStatement(ctx, r.tracer, "TaskStatusRepository.FindByID", r.system)
+	defer End(span, &err)
+	return r.repo.FindByID(ctx, id)
+}
+
+// Update replaces the state, items, error and update time of a task.
+func (r *TaskStatusRepository) Update(ctx context.Context, status *model.TaskStatus) (err error) {
+	ctx, span := startStatement(ctx, r.tracer, "TaskStatusRepository.Update", r.system)
+	defer End(span, &err)
+	return r.repo.Update(ctx, status)
+}
+
+// startStatement starts a client span named after the statement of a repository.
+func startStatement(ctx context.Context, tracer trace.Tracer, statement string, system attribute.KeyValue, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, statement,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(system, semconv.DBOperationName(statement)),
+		trace.WithAttributes(attrs...),
+	)
+}
@@ -0,0 +1,125 @@
+// Package tracing sets up OpenTelemetry tracing of the service.
+//
+// Setup installs the global tracer provider and the W3C trace context propagator, exporting
+// the spans to an OTLP/HTTP collector, to stdout or to a file as configured. Without an
+// exporter the global no-op provider is kept, so the spans started by the other packages
+// cost next to nothing.
+//
+// Spans are started for each HTTP request by Middleware, for the queries of a database
+// repository by the repository wrappers and QueryTracer, by the URLService methods and by
+// the worker pool for each task, continuing the trace of the request that enqueued it.
+//
+// Example usage:
+//
+//	shutdown, err := tracing.Setup(ctx, cfg)
+//	if err != nil {
+//		return err
+//	}
+//	defer shutdown(context.Background())
+//	router.Use(tracing.Middleware)
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/GlebRadaev/shlink/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the name of the tracer used by the instrumentation in this package.
+const TracerName = "github.com/GlebRadaev/shlink/internal/tracing"
+
+// Supported values of config.Config.TracingExporter.
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+// ErrUnknownExporter is returned by Setup when the configured exporter is not supported.
+var ErrUnknownExporter = errors.New("unknown tracing exporter")
+
+// Setup installs the tracer provider exporting spans with the exporter of the configuration
+// and the W3C trace context and baggage propagators. The returned function flushes the
+// remaining spans and closes the exporter. Without an exporter nothing is installed and
+// the returned function does nothing.
+func Setup(ctx context.Context, cfg *config.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if cfg.TracingExporter == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporter, closer, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(cfg.TracingServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TracingSampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}, nil
+}
+
+// newExporter creates the span exporter of the configuration and the file it writes to, if any.
+func newExporter(ctx context.Context, cfg *config.Config) (sdktrace.SpanExporter, io.Closer, error) {
+	switch cfg.TracingExporter {
+	case ExporterOTLP:
+		exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.TracingEndpoint))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		return exporter, nil, nil
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		return exporter, nil, nil
+	case ExporterFile:
+		file, err := os.OpenFile(cfg.TracingFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("failed to create file exporter: %w", err)
+		}
+		return exporter, file, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: %q", ErrUnknownExporter, cfg.TracingExporter)
+	}
+}
+
+// End records err on the span, if any, and ends the span. It is meant to be deferred
+// with a pointer to the named error result of the traced function.
+func End(span trace.Span, err *error) {
+	if err != nil && *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
@@ -0,0 +1,158 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GlebRadaev/shlink/internal/config"
+	"github.com/GlebRadaev/shlink/internal/model"
+	"github.com/GlebRadaev/shlink/internal/repository"
+	"github.com/GlebRadaev/shlink/internal/tracing"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/mock/gomock"
+)
+
+// setupRecorder installs a tracer provider recording the ended spans for the duration of the test.
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	previous := otel.GetTracerProvider()
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+// attributes returns the attributes of the span as a map.
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, attr := range span.Attributes() {
+		attrs[attr.Key] = attr.Value
+	}
+	return attrs
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := setupRecorder(t)
+	_, err := tracing.Setup(context.Background(), &config.Config{})
+	require.NoError(t, err)
+	router := chi.NewRouter()
+	router.Use(tracing.Middleware)
+	router.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	})
+	router.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "GET /{id}", spans[0].Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+	assert.Equal(t, int64(http.StatusTemporaryRedirect), attributes(spans[0])["http.response.status_code"].AsInt64())
+	assert.Equal(t, "/{id}", attributes(spans[0])["http.route"].AsString())
+	assert.Equal(t, "GET /fail", spans[1].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+}
+
+func TestURLRepository(t *testing.T) {
+	recorder := setupRecorder(t)
+	ctrl := gomock.NewController(t)
+	mockRepo := repository.NewMockIURLRepository(ctrl)
+	repo := tracing.NewURLRepository(mockRepo, tracing.SystemPostgreSQL)
+	ctx := context.Background()
+
+	mockRepo.EXPECT().FindByID(gomock.Any(), "abc123").Return(&model.URL{ShortID: "abc123"}, nil)
+	mockRepo.EXPECT().DeleteListByUserIDAndShortIDs(gomock.Any(), "user", []string{"abc123"}).Return(errors.New("connection reset"))
+
+	url, err := repo.FindByID(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", url.ShortID)
+	assert.EqualError(t, repo.DeleteListByUserIDAndShortIDs(ctx, "user", []string{"abc123"}), "connection reset")
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "URLRepository.FindByID", spans[0].Name())
+	assert.Equal(t, "postgresql", attributes(spans[0])["db.system"].AsString())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, "URLRepository.DeleteListByUserIDAndShortIDs", spans[1].Name())
+	assert.Equal(t, int64(1), attributes(spans[1])["db.batch.size"].AsInt64())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+}
+
+func TestQueryTracer(t *testing.T) {
+	recorder := setupRecorder(t)
+	tracer := tracing.NewQueryTracer()
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "\n\tSELECT * FROM urls WHERE short_id = $1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("timeout")})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "SELECT", spans[0].Name())
+	assert.Contains(t, attributes(spans[0])["db.query.text"].AsString(), "FROM urls")
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	ctx := context.Background()
+
+	t.Run("does nothing without an exporter", func(t *testing.T) {
+		shutdown, err := tracing.Setup(ctx, &config.Config{})
+		require.NoError(t, err)
+		assert.NoError(t, shutdown(ctx))
+	})
+
+	t.Run("rejects an unknown exporter", func(t *testing.T) {
+		_, err := tracing.Setup(ctx, &config.Config{TracingExporter: "zipkin"})
+		assert.ErrorIs(t, err, tracing.ErrUnknownExporter)
+	})
+
+	t.Run("writes spans to the trace file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "traces.json")
+		shutdown, err := tracing.Setup(ctx, &config.Config{
+			TracingExporter:    tracing.ExporterFile,
+			TracingFile:        path,
+			TracingSampleRatio: 1,
+			TracingServiceName: "shlink-test",
+		})
+		require.NoError(t, err)
+
+		_, span := otel.Tracer("test").Start(ctx, "URLService.Shorten")
+		span.End()
+		require.NoError(t, shutdown(ctx))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"Name":"URLService.Shorten"`)
+		assert.Contains(t, string(data), "shlink-test")
+	})
+
+	t.Run("fails if the trace file cannot be opened", func(t *testing.T) {
+		_, err := tracing.Setup(ctx, &config.Config{
+			TracingExporter: tracing.ExporterFile,
+			TracingFile:     filepath.Join(t.TempDir(), "missing", "traces.json"),
+		})
+		assert.Error(t, err)
+	})
+}
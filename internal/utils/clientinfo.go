@@ -0,0 +1,34 @@
+// Package utils provides various utility functions, including extraction of
+// client information from incoming HTTP requests.
+package utils
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// countryHeaders lists the headers set by CDNs and reverse proxies that carry the visitor's country code.
+var countryHeaders = []string{"CF-IPCountry", "X-Country-Code", "X-Geo-Country"}
+
+// ClientIP returns the client address of the request without the port. It relies on
+// the RealIP middleware having replaced RemoteAddr with the forwarded address.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ClientCountry returns the two-letter country code of the client if a proxy provided one,
+// or an empty string if it is unknown.
+func ClientCountry(r *http.Request) string {
+	for _, header := range countryHeaders {
+		country := strings.ToUpper(strings.TrimSpace(r.Header.Get(header)))
+		if len(country) == 2 && country != "XX" {
+			return country
+		}
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		want       string
+	}{
+		{"address with port", "192.0.2.1:1234", "192.0.2.1"},
+		{"IPv6 address with port", "[2001:db8::1]:1234", "2001:db8::1"},
+		{"address set by RealIP", "198.51.100.7", "198.51.100.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			assert.Equal(t, tt.want, ClientIP(r))
+		})
+	}
+}
+
+func TestClientCountry(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"no headers", nil, ""},
+		{"cloudflare header", map[string]string{"CF-IPCountry": "de"}, "DE"},
+		{"unknown country", map[string]string{"CF-IPCountry": "XX", "X-Country-Code": "FR"}, "FR"},
+		{"invalid value", map[string]string{"X-Geo-Country": "Germany"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+			assert.Equal(t, tt.want, ClientCountry(r))
+		})
+	}
+}
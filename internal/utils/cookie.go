@@ -3,7 +3,6 @@
 package utils
 
 import (
-	"errors"
 	"net/http"
 	"time"
 
@@ -20,39 +19,19 @@ type Claims struct {
 // Constants used for handling JWT tokens and cookies in the application.
 const (
 	NameCookieUserID = "user_id"     // Name of the cookie where the user ID is stored.
-	SecretKey        = "secret_key"  // Secret key used to sign JWT tokens.
-	TokenExp         = time.Hour * 3 // Expiration time for the JWT token.
+	DefaultTokenTTL  = time.Hour * 3 // Lifetime of the tokens until a token manager is configured.
 )
 
-// GenerateJWT creates a JWT token containing the provided user ID with a 3-hour expiration time.
-// The token is signed with a secret key and returned as a string.
+// GenerateJWT creates a JWT token containing the provided user ID, signed with the active key
+// of the default token manager.
 func GenerateJWT(userID string) (string, error) {
-	claims := &Claims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenExp)),
-		},
-		UserID: userID,
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(SecretKey))
+	return DefaultTokenManager().Generate(userID)
 }
 
-// ParseJWT parses and validates a JWT token string. It uses the secret key to verify the signature
-// and returns an error if the token is invalid or expired.
+// ParseJWT parses and validates a JWT token string. It verifies the signature with the keys of the
+// default token manager and returns an error if the token is invalid or expired.
 func ParseJWT(tokenString string, claims *Claims) error {
-	parsedToken, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("expected signing method: " + jwt.SigningMethodHS256.Alg())
-		}
-		return []byte(SecretKey), nil
-	})
-	if err != nil {
-		return err
-	}
-	if !parsedToken.Valid {
-		return errors.New("invalid token")
-	}
-	return nil
+	return DefaultTokenManager().Parse(tokenString, claims)
 }
 
 // CreateCookie creates and returns an HTTP cookie with the specified name and value.
@@ -66,31 +45,34 @@ func CreateCookie(name, value string) *http.Cookie {
 // SetUserIDInCookie generates a new user ID, creates a JWT with that ID, and sets the token as a cookie
 // in the response. It returns the generated user ID.
 func SetUserIDInCookie(w http.ResponseWriter, r *http.Request) (string, error) {
-	userID := GenerateUUID()
-	tokenString, _ := GenerateJWT(userID)
-	cookie := CreateCookie(NameCookieUserID, tokenString)
-	http.SetCookie(w, cookie)
-	return userID, nil
+	return DefaultTokenManager().SetUserIDInCookie(w, r)
+}
+
+// SetCookieForUserID creates a JWT for an existing user, such as one who signed in, and sets the token
+// as a cookie in the response.
+func SetCookieForUserID(w http.ResponseWriter, userID string) error {
+	return DefaultTokenManager().SetCookieForUserID(w, userID)
+}
+
+// ClearUserIDCookie removes the cookie holding the user ID, signing the user out.
+func ClearUserIDCookie(w http.ResponseWriter) {
+	DefaultTokenManager().ClearCookie(w)
 }
 
 // GetUserIDFromCookie retrieves the user ID from the cookie in the request.
 func GetUserIDFromCookie(r *http.Request) (string, bool) {
-	cookie, err := r.Cookie(NameCookieUserID)
-	if err != nil {
-		return "", false
-	}
-	claims := &Claims{}
-	if err := ParseJWT(cookie.Value, claims); err != nil {
-		return "", false
-	}
-	return claims.UserID, true
+	return DefaultTokenManager().GetUserIDFromCookie(r)
+}
+
+// RefreshUserIDFromCookie retrieves the user ID from the cookie in the request, re-issuing the token
+// if it is about to expire or was signed with a retired key.
+func RefreshUserIDFromCookie(w http.ResponseWriter, r *http.Request) (string, bool) {
+	return DefaultTokenManager().RefreshUserIDFromCookie(w, r)
 }
 
 // GetOrSetUserIDFromCookie checks if a valid user ID is present in the cookie. If a valid ID is found,
-// it returns the user ID.
+// it returns the user ID, re-issuing the token if it is about to expire or was signed with a
+// retired key; otherwise a new user ID is set in the cookie.
 func GetOrSetUserIDFromCookie(w http.ResponseWriter, r *http.Request) (string, error) {
-	if userID, ok := GetUserIDFromCookie(r); ok {
-		return userID, nil
-	}
-	return SetUserIDInCookie(w, r)
+	return DefaultTokenManager().GetOrSetUserIDFromCookie(w, r)
 }
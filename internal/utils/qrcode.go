@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"rsc.io/qr"
+)
+
+// Supported QR code image formats.
+const (
+	QRFormatPNG = "png"
+	QRFormatSVG = "svg"
+)
+
+// Bounds and defaults of the QR code options.
+const (
+	DefaultQRSize   = 256
+	MinQRSize       = 64
+	MaxQRSize       = 2048
+	DefaultQRMargin = 4
+	MaxQRMargin     = 16
+)
+
+// qrLevels maps error correction level names to encoder levels.
+var qrLevels = map[string]qr.Level{"L": qr.L, "M": qr.M, "Q": qr.Q, "H": qr.H}
+
+// QROptions describes how a QR code image is rendered.
+type QROptions struct {
+	Format string // Image format, QRFormatPNG or QRFormatSVG.
+	Size   int    // Width and height of the image in pixels.
+	Level  string // Error correction level: L, M, Q or H.
+	Margin int    // Width of the quiet zone around the code in modules.
+}
+
+// ParseQROptions reads the format, size, ecc and margin query parameters, applying defaults for missing ones.
+func ParseQROptions(query url.Values) (QROptions, error) {
+	opts := QROptions{Format: QRFormatPNG, Size: DefaultQRSize, Level: "M", Margin: DefaultQRMargin}
+	if format := strings.ToLower(query.Get("format")); format != "" {
+		if format != QRFormatPNG && format != QRFormatSVG {
+			return opts, errors.New("invalid format")
+		}
+		opts.Format = format
+	}
+	if size := query.Get("size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < MinQRSize || n > MaxQRSize {
+			return opts, errors.New("invalid size")
+		}
+		opts.Size = n
+	}
+	if level := strings.ToUpper(query.Get("ecc")); level != "" {
+		if _, ok := qrLevels[level]; !ok {
+			return opts, errors.New("invalid error correction level")
+		}
+		opts.Level = level
+	}
+	if margin := query.Get("margin"); margin != "" {
+		n, err := strconv.Atoi(margin)
+		if err != nil || n < 0 || n > MaxQRMargin {
+			return opts, errors.New("invalid margin")
+		}
+		opts.Margin = n
+	}
+	return opts, nil
+}
+
+// ContentType returns the MIME type of the image format.
+func (o QROptions) ContentType() string {
+	if o.Format == QRFormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// ETag returns a strong entity tag for the QR code of content rendered with the options.
+// Rendering is deterministic, so the tag is derived from the inputs without encoding the image.
+func (o QROptions) ETag(content string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%s|%d", content, o.Format, o.Size, o.Level, o.Margin)))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// EncodeQR renders content as a QR code image according to the options.
+func EncodeQR(content string, opts QROptions) ([]byte, error) {
+	level, ok := qrLevels[opts.Level]
+	if !ok {
+		return nil, errors.New("invalid error correction level")
+	}
+	code, err := qr.Encode(content, level)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Format == QRFormatSVG {
+		return encodeQRSVG(code, opts), nil
+	}
+	return encodeQRPNG(code, opts)
+}
+
+// encodeQRPNG draws the code with whole pixels per module, centered in an image of the requested size.
+// The image is larger than requested if the code does not fit with one pixel per module.
+func encodeQRPNG(code *qr.Code, opts QROptions) ([]byte, error) {
+	modules := code.Size + 2*opts.Margin
+	scale := max(opts.Size/modules, 1)
+	dim := max(opts.Size, modules*scale)
+	offset := (dim-modules*scale)/2 + opts.Margin*scale
+
+	img := image.NewPaletted(image.Rect(0, 0, dim, dim), color.Palette{color.White, color.Black})
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if !code.Black(x, y) {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				row := img.PixOffset(offset+x*scale, offset+y*scale+dy)
+				for dx := 0; dx < scale; dx++ {
+					img.Pix[row+dx] = 1
+				}
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeQRSVG draws the code as a single path, merging horizontal runs of dark modules.
+func encodeQRSVG(code *qr.Code, opts QROptions) []byte {
+	modules := code.Size + 2*opts.Margin
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		opts.Size, opts.Size, modules, modules)
+	buf.WriteString(`<rect width="100%" height="100%" fill="#fff"/><path fill="#000" d="`)
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; {
+			if !code.Black(x, y) {
+				x++
+				continue
+			}
+			run := 1
+			for x+run < code.Size && code.Black(x+run, y) {
+				run++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", x+opts.Margin, y+opts.Margin, run, run)
+			x += run
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQROptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    QROptions
+		wantErr string
+	}{
+		{"defaults", "", QROptions{Format: QRFormatPNG, Size: DefaultQRSize, Level: "M", Margin: DefaultQRMargin}, ""},
+		{"all options", "format=SVG&size=512&ecc=h&margin=0", QROptions{Format: QRFormatSVG, Size: 512, Level: "H", Margin: 0}, ""},
+		{"unknown format", "format=gif", QROptions{}, "invalid format"},
+		{"size too small", "size=10", QROptions{}, "invalid size"},
+		{"size not a number", "size=big", QROptions{}, "invalid size"},
+		{"unknown level", "ecc=X", QROptions{}, "invalid error correction level"},
+		{"negative margin", "margin=-1", QROptions{}, "invalid margin"},
+		{"margin too wide", "margin=17", QROptions{}, "invalid margin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			require.NoError(t, err)
+			got, err := ParseQROptions(query)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestQROptions_ETag(t *testing.T) {
+	opts := QROptions{Format: QRFormatPNG, Size: 256, Level: "M", Margin: 4}
+	etag := opts.ETag("http://localhost:8080/abc")
+
+	assert.Equal(t, etag, opts.ETag("http://localhost:8080/abc"))
+	assert.NotEqual(t, etag, opts.ETag("http://localhost:8080/abd"))
+	svg := opts
+	svg.Format = QRFormatSVG
+	assert.NotEqual(t, etag, svg.ETag("http://localhost:8080/abc"))
+	assert.True(t, strings.HasPrefix(etag, `"`) && strings.HasSuffix(etag, `"`))
+}
+
+func TestEncodeQR_PNG(t *testing.T) {
+	opts := QROptions{Format: QRFormatPNG, Size: 300, Level: "M", Margin: 4}
+	data, err := EncodeQR("http://localhost:8080/abcdefgh", opts)
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 300, img.Bounds().Dx())
+	assert.Equal(t, 300, img.Bounds().Dy())
+
+	// The URL needs a version 3 code with 29 modules, plus 8 margin modules,
+	// drawn with 8 pixels per module and centered with a 2 pixel border.
+	black := color.GrayModel.Convert(color.Black)
+	white := color.GrayModel.Convert(color.White)
+	assert.Equal(t, white, color.GrayModel.Convert(img.At(0, 0)), "image corner is white")
+	assert.Equal(t, white, color.GrayModel.Convert(img.At(2+4*8-1, 2+4*8-1)), "quiet zone is white")
+	assert.Equal(t, black, color.GrayModel.Convert(img.At(2+4*8, 2+4*8)), "finder pattern starts after the margin")
+}
+
+func TestEncodeQR_SVG(t *testing.T) {
+	opts := QROptions{Format: QRFormatSVG, Size: 128, Level: "L", Margin: 2}
+	data, err := EncodeQR("http://localhost:8080/abcdefgh", opts)
+	require.NoError(t, err)
+
+	svg := string(data)
+	assert.True(t, strings.HasPrefix(svg, "<svg "))
+	assert.Contains(t, svg, `width="128" height="128"`)
+	assert.Contains(t, svg, `viewBox="0 0 29 29"`)
+	assert.Contains(t, svg, "M2 2h7v1h-7z", "top row of the finder pattern is one run")
+}
+
+func TestEncodeQR_InvalidLevel(t *testing.T) {
+	_, err := EncodeQR("http://localhost:8080/abcdefgh", QROptions{Format: QRFormatPNG, Size: 256, Level: "X"})
+	assert.EqualError(t, err, "invalid error correction level")
+}
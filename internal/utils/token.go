@@ -0,0 +1,416 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signing algorithms supported for the JWT keys.
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// Errors returned when loading the JWT keys.
+var (
+	// ErrNoSigningKey is returned when the active key is missing or cannot sign tokens.
+	ErrNoSigningKey = errors.New("no signing key")
+	// ErrUnknownKey is returned when a token names a key that is not in the key set.
+	ErrUnknownKey = errors.New("unknown key")
+)
+
+// SigningKey is a key of the JWT key set. Keys without SignKey can only verify tokens,
+// which is how retired keys are kept until the tokens they signed have expired.
+type SigningKey struct {
+	ID        string            // Key ID, sent in the kid header of the tokens it signs.
+	Method    jwt.SigningMethod // Signing algorithm of the key.
+	SignKey   interface{}       // Secret or private key signing new tokens, nil for verify-only keys.
+	VerifyKey interface{}       // Secret or public key verifying tokens.
+}
+
+// TokenOptions configures the lifetime of the tokens and the attributes of the cookie holding them.
+type TokenOptions struct {
+	TTL           time.Duration // How long an issued token is valid; also the Max-Age of the cookie.
+	RefreshBefore time.Duration // Tokens expiring sooner than this are re-issued when presented.
+	Secure        bool          // Whether the cookie is only sent over HTTPS.
+	SameSite      http.SameSite // SameSite attribute of the cookie.
+}
+
+// TokenManager issues and verifies the JWT tokens identifying users and keeps them in a cookie.
+// New tokens are signed with the active key and carry its ID in the kid header; tokens signed
+// by any key of the set are accepted. Tokens that are about to expire or were signed by
+// another key than the active one are transparently re-issued by GetOrSetUserIDFromCookie.
+type TokenManager struct {
+	keys   map[string]*SigningKey
+	active *SigningKey
+	opts   TokenOptions
+	now    func() time.Time
+}
+
+// NewTokenManager creates a token manager signing with the key whose ID is activeID.
+func NewTokenManager(keys []SigningKey, activeID string, opts TokenOptions) (*TokenManager, error) {
+	m := &TokenManager{keys: make(map[string]*SigningKey, len(keys)), opts: opts, now: time.Now}
+	for i := range keys {
+		key := &keys[i]
+		if key.VerifyKey == nil {
+			return nil, fmt.Errorf("key %q has no verification key", key.ID)
+		}
+		if _, exists := m.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key %q", key.ID)
+		}
+		m.keys[key.ID] = key
+	}
+	active, ok := m.keys[activeID]
+	if !ok || active.SignKey == nil {
+		return nil, fmt.Errorf("%w: active key %q is missing or has no private key", ErrNoSigningKey, activeID)
+	}
+	m.active = active
+	return m, nil
+}
+
+// newSecretTokenManager creates a token manager signing with a single HS256 key holding secret.
+func newSecretTokenManager(secret string, opts TokenOptions) (*TokenManager, error) {
+	return NewTokenManager([]SigningKey{{
+		ID:        "default",
+		Method:    jwt.SigningMethodHS256,
+		SignKey:   []byte(secret),
+		VerifyKey: []byte(secret),
+	}}, "default", opts)
+}
+
+// keyFile is the format of the JWT key file.
+type keyFile struct {
+	Active string         `json:"active"` // ID of the key signing new tokens.
+	Keys   []keyFileEntry `json:"keys"`
+}
+
+// keyFileEntry is a key of the JWT key file. HS256 keys have a secret; RS256 and EdDSA keys
+// have a PEM private key, or only a public key to verify tokens. PEM keys may be given inline
+// or as the path of a file, relative to the key file.
+type keyFileEntry struct {
+	ID             string `json:"kid"`
+	Algorithm      string `json:"alg"`
+	Secret         string `json:"secret"`
+	PrivateKey     string `json:"private_key"`
+	PrivateKeyFile string `json:"private_key_file"`
+	PublicKey      string `json:"public_key"`
+	PublicKeyFile  string `json:"public_key_file"`
+}
+
+// LoadTokenManager creates the token manager of the configuration: with the keys of the
+// cfg.JWTKeysFile key file, or with a single HS256 key holding cfg.JWTSecret. ErrNoSigningKey is
+// returned if neither is configured, rather than signing with a secret lost on restart.
+func LoadTokenManager(cfg *config.Config) (*TokenManager, error) {
+	opts := TokenOptions{
+		TTL:           cfg.JWTTokenTTL,
+		RefreshBefore: cfg.JWTRefreshBefore,
+		Secure:        cfg.CookieSecure || cfg.EnableHTTPS,
+		SameSite:      parseSameSite(cfg.CookieSameSite),
+	}
+	switch {
+	case cfg.JWTKeysFile != "":
+		keys, active, err := loadKeyFile(cfg.JWTKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		return NewTokenManager(keys, active, opts)
+	case cfg.JWTSecret != "":
+		return newSecretTokenManager(cfg.JWTSecret, opts)
+	default:
+		return nil, fmt.Errorf("%w: neither JWT_KEYS_FILE nor JWT_SECRET is set", ErrNoSigningKey)
+	}
+}
+
+// loadKeyFile reads the keys and the ID of the active key from the key file at path.
+func loadKeyFile(path string) ([]SigningKey, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read JWT key file: %w", err)
+	}
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, "", fmt.Errorf("failed to parse JWT key file: %w", err)
+	}
+	dir := filepath.Dir(path)
+	keys := make([]SigningKey, 0, len(file.Keys))
+	for _, entry := range file.Keys {
+		key, err := entry.signingKey(dir)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid JWT key %q: %w", entry.ID, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, file.Active, nil
+}
+
+// signingKey parses the key material of the entry, reading key files relative to dir.
+func (e keyFileEntry) signingKey(dir string) (SigningKey, error) {
+	if e.ID == "" {
+		return SigningKey{}, errors.New("missing kid")
+	}
+	privatePEM, err := readPEM(e.PrivateKey, e.PrivateKeyFile, dir)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	publicPEM, err := readPEM(e.PublicKey, e.PublicKeyFile, dir)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	key := SigningKey{ID: e.ID}
+	switch e.Algorithm {
+	case AlgorithmHS256:
+		if e.Secret == "" {
+			return SigningKey{}, errors.New("missing secret")
+		}
+		key.Method = jwt.SigningMethodHS256
+		key.SignKey, key.VerifyKey = []byte(e.Secret), []byte(e.Secret)
+	case AlgorithmRS256:
+		key.Method = jwt.SigningMethodRS256
+		if privatePEM != nil {
+			private, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return SigningKey{}, err
+			}
+			key.SignKey, key.VerifyKey = private, &private.PublicKey
+		} else if publicPEM != nil {
+			if key.VerifyKey, err = jwt.ParseRSAPublicKeyFromPEM(publicPEM); err != nil {
+				return SigningKey{}, err
+			}
+		}
+	case AlgorithmEdDSA:
+		key.Method = jwt.SigningMethodEdDSA
+		if privatePEM != nil {
+			private, err := jwt.ParseEdPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return SigningKey{}, err
+			}
+			key.SignKey, key.VerifyKey = private, private.(ed25519.PrivateKey).Public()
+		} else if publicPEM != nil {
+			if key.VerifyKey, err = jwt.ParseEdPublicKeyFromPEM(publicPEM); err != nil {
+				return SigningKey{}, err
+			}
+		}
+	default:
+		return SigningKey{}, fmt.Errorf("unsupported algorithm %q", e.Algorithm)
+	}
+	if key.VerifyKey == nil {
+		return SigningKey{}, errors.New("missing private or public key")
+	}
+	return key, nil
+}
+
+// readPEM returns the inline PEM, or the content of the file at path relative to dir.
+func readPEM(inline, path, dir string) ([]byte, error) {
+	if inline != "" {
+		return []byte(inline), nil
+	}
+	if path == "" {
+		return nil, nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return os.ReadFile(path)
+}
+
+// parseSameSite converts the SameSite setting of the configuration, defaulting to Lax.
+func parseSameSite(value string) http.SameSite {
+	switch strings.ToLower(value) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// Generate issues a token for the user, signed with the active key.
+func (m *TokenManager) Generate(userID string) (string, error) {
+	now := m.now()
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.opts.TTL)),
+		},
+		UserID: userID,
+	}
+	token := jwt.NewWithClaims(m.active.Method, claims)
+	token.Header["kid"] = m.active.ID
+	return token.SignedString(m.active.SignKey)
+}
+
+// Parse verifies the token with the key named by its kid header and fills claims.
+// Tokens without a kid header are tried with every key of their algorithm.
+func (m *TokenManager) Parse(tokenString string, claims *Claims) error {
+	_, err := m.parse(tokenString, claims)
+	return err
+}
+
+// parse verifies the token and returns the key that verified it.
+func (m *TokenManager) parse(tokenString string, claims *Claims) (*SigningKey, error) {
+	var verifiedBy *SigningKey
+	parsedToken, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		if kid, ok := t.Header["kid"].(string); ok {
+			key, exists := m.keys[kid]
+			if !exists {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+			}
+			if key.Method.Alg() != t.Method.Alg() {
+				return nil, fmt.Errorf("expected signing method %s for key %s", key.Method.Alg(), kid)
+			}
+			verifiedBy = key
+			return key.VerifyKey, nil
+		}
+		var candidates jwt.VerificationKeySet
+		for _, key := range m.keys {
+			if key.Method.Alg() == t.Method.Alg() {
+				candidates.Keys = append(candidates.Keys, key.VerifyKey)
+			}
+		}
+		if len(candidates.Keys) == 0 {
+			return nil, errors.New("unexpected signing method: " + t.Method.Alg())
+		}
+		return candidates, nil
+	}, jwt.WithTimeFunc(m.now), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	if !parsedToken.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return verifiedBy, nil
+}
+
+// Cookie returns the cookie holding the token, with the attributes of the options.
+func (m *TokenManager) Cookie(token string) *http.Cookie {
+	return &http.Cookie{
+		Name:     NameCookieUserID,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(m.opts.TTL.Seconds()),
+		HttpOnly: true,
+		Secure:   m.opts.Secure,
+		SameSite: m.opts.SameSite,
+	}
+}
+
+// SetUserIDInCookie generates a new user ID and sets a cookie with its token in the response.
+func (m *TokenManager) SetUserIDInCookie(w http.ResponseWriter, r *http.Request) (string, error) {
+	userID := GenerateUUID()
+	if err := m.setCookie(w, userID); err != nil {
+		return "", err
+	}
+	return userID, nil
+}
+
+// SetCookieForUserID issues a token for the existing user and sets it in the cookie of the response.
+func (m *TokenManager) SetCookieForUserID(w http.ResponseWriter, userID string) error {
+	return m.setCookie(w, userID)
+}
+
+// ClearCookie removes the cookie holding the token from the client.
+func (m *TokenManager) ClearCookie(w http.ResponseWriter) {
+	cookie := m.Cookie("")
+	cookie.MaxAge = -1
+	http.SetCookie(w, cookie)
+}
+
+// GetUserIDFromCookie returns the user ID of a valid token in the cookie of the request.
+func (m *TokenManager) GetUserIDFromCookie(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(NameCookieUserID)
+	if err != nil {
+		return "", false
+	}
+	claims := &Claims{}
+	if err := m.Parse(cookie.Value, claims); err != nil {
+		return "", false
+	}
+	return claims.UserID, true
+}
+
+// RefreshUserIDFromCookie returns the user ID of a valid token in the cookie of the request,
+// re-issuing the token if it expires within the refresh window or was not signed by the active
+// key. Unlike GetOrSetUserIDFromCookie it never creates a user.
+func (m *TokenManager) RefreshUserIDFromCookie(w http.ResponseWriter, r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(NameCookieUserID)
+	if err != nil {
+		return "", false
+	}
+	claims := &Claims{}
+	key, err := m.parse(cookie.Value, claims)
+	if err != nil {
+		return "", false
+	}
+	if key != m.active || claims.ExpiresAt.Sub(m.now()) < m.opts.RefreshBefore {
+		// A failed refresh leaves the still valid token in place.
+		_ = m.setCookie(w, claims.UserID)
+	}
+	return claims.UserID, true
+}
+
+// GetOrSetUserIDFromCookie returns the user ID of a valid token in the cookie of the request,
+// refreshing it as RefreshUserIDFromCookie does. Without a valid token a new user ID is
+// generated and set in the cookie.
+func (m *TokenManager) GetOrSetUserIDFromCookie(w http.ResponseWriter, r *http.Request) (string, error) {
+	if userID, ok := m.RefreshUserIDFromCookie(w, r); ok {
+		return userID, nil
+	}
+	return m.SetUserIDInCookie(w, r)
+}
+
+// setCookie issues a token for the user and sets it in the cookie of the response.
+func (m *TokenManager) setCookie(w http.ResponseWriter, userID string) error {
+	token, err := m.Generate(userID)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, m.Cookie(token))
+	return nil
+}
+
+// defaultTokens is the token manager used by the package-level functions.
+var defaultTokens atomic.Pointer[TokenManager]
+
+func init() {
+	defaultTokens.Store(newEphemeralTokenManager(TokenOptions{
+		TTL:           DefaultTokenTTL,
+		RefreshBefore: DefaultTokenTTL / 4,
+		SameSite:      http.SameSiteLaxMode,
+	}))
+}
+
+// newEphemeralTokenManager creates a token manager signing with a random HS256 secret.
+// Its tokens cannot be forged, and become invalid when the process exits.
+func newEphemeralTokenManager(opts TokenOptions) *TokenManager {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(fmt.Sprintf("failed to generate JWT secret: %v", err))
+	}
+	m, _ := newSecretTokenManager(string(secret), opts)
+	return m
+}
+
+// DefaultTokenManager returns the token manager used by the package-level functions.
+func DefaultTokenManager() *TokenManager {
+	return defaultTokens.Load()
+}
+
+// SetDefaultTokenManager replaces the token manager used by the package-level functions.
+// Until it is called they sign with a random secret generated for the process.
+func SetDefaultTokenManager(m *TokenManager) {
+	defaultTokens.Store(m)
+}
@@ -0,0 +1,343 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GlebRadaev/shlink/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTokenOptions = TokenOptions{TTL: time.Hour, RefreshBefore: 10 * time.Minute, SameSite: http.SameSiteLaxMode}
+
+// hmacKey returns an HS256 key with the given ID and secret.
+func hmacKey(id, secret string) SigningKey {
+	return SigningKey{ID: id, Method: jwt.SigningMethodHS256, SignKey: []byte(secret), VerifyKey: []byte(secret)}
+}
+
+// newTestTokenManager creates a token manager on a clock set by the returned function.
+func newTestTokenManager(t *testing.T, keys []SigningKey, active string) (*TokenManager, func(time.Time)) {
+	t.Helper()
+	m, err := NewTokenManager(keys, active, testTokenOptions)
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	m.now = func() time.Time { return now }
+	return m, func(t time.Time) { now = t }
+}
+
+// requestWithToken returns a request carrying the token in the user cookie.
+func requestWithToken(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: NameCookieUserID, Value: token})
+	return r
+}
+
+// pemBlock encodes the DER bytes as a PEM block of the given type.
+func pemBlock(blockType string, der []byte) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}))
+}
+
+func TestTokenManager_Rotation(t *testing.T) {
+	oldManager, _ := newTestTokenManager(t, []SigningKey{hmacKey("2023", "old secret")}, "2023")
+	newManager, _ := newTestTokenManager(t, []SigningKey{hmacKey("2023", "old secret"), hmacKey("2024", "new secret")}, "2024")
+
+	oldToken, err := oldManager.Generate("alice")
+	require.NoError(t, err)
+	token, _ := jwt.Parse(oldToken, nil)
+	assert.Equal(t, "2023", token.Header["kid"])
+
+	claims := &Claims{}
+	require.NoError(t, newManager.Parse(oldToken, claims), "Expected tokens of the retired key to be accepted")
+	assert.Equal(t, "alice", claims.UserID)
+
+	w := httptest.NewRecorder()
+	userID, err := newManager.GetOrSetUserIDFromCookie(w, requestWithToken(oldToken))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", userID)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1, "Expected the token to be re-issued with the active key")
+	token, _ = jwt.Parse(cookies[0].Value, nil)
+	assert.Equal(t, "2024", token.Header["kid"])
+
+	newToken, err := newManager.Generate("bob")
+	require.NoError(t, err)
+	assert.ErrorIs(t, oldManager.Parse(newToken, &Claims{}), ErrUnknownKey)
+}
+
+func TestTokenManager_TokenWithoutKeyID(t *testing.T) {
+	m, _ := newTestTokenManager(t, []SigningKey{hmacKey("a", "first"), hmacKey("b", "second")}, "a")
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(m.now().Add(time.Hour))},
+		UserID:           "alice",
+	})
+	signed, err := token.SignedString([]byte("second"))
+	require.NoError(t, err)
+
+	claims := &Claims{}
+	require.NoError(t, m.Parse(signed, claims), "Expected tokens without kid to be tried with every key")
+	assert.Equal(t, "alice", claims.UserID)
+
+	signed, err = token.SignedString([]byte("unknown"))
+	require.NoError(t, err)
+	assert.Error(t, m.Parse(signed, &Claims{}))
+}
+
+func TestTokenManager_Algorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	keys := []SigningKey{
+		{ID: "rsa", Method: jwt.SigningMethodRS256, SignKey: rsaKey, VerifyKey: &rsaKey.PublicKey},
+		{ID: "ed", Method: jwt.SigningMethodEdDSA, SignKey: edPrivate, VerifyKey: edPublic},
+	}
+	for _, key := range keys {
+		t.Run(key.Method.Alg(), func(t *testing.T) {
+			m, _ := newTestTokenManager(t, keys, key.ID)
+			token, err := m.Generate("alice")
+			require.NoError(t, err)
+			claims := &Claims{}
+			require.NoError(t, m.Parse(token, claims))
+			assert.Equal(t, "alice", claims.UserID)
+		})
+	}
+
+	t.Run("rejects a token signed with another algorithm than its key", func(t *testing.T) {
+		m, _ := newTestTokenManager(t, keys, "rsa")
+		publicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+		require.NoError(t, err)
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+			RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(m.now().Add(time.Hour))},
+			UserID:           "mallory",
+		})
+		forged.Header["kid"] = "rsa"
+		signed, err := forged.SignedString([]byte(pemBlock("PUBLIC KEY", publicDER)))
+		require.NoError(t, err)
+		assert.Error(t, m.Parse(signed, &Claims{}))
+	})
+
+	t.Run("requires a private key for the active key", func(t *testing.T) {
+		_, err := NewTokenManager([]SigningKey{{ID: "ed", Method: jwt.SigningMethodEdDSA, VerifyKey: edPublic}}, "ed", testTokenOptions)
+		assert.ErrorIs(t, err, ErrNoSigningKey)
+	})
+}
+
+func TestTokenManager_GetOrSetUserIDFromCookie(t *testing.T) {
+	m, setNow := newTestTokenManager(t, []SigningKey{hmacKey("a", "secret")}, "a")
+	start := m.now()
+	token, err := m.Generate("alice")
+	require.NoError(t, err)
+
+	t.Run("keeps a fresh token", func(t *testing.T) {
+		setNow(start.Add(30 * time.Minute))
+		w := httptest.NewRecorder()
+		userID, err := m.GetOrSetUserIDFromCookie(w, requestWithToken(token))
+		require.NoError(t, err)
+		assert.Equal(t, "alice", userID)
+		assert.Empty(t, w.Result().Cookies())
+	})
+
+	t.Run("re-issues a token about to expire", func(t *testing.T) {
+		setNow(start.Add(55 * time.Minute))
+		w := httptest.NewRecorder()
+		userID, err := m.GetOrSetUserIDFromCookie(w, requestWithToken(token))
+		require.NoError(t, err)
+		assert.Equal(t, "alice", userID)
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		claims := &Claims{}
+		require.NoError(t, m.Parse(cookies[0].Value, claims))
+		assert.Equal(t, "alice", claims.UserID)
+		assert.Equal(t, start.Add(115*time.Minute).Unix(), claims.ExpiresAt.Unix())
+	})
+
+	t.Run("issues a new user for an expired token", func(t *testing.T) {
+		setNow(start.Add(2 * time.Hour))
+		w := httptest.NewRecorder()
+		userID, err := m.GetOrSetUserIDFromCookie(w, requestWithToken(token))
+		require.NoError(t, err)
+		assert.NotEqual(t, "alice", userID)
+		assert.Len(t, w.Result().Cookies(), 1)
+	})
+}
+
+func TestTokenManager_RefreshUserIDFromCookie(t *testing.T) {
+	m, setNow := newTestTokenManager(t, []SigningKey{hmacKey("a", "secret")}, "a")
+	start := m.now()
+	token, err := m.Generate("alice")
+	require.NoError(t, err)
+
+	setNow(start.Add(55 * time.Minute))
+	w := httptest.NewRecorder()
+	userID, ok := m.RefreshUserIDFromCookie(w, requestWithToken(token))
+	assert.True(t, ok)
+	assert.Equal(t, "alice", userID)
+	assert.Len(t, w.Result().Cookies(), 1, "Expected a token about to expire to be re-issued")
+
+	setNow(start.Add(2 * time.Hour))
+	w = httptest.NewRecorder()
+	_, ok = m.RefreshUserIDFromCookie(w, requestWithToken(token))
+	assert.False(t, ok)
+	assert.Empty(t, w.Result().Cookies(), "Expected no new user to be created")
+
+	_, ok = m.RefreshUserIDFromCookie(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.False(t, ok)
+}
+
+func TestTokenManager_Cookie(t *testing.T) {
+	m, err := NewTokenManager([]SigningKey{hmacKey("a", "secret")}, "a",
+		TokenOptions{TTL: 24 * time.Hour, Secure: true, SameSite: http.SameSiteStrictMode})
+	require.NoError(t, err)
+
+	cookie := m.Cookie("token")
+	assert.Equal(t, NameCookieUserID, cookie.Name)
+	assert.Equal(t, "token", cookie.Value)
+	assert.Equal(t, "/", cookie.Path)
+	assert.Equal(t, 86400, cookie.MaxAge)
+	assert.True(t, cookie.HttpOnly)
+	assert.True(t, cookie.Secure)
+	assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+}
+
+func TestTokenManager_SetAndClearCookie(t *testing.T) {
+	m, _ := newTestTokenManager(t, []SigningKey{hmacKey("a", "secret")}, "a")
+
+	w := httptest.NewRecorder()
+	require.NoError(t, m.SetCookieForUserID(w, "alice"))
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	userID, ok := m.GetUserIDFromCookie(requestWithToken(cookies[0].Value))
+	assert.True(t, ok)
+	assert.Equal(t, "alice", userID)
+
+	w = httptest.NewRecorder()
+	m.ClearCookie(w)
+	cookies = w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, NameCookieUserID, cookies[0].Name)
+	assert.Empty(t, cookies[0].Value)
+	assert.Negative(t, cookies[0].MaxAge)
+}
+
+func TestLoadTokenManager(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edPrivateDER, err := x509.MarshalPKCS8PrivateKey(edPrivate)
+	require.NoError(t, err)
+	edPublicDER, err := x509.MarshalPKIXPublicKey(edPublic)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "keys"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "keys", "ed.pem"), []byte(pemBlock("PRIVATE KEY", edPrivateDER)), 0o600))
+	keysFile := filepath.Join(dir, "jwt.json")
+	writeKeys := func(t *testing.T, content string) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(keysFile, []byte(content), 0o600))
+	}
+
+	t.Run("loads the keys of the file", func(t *testing.T) {
+		writeKeys(t, `{
+			"active": "ed",
+			"keys": [
+				{"kid": "hmac", "alg": "HS256", "secret": "old secret"},
+				{"kid": "rsa", "alg": "RS256", "private_key": `+jsonString(pemBlock("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)))+`},
+				{"kid": "ed", "alg": "EdDSA", "private_key_file": "keys/ed.pem"},
+				{"kid": "ed-public", "alg": "EdDSA", "public_key": `+jsonString(pemBlock("PUBLIC KEY", edPublicDER))+`}
+			]
+		}`)
+		m, err := LoadTokenManager(&config.Config{JWTKeysFile: keysFile, JWTTokenTTL: time.Hour, CookieSameSite: "strict", EnableHTTPS: true})
+		require.NoError(t, err)
+		assert.Len(t, m.keys, 4)
+		assert.Equal(t, "ed", m.active.ID)
+		assert.Nil(t, m.keys["ed-public"].SignKey)
+
+		token, err := m.Generate("alice")
+		require.NoError(t, err)
+		claims := &Claims{}
+		require.NoError(t, m.Parse(token, claims))
+		assert.Equal(t, "alice", claims.UserID)
+
+		cookie := m.Cookie(token)
+		assert.True(t, cookie.Secure, "Expected the cookie to be secure with HTTPS enabled")
+		assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+	})
+
+	t.Run("fails on invalid keys", func(t *testing.T) {
+		for name, content := range map[string]string{
+			"unknown algorithm": `{"active": "a", "keys": [{"kid": "a", "alg": "none"}]}`,
+			"missing secret":    `{"active": "a", "keys": [{"kid": "a", "alg": "HS256"}]}`,
+			"invalid PEM":       `{"active": "a", "keys": [{"kid": "a", "alg": "RS256", "private_key": "garbage"}]}`,
+			"missing key file":  `{"active": "a", "keys": [{"kid": "a", "alg": "EdDSA", "private_key_file": "missing.pem"}]}`,
+			"unknown active":    `{"active": "b", "keys": [{"kid": "a", "alg": "HS256", "secret": "s"}]}`,
+			"verify-only":       `{"active": "a", "keys": [{"kid": "a", "alg": "EdDSA", "public_key": ` + jsonString(pemBlock("PUBLIC KEY", edPublicDER)) + `}]}`,
+			"not JSON":          `keys`,
+		} {
+			t.Run(name, func(t *testing.T) {
+				writeKeys(t, content)
+				_, err := LoadTokenManager(&config.Config{JWTKeysFile: keysFile})
+				assert.Error(t, err)
+			})
+		}
+	})
+
+	t.Run("uses the secret without a key file", func(t *testing.T) {
+		m, err := LoadTokenManager(&config.Config{JWTSecret: "secret", JWTTokenTTL: time.Hour})
+		require.NoError(t, err)
+		other, err := LoadTokenManager(&config.Config{JWTSecret: "secret", JWTTokenTTL: time.Hour})
+		require.NoError(t, err)
+		token, err := m.Generate("alice")
+		require.NoError(t, err)
+		assert.NoError(t, other.Parse(token, &Claims{}), "Expected tokens to survive a restart")
+	})
+
+	t.Run("accepts tokens without kid", func(t *testing.T) {
+		m, err := LoadTokenManager(&config.Config{JWTSecret: "secret", JWTTokenTTL: time.Hour})
+		require.NoError(t, err)
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+			RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+			UserID:           "alice",
+		})
+		signed, err := token.SignedString([]byte("secret"))
+		require.NoError(t, err)
+		assert.NoError(t, m.Parse(signed, &Claims{}), "Expected cookies without kid to stay valid")
+	})
+
+	t.Run("does not sign the default tokens with a known secret", func(t *testing.T) {
+		for _, secret := range []string{"", "secret", "secret_key"} {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+				RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+				UserID:           "alice",
+			})
+			signed, err := token.SignedString([]byte(secret))
+			require.NoError(t, err)
+			assert.Error(t, newEphemeralTokenManager(TokenOptions{TTL: time.Hour}).Parse(signed, &Claims{}))
+		}
+	})
+
+	t.Run("fails without keys", func(t *testing.T) {
+		_, err := LoadTokenManager(&config.Config{JWTTokenTTL: time.Hour})
+		assert.ErrorIs(t, err, ErrNoSigningKey)
+	})
+}
+
+// jsonString quotes the string as a JSON string literal.
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
@@ -0,0 +1,37 @@
+// Package utils provides various utility functions, including validation of
+// user-chosen aliases for shortened URLs.
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// aliasCharset lists the characters allowed in a custom alias.
+const aliasCharset = charset + "-_"
+
+// reservedAliases contains path segments that are used by the service itself
+// and therefore cannot be taken as aliases.
+var reservedAliases = map[string]struct{}{
+	"api":     {},
+	"auth":    {},
+	"metrics": {},
+	"ping":    {},
+}
+
+// ValidateAlias checks that the alias length is within the given bounds, that it
+// contains only letters, digits, hyphens and underscores, and that it is not reserved.
+func ValidateAlias(alias string, minLength, maxLength int) error {
+	if len(alias) < minLength || len(alias) > maxLength {
+		return errors.New("invalid alias length")
+	}
+	for _, char := range alias {
+		if !strings.ContainsRune(aliasCharset, char) {
+			return errors.New("invalid alias characters")
+		}
+	}
+	if _, reserved := reservedAliases[strings.ToLower(alias)]; reserved {
+		return errors.New("alias is reserved")
+	}
+	return nil
+}
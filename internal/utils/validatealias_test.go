@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateAlias(t *testing.T) {
+	tests := []struct {
+		name    string
+		alias   string
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "valid alias",
+			alias:   "spring-sale",
+			wantErr: false,
+		},
+		{
+			name:    "valid alias with underscore and digits",
+			alias:   "promo_2024",
+			wantErr: false,
+		},
+		{
+			name:    "too short",
+			alias:   "ab",
+			wantErr: true,
+			errMsg:  "invalid alias length",
+		},
+		{
+			name:    "too long",
+			alias:   strings.Repeat("a", 33),
+			wantErr: true,
+			errMsg:  "invalid alias length",
+		},
+		{
+			name:    "invalid characters",
+			alias:   "spring sale",
+			wantErr: true,
+			errMsg:  "invalid alias characters",
+		},
+		{
+			name:    "non-latin characters",
+			alias:   "акция",
+			wantErr: true,
+			errMsg:  "invalid alias characters",
+		},
+		{
+			name:    "reserved word",
+			alias:   "api",
+			wantErr: true,
+			errMsg:  "alias is reserved",
+		},
+		{
+			name:    "reserved metrics path",
+			alias:   "metrics",
+			wantErr: true,
+			errMsg:  "alias is reserved",
+		},
+		{
+			name:    "reserved login path",
+			alias:   "auth",
+			wantErr: true,
+			errMsg:  "alias is reserved",
+		},
+		{
+			name:    "reserved word in another case",
+			alias:   "PING",
+			wantErr: true,
+			errMsg:  "alias is reserved",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAlias(tt.alias, 3, 32)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && err.Error() != tt.errMsg {
+				t.Errorf("error message = %v, want %v", err.Error(), tt.errMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE urls ALTER COLUMN short_id TYPE VARCHAR(32);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE urls ALTER COLUMN short_id TYPE VARCHAR(8);
+-- +goose StatementEnd
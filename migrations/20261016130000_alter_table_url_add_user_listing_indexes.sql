@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX idx_user_id_created_at ON urls (user_id, created_at, short_id);
+CREATE INDEX idx_user_id_original_url ON urls (user_id, original_url, short_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_user_id_original_url;
+DROP INDEX IF EXISTS idx_user_id_created_at;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor_user_id VARCHAR(36) NOT NULL,
+    api_key_id VARCHAR(36) NOT NULL DEFAULT '',
+    action VARCHAR(32) NOT NULL,
+    target TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    affected BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_audit_log_target ON audit_log (target, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS audit_log;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    name VARCHAR(64) PRIMARY KEY,
+    last_run_at TIMESTAMPTZ NOT NULL,
+    locked_by VARCHAR(128) NOT NULL DEFAULT '',
+    locked_until TIMESTAMPTZ NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS scheduled_jobs;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE tasks ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tasks ADD COLUMN fair_key VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN fair_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+UPDATE tasks SET fair_at = run_at;
+CREATE INDEX idx_tasks_fair_at ON tasks (priority DESC, fair_at, id);
+CREATE INDEX idx_tasks_fair_key ON tasks (fair_key, fair_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_tasks_fair_key;
+DROP INDEX IF EXISTS idx_tasks_fair_at;
+ALTER TABLE tasks DROP COLUMN fair_at;
+ALTER TABLE tasks DROP COLUMN fair_key;
+ALTER TABLE tasks DROP COLUMN priority;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE dead_tasks ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE dead_tasks ADD COLUMN fair_key VARCHAR(255) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE dead_tasks DROP COLUMN fair_key;
+ALTER TABLE dead_tasks DROP COLUMN priority;
+-- +goose StatementEnd
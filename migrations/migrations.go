@@ -1,6 +1,55 @@
+// Package migrations embeds the goose migrations of the database schema.
+//
+// The migrations are written for PostgreSQL. Migrations that need different SQL for
+// another dialect have a file with the same name in a directory named after the dialect,
+// such as sqlite/; ForDialect combines them with the common ones.
 package migrations
 
-import "embed"
+import (
+	"embed"
+	"io/fs"
+	"path"
+)
 
-//go:embed *.sql
+// DialectSQLite is the directory of the SQLite versions of the migrations.
+const DialectSQLite = "sqlite"
+
+//go:embed *.sql sqlite/*.sql
 var Migrations embed.FS
+
+// ForDialect returns the migrations for the dialect: the common migrations, each replaced
+// by the file of the same name in the dialect directory if there is one.
+func ForDialect(dialect string) fs.FS {
+	return dialectFS{dialect: dialect}
+}
+
+// dialectFS is a view of Migrations preferring the files of a dialect directory.
+type dialectFS struct {
+	dialect string
+}
+
+// Open opens the dialect version of the named file if it exists, and the common one otherwise.
+func (d dialectFS) Open(name string) (fs.File, error) {
+	if file, err := Migrations.Open(path.Join(d.dialect, name)); err == nil {
+		if info, err := file.Stat(); err == nil && !info.IsDir() {
+			return file, nil
+		}
+		file.Close()
+	}
+	return Migrations.Open(name)
+}
+
+// ReadDir lists the common migrations; the dialect directories are not part of the listing.
+func (d dialectFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := Migrations.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	files := entries[:0]
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files = append(files, entry)
+		}
+	}
+	return files, nil
+}
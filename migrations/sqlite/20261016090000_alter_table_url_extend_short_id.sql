@@ -0,0 +1,8 @@
+-- SQLite does not enforce the length of VARCHAR columns, so short IDs up to 32
+-- characters already fit and there is nothing to change.
+
+-- +goose Up
+SELECT 1;
+
+-- +goose Down
+SELECT 1;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE urls ADD COLUMN expires_at TIMESTAMP NULL;
+ALTER TABLE urls ADD COLUMN max_clicks INTEGER NULL;
+ALTER TABLE urls ADD COLUMN clicks INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX idx_expires_at ON urls (expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_expires_at;
+ALTER TABLE urls DROP COLUMN clicks;
+ALTER TABLE urls DROP COLUMN max_clicks;
+ALTER TABLE urls DROP COLUMN expires_at;
+-- +goose StatementEnd